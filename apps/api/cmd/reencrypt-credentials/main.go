@@ -0,0 +1,88 @@
+// Command reencrypt-credentials migrates every value encrypted with the credentials keyring (Binance
+// credentials, notification channels, webhook secrets, TOTP secrets) to the primary encryption key
+// after a key rotation, printing progress as it goes. It reads the same configuration (environment and APP_CONFIG_FILE) as the server.
+//
+//	reencrypt-credentials            re-encrypt every row not under the primary key
+//	reencrypt-credentials -dry-run   only count stored payloads per key id
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"coin-alert/internal/config"
+	"coin-alert/internal/database"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+	"coin-alert/internal/service"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report how many payloads each key id protects, per table")
+	flag.Parse()
+
+	applicationConfiguration, configurationError := config.LoadApplicationConfiguration()
+	if configurationError != nil {
+		log.Fatalf("Invalid configuration: %v", configurationError)
+	}
+	secretCipher, secretCipherError := security.NewSecretCipherFromConfiguration(applicationConfiguration.Credentials)
+	if secretCipherError != nil {
+		log.Fatalf("Credential encryption is not configured: %v", secretCipherError)
+	}
+
+	postgresConnector, connectionError := database.InitializePostgresConnector(applicationConfiguration.Database.URL())
+	if connectionError != nil {
+		log.Fatalf("Could not connect to database: %v", connectionError)
+	}
+	defer postgresConnector.Close()
+
+	runContext, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	reencryptionService := service.NewCredentialReencryptionService(repository.NewPostgresCiphertextRepository(postgresConnector.Database), secretCipher)
+	fmt.Printf("Keyring: %v, primary key %q\n", secretCipher.KeyIdentifiers(), secretCipher.PrimaryKeyIdentifier())
+
+	if *dryRun {
+		payloadCounts, countError := reencryptionService.CountByKeyIdentifier(runContext)
+		if countError != nil {
+			log.Fatalf("Could not read stored credentials: %v", countError)
+		}
+		keyIdentifiers := make([]string, 0, len(payloadCounts))
+		for keyIdentifier := range payloadCounts {
+			keyIdentifiers = append(keyIdentifiers, keyIdentifier)
+		}
+		sort.Strings(keyIdentifiers)
+		for _, keyIdentifier := range keyIdentifiers {
+			tables := make([]string, 0, len(payloadCounts[keyIdentifier]))
+			keyTotal := 0
+			for table, tableCount := range payloadCounts[keyIdentifier] {
+				tables = append(tables, fmt.Sprintf("%s %d", table, tableCount))
+				keyTotal += tableCount
+			}
+			sort.Strings(tables)
+			fmt.Printf("  key %q: %d payloads (%s)\n", keyIdentifier, keyTotal, strings.Join(tables, ", "))
+		}
+		return
+	}
+
+	progress, runError := reencryptionService.Run(runContext, func(progress service.CredentialReencryptionProgress) {
+		fmt.Printf("  %d/%d scanned, %d re-encrypted, %d already current, %d skipped, %d failed\n",
+			progress.Scanned, progress.Total, progress.Reencrypted, progress.AlreadyCurrent, progress.Skipped, progress.Failed)
+	})
+	if runError != nil {
+		log.Fatalf("Re-encryption stopped after %d of %d rows: %v", progress.Scanned, progress.Total, runError)
+	}
+	fmt.Printf("Done in %s.\n", progress.FinishedAt.Sub(progress.StartedAt).Round(time.Millisecond))
+	if progress.Failed > 0 {
+		fmt.Println("Some rows could not be decrypted with the configured keys; keep the old keys in the ring until they are resolved.")
+		os.Exit(1)
+	}
+}
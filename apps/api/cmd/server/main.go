@@ -2,28 +2,40 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // digest schedules use IANA time zones; do not depend on the image's zoneinfo
 
 	"coin-alert/internal/config"
 	"coin-alert/internal/database"
 	"coin-alert/internal/email"
+	"coin-alert/internal/events"
 	"coin-alert/internal/httpserver"
+	"coin-alert/internal/logging"
+	"coin-alert/internal/notification"
 	"coin-alert/internal/repository"
 	"coin-alert/internal/security"
 	"coin-alert/internal/service"
 )
 
+var mainLogger = logging.For("main")
+
 func main() {
-	applicationConfiguration := config.LoadApplicationConfiguration()
+	applicationConfiguration, configurationError := config.LoadApplicationConfiguration()
+	if configurationError != nil {
+		fatal("could not start with this configuration", "error", configurationError)
+	}
+	logging.Setup(logging.Options{Format: applicationConfiguration.Logging.Format, Level: applicationConfiguration.Logging.Level, ComponentLevels: applicationConfiguration.Logging.ComponentLevels})
+	// Never log secrets; the redacted dump is served to admins at /api/v1/admin/config.
+	mainLogger.Info("loaded configuration", "file", applicationConfiguration.File, "server_port", applicationConfiguration.Server.Port)
 
-	postgresConnector, connectionError := database.InitializePostgresConnector(applicationConfiguration.DatabaseURL)
+	postgresConnector, connectionError := database.InitializePostgresConnector(applicationConfiguration.Database.URL())
 	if connectionError != nil {
-		log.Fatalf("Could not connect to database: %v", connectionError)
+		fatal("could not connect to the database", "error", connectionError)
 	}
 	defer postgresConnector.Close()
 
@@ -38,75 +50,204 @@ func main() {
 	userPortfolioRepository := repository.NewPostgresUserPortfolioRepository(postgresConnector.Database)
 	accountDeletionAuditRepository := repository.NewPostgresAccountDeletionAuditRepository(postgresConnector.Database)
 	authTokenRepository := repository.NewPostgresAuthTokenRepository(postgresConnector.Database)
+	notificationChannelRepository := repository.NewPostgresNotificationChannelRepository(postgresConnector.Database)
+	notificationDeliveryRepository := repository.NewPostgresNotificationDeliveryRepository(postgresConnector.Database)
+	notificationPreferenceRepository := repository.NewPostgresNotificationPreferenceRepository(postgresConnector.Database)
+	digestSubscriptionRepository := repository.NewPostgresDigestSubscriptionRepository(postgresConnector.Database)
+	webhookRepository := repository.NewPostgresWebhookRepository(postgresConnector.Database)
+	outboxRepository := repository.NewPostgresOutboxRepository(postgresConnector.Database)
+	transactionRunner := repository.NewPostgresTransactionRunner(postgresConnector.Database)
+	auditLogRepository := repository.NewPostgresAuditLogRepository(postgresConnector.Database)
+	platformPolicyRepository := repository.NewPostgresPlatformPolicyRepository(postgresConnector.Database)
+	twoFactorRepository := repository.NewPostgresTwoFactorRepository(postgresConnector.Database)
+	personalAccessTokenRepository := repository.NewPostgresPersonalAccessTokenRepository(postgresConnector.Database)
+	userDeviceRepository := repository.NewPostgresUserDeviceRepository(postgresConnector.Database)
 
-	// Encryption for Binance secrets at rest. Without a key, credential storage is refused at runtime.
-	secretCipher, secretCipherError := security.NewSecretCipher(os.Getenv("CREDENTIALS_ENCRYPTION_KEY"))
+	// Domain events: trading writes them to the outbox in its own transaction; the bus fans them out.
+	eventOutbox := events.NewOutbox(outboxRepository)
+	eventBus := events.NewBus(outboxRepository)
+
+	// Encryption for Binance secrets at rest (a keyring, see security.KeyringConfiguration). Without a
+	// key, credential storage is refused at runtime and /health/ready reports degraded.
+	secretCipher, secretCipherError := security.NewSecretCipherFromConfiguration(applicationConfiguration.Credentials)
 	if secretCipherError != nil {
-		log.Printf("WARNING: credential encryption is disabled until CREDENTIALS_ENCRYPTION_KEY is set: %v", secretCipherError)
+		mainLogger.Warn("credential encryption is disabled until CREDENTIALS_ENCRYPTION_KEY is set", "error", secretCipherError)
 	}
+	// After a key rotation, every encrypted value moves to the new primary key in the background.
+	credentialReencryptionService := service.NewCredentialReencryptionService(repository.NewPostgresCiphertextRepository(postgresConnector.Database), secretCipher)
+
+	testnetBaseURL := applicationConfiguration.Binance.TestnetBaseURL
+	productionBaseURL := applicationConfiguration.Binance.ProductionBaseURL
+	publicBaseURL := applicationConfiguration.Server.PublicBaseURL
 
-	testnetBaseURL := environmentValueOrDefault("BINANCE_TESTNET_BASE_URL", "https://testnet.binance.vision")
-	productionBaseURL := environmentValueOrDefault("BINANCE_PRODUCTION_BASE_URL", "https://api.binance.com")
+	// Append-only audit log of security- and money-relevant actions.
+	auditService := service.NewAuditService(auditLogRepository)
+	// Platform-wide security policy (admin-controlled), e.g. whether withdrawal-enabled keys are refused.
+	platformPolicyService := service.NewPlatformPolicyService(platformPolicyRepository, auditService)
 
 	// Authentication.
 	passwordService := service.NewPasswordService()
-	sessionService := service.NewSessionService(userSessionRepository, 720*time.Hour)
-	authService := service.NewAuthService(userRepository, userTradingSettingsRepository, accountDeletionAuditRepository, passwordService, secretCipher)
-	secureSessionCookies := os.Getenv("APP_SECURE_COOKIES") != "false"
+	sessionService := service.NewSessionService(userSessionRepository, applicationConfiguration.Auth.SessionLifetime)
+	authService := service.NewAuthService(userRepository, userTradingSettingsRepository, accountDeletionAuditRepository, passwordService, secretCipher, auditService)
+	secureSessionCookies := applicationConfiguration.Server.SecureCookies
 	googleOAuthService := service.NewGoogleOAuthService(
-		os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
-		os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
-		os.Getenv("GOOGLE_OAUTH_REDIRECT_URL"),
+		applicationConfiguration.GoogleOAuth.ClientID,
+		applicationConfiguration.GoogleOAuth.ClientSecret,
+		applicationConfiguration.GoogleOAuth.RedirectURL,
 	)
 	if googleOAuthService != nil {
-		log.Println("Google sign-in is enabled")
+		mainLogger.Info("Google sign-in is enabled")
 	}
-	emailSender := email.NewSenderFromEnv()
-	accountEmailService := service.NewAccountEmailService(userRepository, authTokenRepository, userSessionRepository, passwordService, emailSender, environmentValueOrDefault("APP_BASE_URL", "https://coin.bobagi.space"))
-	authHandler := httpserver.NewAuthHandler(authService, sessionService, googleOAuthService, accountEmailService, secureSessionCookies)
-	accountHandler := httpserver.NewAccountHandler(authService, sessionService, authHandler.CookieName, secureSessionCookies)
+	emailSender := email.NewSender(applicationConfiguration.SMTP)
+	accountEmailService := service.NewAccountEmailService(userRepository, authTokenRepository, userSessionRepository, passwordService, emailSender, auditService, publicBaseURL)
+	// Throttling of login/signup/password-reset/resend. Postgres shares the counters between instances;
+	// the "memory" store keeps them in process for single-instance setups.
+	var rateLimitRepository repository.RateLimitRepository = repository.NewPostgresRateLimitRepository(postgresConnector.Database)
+	if applicationConfiguration.Auth.RateLimitStore == "memory" {
+		rateLimitRepository = repository.NewMemoryRateLimitRepository()
+	}
+	authRateLimitService := service.NewAuthRateLimitService(rateLimitRepository, accountEmailService)
+	// Optional TOTP second factor; secrets are encrypted with the same keyring as Binance keys.
+	twoFactorService := service.NewTwoFactorService(twoFactorRepository, authTokenRepository, userRepository, userTradingSettingsRepository, secretCipher, transactionRunner, platformPolicyService, auditService, authRateLimitService, "Coin Hub")
+	// Session list/revocation plus new-device sign-in emails. IP_GEOLOCATION_URL (e.g.
+	// "https://ipapi.co/{ip}/json/") enables approximate locations; without it they stay unknown.
+	var sessionLocator service.IPLocator
+	if httpLocator := service.NewHTTPIPLocator(applicationConfiguration.Auth.IPGeolocationURL); httpLocator != nil {
+		sessionLocator = httpLocator
+	}
+	sessionDeviceService := service.NewSessionDeviceService(userSessionRepository, userDeviceRepository, sessionLocator, accountEmailService, auditService)
+	authHandler := httpserver.NewAuthHandler(authService, sessionService, googleOAuthService, accountEmailService, twoFactorService, authRateLimitService, sessionDeviceService, secureSessionCookies)
+	twoFactorHandler := httpserver.NewTwoFactorHandler(twoFactorService)
+	// Personal access tokens for scripts: hashed like sessions, scoped, revocable.
+	personalAccessTokenService := service.NewPersonalAccessTokenService(personalAccessTokenRepository, auditService)
+	accessTokensHandler := httpserver.NewAccessTokensHandler(sessionService, personalAccessTokenService)
+	sessionsHandler := httpserver.NewSessionsHandler(sessionDeviceService)
+	// Sensitive actions need a recent password/2FA confirmation on the session (step-up).
+	stepUpService := service.NewStepUpService(sessionService, userRepository, passwordService, twoFactorService, authRateLimitService)
+	accountHandler := httpserver.NewAccountHandler(authService, sessionService, authHandler.CookieName, secureSessionCookies, stepUpService)
+	auditHandler := httpserver.NewAuditHandler(authService, auditService)
+	adminHandler := httpserver.NewAdminHandler(authService, platformPolicyService, twoFactorService, applicationConfiguration)
+
+	// Outbound notifications (email, Telegram, Discord, webhooks); channel settings are encrypted like Binance keys.
+	notificationService := service.NewNotificationService(notificationChannelRepository, notificationDeliveryRepository, notificationPreferenceRepository, userRepository, secretCipher, notification.NewChannelFactory(emailSender))
+
+	// Signed outgoing webhooks: trading events are queued per endpoint and delivered with backoff.
+	webhookService := service.NewWebhookService(webhookRepository, webhookRepository, secretCipher, notification.NewSignedWebhookSender())
+	webhooksHandler := httpserver.NewWebhooksHandler(webhookService)
 
 	// Per-user trading configuration and Binance credentials.
-	userCredentialService := service.NewUserCredentialService(binanceCredentialRepository, secretCipher, testnetBaseURL, productionBaseURL)
-	apiHandler := httpserver.NewAPIHandler(sessionService, authService, authHandler.CookieName, userTradingSettingsRepository, userCredentialService, testnetBaseURL, productionBaseURL)
+	userCredentialService := service.NewUserCredentialService(binanceCredentialRepository, secretCipher, testnetBaseURL, productionBaseURL, auditService, platformPolicyService)
+	apiHandler := httpserver.NewAPIHandler(sessionService, authService, userTradingSettingsRepository, userCredentialService, testnetBaseURL, productionBaseURL, auditService, twoFactorService)
 
-	userTradingService := service.NewUserTradingService(userCredentialService, userTradingSettingsRepository, tradingOperationRepository, tradingOperationExecutionRepository)
-	operationsHandler := httpserver.NewOperationsHandler(sessionService, authService, authHandler.CookieName, userTradingService)
+	// Idempotency-Key replay for buy, close, take-profit and robot creation, shared through Postgres.
+	idempotencyService := service.NewIdempotencyService(repository.NewPostgresIdempotencyRepository(postgresConnector.Database))
+	userTradingService := service.NewUserTradingService(userCredentialService, userTradingSettingsRepository, tradingOperationRepository, tradingOperationExecutionRepository, transactionRunner, eventOutbox, auditService)
+	operationsHandler := httpserver.NewOperationsHandler(authService, userTradingService, idempotencyService)
 
-	robotService := service.NewRobotService(tradingRobotRepository, userCredentialService)
-	robotsHandler := httpserver.NewRobotsHandler(sessionService, authService, authHandler.CookieName, robotService)
+	robotService := service.NewRobotService(tradingRobotRepository, userCredentialService, transactionRunner, eventOutbox, auditService)
+	robotsHandler := httpserver.NewRobotsHandler(authService, robotService, idempotencyService)
+	credentialHealthService := service.NewCredentialHealthService(binanceCredentialRepository, secretCipher, robotService, transactionRunner, eventOutbox)
 
-	automationWorker := service.NewAutomationWorker(userRepository, userCredentialService, tradingRobotRepository, tradingOperationRepository, tradingOperationExecutionRepository, tradingOperationExecutionRepository, userTradingService, 30*time.Second)
+	digestService := service.NewDigestService(digestSubscriptionRepository, userRepository, userCredentialService, userTradingService, robotService, emailSender, publicBaseURL)
+	notificationsHandler := httpserver.NewNotificationsHandler(notificationService, digestService)
 
-	portfolioScraperClient := service.NewPortfolioScraperClient(environmentValueOrDefault("SCRAPER_BASE_URL", "http://scraper:5000"))
-	portfolioHandler := httpserver.NewPortfolioHandler(sessionService, authService, authHandler.CookieName, userPortfolioRepository, portfolioScraperClient)
+	// Live dashboard updates (SSE), fed by the event bus, the automation worker and a price ticker.
+	liveStreamService := service.NewLiveStreamService(userCredentialService, tradingRobotRepository, tradingOperationRepository)
+	streamHandler := httpserver.NewStreamHandler(liveStreamService)
+
+	tradeEventNotifier := service.NewTradeEventNotifier(notificationPreferenceRepository, notificationService)
+	automationWorker := service.NewAutomationWorker(userRepository, userCredentialService, tradingRobotRepository, tradingOperationRepository, tradingOperationExecutionRepository, tradingOperationExecutionRepository, repository.NewPostgresTradeAlertClaimRepository(postgresConnector.Database), userTradingService, transactionRunner, eventOutbox, liveStreamService, applicationConfiguration.Automation.MonitorInterval, applicationConfiguration.Automation.DailyPurchaseInterval)
+
+	eventBus.Subscribe("webhooks", webhookService.HandleEvent, events.TypeOperationOpened, events.TypeOperationClosed, events.TypeOperationCancelled, events.TypeExecutionLogged, events.TypeRobotChanged)
+	eventBus.Subscribe("trade-notifications", tradeEventNotifier.HandleEvent, events.TypeTradeEvent)
+	eventBus.Subscribe("live-stream", liveStreamService.HandleEvent, events.TypeOperationOpened, events.TypeOperationClosed, events.TypeOperationCancelled, events.TypeExecutionLogged, events.TypeRobotChanged)
+
+	portfolioScraperClient := service.NewPortfolioScraperClient(applicationConfiguration.Scraper.BaseURL)
+	portfolioHandler := httpserver.NewPortfolioHandler(authService, userPortfolioRepository, portfolioScraperClient)
+
+	// Readiness: Postgres, the keyring and the automation loops are required; Binance and the scraper
+	// being unreachable only degrades the service.
+	healthService := service.NewHealthService(
+		service.DatabaseHealthCheck(postgresConnector.Database),
+		service.CipherHealthCheck(secretCipher, secretCipherError),
+		automationWorker.HealthCheck(),
+		service.BinanceHealthCheck("binance_testnet", testnetBaseURL),
+		service.BinanceHealthCheck("binance_production", productionBaseURL),
+		portfolioScraperClient.HealthCheck(),
+	)
+	healthHandler := httpserver.NewHealthHandler(healthService)
 
 	rootRouter := http.NewServeMux()
 	authHandler.RegisterRoutes(rootRouter)
 	accountHandler.RegisterRoutes(rootRouter)
+	twoFactorHandler.RegisterRoutes(rootRouter)
+	accessTokensHandler.RegisterRoutes(rootRouter)
+	sessionsHandler.RegisterRoutes(rootRouter)
+	auditHandler.RegisterRoutes(rootRouter)
+	adminHandler.RegisterRoutes(rootRouter)
 	apiHandler.RegisterRoutes(rootRouter)
 	operationsHandler.RegisterRoutes(rootRouter)
+	operationsHandler.RegisterV2Routes(rootRouter)
 	robotsHandler.RegisterRoutes(rootRouter)
+	robotsHandler.RegisterV2Routes(rootRouter)
 	portfolioHandler.RegisterRoutes(rootRouter)
-	rootRouter.HandleFunc("/health", func(responseWriter http.ResponseWriter, request *http.Request) {
-		responseWriter.WriteHeader(http.StatusOK)
-		_, _ = responseWriter.Write([]byte("ok"))
-	})
+	notificationsHandler.RegisterRoutes(rootRouter)
+	webhooksHandler.RegisterRoutes(rootRouter)
+	streamHandler.RegisterRoutes(rootRouter)
+	httpserver.RegisterOpenAPIRoute(rootRouter)
+	httpserver.RegisterMetricsRoute(rootRouter, authService)
+	httpserver.RegisterAPIV2NotFound(rootRouter)
+	healthHandler.RegisterRoutes(rootRouter)
 
 	applicationContext, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	eventBus.Start(applicationContext, applicationConfiguration.Background.EventBusInterval)
 	automationWorker.Start(applicationContext)
 	sessionService.StartExpiredSessionCleanup(applicationContext, time.Hour)
+	authRateLimitService.StartCleanup(applicationContext, 10*time.Minute)
+	idempotencyService.StartCleanup(applicationContext, time.Hour)
+	digestService.StartScheduler(applicationContext, applicationConfiguration.Background.DigestInterval)
+	webhookService.StartDispatcher(applicationContext, applicationConfiguration.Background.WebhookInterval)
+	liveStreamService.StartPriceTicker(applicationContext, applicationConfiguration.Background.PriceTickerInterval)
+	credentialReencryptionService.StartBackgroundRun(applicationContext, applicationConfiguration.Background.ReencryptionDelay)
+	credentialHealthService.StartHealthChecks(applicationContext, applicationConfiguration.Background.CredentialHealthInterval)
 
-	serverAddress := ":" + applicationConfiguration.ServerPort
-	httpServer := &http.Server{Addr: serverAddress, Handler: rootRouter}
+	serverAddress := ":" + applicationConfiguration.Server.Port
+	// The caller (bearer token or session cookie) is resolved once, in front of every route, and
+	// handlers read it from the request context. The CSRF check runs after it so token-authenticated
+	// calls skip it.
+	originPolicy := httpserver.NewOriginPolicy(applicationConfiguration.Server.AllowedOrigins)
+	rootHandler := httpserver.Chain(rootRouter,
+		httpserver.RequestIDMiddleware,
+		httpserver.ClientAddressMiddleware(applicationConfiguration.Server.TrustedProxies),
+		httpserver.AccessLogMiddleware,
+		httpserver.RecoveryMiddleware,
+		httpserver.SecurityHeadersMiddleware,
+		func(next http.Handler) http.Handler { return httpserver.CORSMiddleware(originPolicy, next) },
+		httpserver.BodyLimitMiddleware(applicationConfiguration.Server.BodyLimit),
+		httpserver.TimeoutMiddleware(applicationConfiguration.Server.RequestTimeout, httpserver.StreamPath),
+		func(next http.Handler) http.Handler {
+			return httpserver.BearerTokenMiddleware(personalAccessTokenService, next)
+		},
+		func(next http.Handler) http.Handler {
+			return httpserver.SessionMiddleware(sessionService, authHandler.CookieName, next)
+		},
+		func(next http.Handler) http.Handler { return httpserver.CSRFMiddleware(originPolicy, next) },
+	)
+	httpServer := &http.Server{
+		Addr:              serverAddress,
+		Handler:           rootHandler,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ErrorLog:          slog.NewLogLogger(logging.For("http").Handler(), slog.LevelWarn),
+	}
 
 	go func() {
-		log.Printf("Coin Hub API listening on %s", serverAddress)
+		mainLogger.Info("Coin Hub API listening", "address", serverAddress)
 		startError := httpServer.ListenAndServe()
 		if startError != nil && startError != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", startError)
+			fatal("server error", "error", startError)
 		}
 	}()
 
@@ -114,14 +255,13 @@ func main() {
 	shutdownContext, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 	if shutdownError := httpServer.Shutdown(shutdownContext); shutdownError != nil {
-		log.Printf("Graceful shutdown failed: %v", shutdownError)
+		mainLogger.Error("graceful shutdown failed", "error", shutdownError)
 	}
-	log.Println("Application stopped")
+	mainLogger.Info("application stopped")
 }
 
-func environmentValueOrDefault(variableName string, fallbackValue string) string {
-	if value := os.Getenv(variableName); value != "" {
-		return value
-	}
-	return fallbackValue
+// fatal logs an error and stops the process.
+func fatal(message string, arguments ...any) {
+	mainLogger.Error(message, arguments...)
+	os.Exit(1)
 }
@@ -1,45 +1,461 @@
+// Package config loads the server configuration once, at startup: defaults, then an optional YAML file
+// (APP_CONFIG_FILE), then environment variables, which win. Every value is parsed and validated
+// before anything starts, so a typo fails the deploy instead of surfacing hours later.
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin-alert/internal/email"
+	"coin-alert/internal/logging"
+	"coin-alert/internal/security"
 )
 
 // ApplicationConfiguration holds the process-level settings the server needs at startup. Per-user
 // trading settings and Binance credentials live in the database (encrypted, scoped per user), not
-// here; this struct intentionally stays minimal.
+// here.
 type ApplicationConfiguration struct {
-	ServerPort  string
-	DatabaseURL string
+	Server      ServerConfiguration
+	Database    DatabaseConfiguration
+	Logging     LoggingConfiguration
+	Auth        AuthConfiguration
+	GoogleOAuth GoogleOAuthConfiguration
+	SMTP        email.SMTPConfiguration
+	Credentials security.KeyringConfiguration
+	Binance     BinanceConfiguration
+	Scraper     ScraperConfiguration
+	Automation  AutomationConfiguration
+	Background  BackgroundConfiguration
+
+	// File is the YAML file the configuration was read from, if any.
+	File     string
+	settings []EffectiveSetting
+}
+
+type ServerConfiguration struct {
+	Port string
+	// PublicBaseURL is where users reach the app; links in emails point to it.
+	PublicBaseURL string
+	// AllowedOrigins may call the API cross-origin with cookies (CORS + CSRF); defaults to the
+	// PublicBaseURL origin.
+	AllowedOrigins []string
+	SecureCookies  bool
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For / X-Real-IP hop is taken as the
+	// client address; requests from anywhere else are identified by their connection address.
+	TrustedProxies []netip.Prefix
+	// BodyLimit caps request bodies, in bytes.
+	BodyLimit int64
+	// RequestTimeout bounds every request except the live stream.
+	RequestTimeout time.Duration
+}
+
+type DatabaseConfiguration struct {
+	Host     string
+	Port     string
+	Name     string
+	User     string
+	Password string
+}
+
+// URL is the lib/pq connection string.
+func (configuration DatabaseConfiguration) URL() string {
+	databaseURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(configuration.User, configuration.Password),
+		Host:     configuration.Host + ":" + configuration.Port,
+		Path:     "/" + configuration.Name,
+		RawQuery: "sslmode=disable",
+	}
+	return databaseURL.String()
+}
+
+type LoggingConfiguration struct {
+	Level           slog.Level
+	ComponentLevels map[string]slog.Level
+	Format          string
+}
+
+type AuthConfiguration struct {
+	SessionLifetime time.Duration
+	// RateLimitStore is "postgres" (shared between instances) or "memory" (single instance).
+	RateLimitStore string
+	// IPGeolocationURL looks up sign-in locations, with "{ip}" replaced by the address; empty skips it.
+	IPGeolocationURL string
+}
+
+// GoogleOAuthConfiguration enables Google sign-in when the client id and secret are set.
+type GoogleOAuthConfiguration struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+type BinanceConfiguration struct {
+	TestnetBaseURL    string
+	ProductionBaseURL string
+}
+
+type ScraperConfiguration struct {
+	BaseURL string
+}
+
+type AutomationConfiguration struct {
+	// MonitorInterval is how often open positions are reconciled and stop-losses checked.
+	MonitorInterval time.Duration
+	// DailyPurchaseInterval is how often due daily purchases are looked for; at most 30 minutes so
+	// none is missed within its UTC hour.
+	DailyPurchaseInterval time.Duration
+}
+
+// BackgroundConfiguration paces the server's other background loops.
+type BackgroundConfiguration struct {
+	// EventBusInterval is how often the outbox is polled; it is the delay before webhooks, alerts and
+	// the live stream see an event.
+	EventBusInterval time.Duration
+	// DigestInterval is how often due digests are looked for; at most an hour so none misses its slot.
+	DigestInterval time.Duration
+	// WebhookInterval is how often queued webhook deliveries are sent.
+	WebhookInterval time.Duration
+	// PriceTickerInterval is how often prices are pushed to open live streams.
+	PriceTickerInterval time.Duration
+	// CredentialHealthInterval is how often each stored Binance key is revalidated.
+	CredentialHealthInterval time.Duration
+	// ReencryptionDelay is how long after startup stored credentials are moved to the primary key.
+	ReencryptionDelay time.Duration
+}
+
+// Setting sources, from lowest to highest precedence.
+const (
+	SourceDefault     = "default"
+	SourceFile        = "file"
+	SourceEnvironment = "environment"
+)
+
+// EffectiveSetting is one setting as the server runs with it, secrets redacted.
+type EffectiveSetting struct {
+	Key         string
+	Environment string
+	Value       string
+	Source      string
+	Secret      bool
+}
+
+// setting ties a YAML key and an environment variable to a field of ApplicationConfiguration.
+type setting struct {
+	key         string
+	environment string
+	fallback    string
+	secret      bool
+	apply       func(value string) error
+}
+
+// settingTable binds every setting to its field of configuration.
+func (configuration *ApplicationConfiguration) settingTable() []setting {
+	return []setting{
+		{key: "server.port", environment: "API_PORT", fallback: "5020", apply: port(&configuration.Server.Port)},
+		{key: "server.public_base_url", environment: "APP_BASE_URL", fallback: "https://coin.bobagi.space", apply: httpURL(&configuration.Server.PublicBaseURL)},
+		{key: "server.allowed_origins", environment: "APP_ALLOWED_ORIGINS", apply: list(&configuration.Server.AllowedOrigins)},
+		{key: "server.secure_cookies", environment: "APP_SECURE_COOKIES", fallback: "true", apply: boolean(&configuration.Server.SecureCookies)},
+		{key: "server.trusted_proxies", environment: "APP_TRUSTED_PROXIES", fallback: "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16", apply: networks(&configuration.Server.TrustedProxies)},
+		{key: "server.body_limit", environment: "API_BODY_LIMIT", fallback: "1MiB", apply: byteSize(&configuration.Server.BodyLimit, 64<<10, 32<<20)},
+		{key: "server.request_timeout", environment: "API_REQUEST_TIMEOUT", fallback: "30s", apply: duration(&configuration.Server.RequestTimeout, 5*time.Second, 5*time.Minute)},
+
+		{key: "database.host", environment: "DB_HOST", fallback: "db", apply: text(&configuration.Database.Host)},
+		{key: "database.port", environment: "DB_PORT", fallback: "5432", apply: port(&configuration.Database.Port)},
+		{key: "database.name", environment: "DB_NAME", fallback: "coin_alert", apply: text(&configuration.Database.Name)},
+		{key: "database.user", environment: "DB_USER", fallback: "postgres", apply: text(&configuration.Database.User)},
+		{key: "database.password", environment: "DB_PASSWORD", fallback: "postgres", secret: true, apply: text(&configuration.Database.Password)},
+
+		{key: "logging.level", environment: "LOG_LEVEL", fallback: "info", apply: func(value string) (parseError error) {
+			configuration.Logging.Level, parseError = logging.ParseLevel(value)
+			return parseError
+		}},
+		{key: "logging.levels", environment: "LOG_LEVELS", apply: func(value string) (parseError error) {
+			configuration.Logging.ComponentLevels, parseError = logging.ParseComponentLevels(value)
+			return parseError
+		}},
+		{key: "logging.format", environment: "LOG_FORMAT", fallback: "json", apply: oneOf(&configuration.Logging.Format, "json", "text")},
+
+		{key: "auth.session_lifetime", environment: "SESSION_LIFETIME", fallback: "720h", apply: duration(&configuration.Auth.SessionLifetime, time.Hour, 365*24*time.Hour)},
+		{key: "auth.rate_limit_store", environment: "AUTH_RATE_LIMIT_STORE", fallback: "postgres", apply: oneOf(&configuration.Auth.RateLimitStore, "postgres", "memory")},
+		{key: "auth.ip_geolocation_url", environment: "IP_GEOLOCATION_URL", secret: true, apply: text(&configuration.Auth.IPGeolocationURL)},
+
+		{key: "google_oauth.client_id", environment: "GOOGLE_OAUTH_CLIENT_ID", apply: text(&configuration.GoogleOAuth.ClientID)},
+		{key: "google_oauth.client_secret", environment: "GOOGLE_OAUTH_CLIENT_SECRET", secret: true, apply: text(&configuration.GoogleOAuth.ClientSecret)},
+		{key: "google_oauth.redirect_url", environment: "GOOGLE_OAUTH_REDIRECT_URL", apply: text(&configuration.GoogleOAuth.RedirectURL)},
+
+		{key: "smtp.host", environment: "SMTP_HOST", apply: text(&configuration.SMTP.Host)},
+		{key: "smtp.port", environment: "SMTP_PORT", fallback: "587", apply: port(&configuration.SMTP.Port)},
+		{key: "smtp.username", environment: "SMTP_USERNAME", apply: text(&configuration.SMTP.Username)},
+		{key: "smtp.password", environment: "SMTP_PASSWORD", secret: true, apply: text(&configuration.SMTP.Password)},
+		{key: "smtp.from", environment: "SMTP_FROM", apply: text(&configuration.SMTP.FromAddress)},
+		{key: "smtp.from_name", environment: "SMTP_FROM_NAME", fallback: "Coin Hub", apply: text(&configuration.SMTP.FromName)},
+
+		{key: "credentials.encryption_key", environment: "CREDENTIALS_ENCRYPTION_KEY", secret: true, apply: text(&configuration.Credentials.EncryptionKey)},
+		{key: "credentials.encryption_keys", environment: "CREDENTIALS_ENCRYPTION_KEYS", secret: true, apply: text(&configuration.Credentials.EncryptionKeys)},
+		{key: "credentials.fingerprint_key", environment: "CREDENTIALS_FINGERPRINT_KEY", secret: true, apply: text(&configuration.Credentials.FingerprintKey)},
+		{key: "credentials.primary_key_id", environment: "CREDENTIALS_ENCRYPTION_PRIMARY_KEY_ID", apply: text(&configuration.Credentials.PrimaryKeyIdentifier)},
+		{key: "credentials.keys_file", environment: "CREDENTIALS_ENCRYPTION_KEYS_FILE", apply: text(&configuration.Credentials.KeysFile)},
+		{key: "credentials.vault.address", environment: "CREDENTIALS_VAULT_ADDR", apply: text(&configuration.Credentials.VaultAddress)},
+		{key: "credentials.vault.transit_mount", environment: "CREDENTIALS_VAULT_TRANSIT_MOUNT", fallback: "transit", apply: text(&configuration.Credentials.VaultTransitMount)},
+		{key: "credentials.vault.transit_key", environment: "CREDENTIALS_VAULT_TRANSIT_KEY", apply: text(&configuration.Credentials.VaultTransitKey)},
+		{key: "credentials.vault.key_id", environment: "CREDENTIALS_VAULT_KEY_ID", fallback: "vault", apply: text(&configuration.Credentials.VaultKeyIdentifier)},
+		{key: "credentials.vault.token_file", environment: "CREDENTIALS_VAULT_TOKEN_FILE", apply: text(&configuration.Credentials.VaultTokenFile)},
+		{key: "credentials.vault.token", environment: "CREDENTIALS_VAULT_TOKEN", secret: true, apply: text(&configuration.Credentials.VaultToken)},
+
+		{key: "binance.testnet_base_url", environment: "BINANCE_TESTNET_BASE_URL", fallback: "https://testnet.binance.vision", apply: httpURL(&configuration.Binance.TestnetBaseURL)},
+		{key: "binance.production_base_url", environment: "BINANCE_PRODUCTION_BASE_URL", fallback: "https://api.binance.com", apply: httpURL(&configuration.Binance.ProductionBaseURL)},
+
+		{key: "scraper.base_url", environment: "SCRAPER_BASE_URL", fallback: "http://scraper:5000", apply: httpURL(&configuration.Scraper.BaseURL)},
+
+		{key: "automation.monitor_interval", environment: "AUTOMATION_MONITOR_INTERVAL", fallback: "30s", apply: duration(&configuration.Automation.MonitorInterval, 5*time.Second, time.Hour)},
+		{key: "automation.daily_purchase_interval", environment: "AUTOMATION_DAILY_PURCHASE_INTERVAL", fallback: "5m", apply: duration(&configuration.Automation.DailyPurchaseInterval, time.Minute, 30*time.Minute)},
+
+		{key: "background.event_bus_interval", environment: "BACKGROUND_EVENT_BUS_INTERVAL", fallback: "2s", apply: duration(&configuration.Background.EventBusInterval, 500*time.Millisecond, time.Minute)},
+		{key: "background.digest_interval", environment: "BACKGROUND_DIGEST_INTERVAL", fallback: "15m", apply: duration(&configuration.Background.DigestInterval, time.Minute, time.Hour)},
+		{key: "background.webhook_interval", environment: "BACKGROUND_WEBHOOK_INTERVAL", fallback: "10s", apply: duration(&configuration.Background.WebhookInterval, time.Second, 5*time.Minute)},
+		{key: "background.price_ticker_interval", environment: "BACKGROUND_PRICE_TICKER_INTERVAL", fallback: "5s", apply: duration(&configuration.Background.PriceTickerInterval, time.Second, time.Minute)},
+		{key: "background.credential_health_interval", environment: "BACKGROUND_CREDENTIAL_HEALTH_INTERVAL", fallback: "1h", apply: duration(&configuration.Background.CredentialHealthInterval, 15*time.Minute, 24*time.Hour)},
+		{key: "background.reencryption_delay", environment: "BACKGROUND_REENCRYPTION_DELAY", fallback: "30s", apply: duration(&configuration.Background.ReencryptionDelay, 0, 10*time.Minute)},
+	}
+}
+
+// LoadApplicationConfiguration reads the configuration from the environment and, when APP_CONFIG_FILE
+// names one, a YAML file. It returns every invalid value at once.
+func LoadApplicationConfiguration() (*ApplicationConfiguration, error) {
+	return loadApplicationConfiguration(os.Getenv, os.ReadFile)
 }
 
-func LoadApplicationConfiguration() ApplicationConfiguration {
-	configuration := ApplicationConfiguration{
-		ServerPort:  getEnvironmentValueWithDefault("API_PORT", "5020"),
-		DatabaseURL: buildDatabaseURL(),
+func loadApplicationConfiguration(lookupEnvironment func(string) string, readFile func(string) ([]byte, error)) (*ApplicationConfiguration, error) {
+	configuration := &ApplicationConfiguration{File: strings.TrimSpace(lookupEnvironment("APP_CONFIG_FILE"))}
+	fileValues := map[string]string{}
+	if configuration.File != "" {
+		content, readError := readFile(configuration.File)
+		if readError != nil {
+			return nil, fmt.Errorf("could not read the configuration file: %w", readError)
+		}
+		var parseError error
+		if fileValues, parseError = parseYAML(string(content)); parseError != nil {
+			return nil, fmt.Errorf("%s: %w", configuration.File, parseError)
+		}
 	}
 
-	// Never log the database URL or any secrets — only the non-sensitive port.
-	log.Printf("Loaded configuration (non-sensitive): serverPort=%s", configuration.ServerPort)
+	var problems []error
+	settings := configuration.settingTable()
+	knownKeys := make(map[string]bool, len(settings))
+	for _, current := range settings {
+		knownKeys[current.key] = true
+		value, source := current.fallback, SourceDefault
+		if fileValue, present := fileValues[current.key]; present {
+			value, source = fileValue, SourceFile
+		}
+		// An empty variable counts as unset, like the blank entries of .env.example.
+		if environmentValue := strings.TrimSpace(lookupEnvironment(current.environment)); environmentValue != "" {
+			value, source = environmentValue, SourceEnvironment
+		}
+		if applyError := current.apply(strings.TrimSpace(value)); applyError != nil {
+			problems = append(problems, fmt.Errorf("%s (%s): %w", current.key, current.environment, applyError))
+		}
+		configuration.settings = append(configuration.settings, EffectiveSetting{Key: current.key, Environment: current.environment, Value: value, Source: source, Secret: current.secret})
+	}
+	unknownKeys := make([]string, 0)
+	for key := range fileValues {
+		if !knownKeys[key] {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	sort.Strings(unknownKeys)
+	for _, key := range unknownKeys {
+		problems = append(problems, fmt.Errorf("%s: unknown setting in %s", key, configuration.File))
+	}
 
-	return configuration
+	problems = append(problems, configuration.validate()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %w", errors.Join(problems...))
+	}
+	if len(configuration.Server.AllowedOrigins) == 0 {
+		configuration.Server.AllowedOrigins = []string{configuration.Server.PublicBaseURL}
+	}
+	return configuration, nil
 }
 
-func buildDatabaseURL() string {
-	databaseUser := getEnvironmentValueWithDefault("DB_USER", "postgres")
-	databasePassword := getEnvironmentValueWithDefault("DB_PASSWORD", "postgres")
-	databaseName := getEnvironmentValueWithDefault("DB_NAME", "coin_alert")
-	databaseHost := getEnvironmentValueWithDefault("DB_HOST", "db")
-	databasePort := getEnvironmentValueWithDefault("DB_PORT", "5432")
+// validate checks the rules that involve more than one setting.
+func (configuration *ApplicationConfiguration) validate() []error {
+	var problems []error
+	smtp := configuration.SMTP
+	if (smtp.Host != "" || smtp.Username != "" || smtp.Password != "") && !smtp.IsConfigured() {
+		problems = append(problems, errors.New("smtp: host, username and password must be set together"))
+	}
+	oauth := configuration.GoogleOAuth
+	if (oauth.ClientID != "") != (oauth.ClientSecret != "") {
+		problems = append(problems, errors.New("google_oauth: client_id and client_secret must be set together"))
+	}
+	if oauth.ClientID != "" && oauth.RedirectURL == "" {
+		problems = append(problems, errors.New("google_oauth: redirect_url is required when Google sign-in is enabled"))
+	}
+	if keyringError := configuration.Credentials.Validate(); keyringError != nil {
+		problems = append(problems, fmt.Errorf("credentials: %w", keyringError))
+	}
+	for _, origin := range configuration.Server.AllowedOrigins {
+		if _, originError := parseHTTPURL(origin); originError != nil {
+			problems = append(problems, fmt.Errorf("server.allowed_origins: %w", originError))
+		}
+	}
+	return problems
+}
+
+// EffectiveSettings lists every setting with the value in use and where it came from. Secret values
+// are replaced by "[REDACTED]" and everything else still goes through the log redaction rules.
+func (configuration *ApplicationConfiguration) EffectiveSettings() []EffectiveSetting {
+	effective := make([]EffectiveSetting, 0, len(configuration.settings))
+	for _, current := range configuration.settings {
+		if current.Secret && current.Value != "" {
+			current.Value = "[REDACTED]"
+		} else {
+			current.Value = logging.RedactString(current.Value)
+		}
+		effective = append(effective, current)
+	}
+	return effective
+}
 
-	return "postgres://" + databaseUser + ":" + databasePassword + "@" + databaseHost + ":" + databasePort + "/" + databaseName + "?sslmode=disable"
+func text(target *string) func(string) error {
+	return func(value string) error {
+		*target = value
+		return nil
+	}
 }
 
-func getEnvironmentValueWithDefault(variableName string, defaultValue string) string {
-	environmentValue := os.Getenv(variableName)
-	if environmentValue == "" {
-		return defaultValue
+func oneOf(target *string, allowedValues ...string) func(string) error {
+	return func(value string) error {
+		for _, allowedValue := range allowedValues {
+			if strings.EqualFold(value, allowedValue) {
+				*target = allowedValue
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(allowedValues, ", "))
 	}
+}
 
-	return environmentValue
+func port(target *string) func(string) error {
+	return func(value string) error {
+		if portNumber, parseError := strconv.Atoi(value); parseError != nil || portNumber < 1 || portNumber > 65535 {
+			return fmt.Errorf("%q is not a port number", value)
+		}
+		*target = value
+		return nil
+	}
+}
+
+func boolean(target *bool) func(string) error {
+	return func(value string) error {
+		parsedValue, parseError := strconv.ParseBool(value)
+		if parseError != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*target = parsedValue
+		return nil
+	}
+}
+
+func duration(target *time.Duration, minimum time.Duration, maximum time.Duration) func(string) error {
+	return func(value string) error {
+		parsedValue, parseError := time.ParseDuration(value)
+		if parseError != nil {
+			return fmt.Errorf("%q is not a duration such as 30s, 5m or 720h", value)
+		}
+		if parsedValue < minimum || parsedValue > maximum {
+			return fmt.Errorf("%s must be between %s and %s", parsedValue, minimum, maximum)
+		}
+		*target = parsedValue
+		return nil
+	}
+}
+
+// byteSize parses a size in bytes, optionally with a KiB or MiB suffix.
+func byteSize(target *int64, minimum int64, maximum int64) func(string) error {
+	return func(value string) error {
+		number, multiplier := value, int64(1)
+		for _, unit := range []struct {
+			suffix string
+			bytes  int64
+		}{{"KiB", 1 << 10}, {"MiB", 1 << 20}} {
+			if strings.HasSuffix(value, unit.suffix) {
+				number, multiplier = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.bytes
+			}
+		}
+		parsedValue, parseError := strconv.ParseInt(number, 10, 64)
+		if parseError != nil || parsedValue < 0 {
+			return fmt.Errorf("%q is not a size such as 1048576, 512KiB or 1MiB", value)
+		}
+		if parsedValue *= multiplier; parsedValue < minimum || parsedValue > maximum {
+			return fmt.Errorf("%d bytes must be between %d and %d", parsedValue, minimum, maximum)
+		}
+		*target = parsedValue
+		return nil
+	}
+}
+
+func list(target *[]string) func(string) error {
+	return func(value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+		return nil
+	}
+}
+
+// networks parses a comma-separated list of CIDR ranges or single addresses.
+func networks(target *[]netip.Prefix) func(string) error {
+	return func(value string) error {
+		var prefixes []netip.Prefix
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			prefix, prefixError := netip.ParsePrefix(item)
+			if prefixError != nil {
+				address, addressError := netip.ParseAddr(item)
+				if addressError != nil {
+					return fmt.Errorf("%q is not an IP address or CIDR range", item)
+				}
+				prefix = netip.PrefixFrom(address, address.BitLen())
+			}
+			prefixes = append(prefixes, prefix.Masked())
+		}
+		*target = prefixes
+		return nil
+	}
+}
+
+func httpURL(target *string) func(string) error {
+	return func(value string) error {
+		if _, parseError := parseHTTPURL(value); parseError != nil {
+			return parseError
+		}
+		*target = strings.TrimRight(value, "/")
+		return nil
+	}
+}
+
+func parseHTTPURL(value string) (*url.URL, error) {
+	parsedURL, parseError := url.Parse(value)
+	if parseError != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", value)
+	}
+	return parsedURL, nil
 }
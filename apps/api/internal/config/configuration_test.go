@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadWith(environment map[string]string, files map[string]string) (*ApplicationConfiguration, error) {
+	return loadApplicationConfiguration(func(name string) string { return environment[name] }, func(path string) ([]byte, error) {
+		content, found := files[path]
+		if !found {
+			return nil, fs.ErrNotExist
+		}
+		return []byte(content), nil
+	})
+}
+
+func TestDefaultsFileAndEnvironmentPrecedence(t *testing.T) {
+	configuration, loadError := loadWith(map[string]string{
+		"APP_CONFIG_FILE":             "/etc/coinhub.yaml",
+		"AUTOMATION_MONITOR_INTERVAL": "45s",
+		"DB_PASSWORD":                 "",
+	}, map[string]string{"/etc/coinhub.yaml": `
+server:
+  port: 8080 # behind the proxy
+  allowed_origins:
+    - https://coin.example.com
+    - "https://admin.example.com"
+database:
+  password: 'from-file'
+automation:
+  monitor_interval: 10s
+  daily_purchase_interval: 2m
+`})
+	if loadError != nil {
+		t.Fatalf("unexpected error: %v", loadError)
+	}
+	if configuration.Server.Port != "8080" || configuration.Database.Password != "from-file" {
+		t.Errorf("file values were not applied: port %q, password %q", configuration.Server.Port, configuration.Database.Password)
+	}
+	if configuration.Automation.MonitorInterval != 45*time.Second || configuration.Automation.DailyPurchaseInterval != 2*time.Minute {
+		t.Errorf("intervals = %s, %s; want the environment to win over the file", configuration.Automation.MonitorInterval, configuration.Automation.DailyPurchaseInterval)
+	}
+	if configuration.Auth.SessionLifetime != 720*time.Hour || configuration.Scraper.BaseURL != "http://scraper:5000" {
+		t.Errorf("defaults were not applied: %s, %q", configuration.Auth.SessionLifetime, configuration.Scraper.BaseURL)
+	}
+	if configuration.Server.BodyLimit != 1<<20 || configuration.Server.RequestTimeout != 30*time.Second || configuration.Background.EventBusInterval != 2*time.Second || configuration.Background.CredentialHealthInterval != time.Hour {
+		t.Errorf("loop and request defaults were not applied: %+v, %+v", configuration.Server, configuration.Background)
+	}
+	if got := strings.Join(configuration.Server.AllowedOrigins, " "); got != "https://coin.example.com https://admin.example.com" {
+		t.Errorf("allowed origins = %q", got)
+	}
+
+	sources := map[string]string{}
+	for _, effective := range configuration.EffectiveSettings() {
+		sources[effective.Key] = effective.Source
+	}
+	if sources["server.port"] != SourceFile || sources["automation.monitor_interval"] != SourceEnvironment || sources["logging.level"] != SourceDefault {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+}
+
+func TestAllowedOriginsDefaultToThePublicBaseURL(t *testing.T) {
+	configuration, loadError := loadWith(map[string]string{"APP_BASE_URL": "https://coin.example.com/"}, nil)
+	if loadError != nil {
+		t.Fatalf("unexpected error: %v", loadError)
+	}
+	if len(configuration.Server.AllowedOrigins) != 1 || configuration.Server.AllowedOrigins[0] != "https://coin.example.com" {
+		t.Errorf("allowed origins = %v", configuration.Server.AllowedOrigins)
+	}
+}
+
+func TestInvalidValuesAreReportedTogether(t *testing.T) {
+	_, loadError := loadWith(map[string]string{
+		"API_PORT":                    "70000",
+		"SESSION_LIFETIME":            "forever",
+		"AUTOMATION_MONITOR_INTERVAL": "1s",
+		"BACKGROUND_DIGEST_INTERVAL":  "2h",
+		"API_BODY_LIMIT":              "1GiB",
+		"AUTH_RATE_LIMIT_STORE":       "redis",
+		"SCRAPER_BASE_URL":            "scraper:5000",
+		"SMTP_HOST":                   "smtp.example.com",
+		"GOOGLE_OAUTH_CLIENT_ID":      "client-id",
+		"CREDENTIALS_VAULT_ADDR":      "https://vault.example.com",
+	}, nil)
+	if loadError == nil {
+		t.Fatal("expected the configuration to be rejected")
+	}
+	for _, expected := range []string{
+		"server.port (API_PORT)",
+		"auth.session_lifetime (SESSION_LIFETIME)",
+		"automation.monitor_interval (AUTOMATION_MONITOR_INTERVAL)",
+		"background.digest_interval (BACKGROUND_DIGEST_INTERVAL)",
+		"server.body_limit (API_BODY_LIMIT)",
+		"auth.rate_limit_store (AUTH_RATE_LIMIT_STORE)",
+		"scraper.base_url (SCRAPER_BASE_URL)",
+		"smtp: host, username and password must be set together",
+		"google_oauth: client_id and client_secret must be set together",
+		"credentials: the Vault address and transit key must be set together",
+	} {
+		if !strings.Contains(loadError.Error(), expected) {
+			t.Errorf("error does not mention %q:\n%v", expected, loadError)
+		}
+	}
+}
+
+func TestConfigurationFileProblems(t *testing.T) {
+	cases := map[string]string{
+		"server:\n  prot: 5020\n":               "server.prot: unknown setting",
+		"server:\n\tport: 5020\n":               "line 2: indent with spaces, not tabs",
+		"server:\n  port: 5020\n  port: 5021\n": "line 3: server.port is set twice",
+		"logging:\n  level: &info info\n":       "line 2: unsupported YAML value",
+	}
+	for content, expected := range cases {
+		_, loadError := loadWith(map[string]string{"APP_CONFIG_FILE": "coinhub.yaml"}, map[string]string{"coinhub.yaml": content})
+		if loadError == nil || !strings.Contains(loadError.Error(), expected) {
+			t.Errorf("%q: error = %v, want it to mention %q", content, loadError, expected)
+		}
+	}
+
+	_, loadError := loadWith(map[string]string{"APP_CONFIG_FILE": "missing.yaml"}, nil)
+	if !errors.Is(loadError, fs.ErrNotExist) {
+		t.Errorf("a missing file should fail the load, got %v", loadError)
+	}
+}
+
+func TestEffectiveSettingsRedactSecrets(t *testing.T) {
+	configuration, loadError := loadWith(map[string]string{
+		"DB_PASSWORD":                 "hunter2-database",
+		"SMTP_HOST":                   "smtp.example.com",
+		"SMTP_USERNAME":               "alerts@example.com",
+		"SMTP_PASSWORD":               "hunter2-smtp",
+		"CREDENTIALS_ENCRYPTION_KEY":  "c2VjcmV0LWtleS1zZWNyZXQta2V5LXNlY3JldC1rZXk=",
+		"CREDENTIALS_FINGERPRINT_KEY": "ZmluZ2VycHJpbnQta2V5LWZpbmdlcnByaW50LWtleSE=",
+	}, nil)
+	if loadError != nil {
+		t.Fatalf("unexpected error: %v", loadError)
+	}
+	for _, effective := range configuration.EffectiveSettings() {
+		for _, forbidden := range []string{"hunter2", "c2VjcmV0", "ZmluZ2Vy", "alerts@example.com"} {
+			if strings.Contains(effective.Value, forbidden) {
+				t.Errorf("%s leaked %q: %s", effective.Key, forbidden, effective.Value)
+			}
+		}
+		if effective.Key == "database.password" && (!effective.Secret || effective.Value != "[REDACTED]") {
+			t.Errorf("database.password = %+v", effective)
+		}
+		if effective.Key == "smtp.host" && effective.Value != "smtp.example.com" {
+			t.Errorf("smtp.host should stay readable, got %q", effective.Value)
+		}
+	}
+}
+
+func TestExampleConfigurationFileLoads(t *testing.T) {
+	content, readError := os.ReadFile("../../config.example.yaml")
+	if readError != nil {
+		t.Fatal(readError)
+	}
+	if _, loadError := loadWith(map[string]string{"APP_CONFIG_FILE": "config.example.yaml"}, map[string]string{"config.example.yaml": string(content)}); loadError != nil {
+		t.Errorf("config.example.yaml does not load: %v", loadError)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected int64
+		valid    bool
+	}{
+		{value: "1048576", expected: 1 << 20, valid: true},
+		{value: "512KiB", expected: 512 << 10, valid: true},
+		{value: "2 MiB", expected: 2 << 20, valid: true},
+		{value: "32KiB"},
+		{value: "64MiB"},
+		{value: "1GiB"},
+		{value: "-1MiB"},
+		{value: "lots"},
+	}
+	for _, testCase := range cases {
+		var parsed int64
+		applyError := byteSize(&parsed, 64<<10, 32<<20)(testCase.value)
+		if (applyError == nil) != testCase.valid || parsed != testCase.expected {
+			t.Errorf("%q: got %d, %v", testCase.value, parsed, applyError)
+		}
+	}
+}
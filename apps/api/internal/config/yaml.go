@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads the small YAML subset the configuration file needs and flattens it to dotted keys
+// ("server.port"): nested mappings indented with spaces, plain or quoted scalars, lists (block "- item"
+// or flow "[a, b]", joined with commas) and # comments. Anchors, multi-line strings and other YAML
+// features are rejected rather than misread.
+func parseYAML(content string) (map[string]string, error) {
+	type frame struct {
+		indent   int
+		path     string
+		isScalar bool
+	}
+	values := make(map[string]string)
+	listItems := make(map[string][]string)
+	frames := []frame{{indent: -1}}
+
+	for lineIndex, rawLine := range strings.Split(content, "\n") {
+		lineNumber := lineIndex + 1
+		line := strings.TrimRight(stripYAMLComment(rawLine), " \r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || (lineIndex == 0 && trimmed == "---") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, not tabs", lineNumber)
+		}
+		indent := len(line) - len(trimmed)
+
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			for frames[len(frames)-1].indent > indent {
+				frames = frames[:len(frames)-1]
+			}
+			parent := frames[len(frames)-1]
+			if parent.path == "" || parent.isScalar {
+				return nil, fmt.Errorf("line %d: a list item must belong to a key", lineNumber)
+			}
+			item, itemError := parseYAMLScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if itemError != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, itemError)
+			}
+			listItems[parent.path] = append(listItems[parent.path], item)
+			continue
+		}
+
+		for frames[len(frames)-1].indent >= indent {
+			frames = frames[:len(frames)-1]
+		}
+		parent := frames[len(frames)-1]
+		if parent.isScalar {
+			return nil, fmt.Errorf("line %d: unexpected indentation", lineNumber)
+		}
+		name, rest, found := strings.Cut(trimmed, ":")
+		if !found || !yamlKeyPattern.MatchString(name) || (rest != "" && rest[0] != ' ') {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", lineNumber)
+		}
+		path := name
+		if parent.path != "" {
+			path = parent.path + "." + name
+		}
+		if _, duplicate := values[path]; duplicate {
+			return nil, fmt.Errorf("line %d: %s is set twice", lineNumber, path)
+		}
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			values[path] = ""
+			frames = append(frames, frame{indent: indent, path: path})
+			continue
+		}
+		value, valueError := parseYAMLValue(rest)
+		if valueError != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, valueError)
+		}
+		values[path] = value
+		frames = append(frames, frame{indent: indent, path: path, isScalar: true})
+	}
+
+	for path, items := range listItems {
+		values[path] = strings.Join(items, ",")
+	}
+	// A key that only opens a nested mapping is not a setting of its own.
+	for path := range values {
+		if values[path] == "" && hasNestedKey(values, path) {
+			delete(values, path)
+		}
+	}
+	return values, nil
+}
+
+var yamlKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+func hasNestedKey(values map[string]string, path string) bool {
+	for candidate := range values {
+		if strings.HasPrefix(candidate, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// stripYAMLComment drops a # comment that starts the line or follows whitespace, outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for index := 0; index < len(line); index++ {
+		character := line[index]
+		switch {
+		case quote != 0:
+			if character == '\\' && quote == '"' {
+				index++
+			} else if character == quote {
+				quote = 0
+			}
+		case character == '"' || character == '\'':
+			quote = character
+		case character == '#' && (index == 0 || line[index-1] == ' ' || line[index-1] == '\t'):
+			return line[:index]
+		}
+	}
+	return line
+}
+
+func parseYAMLValue(text string) (string, error) {
+	if !strings.HasPrefix(text, "[") {
+		return parseYAMLScalar(text)
+	}
+	if !strings.HasSuffix(text, "]") {
+		return "", fmt.Errorf("unterminated list %q", text)
+	}
+	var items []string
+	for _, item := range strings.Split(text[1:len(text)-1], ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parsedItem, itemError := parseYAMLScalar(strings.TrimSpace(item))
+		if itemError != nil {
+			return "", itemError
+		}
+		items = append(items, parsedItem)
+	}
+	return strings.Join(items, ","), nil
+}
+
+func parseYAMLScalar(text string) (string, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		unquoted, unquoteError := strconv.Unquote(text)
+		if unquoteError != nil {
+			return "", fmt.Errorf("invalid double-quoted string %s", text)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", fmt.Errorf("invalid single-quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.ContainsAny(text[:1], "&*!|>{[%@`"):
+		return "", fmt.Errorf("unsupported YAML value %q; quote it", text)
+	case text == "~" || text == "null":
+		return "", nil
+	}
+	return text, nil
+}
@@ -3,11 +3,12 @@ package database
 import (
 	"context"
 	"database/sql"
-	"log"
 	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
+
+	"coin-alert/internal/logging"
 )
 
 type PostgresConnector struct {
@@ -29,21 +30,22 @@ func InitializePostgresConnector(databaseURL string) (*PostgresConnector, error)
 		return nil, pingError
 	}
 
-	log.Println("Connected to PostgreSQL")
+	databaseLogger.Info("connected to PostgreSQL")
 	return &PostgresConnector{Database: databaseConnection}, nil
 }
 
+var databaseLogger = logging.For("database")
+
 func logConnectionTroubleshootingGuidance(connectionError error) {
 	errorMessage := connectionError.Error()
 
 	if strings.Contains(errorMessage, "role") && strings.Contains(errorMessage, "does not exist") {
-		log.Println("The configured database user does not exist inside the PostgreSQL data volume.")
-		log.Println("If you recently changed DB_USER or DB_PASSWORD, recreate the db_data volume or align credentials with the original database owner.")
+		databaseLogger.Error("the configured database user does not exist inside the PostgreSQL data volume; if you recently changed DB_USER or DB_PASSWORD, recreate the db_data volume or align credentials with the original database owner")
 		return
 	}
 
 	if strings.Contains(errorMessage, "password authentication failed") {
-		log.Println("PostgreSQL rejected the supplied credentials. Confirm DB_USER and DB_PASSWORD match the initialized database or recreate the db_data volume.")
+		databaseLogger.Error("PostgreSQL rejected the supplied credentials; confirm DB_USER and DB_PASSWORD match the initialized database or recreate the db_data volume")
 	}
 }
 
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Audited actions.
+const (
+	AuditActionLogin                = "auth.login"
+	AuditActionGoogleLinked         = "account.google_linked"
+	AuditActionPasswordChanged      = "account.password_changed"
+	AuditActionCredentialsSaved     = "credentials.saved"
+	AuditActionEnvironmentActivated = "credentials.environment_activated"
+	AuditActionSettingsUpdated      = "settings.updated"
+	AuditActionLiveTradingToggled   = "settings.live_trading_toggled"
+	AuditActionRobotCreated         = "robot.created"
+	AuditActionRobotUpdated         = "robot.updated"
+	AuditActionRobotDeleted         = "robot.deleted"
+	AuditActionManualBuy            = "trade.manual_buy"
+	AuditActionManualSell           = "trade.manual_sell"
+	AuditActionTakeProfitPlaced     = "trade.take_profit_placed"
+	AuditActionPolicyUpdated        = "admin.policy_updated"
+	AuditActionTwoFactorEnabled     = "account.two_factor_enabled"
+	AuditActionTwoFactorDisabled    = "account.two_factor_disabled"
+	AuditActionTwoFactorReset       = "admin.two_factor_reset"
+	AuditActionRecoveryCodesRenewed = "account.recovery_codes_regenerated"
+	AuditActionRecoveryCodeUsed     = "account.recovery_code_used"
+	AuditActionAccessTokenCreated   = "account.access_token_created"
+	AuditActionAccessTokenRevoked   = "account.access_token_revoked"
+	AuditActionSessionRevoked       = "account.session_revoked"
+	AuditActionOtherSessionsRevoked = "account.other_sessions_revoked"
+)
+
+// AuditEntry is one row of the append-only audit log. BeforeValue/AfterValue are JSON documents
+// (nil when not applicable); ActorUserIdentifier is nil for actions taken by the system.
+type AuditEntry struct {
+	Identifier          int64
+	UserIdentifier      int64
+	ActorUserIdentifier *int64
+	Action              string
+	TargetType          string
+	TargetIdentifier    string
+	IPAddress           string
+	UserAgent           string
+	BeforeValue         *string
+	AfterValue          *string
+	CreatedAt           time.Time
+}
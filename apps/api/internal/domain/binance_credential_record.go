@@ -6,4 +6,6 @@ type BinanceCredentialRecord struct {
         EnvironmentName string
         APIBaseURL      string
         IsActive        bool
+        Permissions     *BinanceKeyPermissions // nil when not checked (e.g. testnet)
+        Health          CredentialHealth
 }
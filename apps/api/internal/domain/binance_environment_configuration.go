@@ -12,6 +12,8 @@ type BinanceEnvironmentConfiguration struct {
         RESTBaseURL     string
         APIKey          string
         APISecret       string
+        // CredentialHealth is the stored key's last health check result (CredentialHealth* constants).
+        CredentialHealth string
 }
 
 func NormalizeBinanceEnvironment(environmentName string) string {
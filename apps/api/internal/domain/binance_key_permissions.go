@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// BinanceKeyPermissions is what Binance reports about an API key's restrictions.
+type BinanceKeyPermissions struct {
+	ReadingEnabled     bool
+	SpotTradingEnabled bool
+	WithdrawalsEnabled bool
+	IPRestricted       bool
+	CheckedAt          time.Time
+}
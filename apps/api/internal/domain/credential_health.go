@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// Credential health statuses, set by the periodic revalidation.
+const (
+	CredentialHealthUnknown = "UNKNOWN"
+	CredentialHealthHealthy = "HEALTHY"
+	CredentialHealthInvalid = "INVALID"
+)
+
+// CredentialHealth is the last revalidation result of a stored key. LastError can be set while the
+// status stays HEALTHY: transient failures (outages, rate limits) are recorded but change nothing.
+type CredentialHealth struct {
+	Status    string
+	CheckedAt *time.Time
+	LastError string
+}
+
+// CredentialHealthCheckTarget is a stored key due for revalidation, still encrypted.
+type CredentialHealthCheckTarget struct {
+	Identifier      int64
+	UserIdentifier  int64
+	EnvironmentName string
+	APIBaseURL      string
+	APIKey          string
+	APISecret       string
+	HealthStatus    string
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+const (
+	DigestFrequencyDaily  = "DAILY"
+	DigestFrequencyWeekly = "WEEKLY"
+)
+
+// DigestSubscription is a user's opt-in for the scheduled portfolio digest email.
+type DigestSubscription struct {
+	UserIdentifier int64
+	Frequency      string // DigestFrequencyDaily or DigestFrequencyWeekly
+	LocalHour      int    // 0-23 in TimeZone
+	Weekday        int    // 0 = Sunday; only used for weekly digests
+	TimeZone       string // IANA name, e.g. America/Sao_Paulo
+	Locale         string // pt | en | es
+	IsEnabled      bool
+	LastSentAt     *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
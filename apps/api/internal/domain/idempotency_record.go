@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of the first request sent with an Idempotency-Key.
+// ResponseStatus is zero while that request is still running.
+type IdempotencyRecord struct {
+	UserIdentifier     int64
+	Key                string
+	RequestFingerprint string
+	ResponseStatus     int
+	ResponseLocation   string
+	ResponseBody       []byte
+	CreatedAt          time.Time
+	ExpiresAt          time.Time
+}
+
+// IsCompleted reports whether the first request has finished and its response can be replayed.
+func (record *IdempotencyRecord) IsCompleted() bool {
+	return record.ResponseStatus != 0
+}
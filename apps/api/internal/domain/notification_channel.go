@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Notification channel types a user can configure.
+const (
+	NotificationChannelEmail    = "EMAIL"
+	NotificationChannelTelegram = "TELEGRAM"
+	NotificationChannelDiscord  = "DISCORD"
+	NotificationChannelWebhook  = "WEBHOOK"
+)
+
+// NotificationChannel is one outbound destination a user configured. Configuration is the channel's
+// settings (bot token, webhook URL, ...) as an ENCRYPTED JSON document; Label is a masked hint that
+// is safe to show in the UI.
+type NotificationChannel struct {
+	Identifier     int64
+	UserIdentifier int64
+	ChannelType    string
+	Label          string
+	Configuration  string
+	IsEnabled      bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// NotificationDelivery is one attempt to push a message through a channel (the delivery log).
+type NotificationDelivery struct {
+	Identifier        int64
+	UserIdentifier    int64
+	ChannelIdentifier *int64 // nil once the channel has been removed
+	ChannelType       string
+	EventType         string
+	Subject           string
+	Success           bool
+	ErrorMessage      *string
+	CreatedAt         time.Time
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Outbox receipt states, per subscriber.
+const (
+	OutboxReceiptPending = "PENDING"
+	OutboxReceiptDone    = "DONE"
+	OutboxReceiptDead    = "DEAD"
+)
+
+// OutboxEvent is a domain event stored in the transactional outbox. Payload is the JSON document of
+// the typed event; AttemptCount is how often the reading subscriber already failed to handle it.
+type OutboxEvent struct {
+	Identifier     int64
+	UserIdentifier int64
+	EventType      string
+	Payload        string
+	CreatedAt      time.Time
+	AttemptCount   int
+}
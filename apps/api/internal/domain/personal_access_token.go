@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Personal access token scopes.
+const (
+	TokenScopeReadOperations = "read:operations"
+	TokenScopeTrade          = "trade"
+	TokenScopeRobotsWrite    = "robots:write"
+	TokenScopeMetricsRead    = "metrics:read" // /metrics, for admins' tokens only
+)
+
+// PersonalAccessTokenScopes lists every scope a token can be granted.
+var PersonalAccessTokenScopes = []string{
+	TokenScopeReadOperations,
+	TokenScopeTrade,
+	TokenScopeRobotsWrite,
+	TokenScopeMetricsRead,
+}
+
+// PersonalAccessToken lets scripts call the API with an `Authorization: Bearer` header. Only a hash of
+// the raw token is persisted; the raw value is shown to the user once, when the token is created.
+type PersonalAccessToken struct {
+	Identifier     int64
+	UserIdentifier int64
+	Name           string
+	TokenHash      string
+	TokenPrefix    string // first characters of the raw token, to tell tokens apart in lists
+	Scopes         []string
+	ExpiresAt      *time.Time // nil = never expires
+	LastUsedAt     *time.Time
+	LastUsedIP     string
+	RevokedAt      *time.Time
+	CreatedAt      time.Time
+}
+
+// HasScope reports whether the token was granted scope.
+func (token PersonalAccessToken) HasScope(scope string) bool {
+	for _, grantedScope := range token.Scopes {
+		if grantedScope == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUsable reports whether the token is neither revoked nor expired at moment.
+func (token PersonalAccessToken) IsUsable(moment time.Time) bool {
+	if token.RevokedAt != nil {
+		return false
+	}
+	return token.ExpiresAt == nil || moment.Before(*token.ExpiresAt)
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Withdrawal key policies: what happens when a user saves a Binance key that can withdraw funds.
+const (
+	WithdrawalKeyPolicyRefuse = "REFUSE"
+	WithdrawalKeyPolicyFlag   = "FLAG"
+)
+
+// PlatformPolicy holds the platform-wide security settings admins control.
+type PlatformPolicy struct {
+	WithdrawalKeyPolicy string
+	// RequireTwoFactorForLiveTrading blocks turning live trading on until the user has enabled 2FA.
+	RequireTwoFactorForLiveTrading bool
+	UpdatedByUserIdentifier        *int64
+	UpdatedAt                      time.Time
+}
@@ -0,0 +1,10 @@
+package domain
+
+// StoredCiphertext is one row holding keyring-encrypted values, as the key-rotation job sees it: the
+// encrypted values only, never decrypted outside the cipher. Values follow the table's encrypted
+// columns in order.
+type StoredCiphertext struct {
+	Table      string
+	Identifier int64
+	Values     []string
+}
@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// Trade event types: the position-changing transitions of the automation that a user can opt in to
+// be notified about.
+const (
+	TradeEventTakeProfitFilled    = "TAKE_PROFIT_FILLED"
+	TradeEventStopLossTriggered   = "STOP_LOSS_TRIGGERED"
+	TradeEventTakeProfitExpired   = "TAKE_PROFIT_EXPIRED"
+	TradeEventDailyPurchaseFailed = "DAILY_PURCHASE_FAILED"
+)
+
+// TradeEventCredentialInvalid is raised when the periodic health check finds that Binance rejects a
+// stored key. It is a security alert: it is always sent and has no opt-in preference, so it is not
+// part of TradeEventTypes.
+const TradeEventCredentialInvalid = "CREDENTIAL_INVALID"
+
+// TradeEventTypes lists every trade event type, in the order the preferences UI shows them.
+var TradeEventTypes = []string{
+	TradeEventTakeProfitFilled,
+	TradeEventStopLossTriggered,
+	TradeEventTakeProfitExpired,
+	TradeEventDailyPurchaseFailed,
+}
+
+// IsTradeEventType reports whether eventType is a known trade event type.
+func IsTradeEventType(eventType string) bool {
+	for _, knownType := range TradeEventTypes {
+		if knownType == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// TradeEvent describes one automation transition for a user's position or robot. Price and PnL
+// fields are zero/nil when they do not apply (e.g. a failed daily purchase has no fill).
+type TradeEvent struct {
+	EventType           string
+	UserIdentifier      int64
+	RobotIdentifier     int64 // 0 when no robot trades the symbol any more
+	RobotName           string
+	TradingPairSymbol   string
+	BinanceEnvironment  string
+	OperationIdentifier int64 // 0 for events that are not tied to an operation
+	Quantity            float64
+	PurchasePrice       float64
+	FillPrice           float64
+	QuoteAmount         float64  // the intended spend, for daily purchases
+	RealizedProfit      *float64 // in the quote asset, before fees; nil when nothing was sold
+	ErrorMessage        string
+	PausedRobotNames    []string // robots paused because of the event, for CREDENTIAL_INVALID
+	OccurredAt          time.Time
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// UserTwoFactor is a user's TOTP enrollment. The secret stays encrypted; EnabledAt is nil while the
+// enrollment waits for its first code.
+type UserTwoFactor struct {
+	UserIdentifier   int64
+	SecretCiphertext string
+	EnabledAt        *time.Time
+	LastUsedTimeStep int64
+}
+
+// IsEnabled reports whether logins and sensitive actions require the second factor.
+func (twoFactor *UserTwoFactor) IsEnabled() bool {
+	return twoFactor != nil && twoFactor.EnabledAt != nil
+}
@@ -13,4 +13,14 @@ type UserSession struct {
 	LastSeenAt       time.Time
 	UserAgent        string
 	IPAddress        string
+	// Location is the approximate place of IPAddress, resolved at sign-in; empty when unknown.
+	Location string
+	// StepUpExpiresAt is when the last password/2FA confirmation stops covering sensitive actions;
+	// nil when the session never had one.
+	StepUpExpiresAt *time.Time
+}
+
+// HasRecentAuthentication reports whether the session may perform a sensitive action at moment.
+func (session *UserSession) HasRecentAuthentication(moment time.Time) bool {
+	return session.StepUpExpiresAt != nil && moment.Before(*session.StepUpExpiresAt)
 }
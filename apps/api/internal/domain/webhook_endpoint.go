@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// Outgoing webhook event types.
+const (
+	WebhookEventOperationOpened    = "operation.opened"
+	WebhookEventOperationClosed    = "operation.closed"
+	WebhookEventOperationCancelled = "operation.cancelled"
+	WebhookEventExecutionLogged    = "execution.logged"
+	WebhookEventRobotChanged       = "robot.changed"
+)
+
+// WebhookEventTypes lists every outgoing webhook event type.
+var WebhookEventTypes = []string{
+	WebhookEventOperationOpened,
+	WebhookEventOperationClosed,
+	WebhookEventOperationCancelled,
+	WebhookEventExecutionLogged,
+	WebhookEventRobotChanged,
+}
+
+// Webhook delivery states.
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+// WebhookEndpoint is a user-registered URL that receives signed event payloads.
+type WebhookEndpoint struct {
+	Identifier     int64
+	UserIdentifier int64
+	URL            string
+	Description    string
+	SigningSecret  string   // encrypted at rest
+	EventTypes     []string // empty = all events
+	IsEnabled      bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Subscribes reports whether the endpoint wants events of eventType.
+func (endpoint WebhookEndpoint) Subscribes(eventType string) bool {
+	if len(endpoint.EventTypes) == 0 {
+		return true
+	}
+	for _, subscribedType := range endpoint.EventTypes {
+		if subscribedType == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one queued or completed delivery of an event to an endpoint.
+type WebhookDelivery struct {
+	Identifier         int64
+	EndpointIdentifier int64
+	UserIdentifier     int64
+	EventIdentifier    string
+	EventType          string
+	Payload            string
+	Status             string
+	AttemptCount       int
+	NextAttemptAt      time.Time
+	LastStatusCode     *int
+	LastError          *string
+	CreatedAt          time.Time
+	DeliveredAt        *time.Time
+}
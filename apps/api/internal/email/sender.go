@@ -12,14 +12,15 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
 	"mime"
 	"mime/quotedprintable"
 	"net"
 	"net/smtp"
-	"os"
 	"strings"
 	"time"
+
+	"coin-alert/internal/logging"
+	"coin-alert/internal/metrics"
 )
 
 // Message is a single email to one recipient, with both a plain-text and an HTML body.
@@ -49,41 +50,65 @@ type SMTPSender struct {
 
 func (sender *SMTPSender) Enabled() bool { return true }
 
+var (
+	emailLogger   = logging.For("email")
+	emailsSent    = metrics.NewCounterVec("coinhub_emails_sent_total", "Emails by send outcome (sent, failed, or skipped when SMTP is not configured).", "outcome")
+	emailDuration = metrics.NewHistogramVec("coinhub_email_send_duration_seconds", "Time spent delivering an email over SMTP.", metrics.DefaultDurationBuckets)
+)
+
 // noopSender is used when SMTP is not configured: it logs instead of sending, so the app still runs.
 type noopSender struct{}
 
 func (noopSender) Enabled() bool { return false }
 func (noopSender) Send(_ context.Context, message Message) error {
-	log.Printf("email (not sent — SMTP not configured): to=%s subject=%q", message.To, message.Subject)
+	emailLogger.Info("email not sent: SMTP is not configured", "subject", message.Subject)
+	emailsSent.Inc("skipped")
 	return nil
 }
 
-// NewSenderFromEnv builds an SMTPSender from SMTP_* env vars, or a no-op sender when they are unset.
-func NewSenderFromEnv() Sender {
-	host := os.Getenv("SMTP_HOST")
-	username := os.Getenv("SMTP_USERNAME")
-	password := os.Getenv("SMTP_PASSWORD")
-	if host == "" || username == "" || password == "" {
+// SMTPConfiguration is the SMTP account emails are sent from. Without a host, username and password
+// NewSender returns a sender that only logs.
+type SMTPConfiguration struct {
+	Host        string
+	Port        string
+	Username    string
+	Password    string
+	FromAddress string // defaults to Username
+	FromName    string
+}
+
+// IsConfigured reports whether the SMTP account is complete enough to send.
+func (configuration SMTPConfiguration) IsConfigured() bool {
+	return configuration.Host != "" && configuration.Username != "" && configuration.Password != ""
+}
+
+// NewSender builds an SMTPSender, or a no-op sender when SMTP is not configured.
+func NewSender(configuration SMTPConfiguration) Sender {
+	if !configuration.IsConfigured() {
 		return noopSender{}
 	}
-	port := os.Getenv("SMTP_PORT")
-	if port == "" {
-		port = "587"
-	}
-	fromAddress := os.Getenv("SMTP_FROM")
+	fromAddress := configuration.FromAddress
 	if fromAddress == "" {
-		fromAddress = username
-	}
-	fromName := os.Getenv("SMTP_FROM_NAME")
-	if fromName == "" {
-		fromName = "Coin Hub"
+		fromAddress = configuration.Username
 	}
-	log.Printf("Email sending is enabled (SMTP %s:%s as %s)", host, port, fromAddress)
-	return &SMTPSender{host: host, port: port, username: username, password: password, fromAddress: fromAddress, fromName: fromName}
+	emailLogger.Info("email sending is enabled", "smtp_host", configuration.Host, "smtp_port", configuration.Port)
+	return &SMTPSender{host: configuration.Host, port: configuration.Port, username: configuration.Username, password: configuration.Password, fromAddress: fromAddress, fromName: configuration.FromName}
 }
 
 // Send delivers the message over SMTP with STARTTLS and PLAIN auth.
 func (sender *SMTPSender) Send(sendContext context.Context, message Message) error {
+	startedAt := time.Now()
+	sendError := sender.send(sendContext, message)
+	emailDuration.ObserveSince(startedAt)
+	if sendError != nil {
+		emailsSent.Inc("failed")
+		return sendError
+	}
+	emailsSent.Inc("sent")
+	return nil
+}
+
+func (sender *SMTPSender) send(sendContext context.Context, message Message) error {
 	if message.To == "" {
 		return errors.New("email recipient is empty")
 	}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/logging"
+	"coin-alert/internal/repository"
+)
+
+const (
+	busBatchSize          = 100
+	busHandlerTimeout     = 30 * time.Second
+	busInitialBackoff     = 5 * time.Second
+	busMaximumBackoff     = 10 * time.Minute
+	busMaximumAttempts    = 8
+	busRetention          = 7 * 24 * time.Hour
+	busRetentionFrequency = time.Hour
+)
+
+// Envelope is what a subscriber receives: the typed event plus its outbox metadata. Identifier is
+// stable across redeliveries, so subscribers can use it to deduplicate.
+type Envelope struct {
+	Identifier     int64
+	UserIdentifier int64
+	OccurredAt     time.Time
+	Event          Event
+}
+
+// Handler consumes one event. Returning an error schedules a retry with backoff; a handler must
+// therefore be safe to run more than once for the same event.
+type Handler func(handlerContext context.Context, envelope Envelope) error
+
+type subscription struct {
+	name       string
+	eventTypes []string
+	handler    Handler
+}
+
+// Bus delivers committed outbox events to in-process subscribers, at least once each. Every
+// subscriber keeps its own receipts, so a failing subscriber never holds back the others. A single
+// server instance runs the bus.
+type Bus struct {
+	repository    repository.OutboxRepository
+	subscriptions []subscription
+}
+
+func NewBus(repositoryInstance repository.OutboxRepository) *Bus {
+	return &Bus{repository: repositoryInstance}
+}
+
+// Subscribe registers handler under a stable name (receipts are keyed by it) for the given event
+// types, or for every type when none are given. Call before Start.
+func (bus *Bus) Subscribe(subscriberName string, handler Handler, eventTypes ...string) {
+	bus.subscriptions = append(bus.subscriptions, subscription{name: subscriberName, eventTypes: eventTypes, handler: handler})
+}
+
+// Start polls the outbox every interval until the context is cancelled, and prunes events past the
+// retention window once an hour.
+func (bus *Bus) Start(loopContext context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var lastPrunedAt time.Time
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				for _, subscriber := range bus.subscriptions {
+					bus.dispatch(loopContext, subscriber)
+				}
+				if time.Since(lastPrunedAt) >= busRetentionFrequency {
+					bus.prune(loopContext)
+					lastPrunedAt = time.Now()
+				}
+			}
+		}
+	}()
+}
+
+var eventsLogger = logging.For("events")
+
+func (bus *Bus) dispatch(loopContext context.Context, subscriber subscription) {
+	pendingEvents, listError := bus.repository.ListPendingEvents(loopContext, subscriber.name, subscriber.eventTypes, busBatchSize)
+	if listError != nil {
+		eventsLogger.ErrorContext(loopContext, "could not list pending events", "subscriber", subscriber.name, "error", listError)
+		return
+	}
+	for _, outboxEvent := range pendingEvents {
+		if loopContext.Err() != nil {
+			return
+		}
+		bus.deliver(loopContext, subscriber, outboxEvent)
+	}
+}
+
+func (bus *Bus) deliver(loopContext context.Context, subscriber subscription, outboxEvent domain.OutboxEvent) {
+	event, decodeError := Decode(outboxEvent.EventType, []byte(outboxEvent.Payload))
+	if decodeError != nil {
+		// Retrying cannot fix a payload this build does not understand.
+		bus.recordFailure(loopContext, subscriber, outboxEvent, decodeError, true)
+		return
+	}
+
+	handlerContext, cancel := context.WithTimeout(logging.WithUserID(loopContext, outboxEvent.UserIdentifier), busHandlerTimeout)
+	handleError := subscriber.handler(handlerContext, Envelope{
+		Identifier:     outboxEvent.Identifier,
+		UserIdentifier: outboxEvent.UserIdentifier,
+		OccurredAt:     outboxEvent.CreatedAt,
+		Event:          event,
+	})
+	cancel()
+
+	if handleError != nil {
+		bus.recordFailure(loopContext, subscriber, outboxEvent, handleError, outboxEvent.AttemptCount+1 >= busMaximumAttempts)
+		return
+	}
+	if markError := bus.repository.MarkEventHandled(loopContext, outboxEvent.Identifier, subscriber.name); markError != nil {
+		eventsLogger.ErrorContext(loopContext, "could not mark the event handled", "event_id", outboxEvent.Identifier, "subscriber", subscriber.name, "error", markError)
+	}
+}
+
+func (bus *Bus) recordFailure(loopContext context.Context, subscriber subscription, outboxEvent domain.OutboxEvent, cause error, giveUp bool) {
+	if giveUp {
+		eventsLogger.ErrorContext(loopContext, "subscriber gave up on an event", "subscriber", subscriber.name, "event_id", outboxEvent.Identifier, "event_type", outboxEvent.EventType, "user_id", outboxEvent.UserIdentifier, "error", cause)
+	}
+	nextAttemptAt := time.Now().Add(busBackoff(outboxEvent.AttemptCount + 1))
+	if markError := bus.repository.MarkEventFailed(loopContext, outboxEvent.Identifier, subscriber.name, cause.Error(), nextAttemptAt, giveUp); markError != nil {
+		eventsLogger.ErrorContext(loopContext, "could not record the event failure", "event_id", outboxEvent.Identifier, "subscriber", subscriber.name, "error", markError)
+	}
+}
+
+func (bus *Bus) prune(loopContext context.Context) {
+	deletedCount, deleteError := bus.repository.DeleteEventsCreatedBefore(loopContext, time.Now().Add(-busRetention))
+	if deleteError != nil {
+		eventsLogger.ErrorContext(loopContext, "could not prune old outbox events", "error", deleteError)
+		return
+	}
+	if deletedCount > 0 {
+		eventsLogger.InfoContext(loopContext, "pruned outbox events", "count", deletedCount)
+	}
+}
+
+// busBackoff is 5s, 10s, 20s, ... capped at 10m, for the given (1-based) failed attempt.
+func busBackoff(attemptNumber int) time.Duration {
+	backoff := float64(busInitialBackoff) * math.Pow(2, float64(attemptNumber-1))
+	if backoff > float64(busMaximumBackoff) {
+		return busMaximumBackoff
+	}
+	return time.Duration(backoff)
+}
@@ -0,0 +1,261 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+type memoryReceipt struct {
+	status        string
+	attemptCount  int
+	nextAttemptAt time.Time
+	lastError     string
+}
+
+type receiptKey struct {
+	eventIdentifier int64
+	subscriber      string
+}
+
+// memoryOutboxRepository is an in-memory repository.OutboxRepository with the same receipt rules as
+// the Postgres one.
+type memoryOutboxRepository struct {
+	mutex    sync.Mutex
+	events   []domain.OutboxEvent
+	receipts map[receiptKey]*memoryReceipt
+}
+
+func newMemoryOutboxRepository() *memoryOutboxRepository {
+	return &memoryOutboxRepository{receipts: map[receiptKey]*memoryReceipt{}}
+}
+
+func (repository *memoryOutboxRepository) AppendEvent(_ context.Context, event domain.OutboxEvent) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	event.Identifier = int64(len(repository.events) + 1)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	repository.events = append(repository.events, event)
+	return event.Identifier, nil
+}
+
+func (repository *memoryOutboxRepository) ListPendingEvents(_ context.Context, subscriber string, eventTypes []string, limit int) ([]domain.OutboxEvent, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var pending []domain.OutboxEvent
+	for _, event := range repository.events {
+		if len(pending) == limit {
+			break
+		}
+		if len(eventTypes) > 0 && !containsEventType(eventTypes, event.EventType) {
+			continue
+		}
+		receipt := repository.receipts[receiptKey{event.Identifier, subscriber}]
+		if receipt != nil && (receipt.status != domain.OutboxReceiptPending || receipt.nextAttemptAt.After(time.Now())) {
+			continue
+		}
+		if receipt != nil {
+			event.AttemptCount = receipt.attemptCount
+		}
+		pending = append(pending, event)
+	}
+	return pending, nil
+}
+
+func containsEventType(eventTypes []string, eventType string) bool {
+	for _, candidate := range eventTypes {
+		if candidate == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (repository *memoryOutboxRepository) receipt(eventIdentifier int64, subscriber string) *memoryReceipt {
+	key := receiptKey{eventIdentifier, subscriber}
+	if repository.receipts[key] == nil {
+		repository.receipts[key] = &memoryReceipt{}
+	}
+	return repository.receipts[key]
+}
+
+func (repository *memoryOutboxRepository) MarkEventHandled(_ context.Context, eventIdentifier int64, subscriber string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	receipt := repository.receipt(eventIdentifier, subscriber)
+	receipt.status = domain.OutboxReceiptDone
+	receipt.attemptCount++
+	receipt.lastError = ""
+	return nil
+}
+
+func (repository *memoryOutboxRepository) MarkEventFailed(_ context.Context, eventIdentifier int64, subscriber string, errorMessage string, nextAttemptAt time.Time, giveUp bool) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	receipt := repository.receipt(eventIdentifier, subscriber)
+	receipt.status = domain.OutboxReceiptPending
+	if giveUp {
+		receipt.status = domain.OutboxReceiptDead
+	}
+	receipt.attemptCount++
+	receipt.nextAttemptAt = nextAttemptAt
+	receipt.lastError = errorMessage
+	return nil
+}
+
+func (repository *memoryOutboxRepository) DeleteEventsCreatedBefore(_ context.Context, cutoff time.Time) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var kept []domain.OutboxEvent
+	for _, event := range repository.events {
+		if !event.CreatedAt.Before(cutoff) {
+			kept = append(kept, event)
+		}
+	}
+	deletedCount := int64(len(repository.events) - len(kept))
+	repository.events = kept
+	return deletedCount, nil
+}
+
+// makeDue moves every pending retry of subscriber to now, as if the backoff had passed.
+func (repository *memoryOutboxRepository) makeDue(subscriber string) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for key, receipt := range repository.receipts {
+		if key.subscriber == subscriber {
+			receipt.nextAttemptAt = time.Now()
+		}
+	}
+}
+
+func publishExecution(t *testing.T, outboxRepository *memoryOutboxRepository, executionIdentifier int64) {
+	t.Helper()
+	publishError := NewOutbox(outboxRepository).Publish(context.Background(), 7, ExecutionLogged{Execution: domain.TradingOperationExecution{Identifier: executionIdentifier}})
+	if publishError != nil {
+		t.Fatal(publishError)
+	}
+}
+
+func TestBusBackoff(t *testing.T) {
+	cases := []struct {
+		attemptNumber int
+		expected      time.Duration
+	}{
+		{attemptNumber: 1, expected: 5 * time.Second},
+		{attemptNumber: 2, expected: 10 * time.Second},
+		{attemptNumber: 3, expected: 20 * time.Second},
+		{attemptNumber: 7, expected: 320 * time.Second},
+		{attemptNumber: 8, expected: busMaximumBackoff},
+		{attemptNumber: 30, expected: busMaximumBackoff},
+	}
+	for _, testCase := range cases {
+		if backoff := busBackoff(testCase.attemptNumber); backoff != testCase.expected {
+			t.Errorf("attempt %d: backoff %s, expected %s", testCase.attemptNumber, backoff, testCase.expected)
+		}
+	}
+}
+
+// TestBusRetriesAFailingSubscriberOnItsOwn checks that a failed delivery waits for its backoff, is
+// retried with the same event identifier, and that a healthy subscriber gets every event exactly once.
+func TestBusRetriesAFailingSubscriberOnItsOwn(t *testing.T) {
+	outboxRepository := newMemoryOutboxRepository()
+	publishExecution(t, outboxRepository, 11)
+	publishExecution(t, outboxRepository, 12)
+
+	bus := NewBus(outboxRepository)
+	var flakyDeliveries []int64
+	bus.Subscribe("flaky", func(_ context.Context, envelope Envelope) error {
+		flakyDeliveries = append(flakyDeliveries, envelope.Identifier)
+		if len(flakyDeliveries) == 1 {
+			return errors.New("endpoint unavailable")
+		}
+		return nil
+	}, TypeExecutionLogged)
+	healthyDeliveries := map[int64]int{}
+	bus.Subscribe("healthy", func(_ context.Context, envelope Envelope) error {
+		if _, isExecution := envelope.Event.(ExecutionLogged); !isExecution {
+			t.Errorf("unexpected event %T", envelope.Event)
+		}
+		healthyDeliveries[envelope.Identifier]++
+		return nil
+	})
+	runOnce := func() {
+		for _, subscriber := range bus.subscriptions {
+			bus.dispatch(context.Background(), subscriber)
+		}
+	}
+
+	runOnce()
+	failedReceipt := outboxRepository.receipts[receiptKey{1, "flaky"}]
+	if failedReceipt == nil || failedReceipt.status != domain.OutboxReceiptPending || failedReceipt.lastError != "endpoint unavailable" {
+		t.Fatalf("expected a pending retry for event 1, got %+v", failedReceipt)
+	}
+	if wait := time.Until(failedReceipt.nextAttemptAt); wait < 4*time.Second || wait > busInitialBackoff {
+		t.Errorf("expected the first retry about %s later, got %s", busInitialBackoff, wait)
+	}
+
+	runOnce() // the retry is not due yet
+	if len(flakyDeliveries) != 2 {
+		t.Fatalf("expected no early retry, got deliveries %v", flakyDeliveries)
+	}
+	outboxRepository.makeDue("flaky")
+	runOnce()
+	runOnce()
+
+	if len(flakyDeliveries) != 3 || flakyDeliveries[2] != 1 {
+		t.Errorf("expected event 1 to be retried once, got deliveries %v", flakyDeliveries)
+	}
+	if receipt := outboxRepository.receipts[receiptKey{1, "flaky"}]; receipt.status != domain.OutboxReceiptDone || receipt.attemptCount != 2 {
+		t.Errorf("expected event 1 done after two attempts, got %+v", receipt)
+	}
+	if len(healthyDeliveries) != 2 || healthyDeliveries[1] != 1 || healthyDeliveries[2] != 1 {
+		t.Errorf("expected the healthy subscriber to get each event once, got %v", healthyDeliveries)
+	}
+}
+
+func TestBusGivesUpAfterTheMaximumAttempts(t *testing.T) {
+	outboxRepository := newMemoryOutboxRepository()
+	publishExecution(t, outboxRepository, 11)
+	_, _ = outboxRepository.AppendEvent(context.Background(), domain.OutboxEvent{UserIdentifier: 7, EventType: "execution.renamed", Payload: "{}"})
+
+	bus := NewBus(outboxRepository)
+	deliveries := 0
+	bus.Subscribe("broken", func(context.Context, Envelope) error {
+		deliveries++
+		return errors.New("always failing")
+	})
+	for round := 0; round < busMaximumAttempts+3; round++ {
+		bus.dispatch(context.Background(), bus.subscriptions[0])
+		outboxRepository.makeDue("broken")
+	}
+
+	if deliveries != busMaximumAttempts {
+		t.Errorf("expected %d deliveries, got %d", busMaximumAttempts, deliveries)
+	}
+	if receipt := outboxRepository.receipts[receiptKey{1, "broken"}]; receipt.status != domain.OutboxReceiptDead || receipt.attemptCount != busMaximumAttempts {
+		t.Errorf("expected the event to be dead after %d attempts, got %+v", busMaximumAttempts, receipt)
+	}
+	// An event this build cannot decode is given up on at once, without calling the handler.
+	if receipt := outboxRepository.receipts[receiptKey{2, "broken"}]; receipt.status != domain.OutboxReceiptDead || receipt.attemptCount != 1 {
+		t.Errorf("expected the unknown event to be dead after one attempt, got %+v", receipt)
+	}
+}
+
+func TestBusPrunesEventsPastTheRetention(t *testing.T) {
+	outboxRepository := newMemoryOutboxRepository()
+	for _, age := range []time.Duration{busRetention + time.Hour, busRetention - time.Hour, time.Minute} {
+		_, _ = outboxRepository.AppendEvent(context.Background(), domain.OutboxEvent{EventType: TypeExecutionLogged, Payload: "{}", CreatedAt: time.Now().Add(-age)})
+	}
+
+	NewBus(outboxRepository).prune(context.Background())
+
+	if len(outboxRepository.events) != 2 || outboxRepository.events[0].Identifier != 2 {
+		t.Errorf("expected only the event older than %s to be pruned, got %+v", busRetention, outboxRepository.events)
+	}
+}
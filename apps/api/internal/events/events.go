@@ -0,0 +1,112 @@
+// Package events is the internal domain event bus. Trading state changes publish typed events into
+// the transactional outbox in the same database transaction as the change itself; the Bus then hands
+// every committed event to the in-process subscribers (webhooks, notifications) at least once.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"coin-alert/internal/domain"
+)
+
+// Event types. They double as the outbox event_type column.
+const (
+	TypeOperationOpened    = "operation.opened"
+	TypeOperationClosed    = "operation.closed"
+	TypeOperationCancelled = "operation.cancelled"
+	TypeExecutionLogged    = "execution.logged"
+	TypeRobotChanged       = "robot.changed"
+	TypeTradeEvent         = "trade.event"
+)
+
+// Robot change actions carried by RobotChanged.
+const (
+	RobotActionCreated = "created"
+	RobotActionUpdated = "updated"
+	RobotActionDeleted = "deleted"
+)
+
+// Event is a typed domain event.
+type Event interface {
+	EventType() string
+}
+
+// OperationOpened is published when a buy opened a new position.
+type OperationOpened struct {
+	Operation domain.TradingOperation
+}
+
+// OperationClosed is published when a position was sold (take-profit, stop-loss or manual close).
+type OperationClosed struct {
+	Operation domain.TradingOperation
+}
+
+// OperationCancelled is published when the take-profit was cancelled outside the app and the
+// position was released.
+type OperationCancelled struct {
+	Operation domain.TradingOperation
+}
+
+// ExecutionLogged is published for every execution history row; Execution.Identifier is set.
+type ExecutionLogged struct {
+	Execution domain.TradingOperationExecution
+}
+
+// RobotChanged is published when a robot was created, updated or deleted. A deleted robot only
+// carries its identifier.
+type RobotChanged struct {
+	Action string
+	Robot  domain.TradingRobot
+}
+
+// TradeEventRaised carries the automation's user-facing trade events (fills, stop-loss, expiries,
+// failed daily buys) to the notification subscriber.
+type TradeEventRaised struct {
+	TradeEvent domain.TradeEvent
+}
+
+func (OperationOpened) EventType() string    { return TypeOperationOpened }
+func (OperationClosed) EventType() string    { return TypeOperationClosed }
+func (OperationCancelled) EventType() string { return TypeOperationCancelled }
+func (ExecutionLogged) EventType() string    { return TypeExecutionLogged }
+func (RobotChanged) EventType() string       { return TypeRobotChanged }
+func (TradeEventRaised) EventType() string   { return TypeTradeEvent }
+
+// Decode rebuilds the typed event stored in the outbox.
+func Decode(eventType string, payload []byte) (Event, error) {
+	var event Event
+	var decodeError error
+	switch eventType {
+	case TypeOperationOpened:
+		var typed OperationOpened
+		decodeError = json.Unmarshal(payload, &typed)
+		event = typed
+	case TypeOperationClosed:
+		var typed OperationClosed
+		decodeError = json.Unmarshal(payload, &typed)
+		event = typed
+	case TypeOperationCancelled:
+		var typed OperationCancelled
+		decodeError = json.Unmarshal(payload, &typed)
+		event = typed
+	case TypeExecutionLogged:
+		var typed ExecutionLogged
+		decodeError = json.Unmarshal(payload, &typed)
+		event = typed
+	case TypeRobotChanged:
+		var typed RobotChanged
+		decodeError = json.Unmarshal(payload, &typed)
+		event = typed
+	case TypeTradeEvent:
+		var typed TradeEventRaised
+		decodeError = json.Unmarshal(payload, &typed)
+		event = typed
+	default:
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+	if decodeError != nil {
+		return nil, fmt.Errorf("decode %s: %w", eventType, decodeError)
+	}
+	return event, nil
+}
@@ -0,0 +1,37 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// Publisher records domain events. Called with a transaction context (see
+// repository.TransactionRunner) the event commits or rolls back together with the state change.
+type Publisher interface {
+	Publish(operationContext context.Context, userIdentifier int64, event Event) error
+}
+
+// Outbox is the Publisher backed by the outbox table.
+type Outbox struct {
+	repository repository.OutboxRepository
+}
+
+func NewOutbox(repositoryInstance repository.OutboxRepository) *Outbox {
+	return &Outbox{repository: repositoryInstance}
+}
+
+func (outbox *Outbox) Publish(operationContext context.Context, userIdentifier int64, event Event) error {
+	payload, encodeError := json.Marshal(event)
+	if encodeError != nil {
+		return encodeError
+	}
+	_, appendError := outbox.repository.AppendEvent(operationContext, domain.OutboxEvent{
+		UserIdentifier: userIdentifier,
+		EventType:      event.EventType(),
+		Payload:        string(payload),
+	})
+	return appendError
+}
@@ -0,0 +1,161 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/service"
+)
+
+// AccessTokensHandler lets the signed-in user create, list and revoke personal access tokens. These
+// endpoints only accept the session cookie: a token can never mint or revoke tokens.
+type AccessTokensHandler struct {
+	sessionService *service.SessionService
+	tokenService   *service.PersonalAccessTokenService
+}
+
+func NewAccessTokensHandler(sessionService *service.SessionService, tokenService *service.PersonalAccessTokenService) *AccessTokensHandler {
+	return &AccessTokensHandler{
+		sessionService: sessionService,
+		tokenService:   tokenService,
+	}
+}
+
+func (handler *AccessTokensHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("/api/v1/account/access-tokens", handler.handleTokens)
+	router.HandleFunc("/api/v1/account/access-tokens/revoke", handler.handleRevoke)
+}
+
+type accessTokenPayload struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Token       string     `json:"token,omitempty"` // only on creation
+}
+
+type accessTokenInputPayload struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 = never
+}
+
+func (handler *AccessTokensHandler) handleTokens(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+		defer cancel()
+		tokens, listError := handler.tokenService.ListTokens(operationContext, userIdentifier)
+		if listError != nil {
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load your access tokens.")
+			return
+		}
+		payloads := make([]accessTokenPayload, 0, len(tokens))
+		for _, token := range tokens {
+			payloads = append(payloads, toAccessTokenPayload(token))
+		}
+		writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+			"tokens": payloads,
+			"scopes": domain.PersonalAccessTokenScopes,
+		})
+
+	case http.MethodPost:
+		// A token can trade on the user's behalf, so minting one needs a recent password/2FA check.
+		if !enforceStepUp(responseWriter, request, handler.sessionService) {
+			return
+		}
+		var payload accessTokenInputPayload
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 6*time.Second)
+		defer cancel()
+		token, rawToken, createError := handler.tokenService.CreateToken(operationContext, userIdentifier, payload.Name, payload.Scopes, payload.ExpiresInDays)
+		if createError != nil {
+			writeAccessTokenError(responseWriter, createError)
+			return
+		}
+		tokenPayload := toAccessTokenPayload(*token)
+		tokenPayload.Token = rawToken
+		writeJSON(responseWriter, http.StatusOK, tokenPayload)
+
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (handler *AccessTokensHandler) handleRevoke(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A token id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 6*time.Second)
+	defer cancel()
+	if revokeError := handler.tokenService.RevokeToken(operationContext, userIdentifier, payload.ID); revokeError != nil {
+		writeAccessTokenError(responseWriter, revokeError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Access token revoked."})
+}
+
+func writeAccessTokenError(responseWriter http.ResponseWriter, tokenError error) {
+	switch {
+	case errors.Is(tokenError, repository.ErrPersonalAccessTokenNotFound):
+		writeJSONError(responseWriter, http.StatusNotFound, "Access token not found.")
+	case errors.Is(tokenError, service.ErrPersonalAccessTokenLimitReached):
+		writeJSONError(responseWriter, http.StatusForbidden, tokenError.Error())
+	case errors.Is(tokenError, service.ErrPersonalAccessTokenNameInvalid),
+		errors.Is(tokenError, service.ErrPersonalAccessTokenScopeInvalid),
+		errors.Is(tokenError, service.ErrPersonalAccessTokenExpiry):
+		writeJSONError(responseWriter, http.StatusBadRequest, tokenError.Error())
+	default:
+		httpLogger.Error("access token request failed", "error", tokenError)
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the access token.")
+	}
+}
+
+func toAccessTokenPayload(token domain.PersonalAccessToken) accessTokenPayload {
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return accessTokenPayload{
+		ID:          token.Identifier,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      scopes,
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		LastUsedIP:  token.LastUsedIP,
+		RevokedAt:   token.RevokedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
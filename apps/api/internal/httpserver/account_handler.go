@@ -18,45 +18,32 @@ type AccountHandler struct {
 	sessionService *service.SessionService
 	cookieName     string
 	secureCookies  bool
+	stepUpService  *service.StepUpService
 }
 
-func NewAccountHandler(authService *service.AuthService, sessionService *service.SessionService, cookieName string, secureCookies bool) *AccountHandler {
+func NewAccountHandler(authService *service.AuthService, sessionService *service.SessionService, cookieName string, secureCookies bool, stepUpService *service.StepUpService) *AccountHandler {
 	return &AccountHandler{
 		authService:    authService,
 		sessionService: sessionService,
 		cookieName:     cookieName,
 		secureCookies:  secureCookies,
+		stepUpService:  stepUpService,
 	}
 }
 
 func (handler *AccountHandler) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("/api/v1/account/profile", handler.handleProfile)
 	router.HandleFunc("/api/v1/account/password", handler.handlePassword)
+	router.HandleFunc("/api/v1/account/step-up", handler.handleStepUp)
 	router.HandleFunc("/api/v1/account", handler.handleDeleteAccount)
 }
 
-func (handler *AccountHandler) requireUser(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
-	sessionCookie, cookieError := request.Cookie(handler.cookieName)
-	if cookieError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
-	defer cancel()
-	userIdentifier, resolveError := handler.sessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
-	if resolveError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	return userIdentifier, true
-}
-
 func (handler *AccountHandler) handleProfile(responseWriter http.ResponseWriter, request *http.Request) {
 	if request.Method != http.MethodPut {
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -88,10 +75,13 @@ func (handler *AccountHandler) handlePassword(responseWriter http.ResponseWriter
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
+	if !enforceStepUp(responseWriter, request, handler.sessionService) {
+		return
+	}
 
 	var payload struct {
 		CurrentPassword string `json:"current_password"`
@@ -102,7 +92,7 @@ func (handler *AccountHandler) handlePassword(responseWriter http.ResponseWriter
 		return
 	}
 
-	operationContext, cancel := context.WithTimeout(request.Context(), 8*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 8*time.Second)
 	defer cancel()
 	changeError := handler.authService.SetOrChangePassword(operationContext, userIdentifier, payload.CurrentPassword, payload.NewPassword)
 	if changeError != nil {
@@ -124,10 +114,13 @@ func (handler *AccountHandler) handleDeleteAccount(responseWriter http.ResponseW
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
+	if !enforceStepUp(responseWriter, request, handler.sessionService) {
+		return
+	}
 
 	var payload struct {
 		Password string `json:"password"`
@@ -164,6 +157,65 @@ func (handler *AccountHandler) handleDeleteAccount(responseWriter http.ResponseW
 	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Account deleted."})
 }
 
+// handleStepUp reports whether the session is stepped up and which confirmation it takes (GET), or
+// confirms the password / authentication code for this session (POST).
+func (handler *AccountHandler) handleStepUp(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	sessionToken := PrincipalFromContext(request.Context()).SessionToken
+
+	switch request.Method {
+	case http.MethodGet:
+		operationContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		defer cancel()
+		method, methodError := handler.stepUpService.RequiredMethod(operationContext, userIdentifier)
+		if methodError != nil {
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load your session.")
+			return
+		}
+		recentError := handler.sessionService.RequireRecentAuthentication(operationContext, sessionToken)
+		writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+			"active": recentError == nil,
+			"method": method,
+		})
+
+	case http.MethodPost:
+		var payload struct {
+			Password string `json:"password"`
+			Code     string `json:"code"`
+		}
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		operationContext, cancel := context.WithTimeout(request.Context(), 8*time.Second)
+		defer cancel()
+		expiresAt, confirmError := handler.stepUpService.Confirm(operationContext, sessionToken, userIdentifier, payload.Password, payload.Code)
+		if confirmError != nil {
+			var rateLimitedError *service.RateLimitedError
+			switch {
+			case errors.As(confirmError, &rateLimitedError):
+				writeRateLimited(responseWriter, rateLimitedError)
+			case errors.Is(confirmError, service.ErrIncorrectPassword):
+				writeJSONError(responseWriter, http.StatusBadRequest, "Your password is incorrect.")
+			case errors.Is(confirmError, service.ErrInvalidTwoFactorCode):
+				writeJSONErrorCode(responseWriter, http.StatusBadRequest, confirmError.Error(), "invalid_two_factor_code")
+			case errors.Is(confirmError, service.ErrStepUpUnavailable):
+				writeJSONErrorCode(responseWriter, http.StatusConflict, confirmError.Error(), "step_up_sign_in")
+			default:
+				writeJSONError(responseWriter, http.StatusInternalServerError, "Could not confirm it is you.")
+			}
+			return
+		}
+		writeJSON(responseWriter, http.StatusOK, map[string]interface{}{"active": true, "expires_at": expiresAt})
+
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (handler *AccountHandler) clearSessionCookie(responseWriter http.ResponseWriter) {
 	http.SetCookie(responseWriter, &http.Cookie{
 		Name:     handler.cookieName,
@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"coin-alert/internal/config"
+	"coin-alert/internal/domain"
+	"coin-alert/internal/service"
+)
+
+// AdminHandler serves the platform-wide settings only admins may change, and the server configuration
+// they may inspect.
+type AdminHandler struct {
+	authService      *service.AuthService
+	policyService    *service.PlatformPolicyService
+	twoFactorService *service.TwoFactorService
+	configuration    *config.ApplicationConfiguration
+}
+
+func NewAdminHandler(authService *service.AuthService, policyService *service.PlatformPolicyService, twoFactorService *service.TwoFactorService, configuration *config.ApplicationConfiguration) *AdminHandler {
+	return &AdminHandler{
+		authService:      authService,
+		policyService:    policyService,
+		twoFactorService: twoFactorService,
+		configuration:    configuration,
+	}
+}
+
+func (handler *AdminHandler) RegisterRoutes(router *http.ServeMux) {
+	const forbiddenMessage = "Platform settings are available to admins only."
+	router.Handle("/api/v1/admin/policy", RequireAdmin(handler.authService, forbiddenMessage, http.HandlerFunc(handler.handlePolicy)))
+	router.Handle("/api/v1/admin/two-factor/reset", RequireAdmin(handler.authService, forbiddenMessage, http.HandlerFunc(handler.handleTwoFactorReset)))
+	router.Handle("GET /api/v1/admin/config", RequireAdmin(handler.authService, "The server configuration is available to admins only.", http.HandlerFunc(handler.handleConfiguration)))
+}
+
+type effectiveSettingPayload struct {
+	Key         string `json:"key"`
+	Environment string `json:"environment_variable"`
+	Value       string `json:"value"`
+	Source      string `json:"source"`
+	Secret      bool   `json:"secret"`
+}
+
+type effectiveConfigurationPayload struct {
+	File     string                    `json:"file,omitempty"`
+	Settings []effectiveSettingPayload `json:"settings"`
+}
+
+// handleConfiguration shows the configuration the server runs with; secret values are redacted.
+func (handler *AdminHandler) handleConfiguration(responseWriter http.ResponseWriter, request *http.Request) {
+	payload := effectiveConfigurationPayload{File: handler.configuration.File, Settings: []effectiveSettingPayload{}}
+	for _, setting := range handler.configuration.EffectiveSettings() {
+		payload.Settings = append(payload.Settings, effectiveSettingPayload{
+			Key:         setting.Key,
+			Environment: setting.Environment,
+			Value:       setting.Value,
+			Source:      setting.Source,
+			Secret:      setting.Secret,
+		})
+	}
+	responseWriter.Header().Set("Cache-Control", "no-store")
+	writeJSON(responseWriter, http.StatusOK, payload)
+}
+
+type platformPolicyPayload struct {
+	WithdrawalKeyPolicy            string     `json:"withdrawal_key_policy"`
+	RequireTwoFactorForLiveTrading bool       `json:"require_two_factor_for_live_trading"`
+	UpdatedByUserID                *int64     `json:"updated_by_user_id,omitempty"`
+	UpdatedAt                      *time.Time `json:"updated_at,omitempty"`
+}
+
+type platformPolicyInputPayload struct {
+	WithdrawalKeyPolicy            *string `json:"withdrawal_key_policy"`
+	RequireTwoFactorForLiveTrading *bool   `json:"require_two_factor_for_live_trading"`
+}
+
+func (handler *AdminHandler) handlePolicy(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authorized := requireSessionUser(responseWriter, request)
+	if !authorized {
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		operationContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		defer cancel()
+		policy, loadError := handler.policyService.GetPolicy(operationContext)
+		if loadError != nil {
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load the platform policy.")
+			return
+		}
+		writeJSON(responseWriter, http.StatusOK, toPlatformPolicyPayload(policy))
+
+	case http.MethodPut:
+		var payload platformPolicyInputPayload
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 5*time.Second)
+		defer cancel()
+		policy, updateError := handler.policyService.UpdatePolicy(operationContext, userIdentifier, service.PlatformPolicyUpdate{
+			WithdrawalKeyPolicy:            payload.WithdrawalKeyPolicy,
+			RequireTwoFactorForLiveTrading: payload.RequireTwoFactorForLiveTrading,
+		})
+		if updateError != nil {
+			if errors.Is(updateError, service.ErrInvalidWithdrawalKeyPolicy) {
+				writeJSONError(responseWriter, http.StatusBadRequest, updateError.Error())
+				return
+			}
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the platform policy.")
+			return
+		}
+		writeJSON(responseWriter, http.StatusOK, toPlatformPolicyPayload(policy))
+
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func toPlatformPolicyPayload(policy domain.PlatformPolicy) platformPolicyPayload {
+	payload := platformPolicyPayload{
+		WithdrawalKeyPolicy:            policy.WithdrawalKeyPolicy,
+		RequireTwoFactorForLiveTrading: policy.RequireTwoFactorForLiveTrading,
+		UpdatedByUserID:                policy.UpdatedByUserIdentifier,
+	}
+	if !policy.UpdatedAt.IsZero() {
+		payload.UpdatedAt = &policy.UpdatedAt
+	}
+	return payload
+}
+
+// handleTwoFactorReset removes a user's 2FA so they can sign in with their password alone and enroll
+// again, for users who lost both their authenticator and their recovery codes.
+func (handler *AdminHandler) handleTwoFactorReset(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	adminUserIdentifier, authorized := requireSessionUser(responseWriter, request)
+	if !authorized {
+		return
+	}
+	var payload struct {
+		UserID int64 `json:"user_id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.UserID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A user_id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, adminUserIdentifier), 5*time.Second)
+	defer cancel()
+	if resetError := handler.twoFactorService.ResetForUser(operationContext, payload.UserID); resetError != nil {
+		if errors.Is(resetError, service.ErrTwoFactorNotEnabled) {
+			writeJSONError(responseWriter, http.StatusNotFound, "That user has no two-factor authentication to reset.")
+			return
+		}
+		httpLogger.ErrorContext(request.Context(), "could not reset two-factor authentication", "target_user_id", payload.UserID, "error", resetError)
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not reset two-factor authentication.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Two-factor authentication reset."})
+}
@@ -18,22 +18,24 @@ import (
 type APIHandler struct {
 	sessionService            *service.SessionService
 	authService               *service.AuthService
-	cookieName                string
 	tradingSettingsRepository repository.UserTradingSettingsRepository
 	credentialService         *service.UserCredentialService
 	testnetBaseURL            string
 	productionBaseURL         string
+	auditRecorder             service.AuditRecorder
+	twoFactorService          *service.TwoFactorService
 }
 
-func NewAPIHandler(sessionService *service.SessionService, authService *service.AuthService, cookieName string, tradingSettingsRepository repository.UserTradingSettingsRepository, credentialService *service.UserCredentialService, testnetBaseURL string, productionBaseURL string) *APIHandler {
+func NewAPIHandler(sessionService *service.SessionService, authService *service.AuthService, tradingSettingsRepository repository.UserTradingSettingsRepository, credentialService *service.UserCredentialService, testnetBaseURL string, productionBaseURL string, auditRecorder service.AuditRecorder, twoFactorService *service.TwoFactorService) *APIHandler {
 	return &APIHandler{
 		sessionService:            sessionService,
 		authService:               authService,
-		cookieName:                cookieName,
 		tradingSettingsRepository: tradingSettingsRepository,
 		credentialService:         credentialService,
 		testnetBaseURL:            testnetBaseURL,
 		productionBaseURL:         productionBaseURL,
+		auditRecorder:             auditRecorder,
+		twoFactorService:          twoFactorService,
 	}
 }
 
@@ -47,22 +49,6 @@ func (handler *APIHandler) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("/api/v1/binance/klines", handler.handleKlines)
 }
 
-func (handler *APIHandler) requireUser(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
-	sessionCookie, cookieError := request.Cookie(handler.cookieName)
-	if cookieError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
-	defer cancel()
-	userIdentifier, resolveError := handler.sessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
-	if resolveError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	return userIdentifier, true
-}
-
 type tradingSettingsPayload struct {
 	TradingPairSymbol            string   `json:"trading_pair_symbol"`
 	CapitalThreshold             float64  `json:"capital_threshold"`
@@ -77,7 +63,7 @@ type tradingSettingsPayload struct {
 }
 
 func (handler *APIHandler) handleSettings(responseWriter http.ResponseWriter, request *http.Request) {
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -100,9 +86,23 @@ func (handler *APIHandler) handleSettings(responseWriter http.ResponseWriter, re
 			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
 			return
 		}
-		operationContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 5*time.Second)
 		defer cancel()
 		environmentName := handler.credentialService.ActiveEnvironmentName(operationContext, userIdentifier)
+		previousSettings, _ := handler.tradingSettingsRepository.GetByUserAndEnvironment(operationContext, userIdentifier, environmentName)
+		if payload.LiveTradingEnabled && (previousSettings == nil || !previousSettings.LiveTradingEnabled) {
+			if !enforceStepUp(responseWriter, request, handler.sessionService) {
+				return
+			}
+			if policyError := handler.twoFactorService.EnsureLiveTradingAllowed(operationContext, userIdentifier); policyError != nil {
+				if errors.Is(policyError, service.ErrTwoFactorRequired) {
+					writeJSONErrorCode(responseWriter, http.StatusForbidden, policyError.Error(), "two_factor_required")
+					return
+				}
+				writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save settings.")
+				return
+			}
+		}
 		updatedSettings := domain.UserTradingSettings{
 			UserIdentifier:               userIdentifier,
 			TradingPairSymbol:            normalizeSymbolOrDefault(payload.TradingPairSymbol),
@@ -121,6 +121,7 @@ func (handler *APIHandler) handleSettings(responseWriter http.ResponseWriter, re
 			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save settings.")
 			return
 		}
+		handler.recordSettingsAudit(operationContext, previousSettings, &updatedSettings)
 		writeJSON(responseWriter, http.StatusOK, toTradingSettingsPayload(&updatedSettings))
 
 	default:
@@ -128,11 +129,88 @@ func (handler *APIHandler) handleSettings(responseWriter http.ResponseWriter, re
 	}
 }
 
+// recordSettingsAudit logs the settings change, plus a separate entry when live trading was switched
+// on or off since that is the one toggle that turns simulated orders into real ones.
+func (handler *APIHandler) recordSettingsAudit(operationContext context.Context, previousSettings *domain.UserTradingSettings, updatedSettings *domain.UserTradingSettings) {
+	if handler.auditRecorder == nil {
+		return
+	}
+	var beforeValue interface{}
+	previousLiveTrading := false
+	if previousSettings != nil {
+		beforeValue = toTradingSettingsPayload(previousSettings)
+		previousLiveTrading = previousSettings.LiveTradingEnabled
+	}
+	handler.auditRecorder.Record(operationContext, service.AuditRecord{
+		UserIdentifier: updatedSettings.UserIdentifier,
+		Action:         domain.AuditActionSettingsUpdated,
+		TargetType:     "trading_settings",
+		Before:         beforeValue,
+		After:          toTradingSettingsPayload(updatedSettings),
+	})
+	if previousLiveTrading != updatedSettings.LiveTradingEnabled {
+		handler.auditRecorder.Record(operationContext, service.AuditRecord{
+			UserIdentifier: updatedSettings.UserIdentifier,
+			Action:         domain.AuditActionLiveTradingToggled,
+			TargetType:     "trading_settings",
+			Before:         map[string]interface{}{"environment": updatedSettings.BinanceEnvironment, "live_trading_enabled": previousLiveTrading},
+			After:          map[string]interface{}{"environment": updatedSettings.BinanceEnvironment, "live_trading_enabled": updatedSettings.LiveTradingEnabled},
+		})
+	}
+}
+
 type credentialStatusPayload struct {
-	HasActiveCredential    bool     `json:"has_active_credential"`
-	ActiveEnvironment      string   `json:"active_environment"`
-	MaskedAPIKey           string   `json:"masked_api_key"`
-	ConfiguredEnvironments []string `json:"configured_environments"`
+	HasActiveCredential    bool                          `json:"has_active_credential"`
+	ActiveEnvironment      string                        `json:"active_environment"`
+	MaskedAPIKey           string                        `json:"masked_api_key"`
+	ConfiguredEnvironments []string                      `json:"configured_environments"`
+	Permissions            *credentialPermissionsPayload `json:"permissions"`
+	PermissionWarnings     []string                      `json:"permission_warnings"`
+	Health                 *credentialHealthPayload      `json:"health"`
+}
+
+type credentialHealthPayload struct {
+	Status    string     `json:"status"`
+	CheckedAt *time.Time `json:"checked_at"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+type credentialPermissionsPayload struct {
+	ReadingEnabled     bool      `json:"reading_enabled"`
+	SpotTradingEnabled bool      `json:"spot_trading_enabled"`
+	WithdrawalsEnabled bool      `json:"withdrawals_enabled"`
+	IPRestricted       bool      `json:"ip_restricted"`
+	CheckedAt          time.Time `json:"checked_at"`
+}
+
+func toCredentialStatusPayload(status service.CredentialStatus) credentialStatusPayload {
+	payload := credentialStatusPayload{
+		HasActiveCredential:    status.HasActiveCredential,
+		ActiveEnvironment:      status.ActiveEnvironment,
+		MaskedAPIKey:           status.MaskedAPIKey,
+		ConfiguredEnvironments: status.ConfiguredEnvironments,
+		PermissionWarnings:     status.PermissionWarnings,
+	}
+	if payload.PermissionWarnings == nil {
+		payload.PermissionWarnings = []string{}
+	}
+	if status.Permissions != nil {
+		payload.Permissions = &credentialPermissionsPayload{
+			ReadingEnabled:     status.Permissions.ReadingEnabled,
+			SpotTradingEnabled: status.Permissions.SpotTradingEnabled,
+			WithdrawalsEnabled: status.Permissions.WithdrawalsEnabled,
+			IPRestricted:       status.Permissions.IPRestricted,
+			CheckedAt:          status.Permissions.CheckedAt,
+		}
+	}
+	if status.HasActiveCredential {
+		payload.Health = &credentialHealthPayload{
+			Status:    status.Health.Status,
+			CheckedAt: status.Health.CheckedAt,
+			LastError: status.Health.LastError,
+		}
+	}
+	return payload
 }
 
 type saveCredentialPayload struct {
@@ -142,7 +220,7 @@ type saveCredentialPayload struct {
 }
 
 func (handler *APIHandler) handleCredentials(responseWriter http.ResponseWriter, request *http.Request) {
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -156,12 +234,7 @@ func (handler *APIHandler) handleCredentials(responseWriter http.ResponseWriter,
 			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load credential status.")
 			return
 		}
-		writeJSON(responseWriter, http.StatusOK, credentialStatusPayload{
-			HasActiveCredential:    status.HasActiveCredential,
-			ActiveEnvironment:      status.ActiveEnvironment,
-			MaskedAPIKey:           status.MaskedAPIKey,
-			ConfiguredEnvironments: status.ConfiguredEnvironments,
-		})
+		writeJSON(responseWriter, http.StatusOK, toCredentialStatusPayload(status))
 
 	case http.MethodPost:
 		var payload saveCredentialPayload
@@ -173,17 +246,28 @@ func (handler *APIHandler) handleCredentials(responseWriter http.ResponseWriter,
 			writeJSONError(responseWriter, http.StatusBadRequest, "API key and secret are required.")
 			return
 		}
-		operationContext, cancel := context.WithTimeout(request.Context(), 12*time.Second)
+		operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 12*time.Second)
 		defer cancel()
 		if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
 			return
 		}
+		if !enforceStepUp(responseWriter, request, handler.sessionService) {
+			return
+		}
 		saveError := handler.credentialService.SaveAndValidate(operationContext, userIdentifier, payload.APIKey, payload.APISecret, payload.Environment)
 		if saveError != nil {
 			if errors.Is(saveError, service.ErrCredentialEncryptionUnavailable) {
 				writeJSONError(responseWriter, http.StatusServiceUnavailable, "Server is not configured to store credentials securely yet.")
 				return
 			}
+			if errors.Is(saveError, service.ErrWithdrawalKeyRefused) {
+				writeJSONErrorCode(responseWriter, http.StatusBadRequest, saveError.Error(), "withdrawal_key_refused")
+				return
+			}
+			if errors.Is(saveError, service.ErrKeyPermissionsUnverified) {
+				writeJSONErrorCode(responseWriter, http.StatusBadRequest, saveError.Error(), "key_permissions_unverified")
+				return
+			}
 			writeJSONError(responseWriter, http.StatusBadRequest, "Binance rejected these credentials: "+saveError.Error())
 			return
 		}
@@ -199,7 +283,7 @@ func (handler *APIHandler) handleActivateEnvironment(responseWriter http.Respons
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -212,11 +296,15 @@ func (handler *APIHandler) handleActivateEnvironment(responseWriter http.Respons
 		return
 	}
 
-	operationContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 5*time.Second)
 	defer cancel()
 	if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
 		return
 	}
+	if domain.NormalizeBinanceEnvironment(payload.Environment) == domain.BinanceEnvironmentProduction &&
+		!enforceStepUp(responseWriter, request, handler.sessionService) {
+		return
+	}
 	if activationError := handler.credentialService.ActivateEnvironment(operationContext, userIdentifier, payload.Environment); activationError != nil {
 		writeJSONError(responseWriter, http.StatusBadRequest, activationError.Error())
 		return
@@ -229,7 +317,7 @@ func (handler *APIHandler) handlePrice(responseWriter http.ResponseWriter, reque
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -256,7 +344,7 @@ func (handler *APIHandler) handleSymbols(responseWriter http.ResponseWriter, req
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -279,7 +367,7 @@ func (handler *APIHandler) handleSymbolFilters(responseWriter http.ResponseWrite
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -313,7 +401,7 @@ func (handler *APIHandler) handleKlines(responseWriter http.ResponseWriter, requ
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Machine-readable error codes shared by every endpoint. Handlers use a more specific code where the
+// client can act on it (e.g. "step_up_required", "email_unverified", "robot_limit_reached").
+const (
+	errorCodeInvalidRequest   = "invalid_request"
+	errorCodeUnauthenticated  = "unauthenticated"
+	errorCodeForbidden        = "forbidden"
+	errorCodeNotFound         = "not_found"
+	errorCodeMethodNotAllowed = "method_not_allowed"
+	errorCodeConflict         = "conflict"
+	errorCodeRateLimited      = "rate_limited"
+	errorCodeInternal         = "internal_error"
+	errorCodeUpstream         = "upstream_error"
+)
+
+// defaultErrorCode is the generic code for an HTTP status, used when a handler gives none.
+func defaultErrorCode(statusCode int) string {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return errorCodeUnauthenticated
+	case statusCode == http.StatusForbidden:
+		return errorCodeForbidden
+	case statusCode == http.StatusNotFound:
+		return errorCodeNotFound
+	case statusCode == http.StatusMethodNotAllowed:
+		return errorCodeMethodNotAllowed
+	case statusCode == http.StatusConflict:
+		return errorCodeConflict
+	case statusCode == http.StatusTooManyRequests:
+		return errorCodeRateLimited
+	case statusCode == http.StatusBadGateway || statusCode == http.StatusGatewayTimeout:
+		return errorCodeUpstream
+	case statusCode >= 500:
+		return errorCodeInternal
+	default:
+		return errorCodeInvalidRequest
+	}
+}
+
+// registerMethodRoutes registers one handler per HTTP method for a /api/v2 resource path, using Go
+// 1.22 method patterns. Any other method gets the JSON error envelope with 405 and an Allow header
+// instead of the mux's plain-text answer.
+func registerMethodRoutes(router *http.ServeMux, path string, handlers map[string]http.HandlerFunc) {
+	allowedMethods := make([]string, 0, len(handlers))
+	for method, handlerFunction := range handlers {
+		router.HandleFunc(method+" "+path, handlerFunction)
+		allowedMethods = append(allowedMethods, method)
+	}
+	sort.Strings(allowedMethods)
+	allowHeader := strings.Join(allowedMethods, ", ")
+	router.HandleFunc(path, func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Allow", allowHeader)
+		writeJSONError(responseWriter, http.StatusMethodNotAllowed, "Method not allowed.")
+	})
+}
+
+// RegisterAPIV2NotFound answers unknown /api/v2 paths with the JSON error envelope.
+func RegisterAPIV2NotFound(router *http.ServeMux) {
+	router.HandleFunc("/api/v2/", func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSONError(responseWriter, http.StatusNotFound, "Not found.")
+	})
+}
+
+// pathIdentifier parses the {id} wildcard of a v2 route, writing 400 when it is not a positive integer.
+func pathIdentifier(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
+	identifier, parseError := strconv.ParseInt(request.PathValue("id"), 10, 64)
+	if parseError != nil || identifier <= 0 {
+		markNotExecuted(responseWriter)
+		writeJSONErrorCode(responseWriter, http.StatusBadRequest, "The id in the path must be a positive integer.", "invalid_id")
+		return 0, false
+	}
+	return identifier, true
+}
@@ -0,0 +1,47 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAPIV2Routing registers the v2 routes (catching pattern conflicts, which panic) and checks that
+// routing failures use the JSON error envelope.
+func TestAPIV2Routing(t *testing.T) {
+	router := http.NewServeMux()
+	NewOperationsHandler(nil, nil, nil).RegisterV2Routes(router)
+	NewRobotsHandler(nil, nil, nil).RegisterV2Routes(router)
+	RegisterAPIV2NotFound(router)
+
+	cases := []struct {
+		method       string
+		path         string
+		expectedCode int
+		expectedKind string
+	}{
+		{method: http.MethodPut, path: "/api/v2/operations/5/close", expectedCode: http.StatusMethodNotAllowed, expectedKind: "method_not_allowed"},
+		{method: http.MethodPost, path: "/api/v2/operations/abc/close", expectedCode: http.StatusBadRequest, expectedKind: "invalid_id"},
+		{method: http.MethodPost, path: "/api/v2/operations/0/take-profit", expectedCode: http.StatusBadRequest, expectedKind: "invalid_id"},
+		{method: http.MethodGet, path: "/api/v2/nothing-here", expectedCode: http.StatusNotFound, expectedKind: "not_found"},
+		{method: http.MethodGet, path: "/api/v2/robots", expectedCode: http.StatusUnauthorized, expectedKind: "unauthenticated"},
+	}
+	for _, testCase := range cases {
+		request := httptest.NewRequest(testCase.method, testCase.path, nil)
+		if testCase.expectedKind == "invalid_id" {
+			request = request.WithContext(context.WithValue(request.Context(), principalContextKey{}, &Principal{UserIdentifier: 1, SessionToken: "session"}))
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var envelope struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &envelope)
+		if recorder.Code != testCase.expectedCode || envelope.Code != testCase.expectedKind || envelope.Error == "" {
+			t.Errorf("%s %s: expected %d %q, got %d %s", testCase.method, testCase.path, testCase.expectedCode, testCase.expectedKind, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/service"
+)
+
+// AuditHandler serves the audit log: a user's own entries and, for admins, every user's.
+type AuditHandler struct {
+	authService  *service.AuthService
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(authService *service.AuthService, auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		authService:  authService,
+		auditService: auditService,
+	}
+}
+
+func (handler *AuditHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("/api/v1/account/audit", handler.handleAccountAudit)
+	router.Handle("/api/v1/admin/audit", RequireAdmin(handler.authService, "The audit log of other users is available to admins only.", http.HandlerFunc(handler.handleAdminAudit)))
+}
+
+type auditEntryPayload struct {
+	ID          int64           `json:"id"`
+	UserID      int64           `json:"user_id"`
+	ActorUserID *int64          `json:"actor_user_id"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"target_type"`
+	TargetID    string          `json:"target_id"`
+	IPAddress   string          `json:"ip_address"`
+	UserAgent   string          `json:"user_agent"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (handler *AuditHandler) handleAccountAudit(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	beforeIdentifier, _ := strconv.ParseInt(request.URL.Query().Get("before"), 10, 64)
+	limit, _ := strconv.Atoi(request.URL.Query().Get("limit"))
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	entries, listError := handler.auditService.ListEntriesForUser(operationContext, userIdentifier, beforeIdentifier, limit)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load the audit log.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toAuditEntryPayloads(entries))
+}
+
+func (handler *AuditHandler) handleAdminAudit(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// user_id narrows the view to one account; without it every user's entries are listed.
+	filterUserIdentifier, _ := strconv.ParseInt(request.URL.Query().Get("user_id"), 10, 64)
+	beforeIdentifier, _ := strconv.ParseInt(request.URL.Query().Get("before"), 10, 64)
+	limit, _ := strconv.Atoi(request.URL.Query().Get("limit"))
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	entries, listError := handler.auditService.ListEntries(operationContext, filterUserIdentifier, beforeIdentifier, limit)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load the audit log.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toAuditEntryPayloads(entries))
+}
+
+func toAuditEntryPayloads(entries []domain.AuditEntry) []auditEntryPayload {
+	payloads := make([]auditEntryPayload, 0, len(entries))
+	for _, entry := range entries {
+		payloads = append(payloads, auditEntryPayload{
+			ID:          entry.Identifier,
+			UserID:      entry.UserIdentifier,
+			ActorUserID: entry.ActorUserIdentifier,
+			Action:      entry.Action,
+			TargetType:  entry.TargetType,
+			TargetID:    entry.TargetIdentifier,
+			IPAddress:   entry.IPAddress,
+			UserAgent:   entry.UserAgent,
+			Before:      auditDocument(entry.BeforeValue),
+			After:       auditDocument(entry.AfterValue),
+			CreatedAt:   entry.CreatedAt,
+		})
+	}
+	return payloads
+}
+
+func auditDocument(value *string) json.RawMessage {
+	if value == nil || *value == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*value)
+}
+
+// auditRequestContext attaches the caller and client (IP, user agent) to the context so services can
+// attribute the audit entries they record. userIdentifier is 0 before authentication (logins).
+func auditRequestContext(request *http.Request, userIdentifier int64) context.Context {
+	return service.WithRequestMetadata(request.Context(), service.RequestMetadata{
+		ActorUserIdentifier: userIdentifier,
+		IPAddress:           clientIPAddress(request),
+		UserAgent:           request.UserAgent(),
+	})
+}
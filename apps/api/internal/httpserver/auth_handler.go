@@ -6,9 +6,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"log"
-	"net"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +20,14 @@ import (
 // postLoginRedirectPath is where the browser lands after a successful Google sign-in.
 const postLoginRedirectPath = "/"
 
+// Sign-in methods recorded in the login audit entry. A login finished with a second factor is
+// recorded as two_factor, whatever the first factor was.
+const (
+	loginMethodPassword  = "password"
+	loginMethodGoogle    = "google"
+	loginMethodTwoFactor = "two_factor"
+)
+
 var errNotAuthenticated = errors.New("not authenticated")
 
 // AuthHandler exposes the authentication endpoints and session-cookie handling. It is
@@ -29,26 +37,38 @@ type AuthHandler struct {
 	SessionService      *service.SessionService
 	GoogleOAuthService  *service.GoogleOAuthService // nil when Google sign-in is not configured
 	AccountEmailService *service.AccountEmailService
-	CookieName          string
-	OAuthStateCookie    string
-	SecureCookies       bool
-}
-
-func NewAuthHandler(authService *service.AuthService, sessionService *service.SessionService, googleOAuthService *service.GoogleOAuthService, accountEmailService *service.AccountEmailService, secureCookies bool) *AuthHandler {
+	TwoFactorService    *service.TwoFactorService
+	RateLimitService    *service.AuthRateLimitService
+	// SessionDeviceService notes the location and device of each sign-in; nil skips that.
+	SessionDeviceService *service.SessionDeviceService
+	CookieName           string
+	OAuthStateCookie     string
+	// TwoFactorCookie carries the login challenge of a Google sign-in that still needs its second
+	// factor, since the OAuth redirect cannot return it in a JSON body.
+	TwoFactorCookie string
+	SecureCookies   bool
+}
+
+func NewAuthHandler(authService *service.AuthService, sessionService *service.SessionService, googleOAuthService *service.GoogleOAuthService, accountEmailService *service.AccountEmailService, twoFactorService *service.TwoFactorService, rateLimitService *service.AuthRateLimitService, sessionDeviceService *service.SessionDeviceService, secureCookies bool) *AuthHandler {
 	return &AuthHandler{
-		AuthService:         authService,
-		SessionService:      sessionService,
-		GoogleOAuthService:  googleOAuthService,
-		AccountEmailService: accountEmailService,
-		CookieName:          "coin_hub_session",
-		OAuthStateCookie:    "coin_hub_oauth_state",
-		SecureCookies:       secureCookies,
+		AuthService:          authService,
+		SessionService:       sessionService,
+		GoogleOAuthService:   googleOAuthService,
+		AccountEmailService:  accountEmailService,
+		TwoFactorService:     twoFactorService,
+		RateLimitService:     rateLimitService,
+		SessionDeviceService: sessionDeviceService,
+		CookieName:           "coin_hub_session",
+		OAuthStateCookie:     "coin_hub_oauth_state",
+		TwoFactorCookie:      "coin_hub_two_factor",
+		SecureCookies:        secureCookies,
 	}
 }
 
 func (handler *AuthHandler) RegisterRoutes(router *http.ServeMux) {
 	router.HandleFunc("/auth/signup", handler.handleSignup)
 	router.HandleFunc("/auth/login", handler.handleLogin)
+	router.HandleFunc("/auth/login/two-factor", handler.handleLoginTwoFactor)
 	router.HandleFunc("/auth/logout", handler.handleLogout)
 	router.HandleFunc("/auth/me", handler.handleCurrentUser)
 	router.HandleFunc("/auth/providers", handler.handleProviders)
@@ -97,6 +117,9 @@ func (handler *AuthHandler) handleSignup(responseWriter http.ResponseWriter, req
 
 	registrationContext, cancel := context.WithTimeout(request.Context(), 8*time.Second)
 	defer cancel()
+	if !handler.enforceRateLimit(registrationContext, responseWriter, request, service.AuthActionSignup, payload.Email) {
+		return
+	}
 
 	createdUser, registrationError := handler.AuthService.Register(registrationContext, payload.Email, payload.Password, payload.DisplayName)
 	if registrationError != nil {
@@ -106,10 +129,10 @@ func (handler *AuthHandler) handleSignup(responseWriter http.ResponseWriter, req
 
 	// Best-effort: send the email-confirmation link. A failure must not block signup.
 	if sendError := handler.AccountEmailService.SendVerificationEmail(registrationContext, createdUser.Identifier, createdUser.Email, resolveRequestLocale(request, payload.Locale)); sendError != nil {
-		log.Printf("Could not send verification email for user %d: %v", createdUser.Identifier, sendError)
+		httpLogger.ErrorContext(request.Context(), "could not send the verification email", "user_id", createdUser.Identifier, "error", sendError)
 	}
 
-	handler.issueSessionAndRespond(responseWriter, request, createdUser)
+	handler.issueSessionAndRespond(responseWriter, request, createdUser, loginMethodPassword)
 }
 
 func (handler *AuthHandler) handleLogin(responseWriter http.ResponseWriter, request *http.Request) {
@@ -124,21 +147,115 @@ func (handler *AuthHandler) handleLogin(responseWriter http.ResponseWriter, requ
 		return
 	}
 
-	authenticationContext, cancel := context.WithTimeout(request.Context(), 8*time.Second)
+	authenticationContext, cancel := context.WithTimeout(auditRequestContext(request, 0), 8*time.Second)
 	defer cancel()
+	if !handler.enforceRateLimit(authenticationContext, responseWriter, request, service.AuthActionLogin, payload.Email) {
+		return
+	}
 
 	authenticatedUser, authenticationError := handler.AuthService.Authenticate(authenticationContext, payload.Email, payload.Password)
 	if authenticationError != nil {
 		if errors.Is(authenticationError, service.ErrInvalidCredentials) || errors.Is(authenticationError, service.ErrAccountDisabled) {
+			if errors.Is(authenticationError, service.ErrInvalidCredentials) && handler.RateLimitService != nil {
+				handler.RateLimitService.RecordLoginFailure(authenticationContext, payload.Email, resolveRequestLocale(request, ""))
+			}
 			writeJSONError(responseWriter, http.StatusUnauthorized, authenticationError.Error())
 			return
 		}
-		log.Printf("Login failed unexpectedly: %v", authenticationError)
+		httpLogger.ErrorContext(request.Context(), "login failed unexpectedly", "error", authenticationError)
 		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not sign in.")
 		return
 	}
+	if handler.RateLimitService != nil {
+		handler.RateLimitService.RecordLoginSuccess(authenticationContext, payload.Email)
+	}
 
-	handler.issueSessionAndRespond(responseWriter, request, authenticatedUser)
+	challengeToken, challengeError := handler.beginTwoFactorChallenge(authenticationContext, authenticatedUser)
+	if challengeError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not sign in.")
+		return
+	}
+	if challengeToken != "" {
+		writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+			"two_factor_required": true,
+			"challenge_token":     challengeToken,
+		})
+		return
+	}
+
+	handler.issueSessionAndRespond(responseWriter, request, authenticatedUser, loginMethodPassword)
+}
+
+// beginTwoFactorChallenge returns a login challenge token when the user has 2FA enabled, or "" when
+// the first factor is enough.
+func (handler *AuthHandler) beginTwoFactorChallenge(operationContext context.Context, user *domain.User) (string, error) {
+	if handler.TwoFactorService == nil {
+		return "", nil
+	}
+	twoFactorEnabled, lookupError := handler.TwoFactorService.IsEnabled(operationContext, user.Identifier)
+	if lookupError != nil {
+		httpLogger.ErrorContext(operationContext, "could not check two-factor authentication", "user_id", user.Identifier, "error", lookupError)
+		return "", lookupError
+	}
+	if !twoFactorEnabled {
+		return "", nil
+	}
+	challengeToken, challengeError := handler.TwoFactorService.BeginLoginChallenge(operationContext, user.Identifier)
+	if challengeError != nil {
+		httpLogger.ErrorContext(operationContext, "could not start the two-factor challenge", "user_id", user.Identifier, "error", challengeError)
+		return "", challengeError
+	}
+	return challengeToken, nil
+}
+
+type twoFactorLoginPayload struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// handleLoginTwoFactor finishes a sign-in with the TOTP or recovery code. The challenge comes from
+// the password login response, or from the cookie set by the Google callback.
+func (handler *AuthHandler) handleLoginTwoFactor(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if handler.TwoFactorService == nil {
+		writeJSONError(responseWriter, http.StatusNotFound, "Two-factor authentication is not available.")
+		return
+	}
+
+	var payload twoFactorLoginPayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	challengeToken := payload.ChallengeToken
+	if challengeToken == "" {
+		if challengeCookie, cookieError := request.Cookie(handler.TwoFactorCookie); cookieError == nil {
+			challengeToken = challengeCookie.Value
+		}
+	}
+	if challengeToken == "" {
+		writeTwoFactorError(responseWriter, service.ErrTwoFactorChallengeFailed, "")
+		return
+	}
+
+	verificationContext, cancel := context.WithTimeout(auditRequestContext(request, 0), 8*time.Second)
+	defer cancel()
+	userIdentifier, verificationError := handler.TwoFactorService.CompleteLoginChallenge(verificationContext, challengeToken, payload.Code)
+	if verificationError != nil {
+		writeTwoFactorError(responseWriter, verificationError, "Could not sign in.")
+		return
+	}
+	authenticatedUser, lookupError := handler.AuthService.GetUserByIdentifier(verificationContext, userIdentifier)
+	if lookupError != nil || authenticatedUser == nil || !authenticatedUser.IsActive {
+		writeJSONError(responseWriter, http.StatusUnauthorized, service.ErrAccountDisabled.Error())
+		return
+	}
+
+	handler.clearTwoFactorCookie(responseWriter)
+	handler.issueSessionAndRespond(responseWriter, request, authenticatedUser, loginMethodTwoFactor)
 }
 
 func (handler *AuthHandler) handleLogout(responseWriter http.ResponseWriter, request *http.Request) {
@@ -151,7 +268,7 @@ func (handler *AuthHandler) handleLogout(responseWriter http.ResponseWriter, req
 		revokeContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
 		defer cancel()
 		if revokeError := handler.SessionService.RevokeSession(revokeContext, sessionCookie.Value); revokeError != nil {
-			log.Printf("Could not revoke session on logout: %v", revokeError)
+			httpLogger.ErrorContext(request.Context(), "could not revoke the session on logout", "error", revokeError)
 		}
 	}
 
@@ -182,43 +299,40 @@ func (handler *AuthHandler) handleCurrentUser(responseWriter http.ResponseWriter
 	writeJSON(responseWriter, http.StatusOK, toUserResponse(currentUser))
 }
 
-// ResolveAuthenticatedUserIdentifier reads and validates the session cookie. It is exported so
-// future protected handlers (and middleware) can reuse it.
+// ResolveAuthenticatedUserIdentifier returns the user behind the session cookie, as resolved by
+// SessionMiddleware. Personal access tokens do not count as a session here.
 func (handler *AuthHandler) ResolveAuthenticatedUserIdentifier(request *http.Request) (int64, error) {
-	sessionCookie, cookieError := request.Cookie(handler.CookieName)
-	if cookieError != nil {
+	principal := PrincipalFromContext(request.Context())
+	if principal == nil || principal.IsAccessToken() {
 		return 0, errNotAuthenticated
 	}
-
-	resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
-	defer cancel()
-	userIdentifier, resolveError := handler.SessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
-	if resolveError != nil {
-		return 0, errNotAuthenticated
-	}
-	return userIdentifier, nil
+	return principal.UserIdentifier, nil
 }
 
-func (handler *AuthHandler) issueSessionAndRespond(responseWriter http.ResponseWriter, request *http.Request, user *domain.User) {
-	if issueError := handler.issueSessionCookie(responseWriter, request, user); issueError != nil {
+func (handler *AuthHandler) issueSessionAndRespond(responseWriter http.ResponseWriter, request *http.Request, user *domain.User, loginMethod string) {
+	if issueError := handler.issueSessionCookie(responseWriter, request, user, loginMethod); issueError != nil {
 		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not start a session.")
 		return
 	}
 	writeJSON(responseWriter, http.StatusOK, toUserResponse(user))
 }
 
-// issueSessionCookie creates a session for the user and writes the session cookie. Callers decide
-// how to respond afterwards (JSON for the email flow, a redirect for the OAuth callback).
-func (handler *AuthHandler) issueSessionCookie(responseWriter http.ResponseWriter, request *http.Request, user *domain.User) error {
+// issueSessionCookie creates a session for the user, writes the session cookie and records the login.
+// Callers decide how to respond afterwards (JSON for the email flow, a redirect for the OAuth callback).
+func (handler *AuthHandler) issueSessionCookie(responseWriter http.ResponseWriter, request *http.Request, user *domain.User, loginMethod string) error {
 	sessionContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
 	defer cancel()
 
 	rawToken, expiresAt, issueError := handler.SessionService.IssueSession(sessionContext, user.Identifier, request.UserAgent(), clientIPAddress(request))
 	if issueError != nil {
-		log.Printf("Could not issue session for user %d: %v", user.Identifier, issueError)
+		httpLogger.ErrorContext(request.Context(), "could not issue a session", "user_id", user.Identifier, "error", issueError)
 		return issueError
 	}
 	handler.setSessionCookie(responseWriter, rawToken, expiresAt)
+	handler.AuthService.RecordLogin(auditRequestContext(request, 0), user.Identifier, loginMethod)
+	if handler.SessionDeviceService != nil {
+		handler.SessionDeviceService.RecordSignIn(user.Identifier, rawToken, request.UserAgent(), clientIPAddress(request), resolveRequestLocale(request, ""))
+	}
 	return nil
 }
 
@@ -279,23 +393,34 @@ func (handler *AuthHandler) handleGoogleCallback(responseWriter http.ResponseWri
 		return
 	}
 
-	exchangeContext, cancel := context.WithTimeout(request.Context(), 12*time.Second)
+	exchangeContext, cancel := context.WithTimeout(auditRequestContext(request, 0), 12*time.Second)
 	defer cancel()
 	googleProfile, profileError := handler.GoogleOAuthService.ExchangeCodeForUserInfo(exchangeContext, authorizationCode)
 	if profileError != nil {
-		log.Printf("Google sign-in failed during code exchange: %v", profileError)
+		httpLogger.WarnContext(request.Context(), "Google sign-in failed during the code exchange", "error", profileError)
 		http.Redirect(responseWriter, request, postLoginRedirectPath+"?login_error=google", http.StatusSeeOther)
 		return
 	}
 
 	authenticatedUser, authenticationError := handler.AuthService.AuthenticateWithGoogle(exchangeContext, googleProfile)
 	if authenticationError != nil {
-		log.Printf("Google sign-in could not resolve an account: %v", authenticationError)
+		httpLogger.ErrorContext(request.Context(), "Google sign-in could not resolve an account", "error", authenticationError)
+		http.Redirect(responseWriter, request, postLoginRedirectPath+"?login_error=google", http.StatusSeeOther)
+		return
+	}
+
+	challengeToken, challengeError := handler.beginTwoFactorChallenge(exchangeContext, authenticatedUser)
+	if challengeError != nil {
 		http.Redirect(responseWriter, request, postLoginRedirectPath+"?login_error=google", http.StatusSeeOther)
 		return
 	}
+	if challengeToken != "" {
+		handler.setTwoFactorCookie(responseWriter, challengeToken)
+		http.Redirect(responseWriter, request, postLoginRedirectPath+"?login_step=two_factor", http.StatusSeeOther)
+		return
+	}
 
-	if issueError := handler.issueSessionCookie(responseWriter, request, authenticatedUser); issueError != nil {
+	if issueError := handler.issueSessionCookie(responseWriter, request, authenticatedUser, loginMethodGoogle); issueError != nil {
 		http.Redirect(responseWriter, request, postLoginRedirectPath+"?login_error=google", http.StatusSeeOther)
 		return
 	}
@@ -319,8 +444,11 @@ func (handler *AuthHandler) handleForgotPassword(responseWriter http.ResponseWri
 	}
 	operationContext, cancel := context.WithTimeout(request.Context(), 8*time.Second)
 	defer cancel()
+	if !handler.enforceRateLimit(operationContext, responseWriter, request, service.AuthActionPasswordForgot, payload.Email) {
+		return
+	}
 	if resetError := handler.AccountEmailService.RequestPasswordReset(operationContext, payload.Email, resolveRequestLocale(request, payload.Locale)); resetError != nil {
-		log.Printf("Password reset request failed: %v", resetError)
+		httpLogger.ErrorContext(request.Context(), "password reset request failed", "error", resetError)
 	}
 	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "If that email has an account, a reset link is on its way."})
 }
@@ -342,7 +470,7 @@ func (handler *AuthHandler) handleResetPassword(responseWriter http.ResponseWrit
 		writeJSONError(responseWriter, http.StatusBadRequest, "A reset token is required.")
 		return
 	}
-	operationContext, cancel := context.WithTimeout(request.Context(), 8*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, 0), 8*time.Second)
 	defer cancel()
 	resetError := handler.AccountEmailService.ConfirmPasswordReset(operationContext, payload.Token, payload.NewPassword)
 	switch {
@@ -353,7 +481,7 @@ func (handler *AuthHandler) handleResetPassword(responseWriter http.ResponseWrit
 	case errors.Is(resetError, service.ErrWeakPassword):
 		writeJSONError(responseWriter, http.StatusBadRequest, service.ErrWeakPassword.Error())
 	default:
-		log.Printf("Password reset failed: %v", resetError)
+		httpLogger.ErrorContext(request.Context(), "password reset failed", "error", resetError)
 		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not reset your password.")
 	}
 }
@@ -383,7 +511,7 @@ func (handler *AuthHandler) handleVerifyEmail(responseWriter http.ResponseWriter
 	case errors.Is(verifyError, repository.ErrAuthTokenInvalid):
 		writeJSONError(responseWriter, http.StatusBadRequest, "This confirmation link is invalid or has expired.")
 	default:
-		log.Printf("Email verification failed: %v", verifyError)
+		httpLogger.ErrorContext(request.Context(), "email verification failed", "error", verifyError)
 		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not confirm your email.")
 	}
 }
@@ -409,12 +537,51 @@ func (handler *AuthHandler) handleResendVerification(responseWriter http.Respons
 		writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Your email is already confirmed."})
 		return
 	}
+	if !handler.enforceRateLimit(operationContext, responseWriter, request, service.AuthActionEmailResend, currentUser.Email) {
+		return
+	}
 	if sendError := handler.AccountEmailService.SendVerificationEmail(operationContext, userIdentifier, currentUser.Email, resolveRequestLocale(request, "")); sendError != nil {
-		log.Printf("Could not resend verification email for user %d: %v", userIdentifier, sendError)
+		httpLogger.ErrorContext(request.Context(), "could not resend the verification email", "user_id", userIdentifier, "error", sendError)
 	}
 	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Verification email sent."})
 }
 
+// enforceRateLimit counts the attempt against the action's IP, email and global limits and answers 429
+// with Retry-After when one is exceeded. Without a rate limiter every request is allowed.
+func (handler *AuthHandler) enforceRateLimit(operationContext context.Context, responseWriter http.ResponseWriter, request *http.Request, action string, emailAddress string) bool {
+	if handler.RateLimitService == nil {
+		return true
+	}
+	limitError := handler.RateLimitService.Allow(operationContext, action, clientIPAddress(request), emailAddress)
+	if limitError == nil {
+		return true
+	}
+	var rateLimitedError *service.RateLimitedError
+	if !errors.As(limitError, &rateLimitedError) {
+		return true
+	}
+	writeRateLimited(responseWriter, rateLimitedError)
+	return false
+}
+
+// writeRateLimited answers 429 with Retry-After and the "rate_limited" or "account_locked" code.
+func writeRateLimited(responseWriter http.ResponseWriter, rateLimitedError *service.RateLimitedError) {
+	retryAfterSeconds := int(math.Ceil(rateLimitedError.RetryAfter.Seconds()))
+	if retryAfterSeconds < 1 {
+		retryAfterSeconds = 1
+	}
+	responseWriter.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	code := "rate_limited"
+	if rateLimitedError.AccountLocked {
+		code = "account_locked"
+	}
+	writeJSON(responseWriter, http.StatusTooManyRequests, map[string]interface{}{
+		"error":               rateLimitedError.Error(),
+		"code":                code,
+		"retry_after_seconds": retryAfterSeconds,
+	})
+}
+
 // resolveRequestLocale picks the email language: the payload's locale if supported, otherwise the
 // browser's Accept-Language, otherwise pt-BR.
 func resolveRequestLocale(request *http.Request, payloadLocale string) string {
@@ -459,6 +626,31 @@ func (handler *AuthHandler) clearStateCookie(responseWriter http.ResponseWriter)
 	})
 }
 
+func (handler *AuthHandler) setTwoFactorCookie(responseWriter http.ResponseWriter, challengeToken string) {
+	http.SetCookie(responseWriter, &http.Cookie{
+		Name:     handler.TwoFactorCookie,
+		Value:    challengeToken,
+		Path:     "/auth",
+		MaxAge:   300,
+		HttpOnly: true,
+		Secure:   handler.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (handler *AuthHandler) clearTwoFactorCookie(responseWriter http.ResponseWriter) {
+	http.SetCookie(responseWriter, &http.Cookie{
+		Name:     handler.TwoFactorCookie,
+		Value:    "",
+		Path:     "/auth",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   handler.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 func generateOAuthState() (string, error) {
 	randomBytes := make([]byte, 32)
 	if _, randomError := rand.Read(randomBytes); randomError != nil {
@@ -499,7 +691,7 @@ func (handler *AuthHandler) writeRegistrationError(responseWriter http.ResponseW
 	case errors.Is(registrationError, repository.ErrEmailAlreadyRegistered):
 		writeJSONError(responseWriter, http.StatusConflict, "That email is already registered.")
 	default:
-		log.Printf("Registration failed unexpectedly: %v", registrationError)
+		httpLogger.Error("registration failed unexpectedly", "error", registrationError)
 		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not create the account.")
 	}
 }
@@ -521,12 +713,14 @@ func writeJSON(responseWriter http.ResponseWriter, statusCode int, payload inter
 	responseWriter.Header().Set("Content-Type", "application/json")
 	responseWriter.WriteHeader(statusCode)
 	if encodeError := json.NewEncoder(responseWriter).Encode(payload); encodeError != nil {
-		log.Printf("Could not encode JSON response: %v", encodeError)
+		httpLogger.Error("could not encode the JSON response", "error", encodeError)
 	}
 }
 
+// writeJSONError writes the API error envelope, {"error": message, "code": code}, with the generic
+// code for statusCode.
 func writeJSONError(responseWriter http.ResponseWriter, statusCode int, message string) {
-	writeJSON(responseWriter, statusCode, map[string]string{"error": message})
+	writeJSONErrorCode(responseWriter, statusCode, message, defaultErrorCode(statusCode))
 }
 
 // writeJSONErrorCode is writeJSONError plus a machine-readable code the SPA can branch on (e.g. to
@@ -549,15 +743,3 @@ func enforceEmailVerified(operationContext context.Context, responseWriter http.
 	}
 	return true
 }
-
-func clientIPAddress(request *http.Request) string {
-	forwardedFor := request.Header.Get("X-Forwarded-For")
-	if forwardedFor != "" {
-		return strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
-	}
-	host, _, splitError := net.SplitHostPort(request.RemoteAddr)
-	if splitError != nil {
-		return request.RemoteAddr
-	}
-	return host
-}
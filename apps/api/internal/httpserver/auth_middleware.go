@@ -0,0 +1,182 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/logging"
+	"coin-alert/internal/service"
+)
+
+type principalContextKey struct{}
+
+// Principal is the authenticated caller behind a request: either a browser session (SessionToken
+// set) or a personal access token (TokenIdentifier and Scopes set).
+type Principal struct {
+	UserIdentifier  int64
+	TokenIdentifier int64
+	Scopes          []string
+	SessionToken    string
+}
+
+// IsAccessToken reports whether the caller authenticated with a personal access token.
+func (principal *Principal) IsAccessToken() bool {
+	return principal.TokenIdentifier != 0
+}
+
+// HasAnyScope reports whether the principal was granted at least one of scopes. A session carries
+// every scope.
+func (principal *Principal) HasAnyScope(scopes ...string) bool {
+	if !principal.IsAccessToken() {
+		return true
+	}
+	token := domain.PersonalAccessToken{Scopes: principal.Scopes}
+	for _, scope := range scopes {
+		if token.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFromContext returns the principal attached by BearerTokenMiddleware or SessionMiddleware,
+// or nil for an anonymous request.
+func PrincipalFromContext(requestContext context.Context) *Principal {
+	principal, _ := requestContext.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// BearerTokenMiddleware resolves an `Authorization: Bearer <token>` header to a Principal. A request
+// with an unknown, expired or revoked token is answered with 401 right away; requests without the
+// header pass through untouched and keep using the session cookie.
+func BearerTokenMiddleware(tokenService *service.PersonalAccessTokenService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		rawToken, hasBearer := bearerToken(request)
+		if !hasBearer {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		lookupContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		token, authenticationError := tokenService.Authenticate(lookupContext, rawToken, clientIPAddress(request))
+		cancel()
+		if authenticationError != nil {
+			if !errors.Is(authenticationError, service.ErrPersonalAccessTokenInvalid) {
+				httpLogger.ErrorContext(request.Context(), "access token lookup failed", "error", authenticationError)
+				writeJSONError(responseWriter, http.StatusInternalServerError, "Could not verify the access token.")
+				return
+			}
+			responseWriter.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeJSONErrorCode(responseWriter, http.StatusUnauthorized, authenticationError.Error(), "invalid_token")
+			return
+		}
+		principal := &Principal{
+			UserIdentifier:  token.UserIdentifier,
+			TokenIdentifier: token.Identifier,
+			Scopes:          token.Scopes,
+		}
+		next.ServeHTTP(responseWriter, withPrincipal(request, principal))
+	})
+}
+
+func bearerToken(request *http.Request) (string, bool) {
+	authorization := request.Header.Get("Authorization")
+	scheme, rawToken, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(rawToken), true
+}
+
+// SessionMiddleware resolves the session cookie into a Principal for requests that did not present a
+// bearer token. A missing, expired or unknown cookie leaves the request anonymous; the handler (or
+// RequireAdmin) decides whether that is acceptable.
+func SessionMiddleware(sessionService *service.SessionService, cookieName string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		sessionCookie, cookieError := request.Cookie(cookieName)
+		if PrincipalFromContext(request.Context()) != nil || cookieError != nil || sessionCookie.Value == "" {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		userIdentifier, resolveError := sessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
+		cancel()
+		if resolveError != nil {
+			if !errors.Is(resolveError, service.ErrSessionNotFound) {
+				httpLogger.ErrorContext(request.Context(), "session lookup failed", "error", resolveError)
+			}
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		principal := &Principal{UserIdentifier: userIdentifier, SessionToken: sessionCookie.Value}
+		next.ServeHTTP(responseWriter, withPrincipal(request, principal))
+	})
+}
+
+// withPrincipal attaches the authenticated principal to the request, tags its log lines with user_id
+// and reports the user to the access log.
+func withPrincipal(request *http.Request, principal *Principal) *http.Request {
+	if recorder, found := request.Context().Value(accessLogContextKey{}).(*statusRecorder); found {
+		recorder.userIdentifier = principal.UserIdentifier
+	}
+	requestContext := logging.WithUserID(context.WithValue(request.Context(), principalContextKey{}, principal), principal.UserIdentifier)
+	return request.WithContext(requestContext)
+}
+
+// RequireAdmin only lets signed-in admins through; everyone else gets 401/403 with forbiddenMessage.
+// Admin areas are not reachable with personal access tokens.
+func RequireAdmin(authService *service.AuthService, forbiddenMessage string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+		if !authenticated {
+			return
+		}
+		lookupContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		currentUser, lookupError := authService.GetUserByIdentifier(lookupContext, userIdentifier)
+		cancel()
+		if lookupError != nil || currentUser == nil {
+			writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+			return
+		}
+		if !currentUser.IsAdmin {
+			writeJSONError(responseWriter, http.StatusForbidden, forbiddenMessage)
+			return
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+// authenticateRequest returns the calling user. Handlers that accept personal access tokens call it
+// with the scopes that grant the action (any one suffices); a session carries every scope. It writes
+// 401/403 and returns false otherwise.
+func authenticateRequest(responseWriter http.ResponseWriter, request *http.Request, acceptedScopes ...string) (int64, bool) {
+	principal := PrincipalFromContext(request.Context())
+	if principal == nil {
+		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+		return 0, false
+	}
+	if !principal.HasAnyScope(acceptedScopes...) {
+		responseWriter.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(acceptedScopes, " ")+`"`)
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, "This access token does not have the "+strings.Join(acceptedScopes, " or ")+" scope.", "insufficient_scope")
+		return 0, false
+	}
+	return principal.UserIdentifier, true
+}
+
+// requireSessionUser returns the user behind the session cookie. Account management and the other
+// browser-only endpoints use it; personal access tokens are refused there.
+func requireSessionUser(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
+	principal := PrincipalFromContext(request.Context())
+	if principal == nil {
+		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+		return 0, false
+	}
+	if principal.IsAccessToken() {
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, "Access tokens cannot be used for this endpoint; sign in instead.", "session_required")
+		return 0, false
+	}
+	return principal.UserIdentifier, true
+}
@@ -0,0 +1,69 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coin-alert/internal/domain"
+)
+
+// TestAuthenticateRequestChecksTokenScopes checks which callers reach a handler guarded by
+// authenticateRequest or requireSessionUser.
+func TestAuthenticateRequestChecksTokenScopes(t *testing.T) {
+	tradeHandler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if _, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeTrade); authenticated {
+			responseWriter.WriteHeader(http.StatusNoContent)
+		}
+	})
+	readHandler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if _, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeReadOperations, domain.TokenScopeTrade); authenticated {
+			responseWriter.WriteHeader(http.StatusNoContent)
+		}
+	})
+	accountHandler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if _, authenticated := requireSessionUser(responseWriter, request); authenticated {
+			responseWriter.WriteHeader(http.StatusNoContent)
+		}
+	})
+	session := &Principal{UserIdentifier: 7, SessionToken: "session"}
+	readToken := &Principal{UserIdentifier: 7, TokenIdentifier: 3, Scopes: []string{domain.TokenScopeReadOperations}}
+	tradeToken := &Principal{UserIdentifier: 7, TokenIdentifier: 4, Scopes: []string{domain.TokenScopeTrade}}
+	scopelessToken := &Principal{UserIdentifier: 7, TokenIdentifier: 5}
+
+	cases := []struct {
+		name          string
+		handler       http.Handler
+		principal     *Principal
+		expected      int
+		expectedError string
+	}{
+		{name: "anonymous", handler: tradeHandler, expected: http.StatusUnauthorized},
+		{name: "session on a trade endpoint", handler: tradeHandler, principal: session, expected: http.StatusNoContent},
+		{name: "trade token on a trade endpoint", handler: tradeHandler, principal: tradeToken, expected: http.StatusNoContent},
+		{name: "read token on a trade endpoint", handler: tradeHandler, principal: readToken, expected: http.StatusForbidden, expectedError: "insufficient_scope"},
+		{name: "token without scopes", handler: readHandler, principal: scopelessToken, expected: http.StatusForbidden, expectedError: "insufficient_scope"},
+		{name: "either accepted scope", handler: readHandler, principal: tradeToken, expected: http.StatusNoContent},
+		{name: "session on an account endpoint", handler: accountHandler, principal: session, expected: http.StatusNoContent},
+		{name: "token on an account endpoint", handler: accountHandler, principal: tradeToken, expected: http.StatusForbidden, expectedError: "session_required"},
+	}
+	for _, testCase := range cases {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/trades", nil)
+		if testCase.principal != nil {
+			request = request.WithContext(context.WithValue(request.Context(), principalContextKey{}, testCase.principal))
+		}
+		recorder := httptest.NewRecorder()
+		testCase.handler.ServeHTTP(recorder, request)
+		if recorder.Code != testCase.expected {
+			t.Errorf("%s: expected status %d, got %d", testCase.name, testCase.expected, recorder.Code)
+		}
+		if testCase.expectedError != "" && !strings.Contains(recorder.Body.String(), testCase.expectedError) {
+			t.Errorf("%s: expected error code %q, got %s", testCase.name, testCase.expectedError, recorder.Body.String())
+		}
+		if testCase.expectedError == "insufficient_scope" && !strings.Contains(recorder.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) {
+			t.Errorf("%s: missing the WWW-Authenticate challenge", testCase.name)
+		}
+	}
+}
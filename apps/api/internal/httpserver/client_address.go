@@ -0,0 +1,82 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientAddressContextKey struct{}
+
+// ClientAddressMiddleware resolves the client IP once per request for the rate limits, the audit log
+// and new-device detection. Forwarding headers are only believed when the connection comes from one of
+// the trusted proxies, and then only the hop that proxy added: the rightmost X-Forwarded-For entry (or
+// X-Real-IP). Everything to the left of it is whatever the client chose to send.
+func ClientAddressMiddleware(trustedProxies []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			clientAddress := resolveClientAddress(request, trustedProxies)
+			next.ServeHTTP(responseWriter, request.WithContext(context.WithValue(request.Context(), clientAddressContextKey{}, clientAddress)))
+		})
+	}
+}
+
+// clientIPAddress is the address ClientAddressMiddleware resolved, or the connection's own address
+// when the middleware did not run.
+func clientIPAddress(request *http.Request) string {
+	if clientAddress, resolved := request.Context().Value(clientAddressContextKey{}).(string); resolved {
+		return clientAddress
+	}
+	return remoteHost(request)
+}
+
+func resolveClientAddress(request *http.Request, trustedProxies []netip.Prefix) string {
+	remoteAddress := remoteHost(request)
+	if !isTrustedProxy(remoteAddress, trustedProxies) {
+		return remoteAddress
+	}
+	if forwardedFor := strings.Join(request.Header.Values("X-Forwarded-For"), ","); forwardedFor != "" {
+		hops := strings.Split(forwardedFor, ",")
+		if proxyHop, valid := normalizeIPAddress(hops[len(hops)-1]); valid {
+			return proxyHop
+		}
+	}
+	if realIP, valid := normalizeIPAddress(request.Header.Get("X-Real-IP")); valid {
+		return realIP
+	}
+	return remoteAddress
+}
+
+func isTrustedProxy(remoteAddress string, trustedProxies []netip.Prefix) bool {
+	address, parseError := netip.ParseAddr(remoteAddress)
+	if parseError != nil {
+		return false
+	}
+	for _, trustedProxy := range trustedProxies {
+		if trustedProxy.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeIPAddress(value string) (string, bool) {
+	address, parseError := netip.ParseAddr(strings.TrimSpace(value))
+	if parseError != nil {
+		return "", false
+	}
+	return address.Unmap().String(), true
+}
+
+func remoteHost(request *http.Request) string {
+	host, _, splitError := net.SplitHostPort(request.RemoteAddr)
+	if splitError != nil {
+		host = request.RemoteAddr
+	}
+	if normalizedHost, valid := normalizeIPAddress(host); valid {
+		return normalizedHost
+	}
+	return host
+}
@@ -0,0 +1,79 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"coin-alert/internal/repository"
+	"coin-alert/internal/service"
+)
+
+var testTrustedProxies = []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}
+
+func TestResolveClientAddress(t *testing.T) {
+	cases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		expected   string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.9:51000", expected: "203.0.113.9"},
+		{name: "direct client forging headers", remoteAddr: "203.0.113.9:51000", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Real-IP": {"198.51.100.2"}}, expected: "203.0.113.9"},
+		{name: "trusted proxy", remoteAddr: "172.18.0.1:40000", headers: map[string][]string{"X-Forwarded-For": {"203.0.113.9"}}, expected: "203.0.113.9"},
+		{name: "trusted proxy appending to a forged header", remoteAddr: "172.18.0.1:40000", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, 203.0.113.9"}}, expected: "203.0.113.9"},
+		{name: "trusted proxy with repeated headers", remoteAddr: "172.18.0.1:40000", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1", "203.0.113.9"}}, expected: "203.0.113.9"},
+		{name: "trusted proxy with X-Real-IP only", remoteAddr: "172.18.0.1:40000", headers: map[string][]string{"X-Real-IP": {"203.0.113.9"}}, expected: "203.0.113.9"},
+		{name: "trusted proxy with garbage", remoteAddr: "172.18.0.1:40000", headers: map[string][]string{"X-Forwarded-For": {"not-an-ip"}}, expected: "172.18.0.1"},
+		{name: "IPv6 client", remoteAddr: "[2001:db8::7]:443", expected: "2001:db8::7"},
+	}
+	for _, testCase := range cases {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+		request.RemoteAddr = testCase.remoteAddr
+		for name, values := range testCase.headers {
+			for _, value := range values {
+				request.Header.Add(name, value)
+			}
+		}
+		if resolved := resolveClientAddress(request, testTrustedProxies); resolved != testCase.expected {
+			t.Errorf("%s: resolved %q, expected %q", testCase.name, resolved, testCase.expected)
+		}
+	}
+}
+
+// TestForgedForwardedForDoesNotResetTheRateLimit sends every request with a new X-Forwarded-For value,
+// directly and through the proxy; the per-IP limit must still apply to the real client.
+func TestForgedForwardedForDoesNotResetTheRateLimit(t *testing.T) {
+	for _, remoteAddr := range []string{"203.0.113.9:51000", "172.18.0.1:40000"} {
+		authHandler := &AuthHandler{RateLimitService: service.NewAuthRateLimitService(repository.NewMemoryRateLimitRepository(), nil)}
+		handler := ClientAddressMiddleware(testTrustedProxies)(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if authHandler.enforceRateLimit(request.Context(), responseWriter, request, service.AuthActionPasswordForgot, "") {
+				responseWriter.WriteHeader(http.StatusNoContent)
+			}
+		}))
+
+		var lastStatus int
+		for attempt := 1; attempt <= 11; attempt++ {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password/forgot", nil)
+			request.RemoteAddr = remoteAddr
+			forgedAddress := fmt.Sprintf("198.51.100.%d", attempt)
+			if remoteAddr == "172.18.0.1:40000" {
+				// What nginx's $proxy_add_x_forwarded_for produces: the client's value, then the real address.
+				request.Header.Set("X-Forwarded-For", forgedAddress+", 203.0.113.9")
+			} else {
+				request.Header.Set("X-Forwarded-For", forgedAddress)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			lastStatus = recorder.Code
+			if attempt <= 10 && lastStatus != http.StatusNoContent {
+				t.Fatalf("%s: attempt %d was refused with %d", remoteAddr, attempt, lastStatus)
+			}
+		}
+		if lastStatus != http.StatusTooManyRequests {
+			t.Errorf("%s: the 11th attempt with a new forged address got %d, expected 429", remoteAddr, lastStatus)
+		}
+	}
+}
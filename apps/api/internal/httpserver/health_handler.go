@@ -0,0 +1,71 @@
+package httpserver
+
+import (
+	"net/http"
+	"time"
+
+	"coin-alert/internal/service"
+)
+
+// HealthHandler serves the liveness and readiness probes. Both are unauthenticated so load balancers
+// and deploy scripts can call them.
+type HealthHandler struct {
+	healthService *service.HealthService
+}
+
+func NewHealthHandler(healthService *service.HealthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+func (handler *HealthHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /health/live", handler.handleLive)
+	router.HandleFunc("GET /health/ready", handler.handleReady)
+	// Kept for probes configured before /health/live existed.
+	router.HandleFunc("GET /health", handler.handleLive)
+}
+
+type componentHealthPayload struct {
+	Name        string     `json:"name"`
+	Critical    bool       `json:"critical"`
+	Status      string     `json:"status"`
+	LatencyMS   int64      `json:"latency_ms"`
+	CheckedAt   time.Time  `json:"checked_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+type healthReportPayload struct {
+	Status     string                   `json:"status"`
+	CheckedAt  time.Time                `json:"checked_at"`
+	Components []componentHealthPayload `json:"components"`
+}
+
+// handleLive only says the process is serving requests; dependencies are the readiness probe's job,
+// so a Postgres outage does not get the API restarted in a loop.
+func (handler *HealthHandler) handleLive(responseWriter http.ResponseWriter, request *http.Request) {
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"status": service.HealthStatusOK})
+}
+
+// handleReady runs the component checks. A degraded service still takes traffic (200); a failing one
+// answers 503.
+func (handler *HealthHandler) handleReady(responseWriter http.ResponseWriter, request *http.Request) {
+	report := handler.healthService.Check(request.Context())
+	payload := healthReportPayload{Status: report.Status, CheckedAt: report.CheckedAt, Components: make([]componentHealthPayload, 0, len(report.Components))}
+	for _, component := range report.Components {
+		payload.Components = append(payload.Components, componentHealthPayload{
+			Name:        component.Name,
+			Critical:    component.Critical,
+			Status:      component.Status,
+			LatencyMS:   component.Latency.Milliseconds(),
+			CheckedAt:   component.CheckedAt,
+			LastError:   component.LastError,
+			LastErrorAt: component.LastErrorAt,
+		})
+	}
+	statusCode := http.StatusOK
+	if report.Status == service.HealthStatusFailing {
+		statusCode = http.StatusServiceUnavailable
+	}
+	responseWriter.Header().Set("Cache-Control", "no-store")
+	writeJSON(responseWriter, statusCode, payload)
+}
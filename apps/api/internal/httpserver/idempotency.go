@@ -0,0 +1,156 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"coin-alert/internal/service"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maximumIdempotencyKeySize = 255
+)
+
+// idempotent lets clients retry an order-placing request safely by sending an Idempotency-Key header:
+// the first request with a key runs, and retries within service.IdempotencyKeyLifetime get its stored
+// response (with Idempotent-Replayed: true) instead of placing another order. A retry that arrives
+// while the first request is still running gets 409. Requests without the header, safe methods and
+// anonymous requests (which the handler rejects) pass straight through, as does everything when
+// idempotencyService is nil.
+func idempotent(idempotencyService *service.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		key := strings.TrimSpace(request.Header.Get(idempotencyKeyHeader))
+		principal := PrincipalFromContext(request.Context())
+		if idempotencyService == nil || key == "" || principal == nil || isSafeMethod(request.Method) {
+			next(responseWriter, request)
+			return
+		}
+		if len(key) > maximumIdempotencyKeySize {
+			writeJSONErrorCode(responseWriter, http.StatusBadRequest, "The Idempotency-Key header is too long.", "invalid_idempotency_key")
+			return
+		}
+		body, readError := io.ReadAll(request.Body)
+		if readError != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(readError, &tooLarge) {
+				writeJSONError(responseWriter, http.StatusRequestEntityTooLarge, "Request body too large.")
+				return
+			}
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userIdentifier := principal.UserIdentifier
+		fingerprint := service.IdempotencyFingerprint(request.Method, request.URL.Path, body)
+		stored, claimError := idempotencyService.Claim(request.Context(), userIdentifier, key, fingerprint)
+		switch {
+		case errors.Is(claimError, service.ErrIdempotencyKeyInFlight):
+			writeJSONErrorCode(responseWriter, http.StatusConflict, "A request with this Idempotency-Key is still being processed.", "idempotency_in_flight")
+			return
+		case errors.Is(claimError, service.ErrIdempotencyKeyReused):
+			writeJSONErrorCode(responseWriter, http.StatusUnprocessableEntity, "This Idempotency-Key was already used for a different request.", "idempotency_key_reused")
+			return
+		case claimError != nil:
+			// Without the claim a retry could place a second order, so the request is not run.
+			httpLogger.ErrorContext(request.Context(), "could not claim the idempotency key", "error", claimError)
+			writeJSONError(responseWriter, http.StatusServiceUnavailable, "Could not process the request right now; try again.")
+			return
+		case stored != nil:
+			if stored.ResponseLocation != "" {
+				responseWriter.Header().Set("Location", stored.ResponseLocation)
+			}
+			if len(stored.ResponseBody) > 0 {
+				responseWriter.Header().Set("Content-Type", "application/json")
+			}
+			responseWriter.Header().Set(idempotentReplayedHeader, "true")
+			responseWriter.WriteHeader(stored.ResponseStatus)
+			_, _ = responseWriter.Write(stored.ResponseBody)
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: responseWriter}
+		finished := false
+		// The outcome is saved even when the client has gone away: that is exactly when it retries.
+		defer func() {
+			storeContext, cancel := context.WithTimeout(context.WithoutCancel(request.Context()), 5*time.Second)
+			defer cancel()
+			var storeError error
+			if finished && !recorder.notExecuted && keepsIdempotentResponse(recorder.status()) {
+				storeError = idempotencyService.Complete(storeContext, userIdentifier, key, recorder.status(), responseWriter.Header().Get("Location"), recorder.body.Bytes())
+			} else {
+				storeError = idempotencyService.Release(storeContext, userIdentifier, key)
+			}
+			if storeError != nil {
+				httpLogger.ErrorContext(storeContext, "could not save the idempotent request outcome", "error", storeError)
+			}
+		}()
+		next(recorder, request)
+		finished = true
+	}
+}
+
+// keepsIdempotentResponse reports whether a response is replayed to retries. Authentication, unverified
+// email, step-up, oversized body and throttling refusals always come before the handler acts, so they
+// release the key instead and the client can fix the problem and retry with it. Everything else,
+// failures included, may have placed an order and is kept; a 400 in particular is also how v1 reports
+// a trade that failed after reaching Binance, so only 400s marked with markNotExecuted are released.
+func keepsIdempotentResponse(statusCode int) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return false
+	default:
+		return true
+	}
+}
+
+// markNotExecuted tells idempotent that the response being written refuses the request before it had
+// any effect (malformed body, missing id), so its key is released rather than replayed. Handlers call
+// it only for checks made before the service is called. Outside idempotent it does nothing.
+func markNotExecuted(responseWriter http.ResponseWriter) {
+	if recorder, isRecorder := responseWriter.(*idempotencyRecorder); isRecorder {
+		recorder.notExecuted = true
+	}
+}
+
+// idempotencyRecorder passes the response through while keeping a copy for replay.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	body        bytes.Buffer
+	notExecuted bool
+}
+
+func (recorder *idempotencyRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *idempotencyRecorder) Write(body []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	recorder.body.Write(body)
+	return recorder.ResponseWriter.Write(body)
+}
+
+func (recorder *idempotencyRecorder) status() int {
+	if recorder.statusCode == 0 {
+		return http.StatusOK
+	}
+	return recorder.statusCode
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (recorder *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -0,0 +1,187 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/service"
+)
+
+// fakeIdempotencyRepository is an in-memory repository.IdempotencyRepository.
+type fakeIdempotencyRepository struct {
+	mutex   sync.Mutex
+	records map[string]*domain.IdempotencyRecord
+}
+
+func (repository *fakeIdempotencyRepository) ClaimIdempotencyKey(_ context.Context, userIdentifier int64, key string, requestFingerprint string, expiresAt time.Time) (*domain.IdempotencyRecord, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if existing, found := repository.records[key]; found {
+		copied := *existing
+		return &copied, nil
+	}
+	repository.records[key] = &domain.IdempotencyRecord{UserIdentifier: userIdentifier, Key: key, RequestFingerprint: requestFingerprint, ExpiresAt: expiresAt}
+	return nil, nil
+}
+
+func (repository *fakeIdempotencyRepository) CompleteIdempotencyKey(_ context.Context, _ int64, key string, responseStatus int, responseLocation string, responseBody []byte) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	record := repository.records[key]
+	record.ResponseStatus = responseStatus
+	record.ResponseLocation = responseLocation
+	record.ResponseBody = append([]byte(nil), responseBody...)
+	return nil
+}
+
+func (repository *fakeIdempotencyRepository) ReleaseIdempotencyKey(_ context.Context, _ int64, key string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	delete(repository.records, key)
+	return nil
+}
+
+func (repository *fakeIdempotencyRepository) DeleteExpiredIdempotencyKeys(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotentReplaysFirstResponse(t *testing.T) {
+	idempotencyService := service.NewIdempotencyService(&fakeIdempotencyRepository{records: make(map[string]*domain.IdempotencyRecord)})
+	executions := 0
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := idempotent(idempotencyService, func(responseWriter http.ResponseWriter, request *http.Request) {
+		executions++
+		if request.URL.Query().Get("block") != "" {
+			close(started)
+			<-release
+		}
+		responseWriter.Header().Set("Location", "/api/v2/operations/7")
+		writeJSON(responseWriter, http.StatusCreated, map[string]int{"id": 7})
+	})
+	send := func(key string, target string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		request.Header.Set(idempotencyKeyHeader, key)
+		request = request.WithContext(context.WithValue(request.Context(), principalContextKey{}, &Principal{UserIdentifier: 1, SessionToken: "session"}))
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		return recorder
+	}
+
+	first := send("key-1", "/api/v2/operations", `{"symbol":"BTCUSDT"}`)
+	retry := send("key-1", "/api/v2/operations", `{"symbol":"BTCUSDT"}`)
+	if executions != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", executions)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("Location") != "/api/v2/operations/7" || retry.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Errorf("retry was not a replay of the first response: %d %q %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+	if reused := send("key-1", "/api/v2/operations", `{"symbol":"ETHUSDT"}`); reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a key reused with another body, got %d", reused.Code)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		send("key-2", "/api/v2/operations?block=1", `{}`)
+		close(done)
+	}()
+	<-started
+	if concurrent := send("key-2", "/api/v2/operations?block=1", `{}`); concurrent.Code != http.StatusConflict {
+		t.Errorf("expected 409 while the first request is in flight, got %d", concurrent.Code)
+	}
+	close(release)
+	<-done
+	if executions != 2 {
+		t.Errorf("expected 2 executions in total, got %d", executions)
+	}
+}
+
+// TestIdempotentReleasesOnlyRefusals checks which outcomes free the key for another attempt. A 400 is
+// kept unless the handler marked it as refused before acting: v1 also answers trade failures with 400.
+func TestIdempotentReleasesOnlyRefusals(t *testing.T) {
+	cases := []struct {
+		name        string
+		status      int
+		notExecuted bool
+		released    bool
+	}{
+		{name: "malformed body", status: http.StatusBadRequest, notExecuted: true, released: true},
+		{name: "trade failure reported as 400", status: http.StatusBadRequest},
+		{name: "not authenticated", status: http.StatusUnauthorized, released: true},
+		{name: "step-up required", status: http.StatusForbidden, released: true},
+		{name: "throttled", status: http.StatusTooManyRequests, released: true},
+		{name: "gateway timeout", status: http.StatusGatewayTimeout},
+		{name: "server error", status: http.StatusInternalServerError},
+	}
+	for _, testCase := range cases {
+		idempotencyService := service.NewIdempotencyService(&fakeIdempotencyRepository{records: make(map[string]*domain.IdempotencyRecord)})
+		executions := 0
+		handler := idempotent(idempotencyService, func(responseWriter http.ResponseWriter, request *http.Request) {
+			executions++
+			if testCase.notExecuted {
+				markNotExecuted(responseWriter)
+			}
+			writeJSONError(responseWriter, testCase.status, "refused")
+		})
+		for attempt := 0; attempt < 2; attempt++ {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/operations", strings.NewReader(`{}`))
+			request.Header.Set(idempotencyKeyHeader, "key-1")
+			request = request.WithContext(context.WithValue(request.Context(), principalContextKey{}, &Principal{UserIdentifier: 1, SessionToken: "session"}))
+			handler(httptest.NewRecorder(), request)
+		}
+		expectedExecutions := 1
+		if testCase.released {
+			expectedExecutions = 2
+		}
+		if executions != expectedExecutions {
+			t.Errorf("%s: expected %d executions, got %d", testCase.name, expectedExecutions, executions)
+		}
+	}
+}
+
+// verifiedUserRepository serves one email-verified user; other methods are not used.
+type verifiedUserRepository struct {
+	repository.UserRepository
+}
+
+func (verifiedUserRepository) FindByIdentifier(_ context.Context, userIdentifier int64) (*domain.User, error) {
+	verifiedAt := time.Now()
+	return &domain.User{Identifier: userIdentifier, EmailVerifiedAt: &verifiedAt}, nil
+}
+
+// TestV1BuyFailureIsReplayed sends a v1 buy that ExecuteBuy refuses with 400. The retry must get the
+// stored answer: the same 400 could have come from a timeout after Binance filled the order.
+func TestV1BuyFailureIsReplayed(t *testing.T) {
+	idempotencyRepository := &fakeIdempotencyRepository{records: make(map[string]*domain.IdempotencyRecord)}
+	authService := service.NewAuthService(verifiedUserRepository{}, nil, nil, service.NewPasswordService(), nil, nil)
+	tradingService := service.NewUserTradingService(nil, nil, nil, nil, nil, nil, nil)
+	router := http.NewServeMux()
+	NewOperationsHandler(authService, tradingService, service.NewIdempotencyService(idempotencyRepository)).RegisterRoutes(router)
+	send := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/operations", strings.NewReader(body))
+		request.Header.Set(idempotencyKeyHeader, "buy-1")
+		request = request.WithContext(context.WithValue(request.Context(), principalContextKey{}, &Principal{UserIdentifier: 1, SessionToken: "session"}))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if malformed := send(`{"symbol":`); malformed.Code != http.StatusBadRequest || len(idempotencyRepository.records) != 0 {
+		t.Fatalf("expected a malformed body to be refused and its key released, got %d with %d stored keys", malformed.Code, len(idempotencyRepository.records))
+	}
+	first := send(`{"symbol":"BTCUSDT","quote_amount":0}`)
+	if first.Code != http.StatusBadRequest || first.Header().Get(idempotentReplayedHeader) != "" {
+		t.Fatalf("expected ExecuteBuy to refuse the buy, got %d %q", first.Code, first.Body.String())
+	}
+	retry := send(`{"symbol":"BTCUSDT","quote_amount":0}`)
+	if retry.Header().Get(idempotentReplayedHeader) != "true" || retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the retry to replay the failed buy, got %d %q %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+}
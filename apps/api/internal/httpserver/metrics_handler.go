@@ -0,0 +1,39 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/metrics"
+	"coin-alert/internal/service"
+)
+
+// RegisterMetricsRoute serves the Prometheus metrics at /metrics. Only admins may read them: with their
+// session, or with a personal access token carrying the metrics:read scope (what a Prometheus scrape
+// job uses as its bearer token).
+func RegisterMetricsRoute(router *http.ServeMux, authService *service.AuthService) {
+	router.HandleFunc("GET /metrics", func(responseWriter http.ResponseWriter, request *http.Request) {
+		userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeMetricsRead)
+		if !authenticated {
+			return
+		}
+		lookupContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+		currentUser, lookupError := authService.GetUserByIdentifier(lookupContext, userIdentifier)
+		cancel()
+		if lookupError != nil || currentUser == nil {
+			writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+			return
+		}
+		if !currentUser.IsAdmin {
+			writeJSONError(responseWriter, http.StatusForbidden, "Metrics are available to admins only.")
+			return
+		}
+		responseWriter.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		responseWriter.Header().Set("Cache-Control", "no-store")
+		if writeError := metrics.Default.Write(responseWriter); writeError != nil {
+			httpLogger.WarnContext(request.Context(), "could not write the metrics", "error", writeError)
+		}
+	})
+}
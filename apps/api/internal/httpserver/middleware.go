@@ -0,0 +1,200 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"coin-alert/internal/logging"
+)
+
+var httpLogger = logging.For("http")
+
+// Middleware wraps a handler with cross-cutting behaviour (authentication, logging, limits...).
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middlewares around handler so that the first one listed runs first on the way in.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for index := len(middlewares) - 1; index >= 0; index-- {
+		handler = middlewares[index](handler)
+	}
+	return handler
+}
+
+type requestIdentifierContextKey struct{}
+
+const requestIdentifierHeader = "X-Request-ID"
+
+// RequestIDMiddleware tags each request with an identifier, echoed in the X-Request-ID response
+// header, available to handlers through RequestIDFromContext and added to every log line written with
+// the request context. A well-formed identifier sent by the client or the proxy is kept so a request
+// can be followed across hops.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		requestIdentifier := request.Header.Get(requestIdentifierHeader)
+		if !isValidRequestIdentifier(requestIdentifier) {
+			requestIdentifier = generateRequestIdentifier()
+		}
+		responseWriter.Header().Set(requestIdentifierHeader, requestIdentifier)
+		requestContext := logging.WithRequestID(context.WithValue(request.Context(), requestIdentifierContextKey{}, requestIdentifier), requestIdentifier)
+		next.ServeHTTP(responseWriter, request.WithContext(requestContext))
+	})
+}
+
+// RequestIDFromContext returns the identifier attached by RequestIDMiddleware, or "".
+func RequestIDFromContext(requestContext context.Context) string {
+	requestIdentifier, _ := requestContext.Value(requestIdentifierContextKey{}).(string)
+	return requestIdentifier
+}
+
+func isValidRequestIdentifier(requestIdentifier string) bool {
+	if requestIdentifier == "" || len(requestIdentifier) > 64 {
+		return false
+	}
+	for _, character := range requestIdentifier {
+		isAllowed := (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') ||
+			(character >= '0' && character <= '9') || character == '-' || character == '_' || character == '.'
+		if !isAllowed {
+			return false
+		}
+	}
+	return true
+}
+
+func generateRequestIdentifier() string {
+	randomBytes := make([]byte, 12)
+	if _, readError := rand.Read(randomBytes); readError != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(randomBytes)
+}
+
+// RecoveryMiddleware turns a panicking handler into a logged 500 instead of a dropped connection.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			httpLogger.ErrorContext(request.Context(), "panic while serving a request", "method", request.Method, "path", request.URL.Path, "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+			if recorder, isRecorder := responseWriter.(*statusRecorder); isRecorder && recorder.statusCode != 0 {
+				return
+			}
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Internal server error.")
+		}()
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+// statusRecorder remembers the status code and body size written through it, for the access log.
+// userIdentifier is filled in by the authentication middleware further in, through the context.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode     int
+	writtenBytes   int64
+	userIdentifier int64
+}
+
+type accessLogContextKey struct{}
+
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *statusRecorder) Write(body []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	writtenBytes, writeError := recorder.ResponseWriter.Write(body)
+	recorder.writtenBytes += int64(writtenBytes)
+	return writtenBytes, writeError
+}
+
+// Flush keeps server-sent events working behind the recorder.
+func (recorder *statusRecorder) Flush() {
+	if flusher, canFlush := recorder.ResponseWriter.(http.Flusher); canFlush {
+		if recorder.statusCode == 0 {
+			recorder.statusCode = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// AccessLogMiddleware logs one line per request: method, path, status, size, duration, request id
+// and the authenticated user, if any. Place it outside RecoveryMiddleware so panics are logged as 500.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		startedAt := time.Now()
+		recorder := &statusRecorder{ResponseWriter: responseWriter}
+		next.ServeHTTP(recorder, request.WithContext(context.WithValue(request.Context(), accessLogContextKey{}, recorder)))
+		statusCode := recorder.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		logContext := request.Context()
+		if recorder.userIdentifier != 0 {
+			logContext = logging.WithUserID(logContext, recorder.userIdentifier)
+		}
+		httpLogger.InfoContext(logContext, "request",
+			"method", request.Method,
+			"path", request.URL.Path,
+			"status", statusCode,
+			"bytes", recorder.writtenBytes,
+			"duration_ms", time.Since(startedAt).Milliseconds(),
+		)
+	})
+}
+
+// BodyLimitMiddleware caps request bodies at maxBytes; decoding a larger body fails, which the
+// handlers already answer with 400.
+func BodyLimitMiddleware(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if request.ContentLength > maxBytes {
+				writeJSONErrorCode(responseWriter, http.StatusRequestEntityTooLarge, "Request body is too large.", "body_too_large")
+				return
+			}
+			if request.Body != nil {
+				request.Body = http.MaxBytesReader(responseWriter, request.Body, maxBytes)
+			}
+			next.ServeHTTP(responseWriter, request)
+		})
+	}
+}
+
+// TimeoutMiddleware bounds the request context, so database and Binance calls made with it give up
+// after timeout. Only the routes in exemptPaths (exact paths, such as the long-lived SSE stream) are
+// left alone; what the client sends cannot lift the limit.
+func TimeoutMiddleware(timeout time.Duration, exemptPaths ...string) Middleware {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, exemptPath := range exemptPaths {
+		exempt[exemptPath] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			if exempt[request.URL.Path] {
+				next.ServeHTTP(responseWriter, request)
+				return
+			}
+			timeoutContext, cancel := context.WithTimeout(request.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(responseWriter, request.WithContext(timeoutContext))
+		})
+	}
+}
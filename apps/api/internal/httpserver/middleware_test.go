@@ -0,0 +1,65 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestMiddlewareChainRecoversPanics checks that a panicking handler becomes a 500 that still carries
+// the request id, and that a client-supplied id is kept while a malformed one is replaced.
+func TestMiddlewareChainRecoversPanics(t *testing.T) {
+	handler := Chain(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		panic("boom")
+	}), RequestIDMiddleware, AccessLogMiddleware, RecoveryMiddleware)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/robots", nil)
+	request.Header.Set(requestIdentifierHeader, "trace-123")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get(requestIdentifierHeader); got != "trace-123" {
+		t.Fatalf("expected the client request id to be kept, got %q", got)
+	}
+
+	request.Header.Set(requestIdentifierHeader, "bad id\n")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if got := recorder.Header().Get(requestIdentifierHeader); got == "" || got == "bad id\n" {
+		t.Fatalf("expected a generated request id, got %q", got)
+	}
+}
+
+// TestTimeoutMiddlewareExemptsOnlyTheStreamRoute checks that asking for an event stream does not lift
+// the timeout on other routes, while the stream route itself has no deadline.
+func TestTimeoutMiddlewareExemptsOnlyTheStreamRoute(t *testing.T) {
+	cases := []struct {
+		name        string
+		path        string
+		accept      string
+		hasDeadline bool
+	}{
+		{name: "ordinary request", path: "/api/v1/portfolio", hasDeadline: true},
+		{name: "ordinary route asking for an event stream", path: "/api/v1/portfolio", accept: "text/event-stream", hasDeadline: true},
+		{name: "stream route", path: StreamPath, accept: "text/event-stream", hasDeadline: false},
+		{name: "stream route without the header", path: StreamPath, hasDeadline: false},
+		{name: "path below the stream route", path: StreamPath + "/x", accept: "text/event-stream", hasDeadline: true},
+	}
+	for _, testCase := range cases {
+		var hasDeadline bool
+		handler := TimeoutMiddleware(30*time.Second, StreamPath)(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			_, hasDeadline = request.Context().Deadline()
+		}))
+		request := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+		if testCase.accept != "" {
+			request.Header.Set("Accept", testCase.accept)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		if hasDeadline != testCase.hasDeadline {
+			t.Errorf("%s: deadline = %v, expected %v", testCase.name, hasDeadline, testCase.hasDeadline)
+		}
+	}
+}
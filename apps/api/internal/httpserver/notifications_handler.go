@@ -0,0 +1,387 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/notification"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/service"
+)
+
+// NotificationsHandler serves the per-user notification channel endpoints (add/list/toggle/delete a
+// channel, send a test message), the per-event opt-in preferences, the portfolio digest schedule and
+// the delivery log.
+type NotificationsHandler struct {
+	notificationService *service.NotificationService
+	digestService       *service.DigestService
+}
+
+func NewNotificationsHandler(notificationService *service.NotificationService, digestService *service.DigestService) *NotificationsHandler {
+	return &NotificationsHandler{
+		notificationService: notificationService,
+		digestService:       digestService,
+	}
+}
+
+func (handler *NotificationsHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("/api/v1/notifications/channels", handler.handleChannels)
+	router.HandleFunc("/api/v1/notifications/channels/update", handler.handleUpdateChannel)
+	router.HandleFunc("/api/v1/notifications/channels/delete", handler.handleDeleteChannel)
+	router.HandleFunc("/api/v1/notifications/channels/test", handler.handleTestChannel)
+	router.HandleFunc("/api/v1/notifications/preferences", handler.handlePreferences)
+	router.HandleFunc("/api/v1/notifications/digest", handler.handleDigest)
+	router.HandleFunc("/api/v1/notifications/deliveries", handler.handleDeliveries)
+}
+
+type notificationChannelPayload struct {
+	ID          int64  `json:"id"`
+	ChannelType string `json:"channel_type"`
+	Label       string `json:"label"`
+	IsEnabled   bool   `json:"is_enabled"`
+}
+
+type notificationChannelInputPayload struct {
+	ChannelType       string `json:"channel_type"`
+	EmailAddress      string `json:"email_address"`
+	TelegramBotToken  string `json:"telegram_bot_token"`
+	TelegramChatID    string `json:"telegram_chat_id"`
+	DiscordWebhookURL string `json:"discord_webhook_url"`
+	WebhookURL        string `json:"webhook_url"`
+	Locale            string `json:"locale"` // the language for trade alerts; defaults to the browser's
+}
+
+type notificationDeliveryPayload struct {
+	ID           int64     `json:"id"`
+	ChannelID    *int64    `json:"channel_id"`
+	ChannelType  string    `json:"channel_type"`
+	EventType    string    `json:"event_type"`
+	Subject      string    `json:"subject"`
+	Success      bool      `json:"success"`
+	ErrorMessage *string   `json:"error_message"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (handler *NotificationsHandler) handleChannels(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+		defer cancel()
+		channels, listError := handler.notificationService.ListChannels(operationContext, userIdentifier)
+		if listError != nil {
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load notification channels.")
+			return
+		}
+		payloads := make([]notificationChannelPayload, 0, len(channels))
+		for _, channel := range channels {
+			payloads = append(payloads, toNotificationChannelPayload(channel))
+		}
+		writeJSON(responseWriter, http.StatusOK, payloads)
+
+	case http.MethodPost:
+		var payload notificationChannelInputPayload
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+		defer cancel()
+		channel, addError := handler.notificationService.AddChannel(operationContext, userIdentifier, payload.ChannelType, notification.Configuration{
+			EmailAddress:      payload.EmailAddress,
+			TelegramBotToken:  payload.TelegramBotToken,
+			TelegramChatID:    payload.TelegramChatID,
+			DiscordWebhookURL: payload.DiscordWebhookURL,
+			WebhookURL:        payload.WebhookURL,
+		})
+		if addError != nil {
+			handler.writeNotificationError(responseWriter, addError)
+			return
+		}
+		handler.rememberLocale(operationContext, request, userIdentifier, payload.Locale)
+		writeJSON(responseWriter, http.StatusOK, toNotificationChannelPayload(*channel))
+
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (handler *NotificationsHandler) handleUpdateChannel(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	var payload struct {
+		ID        int64 `json:"id"`
+		IsEnabled bool  `json:"is_enabled"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A channel id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	if updateError := handler.notificationService.SetChannelEnabled(operationContext, userIdentifier, payload.ID, payload.IsEnabled); updateError != nil {
+		handler.writeNotificationError(responseWriter, updateError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Channel updated."})
+}
+
+func (handler *NotificationsHandler) handleDeleteChannel(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A channel id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	if deleteError := handler.notificationService.DeleteChannel(operationContext, userIdentifier, payload.ID); deleteError != nil {
+		handler.writeNotificationError(responseWriter, deleteError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Channel deleted."})
+}
+
+func (handler *NotificationsHandler) handleTestChannel(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A channel id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 15*time.Second)
+	defer cancel()
+	if testError := handler.notificationService.SendTestMessage(operationContext, userIdentifier, payload.ID); testError != nil {
+		if errors.Is(testError, repository.ErrNotificationChannelNotFound) {
+			writeJSONError(responseWriter, http.StatusNotFound, "Channel not found.")
+			return
+		}
+		// The failure is already in the delivery log; surface it so the user can fix the setup.
+		writeJSONError(responseWriter, http.StatusBadGateway, "The test message could not be delivered: "+testError.Error())
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Test message sent."})
+}
+
+type notificationPreferencePayload struct {
+	EventType string `json:"event_type"`
+	IsEnabled bool   `json:"is_enabled"`
+	Locale    string `json:"locale,omitempty"` // input only: the language for trade alerts
+}
+
+func (handler *NotificationsHandler) handlePreferences(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+
+	switch request.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload notificationPreferencePayload
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		if setError := handler.notificationService.SetEventPreference(operationContext, userIdentifier, payload.EventType, payload.IsEnabled); setError != nil {
+			if errors.Is(setError, service.ErrUnknownNotificationEvent) {
+				writeJSONError(responseWriter, http.StatusBadRequest, "Unknown event type.")
+				return
+			}
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the preference.")
+			return
+		}
+		handler.rememberLocale(operationContext, request, userIdentifier, payload.Locale)
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	preferences, listError := handler.notificationService.ListEventPreferences(operationContext, userIdentifier)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load notification preferences.")
+		return
+	}
+	payloads := make([]notificationPreferencePayload, 0, len(domain.TradeEventTypes))
+	for _, eventType := range domain.TradeEventTypes {
+		payloads = append(payloads, notificationPreferencePayload{EventType: eventType, IsEnabled: preferences[eventType]})
+	}
+	writeJSON(responseWriter, http.StatusOK, payloads)
+}
+
+type digestSubscriptionPayload struct {
+	IsEnabled  bool       `json:"is_enabled"`
+	Frequency  string     `json:"frequency"`
+	LocalHour  int        `json:"local_hour"`
+	Weekday    int        `json:"weekday"`
+	TimeZone   string     `json:"time_zone"`
+	Locale     string     `json:"locale"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+}
+
+// rememberLocale stores the language of trade alerts whenever the user changes where or what they are
+// alerted about: the payload's locale, else the browser's. A failure only keeps the previous language.
+func (handler *NotificationsHandler) rememberLocale(operationContext context.Context, request *http.Request, userIdentifier int64, payloadLocale string) {
+	if localeError := handler.notificationService.RememberLocale(operationContext, userIdentifier, resolveRequestLocale(request, payloadLocale)); localeError != nil {
+		httpLogger.WarnContext(request.Context(), "could not save the alert language", "user_id", userIdentifier, "error", localeError)
+	}
+}
+
+func (handler *NotificationsHandler) handleDigest(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+
+	var subscription *domain.DigestSubscription
+	var digestError error
+	switch request.Method {
+	case http.MethodGet:
+		subscription, digestError = handler.digestService.GetSubscription(operationContext, userIdentifier)
+	case http.MethodPut:
+		var payload digestSubscriptionPayload
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		subscription, digestError = handler.digestService.SaveSubscription(operationContext, domain.DigestSubscription{
+			UserIdentifier: userIdentifier,
+			Frequency:      payload.Frequency,
+			LocalHour:      payload.LocalHour,
+			Weekday:        payload.Weekday,
+			TimeZone:       payload.TimeZone,
+			Locale:         resolveRequestLocale(request, payload.Locale),
+			IsEnabled:      payload.IsEnabled,
+		})
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if digestError != nil {
+		switch {
+		case errors.Is(digestError, service.ErrDigestFrequencyInvalid),
+			errors.Is(digestError, service.ErrDigestHourInvalid),
+			errors.Is(digestError, service.ErrDigestWeekdayInvalid),
+			errors.Is(digestError, service.ErrDigestTimeZoneInvalid):
+			writeJSONError(responseWriter, http.StatusBadRequest, digestError.Error())
+		default:
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the digest settings.")
+		}
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, digestSubscriptionPayload{
+		IsEnabled:  subscription.IsEnabled,
+		Frequency:  subscription.Frequency,
+		LocalHour:  subscription.LocalHour,
+		Weekday:    subscription.Weekday,
+		TimeZone:   subscription.TimeZone,
+		Locale:     subscription.Locale,
+		LastSentAt: subscription.LastSentAt,
+	})
+}
+
+func (handler *NotificationsHandler) handleDeliveries(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	limit, _ := strconv.Atoi(request.URL.Query().Get("limit"))
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	deliveries, listError := handler.notificationService.ListDeliveries(operationContext, userIdentifier, limit)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load the delivery log.")
+		return
+	}
+	payloads := make([]notificationDeliveryPayload, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		payloads = append(payloads, toNotificationDeliveryPayload(delivery))
+	}
+	writeJSON(responseWriter, http.StatusOK, payloads)
+}
+
+func (handler *NotificationsHandler) writeNotificationError(responseWriter http.ResponseWriter, notificationError error) {
+	switch {
+	case errors.Is(notificationError, repository.ErrNotificationChannelNotFound):
+		writeJSONError(responseWriter, http.StatusNotFound, "Channel not found.")
+	case errors.Is(notificationError, service.ErrNotificationChannelLimitReached):
+		writeJSONError(responseWriter, http.StatusForbidden, notificationError.Error())
+	case errors.Is(notificationError, service.ErrCredentialEncryptionUnavailable):
+		writeJSONError(responseWriter, http.StatusServiceUnavailable, "Notification channels are unavailable: the server has no encryption key configured.")
+	case errors.Is(notificationError, notification.ErrUnknownChannelType),
+		errors.Is(notificationError, notification.ErrTelegramFieldsRequired),
+		errors.Is(notificationError, notification.ErrDiscordWebhookInvalid),
+		errors.Is(notificationError, notification.ErrWebhookURLInvalid),
+		errors.Is(notificationError, notification.ErrEmailAddressInvalid):
+		writeJSONError(responseWriter, http.StatusBadRequest, notificationError.Error())
+	default:
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the notification channel.")
+	}
+}
+
+func toNotificationChannelPayload(channel service.NotificationChannelView) notificationChannelPayload {
+	return notificationChannelPayload{
+		ID:          channel.Identifier,
+		ChannelType: channel.ChannelType,
+		Label:       channel.Label,
+		IsEnabled:   channel.IsEnabled,
+	}
+}
+
+func toNotificationDeliveryPayload(delivery domain.NotificationDelivery) notificationDeliveryPayload {
+	return notificationDeliveryPayload{
+		ID:           delivery.Identifier,
+		ChannelID:    delivery.ChannelIdentifier,
+		ChannelType:  delivery.ChannelType,
+		EventType:    delivery.EventType,
+		Subject:      delivery.Subject,
+		Success:      delivery.Success,
+		ErrorMessage: delivery.ErrorMessage,
+		CreatedAt:    delivery.CreatedAt,
+	}
+}
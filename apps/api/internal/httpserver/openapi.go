@@ -0,0 +1,372 @@
+package httpserver
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/service"
+)
+
+// openAPIDocument is the checked-in OpenAPI 3 description of the API. It is produced from the
+// payload structs and documentedRoutes below by buildOpenAPIDocument; TestOpenAPIDocument fails when
+// the two drift apart and rewrites it (and the web app's generated client) when run with -update.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// RegisterOpenAPIRoute serves the document at /api/openapi.json.
+func RegisterOpenAPIRoute(router *http.ServeMux) {
+	router.HandleFunc("GET /api/openapi.json", func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.Header().Set("Cache-Control", "no-cache")
+		_, _ = responseWriter.Write(openAPIDocument)
+	})
+}
+
+// documentedRoute describes one endpoint for the OpenAPI document and the generated client. request
+// and response are zero values of the payload types (a slice for array responses, nil for none).
+type documentedRoute struct {
+	method          string
+	path            string
+	operationID     string
+	summary         string
+	tokenScopes     []string // personal access token scopes accepted; empty = session cookie only
+	queryParameters []string // optional string query parameters
+	idempotent      bool     // accepts an Idempotency-Key header (see idempotent)
+	request         interface{}
+	response        interface{}
+	successStatus   int
+}
+
+var documentedRoutes = []documentedRoute{
+	{method: http.MethodGet, path: "/auth/me", operationID: "getCurrentUser", summary: "The signed-in user.", response: userResponsePayload{}, successStatus: http.StatusOK},
+	{method: http.MethodGet, path: "/api/v1/settings", operationID: "getTradingSettings", summary: "Trading settings of the active Binance environment.", response: tradingSettingsPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodPut, path: "/api/v1/settings", operationID: "saveTradingSettings", summary: "Replace the trading settings; enabling live trading requires a recent step-up.", request: tradingSettingsPayload{}, response: tradingSettingsPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodGet, path: "/api/v2/robots", operationID: "listRobots", summary: "The user's robots in the active environment, with the plan's robot limit.", tokenScopes: []string{domain.TokenScopeRobotsWrite, domain.TokenScopeReadOperations}, response: robotsListPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodPost, path: "/api/v2/robots", operationID: "createRobot", idempotent: true, summary: "Create a robot.", tokenScopes: []string{domain.TokenScopeRobotsWrite}, request: robotInputPayload{}, response: robotPayload{}, successStatus: http.StatusCreated},
+	{method: http.MethodGet, path: "/api/v2/robots/{id}", operationID: "getRobot", summary: "One robot.", tokenScopes: []string{domain.TokenScopeRobotsWrite, domain.TokenScopeReadOperations}, response: robotPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodPatch, path: "/api/v2/robots/{id}", operationID: "updateRobot", summary: "Change some of a robot's settings; absent fields are kept.", tokenScopes: []string{domain.TokenScopeRobotsWrite}, request: robotPatchPayload{}, response: robotPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodDelete, path: "/api/v2/robots/{id}", operationID: "deleteRobot", summary: "Delete a robot.", tokenScopes: []string{domain.TokenScopeRobotsWrite}, successStatus: http.StatusNoContent},
+	{method: http.MethodGet, path: "/api/v2/operations", operationID: "listOperations", summary: "Recent operations in the active environment.", tokenScopes: []string{domain.TokenScopeReadOperations}, response: []operationPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodPost, path: "/api/v2/operations", operationID: "buy", idempotent: true, summary: "Place a market buy.", tokenScopes: []string{domain.TokenScopeTrade}, request: buyRequestPayload{}, response: operationPayload{}, successStatus: http.StatusCreated},
+	{method: http.MethodGet, path: "/api/v2/operations/{id}", operationID: "getOperation", summary: "One operation.", tokenScopes: []string{domain.TokenScopeReadOperations}, response: operationPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodPost, path: "/api/v2/operations/{id}/close", operationID: "closeOperation", idempotent: true, summary: "Sell an open position at market now.", tokenScopes: []string{domain.TokenScopeTrade}, response: operationPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodPost, path: "/api/v2/operations/{id}/take-profit", operationID: "placeTakeProfit", idempotent: true, summary: "Place the take-profit limit sell for an open position.", tokenScopes: []string{domain.TokenScopeTrade}, response: operationPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodGet, path: "/api/v2/executions", operationID: "listExecutions", summary: "Recent order executions in the active environment.", tokenScopes: []string{domain.TokenScopeReadOperations}, response: []executionPayload{}, successStatus: http.StatusOK},
+	{method: http.MethodGet, path: "/api/v2/binance/open-orders", operationID: "listOpenOrders", summary: "Open Binance orders, optionally for one symbol.", tokenScopes: []string{domain.TokenScopeReadOperations}, queryParameters: []string{"symbol"}, response: []service.BinanceOpenOrder{}, successStatus: http.StatusOK},
+}
+
+// schemaNameOverrides keeps component names aligned with the names the web app already used.
+var schemaNameOverrides = map[string]string{
+	"userResponsePayload": "User",
+	"robotsListPayload":   "RobotsResponse",
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	optionalFloatType = reflect.TypeOf(optionalFloat{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+)
+
+func schemaName(payloadType reflect.Type) string {
+	if override, found := schemaNameOverrides[payloadType.Name()]; found {
+		return override
+	}
+	name := strings.TrimSuffix(payloadType.Name(), "Payload")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// schemaField is one JSON property of a payload struct, in declaration order.
+type schemaField struct {
+	name       string
+	fieldType  reflect.Type
+	isOptional bool // omitempty: the property may be absent
+}
+
+func schemaFields(payloadType reflect.Type) []schemaField {
+	fields := make([]schemaField, 0, payloadType.NumField())
+	for index := 0; index < payloadType.NumField(); index++ {
+		field := payloadType.Field(index)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, schemaField{name: name, fieldType: field.Type, isOptional: strings.Contains(options, "omitempty")})
+	}
+	return fields
+}
+
+// schemaCollector turns Go types into OpenAPI schemas, registering every struct as a component.
+type schemaCollector struct {
+	components map[string]interface{}
+	structs    map[string]reflect.Type
+}
+
+func (collector *schemaCollector) schemaFor(valueType reflect.Type) map[string]interface{} {
+	switch {
+	case valueType == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case valueType == optionalFloatType:
+		return map[string]interface{}{"type": "number", "nullable": true}
+	case valueType == rawMessageType:
+		return map[string]interface{}{}
+	}
+	switch valueType.Kind() {
+	case reflect.Pointer:
+		schema := collector.schemaFor(valueType.Elem())
+		if _, isReference := schema["$ref"]; isReference {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": collector.schemaFor(valueType.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": collector.schemaFor(valueType.Elem())}
+	case reflect.Struct:
+		name := schemaName(valueType)
+		if _, known := collector.structs[name]; !known {
+			collector.structs[name] = valueType
+			properties := make(map[string]interface{})
+			required := make([]string, 0)
+			for _, field := range schemaFields(valueType) {
+				properties[field.name] = collector.schemaFor(field.fieldType)
+				if !field.isOptional {
+					required = append(required, field.name)
+				}
+			}
+			schema := map[string]interface{}{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				sort.Strings(required)
+				schema["required"] = required
+			}
+			collector.components[name] = schema
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// buildOpenAPIDocument renders documentedRoutes as an OpenAPI 3.0 document.
+func buildOpenAPIDocument() ([]byte, error) {
+	collector := &schemaCollector{components: make(map[string]interface{}), structs: make(map[string]reflect.Type)}
+	collector.components["Error"] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"code", "error"},
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{"type": "string", "description": "Human-readable message."},
+			"code":  map[string]interface{}{"type": "string", "description": "Machine-readable error code, e.g. not_found or step_up_required."},
+		},
+	}
+	paths := make(map[string]map[string]interface{})
+	for _, route := range documentedRoutes {
+		operation := map[string]interface{}{
+			"operationId": route.operationID,
+			"summary":     route.summary,
+			"responses": map[string]interface{}{
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}}},
+				},
+			},
+		}
+		security := []interface{}{map[string]interface{}{"sessionCookie": []string{}}}
+		if len(route.tokenScopes) > 0 {
+			security = append(security, map[string]interface{}{"accessToken": route.tokenScopes})
+		}
+		operation["security"] = security
+		parameters := make([]interface{}, 0)
+		if strings.Contains(route.path, "{id}") {
+			parameters = append(parameters, map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "integer", "format": "int64"}})
+		}
+		for _, parameterName := range route.queryParameters {
+			parameters = append(parameters, map[string]interface{}{"name": parameterName, "in": "query", "required": false, "schema": map[string]interface{}{"type": "string"}})
+		}
+		if route.idempotent {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        idempotencyKeyHeader,
+				"in":          "header",
+				"required":    false,
+				"description": "Retries with the same key within 24h replay the first response instead of placing another order.",
+				"schema":      map[string]interface{}{"type": "string", "maxLength": maximumIdempotencyKeySize},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": collector.schemaFor(reflect.TypeOf(route.request))}},
+			}
+		}
+		success := map[string]interface{}{"description": http.StatusText(route.successStatus)}
+		if route.response != nil {
+			success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": collector.schemaFor(reflect.TypeOf(route.response))}}
+		}
+		operation["responses"].(map[string]interface{})[fmt.Sprint(route.successStatus)] = success
+		if paths[route.path] == nil {
+			paths[route.path] = make(map[string]interface{})
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+	document := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Coin Hub API",
+			"version":     "2.0.0",
+			"description": "Errors use the envelope {\"error\": message, \"code\": code}. Browser calls authenticate with the session cookie; scripts use personal access tokens.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": collector.components,
+			"securitySchemes": map[string]interface{}{
+				"sessionCookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "coin_hub_session"},
+				"accessToken":   map[string]interface{}{"type": "http", "scheme": "bearer", "description": "Personal access token (chpat_...). Scopes: read:operations, trade, robots:write."},
+			},
+		},
+	}
+	encoded, encodeError := json.MarshalIndent(document, "", "  ")
+	if encodeError != nil {
+		return nil, encodeError
+	}
+	return append(encoded, '\n'), nil
+}
+
+// buildTypeScriptClient renders the interfaces and a typed client for documentedRoutes, for the web
+// app's src/lib/api.gen.ts.
+func buildTypeScriptClient() []byte {
+	collector := &schemaCollector{components: make(map[string]interface{}), structs: make(map[string]reflect.Type)}
+	for _, route := range documentedRoutes {
+		for _, payload := range []interface{}{route.request, route.response} {
+			if payload != nil {
+				collector.schemaFor(reflect.TypeOf(payload))
+			}
+		}
+	}
+	names := make([]string, 0, len(collector.structs))
+	for name := range collector.structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var output bytes.Buffer
+	output.WriteString("// Code generated from the API's payload structs; DO NOT EDIT.\n")
+	output.WriteString("// Regenerate with `go test ./internal/httpserver -run TestOpenAPIDocument -update` in apps/api.\n\n")
+	for _, name := range names {
+		fmt.Fprintf(&output, "export interface %s {\n", name)
+		for _, field := range schemaFields(collector.structs[name]) {
+			optionalMarker := ""
+			if field.isOptional {
+				optionalMarker = "?"
+			}
+			fmt.Fprintf(&output, "  %s%s: %s\n", field.name, optionalMarker, typeScriptType(field.fieldType))
+		}
+		output.WriteString("}\n\n")
+	}
+	output.WriteString("export type Requester = <T>(method: string, path: string, body?: unknown, headers?: Record<string, string>) => Promise<T>\n\n")
+	output.WriteString("export function createApiClient(request: Requester) {\n  return {\n")
+	for _, route := range documentedRoutes {
+		arguments := make([]string, 0, 3)
+		path := "'" + route.path + "'"
+		if strings.Contains(route.path, "{id}") {
+			arguments = append(arguments, "id: number")
+			path = "`" + strings.ReplaceAll(route.path, "{id}", "${id}") + "`"
+		}
+		if route.request != nil {
+			arguments = append(arguments, "body: "+typeScriptType(reflect.TypeOf(route.request)))
+		}
+		if len(route.queryParameters) > 0 {
+			queryFields := make([]string, 0, len(route.queryParameters))
+			for _, parameterName := range route.queryParameters {
+				queryFields = append(queryFields, parameterName+"?: string")
+			}
+			arguments = append(arguments, "query: { "+strings.Join(queryFields, "; ")+" } = {}")
+			path = "withQuery(" + path + ", query)"
+		}
+		if route.idempotent {
+			// One key per call: a retry of the same call (not a new click) reuses it.
+			arguments = append(arguments, "idempotencyKey: string = crypto.randomUUID()")
+		}
+		responseType := "null"
+		if route.response != nil {
+			responseType = typeScriptType(reflect.TypeOf(route.response))
+		}
+		call := fmt.Sprintf("request<%s>('%s', %s", responseType, route.method, path)
+		if route.request != nil {
+			call += ", body"
+		} else if route.idempotent {
+			call += ", undefined"
+		}
+		if route.idempotent {
+			call += ", { '" + idempotencyKeyHeader + "': idempotencyKey }"
+		}
+		fmt.Fprintf(&output, "    %s: (%s) => %s),\n", route.operationID, strings.Join(arguments, ", "), call)
+	}
+	output.WriteString("  }\n}\n\n")
+	output.WriteString("function withQuery(path: string, query: Record<string, string | undefined>): string {\n")
+	output.WriteString("  const search = new URLSearchParams()\n")
+	output.WriteString("  for (const [key, value] of Object.entries(query)) {\n")
+	output.WriteString("    if (value !== undefined && value !== '') search.set(key, value)\n")
+	output.WriteString("  }\n")
+	output.WriteString("  const encoded = search.toString()\n")
+	output.WriteString("  return encoded ? `${path}?${encoded}` : path\n")
+	output.WriteString("}\n")
+	return output.Bytes()
+}
+
+func typeScriptType(valueType reflect.Type) string {
+	switch {
+	case valueType == timeType:
+		return "string"
+	case valueType == optionalFloatType:
+		return "number | null"
+	case valueType == rawMessageType:
+		return "unknown"
+	}
+	switch valueType.Kind() {
+	case reflect.Pointer:
+		return typeScriptType(valueType.Elem()) + " | null"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		elementType := typeScriptType(valueType.Elem())
+		if strings.Contains(elementType, " ") {
+			elementType = "(" + elementType + ")"
+		}
+		return elementType + "[]"
+	case reflect.Map:
+		return "Record<string, " + typeScriptType(valueType.Elem()) + ">"
+	case reflect.Struct:
+		return schemaName(valueType)
+	default:
+		return "unknown"
+	}
+}
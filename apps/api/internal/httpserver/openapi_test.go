@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateOpenAPI = flag.Bool("update", false, "rewrite openapi.json and the web app's generated client")
+
+const generatedClientPath = "../../../web/src/lib/api.gen.ts"
+
+// TestOpenAPIDocument fails when a payload struct or documented route changed without the checked-in
+// OpenAPI document and generated TypeScript client being regenerated (run with -update).
+func TestOpenAPIDocument(t *testing.T) {
+	document, buildError := buildOpenAPIDocument()
+	if buildError != nil {
+		t.Fatalf("could not build the OpenAPI document: %v", buildError)
+	}
+	client := buildTypeScriptClient()
+	if *updateOpenAPI {
+		if writeError := os.WriteFile("openapi.json", document, 0o644); writeError != nil {
+			t.Fatal(writeError)
+		}
+		if writeError := os.WriteFile(generatedClientPath, client, 0o644); writeError != nil {
+			t.Fatal(writeError)
+		}
+		return
+	}
+	if !bytes.Equal(document, openAPIDocument) {
+		t.Errorf("openapi.json is out of date with the payload structs; run `go test ./internal/httpserver -run TestOpenAPIDocument -update` and commit the result")
+	}
+	checkedInClient, readError := os.ReadFile(generatedClientPath)
+	if readError != nil {
+		t.Fatalf("could not read the generated client: %v", readError)
+	}
+	if !bytes.Equal(client, checkedInClient) {
+		t.Errorf("%s is out of date with the payload structs; run `go test ./internal/httpserver -run TestOpenAPIDocument -update` and commit the result", generatedClientPath)
+	}
+}
@@ -15,45 +15,28 @@ import (
 
 // OperationsHandler serves the user-scoped trading endpoints (operations, executions, open orders).
 type OperationsHandler struct {
-	sessionService *service.SessionService
 	authService    *service.AuthService
-	cookieName     string
 	tradingService *service.UserTradingService
+	// idempotencyService backs Idempotency-Key on the order-placing routes; nil disables it.
+	idempotencyService *service.IdempotencyService
 }
 
-func NewOperationsHandler(sessionService *service.SessionService, authService *service.AuthService, cookieName string, tradingService *service.UserTradingService) *OperationsHandler {
+func NewOperationsHandler(authService *service.AuthService, tradingService *service.UserTradingService, idempotencyService *service.IdempotencyService) *OperationsHandler {
 	return &OperationsHandler{
-		sessionService: sessionService,
-		authService:    authService,
-		cookieName:     cookieName,
-		tradingService: tradingService,
+		authService:        authService,
+		tradingService:     tradingService,
+		idempotencyService: idempotencyService,
 	}
 }
 
 func (handler *OperationsHandler) RegisterRoutes(router *http.ServeMux) {
-	router.HandleFunc("/api/v1/operations", handler.handleOperations)
-	router.HandleFunc("/api/v1/operations/sell", handler.handleSellOperation)
-	router.HandleFunc("/api/v1/operations/place-sell", handler.handlePlaceSell)
+	router.HandleFunc("/api/v1/operations", idempotent(handler.idempotencyService, handler.handleOperations))
+	router.HandleFunc("/api/v1/operations/sell", idempotent(handler.idempotencyService, handler.handleSellOperation))
+	router.HandleFunc("/api/v1/operations/place-sell", idempotent(handler.idempotencyService, handler.handlePlaceSell))
 	router.HandleFunc("/api/v1/operations/executions", handler.handleExecutions)
 	router.HandleFunc("/api/v1/binance/open-orders", handler.handleOpenOrders)
 }
 
-func (handler *OperationsHandler) requireUser(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
-	sessionCookie, cookieError := request.Cookie(handler.cookieName)
-	if cookieError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
-	defer cancel()
-	userIdentifier, resolveError := handler.sessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
-	if resolveError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	return userIdentifier, true
-}
-
 type buyRequestPayload struct {
 	Symbol              string  `json:"symbol"`
 	QuoteAmount         float64 `json:"quote_amount"`
@@ -91,7 +74,11 @@ type executionPayload struct {
 }
 
 func (handler *OperationsHandler) handleOperations(responseWriter http.ResponseWriter, request *http.Request) {
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	requiredScope := domain.TokenScopeReadOperations
+	if request.Method != http.MethodGet {
+		requiredScope = domain.TokenScopeTrade
+	}
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, requiredScope)
 	if !authenticated {
 		return
 	}
@@ -110,10 +97,11 @@ func (handler *OperationsHandler) handleOperations(responseWriter http.ResponseW
 	case http.MethodPost:
 		var payload buyRequestPayload
 		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			markNotExecuted(responseWriter)
 			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
 			return
 		}
-		operationContext, cancel := context.WithTimeout(request.Context(), 25*time.Second)
+		operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 25*time.Second)
 		defer cancel()
 		if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
 			return
@@ -139,22 +127,24 @@ func (handler *OperationsHandler) handleSellOperation(responseWriter http.Respon
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeTrade)
 	if !authenticated {
 		return
 	}
 
 	var payload sellRequestPayload
 	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		markNotExecuted(responseWriter)
 		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
 		return
 	}
 	if payload.OperationID <= 0 {
+		markNotExecuted(responseWriter)
 		writeJSONError(responseWriter, http.StatusBadRequest, "An operation id is required.")
 		return
 	}
 
-	operationContext, cancel := context.WithTimeout(request.Context(), 25*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 25*time.Second)
 	defer cancel()
 	if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
 		return
@@ -176,22 +166,24 @@ func (handler *OperationsHandler) handlePlaceSell(responseWriter http.ResponseWr
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeTrade)
 	if !authenticated {
 		return
 	}
 
 	var payload sellRequestPayload
 	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		markNotExecuted(responseWriter)
 		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
 		return
 	}
 	if payload.OperationID <= 0 {
+		markNotExecuted(responseWriter)
 		writeJSONError(responseWriter, http.StatusBadRequest, "An operation id is required.")
 		return
 	}
 
-	operationContext, cancel := context.WithTimeout(request.Context(), 25*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 25*time.Second)
 	defer cancel()
 	if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
 		return
@@ -213,7 +205,7 @@ func (handler *OperationsHandler) handleExecutions(responseWriter http.ResponseW
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeReadOperations)
 	if !authenticated {
 		return
 	}
@@ -233,7 +225,7 @@ func (handler *OperationsHandler) handleOpenOrders(responseWriter http.ResponseW
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeReadOperations)
 	if !authenticated {
 		return
 	}
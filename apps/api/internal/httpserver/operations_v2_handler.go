@@ -0,0 +1,154 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// RegisterV2Routes serves operations as a REST resource; closing an operation and placing its
+// take-profit order are sub-resource actions.
+func (handler *OperationsHandler) RegisterV2Routes(router *http.ServeMux) {
+	registerMethodRoutes(router, "/api/v2/operations", map[string]http.HandlerFunc{
+		http.MethodGet:  handler.handleListOperationsV2,
+		http.MethodPost: idempotent(handler.idempotencyService, handler.handleBuyV2),
+	})
+	registerMethodRoutes(router, "/api/v2/operations/{id}", map[string]http.HandlerFunc{
+		http.MethodGet: handler.handleGetOperationV2,
+	})
+	registerMethodRoutes(router, "/api/v2/operations/{id}/close", map[string]http.HandlerFunc{
+		http.MethodPost: idempotent(handler.idempotencyService, handler.handleCloseOperationV2),
+	})
+	registerMethodRoutes(router, "/api/v2/operations/{id}/take-profit", map[string]http.HandlerFunc{
+		http.MethodPost: idempotent(handler.idempotencyService, handler.handleTakeProfitV2),
+	})
+	registerMethodRoutes(router, "/api/v2/executions", map[string]http.HandlerFunc{
+		http.MethodGet: handler.handleExecutions,
+	})
+	registerMethodRoutes(router, "/api/v2/binance/open-orders", map[string]http.HandlerFunc{
+		http.MethodGet: handler.handleOpenOrders,
+	})
+}
+
+func (handler *OperationsHandler) handleListOperationsV2(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeReadOperations)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	operations, listError := handler.tradingService.ListOperations(operationContext, userIdentifier, 200)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load operations.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toOperationPayloads(operations))
+}
+
+func (handler *OperationsHandler) handleGetOperationV2(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeReadOperations)
+	if !authenticated {
+		return
+	}
+	operationIdentifier, validIdentifier := pathIdentifier(responseWriter, request)
+	if !validIdentifier {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	operation, lookupError := handler.tradingService.GetOperation(operationContext, userIdentifier, operationIdentifier)
+	if lookupError != nil {
+		writeOperationErrorV2(responseWriter, lookupError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toOperationPayload(*operation))
+}
+
+func (handler *OperationsHandler) handleBuyV2(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeTrade)
+	if !authenticated {
+		return
+	}
+	var payload buyRequestPayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		markNotExecuted(responseWriter)
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 25*time.Second)
+	defer cancel()
+	if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
+		return
+	}
+	operation, buyError := handler.tradingService.ExecuteBuy(operationContext, userIdentifier, domain.ExecutionInitiatorUser, payload.Symbol, payload.QuoteAmount, payload.TargetProfitPercent, nil)
+	if buyError != nil {
+		writeOperationErrorV2(responseWriter, buyError)
+		return
+	}
+	responseWriter.Header().Set("Location", "/api/v2/operations/"+strconv.FormatInt(operation.Identifier, 10))
+	writeJSON(responseWriter, http.StatusCreated, toOperationPayload(*operation))
+}
+
+func (handler *OperationsHandler) handleCloseOperationV2(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeTrade)
+	if !authenticated {
+		return
+	}
+	operationIdentifier, validIdentifier := pathIdentifier(responseWriter, request)
+	if !validIdentifier {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 25*time.Second)
+	defer cancel()
+	if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
+		return
+	}
+	operation, closeError := handler.tradingService.CloseOperationNow(operationContext, userIdentifier, operationIdentifier)
+	if closeError != nil {
+		writeOperationErrorV2(responseWriter, closeError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toOperationPayload(*operation))
+}
+
+func (handler *OperationsHandler) handleTakeProfitV2(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, domain.TokenScopeTrade)
+	if !authenticated {
+		return
+	}
+	operationIdentifier, validIdentifier := pathIdentifier(responseWriter, request)
+	if !validIdentifier {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 25*time.Second)
+	defer cancel()
+	if !enforceEmailVerified(operationContext, responseWriter, handler.authService, userIdentifier) {
+		return
+	}
+	operation, placeError := handler.tradingService.PlaceTakeProfitForOperation(operationContext, userIdentifier, operationIdentifier)
+	if placeError != nil {
+		writeOperationErrorV2(responseWriter, placeError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toOperationPayload(*operation))
+}
+
+// writeOperationErrorV2 maps trading errors to the error envelope. The service reports rejected trades
+// (bad symbol, insufficient balance, Binance refusal...) as plain errors, so those become
+// "trade_rejected" with the service's message.
+func writeOperationErrorV2(responseWriter http.ResponseWriter, operationError error) {
+	switch {
+	case errors.Is(operationError, repository.ErrOperationNotFound):
+		writeJSONError(responseWriter, http.StatusNotFound, "Operation not found.")
+	case errors.Is(operationError, context.DeadlineExceeded):
+		writeJSONErrorCode(responseWriter, http.StatusGatewayTimeout, "The order did not complete in time; check your operations before retrying.", "trade_timeout")
+	default:
+		writeJSONErrorCode(responseWriter, http.StatusUnprocessableEntity, operationError.Error(), "trade_rejected")
+	}
+}
@@ -14,68 +14,29 @@ import (
 
 // PortfolioHandler serves the B3 portfolio endpoints, backed by the investidor10 scraper.
 type PortfolioHandler struct {
-	sessionService      *service.SessionService
 	authService         *service.AuthService
-	cookieName          string
 	portfolioRepository repository.UserPortfolioRepository
 	scraperClient       *service.PortfolioScraperClient
 }
 
-func NewPortfolioHandler(sessionService *service.SessionService, authService *service.AuthService, cookieName string, portfolioRepository repository.UserPortfolioRepository, scraperClient *service.PortfolioScraperClient) *PortfolioHandler {
+func NewPortfolioHandler(authService *service.AuthService, portfolioRepository repository.UserPortfolioRepository, scraperClient *service.PortfolioScraperClient) *PortfolioHandler {
 	return &PortfolioHandler{
-		sessionService:      sessionService,
 		authService:         authService,
-		cookieName:          cookieName,
 		portfolioRepository: portfolioRepository,
 		scraperClient:       scraperClient,
 	}
 }
 
 func (handler *PortfolioHandler) RegisterRoutes(router *http.ServeMux) {
-	router.HandleFunc("/api/v1/portfolio/source", handler.handleSource)
-	router.HandleFunc("/api/v1/portfolio/assets", handler.handleAssets)
-	router.HandleFunc("/api/v1/portfolio/dividends", handler.handleDividends)
-}
-
-func (handler *PortfolioHandler) requireUser(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
-	sessionCookie, cookieError := request.Cookie(handler.cookieName)
-	if cookieError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
-	defer cancel()
-	userIdentifier, resolveError := handler.sessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
-	if resolveError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	return userIdentifier, true
-}
-
-// requireAdminUser resolves the session and then enforces that the account is an admin. The whole
-// B3/Investidor10 feature is admin-only, so every portfolio endpoint goes through this.
-func (handler *PortfolioHandler) requireAdminUser(responseWriter http.ResponseWriter, request *http.Request) (int64, bool) {
-	userIdentifier, authenticated := handler.requireUser(responseWriter, request)
-	if !authenticated {
-		return 0, false
-	}
-	lookupContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
-	defer cancel()
-	currentUser, lookupError := handler.authService.GetUserByIdentifier(lookupContext, userIdentifier)
-	if lookupError != nil || currentUser == nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return 0, false
-	}
-	if !currentUser.IsAdmin {
-		writeJSONError(responseWriter, http.StatusForbidden, "The B3 portfolio is available to admins only.")
-		return 0, false
-	}
-	return userIdentifier, true
+	// The whole B3/Investidor10 feature is admin-only.
+	const forbiddenMessage = "The B3 portfolio is available to admins only."
+	router.Handle("/api/v1/portfolio/source", RequireAdmin(handler.authService, forbiddenMessage, http.HandlerFunc(handler.handleSource)))
+	router.Handle("/api/v1/portfolio/assets", RequireAdmin(handler.authService, forbiddenMessage, http.HandlerFunc(handler.handleAssets)))
+	router.Handle("/api/v1/portfolio/dividends", RequireAdmin(handler.authService, forbiddenMessage, http.HandlerFunc(handler.handleDividends)))
 }
 
 func (handler *PortfolioHandler) handleSource(responseWriter http.ResponseWriter, request *http.Request) {
-	userIdentifier, authenticated := handler.requireAdminUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -133,7 +94,7 @@ func (handler *PortfolioHandler) proxyScrape(responseWriter http.ResponseWriter,
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userIdentifier, authenticated := handler.requireAdminUser(responseWriter, request)
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
 	if !authenticated {
 		return
 	}
@@ -15,41 +15,35 @@ import (
 // RobotsHandler serves the per-user trading-robot endpoints. A robot is one automated bot for a
 // single coin; standard users may have one per environment, admins unlimited.
 type RobotsHandler struct {
-	sessionService *service.SessionService
-	authService    *service.AuthService
-	cookieName     string
-	robotService   *service.RobotService
+	authService  *service.AuthService
+	robotService *service.RobotService
+	// idempotencyService backs Idempotency-Key on robot creation; nil disables it.
+	idempotencyService *service.IdempotencyService
 }
 
-func NewRobotsHandler(sessionService *service.SessionService, authService *service.AuthService, cookieName string, robotService *service.RobotService) *RobotsHandler {
+func NewRobotsHandler(authService *service.AuthService, robotService *service.RobotService, idempotencyService *service.IdempotencyService) *RobotsHandler {
 	return &RobotsHandler{
-		sessionService: sessionService,
-		authService:    authService,
-		cookieName:     cookieName,
-		robotService:   robotService,
+		authService:        authService,
+		robotService:       robotService,
+		idempotencyService: idempotencyService,
 	}
 }
 
 func (handler *RobotsHandler) RegisterRoutes(router *http.ServeMux) {
-	router.HandleFunc("/api/v1/robots", handler.handleRobots)
+	router.HandleFunc("/api/v1/robots", idempotent(handler.idempotencyService, handler.handleRobots))
 	router.HandleFunc("/api/v1/robots/update", handler.handleUpdate)
 	router.HandleFunc("/api/v1/robots/delete", handler.handleDelete)
 }
 
-// resolveUser returns the authenticated user (including the is_admin flag), or writes a 401.
-func (handler *RobotsHandler) resolveUser(responseWriter http.ResponseWriter, request *http.Request) (*domain.User, bool) {
-	sessionCookie, cookieError := request.Cookie(handler.cookieName)
-	if cookieError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+// resolveUser returns the authenticated user (including the is_admin flag), or writes a 401. A
+// personal access token must carry one of acceptedScopes.
+func (handler *RobotsHandler) resolveUser(responseWriter http.ResponseWriter, request *http.Request, acceptedScopes ...string) (*domain.User, bool) {
+	userIdentifier, authenticated := authenticateRequest(responseWriter, request, acceptedScopes...)
+	if !authenticated {
 		return nil, false
 	}
 	resolveContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
 	defer cancel()
-	userIdentifier, resolveError := handler.sessionService.ResolveUserIdentifier(resolveContext, sessionCookie.Value)
-	if resolveError != nil {
-		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
-		return nil, false
-	}
 	currentUser, lookupError := handler.authService.GetUserByIdentifier(resolveContext, userIdentifier)
 	if lookupError != nil || currentUser == nil {
 		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
@@ -71,8 +65,14 @@ type robotPayload struct {
 	IsEnabled             bool     `json:"is_enabled"`
 }
 
+type robotsListPayload struct {
+	Robots  []robotPayload `json:"robots"`
+	Limit   int            `json:"limit"` // 0 = unlimited
+	IsAdmin bool           `json:"is_admin"`
+}
+
 type robotInputPayload struct {
-	ID                    int64    `json:"id"`
+	ID                    int64    `json:"id,omitempty"` // v1 update only; ignored on create
 	Symbol                string   `json:"symbol"`
 	Name                  string   `json:"name"`
 	CapitalThreshold      float64  `json:"capital_threshold"`
@@ -99,7 +99,12 @@ func (payload robotInputPayload) toServiceInput() service.RobotInput {
 }
 
 func (handler *RobotsHandler) handleRobots(responseWriter http.ResponseWriter, request *http.Request) {
-	currentUser, authenticated := handler.resolveUser(responseWriter, request)
+	// Listing robots is a read; a token that may manage robots may also see them.
+	acceptedScopes := []string{domain.TokenScopeRobotsWrite}
+	if request.Method == http.MethodGet {
+		acceptedScopes = append(acceptedScopes, domain.TokenScopeReadOperations)
+	}
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, acceptedScopes...)
 	if !authenticated {
 		return
 	}
@@ -113,19 +118,20 @@ func (handler *RobotsHandler) handleRobots(responseWriter http.ResponseWriter, r
 			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load robots.")
 			return
 		}
-		writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
-			"robots":   toRobotPayloads(robots),
-			"limit":    service.RobotLimitForAdmin(currentUser.IsAdmin), // 0 = unlimited
-			"is_admin": currentUser.IsAdmin,
+		writeJSON(responseWriter, http.StatusOK, robotsListPayload{
+			Robots:  toRobotPayloads(robots),
+			Limit:   service.RobotLimitForAdmin(currentUser.IsAdmin),
+			IsAdmin: currentUser.IsAdmin,
 		})
 
 	case http.MethodPost:
 		var payload robotInputPayload
 		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			markNotExecuted(responseWriter)
 			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
 			return
 		}
-		operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+		operationContext, cancel := context.WithTimeout(auditRequestContext(request, currentUser.Identifier), 6*time.Second)
 		defer cancel()
 		if !currentUser.IsEmailVerified() {
 			writeJSONErrorCode(responseWriter, http.StatusForbidden, "Confirm your email before using this feature.", "email_unverified")
@@ -148,7 +154,7 @@ func (handler *RobotsHandler) handleUpdate(responseWriter http.ResponseWriter, r
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	currentUser, authenticated := handler.resolveUser(responseWriter, request)
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite)
 	if !authenticated {
 		return
 	}
@@ -163,7 +169,7 @@ func (handler *RobotsHandler) handleUpdate(responseWriter http.ResponseWriter, r
 		return
 	}
 
-	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, currentUser.Identifier), 6*time.Second)
 	defer cancel()
 	if !currentUser.IsEmailVerified() {
 		writeJSONErrorCode(responseWriter, http.StatusForbidden, "Confirm your email before using this feature.", "email_unverified")
@@ -182,7 +188,7 @@ func (handler *RobotsHandler) handleDelete(responseWriter http.ResponseWriter, r
 		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	currentUser, authenticated := handler.resolveUser(responseWriter, request)
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite)
 	if !authenticated {
 		return
 	}
@@ -199,7 +205,7 @@ func (handler *RobotsHandler) handleDelete(responseWriter http.ResponseWriter, r
 		return
 	}
 
-	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, currentUser.Identifier), 6*time.Second)
 	defer cancel()
 	if deleteError := handler.robotService.DeleteRobot(operationContext, currentUser.Identifier, payload.ID); deleteError != nil {
 		handler.writeRobotError(responseWriter, deleteError)
@@ -211,9 +217,9 @@ func (handler *RobotsHandler) handleDelete(responseWriter http.ResponseWriter, r
 func (handler *RobotsHandler) writeRobotError(responseWriter http.ResponseWriter, robotError error) {
 	switch {
 	case errors.Is(robotError, service.ErrRobotLimitReached):
-		writeJSONError(responseWriter, http.StatusForbidden, robotError.Error())
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, robotError.Error(), "robot_limit_reached")
 	case errors.Is(robotError, service.ErrRobotSymbolExists):
-		writeJSONError(responseWriter, http.StatusConflict, robotError.Error())
+		writeJSONErrorCode(responseWriter, http.StatusConflict, robotError.Error(), "robot_symbol_exists")
 	case errors.Is(robotError, repository.ErrRobotNotFound):
 		writeJSONError(responseWriter, http.StatusNotFound, "Robot not found.")
 	default:
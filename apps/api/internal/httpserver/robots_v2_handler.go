@@ -0,0 +1,203 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/service"
+)
+
+// RegisterV2Routes serves robots as a REST resource: /api/v2/robots and /api/v2/robots/{id}.
+func (handler *RobotsHandler) RegisterV2Routes(router *http.ServeMux) {
+	registerMethodRoutes(router, "/api/v2/robots", map[string]http.HandlerFunc{
+		http.MethodGet:  handler.handleListRobotsV2,
+		http.MethodPost: idempotent(handler.idempotencyService, handler.handleCreateRobotV2),
+	})
+	registerMethodRoutes(router, "/api/v2/robots/{id}", map[string]http.HandlerFunc{
+		http.MethodGet:    handler.handleGetRobotV2,
+		http.MethodPatch:  handler.handlePatchRobotV2,
+		http.MethodDelete: handler.handleDeleteRobotV2,
+	})
+}
+
+// optionalFloat tells an absent JSON field apart from an explicit null, so PATCH can clear a value.
+type optionalFloat struct {
+	IsSet bool
+	Value *float64
+}
+
+func (field *optionalFloat) UnmarshalJSON(data []byte) error {
+	field.IsSet = true
+	return json.Unmarshal(data, &field.Value)
+}
+
+// robotPatchPayload holds the fields a PATCH may change; absent fields keep their value. The coin is
+// immutable, so symbol is not accepted.
+type robotPatchPayload struct {
+	Name                  *string       `json:"name,omitempty"`
+	CapitalThreshold      *float64      `json:"capital_threshold,omitempty"`
+	TargetProfitPercent   *float64      `json:"target_profit_percent,omitempty"`
+	StopLossPercent       optionalFloat `json:"stop_loss_percent,omitempty"`
+	DailyPurchaseHourUTC  *int          `json:"daily_purchase_hour_utc,omitempty"`
+	DailyPurchaseEnabled  *bool         `json:"daily_purchase_enabled,omitempty"`
+	SellOrderValidityDays *int          `json:"sell_order_validity_days,omitempty"`
+	IsEnabled             *bool         `json:"is_enabled,omitempty"`
+}
+
+func (payload robotPatchPayload) applyTo(robot domain.TradingRobot) service.RobotInput {
+	input := service.RobotInput{
+		TradingPairSymbol:     robot.TradingPairSymbol,
+		Name:                  robot.Name,
+		CapitalThreshold:      robot.CapitalThreshold,
+		TargetProfitPercent:   robot.TargetProfitPercent,
+		StopLossPercent:       robot.StopLossPercent,
+		DailyPurchaseHourUTC:  robot.DailyPurchaseHourUTC,
+		DailyPurchaseEnabled:  robot.DailyPurchaseEnabled,
+		SellOrderValidityDays: robot.SellOrderValidityDays,
+		IsEnabled:             robot.IsEnabled,
+	}
+	if payload.Name != nil {
+		input.Name = *payload.Name
+	}
+	if payload.CapitalThreshold != nil {
+		input.CapitalThreshold = *payload.CapitalThreshold
+	}
+	if payload.TargetProfitPercent != nil {
+		input.TargetProfitPercent = *payload.TargetProfitPercent
+	}
+	if payload.StopLossPercent.IsSet {
+		input.StopLossPercent = payload.StopLossPercent.Value
+	}
+	if payload.DailyPurchaseHourUTC != nil {
+		input.DailyPurchaseHourUTC = *payload.DailyPurchaseHourUTC
+	}
+	if payload.DailyPurchaseEnabled != nil {
+		input.DailyPurchaseEnabled = *payload.DailyPurchaseEnabled
+	}
+	if payload.SellOrderValidityDays != nil {
+		input.SellOrderValidityDays = *payload.SellOrderValidityDays
+	}
+	if payload.IsEnabled != nil {
+		input.IsEnabled = *payload.IsEnabled
+	}
+	return input
+}
+
+func (handler *RobotsHandler) handleListRobotsV2(responseWriter http.ResponseWriter, request *http.Request) {
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite, domain.TokenScopeReadOperations)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	robots, listError := handler.robotService.ListRobots(operationContext, currentUser.Identifier)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load robots.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, robotsListPayload{
+		Robots:  toRobotPayloads(robots),
+		Limit:   service.RobotLimitForAdmin(currentUser.IsAdmin),
+		IsAdmin: currentUser.IsAdmin,
+	})
+}
+
+func (handler *RobotsHandler) handleGetRobotV2(responseWriter http.ResponseWriter, request *http.Request) {
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite, domain.TokenScopeReadOperations)
+	if !authenticated {
+		return
+	}
+	robotIdentifier, validIdentifier := pathIdentifier(responseWriter, request)
+	if !validIdentifier {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	robot, lookupError := handler.robotService.GetRobot(operationContext, currentUser.Identifier, robotIdentifier)
+	if lookupError != nil {
+		handler.writeRobotError(responseWriter, lookupError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toRobotPayload(*robot))
+}
+
+func (handler *RobotsHandler) handleCreateRobotV2(responseWriter http.ResponseWriter, request *http.Request) {
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite)
+	if !authenticated {
+		return
+	}
+	var payload robotInputPayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		markNotExecuted(responseWriter)
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	if !currentUser.IsEmailVerified() {
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, "Confirm your email before using this feature.", "email_unverified")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, currentUser.Identifier), 6*time.Second)
+	defer cancel()
+	robot, createError := handler.robotService.CreateRobot(operationContext, currentUser.Identifier, currentUser.IsAdmin, payload.toServiceInput())
+	if createError != nil {
+		handler.writeRobotError(responseWriter, createError)
+		return
+	}
+	responseWriter.Header().Set("Location", "/api/v2/robots/"+strconv.FormatInt(robot.Identifier, 10))
+	writeJSON(responseWriter, http.StatusCreated, toRobotPayload(*robot))
+}
+
+func (handler *RobotsHandler) handlePatchRobotV2(responseWriter http.ResponseWriter, request *http.Request) {
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite)
+	if !authenticated {
+		return
+	}
+	robotIdentifier, validIdentifier := pathIdentifier(responseWriter, request)
+	if !validIdentifier {
+		return
+	}
+	var payload robotPatchPayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	if !currentUser.IsEmailVerified() {
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, "Confirm your email before using this feature.", "email_unverified")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, currentUser.Identifier), 6*time.Second)
+	defer cancel()
+	existing, lookupError := handler.robotService.GetRobot(operationContext, currentUser.Identifier, robotIdentifier)
+	if lookupError != nil {
+		handler.writeRobotError(responseWriter, lookupError)
+		return
+	}
+	robot, updateError := handler.robotService.UpdateRobot(operationContext, currentUser.Identifier, robotIdentifier, payload.applyTo(*existing))
+	if updateError != nil {
+		handler.writeRobotError(responseWriter, updateError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, toRobotPayload(*robot))
+}
+
+func (handler *RobotsHandler) handleDeleteRobotV2(responseWriter http.ResponseWriter, request *http.Request) {
+	currentUser, authenticated := handler.resolveUser(responseWriter, request, domain.TokenScopeRobotsWrite)
+	if !authenticated {
+		return
+	}
+	robotIdentifier, validIdentifier := pathIdentifier(responseWriter, request)
+	if !validIdentifier {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, currentUser.Identifier), 6*time.Second)
+	defer cancel()
+	if deleteError := handler.robotService.DeleteRobot(operationContext, currentUser.Identifier, robotIdentifier); deleteError != nil {
+		handler.writeRobotError(responseWriter, deleteError)
+		return
+	}
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
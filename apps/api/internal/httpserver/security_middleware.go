@@ -0,0 +1,130 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginPolicy is the allowlist of browser origins (scheme://host[:port]) that may call the API with
+// the session cookie from another origin. Same-origin requests are always allowed.
+type OriginPolicy struct {
+	allowedOrigins map[string]bool
+}
+
+func NewOriginPolicy(origins []string) *OriginPolicy {
+	policy := &OriginPolicy{allowedOrigins: make(map[string]bool)}
+	for _, origin := range origins {
+		if normalizedOrigin := normalizeOrigin(origin); normalizedOrigin != "" {
+			policy.allowedOrigins[normalizedOrigin] = true
+		}
+	}
+	return policy
+}
+
+// Allows reports whether origin is on the allowlist.
+func (policy *OriginPolicy) Allows(origin string) bool {
+	normalizedOrigin := normalizeOrigin(origin)
+	return normalizedOrigin != "" && policy.allowedOrigins[normalizedOrigin]
+}
+
+// allowsRequestFrom reports whether a request from origin is same-origin or allowlisted.
+func (policy *OriginPolicy) allowsRequestFrom(request *http.Request, origin string) bool {
+	parsedOrigin, parseError := url.Parse(origin)
+	if parseError == nil && parsedOrigin.Host != "" && strings.EqualFold(parsedOrigin.Host, request.Host) {
+		return true
+	}
+	return policy.Allows(origin)
+}
+
+func normalizeOrigin(origin string) string {
+	parsedOrigin, parseError := url.Parse(strings.TrimSpace(origin))
+	if parseError != nil || parsedOrigin.Scheme == "" || parsedOrigin.Host == "" {
+		return ""
+	}
+	return strings.ToLower(parsedOrigin.Scheme + "://" + parsedOrigin.Host)
+}
+
+// SecurityHeadersMiddleware sets the browser hardening headers on every response. The API only
+// serves JSON (and redirects), so the CSP forbids loading or framing anything. HSTS is only sent over
+// HTTPS, directly or behind a TLS-terminating proxy.
+func SecurityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		headers := responseWriter.Header()
+		headers.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'")
+		headers.Set("X-Frame-Options", "DENY")
+		headers.Set("X-Content-Type-Options", "nosniff")
+		headers.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if request.TLS != nil || strings.EqualFold(request.Header.Get("X-Forwarded-Proto"), "https") {
+			headers.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+// CORSMiddleware lets the allowlisted web origins call the API with credentials and answers their
+// preflight requests. Other origins get no CORS headers, so browsers keep blocking them.
+func CORSMiddleware(policy *OriginPolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		origin := request.Header.Get("Origin")
+		headers := responseWriter.Header()
+		headers.Add("Vary", "Origin")
+		if origin == "" || !policy.Allows(origin) {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		headers.Set("Access-Control-Allow-Origin", origin)
+		headers.Set("Access-Control-Allow-Credentials", "true")
+		headers.Set("Access-Control-Expose-Headers", "Location, Idempotent-Replayed")
+		if request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != "" {
+			headers.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+			headers.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key")
+			headers.Set("Access-Control-Max-Age", "600")
+			responseWriter.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+// CSRFMiddleware refuses cookie-authenticated mutations that a browser sent from a foreign origin.
+// It trusts, in order: bearer-token requests (a browser never attaches those on its own), the
+// Sec-Fetch-Site header, then the Origin header or, failing that, the Referer's origin. Requests with
+// none of them do not come from a browser and pass. Must run after BearerTokenMiddleware.
+func CSRFMiddleware(policy *OriginPolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		principal := PrincipalFromContext(request.Context())
+		if isSafeMethod(request.Method) || (principal != nil && principal.IsAccessToken()) {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		switch request.Header.Get("Sec-Fetch-Site") {
+		case "same-origin", "none":
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		origin := request.Header.Get("Origin")
+		if origin == "" {
+			if referer := request.Header.Get("Referer"); referer != "" {
+				origin = normalizeOrigin(referer)
+				if origin == "" {
+					origin = "null"
+				}
+			}
+		}
+		if origin == "" || (origin != "null" && policy.allowsRequestFrom(request, origin)) {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, "Cross-origin request refused.", "csrf_origin_rejected")
+	})
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
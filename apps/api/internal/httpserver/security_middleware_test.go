@@ -0,0 +1,72 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCSRFMiddleware checks which cookie-authenticated mutations reach the handler.
+func TestCSRFMiddleware(t *testing.T) {
+	policy := NewOriginPolicy([]string{"https://app.example.com"})
+	handler := CSRFMiddleware(policy, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		bearer   bool
+		expected int
+	}{
+		{name: "safe method", method: http.MethodGet, headers: map[string]string{"Origin": "https://evil.example"}, expected: http.StatusNoContent},
+		{name: "same origin", method: http.MethodPost, headers: map[string]string{"Origin": "https://api.example.com"}, expected: http.StatusNoContent},
+		{name: "allowlisted origin", method: http.MethodPost, headers: map[string]string{"Origin": "https://APP.example.com"}, expected: http.StatusNoContent},
+		{name: "foreign origin", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example"}, expected: http.StatusForbidden},
+		{name: "opaque origin", method: http.MethodDelete, headers: map[string]string{"Origin": "null"}, expected: http.StatusForbidden},
+		{name: "foreign referer", method: http.MethodPut, headers: map[string]string{"Referer": "https://evil.example/page"}, expected: http.StatusForbidden},
+		{name: "same-origin fetch metadata", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "https://evil.example"}, expected: http.StatusNoContent},
+		{name: "cross-site fetch metadata", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, expected: http.StatusForbidden},
+		{name: "non-browser client", method: http.MethodPost, expected: http.StatusNoContent},
+		{name: "bearer token", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example"}, bearer: true, expected: http.StatusNoContent},
+	}
+	for _, testCase := range cases {
+		request := httptest.NewRequest(testCase.method, "https://api.example.com/api/v1/operations", nil)
+		for name, value := range testCase.headers {
+			request.Header.Set(name, value)
+		}
+		if testCase.bearer {
+			request = request.WithContext(context.WithValue(request.Context(), principalContextKey{}, &Principal{UserIdentifier: 1, TokenIdentifier: 7}))
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != testCase.expected {
+			t.Errorf("%s: expected status %d, got %d", testCase.name, testCase.expected, recorder.Code)
+		}
+	}
+}
+
+func TestCORSMiddlewarePreflight(t *testing.T) {
+	policy := NewOriginPolicy([]string{"https://app.example.com"})
+	handler := CORSMiddleware(policy, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+
+	request := httptest.NewRequest(http.MethodOptions, "https://api.example.com/api/v1/robots", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent || recorder.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Fatalf("allowlisted preflight: got %d, allow-origin %q", recorder.Code, recorder.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	request.Header.Set("Origin", "https://evil.example")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("foreign preflight must not get CORS headers")
+	}
+}
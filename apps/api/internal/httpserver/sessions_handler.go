@@ -0,0 +1,172 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"coin-alert/internal/service"
+)
+
+// SessionsHandler lists the signed-in user's sessions (devices), revokes them, and toggles the
+// new-device sign-in email.
+type SessionsHandler struct {
+	sessionDeviceService *service.SessionDeviceService
+}
+
+func NewSessionsHandler(sessionDeviceService *service.SessionDeviceService) *SessionsHandler {
+	return &SessionsHandler{
+		sessionDeviceService: sessionDeviceService,
+	}
+}
+
+func (handler *SessionsHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("/api/v1/account/sessions", handler.handleSessions)
+	router.HandleFunc("/api/v1/account/sessions/revoke", handler.handleRevoke)
+	router.HandleFunc("/api/v1/account/sessions/revoke-others", handler.handleRevokeOthers)
+	router.HandleFunc("/api/v1/account/sessions/alerts", handler.handleAlerts)
+}
+
+// requireUser returns the user and the raw session token, which identifies the current session.
+func (handler *SessionsHandler) requireUser(responseWriter http.ResponseWriter, request *http.Request) (int64, string, bool) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return 0, "", false
+	}
+	return userIdentifier, PrincipalFromContext(request.Context()).SessionToken, true
+}
+
+type activeSessionPayload struct {
+	ID              int64     `json:"id"`
+	Browser         string    `json:"browser"`
+	OperatingSystem string    `json:"operating_system"`
+	DeviceType      string    `json:"device_type"`
+	IPAddress       string    `json:"ip_address"`
+	Location        string    `json:"location"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	Current         bool      `json:"current"`
+}
+
+func (handler *SessionsHandler) handleSessions(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, rawToken, authenticated := handler.requireUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	sessions, listError := handler.sessionDeviceService.ListSessions(operationContext, userIdentifier, rawToken)
+	if listError != nil {
+		httpLogger.ErrorContext(request.Context(), "could not list sessions", "error", listError)
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load your sessions.")
+		return
+	}
+	alertsEnabled, alertsError := handler.sessionDeviceService.NewDeviceAlertsEnabled(operationContext, userIdentifier)
+	if alertsError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load your sessions.")
+		return
+	}
+	payloads := make([]activeSessionPayload, 0, len(sessions))
+	for _, session := range sessions {
+		payloads = append(payloads, activeSessionPayload{
+			ID:              session.Identifier,
+			Browser:         session.Device.Browser,
+			OperatingSystem: session.Device.OperatingSystem,
+			DeviceType:      session.Device.DeviceType,
+			IPAddress:       session.IPAddress,
+			Location:        session.Location,
+			CreatedAt:       session.CreatedAt,
+			LastSeenAt:      session.LastSeenAt,
+			ExpiresAt:       session.ExpiresAt,
+			Current:         session.IsCurrent,
+		})
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+		"sessions":          payloads,
+		"new_device_alerts": alertsEnabled,
+	})
+}
+
+func (handler *SessionsHandler) handleRevoke(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, rawToken, authenticated := handler.requireUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A session id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 6*time.Second)
+	defer cancel()
+	revokeError := handler.sessionDeviceService.RevokeSession(operationContext, userIdentifier, payload.ID, rawToken)
+	switch {
+	case revokeError == nil:
+		writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Session signed out."})
+	case errors.Is(revokeError, service.ErrSessionToRevokeNotFound):
+		writeJSONError(responseWriter, http.StatusNotFound, "Session not found.")
+	case errors.Is(revokeError, service.ErrCannotRevokeCurrentSession):
+		writeJSONError(responseWriter, http.StatusBadRequest, revokeError.Error())
+	default:
+		httpLogger.ErrorContext(request.Context(), "could not revoke a session", "session_id", payload.ID, "error", revokeError)
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not sign the session out.")
+	}
+}
+
+func (handler *SessionsHandler) handleRevokeOthers(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, rawToken, authenticated := handler.requireUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 6*time.Second)
+	defer cancel()
+	revokedCount, revokeError := handler.sessionDeviceService.RevokeOtherSessions(operationContext, userIdentifier, rawToken)
+	if revokeError != nil {
+		httpLogger.ErrorContext(request.Context(), "could not revoke the other sessions", "error", revokeError)
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not sign the other sessions out.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]int64{"revoked": revokedCount})
+}
+
+func (handler *SessionsHandler) handleAlerts(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPut {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, _, authenticated := handler.requireUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	var payload struct {
+		Enabled bool `json:"enabled"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	if updateError := handler.sessionDeviceService.SetNewDeviceAlertsEnabled(operationContext, userIdentifier, payload.Enabled); updateError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the setting.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]bool{"new_device_alerts": payload.Enabled})
+}
@@ -0,0 +1,35 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"coin-alert/internal/service"
+)
+
+// enforceStepUp writes 403 with code "step_up_required" unless the current session recently
+// confirmed the user's password or 2FA code. Used in front of sensitive actions; the SPA answers the
+// code by calling POST /api/v1/account/step-up and retrying.
+func enforceStepUp(responseWriter http.ResponseWriter, request *http.Request, sessionService *service.SessionService) bool {
+	principal := PrincipalFromContext(request.Context())
+	if principal == nil || principal.SessionToken == "" {
+		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+		return false
+	}
+	lookupContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+	defer cancel()
+	stepUpError := sessionService.RequireRecentAuthentication(lookupContext, principal.SessionToken)
+	switch {
+	case stepUpError == nil:
+		return true
+	case errors.Is(stepUpError, service.ErrStepUpRequired):
+		writeJSONErrorCode(responseWriter, http.StatusForbidden, stepUpError.Error(), "step_up_required")
+	case errors.Is(stepUpError, service.ErrSessionNotFound):
+		writeJSONError(responseWriter, http.StatusUnauthorized, "Not authenticated.")
+	default:
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not verify your session.")
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin-alert/internal/service"
+)
+
+// streamHeartbeatInterval keeps proxies from closing an idle stream and lets the server notice a
+// client that went away.
+const streamHeartbeatInterval = 15 * time.Second
+
+// StreamPath is the SSE route; it stays open for as long as the dashboard does, so it is exempt from
+// the request timeout.
+const StreamPath = "/api/v1/stream"
+
+// StreamHandler serves the dashboard's live updates as Server-Sent Events.
+type StreamHandler struct {
+	liveStreamService *service.LiveStreamService
+}
+
+func NewStreamHandler(liveStreamService *service.LiveStreamService) *StreamHandler {
+	return &StreamHandler{
+		liveStreamService: liveStreamService,
+	}
+}
+
+func (handler *StreamHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc(StreamPath, handler.handleStream)
+}
+
+// handleStream keeps the response open and writes one SSE message per live event. A reconnecting
+// EventSource sends Last-Event-ID and first receives the events it missed; `last_event_id` in the
+// query string does the same for clients that cannot set headers.
+func (handler *StreamHandler) handleStream(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	flusher, canFlush := responseWriter.(http.Flusher)
+	if !canFlush {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Streaming is not supported.")
+		return
+	}
+
+	lastEventIdentifier := parseLastEventIdentifier(request)
+	backlog, liveEvents, unsubscribe, subscribeError := handler.liveStreamService.Subscribe(userIdentifier, lastEventIdentifier)
+	if subscribeError != nil {
+		writeJSONErrorCode(responseWriter, http.StatusTooManyRequests, "Too many live updates are open for this account; close another tab and reload.", "too_many_streams")
+		return
+	}
+	defer unsubscribe()
+
+	responseWriter.Header().Set("Content-Type", "text/event-stream")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	responseWriter.Header().Set("Connection", "keep-alive")
+	// nginx buffers proxied responses by default, which would hold the events back.
+	responseWriter.Header().Set("X-Accel-Buffering", "no")
+	responseWriter.WriteHeader(http.StatusOK)
+	fmt.Fprint(responseWriter, "retry: 3000\n\n")
+	for _, event := range backlog {
+		writeServerSentEvent(responseWriter, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(responseWriter, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, open := <-liveEvents:
+			if !open {
+				// Fell too far behind; the client reconnects and resumes from its Last-Event-ID.
+				return
+			}
+			writeServerSentEvent(responseWriter, event)
+			flusher.Flush()
+		}
+	}
+}
+
+func parseLastEventIdentifier(request *http.Request) int64 {
+	rawValue := strings.TrimSpace(request.Header.Get("Last-Event-ID"))
+	if rawValue == "" {
+		rawValue = strings.TrimSpace(request.URL.Query().Get("last_event_id"))
+	}
+	lastEventIdentifier, parseError := strconv.ParseInt(rawValue, 10, 64)
+	if parseError != nil || lastEventIdentifier < 0 {
+		return 0
+	}
+	return lastEventIdentifier
+}
+
+func writeServerSentEvent(responseWriter http.ResponseWriter, event service.LiveEvent) {
+	fmt.Fprintf(responseWriter, "id: %d\nevent: %s\ndata: %s\n\n", event.Identifier, event.Type, event.Data)
+}
@@ -0,0 +1,51 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"coin-alert/internal/service"
+)
+
+func TestStreamReplaysMissedEventsAndCapsConnections(t *testing.T) {
+	liveStreamService := service.NewLiveStreamService(nil, nil, nil)
+	liveStreamService.Publish(1, service.LiveEventOperation, map[string]int{"id": 1})
+	liveStreamService.Publish(1, service.LiveEventPrice, map[string]string{"symbol": "BTCUSDT"})
+	liveStreamService.Publish(1, service.LiveEventExecution, map[string]int{"id": 2})
+	backlog, _, unsubscribe, _ := liveStreamService.Subscribe(1, 1)
+	unsubscribe()
+	handler := NewStreamHandler(liveStreamService)
+
+	// A cancelled request writes the backlog and returns instead of waiting for live events.
+	streamRequest := func(lastEventIdentifier string) *httptest.ResponseRecorder {
+		requestContext, cancel := context.WithCancel(context.WithValue(context.Background(), principalContextKey{}, &Principal{UserIdentifier: 1, SessionToken: "session"}))
+		cancel()
+		request := httptest.NewRequest(http.MethodGet, StreamPath, nil).WithContext(requestContext)
+		if lastEventIdentifier != "" {
+			request.Header.Set("Last-Event-ID", lastEventIdentifier)
+		}
+		recorder := httptest.NewRecorder()
+		handler.handleStream(recorder, request)
+		return recorder
+	}
+
+	resumed := streamRequest(strconv.FormatInt(backlog[0].Identifier, 10))
+	if resumed.Code != http.StatusOK || !strings.Contains(resumed.Body.String(), "event: execution\n") || strings.Contains(resumed.Body.String(), "event: operation\n") || strings.Contains(resumed.Body.String(), "event: price\n") {
+		t.Errorf("expected only the missed execution to be replayed, got %d %q", resumed.Code, resumed.Body.String())
+	}
+
+	// Fill the user's connections up to the service's limit.
+	for attempt := 0; attempt < 100; attempt++ {
+		if _, _, _, subscribeError := liveStreamService.Subscribe(1, 0); subscribeError != nil {
+			break
+		}
+	}
+	refused := streamRequest("")
+	if refused.Code != http.StatusTooManyRequests || !strings.Contains(refused.Body.String(), "too_many_streams") {
+		t.Errorf("expected 429 too_many_streams past the limit, got %d %q", refused.Code, refused.Body.String())
+	}
+}
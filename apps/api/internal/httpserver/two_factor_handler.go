@@ -0,0 +1,182 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"coin-alert/internal/service"
+)
+
+// TwoFactorHandler serves TOTP enrollment, disabling and recovery codes for the signed-in user. The
+// login step itself lives in AuthHandler.
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+func (handler *TwoFactorHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("/api/v1/account/two-factor", handler.handleStatus)
+	router.HandleFunc("/api/v1/account/two-factor/enroll", handler.handleEnroll)
+	router.HandleFunc("/api/v1/account/two-factor/confirm", handler.handleConfirm)
+	router.HandleFunc("/api/v1/account/two-factor/disable", handler.handleDisable)
+	router.HandleFunc("/api/v1/account/two-factor/recovery-codes", handler.handleRecoveryCodes)
+}
+
+type twoFactorStatusPayload struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RemainingRecoveryCodes int        `json:"remaining_recovery_codes"`
+}
+
+type twoFactorCodePayload struct {
+	Code string `json:"code"`
+}
+
+func (handler *TwoFactorHandler) handleStatus(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+	defer cancel()
+	status, statusError := handler.twoFactorService.GetStatus(operationContext, userIdentifier)
+	if statusError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load your two-factor settings.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, twoFactorStatusPayload{
+		Enabled:                status.Enabled,
+		EnabledAt:              status.EnabledAt,
+		RemainingRecoveryCodes: status.RemainingRecoveryCodes,
+	})
+}
+
+func (handler *TwoFactorHandler) handleEnroll(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 5*time.Second)
+	defer cancel()
+	enrollment, enrollError := handler.twoFactorService.BeginEnrollment(operationContext, userIdentifier)
+	if enrollError != nil {
+		writeTwoFactorError(responseWriter, enrollError, "Could not start the two-factor setup.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{
+		"secret":           enrollment.Secret,
+		"provisioning_uri": enrollment.ProvisioningURI,
+	})
+}
+
+func (handler *TwoFactorHandler) handleConfirm(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	var payload twoFactorCodePayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 5*time.Second)
+	defer cancel()
+	recoveryCodes, confirmError := handler.twoFactorService.ConfirmEnrollment(operationContext, userIdentifier, payload.Code)
+	if confirmError != nil {
+		writeTwoFactorError(responseWriter, confirmError, "Could not enable two-factor authentication.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string][]string{"recovery_codes": recoveryCodes})
+}
+
+func (handler *TwoFactorHandler) handleDisable(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	var payload twoFactorCodePayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 5*time.Second)
+	defer cancel()
+	if disableError := handler.twoFactorService.Disable(operationContext, userIdentifier, payload.Code); disableError != nil {
+		writeTwoFactorError(responseWriter, disableError, "Could not disable two-factor authentication.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Two-factor authentication disabled."})
+}
+
+func (handler *TwoFactorHandler) handleRecoveryCodes(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+	var payload twoFactorCodePayload
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+		writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(auditRequestContext(request, userIdentifier), 5*time.Second)
+	defer cancel()
+	recoveryCodes, regenerateError := handler.twoFactorService.RegenerateRecoveryCodes(operationContext, userIdentifier, payload.Code)
+	if regenerateError != nil {
+		writeTwoFactorError(responseWriter, regenerateError, "Could not create new recovery codes.")
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string][]string{"recovery_codes": recoveryCodes})
+}
+
+// writeTwoFactorError maps the 2FA service errors to responses; anything else is logged and answered
+// with fallbackMessage.
+func writeTwoFactorError(responseWriter http.ResponseWriter, twoFactorError error, fallbackMessage string) {
+	var rateLimitedError *service.RateLimitedError
+	switch {
+	case errors.As(twoFactorError, &rateLimitedError):
+		writeRateLimited(responseWriter, rateLimitedError)
+	case errors.Is(twoFactorError, service.ErrInvalidTwoFactorCode):
+		writeJSONErrorCode(responseWriter, http.StatusBadRequest, twoFactorError.Error(), "invalid_two_factor_code")
+	case errors.Is(twoFactorError, service.ErrTwoFactorChallengeFailed):
+		writeJSONErrorCode(responseWriter, http.StatusUnauthorized, twoFactorError.Error(), "two_factor_challenge_expired")
+	case errors.Is(twoFactorError, service.ErrTwoFactorAlreadyEnabled),
+		errors.Is(twoFactorError, service.ErrTwoFactorNotEnabled),
+		errors.Is(twoFactorError, service.ErrTwoFactorNotEnrolling):
+		writeJSONError(responseWriter, http.StatusConflict, twoFactorError.Error())
+	case errors.Is(twoFactorError, service.ErrLiveTradingNeedsTwoFactor):
+		writeJSONErrorCode(responseWriter, http.StatusConflict, twoFactorError.Error(), "live_trading_requires_two_factor")
+	case errors.Is(twoFactorError, service.ErrTwoFactorUnavailable):
+		writeJSONError(responseWriter, http.StatusServiceUnavailable, twoFactorError.Error())
+	default:
+		httpLogger.Error("two-factor request failed", "error", twoFactorError)
+		writeJSONError(responseWriter, http.StatusInternalServerError, fallbackMessage)
+	}
+}
@@ -0,0 +1,238 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/service"
+)
+
+// WebhooksHandler serves the outgoing-webhook endpoints: register/list/delete endpoints, rotate a
+// signing secret, and the delivery history.
+type WebhooksHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhooksHandler(webhookService *service.WebhookService) *WebhooksHandler {
+	return &WebhooksHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (handler *WebhooksHandler) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("/api/v1/webhooks", handler.handleEndpoints)
+	router.HandleFunc("/api/v1/webhooks/delete", handler.handleDeleteEndpoint)
+	router.HandleFunc("/api/v1/webhooks/rotate-secret", handler.handleRotateSecret)
+	router.HandleFunc("/api/v1/webhooks/deliveries", handler.handleDeliveries)
+}
+
+type webhookEndpointPayload struct {
+	ID            int64     `json:"id"`
+	URL           string    `json:"url"`
+	Description   string    `json:"description"`
+	EventTypes    []string  `json:"event_types"`
+	IsEnabled     bool      `json:"is_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	SigningSecret string    `json:"signing_secret,omitempty"` // only on creation / rotation
+}
+
+type webhookEndpointInputPayload struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"event_types"`
+}
+
+type webhookDeliveryPayload struct {
+	ID             int64      `json:"id"`
+	EndpointID     int64      `json:"endpoint_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	AttemptCount   int        `json:"attempt_count"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      *string    `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+func (handler *WebhooksHandler) handleEndpoints(responseWriter http.ResponseWriter, request *http.Request) {
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+		defer cancel()
+		endpoints, listError := handler.webhookService.ListEndpoints(operationContext, userIdentifier)
+		if listError != nil {
+			writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load webhooks.")
+			return
+		}
+		payloads := make([]webhookEndpointPayload, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			payloads = append(payloads, toWebhookEndpointPayload(endpoint))
+		}
+		writeJSON(responseWriter, http.StatusOK, map[string]interface{}{
+			"endpoints":   payloads,
+			"event_types": domain.WebhookEventTypes,
+		})
+
+	case http.MethodPost:
+		var payload webhookEndpointInputPayload
+		if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil {
+			writeJSONError(responseWriter, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+		operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+		defer cancel()
+		endpoint, signingSecret, createError := handler.webhookService.CreateEndpoint(operationContext, userIdentifier, payload.URL, payload.Description, payload.EventTypes)
+		if createError != nil {
+			handler.writeWebhookError(responseWriter, createError)
+			return
+		}
+		endpointPayload := toWebhookEndpointPayload(*endpoint)
+		endpointPayload.SigningSecret = signingSecret
+		writeJSON(responseWriter, http.StatusOK, endpointPayload)
+
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (handler *WebhooksHandler) handleDeleteEndpoint(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A webhook id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	if deleteError := handler.webhookService.DeleteEndpoint(operationContext, userIdentifier, payload.ID); deleteError != nil {
+		handler.writeWebhookError(responseWriter, deleteError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"message": "Webhook deleted."})
+}
+
+func (handler *WebhooksHandler) handleRotateSecret(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if decodeError := json.NewDecoder(request.Body).Decode(&payload); decodeError != nil || payload.ID <= 0 {
+		writeJSONError(responseWriter, http.StatusBadRequest, "A webhook id is required.")
+		return
+	}
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	signingSecret, rotateError := handler.webhookService.RotateSecret(operationContext, userIdentifier, payload.ID)
+	if rotateError != nil {
+		handler.writeWebhookError(responseWriter, rotateError)
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, map[string]string{"signing_secret": signingSecret})
+}
+
+func (handler *WebhooksHandler) handleDeliveries(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	userIdentifier, authenticated := requireSessionUser(responseWriter, request)
+	if !authenticated {
+		return
+	}
+
+	endpointIdentifier, _ := strconv.ParseInt(request.URL.Query().Get("endpoint_id"), 10, 64)
+	limit, _ := strconv.Atoi(request.URL.Query().Get("limit"))
+	operationContext, cancel := context.WithTimeout(request.Context(), 6*time.Second)
+	defer cancel()
+	deliveries, listError := handler.webhookService.ListDeliveries(operationContext, userIdentifier, endpointIdentifier, limit)
+	if listError != nil {
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not load the delivery history.")
+		return
+	}
+	payloads := make([]webhookDeliveryPayload, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		payloads = append(payloads, toWebhookDeliveryPayload(delivery))
+	}
+	writeJSON(responseWriter, http.StatusOK, payloads)
+}
+
+func (handler *WebhooksHandler) writeWebhookError(responseWriter http.ResponseWriter, webhookError error) {
+	switch {
+	case errors.Is(webhookError, repository.ErrWebhookEndpointNotFound):
+		writeJSONError(responseWriter, http.StatusNotFound, "Webhook not found.")
+	case errors.Is(webhookError, service.ErrWebhookEndpointLimitReached):
+		writeJSONError(responseWriter, http.StatusForbidden, webhookError.Error())
+	case errors.Is(webhookError, service.ErrWebhookURLInvalid), errors.Is(webhookError, service.ErrWebhookEventTypeInvalid):
+		writeJSONError(responseWriter, http.StatusBadRequest, webhookError.Error())
+	case errors.Is(webhookError, service.ErrCredentialEncryptionUnavailable):
+		writeJSONError(responseWriter, http.StatusServiceUnavailable, "Webhooks are unavailable: the server has no encryption key configured.")
+	default:
+		writeJSONError(responseWriter, http.StatusInternalServerError, "Could not save the webhook.")
+	}
+}
+
+func toWebhookEndpointPayload(endpoint domain.WebhookEndpoint) webhookEndpointPayload {
+	eventTypes := endpoint.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return webhookEndpointPayload{
+		ID:          endpoint.Identifier,
+		URL:         endpoint.URL,
+		Description: endpoint.Description,
+		EventTypes:  eventTypes,
+		IsEnabled:   endpoint.IsEnabled,
+		CreatedAt:   endpoint.CreatedAt,
+	}
+}
+
+func toWebhookDeliveryPayload(delivery domain.WebhookDelivery) webhookDeliveryPayload {
+	payload := webhookDeliveryPayload{
+		ID:             delivery.Identifier,
+		EndpointID:     delivery.EndpointIdentifier,
+		EventID:        delivery.EventIdentifier,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		AttemptCount:   delivery.AttemptCount,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	if delivery.Status == domain.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		payload.NextAttemptAt = &nextAttemptAt
+	}
+	return payload
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// correlation identifies what a log line is about. It travels in the context, so every line logged
+// with a *Context method while handling a request or a robot carries the same identifiers.
+type correlation struct {
+	requestIdentifier   string
+	userIdentifier      int64
+	robotIdentifier     int64
+	operationIdentifier int64
+}
+
+type correlationContextKey struct{}
+
+func correlationFrom(parentContext context.Context) correlation {
+	if parentContext == nil {
+		return correlation{}
+	}
+	fields, _ := parentContext.Value(correlationContextKey{}).(correlation)
+	return fields
+}
+
+func withCorrelation(parentContext context.Context, update func(*correlation)) context.Context {
+	fields := correlationFrom(parentContext)
+	update(&fields)
+	return context.WithValue(parentContext, correlationContextKey{}, fields)
+}
+
+// WithRequestID attaches the HTTP request identifier (request_id) to log lines.
+func WithRequestID(parentContext context.Context, requestIdentifier string) context.Context {
+	return withCorrelation(parentContext, func(fields *correlation) { fields.requestIdentifier = requestIdentifier })
+}
+
+// WithUserID attaches the user the work is done for (user_id) to log lines.
+func WithUserID(parentContext context.Context, userIdentifier int64) context.Context {
+	return withCorrelation(parentContext, func(fields *correlation) { fields.userIdentifier = userIdentifier })
+}
+
+// WithRobotID attaches the robot being run (robot_id) to log lines.
+func WithRobotID(parentContext context.Context, robotIdentifier int64) context.Context {
+	return withCorrelation(parentContext, func(fields *correlation) { fields.robotIdentifier = robotIdentifier })
+}
+
+// WithOperationID attaches the trading operation being handled (operation_id) to log lines.
+func WithOperationID(parentContext context.Context, operationIdentifier int64) context.Context {
+	return withCorrelation(parentContext, func(fields *correlation) { fields.operationIdentifier = operationIdentifier })
+}
+
+// correlationAttributes lists the identifiers known in the context; unknown ones are left out.
+func correlationAttributes(handlerContext context.Context) []slog.Attr {
+	fields := correlationFrom(handlerContext)
+	attributes := make([]slog.Attr, 0, 4)
+	if fields.requestIdentifier != "" {
+		attributes = append(attributes, slog.String("request_id", fields.requestIdentifier))
+	}
+	if fields.userIdentifier != 0 {
+		attributes = append(attributes, slog.Int64("user_id", fields.userIdentifier))
+	}
+	if fields.robotIdentifier != 0 {
+		attributes = append(attributes, slog.Int64("robot_id", fields.robotIdentifier))
+	}
+	if fields.operationIdentifier != 0 {
+		attributes = append(attributes, slog.Int64("operation_id", fields.operationIdentifier))
+	}
+	return attributes
+}
@@ -0,0 +1,158 @@
+// Package logging configures the process-wide log/slog logger: JSON (or text) output, a default
+// level with per-component overrides, correlation attributes taken from the context, and redaction
+// of credentials, tokens and email addresses before anything is written.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Options selects where and how much is logged.
+type Options struct {
+	Output io.Writer
+	// Format is "json" (default) or "text".
+	Format string
+	// Level applies to components without an entry in ComponentLevels.
+	Level           slog.Level
+	ComponentLevels map[string]slog.Level
+}
+
+// componentKey is the attribute that names the part of the code a logger belongs to.
+const componentKey = "component"
+
+type configuration struct {
+	output          slog.Handler
+	level           slog.Level
+	componentLevels map[string]slog.Level
+}
+
+var current atomic.Pointer[configuration]
+
+func init() {
+	current.Store(&configuration{output: slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}), level: slog.LevelInfo})
+}
+
+// Setup installs the configuration and makes it the slog default, which also routes the standard
+// log package through it. Loggers returned by For before Setup pick the new configuration up.
+func Setup(options Options) {
+	output := options.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	// Levels are enforced by componentHandler, so the output handler lets everything through.
+	handlerOptions := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var outputHandler slog.Handler = slog.NewJSONHandler(output, handlerOptions)
+	if strings.EqualFold(options.Format, "text") {
+		outputHandler = slog.NewTextHandler(output, handlerOptions)
+	}
+	current.Store(&configuration{output: outputHandler, level: options.Level, componentLevels: options.ComponentLevels})
+	slog.SetDefault(slog.New(&componentHandler{}))
+}
+
+// For returns the logger of one component (e.g. "automation", "binance", "http"); its level can be
+// set on its own with ParseComponentLevels.
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component, steps: []handlerStep{{attributes: []slog.Attr{slog.String(componentKey, component)}}}})
+}
+
+// ParseLevel reads "debug", "info", "warn" or "error".
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if unmarshalError := level.UnmarshalText([]byte(strings.TrimSpace(value))); unmarshalError != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", value)
+	}
+	return level, nil
+}
+
+// ParseComponentLevels reads per-component levels written as "automation=debug,binance=warn".
+func ParseComponentLevels(value string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		component, levelText, found := strings.Cut(entry, "=")
+		if !found || strings.TrimSpace(component) == "" {
+			return nil, fmt.Errorf("invalid component log level %q (expected component=level)", entry)
+		}
+		level, parseError := ParseLevel(levelText)
+		if parseError != nil {
+			return nil, fmt.Errorf("component %s: %w", strings.TrimSpace(component), parseError)
+		}
+		levels[strings.TrimSpace(component)] = level
+	}
+	return levels, nil
+}
+
+// componentHandler applies the component's level, adds the correlation attributes from the context,
+// redacts the record and hands it to the configured output.
+type componentHandler struct {
+	component string
+	steps     []handlerStep
+	inGroup   bool
+}
+
+// handlerStep is one WithAttrs or WithGroup call, replayed in order onto the output handler.
+type handlerStep struct {
+	attributes []slog.Attr
+	group      string
+}
+
+func (handler *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	configured := current.Load()
+	minimum := configured.level
+	if componentLevel, found := configured.componentLevels[handler.component]; found {
+		minimum = componentLevel
+	}
+	return level >= minimum
+}
+
+func (handler *componentHandler) Handle(handlerContext context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, RedactString(record.Message), record.PC)
+	redacted.AddAttrs(correlationAttributes(handlerContext)...)
+	record.Attrs(func(attribute slog.Attr) bool {
+		redacted.AddAttrs(redactAttribute(attribute))
+		return true
+	})
+	output := current.Load().output
+	for _, step := range handler.steps {
+		if step.group != "" {
+			output = output.WithGroup(step.group)
+			continue
+		}
+		redactedAttributes := make([]slog.Attr, 0, len(step.attributes))
+		for _, attribute := range step.attributes {
+			redactedAttributes = append(redactedAttributes, redactAttribute(attribute))
+		}
+		output = output.WithAttrs(redactedAttributes)
+	}
+	return output.Handle(handlerContext, redacted)
+}
+
+func (handler *componentHandler) WithAttrs(attributes []slog.Attr) slog.Handler {
+	derived := *handler
+	derived.steps = append(append([]handlerStep(nil), handler.steps...), handlerStep{attributes: attributes})
+	for _, attribute := range attributes {
+		if attribute.Key == componentKey && !handler.inGroup {
+			derived.component = attribute.Value.String()
+		}
+	}
+	return &derived
+}
+
+func (handler *componentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+	derived := *handler
+	derived.steps = append(append([]handlerStep(nil), handler.steps...), handlerStep{group: name})
+	derived.inGroup = true
+	return &derived
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	cases := []struct {
+		input     string
+		forbidden string
+	}{
+		{input: "GET /api/v3/order?symbol=BTCUSDT&timestamp=1&signature=0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0", forbidden: "0f1e2d3c"},
+		{input: `Binance rejected buy order (status 401): {"code":-2015,"msg":"Invalid API-key","apiKey":"vmPUZE6mv9SD5VNHk4HlWFsOr6aKE2zvsw0MuIgwCIPy6utIco14y7Ju91duEh8A"}`, forbidden: "vmPUZE6m"},
+		{input: "X-MBX-APIKEY: vmPUZE6mv9SD5VNHk4HlWFsOr6aKE2zvsw0MuIgwCIPy6utIco14y7Ju91duEh8A", forbidden: "vmPUZE6m"},
+		{input: "could not send the reset email to ana.silva+coins@example.com", forbidden: "ana.silva"},
+		{input: "Authorization: Bearer chpat_Zm9vYmFyYmF6", forbidden: "Zm9vYmFy"},
+		{input: "session lookup failed for 3q2-7yBc_8sA9dXkLmNoPqRsTuVwXyZ0123456789ab", forbidden: "3q2-7yBc"},
+	}
+	for _, testCase := range cases {
+		if redacted := RedactString(testCase.input); strings.Contains(redacted, testCase.forbidden) {
+			t.Errorf("%q leaked %q: %s", testCase.input, testCase.forbidden, redacted)
+		}
+	}
+	if kept := RedactString("automation: closed operation 42 via take_profit at 61234.5"); kept != "automation: closed operation 42 via take_profit at 61234.5" {
+		t.Errorf("ordinary text was changed: %s", kept)
+	}
+}
+
+func TestComponentLoggerAttributesAndLevels(t *testing.T) {
+	var output bytes.Buffer
+	levels, parseError := ParseComponentLevels("automation=debug, binance=error")
+	if parseError != nil {
+		t.Fatal(parseError)
+	}
+	Setup(Options{Output: &output, Level: slog.LevelInfo, ComponentLevels: levels})
+	defer Setup(Options{Level: slog.LevelInfo})
+
+	logContext := WithOperationID(WithRobotID(WithUserID(WithRequestID(context.Background(), "req-1"), 7), 3), 42)
+	For("automation").DebugContext(logContext, "stop-loss check", "error", errors.New("rejected for user ana@example.com"), "api_secret", "abc")
+	For("binance").WarnContext(logContext, "slow response")
+	For("http").DebugContext(logContext, "hidden at the default level")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected only the automation debug line, got %d lines: %s", len(lines), output.String())
+	}
+	var entry map[string]interface{}
+	if decodeError := json.Unmarshal([]byte(lines[0]), &entry); decodeError != nil {
+		t.Fatal(decodeError)
+	}
+	if entry["request_id"] != "req-1" || entry["user_id"] != float64(7) || entry["robot_id"] != float64(3) || entry["operation_id"] != float64(42) || entry["component"] != "automation" {
+		t.Errorf("missing correlation attributes: %s", lines[0])
+	}
+	if strings.Contains(lines[0], "ana@example.com") || strings.Contains(lines[0], "abc") {
+		t.Errorf("sensitive values were logged: %s", lines[0])
+	}
+}
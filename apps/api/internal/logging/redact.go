@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeyFragments mark attribute keys whose values are never logged, whatever they contain.
+var sensitiveKeyFragments = []string{"password", "secret", "token", "api_key", "apikey", "signature", "authorization", "cookie", "email"}
+
+// redactionRules rewrite sensitive fragments inside free text: messages, error strings and raw
+// Binance or SMTP responses that end up in an error.
+var redactionRules = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// key=value pairs in query strings and signed payloads, e.g. "signature=ab12..." or "apiKey=...".
+	{regexp.MustCompile(`(?i)\b(signature|api_?key|api_?secret|secret|token|password|session)=[^&\s"',]+`), "${1}=" + redactedValue},
+	// The same names as JSON fields, e.g. in a Binance error body.
+	{regexp.MustCompile(`(?i)"(signature|api_?key|api_?secret|secret|[a-z_]*token|password|session)"\s*:\s*"[^"]*"`), `"${1}":"` + redactedValue + `"`},
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`), "${1} " + redactedValue},
+	{regexp.MustCompile(`(?i)\b(x-mbx-apikey)(["']?\s*[:=]\s*["']?)[A-Za-z0-9]+`), "${1}${2}" + redactedValue},
+	{regexp.MustCompile(`chpat_[A-Za-z0-9_-]+`), "chpat_" + redactedValue},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[email]"},
+	// Long opaque strings: Binance API keys and secrets, HMAC signatures, session and reset tokens.
+	{regexp.MustCompile(`\b[A-Za-z0-9_-]{32,}\b`), redactedValue},
+}
+
+// RedactString removes credentials, tokens, signatures and email addresses from text.
+func RedactString(text string) string {
+	for _, rule := range redactionRules {
+		text = rule.pattern.ReplaceAllString(text, rule.replacement)
+	}
+	return text
+}
+
+func isSensitiveKey(key string) bool {
+	normalizedKey := strings.ToLower(key)
+	for _, fragment := range sensitiveKeyFragments {
+		if strings.Contains(normalizedKey, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttribute(attribute slog.Attr) slog.Attr {
+	value := attribute.Value.Resolve()
+	if isSensitiveKey(attribute.Key) {
+		return slog.String(attribute.Key, redactedValue)
+	}
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attribute.Key, RedactString(value.String()))
+	case slog.KindGroup:
+		members := value.Group()
+		redactedMembers := make([]any, 0, len(members))
+		for _, member := range members {
+			redactedMembers = append(redactedMembers, redactAttribute(member))
+		}
+		return slog.Group(attribute.Key, redactedMembers...)
+	case slog.KindAny:
+		// Errors and arbitrary values are flattened to text so their content can be checked.
+		if errorValue, isError := value.Any().(error); isError {
+			return slog.String(attribute.Key, RedactString(errorValue.Error()))
+		}
+		return slog.String(attribute.Key, RedactString(fmt.Sprintf("%+v", value.Any())))
+	default:
+		return slog.Attr{Key: attribute.Key, Value: value}
+	}
+}
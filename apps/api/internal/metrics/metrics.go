@@ -0,0 +1,301 @@
+// Package metrics keeps in-process counters, gauges and histograms and writes them in the Prometheus
+// text exposition format (version 0.0.4), which is all /metrics needs; it avoids pulling in the
+// Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets suit request and loop latencies, in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry holds every metric family exposed on /metrics.
+type Registry struct {
+	mutex    sync.Mutex
+	families map[string]family
+}
+
+type family interface {
+	write(writer *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// Default is the registry the package-level constructors register with.
+var Default = NewRegistry()
+
+func (registry *Registry) register(name string, metricFamily family) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, exists := registry.families[name]; exists {
+		panic("metrics: " + name + " is registered twice")
+	}
+	registry.families[name] = metricFamily
+}
+
+// Write writes every family, sorted by name, in the text exposition format.
+func (registry *Registry) Write(output io.Writer) error {
+	registry.mutex.Lock()
+	names := sortedKeys(registry.families)
+	families := make([]family, 0, len(names))
+	for _, name := range names {
+		families = append(families, registry.families[name])
+	}
+	registry.mutex.Unlock()
+
+	writer := bufio.NewWriter(output)
+	for _, metricFamily := range families {
+		metricFamily.write(writer)
+	}
+	return writer.Flush()
+}
+
+// descriptor is the part every family shares: its name, help text and label names.
+type descriptor struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (metricDescriptor descriptor) writeHeader(writer *bufio.Writer, metricType string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", metricDescriptor.name, strings.ReplaceAll(metricDescriptor.help, "\n", " "), metricDescriptor.name, metricType)
+}
+
+// seriesKey joins label values into a map key. The values are checked against the label names so a
+// wrong call fails loudly in tests instead of producing a malformed series.
+func (metricDescriptor descriptor) seriesKey(labelValues []string) string {
+	if len(labelValues) != len(metricDescriptor.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", metricDescriptor.name, len(metricDescriptor.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\x00")
+}
+
+// labelText renders {name="value",...} for a series key, plus any extra label (le for buckets).
+func (metricDescriptor descriptor) labelText(key string, extraName string, extraValue string) string {
+	var builder strings.Builder
+	var labelValues []string
+	if len(metricDescriptor.labelNames) > 0 {
+		labelValues = strings.Split(key, "\x00")
+	}
+	for index, labelName := range metricDescriptor.labelNames {
+		if builder.Len() > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(labelName + `="` + escapeLabelValue(labelValues[index]) + `"`)
+	}
+	if extraName != "" {
+		if builder.Len() > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(extraName + `="` + extraValue + `"`)
+	}
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "{" + builder.String() + "}"
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CounterVec is a monotonically increasing count, one series per label combination.
+type CounterVec struct {
+	descriptor
+	mutex  sync.Mutex
+	series map[string]float64
+}
+
+// NewCounterVec registers a counter with the default registry.
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	counter := &CounterVec{descriptor: descriptor{name: name, help: help, labelNames: labelNames}, series: make(map[string]float64)}
+	Default.register(name, counter)
+	return counter
+}
+
+// Inc adds one to the series for labelValues.
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add adds delta (which must not be negative) to the series for labelValues.
+func (counter *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	key := counter.seriesKey(labelValues)
+	counter.mutex.Lock()
+	counter.series[key] += delta
+	counter.mutex.Unlock()
+}
+
+// Value returns the current count for labelValues.
+func (counter *CounterVec) Value(labelValues ...string) float64 {
+	key := counter.seriesKey(labelValues)
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	return counter.series[key]
+}
+
+func (counter *CounterVec) write(writer *bufio.Writer) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	counter.writeHeader(writer, "counter")
+	if len(counter.labelNames) == 0 && len(counter.series) == 0 {
+		fmt.Fprintf(writer, "%s 0\n", counter.name)
+	}
+	for _, key := range sortedKeys(counter.series) {
+		fmt.Fprintf(writer, "%s%s %s\n", counter.name, counter.labelText(key, "", ""), formatValue(counter.series[key]))
+	}
+}
+
+// GaugeVec is a value that can go up and down, one series per label combination.
+type GaugeVec struct {
+	descriptor
+	mutex  sync.Mutex
+	series map[string]float64
+}
+
+// NewGaugeVec registers a gauge with the default registry.
+func NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	gauge := &GaugeVec{descriptor: descriptor{name: name, help: help, labelNames: labelNames}, series: make(map[string]float64)}
+	Default.register(name, gauge)
+	return gauge
+}
+
+// Set replaces the value of the series for labelValues.
+func (gauge *GaugeVec) Set(value float64, labelValues ...string) {
+	key := gauge.seriesKey(labelValues)
+	gauge.mutex.Lock()
+	gauge.series[key] = value
+	gauge.mutex.Unlock()
+}
+
+// Value returns the current value for labelValues.
+func (gauge *GaugeVec) Value(labelValues ...string) float64 {
+	key := gauge.seriesKey(labelValues)
+	gauge.mutex.Lock()
+	defer gauge.mutex.Unlock()
+	return gauge.series[key]
+}
+
+func (gauge *GaugeVec) write(writer *bufio.Writer) {
+	gauge.mutex.Lock()
+	defer gauge.mutex.Unlock()
+	gauge.writeHeader(writer, "gauge")
+	if len(gauge.labelNames) == 0 && len(gauge.series) == 0 {
+		fmt.Fprintf(writer, "%s 0\n", gauge.name)
+	}
+	for _, key := range sortedKeys(gauge.series) {
+		fmt.Fprintf(writer, "%s%s %s\n", gauge.name, gauge.labelText(key, "", ""), formatValue(gauge.series[key]))
+	}
+}
+
+// HistogramVec counts observations into cumulative buckets, one series per label combination.
+type HistogramVec struct {
+	descriptor
+	buckets []float64
+	mutex   sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+// NewHistogramVec registers a histogram with the default registry. buckets are upper bounds in
+// increasing order; the +Inf bucket is implicit.
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	histogram := &HistogramVec{
+		descriptor: descriptor{name: name, help: help, labelNames: labelNames},
+		buckets:    append([]float64(nil), buckets...),
+		series:     make(map[string]*histogramSeries),
+	}
+	sort.Float64s(histogram.buckets)
+	Default.register(name, histogram)
+	return histogram
+}
+
+// Observe records value in the series for labelValues.
+func (histogram *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := histogram.seriesKey(labelValues)
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	observed, exists := histogram.series[key]
+	if !exists {
+		observed = &histogramSeries{bucketCounts: make([]uint64, len(histogram.buckets))}
+		histogram.series[key] = observed
+	}
+	for index, upperBound := range histogram.buckets {
+		if value <= upperBound {
+			observed.bucketCounts[index]++
+		}
+	}
+	observed.count++
+	observed.sum += value
+}
+
+// ObserveSince records the seconds elapsed since start.
+func (histogram *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	histogram.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns how many observations the series for labelValues has.
+func (histogram *HistogramVec) Count(labelValues ...string) uint64 {
+	key := histogram.seriesKey(labelValues)
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	if observed, exists := histogram.series[key]; exists {
+		return observed.count
+	}
+	return 0
+}
+
+func (histogram *HistogramVec) write(writer *bufio.Writer) {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	histogram.writeHeader(writer, "histogram")
+	for _, key := range sortedKeys(histogram.series) {
+		observed := histogram.series[key]
+		for index, upperBound := range histogram.buckets {
+			fmt.Fprintf(writer, "%s_bucket%s %d\n", histogram.name, histogram.labelText(key, "le", formatValue(upperBound)), observed.bucketCounts[index])
+		}
+		fmt.Fprintf(writer, "%s_bucket%s %d\n", histogram.name, histogram.labelText(key, "le", "+Inf"), observed.count)
+		fmt.Fprintf(writer, "%s_sum%s %s\n", histogram.name, histogram.labelText(key, "", ""), formatValue(observed.sum))
+		fmt.Fprintf(writer, "%s_count%s %d\n", histogram.name, histogram.labelText(key, "", ""), observed.count)
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesTextExposition(t *testing.T) {
+	orders := NewCounterVec("test_orders_total", "Orders sent to the exchange.", "type", "outcome")
+	lag := NewGaugeVec("test_loop_lag_seconds", "How late the loop started.", "loop")
+	latency := NewHistogramVec("test_request_duration_seconds", "Request latency.", []float64{0.1, 1}, "endpoint")
+
+	orders.Inc("market_buy", "placed")
+	orders.Inc("market_buy", "placed")
+	orders.Inc("limit_sell", `re"jected`)
+	lag.Set(0.25, "monitor")
+	latency.Observe(0.05, "/api/v3/order")
+	latency.Observe(0.5, "/api/v3/order")
+	latency.Observe(3, "/api/v3/order")
+
+	var output bytes.Buffer
+	if writeError := Default.Write(&output); writeError != nil {
+		t.Fatal(writeError)
+	}
+	exposition := output.String()
+	for _, expectedLine := range []string{
+		"# TYPE test_orders_total counter",
+		`test_orders_total{type="market_buy",outcome="placed"} 2`,
+		`test_orders_total{type="limit_sell",outcome="re\"jected"} 1`,
+		"# TYPE test_loop_lag_seconds gauge",
+		`test_loop_lag_seconds{loop="monitor"} 0.25`,
+		"# TYPE test_request_duration_seconds histogram",
+		`test_request_duration_seconds_bucket{endpoint="/api/v3/order",le="0.1"} 1`,
+		`test_request_duration_seconds_bucket{endpoint="/api/v3/order",le="1"} 2`,
+		`test_request_duration_seconds_bucket{endpoint="/api/v3/order",le="+Inf"} 3`,
+		`test_request_duration_seconds_sum{endpoint="/api/v3/order"} 3.55`,
+		`test_request_duration_seconds_count{endpoint="/api/v3/order"} 3`,
+	} {
+		if !strings.Contains(exposition, expectedLine+"\n") {
+			t.Errorf("missing %q in:\n%s", expectedLine, exposition)
+		}
+	}
+	if strings.Index(exposition, "test_loop_lag_seconds") > strings.Index(exposition, "test_orders_total") {
+		t.Errorf("families are not sorted by name:\n%s", exposition)
+	}
+}
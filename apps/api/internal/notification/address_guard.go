@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedDestination is returned when a user-supplied endpoint resolves to an internal address.
+var ErrBlockedDestination = errors.New("the endpoint resolves to an address that is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), not covered by netip's IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// NewGuardedHTTPClient returns the client for requests to user-supplied URLs. The address is checked
+// when the connection is dialled, after DNS resolution, so a hostname that resolves to an internal
+// address is refused as well as an IP literal; redirects are not followed, so an endpoint cannot bounce
+// the request inward either. Environment proxies are ignored: the check must see the real target.
+func NewGuardedHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refuseBlockedAddress}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			// The 3xx response is returned as is and reported as a failed delivery.
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refuseBlockedAddress is the net.Dialer Control hook; address is the resolved "ip:port".
+func refuseBlockedAddress(_ string, address string, _ syscall.RawConn) error {
+	addressPort, parseError := netip.ParseAddrPort(address)
+	if parseError != nil || isBlockedAddress(addressPort.Addr()) {
+		return ErrBlockedDestination
+	}
+	return nil
+}
+
+// isBlockedAddress reports whether address is loopback, private, link-local (cloud metadata),
+// shared (CGNAT), multicast or unspecified.
+func isBlockedAddress(address netip.Addr) bool {
+	address = address.Unmap()
+	return !address.IsValid() || address.IsLoopback() || address.IsPrivate() || address.IsLinkLocalUnicast() ||
+		address.IsLinkLocalMulticast() || address.IsInterfaceLocalMulticast() || address.IsMulticast() ||
+		address.IsUnspecified() || sharedAddressSpace.Contains(address)
+}
+
+// isPrivateAddressLiteral reports whether host is an IP literal that isBlockedAddress refuses. Hostnames
+// are not resolved here; this rejects the obvious targets at configuration time, and the dial-time
+// check in NewGuardedHTTPClient covers the rest.
+func isPrivateAddressLiteral(host string) bool {
+	address, parseError := netip.ParseAddr(host)
+	if parseError != nil {
+		return false
+	}
+	return isBlockedAddress(address)
+}
@@ -0,0 +1,33 @@
+package notification
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsBlockedAddress(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.20.0.5":      true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"224.0.0.251":     true,
+		"::1":             true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"::ffff:10.0.0.1": true,
+		"93.184.216.34":   false,
+		"2606:4700::6810": false,
+		"::ffff:8.8.8.8":  false,
+		"100.128.0.1":     false,
+		"198.51.100.23":   false,
+	}
+	for literal, expected := range cases {
+		if blocked := isBlockedAddress(netip.MustParseAddr(literal)); blocked != expected {
+			t.Errorf("%s: blocked = %v, expected %v", literal, blocked, expected)
+		}
+	}
+}
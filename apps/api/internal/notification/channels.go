@@ -0,0 +1,180 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"coin-alert/internal/email"
+)
+
+const discordContentLimit = 2000
+
+// EmailChannel sends the message through the shared email.Sender.
+type EmailChannel struct {
+	sender    email.Sender
+	recipient string
+}
+
+func NewEmailChannel(sender email.Sender, recipient string) *EmailChannel {
+	return &EmailChannel{sender: sender, recipient: recipient}
+}
+
+func (channel *EmailChannel) Deliver(deliveryContext context.Context, message Message) error {
+	if channel.sender == nil {
+		return errors.New("email is not configured")
+	}
+	if strings.TrimSpace(channel.recipient) == "" {
+		return errors.New("no email recipient")
+	}
+	return channel.sender.Send(deliveryContext, email.Message{
+		To:       channel.recipient,
+		Subject:  message.Subject,
+		TextBody: message.Text,
+		HTMLBody: message.HTMLBody,
+	})
+}
+
+// TelegramChannel posts through the Bot API sendMessage method.
+type TelegramChannel struct {
+	httpClient *http.Client
+	apiBaseURL string
+	botToken   string
+	chatID     string
+}
+
+func NewTelegramChannel(httpClient *http.Client, apiBaseURL string, botToken string, chatID string) *TelegramChannel {
+	return &TelegramChannel{
+		httpClient: httpClient,
+		apiBaseURL: strings.TrimRight(apiBaseURL, "/"),
+		botToken:   strings.TrimSpace(botToken),
+		chatID:     strings.TrimSpace(chatID),
+	}
+}
+
+func (channel *TelegramChannel) Deliver(deliveryContext context.Context, message Message) error {
+	payload := map[string]any{
+		"chat_id":                  channel.chatID,
+		"text":                     composePlainText(message),
+		"disable_web_page_preview": true,
+	}
+	endpoint := channel.apiBaseURL + "/bot" + channel.botToken + "/sendMessage"
+	responseBody, statusCode, postError := postJSON(deliveryContext, channel.httpClient, endpoint, payload, nil)
+	if postError != nil {
+		return postError
+	}
+	var telegramResponse struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if decodeError := json.Unmarshal(responseBody, &telegramResponse); decodeError != nil || !telegramResponse.OK {
+		if telegramResponse.Description != "" {
+			return fmt.Errorf("telegram rejected the message (%d): %s", statusCode, telegramResponse.Description)
+		}
+		return fmt.Errorf("telegram rejected the message (%d)", statusCode)
+	}
+	return nil
+}
+
+// DiscordChannel posts to a Discord incoming webhook.
+type DiscordChannel struct {
+	httpClient *http.Client
+	webhookURL string
+}
+
+func NewDiscordChannel(httpClient *http.Client, webhookURL string) *DiscordChannel {
+	return &DiscordChannel{httpClient: httpClient, webhookURL: strings.TrimSpace(webhookURL)}
+}
+
+func (channel *DiscordChannel) Deliver(deliveryContext context.Context, message Message) error {
+	content := truncateCharacters(composePlainText(message), discordContentLimit)
+	_, statusCode, postError := postJSON(deliveryContext, channel.httpClient, channel.webhookURL, map[string]any{"content": content}, nil)
+	if postError != nil {
+		return postError
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusNoContent {
+		return fmt.Errorf("discord webhook returned status %d", statusCode)
+	}
+	return nil
+}
+
+// WebhookChannel posts a small JSON document to an arbitrary https endpoint.
+type WebhookChannel struct {
+	httpClient *http.Client
+	webhookURL string
+}
+
+func NewWebhookChannel(httpClient *http.Client, webhookURL string) *WebhookChannel {
+	return &WebhookChannel{httpClient: httpClient, webhookURL: strings.TrimSpace(webhookURL)}
+}
+
+func (channel *WebhookChannel) Deliver(deliveryContext context.Context, message Message) error {
+	payload := map[string]any{
+		"event_type": message.EventType,
+		"subject":    message.Subject,
+		"text":       message.Text,
+		"sent_at":    time.Now().UTC().Format(time.RFC3339),
+	}
+	_, statusCode, postError := postJSON(deliveryContext, channel.httpClient, channel.webhookURL, payload, nil)
+	if postError != nil {
+		return postError
+	}
+	if statusCode < 200 || statusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", statusCode)
+	}
+	return nil
+}
+
+func composePlainText(message Message) string {
+	if message.Subject == "" {
+		return message.Text
+	}
+	if message.Text == "" {
+		return message.Subject
+	}
+	return message.Subject + "\n\n" + message.Text
+}
+
+// truncateCharacters shortens text to at most limit characters (runes, as Discord counts them), ending
+// with "..." when cut. It never splits a multi-byte character.
+func truncateCharacters(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit-3]) + "..."
+}
+
+// postJSON sends payload and returns at most 64 KiB of the response body.
+func postJSON(requestContext context.Context, httpClient *http.Client, endpoint string, payload any, headers map[string]string) ([]byte, int, error) {
+	encodedPayload, encodeError := json.Marshal(payload)
+	if encodeError != nil {
+		return nil, 0, encodeError
+	}
+	httpRequest, requestError := http.NewRequestWithContext(requestContext, http.MethodPost, endpoint, bytes.NewReader(encodedPayload))
+	if requestError != nil {
+		return nil, 0, errors.New("invalid notification endpoint")
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("User-Agent", "CoinHub-Notifier/1.0")
+	for headerName, headerValue := range headers {
+		httpRequest.Header.Set(headerName, headerValue)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, doError := httpClient.Do(httpRequest)
+	if doError != nil {
+		return nil, 0, withoutRequestURL(doError)
+	}
+	defer response.Body.Close()
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	return responseBody, response.StatusCode, nil
+}
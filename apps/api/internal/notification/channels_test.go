@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"coin-alert/internal/domain"
+)
+
+func TestTelegramChannelDeliver(t *testing.T) {
+	var receivedPath string
+	var receivedPayload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		receivedPath = request.URL.Path
+		_ = json.NewDecoder(request.Body).Decode(&receivedPayload)
+		_, _ = responseWriter.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	channel := NewTelegramChannel(server.Client(), server.URL, "123:abc", "42")
+	if deliverError := channel.Deliver(context.Background(), Message{Subject: "Take-profit filled", Text: "BTCUSDT sold"}); deliverError != nil {
+		t.Fatalf("unexpected error: %v", deliverError)
+	}
+	if receivedPath != "/bot123:abc/sendMessage" {
+		t.Fatalf("unexpected path %q", receivedPath)
+	}
+	if receivedPayload["chat_id"] != "42" || !strings.Contains(receivedPayload["text"].(string), "BTCUSDT sold") {
+		t.Fatalf("unexpected payload %v", receivedPayload)
+	}
+}
+
+func TestTelegramChannelReportsRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusBadRequest)
+		_, _ = responseWriter.Write([]byte(`{"ok":false,"description":"chat not found"}`))
+	}))
+	defer server.Close()
+
+	channel := NewTelegramChannel(server.Client(), server.URL, "secret-token", "42")
+	deliverError := channel.Deliver(context.Background(), Message{Subject: "x"})
+	if deliverError == nil || !strings.Contains(deliverError.Error(), "chat not found") {
+		t.Fatalf("expected rejection error, got %v", deliverError)
+	}
+	if strings.Contains(deliverError.Error(), "secret-token") {
+		t.Fatalf("error leaks the bot token: %v", deliverError)
+	}
+}
+
+func TestDiscordChannelTruncatesContent(t *testing.T) {
+	var receivedPayload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_ = json.NewDecoder(request.Body).Decode(&receivedPayload)
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "ASCII", text: strings.Repeat("a", 3000), expected: strings.Repeat("a", discordContentLimit-3) + "..."},
+		{name: "two-byte characters", text: strings.Repeat("é", 3000), expected: strings.Repeat("é", discordContentLimit-3) + "..."},
+		{name: "emoji", text: strings.Repeat("📈", 2500), expected: strings.Repeat("📈", discordContentLimit-3) + "..."},
+		{name: "mixed, cut next to a multi-byte character", text: strings.Repeat("a", discordContentLimit-4) + strings.Repeat("ção", 10), expected: strings.Repeat("a", discordContentLimit-4) + "ç..."},
+		{name: "long in bytes but within the character limit", text: strings.Repeat("ã", discordContentLimit), expected: strings.Repeat("ã", discordContentLimit)},
+	}
+	channel := NewDiscordChannel(server.Client(), server.URL)
+	for _, testCase := range cases {
+		if deliverError := channel.Deliver(context.Background(), Message{Text: testCase.text}); deliverError != nil {
+			t.Fatalf("%s: unexpected error: %v", testCase.name, deliverError)
+		}
+		content := receivedPayload["content"]
+		if !utf8.ValidString(content) || utf8.RuneCountInString(content) > discordContentLimit {
+			t.Errorf("%s: invalid or too long content (%d characters)", testCase.name, utf8.RuneCountInString(content))
+		}
+		if content != testCase.expected {
+			t.Errorf("%s: content ends with %q, expected %q", testCase.name, content[len(content)-8:], testCase.expected[len(testCase.expected)-8:])
+		}
+	}
+}
+
+func TestWebhookChannelFailsOnNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	channel := NewWebhookChannel(server.Client(), server.URL)
+	if deliverError := channel.Deliver(context.Background(), Message{EventType: "TEST"}); deliverError == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+}
+
+// TestFactoryChannelsRefuseInternalDestinations posts through the factory's client to a server on
+// loopback, once by IP and once by a hostname that resolves to it, and once via a redirect.
+func TestFactoryChannelsRefuseInternalDestinations(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	factory := NewChannelFactory(nil)
+	for _, endpointURL := range []string{server.URL, "http://localhost:" + port} {
+		for _, channelType := range []string{domain.NotificationChannelWebhook, domain.NotificationChannelDiscord} {
+			channel, buildError := factory.Build(channelType, Configuration{WebhookURL: endpointURL, DiscordWebhookURL: endpointURL}, "")
+			if buildError != nil {
+				t.Fatal(buildError)
+			}
+			if deliverError := channel.Deliver(context.Background(), Message{Subject: "x"}); !errors.Is(deliverError, ErrBlockedDestination) {
+				t.Errorf("%s %s: expected the destination to be refused, got %v", channelType, endpointURL, deliverError)
+			}
+		}
+	}
+	if reached {
+		t.Error("the internal endpoint received a request")
+	}
+
+	redirectTargetReached := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(responseWriter http.ResponseWriter, request *http.Request) {
+		http.Redirect(responseWriter, request, "/internal", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/internal", func(http.ResponseWriter, *http.Request) {
+		redirectTargetReached = true
+	})
+	redirectingServer := httptest.NewServer(mux)
+	defer redirectingServer.Close()
+	// Only the redirect policy is kept; the server itself is on loopback.
+	factory.HTTPClient.Transport = redirectingServer.Client().Transport
+	channel, _ := factory.Build(domain.NotificationChannelWebhook, Configuration{WebhookURL: redirectingServer.URL + "/hook"}, "")
+	if deliverError := channel.Deliver(context.Background(), Message{Subject: "x"}); deliverError == nil || redirectTargetReached {
+		t.Errorf("expected the redirect to be refused, got %v (followed: %v)", deliverError, redirectTargetReached)
+	}
+}
+
+func TestConfigurationValidate(t *testing.T) {
+	validationCases := []struct {
+		channelType   string
+		configuration Configuration
+		valid         bool
+	}{
+		{domain.NotificationChannelEmail, Configuration{}, true},
+		{domain.NotificationChannelEmail, Configuration{EmailAddress: "not-an-email"}, false},
+		{domain.NotificationChannelTelegram, Configuration{TelegramBotToken: "t"}, false},
+		{domain.NotificationChannelDiscord, Configuration{DiscordWebhookURL: "https://discord.com/api/webhooks/1/x"}, true},
+		{domain.NotificationChannelDiscord, Configuration{DiscordWebhookURL: "https://evil.example/api/webhooks/1/x"}, false},
+		{domain.NotificationChannelWebhook, Configuration{WebhookURL: "https://hooks.example.com/in"}, true},
+		{domain.NotificationChannelWebhook, Configuration{WebhookURL: "http://hooks.example.com/in"}, false},
+		{domain.NotificationChannelWebhook, Configuration{WebhookURL: "https://169.254.169.254/latest"}, false},
+		{"SMS", Configuration{}, false},
+	}
+	for _, validationCase := range validationCases {
+		validationError := validationCase.configuration.Validate(validationCase.channelType)
+		if (validationError == nil) != validationCase.valid {
+			t.Errorf("%s %+v: expected valid=%v, got %v", validationCase.channelType, validationCase.configuration, validationCase.valid, validationError)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package notification delivers user-facing messages over outbound channels: email, a Telegram bot,
+// a Discord webhook, or a generic HTTPS webhook. Every adapter implements Channel, so callers (the
+// NotificationService, trading automation) never care which transport a user picked. HTTP-based
+// adapters take their endpoint and client as fields, which keeps them testable against a local
+// httptest stand-in.
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/email"
+)
+
+// Message is a channel-neutral notification. HTMLBody is optional and only used by the email channel;
+// the other channels render Subject + Text.
+type Message struct {
+	EventType string
+	Subject   string
+	Text      string
+	HTMLBody  string
+}
+
+// Channel delivers a message to one configured destination.
+type Channel interface {
+	Deliver(deliveryContext context.Context, message Message) error
+}
+
+// Notifier delivers a message to every enabled channel a user has configured.
+type Notifier interface {
+	Notify(notifyContext context.Context, userIdentifier int64, message Message) error
+}
+
+// Configuration is the decrypted per-channel settings document. Only the fields relevant to the
+// channel type are set; the whole document is encrypted at rest because tokens and webhook URLs are
+// bearer secrets.
+type Configuration struct {
+	EmailAddress      string `json:"email_address,omitempty"` // empty = the account email
+	TelegramBotToken  string `json:"telegram_bot_token,omitempty"`
+	TelegramChatID    string `json:"telegram_chat_id,omitempty"`
+	DiscordWebhookURL string `json:"discord_webhook_url,omitempty"`
+	WebhookURL        string `json:"webhook_url,omitempty"`
+}
+
+// Validation errors surfaced to the API.
+var (
+	ErrUnknownChannelType     = errors.New("unknown notification channel type")
+	ErrTelegramFieldsRequired = errors.New("a Telegram bot token and chat id are required")
+	ErrDiscordWebhookInvalid  = errors.New("enter a Discord webhook URL (https://discord.com/api/webhooks/...)")
+	ErrWebhookURLInvalid      = errors.New("the webhook URL must be a public https:// address")
+	ErrEmailAddressInvalid    = errors.New("enter a valid email address")
+)
+
+// Validate checks that the configuration carries what the channel type needs. Webhook targets must
+// be https so notification contents (and any token in the URL) never travel in the clear.
+func (configuration Configuration) Validate(channelType string) error {
+	switch channelType {
+	case domain.NotificationChannelEmail:
+		address := strings.TrimSpace(configuration.EmailAddress)
+		if address != "" && (!strings.Contains(address, "@") || strings.ContainsAny(address, " \t\r\n")) {
+			return ErrEmailAddressInvalid
+		}
+		return nil
+	case domain.NotificationChannelTelegram:
+		if strings.TrimSpace(configuration.TelegramBotToken) == "" || strings.TrimSpace(configuration.TelegramChatID) == "" {
+			return ErrTelegramFieldsRequired
+		}
+		return nil
+	case domain.NotificationChannelDiscord:
+		parsedURL, parseError := url.Parse(strings.TrimSpace(configuration.DiscordWebhookURL))
+		if parseError != nil || parsedURL.Scheme != "https" || !isDiscordHost(parsedURL.Hostname()) || !strings.HasPrefix(parsedURL.Path, "/api/webhooks/") {
+			return ErrDiscordWebhookInvalid
+		}
+		return nil
+	case domain.NotificationChannelWebhook:
+		if !IsAllowedWebhookURL(configuration.WebhookURL) {
+			return ErrWebhookURLInvalid
+		}
+		return nil
+	default:
+		return ErrUnknownChannelType
+	}
+}
+
+// Label returns a masked, non-sensitive description of the destination for the UI.
+func (configuration Configuration) Label(channelType string) string {
+	switch channelType {
+	case domain.NotificationChannelEmail:
+		if address := strings.TrimSpace(configuration.EmailAddress); address != "" {
+			return address
+		}
+		return "account email"
+	case domain.NotificationChannelTelegram:
+		return "chat " + maskTail(configuration.TelegramChatID)
+	case domain.NotificationChannelDiscord:
+		return "discord webhook " + maskTail(configuration.DiscordWebhookURL)
+	case domain.NotificationChannelWebhook:
+		if parsedURL, parseError := url.Parse(configuration.WebhookURL); parseError == nil {
+			return parsedURL.Host
+		}
+		return "webhook"
+	default:
+		return ""
+	}
+}
+
+// IsAllowedWebhookURL reports whether rawURL is an https URL that does not point at loopback or an
+// internal compose service. It is the configuration-time half of the SSRF guard; hostnames are only
+// resolved when delivering, through the client from NewGuardedHTTPClient.
+func IsAllowedWebhookURL(rawURL string) bool {
+	parsedURL, parseError := url.Parse(strings.TrimSpace(rawURL))
+	if parseError != nil || parsedURL.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(parsedURL.Hostname())
+	if host == "" || host == "localhost" || !strings.Contains(host, ".") {
+		return false
+	}
+	return !isPrivateAddressLiteral(host)
+}
+
+// ChannelFactory builds channel adapters from a decrypted configuration. Webhook and Discord URLs are
+// user-supplied, so the default client refuses internal addresses and redirects.
+type ChannelFactory struct {
+	EmailSender        email.Sender
+	HTTPClient         *http.Client
+	TelegramAPIBaseURL string
+}
+
+func NewChannelFactory(emailSender email.Sender) *ChannelFactory {
+	return &ChannelFactory{
+		EmailSender:        emailSender,
+		HTTPClient:         NewGuardedHTTPClient(10 * time.Second),
+		TelegramAPIBaseURL: "https://api.telegram.org",
+	}
+}
+
+// Build returns the adapter for a channel type. fallbackEmailAddress is used by the email channel when
+// the configuration leaves the address empty (deliver to the account email).
+func (factory *ChannelFactory) Build(channelType string, configuration Configuration, fallbackEmailAddress string) (Channel, error) {
+	switch channelType {
+	case domain.NotificationChannelEmail:
+		recipient := strings.TrimSpace(configuration.EmailAddress)
+		if recipient == "" {
+			recipient = fallbackEmailAddress
+		}
+		return NewEmailChannel(factory.EmailSender, recipient), nil
+	case domain.NotificationChannelTelegram:
+		return NewTelegramChannel(factory.HTTPClient, factory.TelegramAPIBaseURL, configuration.TelegramBotToken, configuration.TelegramChatID), nil
+	case domain.NotificationChannelDiscord:
+		return NewDiscordChannel(factory.HTTPClient, configuration.DiscordWebhookURL), nil
+	case domain.NotificationChannelWebhook:
+		return NewWebhookChannel(factory.HTTPClient, configuration.WebhookURL), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownChannelType, channelType)
+	}
+}
+
+// withoutRequestURL strips the request URL from transport errors. Telegram bot tokens and Discord
+// webhook tokens live in the URL, and these errors end up in the delivery log.
+func withoutRequestURL(requestError error) error {
+	var urlError *url.Error
+	if errors.As(requestError, &urlError) {
+		return urlError.Err
+	}
+	return requestError
+}
+
+func maskTail(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) <= 4 {
+		return "****"
+	}
+	return "****" + trimmed[len(trimmed)-4:]
+}
+
+func isDiscordHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com")
+}
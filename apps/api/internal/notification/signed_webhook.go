@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signed webhook headers. Receivers recompute the signature over "<timestamp>.<raw body>" with their
+// endpoint secret and reject requests whose timestamp is too old (replay protection).
+const (
+	WebhookSignatureHeader = "X-CoinHub-Signature"
+	WebhookTimestampHeader = "X-CoinHub-Timestamp"
+	WebhookEventHeader     = "X-CoinHub-Event"
+	WebhookDeliveryHeader  = "X-CoinHub-Delivery"
+)
+
+// SignWebhookPayload returns the "sha256=<hex>" HMAC of timestamp + "." + body.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedWebhookSender posts signed event payloads to user webhook endpoints. The endpoints are
+// user-supplied, so the default client refuses internal addresses and redirects (see
+// NewGuardedHTTPClient).
+type SignedWebhookSender struct {
+	HTTPClient *http.Client
+}
+
+func NewSignedWebhookSender() *SignedWebhookSender {
+	return &SignedWebhookSender{HTTPClient: NewGuardedHTTPClient(10 * time.Second)}
+}
+
+// Send posts body to endpointURL and returns the response status code. Any non-2xx status is an error;
+// the status code is still returned (0 when no response was received).
+func (sender *SignedWebhookSender) Send(sendContext context.Context, endpointURL string, secret string, eventType string, deliveryIdentifier string, body []byte) (int, error) {
+	httpRequest, requestError := http.NewRequestWithContext(sendContext, http.MethodPost, endpointURL, bytes.NewReader(body))
+	if requestError != nil {
+		return 0, errors.New("invalid webhook endpoint")
+	}
+	timestamp := time.Now().Unix()
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("User-Agent", "CoinHub-Webhooks/1.0")
+	httpRequest.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	httpRequest.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, timestamp, body))
+	httpRequest.Header.Set(WebhookEventHeader, eventType)
+	httpRequest.Header.Set(WebhookDeliveryHeader, deliveryIdentifier)
+
+	httpClient := sender.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, doError := httpClient.Do(httpRequest)
+	if doError != nil {
+		return 0, withoutRequestURL(doError)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, errors.New("endpoint returned status " + strconv.Itoa(response.StatusCode))
+	}
+	return response.StatusCode, nil
+}
@@ -0,0 +1,101 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSignedWebhookSenderSignsTimestampAndBody(t *testing.T) {
+	var receivedSignature, receivedTimestamp, receivedEvent string
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		receivedSignature = request.Header.Get(WebhookSignatureHeader)
+		receivedTimestamp = request.Header.Get(WebhookTimestampHeader)
+		receivedEvent = request.Header.Get(WebhookEventHeader)
+		receivedBody, _ = io.ReadAll(request.Body)
+		responseWriter.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender := &SignedWebhookSender{HTTPClient: server.Client()}
+	body := []byte(`{"type":"operation.closed"}`)
+	statusCode, sendError := sender.Send(context.Background(), server.URL, "whsec_test", "operation.closed", "7", body)
+	if sendError != nil || statusCode != http.StatusAccepted {
+		t.Fatalf("unexpected result: %d %v", statusCode, sendError)
+	}
+
+	timestamp, parseError := strconv.ParseInt(receivedTimestamp, 10, 64)
+	if parseError != nil {
+		t.Fatalf("timestamp header missing or invalid: %q", receivedTimestamp)
+	}
+	if expected := SignWebhookPayload("whsec_test", timestamp, receivedBody); receivedSignature != expected {
+		t.Fatalf("signature mismatch: got %q, want %q", receivedSignature, expected)
+	}
+	if receivedEvent != "operation.closed" {
+		t.Fatalf("unexpected event header %q", receivedEvent)
+	}
+}
+
+func TestSignedWebhookSenderReportsNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sender := &SignedWebhookSender{HTTPClient: server.Client()}
+	statusCode, sendError := sender.Send(context.Background(), server.URL, "s", "robot.changed", "1", []byte(`{}`))
+	if sendError == nil || statusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503 error, got %d %v", statusCode, sendError)
+	}
+}
+
+func TestSignedWebhookSenderRefusesInternalDestinations(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	sender := NewSignedWebhookSender()
+	// An IP literal, and a hostname that only resolves to loopback.
+	for _, endpointURL := range []string{server.URL, "http://localhost:" + port} {
+		statusCode, sendError := sender.Send(context.Background(), endpointURL, "s", "robot.changed", "1", []byte(`{}`))
+		if !errors.Is(sendError, ErrBlockedDestination) || statusCode != 0 {
+			t.Errorf("%s: expected the destination to be refused, got %d %v", endpointURL, statusCode, sendError)
+		}
+	}
+	if reached {
+		t.Error("the internal endpoint received a request")
+	}
+}
+
+func TestSignedWebhookSenderDoesNotFollowRedirects(t *testing.T) {
+	redirectTargetReached := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(responseWriter http.ResponseWriter, request *http.Request) {
+		http.Redirect(responseWriter, request, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(http.ResponseWriter, *http.Request) {
+		redirectTargetReached = true
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The test server is on loopback, so only the redirect policy of the guarded client is kept.
+	sender := NewSignedWebhookSender()
+	sender.HTTPClient.Transport = server.Client().Transport
+	statusCode, sendError := sender.Send(context.Background(), server.URL+"/hook", "s", "robot.changed", "1", []byte(`{}`))
+	if sendError == nil || statusCode != http.StatusFound {
+		t.Fatalf("expected the redirect to be reported as a failure, got %d %v", statusCode, sendError)
+	}
+	if redirectTargetReached {
+		t.Error("the redirect was followed")
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"coin-alert/internal/domain"
+)
+
+const auditEntryColumns = `id, user_id, actor_user_id, action, target_type, target_id, ip_address, user_agent,
+	before_value::TEXT, after_value::TEXT, created_at`
+
+// AuditLogRepository appends to and pages through the audit log. There is deliberately no update or
+// delete: the table rejects updates, and rows only leave with the account.
+type AuditLogRepository interface {
+	AppendEntry(operationContext context.Context, entry domain.AuditEntry) error
+	// ListEntries returns entries newest first, below beforeIdentifier (0 = from the newest), limited to
+	// one user when userIdentifier > 0.
+	ListEntries(loadContext context.Context, userIdentifier int64, beforeIdentifier int64, limit int) ([]domain.AuditEntry, error)
+}
+
+type PostgresAuditLogRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresAuditLogRepository(database *sql.DB) *PostgresAuditLogRepository {
+	return &PostgresAuditLogRepository{Database: database}
+}
+
+func (repository *PostgresAuditLogRepository) AppendEntry(operationContext context.Context, entry domain.AuditEntry) error {
+	_, insertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO audit_log (user_id, actor_user_id, action, target_type, target_id, ip_address, user_agent, before_value, after_value)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8::JSONB, $9::JSONB)`,
+		entry.UserIdentifier,
+		entry.ActorUserIdentifier,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetIdentifier,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.BeforeValue,
+		entry.AfterValue,
+	)
+	return insertError
+}
+
+func (repository *PostgresAuditLogRepository) ListEntries(loadContext context.Context, userIdentifier int64, beforeIdentifier int64, limit int) ([]domain.AuditEntry, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT `+auditEntryColumns+` FROM audit_log
+		 WHERE ($1::BIGINT = 0 OR user_id = $1) AND ($2::BIGINT = 0 OR id < $2)
+		 ORDER BY id DESC
+		 LIMIT $3`,
+		userIdentifier,
+		beforeIdentifier,
+		limit,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	var entries []domain.AuditEntry
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var actorUserIdentifier sql.NullInt64
+		var beforeValue, afterValue sql.NullString
+		if scanError := rows.Scan(&entry.Identifier, &entry.UserIdentifier, &actorUserIdentifier, &entry.Action, &entry.TargetType, &entry.TargetIdentifier, &entry.IPAddress, &entry.UserAgent, &beforeValue, &afterValue, &entry.CreatedAt); scanError != nil {
+			return nil, scanError
+		}
+		if actorUserIdentifier.Valid {
+			entry.ActorUserIdentifier = &actorUserIdentifier.Int64
+		}
+		if beforeValue.Valid {
+			entry.BeforeValue = &beforeValue.String
+		}
+		if afterValue.Valid {
+			entry.AfterValue = &afterValue.String
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
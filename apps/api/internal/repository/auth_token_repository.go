@@ -11,6 +11,7 @@ import (
 const (
 	AuthTokenPurposePasswordReset     = "password_reset"
 	AuthTokenPurposeEmailVerification = "email_verification"
+	AuthTokenPurposeTwoFactorLogin    = "two_factor_login"
 )
 
 // ErrAuthTokenInvalid is returned when a token does not exist, is expired, or was already used.
@@ -29,6 +30,7 @@ type AuthTokenRepository interface {
 	FindValidByHash(lookupContext context.Context, tokenHash string, purpose string) (*AuthToken, error)
 	MarkUsed(operationContext context.Context, tokenIdentifier int64) error
 	InvalidateUserTokens(operationContext context.Context, userIdentifier int64, purpose string) error
+	RecordFailedAttempt(operationContext context.Context, tokenIdentifier int64, maximumAttempts int) error
 }
 
 type PostgresAuthTokenRepository struct {
@@ -86,3 +88,17 @@ func (repository *PostgresAuthTokenRepository) InvalidateUserTokens(operationCon
 	)
 	return executionError
 }
+
+// RecordFailedAttempt counts a wrong answer to a token's challenge (the second login factor) and
+// retires the token once maximumAttempts is reached.
+func (repository *PostgresAuthTokenRepository) RecordFailedAttempt(operationContext context.Context, tokenIdentifier int64, maximumAttempts int) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE auth_tokens
+		 SET failed_attempts = failed_attempts + 1,
+		     used_at = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() ELSE used_at END
+		 WHERE id = $1`,
+		tokenIdentifier, maximumAttempts,
+	)
+	return executionError
+}
@@ -31,13 +31,17 @@ func (repository *PostgresBinanceCredentialRepository) SaveCredentialForUser(sav
 
 	_, insertError := transaction.ExecContext(
 		saveContext,
-		`INSERT INTO binance_credentials (user_id, api_key, api_secret, environment, api_base_url, is_active)
-		 VALUES ($1, $2, $3, $4, $5, true)`,
-		userIdentifier,
-		credential.APIKey,
-		credential.APISecret,
-		credential.EnvironmentName,
-		credential.APIBaseURL,
+		`INSERT INTO binance_credentials (user_id, api_key, api_secret, environment, api_base_url, is_active,
+		     permissions_checked_at, reading_enabled, spot_trading_enabled, withdrawals_enabled, ip_restricted,
+		     health_status, health_checked_at)
+		 VALUES ($1, $2, $3, $4, $5, true, $6, $7, $8, $9, $10, 'HEALTHY', NOW())`,
+		append([]interface{}{
+			userIdentifier,
+			credential.APIKey,
+			credential.APISecret,
+			credential.EnvironmentName,
+			credential.APIBaseURL,
+		}, permissionArguments(credential.Permissions)...)...,
 	)
 	if insertError != nil {
 		transaction.Rollback()
@@ -50,7 +54,7 @@ func (repository *PostgresBinanceCredentialRepository) SaveCredentialForUser(sav
 func (repository *PostgresBinanceCredentialRepository) LoadActiveCredentialForUser(loadContext context.Context, userIdentifier int64) (*domain.BinanceCredentialRecord, error) {
 	row := repository.Database.QueryRowContext(
 		loadContext,
-		`SELECT api_key, api_secret, environment, api_base_url, is_active
+		`SELECT `+credentialRecordColumns+`
 		 FROM binance_credentials
 		 WHERE user_id = $1 AND is_active = true
 		 ORDER BY created_at DESC LIMIT 1`,
@@ -62,7 +66,7 @@ func (repository *PostgresBinanceCredentialRepository) LoadActiveCredentialForUs
 func (repository *PostgresBinanceCredentialRepository) LoadLatestCredentialForUserByEnvironment(loadContext context.Context, userIdentifier int64, environmentName string) (*domain.BinanceCredentialRecord, error) {
 	row := repository.Database.QueryRowContext(
 		loadContext,
-		`SELECT api_key, api_secret, environment, api_base_url, is_active
+		`SELECT `+credentialRecordColumns+`
 		 FROM binance_credentials
 		 WHERE user_id = $1 AND environment = $2
 		 ORDER BY created_at DESC LIMIT 1`,
@@ -125,14 +129,43 @@ func (repository *PostgresBinanceCredentialRepository) ListConfiguredEnvironment
 	return configuredEnvironments, rows.Err()
 }
 
+const credentialRecordColumns = `api_key, api_secret, environment, api_base_url, is_active,
+		 permissions_checked_at, reading_enabled, spot_trading_enabled, withdrawals_enabled, ip_restricted,
+		 health_status, health_checked_at, COALESCE(health_error, '')`
+
 func scanCredentialRecord(row *sql.Row) (*domain.BinanceCredentialRecord, error) {
 	record := &domain.BinanceCredentialRecord{}
-	scanError := row.Scan(&record.APIKey, &record.APISecret, &record.EnvironmentName, &record.APIBaseURL, &record.IsActive)
+	var permissionsCheckedAt sql.NullTime
+	var readingEnabled, spotTradingEnabled, withdrawalsEnabled, ipRestricted sql.NullBool
+	var healthCheckedAt sql.NullTime
+	scanError := row.Scan(&record.APIKey, &record.APISecret, &record.EnvironmentName, &record.APIBaseURL, &record.IsActive,
+		&permissionsCheckedAt, &readingEnabled, &spotTradingEnabled, &withdrawalsEnabled, &ipRestricted,
+		&record.Health.Status, &healthCheckedAt, &record.Health.LastError)
 	if errors.Is(scanError, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if scanError != nil {
 		return nil, scanError
 	}
+	if healthCheckedAt.Valid {
+		record.Health.CheckedAt = &healthCheckedAt.Time
+	}
+	if permissionsCheckedAt.Valid {
+		record.Permissions = &domain.BinanceKeyPermissions{
+			ReadingEnabled:     readingEnabled.Bool,
+			SpotTradingEnabled: spotTradingEnabled.Bool,
+			WithdrawalsEnabled: withdrawalsEnabled.Bool,
+			IPRestricted:       ipRestricted.Bool,
+			CheckedAt:          permissionsCheckedAt.Time,
+		}
+	}
 	return record, nil
 }
+
+// permissionArguments returns the five permission column values; all NULL when not checked.
+func permissionArguments(permissions *domain.BinanceKeyPermissions) []interface{} {
+	if permissions == nil {
+		return []interface{}{nil, nil, nil, nil, nil}
+	}
+	return []interface{}{permissions.CheckedAt, permissions.ReadingEnabled, permissions.SpotTradingEnabled, permissions.WithdrawalsEnabled, permissions.IPRestricted}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"coin-alert/internal/domain"
+)
+
+// CiphertextRepository walks every column encrypted with the credentials keyring so the values can be
+// re-encrypted under a new key.
+type CiphertextRepository interface {
+	// CiphertextTables names the tables with encrypted columns, in the order the job walks them.
+	CiphertextTables() []string
+	CountCiphertexts(loadContext context.Context, table string) (int, error)
+	// ListCiphertexts returns up to limit rows of table with an id above afterIdentifier, by id.
+	ListCiphertexts(loadContext context.Context, table string, afterIdentifier int64, limit int) ([]domain.StoredCiphertext, error)
+	// ReplaceCiphertext writes the re-encrypted values only if the row still holds the ones that were
+	// read, so a concurrent change is never overwritten. It reports whether the row was updated.
+	ReplaceCiphertext(operationContext context.Context, current domain.StoredCiphertext, reencryptedValues []string) (bool, error)
+}
+
+// encryptedTable is a table whose columns hold SecretCipher payloads. A new encrypted column must be
+// added here, or rotating the key leaves it unreadable.
+type encryptedTable struct {
+	name             string
+	identifierColumn string
+	columns          []string
+}
+
+var encryptedTables = []encryptedTable{
+	{name: "binance_credentials", identifierColumn: "id", columns: []string{"api_key", "api_secret"}},
+	{name: "notification_channels", identifierColumn: "id", columns: []string{"configuration"}},
+	{name: "webhook_endpoints", identifierColumn: "id", columns: []string{"signing_secret"}},
+	{name: "user_two_factor", identifierColumn: "user_id", columns: []string{"secret_ciphertext"}},
+}
+
+type PostgresCiphertextRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresCiphertextRepository(database *sql.DB) *PostgresCiphertextRepository {
+	return &PostgresCiphertextRepository{Database: database}
+}
+
+func (repository *PostgresCiphertextRepository) CiphertextTables() []string {
+	tables := make([]string, 0, len(encryptedTables))
+	for _, table := range encryptedTables {
+		tables = append(tables, table.name)
+	}
+	return tables
+}
+
+func (repository *PostgresCiphertextRepository) CountCiphertexts(loadContext context.Context, table string) (int, error) {
+	definition, lookupError := lookupEncryptedTable(table)
+	if lookupError != nil {
+		return 0, lookupError
+	}
+	var rowCount int
+	scanError := repository.Database.QueryRowContext(loadContext, `SELECT COUNT(*) FROM `+definition.name).Scan(&rowCount)
+	return rowCount, scanError
+}
+
+func (repository *PostgresCiphertextRepository) ListCiphertexts(loadContext context.Context, table string, afterIdentifier int64, limit int) ([]domain.StoredCiphertext, error) {
+	definition, lookupError := lookupEncryptedTable(table)
+	if lookupError != nil {
+		return nil, lookupError
+	}
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT `+definition.identifierColumn+`, `+strings.Join(definition.columns, ", ")+`
+		 FROM `+definition.name+`
+		 WHERE `+definition.identifierColumn+` > $1
+		 ORDER BY `+definition.identifierColumn+`
+		 LIMIT $2`,
+		afterIdentifier,
+		limit,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	var ciphertexts []domain.StoredCiphertext
+	for rows.Next() {
+		ciphertext := domain.StoredCiphertext{Table: definition.name, Values: make([]string, len(definition.columns))}
+		destinations := []interface{}{&ciphertext.Identifier}
+		for index := range ciphertext.Values {
+			destinations = append(destinations, &ciphertext.Values[index])
+		}
+		if scanError := rows.Scan(destinations...); scanError != nil {
+			return nil, scanError
+		}
+		ciphertexts = append(ciphertexts, ciphertext)
+	}
+	return ciphertexts, rows.Err()
+}
+
+func (repository *PostgresCiphertextRepository) ReplaceCiphertext(operationContext context.Context, current domain.StoredCiphertext, reencryptedValues []string) (bool, error) {
+	definition, lookupError := lookupEncryptedTable(current.Table)
+	if lookupError != nil {
+		return false, lookupError
+	}
+	if len(current.Values) != len(definition.columns) || len(reencryptedValues) != len(definition.columns) {
+		return false, fmt.Errorf("%s has %d encrypted columns", definition.name, len(definition.columns))
+	}
+	// $1 is the id, $2.. the new values, then the values that were read.
+	assignments := make([]string, 0, len(definition.columns))
+	conditions := []string{definition.identifierColumn + " = $1"}
+	arguments := []interface{}{current.Identifier}
+	for index, column := range definition.columns {
+		assignments = append(assignments, column+" = $"+strconv.Itoa(2+index))
+		conditions = append(conditions, column+" = $"+strconv.Itoa(2+len(definition.columns)+index))
+		arguments = append(arguments, reencryptedValues[index])
+	}
+	for _, value := range current.Values {
+		arguments = append(arguments, value)
+	}
+	result, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE `+definition.name+` SET `+strings.Join(assignments, ", ")+` WHERE `+strings.Join(conditions, " AND "),
+		arguments...,
+	)
+	if updateError != nil {
+		return false, updateError
+	}
+	affectedRows, affectedError := result.RowsAffected()
+	if affectedError != nil {
+		return false, affectedError
+	}
+	return affectedRows > 0, nil
+}
+
+func lookupEncryptedTable(table string) (encryptedTable, error) {
+	for _, definition := range encryptedTables {
+		if definition.name == table {
+			return definition, nil
+		}
+	}
+	return encryptedTable{}, fmt.Errorf("%q has no encrypted columns", table)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+// CredentialHealthRepository supports the periodic revalidation of stored Binance keys.
+type CredentialHealthRepository interface {
+	// ListCredentialsDueForHealthCheck returns the newest key per user and environment (the one that
+	// would be used) that was not checked since checkedBefore, oldest check first.
+	ListCredentialsDueForHealthCheck(loadContext context.Context, checkedBefore time.Time, limit int) ([]domain.CredentialHealthCheckTarget, error)
+	// RecordCredentialHealth stores a check result; an empty status keeps the current one.
+	RecordCredentialHealth(operationContext context.Context, credentialIdentifier int64, status string, lastError string) error
+}
+
+func (repository *PostgresBinanceCredentialRepository) ListCredentialsDueForHealthCheck(loadContext context.Context, checkedBefore time.Time, limit int) ([]domain.CredentialHealthCheckTarget, error) {
+	rows, queryError := querierFor(loadContext, repository.Database).QueryContext(
+		loadContext,
+		`SELECT id, user_id, environment, api_base_url, api_key, api_secret, health_status
+		 FROM (
+		     SELECT DISTINCT ON (user_id, environment)
+		            id, user_id, environment, api_base_url, api_key, api_secret, health_status, health_checked_at
+		     FROM binance_credentials
+		     WHERE user_id IS NOT NULL
+		     ORDER BY user_id, environment, created_at DESC
+		 ) latest
+		 WHERE health_checked_at IS NULL OR health_checked_at < $1
+		 ORDER BY health_checked_at NULLS FIRST
+		 LIMIT $2`,
+		checkedBefore,
+		limit,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	var targets []domain.CredentialHealthCheckTarget
+	for rows.Next() {
+		var target domain.CredentialHealthCheckTarget
+		if scanError := rows.Scan(&target.Identifier, &target.UserIdentifier, &target.EnvironmentName, &target.APIBaseURL, &target.APIKey, &target.APISecret, &target.HealthStatus); scanError != nil {
+			return nil, scanError
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+func (repository *PostgresBinanceCredentialRepository) RecordCredentialHealth(operationContext context.Context, credentialIdentifier int64, status string, lastError string) error {
+	_, executionError := querierFor(operationContext, repository.Database).ExecContext(
+		operationContext,
+		`UPDATE binance_credentials
+		 SET health_status = COALESCE(NULLIF($2, ''), health_status),
+		     health_checked_at = NOW(),
+		     health_error = NULLIF($3, '')
+		 WHERE id = $1`,
+		credentialIdentifier,
+		status,
+		lastError,
+	)
+	return executionError
+}
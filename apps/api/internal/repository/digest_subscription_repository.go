@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+// ErrDigestSubscriptionNotFound is returned when the user never configured the digest.
+var ErrDigestSubscriptionNotFound = errors.New("digest subscription not found")
+
+const digestSubscriptionColumns = `user_id, frequency, local_hour, weekday, time_zone, locale, is_enabled, last_sent_at, created_at, updated_at`
+
+// DigestSubscriptionRepository persists the per-user digest email settings.
+type DigestSubscriptionRepository interface {
+	GetSubscriptionForUser(loadContext context.Context, userIdentifier int64) (*domain.DigestSubscription, error)
+	SaveSubscriptionForUser(operationContext context.Context, subscription domain.DigestSubscription) error
+	ListEnabledSubscriptions(loadContext context.Context) ([]domain.DigestSubscription, error)
+	MarkDigestSentForUser(operationContext context.Context, userIdentifier int64, sentAt time.Time) error
+}
+
+type PostgresDigestSubscriptionRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresDigestSubscriptionRepository(database *sql.DB) *PostgresDigestSubscriptionRepository {
+	return &PostgresDigestSubscriptionRepository{Database: database}
+}
+
+func (repository *PostgresDigestSubscriptionRepository) GetSubscriptionForUser(loadContext context.Context, userIdentifier int64) (*domain.DigestSubscription, error) {
+	row := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT `+digestSubscriptionColumns+` FROM digest_subscriptions WHERE user_id = $1`,
+		userIdentifier,
+	)
+	subscription, scanError := scanDigestSubscription(row)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, ErrDigestSubscriptionNotFound
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+	return subscription, nil
+}
+
+// SaveSubscriptionForUser creates or replaces the settings. last_sent_at is preserved so changing the
+// schedule does not immediately re-send a digest that already went out today.
+func (repository *PostgresDigestSubscriptionRepository) SaveSubscriptionForUser(operationContext context.Context, subscription domain.DigestSubscription) error {
+	_, upsertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO digest_subscriptions (user_id, frequency, local_hour, weekday, time_zone, locale, is_enabled)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (user_id) DO UPDATE SET
+		    frequency = EXCLUDED.frequency,
+		    local_hour = EXCLUDED.local_hour,
+		    weekday = EXCLUDED.weekday,
+		    time_zone = EXCLUDED.time_zone,
+		    locale = EXCLUDED.locale,
+		    is_enabled = EXCLUDED.is_enabled,
+		    updated_at = NOW()`,
+		subscription.UserIdentifier,
+		subscription.Frequency,
+		subscription.LocalHour,
+		subscription.Weekday,
+		subscription.TimeZone,
+		subscription.Locale,
+		subscription.IsEnabled,
+	)
+	return upsertError
+}
+
+func (repository *PostgresDigestSubscriptionRepository) ListEnabledSubscriptions(loadContext context.Context) ([]domain.DigestSubscription, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT `+digestSubscriptionColumns+` FROM digest_subscriptions WHERE is_enabled = TRUE`,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	subscriptions := make([]domain.DigestSubscription, 0)
+	for rows.Next() {
+		subscription, scanError := scanDigestSubscription(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (repository *PostgresDigestSubscriptionRepository) MarkDigestSentForUser(operationContext context.Context, userIdentifier int64, sentAt time.Time) error {
+	_, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE digest_subscriptions SET last_sent_at = $1 WHERE user_id = $2`,
+		sentAt, userIdentifier,
+	)
+	return updateError
+}
+
+type digestSubscriptionScanner interface {
+	Scan(destination ...interface{}) error
+}
+
+func scanDigestSubscription(scanner digestSubscriptionScanner) (*domain.DigestSubscription, error) {
+	var subscription domain.DigestSubscription
+	var lastSentAt sql.NullTime
+	scanError := scanner.Scan(
+		&subscription.UserIdentifier,
+		&subscription.Frequency,
+		&subscription.LocalHour,
+		&subscription.Weekday,
+		&subscription.TimeZone,
+		&subscription.Locale,
+		&subscription.IsEnabled,
+		&lastSentAt,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+	if lastSentAt.Valid {
+		value := lastSentAt.Time
+		subscription.LastSentAt = &value
+	}
+	return &subscription, nil
+}
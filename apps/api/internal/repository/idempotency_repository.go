@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+// IdempotencyRepository stores the first response per user and Idempotency-Key.
+type IdempotencyRepository interface {
+	// ClaimIdempotencyKey records an in-flight request for the user's key, replacing an expired row.
+	// It returns nil when the claim succeeded, or the existing unexpired record otherwise.
+	ClaimIdempotencyKey(operationContext context.Context, userIdentifier int64, key string, requestFingerprint string, expiresAt time.Time) (*domain.IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response of a claimed request.
+	CompleteIdempotencyKey(operationContext context.Context, userIdentifier int64, key string, responseStatus int, responseLocation string, responseBody []byte) error
+	// ReleaseIdempotencyKey drops a claim so the request may be sent again with the same key.
+	ReleaseIdempotencyKey(operationContext context.Context, userIdentifier int64, key string) error
+	DeleteExpiredIdempotencyKeys(operationContext context.Context) (int64, error)
+}
+
+type PostgresIdempotencyRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresIdempotencyRepository(database *sql.DB) *PostgresIdempotencyRepository {
+	return &PostgresIdempotencyRepository{Database: database}
+}
+
+func (repository *PostgresIdempotencyRepository) ClaimIdempotencyKey(operationContext context.Context, userIdentifier int64, key string, requestFingerprint string, expiresAt time.Time) (*domain.IdempotencyRecord, error) {
+	// Two attempts: the existing row may expire or be released between the insert and the lookup.
+	for attempt := 0; attempt < 2; attempt++ {
+		var claimedUser int64
+		claimError := repository.Database.QueryRowContext(
+			operationContext,
+			`INSERT INTO idempotency_keys (user_id, idempotency_key, request_fingerprint, expires_at)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (user_id, idempotency_key) DO UPDATE
+			 SET request_fingerprint = EXCLUDED.request_fingerprint, response_status = NULL, response_location = NULL,
+			     response_body = NULL, created_at = NOW(), expires_at = EXCLUDED.expires_at
+			 WHERE idempotency_keys.expires_at <= NOW()
+			 RETURNING user_id`,
+			userIdentifier, key, requestFingerprint, expiresAt,
+		).Scan(&claimedUser)
+		if claimError == nil {
+			return nil, nil
+		}
+		if !errors.Is(claimError, sql.ErrNoRows) {
+			return nil, claimError
+		}
+		record, loadError := repository.loadIdempotencyRecord(operationContext, userIdentifier, key)
+		if loadError == nil {
+			return record, nil
+		}
+		if !errors.Is(loadError, sql.ErrNoRows) {
+			return nil, loadError
+		}
+	}
+	return nil, errors.New("could not claim the idempotency key")
+}
+
+func (repository *PostgresIdempotencyRepository) loadIdempotencyRecord(loadContext context.Context, userIdentifier int64, key string) (*domain.IdempotencyRecord, error) {
+	record := domain.IdempotencyRecord{UserIdentifier: userIdentifier, Key: key}
+	var responseStatus sql.NullInt64
+	var responseLocation sql.NullString
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT request_fingerprint, response_status, response_location, response_body, created_at, expires_at
+		 FROM idempotency_keys
+		 WHERE user_id = $1 AND idempotency_key = $2 AND expires_at > NOW()`,
+		userIdentifier, key,
+	).Scan(&record.RequestFingerprint, &responseStatus, &responseLocation, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+	record.ResponseStatus = int(responseStatus.Int64)
+	record.ResponseLocation = responseLocation.String
+	return &record, nil
+}
+
+func (repository *PostgresIdempotencyRepository) CompleteIdempotencyKey(operationContext context.Context, userIdentifier int64, key string, responseStatus int, responseLocation string, responseBody []byte) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE idempotency_keys
+		 SET response_status = $3, response_location = NULLIF($4, ''), response_body = $5
+		 WHERE user_id = $1 AND idempotency_key = $2`,
+		userIdentifier, key, responseStatus, responseLocation, responseBody,
+	)
+	return executionError
+}
+
+func (repository *PostgresIdempotencyRepository) ReleaseIdempotencyKey(operationContext context.Context, userIdentifier int64, key string) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND response_status IS NULL`,
+		userIdentifier, key,
+	)
+	return executionError
+}
+
+func (repository *PostgresIdempotencyRepository) DeleteExpiredIdempotencyKeys(operationContext context.Context) (int64, error) {
+	result, executionError := repository.Database.ExecContext(operationContext, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if executionError != nil {
+		return 0, executionError
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"coin-alert/internal/domain"
+)
+
+// ErrNotificationChannelNotFound is returned when no channel matches the id for the given user.
+var ErrNotificationChannelNotFound = errors.New("notification channel not found")
+
+const notificationChannelColumns = `id, user_id, channel_type, label, configuration, is_enabled, created_at, updated_at`
+
+// NotificationChannelRepository persists per-user notification destinations. The configuration column
+// holds the encrypted JSON document; encryption/decryption is the service's job.
+type NotificationChannelRepository interface {
+	ListChannelsForUser(loadContext context.Context, userIdentifier int64) ([]domain.NotificationChannel, error)
+	ListEnabledChannelsForUser(loadContext context.Context, userIdentifier int64) ([]domain.NotificationChannel, error)
+	GetChannelForUser(loadContext context.Context, userIdentifier int64, channelIdentifier int64) (*domain.NotificationChannel, error)
+	CountChannelsForUser(loadContext context.Context, userIdentifier int64) (int, error)
+	CreateChannelForUser(operationContext context.Context, userIdentifier int64, channel domain.NotificationChannel) (int64, error)
+	SetChannelEnabledForUser(operationContext context.Context, userIdentifier int64, channelIdentifier int64, isEnabled bool) error
+	DeleteChannelForUser(operationContext context.Context, userIdentifier int64, channelIdentifier int64) error
+}
+
+type PostgresNotificationChannelRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresNotificationChannelRepository(database *sql.DB) *PostgresNotificationChannelRepository {
+	return &PostgresNotificationChannelRepository{Database: database}
+}
+
+func (repository *PostgresNotificationChannelRepository) ListChannelsForUser(loadContext context.Context, userIdentifier int64) ([]domain.NotificationChannel, error) {
+	return repository.queryChannels(loadContext,
+		`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE user_id = $1 ORDER BY created_at ASC`,
+		userIdentifier,
+	)
+}
+
+func (repository *PostgresNotificationChannelRepository) ListEnabledChannelsForUser(loadContext context.Context, userIdentifier int64) ([]domain.NotificationChannel, error) {
+	return repository.queryChannels(loadContext,
+		`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE user_id = $1 AND is_enabled = TRUE ORDER BY created_at ASC`,
+		userIdentifier,
+	)
+}
+
+func (repository *PostgresNotificationChannelRepository) GetChannelForUser(loadContext context.Context, userIdentifier int64, channelIdentifier int64) (*domain.NotificationChannel, error) {
+	row := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE id = $1 AND user_id = $2`,
+		channelIdentifier, userIdentifier,
+	)
+	var channel domain.NotificationChannel
+	scanError := row.Scan(&channel.Identifier, &channel.UserIdentifier, &channel.ChannelType, &channel.Label, &channel.Configuration, &channel.IsEnabled, &channel.CreatedAt, &channel.UpdatedAt)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, ErrNotificationChannelNotFound
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+	return &channel, nil
+}
+
+func (repository *PostgresNotificationChannelRepository) CountChannelsForUser(loadContext context.Context, userIdentifier int64) (int, error) {
+	row := repository.Database.QueryRowContext(loadContext, `SELECT COUNT(*) FROM notification_channels WHERE user_id = $1`, userIdentifier)
+	var channelCount int
+	if scanError := row.Scan(&channelCount); scanError != nil {
+		return 0, scanError
+	}
+	return channelCount, nil
+}
+
+func (repository *PostgresNotificationChannelRepository) CreateChannelForUser(operationContext context.Context, userIdentifier int64, channel domain.NotificationChannel) (int64, error) {
+	row := repository.Database.QueryRowContext(
+		operationContext,
+		`INSERT INTO notification_channels (user_id, channel_type, label, configuration, is_enabled)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id`,
+		userIdentifier,
+		channel.ChannelType,
+		channel.Label,
+		channel.Configuration,
+		channel.IsEnabled,
+	)
+	var channelIdentifier int64
+	if scanError := row.Scan(&channelIdentifier); scanError != nil {
+		return 0, scanError
+	}
+	return channelIdentifier, nil
+}
+
+func (repository *PostgresNotificationChannelRepository) SetChannelEnabledForUser(operationContext context.Context, userIdentifier int64, channelIdentifier int64, isEnabled bool) error {
+	result, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE notification_channels SET is_enabled = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3`,
+		isEnabled, channelIdentifier, userIdentifier,
+	)
+	if updateError != nil {
+		return updateError
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return ErrNotificationChannelNotFound
+	}
+	return nil
+}
+
+func (repository *PostgresNotificationChannelRepository) DeleteChannelForUser(operationContext context.Context, userIdentifier int64, channelIdentifier int64) error {
+	result, deleteError := repository.Database.ExecContext(
+		operationContext,
+		`DELETE FROM notification_channels WHERE id = $1 AND user_id = $2`,
+		channelIdentifier, userIdentifier,
+	)
+	if deleteError != nil {
+		return deleteError
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return ErrNotificationChannelNotFound
+	}
+	return nil
+}
+
+func (repository *PostgresNotificationChannelRepository) queryChannels(loadContext context.Context, querySQL string, arguments ...interface{}) ([]domain.NotificationChannel, error) {
+	rows, queryError := repository.Database.QueryContext(loadContext, querySQL, arguments...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	channels := make([]domain.NotificationChannel, 0)
+	for rows.Next() {
+		var channel domain.NotificationChannel
+		if scanError := rows.Scan(&channel.Identifier, &channel.UserIdentifier, &channel.ChannelType, &channel.Label, &channel.Configuration, &channel.IsEnabled, &channel.CreatedAt, &channel.UpdatedAt); scanError != nil {
+			return nil, scanError
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
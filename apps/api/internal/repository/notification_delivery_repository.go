@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"coin-alert/internal/domain"
+)
+
+// NotificationDeliveryRepository is the delivery log: one row per attempt to send a notification
+// through a channel, successful or not.
+type NotificationDeliveryRepository interface {
+	RecordDelivery(operationContext context.Context, delivery domain.NotificationDelivery) error
+	ListRecentDeliveriesForUser(loadContext context.Context, userIdentifier int64, limit int) ([]domain.NotificationDelivery, error)
+}
+
+type PostgresNotificationDeliveryRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresNotificationDeliveryRepository(database *sql.DB) *PostgresNotificationDeliveryRepository {
+	return &PostgresNotificationDeliveryRepository{Database: database}
+}
+
+func (repository *PostgresNotificationDeliveryRepository) RecordDelivery(operationContext context.Context, delivery domain.NotificationDelivery) error {
+	_, insertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO notification_deliveries (user_id, channel_id, channel_type, event_type, subject, success, error_message)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		delivery.UserIdentifier,
+		delivery.ChannelIdentifier,
+		delivery.ChannelType,
+		delivery.EventType,
+		delivery.Subject,
+		delivery.Success,
+		delivery.ErrorMessage,
+	)
+	return insertError
+}
+
+func (repository *PostgresNotificationDeliveryRepository) ListRecentDeliveriesForUser(loadContext context.Context, userIdentifier int64, limit int) ([]domain.NotificationDelivery, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT id, user_id, channel_id, channel_type, event_type, subject, success, error_message, created_at
+		 FROM notification_deliveries
+		 WHERE user_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`,
+		userIdentifier, limit,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.NotificationDelivery, 0)
+	for rows.Next() {
+		var delivery domain.NotificationDelivery
+		var channelIdentifier sql.NullInt64
+		var errorMessage sql.NullString
+		if scanError := rows.Scan(&delivery.Identifier, &delivery.UserIdentifier, &channelIdentifier, &delivery.ChannelType, &delivery.EventType, &delivery.Subject, &delivery.Success, &errorMessage, &delivery.CreatedAt); scanError != nil {
+			return nil, scanError
+		}
+		if channelIdentifier.Valid {
+			value := channelIdentifier.Int64
+			delivery.ChannelIdentifier = &value
+		}
+		if errorMessage.Valid {
+			value := errorMessage.String
+			delivery.ErrorMessage = &value
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// NotificationPreferenceRepository stores the per-user, per-event-type notification opt-in.
+type NotificationPreferenceRepository interface {
+	ListPreferencesForUser(loadContext context.Context, userIdentifier int64) (map[string]bool, error)
+	IsEventEnabledForUser(loadContext context.Context, userIdentifier int64, eventType string) (bool, error)
+	SetPreferenceForUser(operationContext context.Context, userIdentifier int64, eventType string, isEnabled bool) error
+	// LoadLocaleForUser returns the language for trade alerts, or "" when none was saved.
+	LoadLocaleForUser(loadContext context.Context, userIdentifier int64) (string, error)
+	SaveLocaleForUser(operationContext context.Context, userIdentifier int64, locale string) error
+}
+
+type PostgresNotificationPreferenceRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresNotificationPreferenceRepository(database *sql.DB) *PostgresNotificationPreferenceRepository {
+	return &PostgresNotificationPreferenceRepository{Database: database}
+}
+
+// ListPreferencesForUser returns the stored opt-ins keyed by event type; missing types are not opted in.
+func (repository *PostgresNotificationPreferenceRepository) ListPreferencesForUser(loadContext context.Context, userIdentifier int64) (map[string]bool, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT event_type, is_enabled FROM notification_preferences WHERE user_id = $1`,
+		userIdentifier,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	preferences := make(map[string]bool)
+	for rows.Next() {
+		var eventType string
+		var isEnabled bool
+		if scanError := rows.Scan(&eventType, &isEnabled); scanError != nil {
+			return nil, scanError
+		}
+		preferences[eventType] = isEnabled
+	}
+	return preferences, rows.Err()
+}
+
+func (repository *PostgresNotificationPreferenceRepository) IsEventEnabledForUser(loadContext context.Context, userIdentifier int64, eventType string) (bool, error) {
+	row := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT COALESCE((SELECT is_enabled FROM notification_preferences WHERE user_id = $1 AND event_type = $2), false)`,
+		userIdentifier, eventType,
+	)
+	var isEnabled bool
+	if scanError := row.Scan(&isEnabled); scanError != nil {
+		return false, scanError
+	}
+	return isEnabled, nil
+}
+
+func (repository *PostgresNotificationPreferenceRepository) SetPreferenceForUser(operationContext context.Context, userIdentifier int64, eventType string, isEnabled bool) error {
+	_, upsertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO notification_preferences (user_id, event_type, is_enabled, updated_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT (user_id, event_type) DO UPDATE SET is_enabled = EXCLUDED.is_enabled, updated_at = NOW()`,
+		userIdentifier, eventType, isEnabled,
+	)
+	return upsertError
+}
+
+func (repository *PostgresNotificationPreferenceRepository) LoadLocaleForUser(loadContext context.Context, userIdentifier int64) (string, error) {
+	var locale string
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT locale FROM notification_locales WHERE user_id = $1`,
+		userIdentifier,
+	).Scan(&locale)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return "", nil
+	}
+	return locale, scanError
+}
+
+func (repository *PostgresNotificationPreferenceRepository) SaveLocaleForUser(operationContext context.Context, userIdentifier int64, locale string) error {
+	_, upsertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO notification_locales (user_id, locale, updated_at)
+		 VALUES ($1, $2, NOW())
+		 ON CONFLICT (user_id) DO UPDATE SET locale = EXCLUDED.locale, updated_at = NOW()`,
+		userIdentifier, locale,
+	)
+	return upsertError
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"coin-alert/internal/domain"
+)
+
+// OutboxRepository stores domain events and which subscribers have handled them.
+type OutboxRepository interface {
+	// AppendEvent joins the caller's transaction when the context carries one (see RunInTransaction).
+	AppendEvent(operationContext context.Context, event domain.OutboxEvent) (int64, error)
+	// ListPendingEvents returns, oldest first, events of the given types (all types when empty) that
+	// the subscriber has not handled yet and that are due for a (re)try.
+	ListPendingEvents(loadContext context.Context, subscriber string, eventTypes []string, limit int) ([]domain.OutboxEvent, error)
+	MarkEventHandled(operationContext context.Context, eventIdentifier int64, subscriber string) error
+	MarkEventFailed(operationContext context.Context, eventIdentifier int64, subscriber string, errorMessage string, nextAttemptAt time.Time, giveUp bool) error
+	DeleteEventsCreatedBefore(operationContext context.Context, cutoff time.Time) (int64, error)
+}
+
+type PostgresOutboxRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresOutboxRepository(database *sql.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{Database: database}
+}
+
+func (repository *PostgresOutboxRepository) AppendEvent(operationContext context.Context, event domain.OutboxEvent) (int64, error) {
+	var eventIdentifier int64
+	insertError := querierFor(operationContext, repository.Database).QueryRowContext(
+		operationContext,
+		`INSERT INTO outbox_events (user_id, event_type, payload) VALUES ($1, $2, $3) RETURNING id`,
+		event.UserIdentifier,
+		event.EventType,
+		event.Payload,
+	).Scan(&eventIdentifier)
+	return eventIdentifier, insertError
+}
+
+func (repository *PostgresOutboxRepository) ListPendingEvents(loadContext context.Context, subscriber string, eventTypes []string, limit int) ([]domain.OutboxEvent, error) {
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT events.id, events.user_id, events.event_type, events.payload, events.created_at, COALESCE(receipts.attempt_count, 0)
+		 FROM outbox_events events
+		 LEFT JOIN outbox_event_receipts receipts ON receipts.event_id = events.id AND receipts.subscriber = $1
+		 WHERE (cardinality($2::TEXT[]) = 0 OR events.event_type = ANY($2::TEXT[]))
+		   AND (receipts.event_id IS NULL OR (receipts.status = 'PENDING' AND receipts.next_attempt_at <= NOW()))
+		 ORDER BY events.id ASC
+		 LIMIT $3`,
+		subscriber,
+		pq.Array(eventTypes),
+		limit,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	var events []domain.OutboxEvent
+	for rows.Next() {
+		var event domain.OutboxEvent
+		if scanError := rows.Scan(&event.Identifier, &event.UserIdentifier, &event.EventType, &event.Payload, &event.CreatedAt, &event.AttemptCount); scanError != nil {
+			return nil, scanError
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (repository *PostgresOutboxRepository) MarkEventHandled(operationContext context.Context, eventIdentifier int64, subscriber string) error {
+	_, upsertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO outbox_event_receipts (event_id, subscriber, status, attempt_count, updated_at)
+		 VALUES ($1, $2, 'DONE', 1, NOW())
+		 ON CONFLICT (event_id, subscriber) DO UPDATE
+		 SET status = 'DONE', attempt_count = outbox_event_receipts.attempt_count + 1, last_error = NULL, updated_at = NOW()`,
+		eventIdentifier,
+		subscriber,
+	)
+	return upsertError
+}
+
+func (repository *PostgresOutboxRepository) MarkEventFailed(operationContext context.Context, eventIdentifier int64, subscriber string, errorMessage string, nextAttemptAt time.Time, giveUp bool) error {
+	status := domain.OutboxReceiptPending
+	if giveUp {
+		status = domain.OutboxReceiptDead
+	}
+	_, upsertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO outbox_event_receipts (event_id, subscriber, status, attempt_count, next_attempt_at, last_error, updated_at)
+		 VALUES ($1, $2, $3, 1, $4, $5, NOW())
+		 ON CONFLICT (event_id, subscriber) DO UPDATE
+		 SET status = $3, attempt_count = outbox_event_receipts.attempt_count + 1, next_attempt_at = $4, last_error = $5, updated_at = NOW()`,
+		eventIdentifier,
+		subscriber,
+		status,
+		nextAttemptAt,
+		errorMessage,
+	)
+	return upsertError
+}
+
+func (repository *PostgresOutboxRepository) DeleteEventsCreatedBefore(operationContext context.Context, cutoff time.Time) (int64, error) {
+	result, deleteError := repository.Database.ExecContext(operationContext, `DELETE FROM outbox_events WHERE created_at < $1`, cutoff)
+	if deleteError != nil {
+		return 0, deleteError
+	}
+	return result.RowsAffected()
+}
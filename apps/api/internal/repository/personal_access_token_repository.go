@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"coin-alert/internal/domain"
+)
+
+// ErrPersonalAccessTokenNotFound is returned when no token matches the id for the given user.
+var ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+
+const personalAccessTokenColumns = `t.id, t.user_id, t.name, t.token_hash, t.token_prefix, t.scopes, t.expires_at,
+	t.last_used_at, COALESCE(t.last_used_ip, ''), t.revoked_at, t.created_at`
+
+// PersonalAccessTokenRepository persists the API tokens users create for scripts.
+type PersonalAccessTokenRepository interface {
+	ListTokensForUser(loadContext context.Context, userIdentifier int64) ([]domain.PersonalAccessToken, error)
+	CountActiveTokensForUser(loadContext context.Context, userIdentifier int64) (int, error)
+	CreateToken(operationContext context.Context, token domain.PersonalAccessToken) (int64, error)
+	// FindTokenByHash returns the token for a hash whose owner is still active, or (nil, nil) if none.
+	// Revoked and expired tokens are returned too; the caller decides.
+	FindTokenByHash(lookupContext context.Context, tokenHash string) (*domain.PersonalAccessToken, error)
+	// RecordTokenUse stores the last use, at most once a minute per token so busy scripts do not write
+	// on every request.
+	RecordTokenUse(operationContext context.Context, tokenIdentifier int64, ipAddress string) error
+	RevokeTokenForUser(operationContext context.Context, userIdentifier int64, tokenIdentifier int64) error
+}
+
+type PostgresPersonalAccessTokenRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresPersonalAccessTokenRepository(database *sql.DB) *PostgresPersonalAccessTokenRepository {
+	return &PostgresPersonalAccessTokenRepository{Database: database}
+}
+
+func (repository *PostgresPersonalAccessTokenRepository) ListTokensForUser(loadContext context.Context, userIdentifier int64) ([]domain.PersonalAccessToken, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens t
+		 WHERE t.user_id = $1
+		 ORDER BY t.created_at DESC`,
+		userIdentifier,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	tokens := make([]domain.PersonalAccessToken, 0)
+	for rows.Next() {
+		token, scanError := scanPersonalAccessToken(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func (repository *PostgresPersonalAccessTokenRepository) CountActiveTokensForUser(loadContext context.Context, userIdentifier int64) (int, error) {
+	var tokenCount int
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT COUNT(*) FROM personal_access_tokens
+		 WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`,
+		userIdentifier,
+	).Scan(&tokenCount)
+	return tokenCount, scanError
+}
+
+func (repository *PostgresPersonalAccessTokenRepository) CreateToken(operationContext context.Context, token domain.PersonalAccessToken) (int64, error) {
+	var tokenIdentifier int64
+	scanError := repository.Database.QueryRowContext(
+		operationContext,
+		`INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id`,
+		token.UserIdentifier,
+		token.Name,
+		token.TokenHash,
+		token.TokenPrefix,
+		pq.Array(token.Scopes),
+		token.ExpiresAt,
+	).Scan(&tokenIdentifier)
+	return tokenIdentifier, scanError
+}
+
+func (repository *PostgresPersonalAccessTokenRepository) FindTokenByHash(lookupContext context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	row := repository.Database.QueryRowContext(
+		lookupContext,
+		`SELECT `+personalAccessTokenColumns+` FROM personal_access_tokens t
+		 JOIN users u ON u.id = t.user_id
+		 WHERE t.token_hash = $1 AND u.is_active = TRUE`,
+		tokenHash,
+	)
+	token, scanError := scanPersonalAccessToken(row)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return token, scanError
+}
+
+func (repository *PostgresPersonalAccessTokenRepository) RecordTokenUse(operationContext context.Context, tokenIdentifier int64, ipAddress string) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE personal_access_tokens SET last_used_at = NOW(), last_used_ip = NULLIF($2, '')
+		 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`,
+		tokenIdentifier, ipAddress,
+	)
+	return executionError
+}
+
+func (repository *PostgresPersonalAccessTokenRepository) RevokeTokenForUser(operationContext context.Context, userIdentifier int64, tokenIdentifier int64) error {
+	result, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE personal_access_tokens SET revoked_at = NOW()
+		 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		tokenIdentifier, userIdentifier,
+	)
+	if updateError != nil {
+		return updateError
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+type personalAccessTokenScanner interface {
+	Scan(destinations ...interface{}) error
+}
+
+func scanPersonalAccessToken(scanner personalAccessTokenScanner) (*domain.PersonalAccessToken, error) {
+	var token domain.PersonalAccessToken
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	if scanError := scanner.Scan(
+		&token.Identifier,
+		&token.UserIdentifier,
+		&token.Name,
+		&token.TokenHash,
+		&token.TokenPrefix,
+		pq.Array(&token.Scopes),
+		&expiresAt,
+		&lastUsedAt,
+		&token.LastUsedIP,
+		&revokedAt,
+		&token.CreatedAt,
+	); scanError != nil {
+		return nil, scanError
+	}
+	token.ExpiresAt = nullTimePointer(expiresAt)
+	token.LastUsedAt = nullTimePointer(lastUsedAt)
+	token.RevokedAt = nullTimePointer(revokedAt)
+	return &token, nil
+}
+
+func nullTimePointer(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	moment := value.Time
+	return &moment
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"coin-alert/internal/domain"
+)
+
+// PlatformPolicyRepository reads and updates the single platform_policy row.
+type PlatformPolicyRepository interface {
+	GetPolicy(loadContext context.Context) (domain.PlatformPolicy, error)
+	UpdatePolicy(operationContext context.Context, policy domain.PlatformPolicy) error
+}
+
+type PostgresPlatformPolicyRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresPlatformPolicyRepository(database *sql.DB) *PostgresPlatformPolicyRepository {
+	return &PostgresPlatformPolicyRepository{Database: database}
+}
+
+// GetPolicy returns the stored policy, or the defaults when the row is missing.
+func (repository *PostgresPlatformPolicyRepository) GetPolicy(loadContext context.Context) (domain.PlatformPolicy, error) {
+	policy := domain.PlatformPolicy{WithdrawalKeyPolicy: domain.WithdrawalKeyPolicyRefuse}
+	var updatedByUserIdentifier sql.NullInt64
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT withdrawal_key_policy, require_two_factor_for_live_trading, updated_by_user_id, updated_at
+		 FROM platform_policy WHERE id = 1`,
+	).Scan(&policy.WithdrawalKeyPolicy, &policy.RequireTwoFactorForLiveTrading, &updatedByUserIdentifier, &policy.UpdatedAt)
+	if scanError == sql.ErrNoRows {
+		return policy, nil
+	}
+	if scanError != nil {
+		return domain.PlatformPolicy{}, scanError
+	}
+	if updatedByUserIdentifier.Valid {
+		policy.UpdatedByUserIdentifier = &updatedByUserIdentifier.Int64
+	}
+	return policy, nil
+}
+
+func (repository *PostgresPlatformPolicyRepository) UpdatePolicy(operationContext context.Context, policy domain.PlatformPolicy) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO platform_policy (id, withdrawal_key_policy, require_two_factor_for_live_trading, updated_by_user_id, updated_at)
+		 VALUES (1, $1, $2, $3, NOW())
+		 ON CONFLICT (id) DO UPDATE
+		 SET withdrawal_key_policy = EXCLUDED.withdrawal_key_policy,
+		     require_two_factor_for_live_trading = EXCLUDED.require_two_factor_for_live_trading,
+		     updated_by_user_id = EXCLUDED.updated_by_user_id,
+		     updated_at = NOW()`,
+		policy.WithdrawalKeyPolicy,
+		policy.RequireTwoFactorForLiveTrading,
+		policy.UpdatedByUserIdentifier,
+	)
+	return executionError
+}
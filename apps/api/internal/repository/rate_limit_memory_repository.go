@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryRateLimitEntry struct {
+	hitCount     int
+	windowEndsAt time.Time
+	blockedUntil time.Time
+}
+
+// MemoryRateLimitRepository keeps the throttling state in process memory. Counters are lost on
+// restart and not shared between instances, so it only suits single-instance deployments.
+type MemoryRateLimitRepository struct {
+	mutex   sync.Mutex
+	entries map[string]*memoryRateLimitEntry
+}
+
+func NewMemoryRateLimitRepository() *MemoryRateLimitRepository {
+	return &MemoryRateLimitRepository{entries: make(map[string]*memoryRateLimitEntry)}
+}
+
+func (repository *MemoryRateLimitRepository) IncrementRateLimitCounter(_ context.Context, key string, window time.Duration) (int, time.Time, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	moment := time.Now()
+	entry := repository.entries[key]
+	if entry == nil {
+		entry = &memoryRateLimitEntry{}
+		repository.entries[key] = entry
+	}
+	if !moment.Before(entry.windowEndsAt) {
+		entry.hitCount = 0
+		entry.windowEndsAt = moment.Add(window)
+	}
+	entry.hitCount++
+	return entry.hitCount, entry.windowEndsAt, nil
+}
+
+func (repository *MemoryRateLimitRepository) BlockRateLimitKey(_ context.Context, key string, blockedUntil time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	entry := repository.entries[key]
+	if entry == nil {
+		entry = &memoryRateLimitEntry{windowEndsAt: blockedUntil}
+		repository.entries[key] = entry
+	}
+	if blockedUntil.After(entry.blockedUntil) {
+		entry.blockedUntil = blockedUntil
+	}
+	return nil
+}
+
+func (repository *MemoryRateLimitRepository) LoadRateLimitBlock(_ context.Context, key string) (time.Time, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	entry := repository.entries[key]
+	if entry == nil || !entry.blockedUntil.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return entry.blockedUntil, nil
+}
+
+func (repository *MemoryRateLimitRepository) ResetRateLimitKey(_ context.Context, key string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	delete(repository.entries, key)
+	return nil
+}
+
+func (repository *MemoryRateLimitRepository) DeleteExpiredRateLimits(_ context.Context) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	moment := time.Now()
+	var deletedCount int64
+	for key, entry := range repository.entries {
+		if entry.windowEndsAt.Before(moment) && entry.blockedUntil.Before(moment) {
+			delete(repository.entries, key)
+			deletedCount++
+		}
+	}
+	return deletedCount, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RateLimitRepository keeps fixed-window hit counters and temporary blocks for the auth throttling.
+// PostgresRateLimitRepository shares the state between API instances; MemoryRateLimitRepository is
+// for single-instance deployments and development.
+type RateLimitRepository interface {
+	// IncrementRateLimitCounter counts one hit for key, starting a new window of the given length once
+	// the previous one has ended, and returns the hits so far and when the window ends.
+	IncrementRateLimitCounter(operationContext context.Context, key string, window time.Duration) (int, time.Time, error)
+	// BlockRateLimitKey blocks key until the given time; an existing longer block is kept.
+	BlockRateLimitKey(operationContext context.Context, key string, blockedUntil time.Time) error
+	// LoadRateLimitBlock returns when key's block ends, or the zero time when it is not blocked.
+	LoadRateLimitBlock(loadContext context.Context, key string) (time.Time, error)
+	// ResetRateLimitKey forgets key's counter and block.
+	ResetRateLimitKey(operationContext context.Context, key string) error
+	DeleteExpiredRateLimits(operationContext context.Context) (int64, error)
+}
+
+type PostgresRateLimitRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresRateLimitRepository(database *sql.DB) *PostgresRateLimitRepository {
+	return &PostgresRateLimitRepository{Database: database}
+}
+
+func (repository *PostgresRateLimitRepository) IncrementRateLimitCounter(operationContext context.Context, key string, window time.Duration) (int, time.Time, error) {
+	var hitCount int
+	var windowEndsAt time.Time
+	scanError := repository.Database.QueryRowContext(
+		operationContext,
+		`INSERT INTO auth_rate_limits (bucket_key, hit_count, window_ends_at)
+		 VALUES ($1, 1, NOW() + make_interval(secs => $2))
+		 ON CONFLICT (bucket_key) DO UPDATE
+		 SET hit_count = CASE WHEN auth_rate_limits.window_ends_at <= NOW() THEN 1 ELSE auth_rate_limits.hit_count + 1 END,
+		     window_ends_at = CASE WHEN auth_rate_limits.window_ends_at <= NOW() THEN EXCLUDED.window_ends_at ELSE auth_rate_limits.window_ends_at END
+		 RETURNING hit_count, window_ends_at`,
+		key, window.Seconds(),
+	).Scan(&hitCount, &windowEndsAt)
+	return hitCount, windowEndsAt, scanError
+}
+
+func (repository *PostgresRateLimitRepository) BlockRateLimitKey(operationContext context.Context, key string, blockedUntil time.Time) error {
+	// window_ends_at is pushed out with the block so the cleanup never drops an active block.
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO auth_rate_limits (bucket_key, hit_count, window_ends_at, blocked_until)
+		 VALUES ($1, 0, $2, $2)
+		 ON CONFLICT (bucket_key) DO UPDATE
+		 SET blocked_until = GREATEST(COALESCE(auth_rate_limits.blocked_until, $2), $2),
+		     window_ends_at = GREATEST(auth_rate_limits.window_ends_at, $2)`,
+		key, blockedUntil,
+	)
+	return executionError
+}
+
+func (repository *PostgresRateLimitRepository) LoadRateLimitBlock(loadContext context.Context, key string) (time.Time, error) {
+	var blockedUntil time.Time
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT blocked_until FROM auth_rate_limits WHERE bucket_key = $1 AND blocked_until > NOW()`,
+		key,
+	).Scan(&blockedUntil)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return blockedUntil, scanError
+}
+
+func (repository *PostgresRateLimitRepository) ResetRateLimitKey(operationContext context.Context, key string) error {
+	_, executionError := repository.Database.ExecContext(operationContext, `DELETE FROM auth_rate_limits WHERE bucket_key = $1`, key)
+	return executionError
+}
+
+func (repository *PostgresRateLimitRepository) DeleteExpiredRateLimits(operationContext context.Context) (int64, error) {
+	result, executionError := repository.Database.ExecContext(
+		operationContext,
+		`DELETE FROM auth_rate_limits
+		 WHERE window_ends_at < NOW() AND (blocked_until IS NULL OR blocked_until < NOW())`,
+	)
+	if executionError != nil {
+		return 0, executionError
+	}
+	return result.RowsAffected()
+}
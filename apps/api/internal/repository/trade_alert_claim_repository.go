@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TradeAlertClaimRepository remembers which once-a-day trade alerts were already sent.
+type TradeAlertClaimRepository interface {
+	// ClaimDailyAlert returns true the first time it is called for a robot, event type and UTC day, and
+	// false afterwards. It joins the caller's transaction when the context carries one.
+	ClaimDailyAlert(operationContext context.Context, userIdentifier int64, robotIdentifier int64, eventType string, day time.Time) (bool, error)
+}
+
+type PostgresTradeAlertClaimRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresTradeAlertClaimRepository(database *sql.DB) *PostgresTradeAlertClaimRepository {
+	return &PostgresTradeAlertClaimRepository{Database: database}
+}
+
+func (repository *PostgresTradeAlertClaimRepository) ClaimDailyAlert(operationContext context.Context, userIdentifier int64, robotIdentifier int64, eventType string, day time.Time) (bool, error) {
+	result, insertError := querierFor(operationContext, repository.Database).ExecContext(
+		operationContext,
+		`INSERT INTO trade_alert_claims (user_id, robot_id, event_type, alert_date)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (robot_id, event_type, alert_date) DO NOTHING`,
+		userIdentifier,
+		robotIdentifier,
+		eventType,
+		day.UTC().Format("2006-01-02"),
+	)
+	if insertError != nil {
+		return false, insertError
+	}
+	insertedCount, countError := result.RowsAffected()
+	return insertedCount == 1, countError
+}
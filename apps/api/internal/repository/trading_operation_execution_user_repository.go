@@ -18,7 +18,7 @@ type UserTradingOperationExecutionRepository interface {
 }
 
 func (repository *PostgresTradingOperationExecutionRepository) LogExecutionForUser(operationContext context.Context, userIdentifier int64, execution domain.TradingOperationExecution) (int64, error) {
-	row := repository.Database.QueryRowContext(
+	row := querierFor(operationContext, repository.Database).QueryRowContext(
 		operationContext,
 		`INSERT INTO trading_operation_executions
 		    (user_id, scheduled_operation_id, trading_pair_symbol, operation_type, unit_price, quantity, total_value, executed_at, success, error_message, order_id, binance_environment, initiated_by)
@@ -46,7 +46,7 @@ func (repository *PostgresTradingOperationExecutionRepository) LogExecutionForUs
 }
 
 func (repository *PostgresTradingOperationExecutionRepository) ListRecentExecutionsForUser(loadContext context.Context, userIdentifier int64, environment string, limit int) ([]domain.TradingOperationExecution, error) {
-	rows, queryError := repository.Database.QueryContext(
+	rows, queryError := querierFor(loadContext, repository.Database).QueryContext(
 		loadContext,
 		`SELECT `+userExecutionColumns+` FROM trading_operation_executions WHERE user_id = $1 AND binance_environment = $2 ORDER BY executed_at DESC LIMIT $3`,
 		userIdentifier, environment, limit,
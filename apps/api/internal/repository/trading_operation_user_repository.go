@@ -30,7 +30,7 @@ type UserTradingOperationRepository interface {
 }
 
 func (repository *PostgresTradingOperationRepository) CreatePurchaseOperationForUser(operationContext context.Context, userIdentifier int64, operation domain.TradingOperation) (int64, error) {
-	row := repository.Database.QueryRowContext(
+	row := querierFor(operationContext, repository.Database).QueryRowContext(
 		operationContext,
 		`INSERT INTO trading_operations
 		    (user_id, trading_pair_symbol, quantity_purchased, purchase_price_per_unit, target_profit_percent, status, buy_order_id, sell_order_id, sell_target_price_per_unit, binance_environment, sell_order_expires_at)
@@ -56,7 +56,7 @@ func (repository *PostgresTradingOperationRepository) CreatePurchaseOperationFor
 }
 
 func (repository *PostgresTradingOperationRepository) ListRecentOperationsForUser(loadContext context.Context, userIdentifier int64, environment string, limit int) ([]domain.TradingOperation, error) {
-	rows, queryError := repository.Database.QueryContext(
+	rows, queryError := querierFor(loadContext, repository.Database).QueryContext(
 		loadContext,
 		`SELECT `+userTradingOperationColumns+` FROM trading_operations WHERE user_id = $1 AND binance_environment = $2 ORDER BY purchased_at DESC LIMIT $3`,
 		userIdentifier, environment, limit,
@@ -69,7 +69,7 @@ func (repository *PostgresTradingOperationRepository) ListRecentOperationsForUse
 }
 
 func (repository *PostgresTradingOperationRepository) ListOpenOperationsForUser(loadContext context.Context, userIdentifier int64, environment string) ([]domain.TradingOperation, error) {
-	rows, queryError := repository.Database.QueryContext(
+	rows, queryError := querierFor(loadContext, repository.Database).QueryContext(
 		loadContext,
 		`SELECT `+userTradingOperationColumns+` FROM trading_operations WHERE user_id = $1 AND binance_environment = $2 AND status = $3 ORDER BY purchased_at ASC`,
 		userIdentifier, environment, domain.TradingOperationStatusOpen,
@@ -82,7 +82,7 @@ func (repository *PostgresTradingOperationRepository) ListOpenOperationsForUser(
 }
 
 func (repository *PostgresTradingOperationRepository) FindOperationByIdForUser(loadContext context.Context, userIdentifier int64, operationIdentifier int64) (*domain.TradingOperation, error) {
-	rows, queryError := repository.Database.QueryContext(
+	rows, queryError := querierFor(loadContext, repository.Database).QueryContext(
 		loadContext,
 		`SELECT `+userTradingOperationColumns+` FROM trading_operations WHERE id = $1 AND user_id = $2 LIMIT 1`,
 		operationIdentifier, userIdentifier,
@@ -102,7 +102,7 @@ func (repository *PostgresTradingOperationRepository) FindOperationByIdForUser(l
 }
 
 func (repository *PostgresTradingOperationRepository) UpdateOperationAsSoldForUser(operationContext context.Context, userIdentifier int64, operationIdentifier int64, sellPricePerUnit float64) error {
-	_, updateError := repository.Database.ExecContext(
+	_, updateError := querierFor(operationContext, repository.Database).ExecContext(
 		operationContext,
 		`UPDATE trading_operations SET status = $1, sell_price_per_unit = $2, sold_at = NOW() WHERE id = $3 AND user_id = $4`,
 		domain.TradingOperationStatusSold, sellPricePerUnit, operationIdentifier, userIdentifier,
@@ -111,7 +111,7 @@ func (repository *PostgresTradingOperationRepository) UpdateOperationAsSoldForUs
 }
 
 func (repository *PostgresTradingOperationRepository) UpdateOperationSellOrderForUser(operationContext context.Context, userIdentifier int64, operationIdentifier int64, sellOrderIdentifier string, sellTargetPrice float64, sellOrderExpiresAt *time.Time) error {
-	_, updateError := repository.Database.ExecContext(
+	_, updateError := querierFor(operationContext, repository.Database).ExecContext(
 		operationContext,
 		`UPDATE trading_operations SET sell_order_id = $1, sell_target_price_per_unit = $2, sell_order_expires_at = $3 WHERE id = $4 AND user_id = $5`,
 		sellOrderIdentifier, sellTargetPrice, sellOrderExpiresAt, operationIdentifier, userIdentifier,
@@ -122,7 +122,7 @@ func (repository *PostgresTradingOperationRepository) UpdateOperationSellOrderFo
 // MarkOperationCanceledForUser closes an operation as CANCELED (its take-profit was cancelled outside
 // the app), removing it from the active positions view.
 func (repository *PostgresTradingOperationRepository) MarkOperationCanceledForUser(operationContext context.Context, userIdentifier int64, operationIdentifier int64) error {
-	_, updateError := repository.Database.ExecContext(
+	_, updateError := querierFor(operationContext, repository.Database).ExecContext(
 		operationContext,
 		`UPDATE trading_operations SET status = $1, sold_at = NOW() WHERE id = $2 AND user_id = $3`,
 		domain.TradingOperationStatusCanceled, operationIdentifier, userIdentifier,
@@ -133,7 +133,7 @@ func (repository *PostgresTradingOperationRepository) MarkOperationCanceledForUs
 // ClearSellOrderForUser detaches the resting sell order from an OPEN operation (e.g. after its
 // validity expired), leaving the position open but unprotected so the user can re-place or sell.
 func (repository *PostgresTradingOperationRepository) ClearSellOrderForUser(operationContext context.Context, userIdentifier int64, operationIdentifier int64) error {
-	_, updateError := repository.Database.ExecContext(
+	_, updateError := querierFor(operationContext, repository.Database).ExecContext(
 		operationContext,
 		`UPDATE trading_operations SET sell_order_id = NULL, sell_order_expires_at = NULL WHERE id = $1 AND user_id = $2`,
 		operationIdentifier, userIdentifier,
@@ -142,7 +142,7 @@ func (repository *PostgresTradingOperationRepository) ClearSellOrderForUser(oper
 }
 
 func (repository *PostgresTradingOperationRepository) CalculateOpenAllocationTotalForUser(loadContext context.Context, userIdentifier int64, environment string) (float64, error) {
-	row := repository.Database.QueryRowContext(
+	row := querierFor(loadContext, repository.Database).QueryRowContext(
 		loadContext,
 		`SELECT COALESCE(SUM(quantity_purchased * purchase_price_per_unit), 0) FROM trading_operations WHERE user_id = $1 AND binance_environment = $2 AND status = $3`,
 		userIdentifier, environment, domain.TradingOperationStatusOpen,
@@ -39,7 +39,7 @@ func NewPostgresTradingRobotRepository(database *sql.DB) *PostgresTradingRobotRe
 }
 
 func (repository *PostgresTradingRobotRepository) ListRobotsForUser(loadContext context.Context, userIdentifier int64, environment string) ([]domain.TradingRobot, error) {
-	rows, queryError := repository.Database.QueryContext(
+	rows, queryError := querierFor(loadContext, repository.Database).QueryContext(
 		loadContext,
 		`SELECT `+tradingRobotColumns+` FROM trading_robots WHERE user_id = $1 AND binance_environment = $2 ORDER BY created_at ASC`,
 		userIdentifier, environment,
@@ -52,7 +52,7 @@ func (repository *PostgresTradingRobotRepository) ListRobotsForUser(loadContext
 }
 
 func (repository *PostgresTradingRobotRepository) GetRobotForUser(loadContext context.Context, userIdentifier int64, robotIdentifier int64) (*domain.TradingRobot, error) {
-	row := repository.Database.QueryRowContext(
+	row := querierFor(loadContext, repository.Database).QueryRowContext(
 		loadContext,
 		`SELECT `+tradingRobotColumns+` FROM trading_robots WHERE id = $1 AND user_id = $2`,
 		robotIdentifier, userIdentifier,
@@ -68,7 +68,7 @@ func (repository *PostgresTradingRobotRepository) GetRobotForUser(loadContext co
 }
 
 func (repository *PostgresTradingRobotRepository) CountRobotsForUser(loadContext context.Context, userIdentifier int64, environment string) (int, error) {
-	row := repository.Database.QueryRowContext(
+	row := querierFor(loadContext, repository.Database).QueryRowContext(
 		loadContext,
 		`SELECT COUNT(*) FROM trading_robots WHERE user_id = $1 AND binance_environment = $2`,
 		userIdentifier, environment,
@@ -81,7 +81,7 @@ func (repository *PostgresTradingRobotRepository) CountRobotsForUser(loadContext
 }
 
 func (repository *PostgresTradingRobotRepository) CreateRobotForUser(operationContext context.Context, userIdentifier int64, robot domain.TradingRobot) (int64, error) {
-	row := repository.Database.QueryRowContext(
+	row := querierFor(operationContext, repository.Database).QueryRowContext(
 		operationContext,
 		`INSERT INTO trading_robots
 		    (user_id, binance_environment, trading_pair_symbol, name, capital_threshold, target_profit_percent,
@@ -111,7 +111,7 @@ func (repository *PostgresTradingRobotRepository) CreateRobotForUser(operationCo
 }
 
 func (repository *PostgresTradingRobotRepository) UpdateRobotForUser(operationContext context.Context, userIdentifier int64, robot domain.TradingRobot) error {
-	result, updateError := repository.Database.ExecContext(
+	result, updateError := querierFor(operationContext, repository.Database).ExecContext(
 		operationContext,
 		`UPDATE trading_robots SET
 		    name = NULLIF($1, ''),
@@ -146,7 +146,7 @@ func (repository *PostgresTradingRobotRepository) UpdateRobotForUser(operationCo
 }
 
 func (repository *PostgresTradingRobotRepository) DeleteRobotForUser(operationContext context.Context, userIdentifier int64, robotIdentifier int64) error {
-	result, deleteError := repository.Database.ExecContext(
+	result, deleteError := querierFor(operationContext, repository.Database).ExecContext(
 		operationContext,
 		`DELETE FROM trading_robots WHERE id = $1 AND user_id = $2`,
 		robotIdentifier, userIdentifier,
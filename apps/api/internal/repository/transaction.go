@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// sqlQuerier is the subset of *sql.DB and *sql.Tx the repositories use, so the same method runs
+// either on its own or as part of a caller's transaction.
+type sqlQuerier interface {
+	ExecContext(queryContext context.Context, query string, arguments ...interface{}) (sql.Result, error)
+	QueryContext(queryContext context.Context, query string, arguments ...interface{}) (*sql.Rows, error)
+	QueryRowContext(queryContext context.Context, query string, arguments ...interface{}) *sql.Row
+}
+
+type transactionContextKey struct{}
+
+// querierFor returns the transaction carried by the context (see RunInTransaction), or the database.
+func querierFor(queryContext context.Context, database *sql.DB) sqlQuerier {
+	if transaction, present := queryContext.Value(transactionContextKey{}).(*sql.Tx); present {
+		return transaction
+	}
+	return database
+}
+
+// TransactionRunner runs a unit of work in one database transaction. Repository calls made with the
+// context passed to the callback join that transaction.
+type TransactionRunner interface {
+	RunInTransaction(operationContext context.Context, work func(transactionContext context.Context) error) error
+}
+
+type PostgresTransactionRunner struct {
+	Database *sql.DB
+}
+
+func NewPostgresTransactionRunner(database *sql.DB) *PostgresTransactionRunner {
+	return &PostgresTransactionRunner{Database: database}
+}
+
+// RunInTransaction commits when work returns nil and rolls back otherwise. A context that already
+// carries a transaction is reused, so nested units of work commit together.
+func (runner *PostgresTransactionRunner) RunInTransaction(operationContext context.Context, work func(transactionContext context.Context) error) error {
+	if _, present := operationContext.Value(transactionContextKey{}).(*sql.Tx); present {
+		return work(operationContext)
+	}
+
+	transaction, beginError := runner.Database.BeginTx(operationContext, nil)
+	if beginError != nil {
+		return beginError
+	}
+	if workError := work(context.WithValue(operationContext, transactionContextKey{}, transaction)); workError != nil {
+		_ = transaction.Rollback()
+		return workError
+	}
+	return transaction.Commit()
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"coin-alert/internal/domain"
+)
+
+// TwoFactorRepository persists TOTP enrollments and their recovery codes.
+type TwoFactorRepository interface {
+	// LoadTwoFactor returns the user's enrollment, or (nil, nil) when there is none.
+	LoadTwoFactor(loadContext context.Context, userIdentifier int64) (*domain.UserTwoFactor, error)
+	// SavePendingTwoFactor stores a new, not yet confirmed secret. It never replaces an enabled one.
+	SavePendingTwoFactor(operationContext context.Context, userIdentifier int64, secretCiphertext string) error
+	EnableTwoFactor(operationContext context.Context, userIdentifier int64, timeStep int64) error
+	// ConsumeTimeStep records an accepted code's time step; it reports false when that step (or a
+	// later one) was already used, i.e. the code is a replay.
+	ConsumeTimeStep(operationContext context.Context, userIdentifier int64, timeStep int64) (bool, error)
+	// DeleteTwoFactor removes the enrollment and every recovery code.
+	DeleteTwoFactor(operationContext context.Context, userIdentifier int64) error
+	// ReplaceRecoveryCodes drops the user's recovery codes and stores the new hashes.
+	ReplaceRecoveryCodes(operationContext context.Context, userIdentifier int64, codeHashes []string) error
+	// UseRecoveryCode marks an unused code as used; it reports false when no unused code matches.
+	UseRecoveryCode(operationContext context.Context, userIdentifier int64, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(loadContext context.Context, userIdentifier int64) (int, error)
+}
+
+type PostgresTwoFactorRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresTwoFactorRepository(database *sql.DB) *PostgresTwoFactorRepository {
+	return &PostgresTwoFactorRepository{Database: database}
+}
+
+func (repository *PostgresTwoFactorRepository) LoadTwoFactor(loadContext context.Context, userIdentifier int64) (*domain.UserTwoFactor, error) {
+	twoFactor := &domain.UserTwoFactor{UserIdentifier: userIdentifier}
+	var enabledAt sql.NullTime
+	scanError := querierFor(loadContext, repository.Database).QueryRowContext(
+		loadContext,
+		`SELECT secret_ciphertext, enabled_at, last_used_step FROM user_two_factor WHERE user_id = $1`,
+		userIdentifier,
+	).Scan(&twoFactor.SecretCiphertext, &enabledAt, &twoFactor.LastUsedTimeStep)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+	if enabledAt.Valid {
+		twoFactor.EnabledAt = &enabledAt.Time
+	}
+	return twoFactor, nil
+}
+
+func (repository *PostgresTwoFactorRepository) SavePendingTwoFactor(operationContext context.Context, userIdentifier int64, secretCiphertext string) error {
+	_, executionError := querierFor(operationContext, repository.Database).ExecContext(
+		operationContext,
+		`INSERT INTO user_two_factor (user_id, secret_ciphertext)
+		 VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET secret_ciphertext = EXCLUDED.secret_ciphertext,
+		     last_used_step = 0,
+		     updated_at = NOW()
+		 WHERE user_two_factor.enabled_at IS NULL`,
+		userIdentifier, secretCiphertext,
+	)
+	return executionError
+}
+
+func (repository *PostgresTwoFactorRepository) EnableTwoFactor(operationContext context.Context, userIdentifier int64, timeStep int64) error {
+	_, executionError := querierFor(operationContext, repository.Database).ExecContext(
+		operationContext,
+		`UPDATE user_two_factor
+		 SET enabled_at = NOW(), last_used_step = $2, updated_at = NOW()
+		 WHERE user_id = $1 AND enabled_at IS NULL`,
+		userIdentifier, timeStep,
+	)
+	return executionError
+}
+
+func (repository *PostgresTwoFactorRepository) ConsumeTimeStep(operationContext context.Context, userIdentifier int64, timeStep int64) (bool, error) {
+	result, executionError := querierFor(operationContext, repository.Database).ExecContext(
+		operationContext,
+		`UPDATE user_two_factor SET last_used_step = $2, updated_at = NOW()
+		 WHERE user_id = $1 AND last_used_step < $2`,
+		userIdentifier, timeStep,
+	)
+	if executionError != nil {
+		return false, executionError
+	}
+	affectedRows, rowsError := result.RowsAffected()
+	return affectedRows == 1, rowsError
+}
+
+func (repository *PostgresTwoFactorRepository) DeleteTwoFactor(operationContext context.Context, userIdentifier int64) error {
+	querier := querierFor(operationContext, repository.Database)
+	if _, executionError := querier.ExecContext(operationContext, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userIdentifier); executionError != nil {
+		return executionError
+	}
+	_, executionError := querier.ExecContext(operationContext, `DELETE FROM user_two_factor WHERE user_id = $1`, userIdentifier)
+	return executionError
+}
+
+func (repository *PostgresTwoFactorRepository) ReplaceRecoveryCodes(operationContext context.Context, userIdentifier int64, codeHashes []string) error {
+	querier := querierFor(operationContext, repository.Database)
+	if _, executionError := querier.ExecContext(operationContext, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userIdentifier); executionError != nil {
+		return executionError
+	}
+	for _, codeHash := range codeHashes {
+		if _, executionError := querier.ExecContext(
+			operationContext,
+			`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userIdentifier, codeHash,
+		); executionError != nil {
+			return executionError
+		}
+	}
+	return nil
+}
+
+func (repository *PostgresTwoFactorRepository) UseRecoveryCode(operationContext context.Context, userIdentifier int64, codeHash string) (bool, error) {
+	result, executionError := querierFor(operationContext, repository.Database).ExecContext(
+		operationContext,
+		`UPDATE user_recovery_codes SET used_at = NOW()
+		 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userIdentifier, codeHash,
+	)
+	if executionError != nil {
+		return false, executionError
+	}
+	affectedRows, rowsError := result.RowsAffected()
+	return affectedRows == 1, rowsError
+}
+
+func (repository *PostgresTwoFactorRepository) CountUnusedRecoveryCodes(loadContext context.Context, userIdentifier int64) (int, error) {
+	var unusedCount int
+	scanError := querierFor(loadContext, repository.Database).QueryRowContext(
+		loadContext,
+		`SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`,
+		userIdentifier,
+	).Scan(&unusedCount)
+	return unusedCount, scanError
+}
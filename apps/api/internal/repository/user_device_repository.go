@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// UserDeviceRepository remembers the devices users signed in from and whether they want to hear
+// about sign-ins from new ones.
+type UserDeviceRepository interface {
+	CountKnownDevices(loadContext context.Context, userIdentifier int64) (int, error)
+	// RememberDevice records a sign-in from deviceKey and reports whether the device was new.
+	RememberDevice(operationContext context.Context, userIdentifier int64, deviceKey string) (bool, error)
+	LoadNewDeviceAlertsEnabled(loadContext context.Context, userIdentifier int64) (bool, error)
+	SetNewDeviceAlertsEnabled(operationContext context.Context, userIdentifier int64, isEnabled bool) error
+}
+
+type PostgresUserDeviceRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresUserDeviceRepository(database *sql.DB) *PostgresUserDeviceRepository {
+	return &PostgresUserDeviceRepository{Database: database}
+}
+
+func (repository *PostgresUserDeviceRepository) CountKnownDevices(loadContext context.Context, userIdentifier int64) (int, error) {
+	var deviceCount int
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT COUNT(*) FROM user_known_devices WHERE user_id = $1`,
+		userIdentifier,
+	).Scan(&deviceCount)
+	return deviceCount, scanError
+}
+
+func (repository *PostgresUserDeviceRepository) RememberDevice(operationContext context.Context, userIdentifier int64, deviceKey string) (bool, error) {
+	// xmax is 0 only for a freshly inserted row, so it tells an insert from the conflict update.
+	var inserted bool
+	scanError := repository.Database.QueryRowContext(
+		operationContext,
+		`INSERT INTO user_known_devices (user_id, device_key)
+		 VALUES ($1, $2)
+		 ON CONFLICT (user_id, device_key) DO UPDATE SET last_seen_at = NOW()
+		 RETURNING (xmax = 0)`,
+		userIdentifier, deviceKey,
+	).Scan(&inserted)
+	return inserted, scanError
+}
+
+func (repository *PostgresUserDeviceRepository) LoadNewDeviceAlertsEnabled(loadContext context.Context, userIdentifier int64) (bool, error) {
+	var isEnabled bool
+	scanError := repository.Database.QueryRowContext(
+		loadContext,
+		`SELECT new_device_alerts FROM users WHERE id = $1`,
+		userIdentifier,
+	).Scan(&isEnabled)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return false, ErrUserNotFound
+	}
+	return isEnabled, scanError
+}
+
+func (repository *PostgresUserDeviceRepository) SetNewDeviceAlertsEnabled(operationContext context.Context, userIdentifier int64, isEnabled bool) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE users SET new_device_alerts = $2, updated_at = NOW() WHERE id = $1`,
+		userIdentifier, isEnabled,
+	)
+	return executionError
+}
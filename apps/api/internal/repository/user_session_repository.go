@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"coin-alert/internal/domain"
 )
@@ -14,8 +15,20 @@ type UserSessionRepository interface {
 	DeleteByTokenHash(deletionContext context.Context, sessionTokenHash string) error
 	DeleteAllForUser(deletionContext context.Context, userIdentifier int64) error
 	DeleteExpiredSessions(deletionContext context.Context) (int64, error)
+	UpdateStepUpExpiry(operationContext context.Context, sessionTokenHash string, stepUpExpiresAt time.Time) error
+	// ListActiveForUser returns the user's unexpired sessions, most recently used first.
+	ListActiveForUser(loadContext context.Context, userIdentifier int64) ([]domain.UserSession, error)
+	// DeleteForUser revokes one session of the user; it reports false when none matched.
+	DeleteForUser(deletionContext context.Context, userIdentifier int64, sessionIdentifier int64) (bool, error)
+	// DeleteAllForUserExcept revokes every session of the user but the one with keepTokenHash.
+	DeleteAllForUserExcept(deletionContext context.Context, userIdentifier int64, keepTokenHash string) (int64, error)
+	UpdateLocation(operationContext context.Context, sessionTokenHash string, location string) error
+	TouchLastSeen(operationContext context.Context, sessionIdentifier int64) error
 }
 
+const userSessionColumns = `id, user_id, session_token_hash, expires_at, created_at, last_seen_at,
+	COALESCE(user_agent, ''), COALESCE(ip_address, ''), COALESCE(location, ''), step_up_expires_at`
+
 type PostgresUserSessionRepository struct {
 	Database *sql.DB
 }
@@ -27,13 +40,14 @@ func NewPostgresUserSessionRepository(database *sql.DB) *PostgresUserSessionRepo
 func (repository *PostgresUserSessionRepository) CreateSession(creationContext context.Context, session domain.UserSession) error {
 	_, executionError := repository.Database.ExecContext(
 		creationContext,
-		`INSERT INTO user_sessions (user_id, session_token_hash, expires_at, user_agent, ip_address)
-		 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))`,
+		`INSERT INTO user_sessions (user_id, session_token_hash, expires_at, user_agent, ip_address, step_up_expires_at)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)`,
 		session.UserIdentifier,
 		session.SessionTokenHash,
 		session.ExpiresAt,
 		session.UserAgent,
 		session.IPAddress,
+		session.StepUpExpiresAt,
 	)
 	return executionError
 }
@@ -42,31 +56,41 @@ func (repository *PostgresUserSessionRepository) CreateSession(creationContext c
 func (repository *PostgresUserSessionRepository) FindActiveByTokenHash(lookupContext context.Context, sessionTokenHash string) (*domain.UserSession, error) {
 	row := repository.Database.QueryRowContext(
 		lookupContext,
-		`SELECT id, user_id, session_token_hash, expires_at, created_at, last_seen_at,
-		        COALESCE(user_agent, ''), COALESCE(ip_address, '')
+		`SELECT `+userSessionColumns+`
 		 FROM user_sessions
 		 WHERE session_token_hash = $1 AND expires_at > NOW()`,
 		sessionTokenHash,
 	)
-
-	session := &domain.UserSession{}
-	scanError := row.Scan(
-		&session.Identifier,
-		&session.UserIdentifier,
-		&session.SessionTokenHash,
-		&session.ExpiresAt,
-		&session.CreatedAt,
-		&session.LastSeenAt,
-		&session.UserAgent,
-		&session.IPAddress,
-	)
+	session, scanError := scanUserSession(row)
 	if errors.Is(scanError, sql.ErrNoRows) {
 		return nil, nil
 	}
-	if scanError != nil {
-		return nil, scanError
+	return session, scanError
+}
+
+func (repository *PostgresUserSessionRepository) ListActiveForUser(loadContext context.Context, userIdentifier int64) ([]domain.UserSession, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT `+userSessionColumns+`
+		 FROM user_sessions
+		 WHERE user_id = $1 AND expires_at > NOW()
+		 ORDER BY last_seen_at DESC`,
+		userIdentifier,
+	)
+	if queryError != nil {
+		return nil, queryError
 	}
-	return session, nil
+	defer rows.Close()
+
+	sessions := make([]domain.UserSession, 0)
+	for rows.Next() {
+		session, scanError := scanUserSession(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
 }
 
 func (repository *PostgresUserSessionRepository) DeleteByTokenHash(deletionContext context.Context, sessionTokenHash string) error {
@@ -98,3 +122,84 @@ func (repository *PostgresUserSessionRepository) DeleteExpiredSessions(deletionC
 	}
 	return result.RowsAffected()
 }
+
+// UpdateStepUpExpiry records a fresh password/2FA confirmation on an active session.
+func (repository *PostgresUserSessionRepository) UpdateStepUpExpiry(operationContext context.Context, sessionTokenHash string, stepUpExpiresAt time.Time) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE user_sessions SET step_up_expires_at = $2 WHERE session_token_hash = $1 AND expires_at > NOW()`,
+		sessionTokenHash,
+		stepUpExpiresAt,
+	)
+	return executionError
+}
+
+func (repository *PostgresUserSessionRepository) DeleteForUser(deletionContext context.Context, userIdentifier int64, sessionIdentifier int64) (bool, error) {
+	result, executionError := repository.Database.ExecContext(
+		deletionContext,
+		`DELETE FROM user_sessions WHERE id = $1 AND user_id = $2`,
+		sessionIdentifier, userIdentifier,
+	)
+	if executionError != nil {
+		return false, executionError
+	}
+	affectedRows, rowsError := result.RowsAffected()
+	return affectedRows == 1, rowsError
+}
+
+func (repository *PostgresUserSessionRepository) DeleteAllForUserExcept(deletionContext context.Context, userIdentifier int64, keepTokenHash string) (int64, error) {
+	result, executionError := repository.Database.ExecContext(
+		deletionContext,
+		`DELETE FROM user_sessions WHERE user_id = $1 AND session_token_hash <> $2`,
+		userIdentifier, keepTokenHash,
+	)
+	if executionError != nil {
+		return 0, executionError
+	}
+	return result.RowsAffected()
+}
+
+func (repository *PostgresUserSessionRepository) UpdateLocation(operationContext context.Context, sessionTokenHash string, location string) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE user_sessions SET location = NULLIF($2, '') WHERE session_token_hash = $1`,
+		sessionTokenHash, location,
+	)
+	return executionError
+}
+
+func (repository *PostgresUserSessionRepository) TouchLastSeen(operationContext context.Context, sessionIdentifier int64) error {
+	_, executionError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE user_sessions SET last_seen_at = NOW() WHERE id = $1`,
+		sessionIdentifier,
+	)
+	return executionError
+}
+
+type userSessionScanner interface {
+	Scan(destinations ...interface{}) error
+}
+
+func scanUserSession(scanner userSessionScanner) (*domain.UserSession, error) {
+	session := &domain.UserSession{}
+	var stepUpExpiresAt sql.NullTime
+	if scanError := scanner.Scan(
+		&session.Identifier,
+		&session.UserIdentifier,
+		&session.SessionTokenHash,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.Location,
+		&stepUpExpiresAt,
+	); scanError != nil {
+		return nil, scanError
+	}
+	if stepUpExpiresAt.Valid {
+		session.StepUpExpiresAt = &stepUpExpiresAt.Time
+	}
+	return session, nil
+}
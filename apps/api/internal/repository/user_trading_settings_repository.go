@@ -14,6 +14,11 @@ type UserTradingSettingsRepository interface {
 	GetByUserAndEnvironment(lookupContext context.Context, userIdentifier int64, environment string) (*domain.UserTradingSettings, error)
 	EnsureDefaults(operationContext context.Context, userIdentifier int64, environment string) (*domain.UserTradingSettings, error)
 	Upsert(operationContext context.Context, settings domain.UserTradingSettings) error
+	// HasLiveTradingEnabled reports whether live trading is on in any of the user's environments.
+	HasLiveTradingEnabled(lookupContext context.Context, userIdentifier int64) (bool, error)
+	// DisableLiveTradingForUser turns live trading off in every environment and returns the
+	// environments where it was on.
+	DisableLiveTradingForUser(operationContext context.Context, userIdentifier int64) ([]string, error)
 }
 
 type PostgresUserTradingSettingsRepository struct {
@@ -118,3 +123,37 @@ func (repository *PostgresUserTradingSettingsRepository) Upsert(operationContext
 	)
 	return executionError
 }
+
+func (repository *PostgresUserTradingSettingsRepository) HasLiveTradingEnabled(lookupContext context.Context, userIdentifier int64) (bool, error) {
+	var enabled bool
+	queryError := querierFor(lookupContext, repository.Database).QueryRowContext(
+		lookupContext,
+		`SELECT EXISTS (SELECT 1 FROM user_trading_settings WHERE user_id = $1 AND live_trading_enabled)`,
+		userIdentifier,
+	).Scan(&enabled)
+	return enabled, queryError
+}
+
+func (repository *PostgresUserTradingSettingsRepository) DisableLiveTradingForUser(operationContext context.Context, userIdentifier int64) ([]string, error) {
+	rows, queryError := querierFor(operationContext, repository.Database).QueryContext(
+		operationContext,
+		`UPDATE user_trading_settings SET live_trading_enabled = FALSE, updated_at = NOW()
+		 WHERE user_id = $1 AND live_trading_enabled
+		 RETURNING binance_environment`,
+		userIdentifier,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	environments := make([]string, 0)
+	for rows.Next() {
+		var environment string
+		if scanError := rows.Scan(&environment); scanError != nil {
+			return nil, scanError
+		}
+		environments = append(environments, environment)
+	}
+	return environments, rows.Err()
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"coin-alert/internal/domain"
+)
+
+// ErrWebhookEndpointNotFound is returned when no endpoint matches the id for the given user.
+var ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+
+const webhookEndpointColumns = `id, user_id, url, description, signing_secret, event_types, is_enabled, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, endpoint_id, user_id, event_id, event_type, payload, status, attempt_count,
+	next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+// WebhookEndpointRepository persists user-registered webhook endpoints.
+type WebhookEndpointRepository interface {
+	ListEndpointsForUser(loadContext context.Context, userIdentifier int64) ([]domain.WebhookEndpoint, error)
+	ListEnabledEndpointsForUser(loadContext context.Context, userIdentifier int64) ([]domain.WebhookEndpoint, error)
+	GetEndpoint(loadContext context.Context, endpointIdentifier int64) (*domain.WebhookEndpoint, error)
+	CountEndpointsForUser(loadContext context.Context, userIdentifier int64) (int, error)
+	CreateEndpointForUser(operationContext context.Context, userIdentifier int64, endpoint domain.WebhookEndpoint) (int64, error)
+	UpdateSigningSecretForUser(operationContext context.Context, userIdentifier int64, endpointIdentifier int64, encryptedSecret string) error
+	DeleteEndpointForUser(operationContext context.Context, userIdentifier int64, endpointIdentifier int64) error
+}
+
+// WebhookDeliveryRepository is the persistent delivery queue plus its history.
+type WebhookDeliveryRepository interface {
+	// EnqueueDelivery queues a delivery; queuing the same event for the same endpoint again is a no-op.
+	EnqueueDelivery(operationContext context.Context, delivery domain.WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit due PENDING deliveries and pushes their next_attempt_at
+	// forward by lease, so concurrent dispatchers (or a crash mid-send) never double-send right away.
+	ClaimDueDeliveries(operationContext context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error)
+	MarkDeliverySucceeded(operationContext context.Context, deliveryIdentifier int64, statusCode int) error
+	MarkDeliveryAttemptFailed(operationContext context.Context, deliveryIdentifier int64, statusCode *int, errorMessage string, nextAttemptAt time.Time, giveUp bool) error
+	ListDeliveriesForUser(loadContext context.Context, userIdentifier int64, endpointIdentifier int64, limit int) ([]domain.WebhookDelivery, error)
+}
+
+type PostgresWebhookRepository struct {
+	Database *sql.DB
+}
+
+func NewPostgresWebhookRepository(database *sql.DB) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{Database: database}
+}
+
+func (repository *PostgresWebhookRepository) ListEndpointsForUser(loadContext context.Context, userIdentifier int64) ([]domain.WebhookEndpoint, error) {
+	return repository.queryEndpoints(loadContext, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at ASC`, userIdentifier)
+}
+
+func (repository *PostgresWebhookRepository) ListEnabledEndpointsForUser(loadContext context.Context, userIdentifier int64) ([]domain.WebhookEndpoint, error) {
+	return repository.queryEndpoints(loadContext, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE user_id = $1 AND is_enabled = TRUE ORDER BY created_at ASC`, userIdentifier)
+}
+
+func (repository *PostgresWebhookRepository) GetEndpoint(loadContext context.Context, endpointIdentifier int64) (*domain.WebhookEndpoint, error) {
+	endpoints, queryError := repository.queryEndpoints(loadContext, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = $1`, endpointIdentifier)
+	if queryError != nil {
+		return nil, queryError
+	}
+	if len(endpoints) == 0 {
+		return nil, ErrWebhookEndpointNotFound
+	}
+	return &endpoints[0], nil
+}
+
+func (repository *PostgresWebhookRepository) CountEndpointsForUser(loadContext context.Context, userIdentifier int64) (int, error) {
+	row := repository.Database.QueryRowContext(loadContext, `SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1`, userIdentifier)
+	var endpointCount int
+	if scanError := row.Scan(&endpointCount); scanError != nil {
+		return 0, scanError
+	}
+	return endpointCount, nil
+}
+
+func (repository *PostgresWebhookRepository) CreateEndpointForUser(operationContext context.Context, userIdentifier int64, endpoint domain.WebhookEndpoint) (int64, error) {
+	row := repository.Database.QueryRowContext(
+		operationContext,
+		`INSERT INTO webhook_endpoints (user_id, url, description, signing_secret, event_types, is_enabled)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id`,
+		userIdentifier,
+		endpoint.URL,
+		endpoint.Description,
+		endpoint.SigningSecret,
+		pq.Array(endpoint.EventTypes),
+		endpoint.IsEnabled,
+	)
+	var endpointIdentifier int64
+	if scanError := row.Scan(&endpointIdentifier); scanError != nil {
+		return 0, scanError
+	}
+	return endpointIdentifier, nil
+}
+
+func (repository *PostgresWebhookRepository) UpdateSigningSecretForUser(operationContext context.Context, userIdentifier int64, endpointIdentifier int64, encryptedSecret string) error {
+	result, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE webhook_endpoints SET signing_secret = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3`,
+		encryptedSecret, endpointIdentifier, userIdentifier,
+	)
+	if updateError != nil {
+		return updateError
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+func (repository *PostgresWebhookRepository) DeleteEndpointForUser(operationContext context.Context, userIdentifier int64, endpointIdentifier int64) error {
+	result, deleteError := repository.Database.ExecContext(
+		operationContext,
+		`DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`,
+		endpointIdentifier, userIdentifier,
+	)
+	if deleteError != nil {
+		return deleteError
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+func (repository *PostgresWebhookRepository) EnqueueDelivery(operationContext context.Context, delivery domain.WebhookDelivery) error {
+	_, insertError := repository.Database.ExecContext(
+		operationContext,
+		`INSERT INTO webhook_deliveries (endpoint_id, user_id, event_id, event_type, payload)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (endpoint_id, event_id) DO NOTHING`,
+		delivery.EndpointIdentifier,
+		delivery.UserIdentifier,
+		delivery.EventIdentifier,
+		delivery.EventType,
+		delivery.Payload,
+	)
+	return insertError
+}
+
+func (repository *PostgresWebhookRepository) ClaimDueDeliveries(operationContext context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	rows, queryError := repository.Database.QueryContext(
+		operationContext,
+		`UPDATE webhook_deliveries SET next_attempt_at = NOW() + make_interval(secs => $2)
+		 WHERE id IN (
+		    SELECT id FROM webhook_deliveries
+		    WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+		    ORDER BY next_attempt_at ASC
+		    LIMIT $1
+		    FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+webhookDeliveryColumns,
+		limit, lease.Seconds(),
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+	return scanWebhookDeliveryRows(rows)
+}
+
+func (repository *PostgresWebhookRepository) MarkDeliverySucceeded(operationContext context.Context, deliveryIdentifier int64, statusCode int) error {
+	_, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE webhook_deliveries
+		 SET status = 'SUCCEEDED', attempt_count = attempt_count + 1, last_status_code = $1, last_error = NULL, delivered_at = NOW()
+		 WHERE id = $2`,
+		statusCode, deliveryIdentifier,
+	)
+	return updateError
+}
+
+func (repository *PostgresWebhookRepository) MarkDeliveryAttemptFailed(operationContext context.Context, deliveryIdentifier int64, statusCode *int, errorMessage string, nextAttemptAt time.Time, giveUp bool) error {
+	status := domain.WebhookDeliveryPending
+	if giveUp {
+		status = domain.WebhookDeliveryFailed
+	}
+	_, updateError := repository.Database.ExecContext(
+		operationContext,
+		`UPDATE webhook_deliveries
+		 SET status = $1, attempt_count = attempt_count + 1, last_status_code = $2, last_error = $3, next_attempt_at = $4
+		 WHERE id = $5`,
+		status, statusCode, errorMessage, nextAttemptAt, deliveryIdentifier,
+	)
+	return updateError
+}
+
+// ListDeliveriesForUser returns the newest deliveries of the user; endpointIdentifier 0 means all endpoints.
+func (repository *PostgresWebhookRepository) ListDeliveriesForUser(loadContext context.Context, userIdentifier int64, endpointIdentifier int64, limit int) ([]domain.WebhookDelivery, error) {
+	rows, queryError := repository.Database.QueryContext(
+		loadContext,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE user_id = $1 AND ($2::BIGINT = 0 OR endpoint_id = $2::BIGINT)
+		 ORDER BY created_at DESC
+		 LIMIT $3`,
+		userIdentifier, endpointIdentifier, limit,
+	)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+	return scanWebhookDeliveryRows(rows)
+}
+
+func (repository *PostgresWebhookRepository) queryEndpoints(loadContext context.Context, querySQL string, arguments ...interface{}) ([]domain.WebhookEndpoint, error) {
+	rows, queryError := repository.Database.QueryContext(loadContext, querySQL, arguments...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	endpoints := make([]domain.WebhookEndpoint, 0)
+	for rows.Next() {
+		var endpoint domain.WebhookEndpoint
+		if scanError := rows.Scan(
+			&endpoint.Identifier,
+			&endpoint.UserIdentifier,
+			&endpoint.URL,
+			&endpoint.Description,
+			&endpoint.SigningSecret,
+			pq.Array(&endpoint.EventTypes),
+			&endpoint.IsEnabled,
+			&endpoint.CreatedAt,
+			&endpoint.UpdatedAt,
+		); scanError != nil {
+			return nil, scanError
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func scanWebhookDeliveryRows(rows *sql.Rows) ([]domain.WebhookDelivery, error) {
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery domain.WebhookDelivery
+		var lastStatusCode sql.NullInt64
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+		if scanError := rows.Scan(
+			&delivery.Identifier,
+			&delivery.EndpointIdentifier,
+			&delivery.UserIdentifier,
+			&delivery.EventIdentifier,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.AttemptCount,
+			&delivery.NextAttemptAt,
+			&lastStatusCode,
+			&lastError,
+			&delivery.CreatedAt,
+			&deliveredAt,
+		); scanError != nil {
+			return nil, scanError
+		}
+		if lastStatusCode.Valid {
+			value := int(lastStatusCode.Int64)
+			delivery.LastStatusCode = &value
+		}
+		if lastError.Valid {
+			value := lastError.String
+			delivery.LastError = &value
+		}
+		if deliveredAt.Valid {
+			value := deliveredAt.Time
+			delivery.DeliveredAt = &value
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,117 @@
+package security
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// KeyProvider protects the per-record data keys SecretCipher encrypts with (envelope encryption): the
+// data key is stored next to the ciphertext only in wrapped form, and only the provider can unwrap it.
+type KeyProvider interface {
+	// KeyIdentifier names the key-encryption key; it is embedded in every payload the provider protects.
+	KeyIdentifier() string
+	WrapDataKey(operationContext context.Context, dataKey []byte) ([]byte, error)
+	UnwrapDataKey(operationContext context.Context, wrappedDataKey []byte) ([]byte, error)
+}
+
+// LocalKeyProvider wraps data keys with an AES-256-GCM key held in process memory, read from the
+// environment or a mounted secrets file.
+type LocalKeyProvider struct {
+	identifier          string
+	keyBytes            []byte
+	authenticatedCipher cipher.AEAD
+}
+
+func NewLocalKeyProvider(identifier string, base64EncodedKey string) (*LocalKeyProvider, error) {
+	keyBytes, authenticatedCipher, cipherError := newAuthenticatedCipher(base64EncodedKey)
+	if cipherError != nil {
+		return nil, fmt.Errorf("credentials encryption key %q: %w", identifier, cipherError)
+	}
+	return &LocalKeyProvider{identifier: identifier, keyBytes: keyBytes, authenticatedCipher: authenticatedCipher}, nil
+}
+
+func (provider *LocalKeyProvider) KeyIdentifier() string {
+	return provider.identifier
+}
+
+func (provider *LocalKeyProvider) WrapDataKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	return sealWithNonce(provider.authenticatedCipher, dataKey)
+}
+
+func (provider *LocalKeyProvider) UnwrapDataKey(_ context.Context, wrappedDataKey []byte) ([]byte, error) {
+	return openWithNonce(provider.authenticatedCipher, wrappedDataKey)
+}
+
+// LoadKeyringFile reads keys from a mounted secrets file: one "id:base64key" per line (or
+// comma-separated), '#' starts a comment. A file holding a single bare base64 key is read as
+// LegacyKeyIdentifier, the layout of a plain Docker/Kubernetes secret.
+func LoadKeyringFile(filePath string) ([]KeyringKey, error) {
+	fileContents, readError := os.ReadFile(filePath)
+	if readError != nil {
+		return nil, fmt.Errorf("could not read credentials key file: %w", readError)
+	}
+	var entries []string
+	for _, line := range strings.Split(string(fileContents), "\n") {
+		if commentStart := strings.Index(line, "#"); commentStart >= 0 {
+			line = line[:commentStart]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	if len(entries) == 1 && !strings.Contains(entries[0], keyIdentifierSeparator) {
+		return []KeyringKey{{Identifier: LegacyKeyIdentifier, Base64Key: entries[0]}}, nil
+	}
+	return ParseKeyring(strings.Join(entries, ","))
+}
+
+func newAuthenticatedCipher(base64EncodedKey string) ([]byte, cipher.AEAD, error) {
+	keyBytes, decodeError := base64.StdEncoding.DecodeString(base64EncodedKey)
+	if decodeError != nil {
+		return nil, nil, fmt.Errorf("key is not valid base64: %w", decodeError)
+	}
+	authenticatedCipher, cipherError := newAuthenticatedCipherFromBytes(keyBytes)
+	if cipherError != nil {
+		return nil, nil, cipherError
+	}
+	return keyBytes, authenticatedCipher, nil
+}
+
+func newAuthenticatedCipherFromBytes(keyBytes []byte) (cipher.AEAD, error) {
+	if len(keyBytes) != 32 {
+		return nil, fmt.Errorf("key must decode to 32 bytes, got %d", len(keyBytes))
+	}
+
+	blockCipher, blockCipherError := aes.NewCipher(keyBytes)
+	if blockCipherError != nil {
+		return nil, blockCipherError
+	}
+
+	return cipher.NewGCM(blockCipher)
+}
+
+// sealWithNonce returns (nonce || ciphertext || auth tag).
+func sealWithNonce(authenticatedCipher cipher.AEAD, plainBytes []byte) ([]byte, error) {
+	nonce := make([]byte, authenticatedCipher.NonceSize())
+	if _, randomReadError := io.ReadFull(rand.Reader, nonce); randomReadError != nil {
+		return nil, randomReadError
+	}
+	return authenticatedCipher.Seal(nonce, nonce, plainBytes, nil), nil
+}
+
+// openWithNonce reverses sealWithNonce. It fails if the payload was tampered with.
+func openWithNonce(authenticatedCipher cipher.AEAD, sealedPayload []byte) ([]byte, error) {
+	nonceSize := authenticatedCipher.NonceSize()
+	if len(sealedPayload) < nonceSize {
+		return nil, errors.New("encrypted payload is too short to contain a nonce")
+	}
+	return authenticatedCipher.Open(nil, sealedPayload[:nonceSize], sealedPayload[nonceSize:], nil)
+}
@@ -1,8 +1,7 @@
 package security
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -12,86 +11,308 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
 )
 
-// SecretCipher encrypts and decrypts sensitive values (such as Binance API secrets) at rest
-// using AES-256-GCM. The encryption key is supplied as a base64-encoded 32-byte key, typically
-// via the CREDENTIALS_ENCRYPTION_KEY environment variable.
+// LegacyKeyIdentifier is the id given to CREDENTIALS_ENCRYPTION_KEY in the keyring. Payloads written
+// before ciphertexts carried a key id were all encrypted with it.
+const LegacyKeyIdentifier = "1"
+
+const (
+	// keyIdentifierSeparator splits "<key id>:<body>". Standard base64 never contains ':', so a payload
+	// without it is a legacy one.
+	keyIdentifierSeparator = ":"
+	// envelopeSeparator splits an envelope body "<wrapped data key>.<sealed value>", both base64.
+	envelopeSeparator = "."
+
+	// providerCallTimeout bounds a key provider round trip (a Vault call) per encrypt/decrypt.
+	providerCallTimeout = 10 * time.Second
+	// Unwrapped data keys are cached briefly so the automation loop does not call the provider for
+	// every credential load.
+	dataKeyCacheTTL      = 10 * time.Minute
+	dataKeyCacheCapacity = 1024
+)
+
+// ErrUnknownEncryptionKey is returned when a payload names a key id that is not in the keyring, e.g.
+// after a key was retired before every row was re-encrypted.
+var ErrUnknownEncryptionKey = errors.New("payload was encrypted with a key that is not configured")
+
+// SecretCipher encrypts and decrypts sensitive values (such as Binance API secrets) at rest using
+// AES-256-GCM with envelope encryption: every value gets its own random data key, which is stored
+// wrapped by a KeyProvider. Payloads are prefixed with the provider's key id; new payloads always use
+// the primary provider and any provider in the ring can decrypt, so the key can rotate (see
+// ReencryptString). Payloads written before envelopes (no key id, or "<id>:<base64>") are still
+// readable with the local key that wrote them.
 type SecretCipher struct {
-	authenticatedCipher cipher.AEAD
-	keyBytes            []byte // kept for keyed fingerprinting (HMAC), separate purpose from encryption
+	primaryKeyIdentifier  string
+	providersByIdentifier map[string]KeyProvider
+	keyIdentifiers        []string // in configuration order
+	fingerprintKeyBytes   []byte   // dedicated HMAC key for EmailFingerprint, never used for encryption
+
+	cacheMutex   sync.Mutex
+	dataKeyCache map[string]cachedDataKey
+}
+
+type cachedDataKey struct {
+	dataKey   []byte
+	expiresAt time.Time
 }
 
-// NewSecretCipher builds a SecretCipher from a base64-encoded 32-byte key.
+// KeyringKey is one local key of the ring: an id (letters, digits, '-', '_') and a base64-encoded
+// 32-byte key.
+type KeyringKey struct {
+	Identifier string
+	Base64Key  string
+}
+
+// NewSecretCipher builds a single-key SecretCipher from a base64-encoded 32-byte key. The key gets
+// LegacyKeyIdentifier, so payloads written before the keyring existed still decrypt.
 func NewSecretCipher(base64EncodedKey string) (*SecretCipher, error) {
 	if base64EncodedKey == "" {
 		return nil, errors.New("credentials encryption key is not configured")
 	}
+	localProvider, providerError := NewLocalKeyProvider(LegacyKeyIdentifier, base64EncodedKey)
+	if providerError != nil {
+		return nil, providerError
+	}
+	return NewKeyringSecretCipher([]KeyProvider{localProvider}, LegacyKeyIdentifier)
+}
 
-	keyBytes, decodeError := base64.StdEncoding.DecodeString(base64EncodedKey)
-	if decodeError != nil {
-		return nil, fmt.Errorf("credentials encryption key is not valid base64: %w", decodeError)
+// NewKeyringSecretCipher builds a SecretCipher that encrypts with primaryKeyIdentifier and decrypts
+// with any of providers. It has no fingerprint key; NewSecretCipherFromConfiguration adds the configured
+// one.
+func NewKeyringSecretCipher(providers []KeyProvider, primaryKeyIdentifier string) (*SecretCipher, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("credentials encryption key is not configured")
+	}
+
+	secretCipher := &SecretCipher{
+		primaryKeyIdentifier:  primaryKeyIdentifier,
+		providersByIdentifier: make(map[string]KeyProvider, len(providers)),
+		dataKeyCache:          make(map[string]cachedDataKey),
+	}
+	for _, provider := range providers {
+		keyIdentifier := provider.KeyIdentifier()
+		if !isValidKeyIdentifier(keyIdentifier) {
+			return nil, fmt.Errorf("credentials encryption key id %q must be 1-32 letters, digits, '-' or '_'", keyIdentifier)
+		}
+		if _, duplicate := secretCipher.providersByIdentifier[keyIdentifier]; duplicate {
+			return nil, fmt.Errorf("credentials encryption key id %q is configured twice", keyIdentifier)
+		}
+		secretCipher.providersByIdentifier[keyIdentifier] = provider
+		secretCipher.keyIdentifiers = append(secretCipher.keyIdentifiers, keyIdentifier)
 	}
-	if len(keyBytes) != 32 {
-		return nil, fmt.Errorf("credentials encryption key must decode to 32 bytes, got %d", len(keyBytes))
+	if _, present := secretCipher.providersByIdentifier[primaryKeyIdentifier]; !present {
+		return nil, fmt.Errorf("primary credentials encryption key id %q is not in the keyring", primaryKeyIdentifier)
 	}
+	return secretCipher, nil
+}
 
-	blockCipher, blockCipherError := aes.NewCipher(keyBytes)
-	if blockCipherError != nil {
-		return nil, blockCipherError
+// NewLocalKeyProviders turns parsed keyring entries into local providers.
+func NewLocalKeyProviders(keys []KeyringKey) ([]KeyProvider, error) {
+	providers := make([]KeyProvider, 0, len(keys))
+	for _, key := range keys {
+		localProvider, providerError := NewLocalKeyProvider(key.Identifier, key.Base64Key)
+		if providerError != nil {
+			return nil, providerError
+		}
+		providers = append(providers, localProvider)
 	}
+	return providers, nil
+}
+
+// ParseKeyring parses "id:base64key,id:base64key" (whitespace around entries is ignored).
+func ParseKeyring(specification string) ([]KeyringKey, error) {
+	var keys []KeyringKey
+	for _, entry := range strings.Split(specification, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		identifier, base64Key, found := strings.Cut(entry, keyIdentifierSeparator)
+		if !found {
+			return nil, fmt.Errorf("keyring entry must look like id:base64key")
+		}
+		keys = append(keys, KeyringKey{Identifier: strings.TrimSpace(identifier), Base64Key: strings.TrimSpace(base64Key)})
+	}
+	return keys, nil
+}
 
-	galoisCounterMode, galoisCounterModeError := cipher.NewGCM(blockCipher)
-	if galoisCounterModeError != nil {
-		return nil, galoisCounterModeError
+func isValidKeyIdentifier(identifier string) bool {
+	if identifier == "" || len(identifier) > 32 {
+		return false
 	}
+	for _, character := range identifier {
+		isAllowed := (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') ||
+			(character >= '0' && character <= '9') || character == '-' || character == '_'
+		if !isAllowed {
+			return false
+		}
+	}
+	return true
+}
+
+// PrimaryKeyIdentifier is the id of the key new payloads are encrypted with.
+func (secretCipher *SecretCipher) PrimaryKeyIdentifier() string {
+	return secretCipher.primaryKeyIdentifier
+}
 
-	return &SecretCipher{authenticatedCipher: galoisCounterMode, keyBytes: keyBytes}, nil
+// KeyIdentifiers lists every key id in the ring, in configuration order.
+func (secretCipher *SecretCipher) KeyIdentifiers() []string {
+	return append([]string(nil), secretCipher.keyIdentifiers...)
 }
 
 // EmailFingerprint returns a keyed one-way fingerprint (HMAC-SHA256) of an email address. It lets the
 // app correlate or look up an email (e.g. for the deletion audit) WITHOUT storing the address itself:
-// the result cannot be reversed to the email without this server key. Returns "" if no key is set.
+// the result cannot be reversed to the email without this server key. The key is the configured
+// fingerprint key, independent of the encryption keys so it works with Vault-only keyrings and survives
+// key rotation. Returns "" if no fingerprint key is set.
 func (secretCipher *SecretCipher) EmailFingerprint(email string) string {
-	if secretCipher == nil || len(secretCipher.keyBytes) == 0 {
+	if secretCipher == nil || len(secretCipher.fingerprintKeyBytes) == 0 {
 		return ""
 	}
-	mac := hmac.New(sha256.New, secretCipher.keyBytes)
+	mac := hmac.New(sha256.New, secretCipher.fingerprintKeyBytes)
 	// Domain-separate from any other use of the same key.
 	mac.Write([]byte("account-deletion-email-fingerprint:" + strings.ToLower(strings.TrimSpace(email))))
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// EncryptString returns a base64-encoded payload of (nonce || ciphertext || auth tag).
+// EncryptString returns "<primary key id>:<wrapped data key>.<sealed value>", where the sealed value is
+// (nonce || ciphertext || auth tag) under a fresh data key.
 func (secretCipher *SecretCipher) EncryptString(plainText string) (string, error) {
-	nonce := make([]byte, secretCipher.authenticatedCipher.NonceSize())
-	if _, randomReadError := io.ReadFull(rand.Reader, nonce); randomReadError != nil {
+	dataKey := make([]byte, 32)
+	if _, randomReadError := io.ReadFull(rand.Reader, dataKey); randomReadError != nil {
 		return "", randomReadError
 	}
+	authenticatedCipher, cipherError := newAuthenticatedCipherFromBytes(dataKey)
+	if cipherError != nil {
+		return "", cipherError
+	}
+	sealedValue, sealError := sealWithNonce(authenticatedCipher, []byte(plainText))
+	if sealError != nil {
+		return "", sealError
+	}
+
+	providerContext, cancel := context.WithTimeout(context.Background(), providerCallTimeout)
+	defer cancel()
+	wrappedDataKey, wrapError := secretCipher.providersByIdentifier[secretCipher.primaryKeyIdentifier].WrapDataKey(providerContext, dataKey)
+	if wrapError != nil {
+		return "", wrapError
+	}
 
-	sealedPayload := secretCipher.authenticatedCipher.Seal(nonce, nonce, []byte(plainText), nil)
-	return base64.StdEncoding.EncodeToString(sealedPayload), nil
+	return secretCipher.primaryKeyIdentifier + keyIdentifierSeparator +
+		base64.StdEncoding.EncodeToString(wrappedDataKey) + envelopeSeparator +
+		base64.StdEncoding.EncodeToString(sealedValue), nil
 }
 
-// DecryptString reverses EncryptString. It fails if the payload was tampered with.
+// DecryptString reverses EncryptString with whichever key the payload names. It fails if the payload
+// was tampered with or its key is no longer configured.
 func (secretCipher *SecretCipher) DecryptString(encodedPayload string) (string, error) {
-	rawPayload, decodeError := base64.StdEncoding.DecodeString(encodedPayload)
-	if decodeError != nil {
-		return "", decodeError
+	keyIdentifier, body := splitKeyIdentifier(encodedPayload)
+	provider, present := secretCipher.providersByIdentifier[keyIdentifier]
+	if !present {
+		return "", fmt.Errorf("%w: key id %q", ErrUnknownEncryptionKey, keyIdentifier)
 	}
 
-	nonceSize := secretCipher.authenticatedCipher.NonceSize()
-	if len(rawPayload) < nonceSize {
-		return "", errors.New("encrypted payload is too short to contain a nonce")
+	encodedWrappedKey, encodedSealedValue, isEnvelope := strings.Cut(body, envelopeSeparator)
+	if !isEnvelope {
+		return decryptDirectPayload(provider, body)
 	}
 
-	nonce := rawPayload[:nonceSize]
-	cipherText := rawPayload[nonceSize:]
-
-	plainBytes, openError := secretCipher.authenticatedCipher.Open(nil, nonce, cipherText, nil)
+	dataKey, unwrapError := secretCipher.unwrapDataKey(provider, encodedWrappedKey)
+	if unwrapError != nil {
+		return "", unwrapError
+	}
+	authenticatedCipher, cipherError := newAuthenticatedCipherFromBytes(dataKey)
+	if cipherError != nil {
+		return "", cipherError
+	}
+	sealedValue, decodeError := base64.StdEncoding.DecodeString(encodedSealedValue)
+	if decodeError != nil {
+		return "", decodeError
+	}
+	plainBytes, openError := openWithNonce(authenticatedCipher, sealedValue)
 	if openError != nil {
 		return "", openError
 	}
+	return string(plainBytes), nil
+}
 
+// decryptDirectPayload reads the pre-envelope format, sealed directly with a local key.
+func decryptDirectPayload(provider KeyProvider, encodedSealedValue string) (string, error) {
+	localProvider, isLocal := provider.(*LocalKeyProvider)
+	if !isLocal {
+		return "", fmt.Errorf("payload for key id %q is not an envelope", provider.KeyIdentifier())
+	}
+	sealedValue, decodeError := base64.StdEncoding.DecodeString(encodedSealedValue)
+	if decodeError != nil {
+		return "", decodeError
+	}
+	plainBytes, openError := openWithNonce(localProvider.authenticatedCipher, sealedValue)
+	if openError != nil {
+		return "", openError
+	}
 	return string(plainBytes), nil
 }
+
+func (secretCipher *SecretCipher) unwrapDataKey(provider KeyProvider, encodedWrappedKey string) ([]byte, error) {
+	cacheKey := provider.KeyIdentifier() + keyIdentifierSeparator + encodedWrappedKey
+	secretCipher.cacheMutex.Lock()
+	cached, present := secretCipher.dataKeyCache[cacheKey]
+	secretCipher.cacheMutex.Unlock()
+	if present && time.Now().Before(cached.expiresAt) {
+		return cached.dataKey, nil
+	}
+
+	wrappedDataKey, decodeError := base64.StdEncoding.DecodeString(encodedWrappedKey)
+	if decodeError != nil {
+		return nil, decodeError
+	}
+	providerContext, cancel := context.WithTimeout(context.Background(), providerCallTimeout)
+	defer cancel()
+	dataKey, unwrapError := provider.UnwrapDataKey(providerContext, wrappedDataKey)
+	if unwrapError != nil {
+		return nil, unwrapError
+	}
+
+	secretCipher.cacheMutex.Lock()
+	if len(secretCipher.dataKeyCache) >= dataKeyCacheCapacity {
+		secretCipher.dataKeyCache = make(map[string]cachedDataKey)
+	}
+	secretCipher.dataKeyCache[cacheKey] = cachedDataKey{dataKey: dataKey, expiresAt: time.Now().Add(dataKeyCacheTTL)}
+	secretCipher.cacheMutex.Unlock()
+	return dataKey, nil
+}
+
+// KeyIdentifierOf returns the id of the key a payload was encrypted with (LegacyKeyIdentifier for
+// payloads written before key ids were embedded).
+func (secretCipher *SecretCipher) KeyIdentifierOf(encodedPayload string) string {
+	keyIdentifier, _ := splitKeyIdentifier(encodedPayload)
+	return keyIdentifier
+}
+
+// NeedsReencryption reports whether a payload is not yet under the primary key.
+func (secretCipher *SecretCipher) NeedsReencryption(encodedPayload string) bool {
+	return encodedPayload != "" && secretCipher.KeyIdentifierOf(encodedPayload) != secretCipher.primaryKeyIdentifier
+}
+
+// ReencryptString decrypts a payload with its own key and encrypts it again with the primary key.
+// Payloads already under the primary key are returned unchanged.
+func (secretCipher *SecretCipher) ReencryptString(encodedPayload string) (string, error) {
+	if !secretCipher.NeedsReencryption(encodedPayload) {
+		return encodedPayload, nil
+	}
+	plainText, decryptError := secretCipher.DecryptString(encodedPayload)
+	if decryptError != nil {
+		return "", decryptError
+	}
+	return secretCipher.EncryptString(plainText)
+}
+
+func splitKeyIdentifier(encodedPayload string) (string, string) {
+	if keyIdentifier, body, found := strings.Cut(encodedPayload, keyIdentifierSeparator); found {
+		return keyIdentifier, body
+	}
+	return LegacyKeyIdentifier, encodedPayload
+}
@@ -0,0 +1,190 @@
+package security
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"coin-alert/internal/logging"
+)
+
+var keyringLogger = logging.For("credentials")
+
+// KeyringConfiguration lists every key provider the keyring can be built from:
+//
+//   - EncryptionKey: the original single key; it joins the ring as LegacyKeyIdentifier.
+//   - EncryptionKeys: additional local keys as "id:base64key,id:base64key".
+//   - KeysFile: local keys from a mounted secrets file (see LoadKeyringFile).
+//   - VaultAddress + VaultTransitKey: a Vault Transit key, with the token in VaultTokenFile (preferred)
+//     or VaultToken, the mount in VaultTransitMount (default "transit") and its key id in
+//     VaultKeyIdentifier (default "vault").
+//   - PrimaryKeyIdentifier: the key new payloads use; defaults to the Vault key when configured,
+//     otherwise the last local key listed.
+//   - FingerprintKey: a base64-encoded HMAC key (at least 32 bytes) for email fingerprints. It must
+//     never change, or stored fingerprints stop matching, and it is not rotated with the ring. When it
+//     is unset, the legacy EncryptionKey is used as before (with a warning); keyrings without a legacy
+//     key (local lists or Vault only) must set it.
+//
+// To rotate, add the new key, restart (the server then re-encrypts stored credentials in the
+// background, or run cmd/reencrypt-credentials), and only then drop the old key.
+type KeyringConfiguration struct {
+	EncryptionKey        string
+	EncryptionKeys       string
+	KeysFile             string
+	PrimaryKeyIdentifier string
+	FingerprintKey       string
+	VaultAddress         string
+	VaultTransitMount    string
+	VaultTransitKey      string
+	VaultKeyIdentifier   string
+	VaultToken           string
+	VaultTokenFile       string
+}
+
+// Validate checks the values that can be checked without reading files or calling Vault.
+func (configuration KeyringConfiguration) Validate() error {
+	if _, parseError := ParseKeyring(configuration.EncryptionKeys); parseError != nil {
+		return parseError
+	}
+	address := strings.TrimSpace(configuration.VaultAddress)
+	keyName := strings.TrimSpace(configuration.VaultTransitKey)
+	if (address == "") != (keyName == "") {
+		return errors.New("the Vault address and transit key must be set together")
+	}
+	if configuration.hasEncryptionKey() || strings.TrimSpace(configuration.FingerprintKey) != "" {
+		if _, _, fingerprintKeyError := configuration.fingerprintKey(); fingerprintKeyError != nil {
+			return fingerprintKeyError
+		}
+	}
+	return nil
+}
+
+// fingerprintKey returns the email fingerprint key, and whether it is the legacy encryption key. Before
+// the dedicated key existed, fingerprints were keyed with the legacy key, so falling back to it keeps
+// an upgraded deployment's stored fingerprints matching.
+func (configuration KeyringConfiguration) fingerprintKey() ([]byte, bool, error) {
+	legacyKey := strings.TrimSpace(configuration.EncryptionKey)
+	if strings.TrimSpace(configuration.FingerprintKey) != "" || legacyKey == "" {
+		keyBytes, decodeError := decodeFingerprintKey(configuration.FingerprintKey)
+		return keyBytes, false, decodeError
+	}
+	keyBytes, decodeError := base64.StdEncoding.DecodeString(legacyKey)
+	if decodeError != nil {
+		return nil, false, errors.New("the credentials encryption key must be base64-encoded")
+	}
+	return keyBytes, true, nil
+}
+
+func (configuration KeyringConfiguration) hasEncryptionKey() bool {
+	for _, value := range []string{configuration.EncryptionKey, configuration.EncryptionKeys, configuration.KeysFile, configuration.VaultAddress, configuration.VaultTransitKey} {
+		if strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeFingerprintKey decodes the base64 email fingerprint key; it must hold at least 32 bytes.
+func decodeFingerprintKey(base64EncodedKey string) ([]byte, error) {
+	base64EncodedKey = strings.TrimSpace(base64EncodedKey)
+	if base64EncodedKey == "" {
+		return nil, errors.New("the email fingerprint key is required when the keyring has no legacy encryption key")
+	}
+	keyBytes, decodeError := base64.StdEncoding.DecodeString(base64EncodedKey)
+	if decodeError != nil {
+		return nil, errors.New("the email fingerprint key must be base64-encoded")
+	}
+	if len(keyBytes) < 32 {
+		return nil, errors.New("the email fingerprint key must be at least 32 bytes")
+	}
+	return keyBytes, nil
+}
+
+// NewSecretCipherFromConfiguration builds the keyring from every configured key provider, with the
+// configured email fingerprint key.
+func NewSecretCipherFromConfiguration(configuration KeyringConfiguration) (*SecretCipher, error) {
+	var keys []KeyringKey
+	if legacyKey := strings.TrimSpace(configuration.EncryptionKey); legacyKey != "" {
+		keys = append(keys, KeyringKey{Identifier: LegacyKeyIdentifier, Base64Key: legacyKey})
+	}
+	additionalKeys, parseError := ParseKeyring(configuration.EncryptionKeys)
+	if parseError != nil {
+		return nil, parseError
+	}
+	keys = append(keys, additionalKeys...)
+	if keyFilePath := strings.TrimSpace(configuration.KeysFile); keyFilePath != "" {
+		fileKeys, fileError := LoadKeyringFile(keyFilePath)
+		if fileError != nil {
+			return nil, fileError
+		}
+		keys = append(keys, fileKeys...)
+	}
+	providers, providersError := NewLocalKeyProviders(keys)
+	if providersError != nil {
+		return nil, providersError
+	}
+
+	primaryKeyIdentifier := ""
+	if len(providers) > 0 {
+		primaryKeyIdentifier = providers[len(providers)-1].KeyIdentifier()
+	}
+	vaultProvider, vaultError := vaultTransitKeyProvider(configuration)
+	if vaultError != nil {
+		return nil, vaultError
+	}
+	if vaultProvider != nil {
+		providers = append(providers, vaultProvider)
+		primaryKeyIdentifier = vaultProvider.KeyIdentifier()
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("credentials encryption key is not configured")
+	}
+
+	if configuredPrimary := strings.TrimSpace(configuration.PrimaryKeyIdentifier); configuredPrimary != "" {
+		primaryKeyIdentifier = configuredPrimary
+	}
+	fingerprintKeyBytes, legacyFingerprintKey, fingerprintKeyError := configuration.fingerprintKey()
+	if fingerprintKeyError != nil {
+		return nil, fingerprintKeyError
+	}
+	if legacyFingerprintKey {
+		keyringLogger.Warn("CREDENTIALS_FINGERPRINT_KEY is not set; email fingerprints use the legacy encryption key. Set it to the value of CREDENTIALS_ENCRYPTION_KEY before rotating that key away")
+	}
+	secretCipher, cipherError := NewKeyringSecretCipher(providers, primaryKeyIdentifier)
+	if cipherError != nil {
+		return nil, cipherError
+	}
+	secretCipher.fingerprintKeyBytes = fingerprintKeyBytes
+	return secretCipher, nil
+}
+
+func vaultTransitKeyProvider(configuration KeyringConfiguration) (*VaultTransitKeyProvider, error) {
+	address := strings.TrimSpace(configuration.VaultAddress)
+	keyName := strings.TrimSpace(configuration.VaultTransitKey)
+	if address == "" && keyName == "" {
+		return nil, nil
+	}
+	if address == "" || keyName == "" {
+		return nil, errors.New("the Vault address and transit key must be set together")
+	}
+
+	token := strings.TrimSpace(configuration.VaultToken)
+	if tokenFilePath := strings.TrimSpace(configuration.VaultTokenFile); tokenFilePath != "" {
+		tokenBytes, readError := os.ReadFile(tokenFilePath)
+		if readError != nil {
+			return nil, fmt.Errorf("could not read the Vault token file: %w", readError)
+		}
+		token = strings.TrimSpace(string(tokenBytes))
+	}
+	if token == "" {
+		return nil, errors.New("a Vault token is required (a token file or the token itself)")
+	}
+
+	keyIdentifier := strings.TrimSpace(configuration.VaultKeyIdentifier)
+	if keyIdentifier == "" {
+		keyIdentifier = "vault"
+	}
+	return NewVaultTransitKeyProvider(keyIdentifier, address, token, configuration.VaultTransitMount, keyName), nil
+}
@@ -0,0 +1,83 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyringConfigurationRequiresAFingerprintKeyWithoutALegacyKey(t *testing.T) {
+	cases := []struct {
+		name          string
+		configuration KeyringConfiguration
+		expected      string
+	}{
+		{name: "nothing configured", configuration: KeyringConfiguration{}},
+		{name: "legacy key without fingerprint key", configuration: KeyringConfiguration{EncryptionKey: "a2V5"}},
+		{name: "legacy key not base64", configuration: KeyringConfiguration{EncryptionKey: "not base64!"}, expected: "base64"},
+		{name: "local list without fingerprint key", configuration: KeyringConfiguration{EncryptionKeys: "v2:a2V5"}, expected: "fingerprint key is required"},
+		{name: "keys file without fingerprint key", configuration: KeyringConfiguration{KeysFile: "/run/secrets/credential_keys"}, expected: "fingerprint key is required"},
+		{name: "Vault only without fingerprint key", configuration: KeyringConfiguration{VaultAddress: "https://vault.example.com", VaultTransitKey: "coin-hub"}, expected: "fingerprint key is required"},
+		{name: "fingerprint key too short", configuration: KeyringConfiguration{EncryptionKey: "a2V5", FingerprintKey: "c2hvcnQ="}, expected: "at least 32 bytes"},
+		{name: "fingerprint key not base64", configuration: KeyringConfiguration{EncryptionKey: "a2V5", FingerprintKey: "not base64!"}, expected: "base64"},
+		{name: "fingerprint key configured", configuration: KeyringConfiguration{EncryptionKey: "a2V5", FingerprintKey: "ZmluZ2VycHJpbnQta2V5LWZpbmdlcnByaW50LWtleSE="}},
+	}
+	for _, testCase := range cases {
+		validationError := testCase.configuration.Validate()
+		if testCase.expected == "" && validationError != nil {
+			t.Errorf("%s: unexpected error %v", testCase.name, validationError)
+		}
+		if testCase.expected != "" && (validationError == nil || !strings.Contains(validationError.Error(), testCase.expected)) {
+			t.Errorf("%s: error = %v, want it to mention %q", testCase.name, validationError, testCase.expected)
+		}
+	}
+}
+
+// TestVaultOnlyKeyringFingerprintsEmails checks that a keyring without local keys still gives every
+// email its own fingerprint, and that the fingerprint does not change with the encryption keys.
+func TestVaultOnlyKeyringFingerprintsEmails(t *testing.T) {
+	fingerprintKey := randomBase64Key(t)
+	vaultOnly, vaultError := NewSecretCipherFromConfiguration(KeyringConfiguration{
+		VaultAddress: "https://vault.example.com", VaultTransitKey: "coin-hub", VaultToken: "token", FingerprintKey: fingerprintKey,
+	})
+	if vaultError != nil {
+		t.Fatal(vaultError)
+	}
+	localOnly, localError := NewSecretCipherFromConfiguration(KeyringConfiguration{EncryptionKey: randomBase64Key(t), FingerprintKey: fingerprintKey})
+	if localError != nil {
+		t.Fatal(localError)
+	}
+
+	first, second := vaultOnly.EmailFingerprint("ana@example.com"), vaultOnly.EmailFingerprint("bruno@example.com")
+	if first == "" || first == second {
+		t.Fatalf("expected distinct fingerprints, got %q and %q", first, second)
+	}
+	if vaultOnly.EmailFingerprint(" Ana@Example.com ") != first {
+		t.Error("the fingerprint should ignore case and surrounding spaces")
+	}
+	if localOnly.EmailFingerprint("ana@example.com") != first {
+		t.Error("the fingerprint should depend only on the fingerprint key")
+	}
+
+	if _, missingError := NewSecretCipherFromConfiguration(KeyringConfiguration{EncryptionKeys: "v2:" + randomBase64Key(t)}); missingError == nil {
+		t.Error("a keyring without a fingerprint key or a legacy key should be refused")
+	}
+}
+
+// TestLegacyKeyFingerprintsEmailsUntilAFingerprintKeyIsSet checks the upgrade path: without a
+// fingerprint key, fingerprints are keyed with the legacy key as before, and setting the fingerprint
+// key to that value keeps them matching.
+func TestLegacyKeyFingerprintsEmailsUntilAFingerprintKeyIsSet(t *testing.T) {
+	legacyKey := randomBase64Key(t)
+	upgraded, upgradeError := NewSecretCipherFromConfiguration(KeyringConfiguration{EncryptionKey: legacyKey, EncryptionKeys: "v2:" + randomBase64Key(t)})
+	if upgradeError != nil {
+		t.Fatal(upgradeError)
+	}
+	configured, configuredError := NewSecretCipherFromConfiguration(KeyringConfiguration{EncryptionKey: legacyKey, FingerprintKey: legacyKey})
+	if configuredError != nil {
+		t.Fatal(configuredError)
+	}
+	fingerprint := upgraded.EmailFingerprint("ana@example.com")
+	if fingerprint == "" || configured.EmailFingerprint("ana@example.com") != fingerprint {
+		t.Errorf("expected the legacy key to keep fingerprinting, got %q and %q", fingerprint, configured.EmailFingerprint("ana@example.com"))
+	}
+}
@@ -0,0 +1,102 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) understood by every common authenticator app: HMAC-SHA1, 6 digits,
+// 30-second steps.
+const (
+	TOTPDigits      = 6
+	TOTPStepSeconds = 30
+	// totpAllowedSkew is how many steps before and after the current one are accepted, to absorb
+	// clock drift between the server and the phone.
+	totpAllowedSkew = 1
+)
+
+var totpSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random 160-bit secret, base32-encoded as authenticator apps expect.
+func GenerateTOTPSecret() (string, error) {
+	secretBytes := make([]byte, 20)
+	if _, randomError := rand.Read(secretBytes); randomError != nil {
+		return "", randomError
+	}
+	return totpSecretEncoding.EncodeToString(secretBytes), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that authenticator apps read from a QR code.
+func TOTPProvisioningURI(issuer string, accountName string, secret string) string {
+	label := url.PathEscape(issuer + ":" + accountName)
+	parameters := url.Values{}
+	parameters.Set("secret", secret)
+	parameters.Set("issuer", issuer)
+	parameters.Set("algorithm", "SHA1")
+	parameters.Set("digits", fmt.Sprintf("%d", TOTPDigits))
+	parameters.Set("period", fmt.Sprintf("%d", TOTPStepSeconds))
+	return "otpauth://totp/" + label + "?" + parameters.Encode()
+}
+
+// TOTPTimeStep returns the RFC 6238 counter for a point in time.
+func TOTPTimeStep(moment time.Time) int64 {
+	return moment.Unix() / TOTPStepSeconds
+}
+
+// TOTPCode computes the code for a secret at a time step.
+func TOTPCode(secret string, timeStep int64) (string, error) {
+	secretBytes, decodeError := decodeTOTPSecret(secret)
+	if decodeError != nil {
+		return "", decodeError
+	}
+	counterBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(counterBytes, uint64(timeStep))
+	mac := hmac.New(sha1.New, secretBytes)
+	mac.Write(counterBytes)
+	digest := mac.Sum(nil)
+
+	offset := digest[len(digest)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(digest[offset:offset+4]) & 0x7fffffff
+	modulus := uint32(1)
+	for digit := 0; digit < TOTPDigits; digit++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, truncated%modulus), nil
+}
+
+// VerifyTOTPCode checks a code against the steps around moment and returns the step it matched, so
+// callers can refuse to accept the same step twice.
+func VerifyTOTPCode(secret string, code string, moment time.Time) (int64, bool) {
+	normalizedCode := strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(normalizedCode) != TOTPDigits {
+		return 0, false
+	}
+	currentStep := TOTPTimeStep(moment)
+	for skew := -totpAllowedSkew; skew <= totpAllowedSkew; skew++ {
+		candidateStep := currentStep + int64(skew)
+		expectedCode, codeError := TOTPCode(secret, candidateStep)
+		if codeError != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expectedCode), []byte(normalizedCode)) {
+			return candidateStep, true
+		}
+	}
+	return 0, false
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	normalizedSecret := strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	secretBytes, decodeError := totpSecretEncoding.DecodeString(normalizedSecret)
+	if decodeError != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", decodeError)
+	}
+	return secretBytes, nil
+}
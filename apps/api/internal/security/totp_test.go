@@ -0,0 +1,61 @@
+package security
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B ("12345678901234567890").
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; the 6-digit code is their last six digits.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unixSeconds, expectedCode := range vectors {
+		code, codeError := TOTPCode(rfc6238Secret, TOTPTimeStep(time.Unix(unixSeconds, 0)))
+		if codeError != nil {
+			t.Fatalf("unexpected error: %v", codeError)
+		}
+		if code != expectedCode {
+			t.Fatalf("at %d: expected %s, got %s", unixSeconds, expectedCode, code)
+		}
+	}
+}
+
+func TestVerifyTOTPCodeAcceptsOneStepOfDrift(t *testing.T) {
+	moment := time.Unix(1111111111, 0)
+	currentStep := TOTPTimeStep(moment)
+	previousCode, _ := TOTPCode(rfc6238Secret, currentStep-1)
+	staleCode, _ := TOTPCode(rfc6238Secret, currentStep-2)
+
+	matchedStep, verified := VerifyTOTPCode(rfc6238Secret, previousCode, moment)
+	if !verified || matchedStep != currentStep-1 {
+		t.Fatalf("expected the previous step to verify, got %d %v", matchedStep, verified)
+	}
+	if _, verified := VerifyTOTPCode(rfc6238Secret, staleCode, moment); verified {
+		t.Fatalf("a code two steps old must not verify")
+	}
+	if _, verified := VerifyTOTPCode(rfc6238Secret, "12345", moment); verified {
+		t.Fatalf("a short code must not verify")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	secret, secretError := GenerateTOTPSecret()
+	if secretError != nil {
+		t.Fatalf("unexpected error: %v", secretError)
+	}
+	uri := TOTPProvisioningURI("Coin Hub", "ana@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Coin%20Hub:ana@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected provisioning URI %q", uri)
+	}
+}
@@ -0,0 +1,113 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultTransitKeyProvider wraps data keys with a HashiCorp Vault Transit key (or any server speaking
+// the same API). The key-encryption key never leaves Vault, so a database dump plus the process
+// environment is not enough to decrypt stored secrets: unwrapping needs a live, authorized call.
+type VaultTransitKeyProvider struct {
+	identifier string
+	address    string
+	token      string
+	mountPath  string
+	keyName    string
+	httpClient *http.Client
+}
+
+// NewVaultTransitKeyProvider targets POST {address}/v1/{mountPath}/encrypt|decrypt/{keyName}.
+func NewVaultTransitKeyProvider(identifier string, address string, token string, mountPath string, keyName string) *VaultTransitKeyProvider {
+	if mountPath == "" {
+		mountPath = "transit"
+	}
+	return &VaultTransitKeyProvider{
+		identifier: identifier,
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		mountPath:  strings.Trim(mountPath, "/"),
+		keyName:    keyName,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (provider *VaultTransitKeyProvider) KeyIdentifier() string {
+	return provider.identifier
+}
+
+type vaultTransitResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"`
+		Plaintext  string `json:"plaintext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// WrapDataKey returns Vault's ciphertext ("vault:v<version>:...") for the data key.
+func (provider *VaultTransitKeyProvider) WrapDataKey(operationContext context.Context, dataKey []byte) ([]byte, error) {
+	response, requestError := provider.call(operationContext, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)})
+	if requestError != nil {
+		return nil, requestError
+	}
+	if response.Data.Ciphertext == "" {
+		return nil, errors.New("vault transit: encrypt returned no ciphertext")
+	}
+	return []byte(response.Data.Ciphertext), nil
+}
+
+func (provider *VaultTransitKeyProvider) UnwrapDataKey(operationContext context.Context, wrappedDataKey []byte) ([]byte, error) {
+	response, requestError := provider.call(operationContext, "decrypt", map[string]string{"ciphertext": string(wrappedDataKey)})
+	if requestError != nil {
+		return nil, requestError
+	}
+	dataKey, decodeError := base64.StdEncoding.DecodeString(response.Data.Plaintext)
+	if decodeError != nil {
+		return nil, fmt.Errorf("vault transit: decrypt returned invalid plaintext: %w", decodeError)
+	}
+	return dataKey, nil
+}
+
+func (provider *VaultTransitKeyProvider) call(operationContext context.Context, operation string, requestBody map[string]string) (*vaultTransitResponse, error) {
+	encodedBody, encodeError := json.Marshal(requestBody)
+	if encodeError != nil {
+		return nil, encodeError
+	}
+	endpoint := provider.address + "/v1/" + provider.mountPath + "/" + operation + "/" + url.PathEscape(provider.keyName)
+	request, requestError := http.NewRequestWithContext(operationContext, http.MethodPost, endpoint, bytes.NewReader(encodedBody))
+	if requestError != nil {
+		return nil, requestError
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Vault-Token", provider.token)
+
+	httpResponse, sendError := provider.httpClient.Do(request)
+	if sendError != nil {
+		return nil, fmt.Errorf("vault transit: %s failed: %w", operation, sendError)
+	}
+	defer httpResponse.Body.Close()
+
+	responseBody, readError := io.ReadAll(io.LimitReader(httpResponse.Body, 1<<20))
+	if readError != nil {
+		return nil, fmt.Errorf("vault transit: %s failed: %w", operation, readError)
+	}
+	var response vaultTransitResponse
+	_ = json.Unmarshal(responseBody, &response)
+	if httpResponse.StatusCode != http.StatusOK {
+		// Vault's error messages never contain the plaintext or the token, so they are safe to surface.
+		if len(response.Errors) > 0 {
+			return nil, fmt.Errorf("vault transit: %s returned %d: %s", operation, httpResponse.StatusCode, strings.Join(response.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault transit: %s returned %d", operation, httpResponse.StatusCode)
+	}
+	return &response, nil
+}
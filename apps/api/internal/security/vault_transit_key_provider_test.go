@@ -0,0 +1,159 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// transitStandIn mimics Vault's Transit encrypt/decrypt endpoints for one key, sealing with a key
+// the test never hands to the cipher.
+type transitStandIn struct {
+	token    string
+	keyName  string
+	provider *LocalKeyProvider
+
+	mutex        sync.Mutex
+	decryptCalls int
+}
+
+func newTransitStandIn(t *testing.T, token string, keyName string) *transitStandIn {
+	provider, providerError := NewLocalKeyProvider("transit", randomBase64Key(t))
+	if providerError != nil {
+		t.Fatalf("unexpected error: %v", providerError)
+	}
+	return &transitStandIn{token: token, keyName: keyName, provider: provider}
+}
+
+func (standIn *transitStandIn) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Header.Get("X-Vault-Token") != standIn.token {
+		responseWriter.WriteHeader(http.StatusForbidden)
+		_, _ = responseWriter.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+	var body map[string]string
+	_ = json.NewDecoder(request.Body).Decode(&body)
+
+	switch request.URL.Path {
+	case "/v1/transit/encrypt/" + standIn.keyName:
+		plainBytes, _ := base64.StdEncoding.DecodeString(body["plaintext"])
+		wrapped, _ := standIn.provider.WrapDataKey(request.Context(), plainBytes)
+		writeTransitData(responseWriter, map[string]string{"ciphertext": "vault:v1:" + base64.StdEncoding.EncodeToString(wrapped)})
+	case "/v1/transit/decrypt/" + standIn.keyName:
+		standIn.mutex.Lock()
+		standIn.decryptCalls++
+		standIn.mutex.Unlock()
+		wrapped, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(body["ciphertext"], "vault:v1:"))
+		plainBytes, unwrapError := standIn.provider.UnwrapDataKey(request.Context(), wrapped)
+		if unwrapError != nil {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			_, _ = responseWriter.Write([]byte(`{"errors":["cipher: message authentication failed"]}`))
+			return
+		}
+		writeTransitData(responseWriter, map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plainBytes)})
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeTransitData(responseWriter http.ResponseWriter, data map[string]string) {
+	_ = json.NewEncoder(responseWriter).Encode(map[string]interface{}{"data": data})
+}
+
+func randomBase64Key(t *testing.T) string {
+	keyBytes := make([]byte, 32)
+	if _, randomError := rand.Read(keyBytes); randomError != nil {
+		t.Fatalf("unexpected error: %v", randomError)
+	}
+	return base64.StdEncoding.EncodeToString(keyBytes)
+}
+
+func TestVaultTransitEnvelopeRoundTrip(t *testing.T) {
+	standIn := newTransitStandIn(t, "s.test-token", "coin-hub")
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	vaultProvider := NewVaultTransitKeyProvider("vault", server.URL, "s.test-token", "", "coin-hub")
+	secretCipher, cipherError := NewKeyringSecretCipher([]KeyProvider{vaultProvider}, "vault")
+	if cipherError != nil {
+		t.Fatalf("unexpected error: %v", cipherError)
+	}
+
+	firstPayload, encryptError := secretCipher.EncryptString("binance-secret")
+	if encryptError != nil {
+		t.Fatalf("unexpected error: %v", encryptError)
+	}
+	secondPayload, _ := secretCipher.EncryptString("binance-secret")
+	if !strings.HasPrefix(firstPayload, "vault:") || strings.Contains(firstPayload, "binance-secret") {
+		t.Fatalf("unexpected payload %q", firstPayload)
+	}
+	if strings.SplitN(firstPayload, ".", 2)[0] == strings.SplitN(secondPayload, ".", 2)[0] {
+		t.Fatalf("expected a fresh data key per record")
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		plainText, decryptError := secretCipher.DecryptString(firstPayload)
+		if decryptError != nil || plainText != "binance-secret" {
+			t.Fatalf("unexpected decrypt result %q, %v", plainText, decryptError)
+		}
+	}
+	if standIn.decryptCalls != 1 {
+		t.Fatalf("expected the unwrapped data key to be cached, got %d decrypt calls", standIn.decryptCalls)
+	}
+}
+
+func TestVaultTransitRejectsWrongToken(t *testing.T) {
+	server := httptest.NewServer(newTransitStandIn(t, "s.right", "coin-hub"))
+	defer server.Close()
+
+	vaultProvider := NewVaultTransitKeyProvider("vault", server.URL, "s.wrong", "transit", "coin-hub")
+	secretCipher, _ := NewKeyringSecretCipher([]KeyProvider{vaultProvider}, "vault")
+	_, encryptError := secretCipher.EncryptString("binance-secret")
+	if encryptError == nil || !strings.Contains(encryptError.Error(), "permission denied") {
+		t.Fatalf("expected permission error, got %v", encryptError)
+	}
+	if strings.Contains(encryptError.Error(), "s.wrong") {
+		t.Fatalf("error leaks the token: %v", encryptError)
+	}
+}
+
+func TestKeyringReadsOlderPayloadsAfterMovingToVault(t *testing.T) {
+	server := httptest.NewServer(newTransitStandIn(t, "s.test-token", "coin-hub"))
+	defer server.Close()
+
+	legacyKey := randomBase64Key(t)
+	legacyCipher, _ := NewSecretCipher(legacyKey)
+	legacyPayload, _ := legacyCipher.EncryptString("old-secret")
+
+	localProvider, _ := NewLocalKeyProvider(LegacyKeyIdentifier, legacyKey)
+	vaultProvider := NewVaultTransitKeyProvider("vault", server.URL, "s.test-token", "transit", "coin-hub")
+	rotatedCipher, cipherError := NewKeyringSecretCipher([]KeyProvider{localProvider, vaultProvider}, "vault")
+	if cipherError != nil {
+		t.Fatalf("unexpected error: %v", cipherError)
+	}
+
+	if !rotatedCipher.NeedsReencryption(legacyPayload) {
+		t.Fatalf("expected a payload under key %q to need re-encryption", LegacyKeyIdentifier)
+	}
+	reencryptedPayload, reencryptError := rotatedCipher.ReencryptString(legacyPayload)
+	if reencryptError != nil {
+		t.Fatalf("unexpected error: %v", reencryptError)
+	}
+	if rotatedCipher.KeyIdentifierOf(reencryptedPayload) != "vault" {
+		t.Fatalf("unexpected key id for %q", reencryptedPayload)
+	}
+	if plainText, _ := rotatedCipher.DecryptString(reencryptedPayload); plainText != "old-secret" {
+		t.Fatalf("unexpected plaintext %q", plainText)
+	}
+	if _, decryptError := legacyCipher.DecryptString(reencryptedPayload); decryptError == nil {
+		t.Fatalf("the local key alone must not decrypt a Vault-wrapped payload")
+	}
+	if legacyCipher.EmailFingerprint("a@example.com") != rotatedCipher.EmailFingerprint("A@example.com ") {
+		t.Fatalf("email fingerprints changed across the rotation")
+	}
+}
@@ -3,10 +3,10 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
 	"strings"
 	"time"
 
+	"coin-alert/internal/domain"
 	"coin-alert/internal/email"
 	"coin-alert/internal/repository"
 )
@@ -22,10 +22,11 @@ type AccountEmailService struct {
 	sessionRepository repository.UserSessionRepository
 	passwordService   *PasswordService
 	emailSender       email.Sender
+	auditRecorder     AuditRecorder
 	baseURL           string
 }
 
-func NewAccountEmailService(userRepository repository.UserRepository, tokenRepository repository.AuthTokenRepository, sessionRepository repository.UserSessionRepository, passwordService *PasswordService, emailSender email.Sender, baseURL string) *AccountEmailService {
+func NewAccountEmailService(userRepository repository.UserRepository, tokenRepository repository.AuthTokenRepository, sessionRepository repository.UserSessionRepository, passwordService *PasswordService, emailSender email.Sender, auditRecorder AuditRecorder, baseURL string) *AccountEmailService {
 	if strings.TrimSpace(baseURL) == "" {
 		baseURL = "https://coin.bobagi.space"
 	}
@@ -35,6 +36,7 @@ func NewAccountEmailService(userRepository repository.UserRepository, tokenRepos
 		sessionRepository: sessionRepository,
 		passwordService:   passwordService,
 		emailSender:       emailSender,
+		auditRecorder:     auditRecorder,
 		baseURL:           strings.TrimRight(baseURL, "/"),
 	}
 }
@@ -75,7 +77,7 @@ func (service *AccountEmailService) RequestPasswordReset(operationContext contex
 }
 
 // ConfirmPasswordReset validates the token and sets the new password. It also marks the email
-// verified (clicking the link proves ownership) and revokes all existing sessions.
+// verified (clicking the link proves ownership), revokes all existing sessions and audits the change.
 func (service *AccountEmailService) ConfirmPasswordReset(operationContext context.Context, rawToken string, newPassword string) error {
 	token, lookupError := service.tokenRepository.FindValidByHash(operationContext, hashSessionToken(rawToken), repository.AuthTokenPurposePasswordReset)
 	if lookupError != nil {
@@ -96,6 +98,11 @@ func (service *AccountEmailService) ConfirmPasswordReset(operationContext contex
 	_ = service.userRepository.MarkEmailVerified(operationContext, token.UserIdentifier)
 	// A password reset should sign the account out everywhere.
 	_ = service.sessionRepository.DeleteAllForUser(operationContext, token.UserIdentifier)
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: token.UserIdentifier,
+		Action:         domain.AuditActionPasswordChanged,
+		After:          map[string]interface{}{"has_password": true, "method": "reset_link"},
+	})
 	return nil
 }
 
@@ -128,6 +135,31 @@ func (service *AccountEmailService) ConfirmEmailVerification(operationContext co
 	return nil
 }
 
+// SendLoginLockoutNotice tells the owner of emailAddress that password sign-in is locked for a while
+// after repeated failures. Unknown addresses are ignored silently.
+func (service *AccountEmailService) SendLoginLockoutNotice(operationContext context.Context, emailAddress string, locale string, lockedFor time.Duration) error {
+	foundUser, lookupError := service.userRepository.FindByEmail(operationContext, strings.TrimSpace(emailAddress))
+	if errors.Is(lookupError, repository.ErrUserNotFound) {
+		return nil
+	}
+	if lookupError != nil {
+		return lookupError
+	}
+	service.sendAsync(loginLockoutEmail(locale, service.baseURL+"/", int(lockedFor.Minutes())), foundUser.Email)
+	return nil
+}
+
+// SendNewDeviceNotice tells the user their account was signed in from a device it had not seen before.
+func (service *AccountEmailService) SendNewDeviceNotice(operationContext context.Context, userIdentifier int64, locale string, deviceLabel string, location string, ipAddress string, signedInAt time.Time) error {
+	foundUser, lookupError := service.userRepository.FindByIdentifier(operationContext, userIdentifier)
+	if lookupError != nil {
+		return lookupError
+	}
+	message := newDeviceSignInEmail(locale, service.baseURL+"/", deviceLabel, location, ipAddress, signedInAt.UTC().Format("2006-01-02 15:04 UTC"))
+	service.sendAsync(message, foundUser.Email)
+	return nil
+}
+
 // sendAsync delivers the email in the background so the HTTP request never blocks on SMTP.
 func (service *AccountEmailService) sendAsync(message email.Message, recipient string) {
 	message.To = recipient
@@ -135,7 +167,7 @@ func (service *AccountEmailService) sendAsync(message email.Message, recipient s
 		sendContext, cancel := context.WithTimeout(context.Background(), 25*time.Second)
 		defer cancel()
 		if sendError := service.emailSender.Send(sendContext, message); sendError != nil {
-			log.Printf("email send failed (to=%s subject=%q): %v", message.To, message.Subject, sendError)
+			emailLogger.Error("email send failed", "subject", message.Subject, "error", sendError)
 		}
 	}()
 }
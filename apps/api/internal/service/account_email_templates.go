@@ -63,6 +63,61 @@ func emailVerificationEmail(locale string, link string) email.Message {
 	}
 }
 
+func loginLockoutEmail(locale string, link string, lockedMinutes int) email.Message {
+	switch normalizeEmailLocale(locale) {
+	case "en":
+		paragraph := fmt.Sprintf("There were too many failed sign-in attempts on your Coin Hub account, so signing in with a password is paused for %d minutes.", lockedMinutes)
+		return email.Message{
+			Subject:  "Coin Hub — sign-in temporarily locked",
+			TextBody: paragraph + "\n\nIf this wasn't you, someone may be guessing your password. Consider resetting it:\n\n" + link,
+			HTMLBody: brandedEmailHTML("Sign-in temporarily locked", paragraph, "Reset password", link, "If this was you, just wait and try again. If not, consider resetting your password and enabling two-factor authentication."),
+		}
+	case "es":
+		paragraph := fmt.Sprintf("Hubo demasiados intentos fallidos de inicio de sesión en tu cuenta de Coin Hub, así que el acceso con contraseña queda pausado durante %d minutos.", lockedMinutes)
+		return email.Message{
+			Subject:  "Coin Hub — inicio de sesión bloqueado temporalmente",
+			TextBody: paragraph + "\n\nSi no fuiste tú, alguien podría estar intentando adivinar tu contraseña. Considera restablecerla:\n\n" + link,
+			HTMLBody: brandedEmailHTML("Inicio de sesión bloqueado", paragraph, "Restablecer contraseña", link, "Si fuiste tú, espera e inténtalo de nuevo. Si no, considera restablecer tu contraseña y activar la autenticación en dos pasos."),
+		}
+	default:
+		paragraph := fmt.Sprintf("Houve muitas tentativas de login com falha na sua conta do Coin Hub, então o acesso com senha ficou pausado por %d minutos.", lockedMinutes)
+		return email.Message{
+			Subject:  "Coin Hub — login bloqueado temporariamente",
+			TextBody: paragraph + "\n\nSe não foi você, alguém pode estar tentando adivinhar sua senha. Considere redefini-la:\n\n" + link,
+			HTMLBody: brandedEmailHTML("Login bloqueado temporariamente", paragraph, "Redefinir senha", link, "Se foi você, aguarde e tente novamente. Se não, considere redefinir sua senha e ativar a verificação em duas etapas."),
+		}
+	}
+}
+
+func newDeviceSignInEmail(locale string, link string, deviceLabel string, location string, ipAddress string, signedInAt string) email.Message {
+	if location == "" {
+		location = "?"
+	}
+	switch normalizeEmailLocale(locale) {
+	case "en":
+		paragraph := fmt.Sprintf("Your Coin Hub account was just signed in from a new device: %s, near %s (IP %s) at %s.", deviceLabel, location, ipAddress, signedInAt)
+		return email.Message{
+			Subject:  "Coin Hub — new sign-in from " + deviceLabel,
+			TextBody: paragraph + "\n\nIf this was you, there is nothing to do. If not, sign out the other sessions under Account → Sessions and change your password:\n\n" + link,
+			HTMLBody: brandedEmailHTML("New sign-in to your account", paragraph, "Review sessions", link, "If this wasn't you, sign out the other sessions and change your password right away."),
+		}
+	case "es":
+		paragraph := fmt.Sprintf("Se acaba de iniciar sesión en tu cuenta de Coin Hub desde un dispositivo nuevo: %s, cerca de %s (IP %s) a las %s.", deviceLabel, location, ipAddress, signedInAt)
+		return email.Message{
+			Subject:  "Coin Hub — nuevo inicio de sesión desde " + deviceLabel,
+			TextBody: paragraph + "\n\nSi fuiste tú, no tienes que hacer nada. Si no, cierra las otras sesiones en Cuenta → Sesiones y cambia tu contraseña:\n\n" + link,
+			HTMLBody: brandedEmailHTML("Nuevo inicio de sesión", paragraph, "Revisar sesiones", link, "Si no fuiste tú, cierra las otras sesiones y cambia tu contraseña de inmediato."),
+		}
+	default:
+		paragraph := fmt.Sprintf("Sua conta do Coin Hub acabou de ser acessada de um novo dispositivo: %s, perto de %s (IP %s) às %s.", deviceLabel, location, ipAddress, signedInAt)
+		return email.Message{
+			Subject:  "Coin Hub — novo acesso pelo " + deviceLabel,
+			TextBody: paragraph + "\n\nSe foi você, não precisa fazer nada. Se não, encerre as outras sessões em Conta → Sessões e troque sua senha:\n\n" + link,
+			HTMLBody: brandedEmailHTML("Novo acesso à sua conta", paragraph, "Ver sessões", link, "Se não foi você, encerre as outras sessões e troque sua senha imediatamente."),
+		}
+	}
+}
+
 // brandedEmailHTML renders a simple, inline-styled email matching the warm-dark + gold brand. Links
 // and text are HTML-escaped.
 func brandedEmailHTML(heading string, paragraph string, buttonLabel string, link string, footer string) string {
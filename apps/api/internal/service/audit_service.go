@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// AuditRecord describes one audited action. Before/After are snapshots marshalled to JSON; pass nil
+// when there is nothing to show. They must never contain secrets.
+type AuditRecord struct {
+	UserIdentifier   int64
+	Action           string
+	TargetType       string
+	TargetIdentifier int64
+	Before           interface{}
+	After            interface{}
+}
+
+// AuditRecorder appends to the audit log. Recording never fails the audited action.
+type AuditRecorder interface {
+	Record(operationContext context.Context, record AuditRecord)
+}
+
+type requestMetadataContextKey struct{}
+
+// RequestMetadata is the client information attached to audit entries.
+type RequestMetadata struct {
+	ActorUserIdentifier int64
+	IPAddress           string
+	UserAgent           string
+}
+
+// WithRequestMetadata attaches the caller's identity and client to the context, so services can
+// audit actions without knowing about HTTP. Without it an entry is attributed to the system.
+func WithRequestMetadata(parentContext context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(parentContext, requestMetadataContextKey{}, metadata)
+}
+
+func requestMetadataFrom(operationContext context.Context) (RequestMetadata, bool) {
+	metadata, present := operationContext.Value(requestMetadataContextKey{}).(RequestMetadata)
+	return metadata, present
+}
+
+// AuditService writes and reads the append-only audit log.
+type AuditService struct {
+	repository repository.AuditLogRepository
+}
+
+func NewAuditService(repositoryInstance repository.AuditLogRepository) *AuditService {
+	return &AuditService{repository: repositoryInstance}
+}
+
+func (service *AuditService) Record(operationContext context.Context, record AuditRecord) {
+	entry := domain.AuditEntry{
+		UserIdentifier: record.UserIdentifier,
+		Action:         record.Action,
+		TargetType:     record.TargetType,
+		BeforeValue:    auditJSON(record.Before),
+		AfterValue:     auditJSON(record.After),
+	}
+	if record.TargetIdentifier > 0 {
+		entry.TargetIdentifier = strconv.FormatInt(record.TargetIdentifier, 10)
+	}
+	if metadata, present := requestMetadataFrom(operationContext); present {
+		entry.IPAddress = metadata.IPAddress
+		entry.UserAgent = truncateRunes(metadata.UserAgent, 512)
+		actorUserIdentifier := metadata.ActorUserIdentifier
+		if actorUserIdentifier == 0 {
+			actorUserIdentifier = record.UserIdentifier
+		}
+		entry.ActorUserIdentifier = &actorUserIdentifier
+	}
+	// Detached from the request's cancellation so an entry is not lost when the client disconnects
+	// right after the action succeeded.
+	if appendError := service.repository.AppendEntry(context.WithoutCancel(operationContext), entry); appendError != nil {
+		auditLogger.ErrorContext(operationContext, "could not record the audit entry", "action", record.Action, "user_id", record.UserIdentifier, "error", appendError)
+	}
+}
+
+// ListEntriesForUser pages through one user's audit log, newest first.
+func (service *AuditService) ListEntriesForUser(loadContext context.Context, userIdentifier int64, beforeIdentifier int64, limit int) ([]domain.AuditEntry, error) {
+	return service.repository.ListEntries(loadContext, userIdentifier, beforeIdentifier, normalizeAuditLimit(limit))
+}
+
+// ListEntries pages through the whole audit log (admin view); userIdentifier 0 means every user.
+func (service *AuditService) ListEntries(loadContext context.Context, userIdentifier int64, beforeIdentifier int64, limit int) ([]domain.AuditEntry, error) {
+	return service.repository.ListEntries(loadContext, userIdentifier, beforeIdentifier, normalizeAuditLimit(limit))
+}
+
+func normalizeAuditLimit(limit int) int {
+	if limit <= 0 || limit > 200 {
+		return 50
+	}
+	return limit
+}
+
+func auditJSON(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+	encoded, encodeError := json.Marshal(value)
+	if encodeError != nil {
+		return nil
+	}
+	document := string(encoded)
+	return &document
+}
+
+// recordAudit is a nil-safe helper for services whose audit recorder is optional.
+func recordAudit(operationContext context.Context, recorder AuditRecorder, record AuditRecord) {
+	if recorder == nil {
+		return
+	}
+	recorder.Record(operationContext, record)
+}
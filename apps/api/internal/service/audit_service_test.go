@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// memoryAuditLog is an in-memory repository.AuditLogRepository. Like the audit_log table, which a
+// trigger keeps append-only, it only ever adds rows.
+type memoryAuditLog struct {
+	mutex       sync.Mutex
+	entries     []domain.AuditEntry
+	appendError error
+	// appendContextErrors records ctx.Err() of every append, to show entries survive a cancelled request.
+	appendContextErrors []error
+}
+
+func (auditLog *memoryAuditLog) AppendEntry(operationContext context.Context, entry domain.AuditEntry) error {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+	auditLog.appendContextErrors = append(auditLog.appendContextErrors, operationContext.Err())
+	if auditLog.appendError != nil {
+		return auditLog.appendError
+	}
+	entry.Identifier = int64(len(auditLog.entries) + 1)
+	auditLog.entries = append(auditLog.entries, entry)
+	return nil
+}
+
+func (auditLog *memoryAuditLog) ListEntries(_ context.Context, userIdentifier int64, beforeIdentifier int64, limit int) ([]domain.AuditEntry, error) {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+	var entries []domain.AuditEntry
+	for index := len(auditLog.entries) - 1; index >= 0 && len(entries) < limit; index-- {
+		entry := auditLog.entries[index]
+		if (userIdentifier == 0 || entry.UserIdentifier == userIdentifier) && (beforeIdentifier == 0 || entry.Identifier < beforeIdentifier) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// actions returns the recorded actions in the order they were appended.
+func (auditLog *memoryAuditLog) actions() []string {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+	actions := make([]string, 0, len(auditLog.entries))
+	for _, entry := range auditLog.entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+func TestAuditRecordBuildsTheEntry(t *testing.T) {
+	requestContext := WithRequestMetadata(context.Background(), RequestMetadata{IPAddress: "203.0.113.9", UserAgent: strings.Repeat("é", 600)})
+	adminContext := WithRequestMetadata(context.Background(), RequestMetadata{ActorUserIdentifier: 1, IPAddress: "198.51.100.4", UserAgent: "curl/8"})
+	cases := []struct {
+		name           string
+		context        context.Context
+		record         AuditRecord
+		expectedActor  int64 // 0 = attributed to the system
+		expectedTarget string
+		expectedBefore string
+		expectedAfter  string
+		expectedIP     string
+	}{
+		{
+			name:           "own action",
+			context:        requestContext,
+			record:         AuditRecord{UserIdentifier: 7, Action: domain.AuditActionRobotUpdated, TargetType: "robot", TargetIdentifier: 12, Before: map[string]bool{"enabled": true}, After: map[string]bool{"enabled": false}},
+			expectedActor:  7,
+			expectedTarget: "12",
+			expectedBefore: `{"enabled":true}`,
+			expectedAfter:  `{"enabled":false}`,
+			expectedIP:     "203.0.113.9",
+		},
+		{
+			name:          "admin acting on another user",
+			context:       adminContext,
+			record:        AuditRecord{UserIdentifier: 7, Action: domain.AuditActionTwoFactorReset, TargetType: "user"},
+			expectedActor: 1,
+			expectedIP:    "198.51.100.4",
+		},
+		{
+			name:    "background job",
+			context: context.Background(),
+			record:  AuditRecord{UserIdentifier: 7, Action: domain.AuditActionTakeProfitPlaced, TargetType: "operation", TargetIdentifier: 3},
+			// No request metadata: no actor, IP or user agent.
+			expectedTarget: "3",
+		},
+	}
+	for _, testCase := range cases {
+		auditLog := &memoryAuditLog{}
+		NewAuditService(auditLog).Record(testCase.context, testCase.record)
+		if len(auditLog.entries) != 1 {
+			t.Fatalf("%s: expected one entry, got %d", testCase.name, len(auditLog.entries))
+		}
+		entry := auditLog.entries[0]
+		if entry.UserIdentifier != testCase.record.UserIdentifier || entry.Action != testCase.record.Action || entry.TargetType != testCase.record.TargetType {
+			t.Errorf("%s: unexpected entry %+v", testCase.name, entry)
+		}
+		switch {
+		case testCase.expectedActor == 0 && entry.ActorUserIdentifier != nil:
+			t.Errorf("%s: expected no actor, got %d", testCase.name, *entry.ActorUserIdentifier)
+		case testCase.expectedActor != 0 && (entry.ActorUserIdentifier == nil || *entry.ActorUserIdentifier != testCase.expectedActor):
+			t.Errorf("%s: expected actor %d, got %v", testCase.name, testCase.expectedActor, entry.ActorUserIdentifier)
+		}
+		if entry.TargetIdentifier != testCase.expectedTarget || entry.IPAddress != testCase.expectedIP {
+			t.Errorf("%s: got target %q and IP %q", testCase.name, entry.TargetIdentifier, entry.IPAddress)
+		}
+		if got := derefAuditJSON(entry.BeforeValue); got != testCase.expectedBefore {
+			t.Errorf("%s: before %q, expected %q", testCase.name, got, testCase.expectedBefore)
+		}
+		if got := derefAuditJSON(entry.AfterValue); got != testCase.expectedAfter {
+			t.Errorf("%s: after %q, expected %q", testCase.name, got, testCase.expectedAfter)
+		}
+		if length := len([]rune(entry.UserAgent)); length > 512 {
+			t.Errorf("%s: user agent of %d characters was not truncated", testCase.name, length)
+		}
+	}
+}
+
+func derefAuditJSON(document *string) string {
+	if document == nil {
+		return ""
+	}
+	return *document
+}
+
+// TestAuditRecordOnlyAppends checks that later actions add entries without touching earlier ones, that
+// an entry is kept when the request is cancelled right after the action, and that a failing log never
+// fails the audited action.
+func TestAuditRecordOnlyAppends(t *testing.T) {
+	auditLog := &memoryAuditLog{}
+	auditService := NewAuditService(auditLog)
+	operationContext := WithRequestMetadata(context.Background(), RequestMetadata{IPAddress: "203.0.113.9"})
+
+	auditService.Record(operationContext, AuditRecord{UserIdentifier: 7, Action: domain.AuditActionLogin, TargetType: "user", TargetIdentifier: 7})
+	firstEntry := auditLog.entries[0]
+
+	cancelledContext, cancel := context.WithCancel(operationContext)
+	cancel()
+	auditService.Record(cancelledContext, AuditRecord{UserIdentifier: 7, Action: domain.AuditActionPasswordChanged, TargetType: "user", TargetIdentifier: 7})
+	recordAudit(operationContext, auditService, AuditRecord{UserIdentifier: 8, Action: domain.AuditActionRobotCreated, TargetType: "robot", TargetIdentifier: 4})
+	recordAudit(operationContext, nil, AuditRecord{UserIdentifier: 7, Action: domain.AuditActionRobotDeleted})
+
+	expectedActions := []string{domain.AuditActionLogin, domain.AuditActionPasswordChanged, domain.AuditActionRobotCreated}
+	if actions := auditLog.actions(); strings.Join(actions, ",") != strings.Join(expectedActions, ",") {
+		t.Fatalf("expected actions %v, got %v", expectedActions, actions)
+	}
+	if auditLog.entries[0] != firstEntry {
+		t.Errorf("the first entry changed from %+v to %+v", firstEntry, auditLog.entries[0])
+	}
+	if auditLog.appendContextErrors[1] != nil {
+		t.Errorf("the entry of a cancelled request was appended with a cancelled context: %v", auditLog.appendContextErrors[1])
+	}
+
+	ownEntries, listError := auditService.ListEntriesForUser(context.Background(), 7, 0, 0)
+	if listError != nil || len(ownEntries) != 2 || ownEntries[0].Action != domain.AuditActionPasswordChanged {
+		t.Errorf("expected user 7's two entries newest first, got %+v, %v", ownEntries, listError)
+	}
+	olderEntries, _ := auditService.ListEntriesForUser(context.Background(), 7, ownEntries[0].Identifier, 50)
+	if len(olderEntries) != 1 || olderEntries[0].Identifier != firstEntry.Identifier {
+		t.Errorf("expected paging below entry %d to return the first entry, got %+v", ownEntries[0].Identifier, olderEntries)
+	}
+
+	auditLog.appendError = errors.New("database is read-only")
+	auditService.Record(operationContext, AuditRecord{UserIdentifier: 7, Action: domain.AuditActionLogin})
+	if len(auditLog.entries) != 3 {
+		t.Errorf("a failed append must not change the log, got %d entries", len(auditLog.entries))
+	}
+}
+
+// resetTokenRepository holds one password-reset token; other methods are not used.
+type resetTokenRepository struct {
+	repository.AuthTokenRepository
+	token     repository.AuthToken
+	tokenHash string
+}
+
+func (tokenRepository resetTokenRepository) FindValidByHash(_ context.Context, tokenHash string, purpose string) (*repository.AuthToken, error) {
+	if tokenHash != tokenRepository.tokenHash || purpose != tokenRepository.token.Purpose {
+		return nil, repository.ErrAuthTokenInvalid
+	}
+	token := tokenRepository.token
+	return &token, nil
+}
+
+func (resetTokenRepository) MarkUsed(context.Context, int64) error { return nil }
+
+// passwordUserRepository stores the password hash set for its one user.
+type passwordUserRepository struct {
+	accountUserRepository
+}
+
+func (userRepository passwordUserRepository) UpdatePasswordHash(_ context.Context, _ int64, passwordHash string) error {
+	userRepository.user.PasswordHash = passwordHash
+	return nil
+}
+
+func (passwordUserRepository) MarkEmailVerified(context.Context, int64) error { return nil }
+
+func TestPasswordResetIsAudited(t *testing.T) {
+	user := &domain.User{Identifier: 7, Email: "ana@example.com"}
+	tokenRepository := resetTokenRepository{token: repository.AuthToken{Identifier: 3, UserIdentifier: 7, Purpose: repository.AuthTokenPurposePasswordReset}, tokenHash: hashSessionToken("reset-token")}
+	auditLog := &memoryAuditLog{}
+	accountEmailService := NewAccountEmailService(passwordUserRepository{accountUserRepository{user: user}}, tokenRepository, &memorySessionRepository{}, NewPasswordService(), nil, NewAuditService(auditLog), "https://coin.example.com")
+	requestContext := WithRequestMetadata(context.Background(), RequestMetadata{IPAddress: "203.0.113.9", UserAgent: desktopUserAgent})
+
+	if resetError := accountEmailService.ConfirmPasswordReset(requestContext, "wrong-token", "a long new password"); !errors.Is(resetError, repository.ErrAuthTokenInvalid) {
+		t.Fatalf("expected an invalid token error, got %v", resetError)
+	}
+	if resetError := accountEmailService.ConfirmPasswordReset(requestContext, "reset-token", "short"); !errors.Is(resetError, ErrWeakPassword) {
+		t.Fatalf("expected a weak password error, got %v", resetError)
+	}
+	if len(auditLog.entries) != 0 {
+		t.Fatalf("a refused reset was audited: %v", auditLog.actions())
+	}
+	if resetError := accountEmailService.ConfirmPasswordReset(requestContext, "reset-token", "a long new password"); resetError != nil {
+		t.Fatal(resetError)
+	}
+	if len(auditLog.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %v", auditLog.actions())
+	}
+	entry := auditLog.entries[0]
+	if entry.Action != domain.AuditActionPasswordChanged || entry.UserIdentifier != 7 || entry.IPAddress != "203.0.113.9" || entry.ActorUserIdentifier == nil || *entry.ActorUserIdentifier != 7 {
+		t.Errorf("unexpected audit entry %+v", entry)
+	}
+}
+
+// TestPasswordCheckAloneIsNotALogin checks that Authenticate leaves the login to be recorded when the
+// session is issued, after any second factor.
+func TestPasswordCheckAloneIsNotALogin(t *testing.T) {
+	passwordService := NewPasswordService()
+	passwordHash, hashError := passwordService.HashPassword("correct horse battery")
+	if hashError != nil {
+		t.Fatal(hashError)
+	}
+	user := &domain.User{Identifier: 7, Email: "ana@example.com", PasswordHash: passwordHash, IsActive: true}
+	auditLog := &memoryAuditLog{}
+	authService := NewAuthService(accountUserRepository{user: user}, nil, nil, passwordService, nil, NewAuditService(auditLog))
+
+	if _, authenticationError := authService.Authenticate(context.Background(), "ana@example.com", "correct horse battery"); authenticationError != nil {
+		t.Fatal(authenticationError)
+	}
+	if len(auditLog.entries) != 0 {
+		t.Fatalf("the password step was recorded as a login: %v", auditLog.actions())
+	}
+	authService.RecordLogin(context.Background(), user.Identifier, "two_factor")
+	if actions := auditLog.actions(); len(actions) != 1 || actions[0] != domain.AuditActionLogin {
+		t.Errorf("expected one login entry, got %v", actions)
+	}
+}
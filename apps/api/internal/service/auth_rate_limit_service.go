@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin-alert/internal/repository"
+)
+
+// Throttled auth actions.
+const (
+	AuthActionLogin          = "login"
+	AuthActionSignup         = "signup"
+	AuthActionPasswordForgot = "password_forgot"
+	AuthActionEmailResend    = "email_resend"
+)
+
+// ErrRateLimited is matched (errors.Is) by every RateLimitedError.
+var ErrRateLimited = errors.New("too many attempts, try again later")
+
+// RateLimitedError is returned when a request must wait; RetryAfter is how long.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+	// AccountLocked is set when the email is locked after repeated failed sign-ins, rather than the
+	// client simply going too fast.
+	AccountLocked bool
+	// IdentityCheck is set when the lock is on the account's password and 2FA code checks rather than
+	// on password sign-in.
+	IdentityCheck bool
+}
+
+func (rateLimitedError *RateLimitedError) Error() string {
+	switch {
+	case rateLimitedError.AccountLocked && rateLimitedError.IdentityCheck:
+		return "too many incorrect passwords or codes; confirming it is you is paused for a few minutes"
+	case rateLimitedError.AccountLocked:
+		return "too many failed sign-in attempts; password sign-in for this account is paused for a few minutes"
+	}
+	return ErrRateLimited.Error()
+}
+
+func (rateLimitedError *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// rateLimitRule allows limit hits per fixed window; a zero limit disables the rule.
+type rateLimitRule struct {
+	limit  int
+	window time.Duration
+}
+
+// authRateLimitPolicy is the set of counters one action goes through: the whole platform (protects the
+// mail provider and the password hasher), the client IP, and the target email or account.
+type authRateLimitPolicy struct {
+	global   rateLimitRule
+	perIP    rateLimitRule
+	perEmail rateLimitRule
+}
+
+var authRateLimitPolicies = map[string]authRateLimitPolicy{
+	// Login has no per-email counter: wrong passwords are handled by the progressive delay and
+	// lockout below, so a user typing their own password correctly is never throttled by email.
+	AuthActionLogin: {
+		global: rateLimitRule{limit: 600, window: time.Minute},
+		perIP:  rateLimitRule{limit: 50, window: 15 * time.Minute},
+	},
+	AuthActionSignup: {
+		global:   rateLimitRule{limit: 60, window: time.Minute},
+		perIP:    rateLimitRule{limit: 5, window: time.Hour},
+		perEmail: rateLimitRule{limit: 3, window: time.Hour},
+	},
+	AuthActionPasswordForgot: {
+		global:   rateLimitRule{limit: 60, window: time.Minute},
+		perIP:    rateLimitRule{limit: 10, window: time.Hour},
+		perEmail: rateLimitRule{limit: 3, window: time.Hour},
+	},
+	AuthActionEmailResend: {
+		global:   rateLimitRule{limit: 60, window: time.Minute},
+		perIP:    rateLimitRule{limit: 10, window: time.Hour},
+		perEmail: rateLimitRule{limit: 3, window: time.Hour},
+	},
+}
+
+const (
+	// loginFailureWindow is how long failed sign-ins of one email are remembered. Failed identity
+	// checks of one account follow the same window, delays and lockout.
+	loginFailureWindow = 15 * time.Minute
+	// loginDelayThreshold is the failure count after which each further attempt must wait, doubling
+	// from one second up to loginMaximumDelay.
+	loginDelayThreshold = 3
+	loginMaximumDelay   = time.Minute
+	// loginLockoutThreshold failures within the window lock password sign-in for loginLockoutDuration
+	// and email the account owner.
+	loginLockoutThreshold = 10
+	loginLockoutDuration  = 15 * time.Minute
+)
+
+// AuthRateLimitService throttles the public auth endpoints by client IP, by email and globally, and
+// slows down then temporarily locks password sign-in for an email after repeated failures. Identity
+// checks made by a signed-in account (2FA codes, step-up passwords) get the same delays and lockout,
+// counted per account. When the store is unreachable it lets requests through (and logs), so an
+// outage never locks everyone out.
+type AuthRateLimitService struct {
+	store               repository.RateLimitRepository
+	accountEmailService *AccountEmailService
+}
+
+func NewAuthRateLimitService(store repository.RateLimitRepository, accountEmailService *AccountEmailService) *AuthRateLimitService {
+	return &AuthRateLimitService{store: store, accountEmailService: accountEmailService}
+}
+
+// Allow counts one attempt of action and returns a *RateLimitedError when any of its limits is
+// exceeded. emailAddress may be an account identifier for actions made by a signed-in user.
+func (service *AuthRateLimitService) Allow(operationContext context.Context, action string, ipAddress string, emailAddress string) error {
+	policy, known := authRateLimitPolicies[action]
+	if !known {
+		return nil
+	}
+	normalizedEmail := normalizeRateLimitEmail(emailAddress)
+	if action == AuthActionLogin && normalizedEmail != "" {
+		if lockError := service.checkLoginBlock(operationContext, normalizedEmail); lockError != nil {
+			return lockError
+		}
+	}
+	counters := []struct {
+		rule    rateLimitRule
+		scope   string
+		subject string
+	}{
+		{policy.global, "global", "all"},
+		{policy.perIP, "ip", ipAddress},
+		{policy.perEmail, "email", normalizedEmail},
+	}
+	for _, counter := range counters {
+		if counter.rule.limit == 0 || counter.subject == "" {
+			continue
+		}
+		counterKey := action + ":" + counter.scope + ":" + counter.subject
+		hitCount, windowEndsAt, incrementError := service.store.IncrementRateLimitCounter(operationContext, counterKey, counter.rule.window)
+		if incrementError != nil {
+			authLogger.ErrorContext(operationContext, "rate limit check failed, allowing the request", "action", action, "scope", counter.scope, "error", incrementError)
+			continue
+		}
+		if hitCount > counter.rule.limit {
+			return &RateLimitedError{RetryAfter: time.Until(windowEndsAt)}
+		}
+	}
+	return nil
+}
+
+// RecordLoginFailure counts a failed password sign-in for the email. From the loginDelayThreshold-th
+// failure on, the next attempt has to wait a doubling delay; at loginLockoutThreshold the email is
+// locked and its owner notified once.
+func (service *AuthRateLimitService) RecordLoginFailure(operationContext context.Context, emailAddress string, locale string) {
+	normalizedEmail := normalizeRateLimitEmail(emailAddress)
+	if normalizedEmail == "" {
+		return
+	}
+	failureCount := service.recordFailure(operationContext, loginFailureKey(normalizedEmail))
+	if failureCount == loginLockoutThreshold && service.accountEmailService != nil {
+		if noticeError := service.accountEmailService.SendLoginLockoutNotice(operationContext, normalizedEmail, locale, loginLockoutDuration); noticeError != nil {
+			authLogger.ErrorContext(operationContext, "could not send the sign-in lockout notice", "error", noticeError)
+		}
+	}
+}
+
+// RecordLoginSuccess clears the email's failure count and any delay.
+func (service *AuthRateLimitService) RecordLoginSuccess(operationContext context.Context, emailAddress string) {
+	normalizedEmail := normalizeRateLimitEmail(emailAddress)
+	if normalizedEmail == "" {
+		return
+	}
+	if resetError := service.store.ResetRateLimitKey(operationContext, loginFailureKey(normalizedEmail)); resetError != nil {
+		authLogger.ErrorContext(operationContext, "could not reset the failed sign-in count", "error", resetError)
+	}
+}
+
+// CheckIdentityCheckBlock returns a *RateLimitedError while the account has to wait after failed
+// 2FA codes or step-up passwords. Call it before checking the secret, so a locked account learns
+// nothing from further guesses.
+func (service *AuthRateLimitService) CheckIdentityCheckBlock(operationContext context.Context, userIdentifier int64) error {
+	if service == nil {
+		return nil
+	}
+	blockError := service.checkBlock(operationContext, identityCheckFailureKey(userIdentifier))
+	var rateLimitedError *RateLimitedError
+	if errors.As(blockError, &rateLimitedError) {
+		rateLimitedError.IdentityCheck = true
+	}
+	return blockError
+}
+
+// RecordIdentityCheckFailure counts a wrong 2FA code or step-up password for the account, with the
+// same doubling delay and lockout as password sign-in.
+func (service *AuthRateLimitService) RecordIdentityCheckFailure(operationContext context.Context, userIdentifier int64) {
+	if service == nil {
+		return
+	}
+	if failureCount := service.recordFailure(operationContext, identityCheckFailureKey(userIdentifier)); failureCount == loginLockoutThreshold {
+		authLogger.WarnContext(operationContext, "identity checks locked after repeated failures", "user_id", userIdentifier)
+	}
+}
+
+// RecordIdentityCheckSuccess clears the account's failure count and any delay.
+func (service *AuthRateLimitService) RecordIdentityCheckSuccess(operationContext context.Context, userIdentifier int64) {
+	if service == nil {
+		return
+	}
+	if resetError := service.store.ResetRateLimitKey(operationContext, identityCheckFailureKey(userIdentifier)); resetError != nil {
+		authLogger.ErrorContext(operationContext, "could not reset the failed identity check count", "error", resetError)
+	}
+}
+
+// StartCleanup periodically drops expired counters. It returns immediately; the loop stops when the
+// supplied context is cancelled.
+func (service *AuthRateLimitService) StartCleanup(loopContext context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				cleanupContext, cancel := context.WithTimeout(loopContext, 30*time.Second)
+				if _, deletionError := service.store.DeleteExpiredRateLimits(cleanupContext); deletionError != nil {
+					authLogger.Error("rate limit cleanup failed", "error", deletionError)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+func (service *AuthRateLimitService) checkLoginBlock(operationContext context.Context, normalizedEmail string) error {
+	return service.checkBlock(operationContext, loginFailureKey(normalizedEmail))
+}
+
+func (service *AuthRateLimitService) checkBlock(operationContext context.Context, failureKey string) error {
+	blockedUntil, loadError := service.store.LoadRateLimitBlock(operationContext, failureKey)
+	if loadError != nil {
+		authLogger.ErrorContext(operationContext, "could not check the failure delay, allowing the request", "key", failureKey, "error", loadError)
+		return nil
+	}
+	if blockedUntil.IsZero() {
+		return nil
+	}
+	// A block longer than the maximum progressive delay can only be a lockout.
+	retryAfter := time.Until(blockedUntil)
+	return &RateLimitedError{RetryAfter: retryAfter, AccountLocked: retryAfter > loginMaximumDelay}
+}
+
+// recordFailure counts a failure under failureKey and blocks the key for the progressive delay or the
+// lockout. It returns the failure count, or 0 when the store could not record it.
+func (service *AuthRateLimitService) recordFailure(operationContext context.Context, failureKey string) int {
+	failureCount, _, incrementError := service.store.IncrementRateLimitCounter(operationContext, failureKey, loginFailureWindow)
+	if incrementError != nil {
+		authLogger.ErrorContext(operationContext, "could not record a failed attempt", "key", failureKey, "error", incrementError)
+		return 0
+	}
+	var blockFor time.Duration
+	switch {
+	case failureCount >= loginLockoutThreshold:
+		blockFor = loginLockoutDuration
+	case failureCount >= loginDelayThreshold:
+		blockFor = time.Second << (failureCount - loginDelayThreshold)
+		if blockFor > loginMaximumDelay {
+			blockFor = loginMaximumDelay
+		}
+	default:
+		return failureCount
+	}
+	if blockError := service.store.BlockRateLimitKey(operationContext, failureKey, time.Now().Add(blockFor)); blockError != nil {
+		authLogger.ErrorContext(operationContext, "could not apply the failure delay", "failures", failureCount, "error", blockError)
+		return 0
+	}
+	return failureCount
+}
+
+func identityCheckFailureKey(userIdentifier int64) string {
+	return "identity_check_failure:" + strconv.FormatInt(userIdentifier, 10)
+}
+
+func loginFailureKey(normalizedEmail string) string {
+	return AuthActionLogin + "_failure:" + normalizedEmail
+}
+
+func normalizeRateLimitEmail(emailAddress string) string {
+	return strings.ToLower(strings.TrimSpace(emailAddress))
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/email"
+	"coin-alert/internal/repository"
+)
+
+// recordingSender collects the emails AccountEmailService sends in the background.
+type recordingSender struct {
+	messages chan email.Message
+}
+
+func newRecordingSender() *recordingSender {
+	return &recordingSender{messages: make(chan email.Message, 10)}
+}
+
+func (sender *recordingSender) Send(_ context.Context, message email.Message) error {
+	sender.messages <- message
+	return nil
+}
+
+func (sender *recordingSender) Enabled() bool { return true }
+
+// nextMessage waits for the next email, or returns false when none is sent within wait.
+func (sender *recordingSender) nextMessage(wait time.Duration) (email.Message, bool) {
+	select {
+	case message := <-sender.messages:
+		return message, true
+	case <-time.After(wait):
+		return email.Message{}, false
+	}
+}
+
+// accountUserRepository serves one user to the account emails; other methods are not used.
+type accountUserRepository struct {
+	repository.UserRepository
+	user *domain.User
+}
+
+func (userRepository accountUserRepository) FindByEmail(_ context.Context, emailAddress string) (*domain.User, error) {
+	if !strings.EqualFold(emailAddress, userRepository.user.Email) {
+		return nil, repository.ErrUserNotFound
+	}
+	return userRepository.user, nil
+}
+
+func (userRepository accountUserRepository) FindByIdentifier(context.Context, int64) (*domain.User, error) {
+	return userRepository.user, nil
+}
+
+func newTestAccountEmailService(user *domain.User, sender email.Sender) *AccountEmailService {
+	return NewAccountEmailService(accountUserRepository{user: user}, nil, nil, &PasswordService{}, sender, nil, "https://coin.example.com")
+}
+
+// blockRecordingStore remembers how long the last block asked for lasted.
+type blockRecordingStore struct {
+	*repository.MemoryRateLimitRepository
+	lastBlock time.Duration
+}
+
+func (store *blockRecordingStore) BlockRateLimitKey(operationContext context.Context, key string, blockedUntil time.Time) error {
+	store.lastBlock = time.Until(blockedUntil).Round(time.Second)
+	return store.MemoryRateLimitRepository.BlockRateLimitKey(operationContext, key, blockedUntil)
+}
+
+// failingRateLimitStore stands in for an unreachable database.
+type failingRateLimitStore struct {
+	repository.RateLimitRepository
+}
+
+func (failingRateLimitStore) IncrementRateLimitCounter(context.Context, string, time.Duration) (int, time.Time, error) {
+	return 0, time.Time{}, errors.New("connection refused")
+}
+
+func (failingRateLimitStore) LoadRateLimitBlock(context.Context, string) (time.Time, error) {
+	return time.Time{}, errors.New("connection refused")
+}
+
+func TestLoginFailureDelays(t *testing.T) {
+	cases := []struct {
+		failure  int
+		expected time.Duration // 0 = no delay
+	}{
+		{failure: 1},
+		{failure: 2},
+		{failure: loginDelayThreshold, expected: time.Second},
+		{failure: 4, expected: 2 * time.Second},
+		{failure: 5, expected: 4 * time.Second},
+		{failure: 8, expected: 32 * time.Second},
+		{failure: 9, expected: loginMaximumDelay},
+		{failure: 10, expected: loginLockoutDuration},
+		{failure: 11, expected: loginLockoutDuration},
+	}
+	store := &blockRecordingStore{MemoryRateLimitRepository: repository.NewMemoryRateLimitRepository()}
+	rateLimitService := NewAuthRateLimitService(store, nil)
+	failures := 0
+	for _, testCase := range cases {
+		for failures < testCase.failure {
+			store.lastBlock = 0
+			rateLimitService.RecordLoginFailure(context.Background(), "ana@example.com", "en")
+			failures++
+		}
+		if store.lastBlock != testCase.expected {
+			t.Errorf("failure %d: blocked for %s, expected %s", testCase.failure, store.lastBlock, testCase.expected)
+		}
+	}
+}
+
+// TestLoginLocksAfterTenFailures drops the short delays so each attempt is checked right away, and
+// expects the lockout exactly at the tenth failure, for that email only, with one notice to its owner.
+func TestLoginLocksAfterTenFailures(t *testing.T) {
+	sender := newRecordingSender()
+	owner := &domain.User{Identifier: 7, Email: "ana@example.com"}
+	rateLimitService := NewAuthRateLimitService(lockoutOnlyRateLimitStore{repository.NewMemoryRateLimitRepository()}, newTestAccountEmailService(owner, sender))
+	operationContext := context.Background()
+
+	// Nine failures only ever delay; the tenth locks.
+	for failure := 1; failure <= 10; failure++ {
+		// Address casing and spacing must not give an attacker a fresh counter.
+		attemptedEmail := []string{"ana@example.com", " ANA@example.com", "Ana@Example.COM "}[failure%3]
+		if allowError := rateLimitService.Allow(operationContext, AuthActionLogin, "203.0.113.9", attemptedEmail); allowError != nil {
+			t.Fatalf("attempt %d was refused before the lockout: %v", failure, allowError)
+		}
+		rateLimitService.RecordLoginFailure(operationContext, attemptedEmail, "en")
+	}
+
+	allowError := rateLimitService.Allow(operationContext, AuthActionLogin, "198.51.100.4", "ana@example.com")
+	var rateLimitedError *RateLimitedError
+	if !errors.As(allowError, &rateLimitedError) || !rateLimitedError.AccountLocked || rateLimitedError.IdentityCheck {
+		t.Fatalf("expected the account to be locked from any IP, got %v", allowError)
+	}
+	if rateLimitedError.RetryAfter <= loginLockoutDuration-time.Minute || rateLimitedError.RetryAfter > loginLockoutDuration {
+		t.Errorf("expected to retry in about %s, got %s", loginLockoutDuration, rateLimitedError.RetryAfter)
+	}
+	if !errors.Is(allowError, ErrRateLimited) {
+		t.Errorf("a lockout must match ErrRateLimited")
+	}
+	if otherError := rateLimitService.Allow(operationContext, AuthActionLogin, "203.0.113.9", "bruno@example.com"); otherError != nil {
+		t.Errorf("another account was throttled by the lockout: %v", otherError)
+	}
+
+	notice, sent := sender.nextMessage(time.Second)
+	if !sent || notice.To != owner.Email {
+		t.Fatalf("expected a lockout notice to %s, got %+v", owner.Email, notice)
+	}
+	rateLimitService.RecordLoginFailure(operationContext, "ana@example.com", "en")
+	if extra, sentAgain := sender.nextMessage(100 * time.Millisecond); sentAgain {
+		t.Errorf("expected a single lockout notice, got another: %q", extra.Subject)
+	}
+}
+
+func TestProgressiveDelayBlocksUntilASuccess(t *testing.T) {
+	rateLimitService := NewAuthRateLimitService(repository.NewMemoryRateLimitRepository(), nil)
+	operationContext := context.Background()
+	for failure := 0; failure < loginDelayThreshold; failure++ {
+		rateLimitService.RecordLoginFailure(operationContext, "ana@example.com", "en")
+	}
+
+	allowError := rateLimitService.Allow(operationContext, AuthActionLogin, "203.0.113.9", "ana@example.com")
+	var rateLimitedError *RateLimitedError
+	if !errors.As(allowError, &rateLimitedError) || rateLimitedError.AccountLocked || rateLimitedError.RetryAfter > time.Second {
+		t.Fatalf("expected a delay of at most a second, got %v", allowError)
+	}
+
+	rateLimitService.RecordLoginSuccess(operationContext, "ANA@example.com")
+	if allowError := rateLimitService.Allow(operationContext, AuthActionLogin, "203.0.113.9", "ana@example.com"); allowError != nil {
+		t.Errorf("a successful sign-in should clear the delay, got %v", allowError)
+	}
+}
+
+func TestAllowCountsPerIPAndPerEmail(t *testing.T) {
+	cases := []struct {
+		name     string
+		action   string
+		attempts []struct{ ipAddress, emailAddress string }
+		expected []bool // whether each attempt is allowed
+	}{
+		{
+			name:   "signup per email",
+			action: AuthActionSignup,
+			attempts: []struct{ ipAddress, emailAddress string }{
+				{"203.0.113.1", "ana@example.com"}, {"203.0.113.2", "ANA@example.com"}, {"203.0.113.3", "ana@example.com"}, {"203.0.113.4", "ana@example.com"}, {"203.0.113.4", "bruno@example.com"},
+			},
+			expected: []bool{true, true, true, false, true},
+		},
+		{
+			name:   "signup per IP",
+			action: AuthActionSignup,
+			attempts: []struct{ ipAddress, emailAddress string }{
+				{"203.0.113.1", "a@example.com"}, {"203.0.113.1", "b@example.com"}, {"203.0.113.1", "c@example.com"}, {"203.0.113.1", "d@example.com"}, {"203.0.113.1", "e@example.com"}, {"203.0.113.1", "f@example.com"}, {"203.0.113.2", "f@example.com"},
+			},
+			expected: []bool{true, true, true, true, true, false, true},
+		},
+		{
+			name:   "unthrottled action",
+			action: "profile_view",
+			attempts: []struct{ ipAddress, emailAddress string }{
+				{"203.0.113.1", "ana@example.com"}, {"203.0.113.1", "ana@example.com"}, {"203.0.113.1", "ana@example.com"}, {"203.0.113.1", "ana@example.com"},
+			},
+			expected: []bool{true, true, true, true},
+		},
+	}
+	for _, testCase := range cases {
+		rateLimitService := NewAuthRateLimitService(repository.NewMemoryRateLimitRepository(), nil)
+		for index, attempt := range testCase.attempts {
+			allowError := rateLimitService.Allow(context.Background(), testCase.action, attempt.ipAddress, attempt.emailAddress)
+			if allowed := allowError == nil; allowed != testCase.expected[index] {
+				t.Errorf("%s: attempt %d from %s for %s allowed=%t, expected %t (%v)", testCase.name, index+1, attempt.ipAddress, attempt.emailAddress, allowed, testCase.expected[index], allowError)
+			}
+			var rateLimitedError *RateLimitedError
+			if errors.As(allowError, &rateLimitedError) && (rateLimitedError.AccountLocked || rateLimitedError.RetryAfter <= 0) {
+				t.Errorf("%s: expected a plain limit with a retry time, got %+v", testCase.name, rateLimitedError)
+			}
+		}
+	}
+}
+
+// TestRateLimitsFailOpen checks that an unreachable store never locks users out.
+func TestRateLimitsFailOpen(t *testing.T) {
+	rateLimitService := NewAuthRateLimitService(failingRateLimitStore{}, nil)
+	for attempt := 0; attempt < loginLockoutThreshold+1; attempt++ {
+		rateLimitService.RecordLoginFailure(context.Background(), "ana@example.com", "en")
+		if allowError := rateLimitService.Allow(context.Background(), AuthActionLogin, "203.0.113.9", "ana@example.com"); allowError != nil {
+			t.Fatalf("attempt %d was refused while the store was down: %v", attempt+1, allowError)
+		}
+	}
+}
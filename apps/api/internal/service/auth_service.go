@@ -3,7 +3,6 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
 	"strings"
 
 	"coin-alert/internal/domain"
@@ -32,10 +31,11 @@ type AuthService struct {
 	deletionAuditRepository   repository.AccountDeletionAuditRepository
 	passwordService           *PasswordService
 	secretCipher              *security.SecretCipher // may be nil; used only to fingerprint emails for the deletion audit
+	auditRecorder             AuditRecorder
 	placeholderPasswordHash   string
 }
 
-func NewAuthService(userRepository repository.UserRepository, tradingSettingsRepository repository.UserTradingSettingsRepository, deletionAuditRepository repository.AccountDeletionAuditRepository, passwordService *PasswordService, secretCipher *security.SecretCipher) *AuthService {
+func NewAuthService(userRepository repository.UserRepository, tradingSettingsRepository repository.UserTradingSettingsRepository, deletionAuditRepository repository.AccountDeletionAuditRepository, passwordService *PasswordService, secretCipher *security.SecretCipher, auditRecorder AuditRecorder) *AuthService {
 	// A real bcrypt hash compared against when an email is unknown, to keep authentication
 	// timing roughly constant and avoid leaking which emails exist.
 	placeholderPasswordHash, _ := passwordService.HashPassword("placeholder-password-for-constant-time-auth")
@@ -45,6 +45,7 @@ func NewAuthService(userRepository repository.UserRepository, tradingSettingsRep
 		deletionAuditRepository:   deletionAuditRepository,
 		passwordService:           passwordService,
 		secretCipher:              secretCipher,
+		auditRecorder:             auditRecorder,
 		placeholderPasswordHash:   placeholderPasswordHash,
 	}
 }
@@ -69,7 +70,7 @@ func (service *AuthService) Register(registrationContext context.Context, email
 	}
 
 	if _, defaultsError := service.tradingSettingsRepository.EnsureDefaults(registrationContext, createdUser.Identifier, domain.BinanceEnvironmentTestnet); defaultsError != nil {
-		log.Printf("Could not seed default trading settings for user %d: %v", createdUser.Identifier, defaultsError)
+		authLogger.ErrorContext(registrationContext, "could not seed default trading settings", "user_id", createdUser.Identifier, "error", defaultsError)
 	}
 
 	return createdUser, nil
@@ -94,6 +95,16 @@ func (service *AuthService) Authenticate(authenticationContext context.Context,
 	return foundUser, nil
 }
 
+// RecordLogin audits a sign-in. Callers record it once the session is issued, so a first factor that
+// is followed by a failed second factor never shows up as a login.
+func (service *AuthService) RecordLogin(operationContext context.Context, userIdentifier int64, method string) {
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionLogin,
+		After:          map[string]string{"method": method},
+	})
+}
+
 func (service *AuthService) GetUserByIdentifier(lookupContext context.Context, userIdentifier int64) (*domain.User, error) {
 	return service.userRepository.FindByIdentifier(lookupContext, userIdentifier)
 }
@@ -129,6 +140,12 @@ func (service *AuthService) AuthenticateWithGoogle(authenticationContext context
 			return nil, linkError
 		}
 		existingByEmail.GoogleSubject = googleProfile.Subject
+		recordAudit(authenticationContext, service.auditRecorder, AuditRecord{
+			UserIdentifier: existingByEmail.Identifier,
+			Action:         domain.AuditActionGoogleLinked,
+			Before:         map[string]bool{"google_connected": false},
+			After:          map[string]bool{"google_connected": true},
+		})
 		return existingByEmail, nil
 	}
 	if !errors.Is(emailLookupError, repository.ErrUserNotFound) {
@@ -140,7 +157,7 @@ func (service *AuthService) AuthenticateWithGoogle(authenticationContext context
 		return nil, creationError
 	}
 	if _, defaultsError := service.tradingSettingsRepository.EnsureDefaults(authenticationContext, createdUser.Identifier, domain.BinanceEnvironmentTestnet); defaultsError != nil {
-		log.Printf("Could not seed default trading settings for user %d: %v", createdUser.Identifier, defaultsError)
+		authLogger.ErrorContext(authenticationContext, "could not seed default trading settings", "user_id", createdUser.Identifier, "error", defaultsError)
 	}
 	return createdUser, nil
 }
@@ -168,7 +185,16 @@ func (service *AuthService) SetOrChangePassword(updateContext context.Context, u
 	if hashError != nil {
 		return hashError
 	}
-	return service.userRepository.UpdatePasswordHash(updateContext, userIdentifier, passwordHash)
+	if updateError := service.userRepository.UpdatePasswordHash(updateContext, userIdentifier, passwordHash); updateError != nil {
+		return updateError
+	}
+	recordAudit(updateContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionPasswordChanged,
+		Before:         map[string]bool{"has_password": existingUser.HasPassword()},
+		After:          map[string]bool{"has_password": true},
+	})
+	return nil
 }
 
 // DeleteAccount permanently removes the account and (via ON DELETE CASCADE) all of its data. For
@@ -188,9 +214,9 @@ func (service *AuthService) DeleteAccount(deletionContext context.Context, userI
 		emailFingerprint := service.secretCipher.EmailFingerprint(existingUser.Email) // nil-safe; "" when no key
 		authMethod := deriveAuthMethod(existingUser)
 		if auditError := service.deletionAuditRepository.RecordDeletion(deletionContext, userIdentifier, emailFingerprint, authMethod, existingUser.CreatedAt); auditError != nil {
-			log.Printf("Could not record account deletion audit for user %d: %v", userIdentifier, auditError)
+			authLogger.ErrorContext(deletionContext, "could not record the account deletion audit", "user_id", userIdentifier, "error", auditError)
 		} else {
-			log.Printf("Account %d deleted (auth=%s) and recorded in the deletion audit", userIdentifier, authMethod)
+			authLogger.InfoContext(deletionContext, "account deleted and recorded in the deletion audit", "user_id", userIdentifier, "auth_method", authMethod)
 		}
 	}
 
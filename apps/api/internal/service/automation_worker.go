@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
-	"log"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/logging"
 	"coin-alert/internal/repository"
 )
 
@@ -14,6 +16,10 @@ type activeUserLister interface {
 	ListActiveUserIdentifiers(loadContext context.Context) ([]int64, error)
 }
 
+type dailyAlertClaimer interface {
+	ClaimDailyAlert(operationContext context.Context, userIdentifier int64, robotIdentifier int64, eventType string, day time.Time) (bool, error)
+}
+
 type dailyPurchaseGuard interface {
 	HasSuccessfulExecutionOfTypeSince(loadContext context.Context, userIdentifier int64, environment string, operationType string, tradingPairSymbol string, since time.Time) (bool, error)
 }
@@ -28,8 +34,18 @@ type AutomationWorker struct {
 	operationRepository repository.UserTradingOperationRepository
 	executionRepository repository.UserTradingOperationExecutionRepository
 	purchaseGuard       dailyPurchaseGuard
+	alertClaimer        dailyAlertClaimer
 	tradingService      *UserTradingService
+	transactionRunner   repository.TransactionRunner
+	eventPublisher      events.Publisher
+	liveStream          *LiveStreamService
 	monitorInterval     time.Duration
+	// dailyPurchaseInterval is how often due daily purchases are looked for.
+	dailyPurchaseInterval time.Duration
+
+	// Unix nanoseconds of the last finished pass of each loop, for HealthCheck.
+	monitorHeartbeat       atomic.Int64
+	dailyPurchaseHeartbeat atomic.Int64
 }
 
 func NewAutomationWorker(
@@ -39,28 +55,44 @@ func NewAutomationWorker(
 	operationRepository repository.UserTradingOperationRepository,
 	executionRepository repository.UserTradingOperationExecutionRepository,
 	purchaseGuard dailyPurchaseGuard,
+	alertClaimer dailyAlertClaimer,
 	tradingService *UserTradingService,
+	transactionRunner repository.TransactionRunner,
+	eventPublisher events.Publisher,
+	liveStream *LiveStreamService,
 	monitorInterval time.Duration,
+	dailyPurchaseInterval time.Duration,
 ) *AutomationWorker {
 	if monitorInterval <= 0 {
 		monitorInterval = 30 * time.Second
 	}
+	// Daily purchases run at a configured UTC hour; checking every few minutes is precise enough.
+	if dailyPurchaseInterval <= 0 {
+		dailyPurchaseInterval = 5 * time.Minute
+	}
 	return &AutomationWorker{
-		userLister:          userLister,
-		credentialService:   credentialService,
-		robotRepository:     robotRepository,
-		operationRepository: operationRepository,
-		executionRepository: executionRepository,
-		purchaseGuard:       purchaseGuard,
-		tradingService:      tradingService,
-		monitorInterval:     monitorInterval,
+		userLister:            userLister,
+		credentialService:     credentialService,
+		robotRepository:       robotRepository,
+		operationRepository:   operationRepository,
+		executionRepository:   executionRepository,
+		purchaseGuard:         purchaseGuard,
+		alertClaimer:          alertClaimer,
+		tradingService:        tradingService,
+		transactionRunner:     transactionRunner,
+		eventPublisher:        eventPublisher,
+		liveStream:            liveStream,
+		monitorInterval:       monitorInterval,
+		dailyPurchaseInterval: dailyPurchaseInterval,
 	}
 }
 
 func (worker *AutomationWorker) Start(applicationContext context.Context) {
+	worker.monitorHeartbeat.Store(time.Now().UnixNano())
+	worker.dailyPurchaseHeartbeat.Store(time.Now().UnixNano())
 	go worker.runMonitorLoop(applicationContext)
 	go worker.runDailyPurchaseLoop(applicationContext)
-	log.Printf("Automation worker started (monitor interval %s)", worker.monitorInterval)
+	automationLogger.Info("automation worker started", "monitor_interval", worker.monitorInterval.String(), "daily_purchase_interval", worker.dailyPurchaseInterval.String())
 }
 
 func (worker *AutomationWorker) runMonitorLoop(applicationContext context.Context) {
@@ -69,10 +101,11 @@ func (worker *AutomationWorker) runMonitorLoop(applicationContext context.Contex
 	for {
 		select {
 		case <-applicationContext.Done():
-			log.Println("Automation monitor loop stopped")
+			automationLogger.Info("automation monitor loop stopped")
 			return
-		case <-ticker.C:
-			worker.monitorAllUsers(applicationContext)
+		case tickedAt := <-ticker.C:
+			observeWorkerLoop(workerLoopMonitor, tickedAt, func() { worker.monitorAllUsers(applicationContext) })
+			worker.monitorHeartbeat.Store(time.Now().UnixNano())
 		}
 	}
 }
@@ -80,7 +113,7 @@ func (worker *AutomationWorker) runMonitorLoop(applicationContext context.Contex
 func (worker *AutomationWorker) monitorAllUsers(applicationContext context.Context) {
 	userIdentifiers, listError := worker.userLister.ListActiveUserIdentifiers(applicationContext)
 	if listError != nil {
-		log.Printf("automation: could not list active users: %v", listError)
+		automationLogger.ErrorContext(applicationContext, "could not list active users", "error", listError)
 		return
 	}
 	for _, userIdentifier := range userIdentifiers {
@@ -89,28 +122,33 @@ func (worker *AutomationWorker) monitorAllUsers(applicationContext context.Conte
 }
 
 func (worker *AutomationWorker) monitorUser(applicationContext context.Context, userIdentifier int64) {
+	applicationContext = logging.WithUserID(applicationContext, userIdentifier)
 	environmentConfiguration, configurationError := worker.credentialService.LoadActiveEnvironmentConfiguration(applicationContext, userIdentifier)
 	if configurationError != nil || environmentConfiguration == nil {
 		return
 	}
+	// Binance rejected the stored key; trading resumes once the user saves a working one.
+	if environmentConfiguration.CredentialHealth == domain.CredentialHealthInvalid {
+		return
+	}
 
 	openOperations, listError := worker.operationRepository.ListOpenOperationsForUser(applicationContext, userIdentifier, environmentConfiguration.EnvironmentName)
 	if listError != nil {
-		log.Printf("automation: open operations for user %d failed: %v", userIdentifier, listError)
+		automationLogger.ErrorContext(applicationContext, "could not list open operations", "error", listError)
 		return
 	}
+	worker.publishRobotStatus(applicationContext, userIdentifier, environmentConfiguration.EnvironmentName, openOperations)
 	if len(openOperations) == 0 {
 		return
 	}
 
-	// Stop-loss is configured per robot (one per coin). Map each coin to its robot's stop-loss so an
-	// open position is judged against the robot that trades that coin (or no stop-loss if none).
+	// Stop-loss is configured per robot (one per coin). Map each coin to its robot so an open position
+	// is judged against the robot that trades that coin (or no stop-loss if none), and so trade events
+	// can name the robot.
 	robots, _ := worker.robotRepository.ListRobotsForUser(applicationContext, userIdentifier, environmentConfiguration.EnvironmentName)
-	stopLossBySymbol := make(map[string]*float64)
+	robotBySymbol := make(map[string]domain.TradingRobot)
 	for _, robot := range robots {
-		if robot.IsEnabled {
-			stopLossBySymbol[robot.TradingPairSymbol] = robot.StopLossPercent
-		}
+		robotBySymbol[robot.TradingPairSymbol] = robot
 	}
 
 	tradingService := NewBinanceTradingService(*environmentConfiguration)
@@ -130,18 +168,26 @@ func (worker *AutomationWorker) monitorUser(applicationContext context.Context,
 	}
 
 	for _, openOperation := range openOperations {
-		worker.processOpenOperation(applicationContext, userIdentifier, openOperation, stopLossBySymbol[openOperation.TradingPairSymbol], tradingService, resolvePrice)
+		var robot *domain.TradingRobot
+		if matchedRobot, present := robotBySymbol[openOperation.TradingPairSymbol]; present {
+			robot = &matchedRobot
+		}
+		worker.processOpenOperation(applicationContext, userIdentifier, openOperation, robot, tradingService, resolvePrice)
 	}
 }
 
-func (worker *AutomationWorker) processOpenOperation(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, stopLossPercent *float64, tradingService *BinanceTradingService, resolvePrice func(string) (float64, bool)) {
+func (worker *AutomationWorker) processOpenOperation(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, robot *domain.TradingRobot, tradingService *BinanceTradingService, resolvePrice func(string) (float64, bool)) {
+	applicationContext = logging.WithOperationID(applicationContext, operation.Identifier)
+	if robot != nil {
+		applicationContext = logging.WithRobotID(applicationContext, robot.Identifier)
+	}
 	// 1) Reconcile the resting take-profit limit sell against Binance.
 	if operation.SellOrderIdentifier != nil {
 		orderStatus, statusError := tradingService.GetOrderStatus(applicationContext, operation.TradingPairSymbol, *operation.SellOrderIdentifier)
 		if statusError == nil && orderStatus != nil {
 			switch orderStatus.Status {
 			case "FILLED":
-				worker.markOperationSold(applicationContext, userIdentifier, operation, fillPriceFromStatus(*orderStatus, operation.PurchasePricePerUnit), "take-profit filled")
+				worker.markOperationSold(applicationContext, userIdentifier, operation, robot, fillPriceFromStatus(*orderStatus, operation.PurchasePricePerUnit), domain.TradeEventTakeProfitFilled)
 				return
 			case "CANCELED", "EXPIRED", "REJECTED":
 				// Removed outside the app (e.g. the user cancelled it in the Binance app).
@@ -150,21 +196,21 @@ func (worker *AutomationWorker) processOpenOperation(applicationContext context.
 			}
 			// Still resting: enforce the app-side validity window (Binance spot LIMIT has no native expiry).
 			if operation.SellOrderExpiresAt != nil && time.Now().After(*operation.SellOrderExpiresAt) {
-				worker.expireSellOrder(applicationContext, userIdentifier, operation, tradingService)
+				worker.expireSellOrder(applicationContext, userIdentifier, operation, robot, tradingService)
 				return
 			}
 		}
 	}
 
-	// 2) Stop-loss: if this coin's robot has one configured and the price fell below it, sell now.
-	if stopLossPercent == nil || *stopLossPercent <= 0 {
+	// 2) Stop-loss: if this coin's robot is enabled, has one configured and the price fell below it, sell now.
+	if robot == nil || !robot.IsEnabled || robot.StopLossPercent == nil || *robot.StopLossPercent <= 0 {
 		return
 	}
 	currentPrice, pricePresent := resolvePrice(operation.TradingPairSymbol)
 	if !pricePresent {
 		return
 	}
-	stopLossThreshold := operation.PurchasePricePerUnit * (1 - (*stopLossPercent / 100))
+	stopLossThreshold := operation.PurchasePricePerUnit * (1 - (*robot.StopLossPercent / 100))
 	if currentPrice > stopLossThreshold {
 		return
 	}
@@ -174,9 +220,9 @@ func (worker *AutomationWorker) processOpenOperation(applicationContext context.
 		if cancelError := tradingService.CancelOrder(applicationContext, operation.TradingPairSymbol, *operation.SellOrderIdentifier); cancelError != nil {
 			// The cancel may have failed because the order just filled — reconcile that case.
 			if orderStatus, statusError := tradingService.GetOrderStatus(applicationContext, operation.TradingPairSymbol, *operation.SellOrderIdentifier); statusError == nil && orderStatus != nil && orderStatus.Status == "FILLED" {
-				worker.markOperationSold(applicationContext, userIdentifier, operation, fillPriceFromStatus(*orderStatus, operation.PurchasePricePerUnit), "take-profit filled")
+				worker.markOperationSold(applicationContext, userIdentifier, operation, robot, fillPriceFromStatus(*orderStatus, operation.PurchasePricePerUnit), domain.TradeEventTakeProfitFilled)
 			} else {
-				log.Printf("automation: stop-loss cancel failed for operation %d (user %d): %v", operation.Identifier, userIdentifier, cancelError)
+				automationLogger.ErrorContext(applicationContext, "stop-loss could not cancel the take-profit order", "error", cancelError)
 			}
 			return
 		}
@@ -184,100 +230,174 @@ func (worker *AutomationWorker) processOpenOperation(applicationContext context.
 
 	sellResponse, sellError := tradingService.PlaceMarketSellByQuantity(applicationContext, operation.TradingPairSymbol, operation.QuantityPurchased)
 	if sellError != nil {
-		worker.logSellExecution(applicationContext, userIdentifier, operation.BinanceEnvironment, domain.ExecutionInitiatorBot, operation.TradingPairSymbol, currentPrice, operation.QuantityPurchased, false, sellError, nil)
-		log.Printf("automation: stop-loss market sell failed for operation %d (user %d): %v", operation.Identifier, userIdentifier, sellError)
+		failedExecution := newExecution(operation.BinanceEnvironment, domain.ExecutionInitiatorBot, operation.TradingPairSymbol, domain.TradingOperationTypeSell, currentPrice, operation.QuantityPurchased, false, sellError, nil)
+		if recordError := recordExecution(applicationContext, worker.executionRepository, worker.eventPublisher, userIdentifier, failedExecution); recordError != nil {
+			automationLogger.ErrorContext(applicationContext, "could not record the failed stop-loss", "error", recordError)
+		}
+		automationLogger.ErrorContext(applicationContext, "stop-loss market sell failed", "symbol", operation.TradingPairSymbol, "error", sellError)
+		stopLossTriggers.Inc(environmentLabel(operation.BinanceEnvironment), "failed")
 		return
 	}
-	worker.markOperationSold(applicationContext, userIdentifier, operation, fillPriceFromOrder(*sellResponse, currentPrice), "stop-loss")
+	worker.markOperationSold(applicationContext, userIdentifier, operation, robot, fillPriceFromOrder(*sellResponse, currentPrice), domain.TradeEventStopLossTriggered)
 }
 
-func (worker *AutomationWorker) markOperationSold(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, fillPrice float64, reason string) {
-	if updateError := worker.operationRepository.UpdateOperationAsSoldForUser(applicationContext, userIdentifier, operation.Identifier, fillPrice); updateError != nil {
-		log.Printf("automation: could not mark operation %d sold (user %d): %v", operation.Identifier, userIdentifier, updateError)
+// publishRobotStatus reports each robot's state to the user's live dashboard, if it is open.
+func (worker *AutomationWorker) publishRobotStatus(applicationContext context.Context, userIdentifier int64, environment string, openOperations []domain.TradingOperation) {
+	if worker.liveStream == nil || !worker.liveStream.IsConnected(userIdentifier) {
+		return
+	}
+	robots, listError := worker.robotRepository.ListRobotsForUser(applicationContext, userIdentifier, environment)
+	if listError != nil {
+		return
+	}
+	worker.liveStream.PublishRobotStatus(userIdentifier, robots, openOperations, time.Now())
+}
+
+// markOperationSold closes the operation at fillPrice; eventType is the trade event that caused it
+// (take-profit filled or stop-loss triggered).
+func (worker *AutomationWorker) markOperationSold(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, robot *domain.TradingRobot, fillPrice float64, eventType string) {
+	soldAt := time.Now()
+	soldOperation := operation
+	soldOperation.Status = domain.TradingOperationStatusSold
+	soldOperation.SellPricePerUnit = &fillPrice
+	soldOperation.SellTimestamp = &soldAt
+	realizedProfit := (fillPrice - operation.PurchasePricePerUnit) * operation.QuantityPurchased
+
+	// The sale already happened on Binance: the SOLD status commits first so the next pass does not sell
+	// again, and the fill is then recorded on its own.
+	soldError := worker.transactionRunner.RunInTransaction(applicationContext, func(transactionContext context.Context) error {
+		if updateError := worker.operationRepository.UpdateOperationAsSoldForUser(transactionContext, userIdentifier, operation.Identifier, fillPrice); updateError != nil {
+			return updateError
+		}
+		if publishError := worker.eventPublisher.Publish(transactionContext, userIdentifier, events.OperationClosed{Operation: soldOperation}); publishError != nil {
+			return publishError
+		}
+		return worker.publishTradeEvent(transactionContext, robot, domain.TradeEvent{
+			EventType:           eventType,
+			UserIdentifier:      userIdentifier,
+			TradingPairSymbol:   operation.TradingPairSymbol,
+			BinanceEnvironment:  operation.BinanceEnvironment,
+			OperationIdentifier: operation.Identifier,
+			Quantity:            operation.QuantityPurchased,
+			PurchasePrice:       operation.PurchasePricePerUnit,
+			FillPrice:           fillPrice,
+			RealizedProfit:      &realizedProfit,
+		})
+	})
+	if soldError != nil {
+		automationLogger.ErrorContext(applicationContext, "could not mark the operation sold", "error", soldError)
 		return
 	}
-	worker.logSellExecution(applicationContext, userIdentifier, operation.BinanceEnvironment, domain.ExecutionInitiatorBot, operation.TradingPairSymbol, fillPrice, operation.QuantityPurchased, true, nil, operation.SellOrderIdentifier)
-	log.Printf("automation: closed operation %d (user %d) via %s at %.8f", operation.Identifier, userIdentifier, reason, fillPrice)
+	execution := newExecution(operation.BinanceEnvironment, domain.ExecutionInitiatorBot, operation.TradingPairSymbol, domain.TradingOperationTypeSell, fillPrice, operation.QuantityPurchased, true, nil, operation.SellOrderIdentifier)
+	recordPlacedOrder(applicationContext, worker.transactionRunner, worker.executionRepository, worker.eventPublisher, userIdentifier, execution)
+	switch eventType {
+	case domain.TradeEventTakeProfitFilled:
+		takeProfitFills.Inc(environmentLabel(operation.BinanceEnvironment))
+	case domain.TradeEventStopLossTriggered:
+		stopLossTriggers.Inc(environmentLabel(operation.BinanceEnvironment), "sold")
+	}
+	automationLogger.InfoContext(applicationContext, "operation closed", "symbol", operation.TradingPairSymbol, "event", eventType, "fill_price", fillPrice)
 }
 
 // markOperationCanceledExternally handles a take-profit that was cancelled outside the app: it closes
 // the operation as CANCELED (drops it from the active positions view) and records a history event.
 func (worker *AutomationWorker) markOperationCanceledExternally(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation) {
-	if updateError := worker.operationRepository.MarkOperationCanceledForUser(applicationContext, userIdentifier, operation.Identifier); updateError != nil {
-		log.Printf("automation: could not mark operation %d canceled (user %d): %v", operation.Identifier, userIdentifier, updateError)
+	canceledOperation := operation
+	canceledOperation.Status = domain.TradingOperationStatusCanceled
+
+	canceledError := worker.transactionRunner.RunInTransaction(applicationContext, func(transactionContext context.Context) error {
+		if updateError := worker.operationRepository.MarkOperationCanceledForUser(transactionContext, userIdentifier, operation.Identifier); updateError != nil {
+			return updateError
+		}
+		if executionError := worker.recordTakeProfitEvent(transactionContext, userIdentifier, operation, domain.TradingOperationTypeSellCancel, domain.ExecutionInitiatorUser); executionError != nil {
+			return executionError
+		}
+		return worker.eventPublisher.Publish(transactionContext, userIdentifier, events.OperationCancelled{Operation: canceledOperation})
+	})
+	if canceledError != nil {
+		automationLogger.ErrorContext(applicationContext, "could not mark the operation canceled", "error", canceledError)
 		return
 	}
-	worker.logTakeProfitEvent(applicationContext, userIdentifier, operation, domain.TradingOperationTypeSellCancel, domain.ExecutionInitiatorUser)
-	log.Printf("automation: operation %d (user %d) take-profit cancelled externally; position released", operation.Identifier, userIdentifier)
+	automationLogger.InfoContext(applicationContext, "take-profit cancelled outside the app; position released", "symbol", operation.TradingPairSymbol)
 }
 
 // expireSellOrder cancels a take-profit that reached its validity window, leaving the position OPEN
 // but unprotected (⚠) so the user can re-place it or sell. Records a history event.
-func (worker *AutomationWorker) expireSellOrder(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, tradingService *BinanceTradingService) {
+func (worker *AutomationWorker) expireSellOrder(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, robot *domain.TradingRobot, tradingService *BinanceTradingService) {
 	if operation.SellOrderIdentifier != nil {
 		if cancelError := tradingService.CancelOrder(applicationContext, operation.TradingPairSymbol, *operation.SellOrderIdentifier); cancelError != nil {
 			// If it actually filled meanwhile, reconcile to sold instead of expiring it.
 			if orderStatus, statusError := tradingService.GetOrderStatus(applicationContext, operation.TradingPairSymbol, *operation.SellOrderIdentifier); statusError == nil && orderStatus != nil && orderStatus.Status == "FILLED" {
-				worker.markOperationSold(applicationContext, userIdentifier, operation, fillPriceFromStatus(*orderStatus, operation.PurchasePricePerUnit), "take-profit filled")
+				worker.markOperationSold(applicationContext, userIdentifier, operation, robot, fillPriceFromStatus(*orderStatus, operation.PurchasePricePerUnit), domain.TradeEventTakeProfitFilled)
 				return
 			}
-			log.Printf("automation: could not cancel expired sell order for operation %d (user %d): %v", operation.Identifier, userIdentifier, cancelError)
+			automationLogger.ErrorContext(applicationContext, "could not cancel the expired take-profit order", "error", cancelError)
 			return
 		}
 	}
-	if clearError := worker.operationRepository.ClearSellOrderForUser(applicationContext, userIdentifier, operation.Identifier); clearError != nil {
-		log.Printf("automation: could not clear expired sell order for operation %d (user %d): %v", operation.Identifier, userIdentifier, clearError)
+	expireError := worker.transactionRunner.RunInTransaction(applicationContext, func(transactionContext context.Context) error {
+		if clearError := worker.operationRepository.ClearSellOrderForUser(transactionContext, userIdentifier, operation.Identifier); clearError != nil {
+			return clearError
+		}
+		if executionError := worker.recordTakeProfitEvent(transactionContext, userIdentifier, operation, domain.TradingOperationTypeSellExpire, domain.ExecutionInitiatorBot); executionError != nil {
+			return executionError
+		}
+		return worker.publishTradeEvent(transactionContext, robot, domain.TradeEvent{
+			EventType:           domain.TradeEventTakeProfitExpired,
+			UserIdentifier:      userIdentifier,
+			TradingPairSymbol:   operation.TradingPairSymbol,
+			BinanceEnvironment:  operation.BinanceEnvironment,
+			OperationIdentifier: operation.Identifier,
+			Quantity:            operation.QuantityPurchased,
+			PurchasePrice:       operation.PurchasePricePerUnit,
+		})
+	})
+	if expireError != nil {
+		automationLogger.ErrorContext(applicationContext, "could not clear the expired take-profit order", "error", expireError)
 		return
 	}
-	worker.logTakeProfitEvent(applicationContext, userIdentifier, operation, domain.TradingOperationTypeSellExpire, domain.ExecutionInitiatorBot)
-	log.Printf("automation: take-profit for operation %d (user %d) reached its validity and was cancelled", operation.Identifier, userIdentifier)
+	automationLogger.InfoContext(applicationContext, "take-profit reached its validity and was cancelled", "symbol", operation.TradingPairSymbol)
 }
 
-// logTakeProfitEvent records a non-trade history event (cancel/expire) for a take-profit order.
-func (worker *AutomationWorker) logTakeProfitEvent(applicationContext context.Context, userIdentifier int64, operation domain.TradingOperation, operationType string, initiatedBy string) {
-	_, _ = worker.executionRepository.LogExecutionForUser(applicationContext, userIdentifier, domain.TradingOperationExecution{
-		TradingPairSymbol:  operation.TradingPairSymbol,
-		OperationType:      operationType,
-		BinanceEnvironment: operation.BinanceEnvironment,
-		InitiatedBy:        initiatedBy,
-		Quantity:           operation.QuantityPurchased,
-		ExecutedAt:         time.Now(),
-		Success:            true,
-		OrderIdentifier:    operation.SellOrderIdentifier,
-	})
+// publishTradeEvent fills in the robot and timestamp and publishes the user-facing trade event.
+func (worker *AutomationWorker) publishTradeEvent(eventContext context.Context, robot *domain.TradingRobot, event domain.TradeEvent) error {
+	if robot != nil {
+		event.RobotIdentifier = robot.Identifier
+		event.RobotName = robot.Name
+	}
+	event.OccurredAt = time.Now()
+	return worker.eventPublisher.Publish(eventContext, event.UserIdentifier, events.TradeEventRaised{TradeEvent: event})
 }
 
-func (worker *AutomationWorker) logSellExecution(applicationContext context.Context, userIdentifier int64, environment string, initiatedBy string, tradingPairSymbol string, unitPrice float64, quantity float64, success bool, cause error, orderIdentifier *string) {
-	var errorMessage *string
-	if cause != nil {
-		message := cause.Error()
-		errorMessage = &message
-	}
-	_, _ = worker.executionRepository.LogExecutionForUser(applicationContext, userIdentifier, domain.TradingOperationExecution{
-		TradingPairSymbol:  tradingPairSymbol,
-		OperationType:      domain.TradingOperationTypeSell,
-		BinanceEnvironment: environment,
-		InitiatedBy:        initiatedBy,
-		UnitPrice:          unitPrice,
-		Quantity:           quantity,
-		TotalValue:         unitPrice * quantity,
-		ExecutedAt:         time.Now(),
-		Success:            success,
-		ErrorMessage:       errorMessage,
-		OrderIdentifier:    orderIdentifier,
+// publishDailyPurchaseFailure publishes the failed daily purchase once per robot and UTC day: the
+// purchase is retried on every tick of its hour, and each retry would otherwise alert again.
+func (worker *AutomationWorker) publishDailyPurchaseFailure(eventContext context.Context, robot *domain.TradingRobot, day time.Time, event domain.TradeEvent) error {
+	return worker.transactionRunner.RunInTransaction(eventContext, func(transactionContext context.Context) error {
+		claimed, claimError := worker.alertClaimer.ClaimDailyAlert(transactionContext, event.UserIdentifier, robot.Identifier, event.EventType, day)
+		if claimError != nil || !claimed {
+			return claimError
+		}
+		return worker.publishTradeEvent(transactionContext, robot, event)
 	})
 }
 
+// recordTakeProfitEvent records a non-trade history event (cancel/expire) for a take-profit order.
+func (worker *AutomationWorker) recordTakeProfitEvent(transactionContext context.Context, userIdentifier int64, operation domain.TradingOperation, operationType string, initiatedBy string) error {
+	execution := newExecution(operation.BinanceEnvironment, initiatedBy, operation.TradingPairSymbol, operationType, 0, operation.QuantityPurchased, true, nil, operation.SellOrderIdentifier)
+	return recordExecution(transactionContext, worker.executionRepository, worker.eventPublisher, userIdentifier, execution)
+}
+
 func (worker *AutomationWorker) runDailyPurchaseLoop(applicationContext context.Context) {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(worker.dailyPurchaseInterval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-applicationContext.Done():
-			log.Println("Automation daily purchase loop stopped")
+			automationLogger.Info("automation daily purchase loop stopped")
 			return
-		case <-ticker.C:
-			worker.processDailyPurchases(applicationContext)
+		case tickedAt := <-ticker.C:
+			observeWorkerLoop(workerLoopDailyPurchase, tickedAt, func() { worker.processDailyPurchases(applicationContext) })
+			worker.dailyPurchaseHeartbeat.Store(time.Now().UnixNano())
 		}
 	}
 }
@@ -307,14 +427,35 @@ func (worker *AutomationWorker) processDailyPurchases(applicationContext context
 			if nowUTC.Hour() != robot.DailyPurchaseHourUTC {
 				continue
 			}
+			if environmentConfiguration.CredentialHealth == domain.CredentialHealthInvalid {
+				dailyPurchasesSkipped.Inc("credentials_invalid")
+				continue
+			}
 			alreadyPurchased, _ := worker.purchaseGuard.HasSuccessfulExecutionOfTypeSince(applicationContext, userIdentifier, environmentName, domain.TradingOperationTypeDailyBuy, robot.TradingPairSymbol, startOfDayUTC)
 			if alreadyPurchased {
+				dailyPurchasesSkipped.Inc("already_purchased")
 				continue
 			}
 
-			log.Printf("automation: running daily purchase for user %d robot %d (%s)", userIdentifier, robot.Identifier, robot.TradingPairSymbol)
-			if _, purchaseError := worker.tradingService.ExecuteDailyPurchase(applicationContext, userIdentifier, environmentName, robot.TradingPairSymbol, robot.CapitalThreshold, robot.TargetProfitPercent, robot.SellOrderValidityDays); purchaseError != nil {
-				log.Printf("automation: daily purchase failed for user %d robot %d: %v", userIdentifier, robot.Identifier, purchaseError)
+			robotContext := logging.WithRobotID(logging.WithUserID(applicationContext, userIdentifier), robot.Identifier)
+			automationLogger.InfoContext(robotContext, "running daily purchase", "symbol", robot.TradingPairSymbol)
+			_, purchaseError := worker.tradingService.ExecuteDailyPurchase(robotContext, userIdentifier, environmentName, robot.TradingPairSymbol, robot.CapitalThreshold, robot.TargetProfitPercent, robot.SellOrderValidityDays)
+			if purchaseError == nil {
+				dailyPurchasesTotal.Inc(environmentLabel(environmentName), "succeeded")
+			} else {
+				dailyPurchasesTotal.Inc(environmentLabel(environmentName), "failed")
+				automationLogger.ErrorContext(robotContext, "daily purchase failed", "symbol", robot.TradingPairSymbol, "error", purchaseError)
+				failedRobot := robot
+				if publishError := worker.publishDailyPurchaseFailure(robotContext, &failedRobot, startOfDayUTC, domain.TradeEvent{
+					EventType:          domain.TradeEventDailyPurchaseFailed,
+					UserIdentifier:     userIdentifier,
+					TradingPairSymbol:  robot.TradingPairSymbol,
+					BinanceEnvironment: environmentName,
+					QuoteAmount:        robot.CapitalThreshold,
+					ErrorMessage:       purchaseError.Error(),
+				}); publishError != nil {
+					automationLogger.ErrorContext(robotContext, "could not publish the failed daily purchase", "error", publishError)
+				}
 			}
 		}
 	}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/repository"
+)
+
+// stagingTransactionRunner applies the writes staged during a transaction only when it commits, so a
+// test sees what a rollback would discard.
+type stagingTransactionRunner struct {
+	staged []func()
+}
+
+func (runner *stagingTransactionRunner) RunInTransaction(operationContext context.Context, work func(transactionContext context.Context) error) error {
+	runner.staged = nil
+	workError := work(operationContext)
+	if workError == nil {
+		for _, apply := range runner.staged {
+			apply()
+		}
+	}
+	runner.staged = nil
+	return workError
+}
+
+// soldOperationRepository records committed sales; other methods are not used.
+type soldOperationRepository struct {
+	repository.UserTradingOperationRepository
+	runner *stagingTransactionRunner
+	sold   map[int64]float64
+}
+
+func (operationRepository *soldOperationRepository) UpdateOperationAsSoldForUser(_ context.Context, _ int64, operationIdentifier int64, sellPricePerUnit float64) error {
+	operationRepository.runner.staged = append(operationRepository.runner.staged, func() {
+		operationRepository.sold[operationIdentifier] = sellPricePerUnit
+	})
+	return nil
+}
+
+// flakyExecutionRepository fails every insert while failing is set; other methods are not used.
+type flakyExecutionRepository struct {
+	repository.UserTradingOperationExecutionRepository
+	runner   *stagingTransactionRunner
+	failing  bool
+	recorded []domain.TradingOperationExecution
+}
+
+func (executionRepository *flakyExecutionRepository) LogExecutionForUser(_ context.Context, _ int64, execution domain.TradingOperationExecution) (int64, error) {
+	if executionRepository.failing {
+		return 0, errors.New("deadlock detected")
+	}
+	executionRepository.runner.staged = append(executionRepository.runner.staged, func() {
+		executionRepository.recorded = append(executionRepository.recorded, execution)
+	})
+	return int64(len(executionRepository.recorded) + 1), nil
+}
+
+// TestFilledSellClosesTheOperationEvenWhenItsExecutionIsNotRecorded checks both places that record a
+// sale Binance already filled: losing the execution row must not leave the operation OPEN, where the
+// next pass or a retry would sell it again.
+func TestFilledSellClosesTheOperationEvenWhenItsExecutionIsNotRecorded(t *testing.T) {
+	sellOrderIdentifier := "991"
+	operation := domain.TradingOperation{Identifier: 5, TradingPairSymbol: "BTCUSDT", QuantityPurchased: 0.01, PurchasePricePerUnit: 60000, Status: domain.TradingOperationStatusOpen, BinanceEnvironment: domain.BinanceEnvironmentTestnet, SellOrderIdentifier: &sellOrderIdentifier}
+	closers := []struct {
+		name  string
+		close func(runner *stagingTransactionRunner, operationRepository *soldOperationRepository, executionRepository *flakyExecutionRepository, publisher *recordingPublisher) error
+	}{
+		{
+			name: "automation worker",
+			close: func(runner *stagingTransactionRunner, operationRepository *soldOperationRepository, executionRepository *flakyExecutionRepository, publisher *recordingPublisher) error {
+				worker := &AutomationWorker{operationRepository: operationRepository, executionRepository: executionRepository, transactionRunner: runner, eventPublisher: publisher}
+				worker.markOperationSold(context.Background(), 7, operation, nil, 61000, domain.TradeEventTakeProfitFilled)
+				return nil
+			},
+		},
+		{
+			name: "manual sell",
+			close: func(runner *stagingTransactionRunner, operationRepository *soldOperationRepository, executionRepository *flakyExecutionRepository, publisher *recordingPublisher) error {
+				tradingService := NewUserTradingService(nil, nil, operationRepository, executionRepository, runner, publisher, nil)
+				_, finalizeError := tradingService.finalizeManualSell(context.Background(), 7, domain.BinanceEnvironmentTestnet, domain.ExecutionInitiatorUser, operation, 61000, &sellOrderIdentifier)
+				return finalizeError
+			},
+		},
+	}
+	for _, closer := range closers {
+		for _, executionFails := range []bool{false, true} {
+			runner := &stagingTransactionRunner{}
+			operationRepository := &soldOperationRepository{runner: runner, sold: map[int64]float64{}}
+			executionRepository := &flakyExecutionRepository{runner: runner, failing: executionFails}
+			publisher := &recordingPublisher{}
+
+			if closeError := closer.close(runner, operationRepository, executionRepository, publisher); closeError != nil {
+				t.Errorf("%s (execution fails %t): %v", closer.name, executionFails, closeError)
+			}
+			if operationRepository.sold[operation.Identifier] != 61000 {
+				t.Errorf("%s (execution fails %t): the operation was left open", closer.name, executionFails)
+			}
+			closedEvents := 0
+			for _, event := range publisher.published {
+				if _, isClosed := event.(events.OperationClosed); isClosed {
+					closedEvents++
+				}
+			}
+			if closedEvents != 1 {
+				t.Errorf("%s (execution fails %t): expected one OperationClosed event, got %d", closer.name, executionFails, closedEvents)
+			}
+			expectedExecutions := 1
+			if executionFails {
+				expectedExecutions = 0
+			}
+			if len(executionRepository.recorded) != expectedExecutions {
+				t.Errorf("%s (execution fails %t): expected %d recorded executions, got %d", closer.name, executionFails, expectedExecutions, len(executionRepository.recorded))
+			}
+		}
+	}
+}
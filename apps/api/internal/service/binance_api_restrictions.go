@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+const binanceAPIRestrictionsEndpointPath = "/sapi/v1/account/apiRestrictions"
+
+type binanceAPIRestrictionsPayload struct {
+	IPRestrict                 bool `json:"ipRestrict"`
+	EnableReading              bool `json:"enableReading"`
+	EnableSpotAndMarginTrading bool `json:"enableSpotAndMarginTrading"`
+	EnableWithdrawals          bool `json:"enableWithdrawals"`
+}
+
+// FetchKeyPermissions asks Binance what the key may do (GET /sapi/v1/account/apiRestrictions). Only the
+// production API serves /sapi; callers skip it on the Spot testnet.
+func (validator *BinanceCredentialValidator) FetchKeyPermissions(requestContext context.Context, apiKey string, apiSecret string) (*domain.BinanceKeyPermissions, error) {
+	serverTimestamp, serverTimeError := validator.fetchBinanceServerTimestamp(requestContext)
+	if serverTimeError != nil {
+		return nil, serverTimeError
+	}
+
+	restrictionsEndpoint, parseError := url.Parse(validator.APIBaseURL)
+	if parseError != nil {
+		return nil, parseError
+	}
+	restrictionsEndpoint.Path = binanceAPIRestrictionsEndpointPath
+	parameters := url.Values{}
+	parameters.Set("timestamp", fmt.Sprintf("%d", serverTimestamp))
+	parameters.Set("signature", computeHMACSignature(parameters.Encode(), apiSecret))
+	restrictionsEndpoint.RawQuery = parameters.Encode()
+
+	signedRequest, buildError := http.NewRequestWithContext(requestContext, http.MethodGet, restrictionsEndpoint.String(), nil)
+	if buildError != nil {
+		return nil, buildError
+	}
+	signedRequest.Header.Set("X-MBX-APIKEY", apiKey)
+
+	binanceResponse, responseError := validator.HTTPClient.Do(signedRequest)
+	if responseError != nil {
+		return nil, responseError
+	}
+	defer binanceResponse.Body.Close()
+
+	if binanceResponse.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(io.LimitReader(binanceResponse.Body, 4096))
+		return nil, fmt.Errorf("Binance did not return the key's permissions (status %d): %s", binanceResponse.StatusCode, string(responseBody))
+	}
+
+	var restrictions binanceAPIRestrictionsPayload
+	if decodeError := json.NewDecoder(binanceResponse.Body).Decode(&restrictions); decodeError != nil {
+		return nil, decodeError
+	}
+	return &domain.BinanceKeyPermissions{
+		ReadingEnabled:     restrictions.EnableReading,
+		SpotTradingEnabled: restrictions.EnableSpotAndMarginTrading,
+		WithdrawalsEnabled: restrictions.EnableWithdrawals,
+		IPRestricted:       restrictions.IPRestrict,
+		CheckedAt:          time.Now().UTC(),
+	}, nil
+}
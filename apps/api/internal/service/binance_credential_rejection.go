@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BinanceCredentialRejectedError is a non-200 answer from Binance to a signed account request.
+type BinanceCredentialRejectedError struct {
+	EndpointURL  string
+	StatusCode   int
+	ResponseBody string
+}
+
+func (rejection *BinanceCredentialRejectedError) Error() string {
+	if rejection.ResponseBody == "" {
+		return fmt.Sprintf("Binance rejected the credentials at %s (status %d)", rejection.EndpointURL, rejection.StatusCode)
+	}
+	return fmt.Sprintf("Binance rejected the credentials at %s (status %d): %s", rejection.EndpointURL, rejection.StatusCode, rejection.ResponseBody)
+}
+
+// binanceKeyRejectionCodes are the Binance error codes that mean the key itself is unusable: malformed
+// key (-2014), invalid key, IP or permissions (-2015), and a signature the secret cannot produce (-1022).
+var binanceKeyRejectionCodes = []string{`"code":-2014`, `"code":-2015`, `"code":-1022`}
+
+// IsKeyRejected reports whether Binance refused the key itself, as opposed to rate limits, WAF blocks
+// or outages, which say nothing about the key.
+func (rejection *BinanceCredentialRejectedError) IsKeyRejected() bool {
+	if rejection.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if rejection.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	compactBody := strings.ReplaceAll(rejection.ResponseBody, " ", "")
+	for _, code := range binanceKeyRejectionCodes {
+		if strings.Contains(compactBody, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinanceKeyRejection reports whether validationError means the stored key no longer works.
+func isBinanceKeyRejection(validationError error) bool {
+	var rejection *BinanceCredentialRejectedError
+	return errors.As(validationError, &rejection) && rejection.IsKeyRejected()
+}
@@ -21,7 +21,7 @@ type BinanceCredentialValidator struct {
 }
 
 func NewBinanceCredentialValidator(apiBaseURL string) *BinanceCredentialValidator {
-        return &BinanceCredentialValidator{APIBaseURL: apiBaseURL, HTTPClient: &http.Client{Timeout: 8 * time.Second}}
+        return &BinanceCredentialValidator{APIBaseURL: apiBaseURL, HTTPClient: newBinanceHTTPClient(8 * time.Second)}
 }
 
 func (validator *BinanceCredentialValidator) UpdateAPIBaseURL(newBaseURL string) {
@@ -55,10 +55,7 @@ func (validator *BinanceCredentialValidator) ValidateCredentials(validationConte
 
         if binanceResponse.StatusCode != http.StatusOK {
                 responseBody, _ := io.ReadAll(binanceResponse.Body)
-                if len(responseBody) == 0 {
-                        return fmt.Errorf("Binance rejected the credentials at %s (status %d)", validator.APIBaseURL+binanceAccountEndpointPath, binanceResponse.StatusCode)
-                }
-                return fmt.Errorf("Binance rejected the credentials at %s (status %d): %s", validator.APIBaseURL+binanceAccountEndpointPath, binanceResponse.StatusCode, string(responseBody))
+                return &BinanceCredentialRejectedError{EndpointURL: validator.APIBaseURL + binanceAccountEndpointPath, StatusCode: binanceResponse.StatusCode, ResponseBody: string(responseBody)}
         }
 
         return nil
@@ -26,7 +26,7 @@ type binanceTickerPriceResponse struct {
 func NewBinancePriceService(environmentConfiguration domain.BinanceEnvironmentConfiguration) *BinancePriceService {
         return &BinancePriceService{
                 EnvironmentConfiguration: environmentConfiguration,
-                HTTPClient:               &http.Client{Timeout: 8 * time.Second},
+                HTTPClient:               newBinanceHTTPClient(8 * time.Second),
         }
 }
 
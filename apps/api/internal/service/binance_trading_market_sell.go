@@ -62,7 +62,7 @@ func (service *BinanceTradingService) PlaceMarketSellByQuantity(requestContext c
 	}
 	orderRequest.Header.Set("X-MBX-APIKEY", service.EnvironmentConfiguration.APIKey)
 
-	orderResponse, responseError := service.HTTPClient.Do(orderRequest)
+	orderResponse, responseError := service.sendOrder(orderRequest, orderTypeMarketSell)
 	if responseError != nil {
 		return nil, responseError
 	}
@@ -65,7 +65,7 @@ type BinanceOrderStatus struct {
 func NewBinanceTradingService(environmentConfiguration domain.BinanceEnvironmentConfiguration) *BinanceTradingService {
 	return &BinanceTradingService{
 		EnvironmentConfiguration: environmentConfiguration,
-		HTTPClient:               &http.Client{Timeout: 10 * time.Second},
+		HTTPClient:               newBinanceHTTPClient(10 * time.Second),
 	}
 }
 
@@ -92,7 +92,7 @@ func (service *BinanceTradingService) PlaceMarketBuyByQuote(requestContext conte
 	}
 	orderRequest.Header.Set("X-MBX-APIKEY", service.EnvironmentConfiguration.APIKey)
 
-	orderResponse, responseError := service.HTTPClient.Do(orderRequest)
+	orderResponse, responseError := service.sendOrder(orderRequest, orderTypeMarketBuy)
 	if responseError != nil {
 		return nil, responseError
 	}
@@ -161,7 +161,7 @@ func (service *BinanceTradingService) PlaceLimitSell(requestContext context.Cont
 	}
 	orderRequest.Header.Set("X-MBX-APIKEY", service.EnvironmentConfiguration.APIKey)
 
-	orderResponse, responseError := service.HTTPClient.Do(orderRequest)
+	orderResponse, responseError := service.sendOrder(orderRequest, orderTypeLimitSell)
 	if responseError != nil {
 		return nil, responseError
 	}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+// credentialHealthCheckBatchSize bounds how many keys one sweep revalidates, so a large backlog is
+// spread over several sweeps instead of bursting Binance's rate limits.
+const credentialHealthCheckBatchSize = 50
+
+// CredentialHealthService periodically revalidates stored Binance keys. A key Binance rejects is
+// marked INVALID, its environment's robots are paused and the user is alerted; transient failures are
+// recorded but change nothing.
+type CredentialHealthService struct {
+	repository        repository.CredentialHealthRepository
+	cipher            *security.SecretCipher
+	robotService      *RobotService
+	transactionRunner repository.TransactionRunner
+	eventPublisher    events.Publisher
+}
+
+func NewCredentialHealthService(repositoryInstance repository.CredentialHealthRepository, cipher *security.SecretCipher, robotService *RobotService, transactionRunner repository.TransactionRunner, eventPublisher events.Publisher) *CredentialHealthService {
+	return &CredentialHealthService{
+		repository:        repositoryInstance,
+		cipher:            cipher,
+		robotService:      robotService,
+		transactionRunner: transactionRunner,
+		eventPublisher:    eventPublisher,
+	}
+}
+
+// StartHealthChecks revalidates every stored key roughly once per interval. Sweeps run more often
+// than the interval so keys saved at different times are checked as they come due.
+func (service *CredentialHealthService) StartHealthChecks(loopContext context.Context, interval time.Duration) {
+	if service.cipher == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	sweepInterval := 5 * time.Minute
+	if interval < sweepInterval {
+		sweepInterval = interval
+	}
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				service.checkDueCredentials(loopContext, time.Now().Add(-interval))
+			}
+		}
+	}()
+}
+
+func (service *CredentialHealthService) checkDueCredentials(loopContext context.Context, checkedBefore time.Time) {
+	targets, listError := service.repository.ListCredentialsDueForHealthCheck(loopContext, checkedBefore, credentialHealthCheckBatchSize)
+	if listError != nil {
+		credentialsLogger.ErrorContext(loopContext, "health check could not list due credentials", "error", listError)
+		return
+	}
+	for _, target := range targets {
+		if loopContext.Err() != nil {
+			return
+		}
+		checkContext, cancel := context.WithTimeout(loopContext, 20*time.Second)
+		service.CheckCredential(checkContext, target)
+		cancel()
+	}
+}
+
+// CheckCredential revalidates one stored key and records the result.
+func (service *CredentialHealthService) CheckCredential(checkContext context.Context, target domain.CredentialHealthCheckTarget) {
+	apiKey, keyDecryptionError := service.cipher.DecryptString(target.APIKey)
+	if keyDecryptionError != nil {
+		service.recordHealth(checkContext, target, "", "could not decrypt the stored key")
+		return
+	}
+	apiSecret, secretDecryptionError := service.cipher.DecryptString(target.APISecret)
+	if secretDecryptionError != nil {
+		service.recordHealth(checkContext, target, "", "could not decrypt the stored key")
+		return
+	}
+
+	validationError := NewBinanceCredentialValidator(target.APIBaseURL).ValidateCredentials(checkContext, apiKey, apiSecret)
+	switch {
+	case validationError == nil:
+		service.recordHealth(checkContext, target, domain.CredentialHealthHealthy, "")
+	case isBinanceKeyRejection(validationError):
+		service.markInvalid(checkContext, target, validationError.Error())
+	default:
+		service.recordHealth(checkContext, target, "", validationError.Error())
+	}
+}
+
+func (service *CredentialHealthService) recordHealth(checkContext context.Context, target domain.CredentialHealthCheckTarget, status string, lastError string) {
+	if recordError := service.repository.RecordCredentialHealth(checkContext, target.Identifier, status, lastError); recordError != nil {
+		credentialsLogger.ErrorContext(checkContext, "could not record the health check", "credential_id", target.Identifier, "error", recordError)
+	}
+}
+
+// markInvalid records the rejection. Robots are paused and the user alerted only when the key turns
+// invalid, not on every later check that still fails. The pause, the INVALID status and the alert
+// commit together, so a failure leaves the key to be caught again by the next sweep.
+func (service *CredentialHealthService) markInvalid(checkContext context.Context, target domain.CredentialHealthCheckTarget, rejectionMessage string) {
+	if target.HealthStatus == domain.CredentialHealthInvalid {
+		service.recordHealth(checkContext, target, domain.CredentialHealthInvalid, rejectionMessage)
+		return
+	}
+
+	var pausedRobots []domain.TradingRobot
+	invalidationError := service.transactionRunner.RunInTransaction(checkContext, func(transactionContext context.Context) error {
+		var pauseError error
+		pausedRobots, pauseError = service.robotService.pauseRobotsInTransaction(transactionContext, target.UserIdentifier, target.EnvironmentName)
+		if pauseError != nil {
+			return pauseError
+		}
+		if recordError := service.repository.RecordCredentialHealth(transactionContext, target.Identifier, domain.CredentialHealthInvalid, rejectionMessage); recordError != nil {
+			return recordError
+		}
+		pausedRobotNames := make([]string, 0, len(pausedRobots))
+		for _, robot := range pausedRobots {
+			pausedRobotNames = append(pausedRobotNames, robot.Name)
+		}
+		return service.eventPublisher.Publish(transactionContext, target.UserIdentifier, events.TradeEventRaised{TradeEvent: domain.TradeEvent{
+			EventType:          domain.TradeEventCredentialInvalid,
+			UserIdentifier:     target.UserIdentifier,
+			BinanceEnvironment: target.EnvironmentName,
+			ErrorMessage:       rejectionMessage,
+			PausedRobotNames:   pausedRobotNames,
+			OccurredAt:         time.Now().UTC(),
+		}})
+	})
+	if invalidationError != nil {
+		credentialsLogger.ErrorContext(checkContext, "could not mark the credential invalid", "credential_id", target.Identifier, "error", invalidationError)
+		return
+	}
+	service.robotService.auditPausedRobots(checkContext, target.UserIdentifier, pausedRobots)
+	credentialsLogger.WarnContext(checkContext, "Binance rejected the stored key; robots paused", "environment", target.EnvironmentName, "user_id", target.UserIdentifier, "paused_robots", len(pausedRobots))
+}
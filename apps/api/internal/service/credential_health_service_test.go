@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+func TestIsKeyRejected(t *testing.T) {
+	cases := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   bool
+	}{
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, expected: true},
+		{name: "malformed key", statusCode: http.StatusBadRequest, body: `{"code":-2014,"msg":"API-key format invalid."}`, expected: true},
+		{name: "invalid key, IP or permissions", statusCode: http.StatusBadRequest, body: `{"code": -2015, "msg": "Invalid API-key, IP, or permissions for action."}`, expected: true},
+		{name: "bad signature", statusCode: http.StatusBadRequest, body: `{"code":-1022,"msg":"Signature for this request is not valid."}`, expected: true},
+		{name: "clock skew", statusCode: http.StatusBadRequest, body: `{"code":-1021,"msg":"Timestamp for this request is outside of the recvWindow."}`},
+		{name: "bad request without a code", statusCode: http.StatusBadRequest, body: "Bad Request"},
+		{name: "rejection code on another status", statusCode: http.StatusForbidden, body: `{"code":-2015}`},
+		{name: "WAF block", statusCode: http.StatusForbidden},
+		{name: "IP ban", statusCode: http.StatusTeapot},
+		{name: "rate limit", statusCode: http.StatusTooManyRequests, body: `{"code":-1003,"msg":"Too many requests."}`},
+		{name: "internal error", statusCode: http.StatusInternalServerError},
+		{name: "outage", statusCode: http.StatusServiceUnavailable, body: `{"code":-2015}`},
+	}
+	for _, testCase := range cases {
+		rejection := &BinanceCredentialRejectedError{StatusCode: testCase.statusCode, ResponseBody: testCase.body}
+		if rejected := rejection.IsKeyRejected(); rejected != testCase.expected {
+			t.Errorf("%s: IsKeyRejected = %t, expected %t", testCase.name, rejected, testCase.expected)
+		}
+		if rejected := isBinanceKeyRejection(fmt.Errorf("validating: %w", rejection)); rejected != testCase.expected {
+			t.Errorf("%s: a wrapped rejection gave %t, expected %t", testCase.name, rejected, testCase.expected)
+		}
+	}
+	if isBinanceKeyRejection(errors.New("connection reset by peer")) {
+		t.Error("a network error was taken for a rejected key")
+	}
+}
+
+// memoryCredentialHealth records the health results; other methods are not used.
+type memoryCredentialHealth struct {
+	repository.CredentialHealthRepository
+	statuses []string
+}
+
+func (healthRepository *memoryCredentialHealth) RecordCredentialHealth(_ context.Context, _ int64, status string, _ string) error {
+	healthRepository.statuses = append(healthRepository.statuses, status)
+	return nil
+}
+
+// memoryRobotRepository holds one user's robots; other methods are not used.
+type memoryRobotRepository struct {
+	repository.TradingRobotRepository
+	robots      []domain.TradingRobot
+	updateError error
+}
+
+func (robotRepository *memoryRobotRepository) ListRobotsForUser(context.Context, int64, string) ([]domain.TradingRobot, error) {
+	return append([]domain.TradingRobot(nil), robotRepository.robots...), nil
+}
+
+func (robotRepository *memoryRobotRepository) UpdateRobotForUser(_ context.Context, _ int64, robot domain.TradingRobot) error {
+	if robotRepository.updateError != nil {
+		return robotRepository.updateError
+	}
+	for index := range robotRepository.robots {
+		if robotRepository.robots[index].Identifier == robot.Identifier {
+			robotRepository.robots[index] = robot
+		}
+	}
+	return nil
+}
+
+// rejectingBinance answers the time endpoint and refuses every signed request with -2015.
+func rejectingBinance(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == binanceTimeEndpointPath {
+			_, _ = responseWriter.Write([]byte(`{"serverTime":1700000000000}`))
+			return
+		}
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		_, _ = responseWriter.Write([]byte(`{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestRejectedKeyAlertsOnlyWhenItTurnsInvalid checks that the first rejection pauses the robots,
+// stores INVALID and raises one alert, that later rejections only record the check, and that a pause
+// that fails leaves the key to be caught again.
+func TestRejectedKeyAlertsOnlyWhenItTurnsInvalid(t *testing.T) {
+	secretCipher, cipherError := security.NewSecretCipher(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	if cipherError != nil {
+		t.Fatal(cipherError)
+	}
+	encryptedKey, _ := secretCipher.EncryptString("api-key")
+	encryptedSecret, _ := secretCipher.EncryptString("api-secret")
+	server := rejectingBinance(t)
+
+	cases := []struct {
+		name             string
+		previousStatus   string
+		updateError      error
+		expectedStatuses []string
+		expectedAlerts   int
+		expectedEnabled  bool
+	}{
+		{name: "healthy key rejected", previousStatus: domain.CredentialHealthHealthy, expectedStatuses: []string{domain.CredentialHealthInvalid}, expectedAlerts: 1},
+		{name: "invalid key still rejected", previousStatus: domain.CredentialHealthInvalid, expectedStatuses: []string{domain.CredentialHealthInvalid}, expectedEnabled: true},
+		{name: "pause fails", previousStatus: domain.CredentialHealthHealthy, updateError: errors.New("deadlock detected"), expectedEnabled: true},
+	}
+	for _, testCase := range cases {
+		healthRepository := &memoryCredentialHealth{}
+		robotRepository := &memoryRobotRepository{robots: []domain.TradingRobot{{Identifier: 1, Name: "BTC dip", IsEnabled: true}}, updateError: testCase.updateError}
+		publisher := &recordingPublisher{}
+		auditLog := &memoryAuditLog{}
+		robotService := NewRobotService(robotRepository, nil, immediateTransactionRunner{}, publisher, NewAuditService(auditLog))
+		healthService := NewCredentialHealthService(healthRepository, secretCipher, robotService, immediateTransactionRunner{}, publisher)
+
+		healthService.CheckCredential(context.Background(), domain.CredentialHealthCheckTarget{
+			Identifier: 9, UserIdentifier: 7, EnvironmentName: domain.BinanceEnvironmentProduction, APIBaseURL: server.URL,
+			APIKey: encryptedKey, APISecret: encryptedSecret, HealthStatus: testCase.previousStatus,
+		})
+
+		if fmt.Sprint(healthRepository.statuses) != fmt.Sprint(testCase.expectedStatuses) {
+			t.Errorf("%s: expected recorded statuses %v, got %v", testCase.name, testCase.expectedStatuses, healthRepository.statuses)
+		}
+		alerts := 0
+		for _, event := range publisher.published {
+			if raised, isRaised := event.(events.TradeEventRaised); isRaised && raised.TradeEvent.EventType == domain.TradeEventCredentialInvalid {
+				alerts++
+				if len(raised.TradeEvent.PausedRobotNames) != 1 || raised.TradeEvent.PausedRobotNames[0] != "BTC dip" {
+					t.Errorf("%s: expected the alert to name the paused robot, got %v", testCase.name, raised.TradeEvent.PausedRobotNames)
+				}
+			}
+		}
+		if alerts != testCase.expectedAlerts {
+			t.Errorf("%s: expected %d alerts, got %d", testCase.name, testCase.expectedAlerts, alerts)
+		}
+		if robotRepository.robots[0].IsEnabled != testCase.expectedEnabled {
+			t.Errorf("%s: robot enabled=%t, expected %t", testCase.name, robotRepository.robots[0].IsEnabled, testCase.expectedEnabled)
+		}
+		if expectedAudits := testCase.expectedAlerts; len(auditLog.entries) != expectedAudits {
+			t.Errorf("%s: expected %d robot audit entries, got %v", testCase.name, expectedAudits, auditLog.actions())
+		}
+	}
+}
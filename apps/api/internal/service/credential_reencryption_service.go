@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+const credentialReencryptionBatchSize = 100
+
+// CredentialReencryptionProgress is reported after every batch and at the end of a run. Rows are
+// counted across every encrypted table.
+type CredentialReencryptionProgress struct {
+	PrimaryKeyIdentifier string
+	Total                int // rows when the run started
+	Scanned              int
+	Reencrypted          int
+	AlreadyCurrent       int
+	Skipped              int // changed by someone else while being re-encrypted
+	Failed               int // could not be decrypted, e.g. their key is no longer in the ring
+	StartedAt            time.Time
+	FinishedAt           time.Time
+}
+
+// CredentialReencryptionService migrates every value encrypted with the credentials keyring (Binance
+// credentials, notification channel settings, webhook signing secrets and TOTP secrets) to the
+// cipher's primary key after a rotation. Runs are idempotent: rows already under the primary key are
+// left alone.
+type CredentialReencryptionService struct {
+	repository repository.CiphertextRepository
+	cipher     *security.SecretCipher
+}
+
+func NewCredentialReencryptionService(repositoryInstance repository.CiphertextRepository, cipher *security.SecretCipher) *CredentialReencryptionService {
+	return &CredentialReencryptionService{repository: repositoryInstance, cipher: cipher}
+}
+
+// Run walks every encrypted table in id order and re-encrypts the rows not under the primary key.
+// report, when set, is called after each batch. A row that fails is counted and skipped; only database
+// errors abort the run.
+func (service *CredentialReencryptionService) Run(runContext context.Context, report func(CredentialReencryptionProgress)) (CredentialReencryptionProgress, error) {
+	progress := CredentialReencryptionProgress{StartedAt: time.Now()}
+	if service.cipher == nil {
+		return progress, ErrCredentialEncryptionUnavailable
+	}
+	progress.PrimaryKeyIdentifier = service.cipher.PrimaryKeyIdentifier()
+
+	tables := service.repository.CiphertextTables()
+	for _, table := range tables {
+		tableTotal, countError := service.repository.CountCiphertexts(runContext, table)
+		if countError != nil {
+			return progress, countError
+		}
+		progress.Total += tableTotal
+	}
+
+	for _, table := range tables {
+		var afterIdentifier int64
+		for {
+			ciphertexts, listError := service.repository.ListCiphertexts(runContext, table, afterIdentifier, credentialReencryptionBatchSize)
+			if listError != nil {
+				return progress, listError
+			}
+			if len(ciphertexts) == 0 {
+				break
+			}
+			for _, ciphertext := range ciphertexts {
+				afterIdentifier = ciphertext.Identifier
+				progress.Scanned++
+				if replaceError := service.reencryptRow(runContext, ciphertext, &progress); replaceError != nil {
+					return progress, replaceError
+				}
+			}
+			if report != nil {
+				report(progress)
+			}
+		}
+	}
+
+	progress.FinishedAt = time.Now()
+	if report != nil {
+		report(progress)
+	}
+	return progress, nil
+}
+
+// reencryptRow moves one row to the primary key and counts the outcome. Only a failed write is
+// returned.
+func (service *CredentialReencryptionService) reencryptRow(runContext context.Context, ciphertext domain.StoredCiphertext, progress *CredentialReencryptionProgress) error {
+	needsReencryption := false
+	for _, value := range ciphertext.Values {
+		needsReencryption = needsReencryption || service.cipher.NeedsReencryption(value)
+	}
+	if !needsReencryption {
+		progress.AlreadyCurrent++
+		return nil
+	}
+	reencryptedValues := make([]string, len(ciphertext.Values))
+	for index, value := range ciphertext.Values {
+		reencryptedValue, reencryptError := service.cipher.ReencryptString(value)
+		if reencryptError != nil {
+			progress.Failed++
+			credentialsLogger.ErrorContext(runContext, "re-encryption could not decrypt a stored value", "table", ciphertext.Table, "row_id", ciphertext.Identifier, "key_id", service.cipher.KeyIdentifierOf(value), "error", reencryptError)
+			return nil
+		}
+		reencryptedValues[index] = reencryptedValue
+	}
+	replaced, replaceError := service.repository.ReplaceCiphertext(runContext, ciphertext, reencryptedValues)
+	if replaceError != nil {
+		return replaceError
+	}
+	if replaced {
+		progress.Reencrypted++
+	} else {
+		progress.Skipped++
+	}
+	return nil
+}
+
+// CountByKeyIdentifier counts stored payloads per key id and table (an API key and its secret count
+// separately), so an operator can confirm nothing in any table still depends on a key before removing
+// it from the ring.
+func (service *CredentialReencryptionService) CountByKeyIdentifier(runContext context.Context) (map[string]map[string]int, error) {
+	if service.cipher == nil {
+		return nil, ErrCredentialEncryptionUnavailable
+	}
+	payloadCounts := make(map[string]map[string]int)
+	for _, table := range service.repository.CiphertextTables() {
+		var afterIdentifier int64
+		for {
+			ciphertexts, listError := service.repository.ListCiphertexts(runContext, table, afterIdentifier, credentialReencryptionBatchSize)
+			if listError != nil {
+				return nil, listError
+			}
+			if len(ciphertexts) == 0 {
+				break
+			}
+			for _, ciphertext := range ciphertexts {
+				afterIdentifier = ciphertext.Identifier
+				for _, value := range ciphertext.Values {
+					if value == "" {
+						continue
+					}
+					keyIdentifier := service.cipher.KeyIdentifierOf(value)
+					if payloadCounts[keyIdentifier] == nil {
+						payloadCounts[keyIdentifier] = make(map[string]int)
+					}
+					payloadCounts[keyIdentifier][table]++
+				}
+			}
+		}
+	}
+	return payloadCounts, nil
+}
+
+// StartBackgroundRun runs one pass shortly after startup, so restarting with a new primary key is
+// enough to migrate stored credentials. Progress goes to the log.
+func (service *CredentialReencryptionService) StartBackgroundRun(runContext context.Context, delay time.Duration) {
+	if service.cipher == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-runContext.Done():
+			return
+		case <-time.After(delay):
+		}
+		progress, runError := service.Run(runContext, nil)
+		if runError != nil {
+			credentialsLogger.Error("re-encryption stopped", "scanned", progress.Scanned, "total", progress.Total, "error", runError)
+			return
+		}
+		if progress.Reencrypted > 0 || progress.Failed > 0 || progress.Skipped > 0 {
+			credentialsLogger.Info("re-encryption finished", "reencrypted", progress.Reencrypted, "key_id", progress.PrimaryKeyIdentifier,
+				"already_current", progress.AlreadyCurrent, "skipped", progress.Skipped, "failed", progress.Failed, "total", progress.Total)
+		}
+	}()
+}
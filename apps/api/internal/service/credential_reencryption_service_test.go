@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"sort"
+	"testing"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/security"
+)
+
+// memoryCiphertextRepository is an in-memory repository.CiphertextRepository.
+type memoryCiphertextRepository struct {
+	tables []string
+	rows   map[string][]domain.StoredCiphertext
+}
+
+func (repository *memoryCiphertextRepository) CiphertextTables() []string {
+	return repository.tables
+}
+
+func (repository *memoryCiphertextRepository) CountCiphertexts(_ context.Context, table string) (int, error) {
+	return len(repository.rows[table]), nil
+}
+
+func (repository *memoryCiphertextRepository) ListCiphertexts(_ context.Context, table string, afterIdentifier int64, limit int) ([]domain.StoredCiphertext, error) {
+	var ciphertexts []domain.StoredCiphertext
+	for _, row := range repository.rows[table] {
+		if row.Identifier > afterIdentifier && len(ciphertexts) < limit {
+			ciphertexts = append(ciphertexts, domain.StoredCiphertext{Table: table, Identifier: row.Identifier, Values: append([]string(nil), row.Values...)})
+		}
+	}
+	return ciphertexts, nil
+}
+
+func (repository *memoryCiphertextRepository) ReplaceCiphertext(_ context.Context, current domain.StoredCiphertext, reencryptedValues []string) (bool, error) {
+	for index, row := range repository.rows[current.Table] {
+		if row.Identifier != current.Identifier {
+			continue
+		}
+		for valueIndex, value := range row.Values {
+			if value != current.Values[valueIndex] {
+				return false, nil
+			}
+		}
+		repository.rows[current.Table][index].Values = append([]string(nil), reencryptedValues...)
+		return true, nil
+	}
+	return false, nil
+}
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, 32)
+}
+
+// sealDirectly writes the pre-envelope format: base64(nonce || AES-GCM ciphertext) under the raw key.
+func sealDirectly(t *testing.T, key []byte, plainText string) string {
+	t.Helper()
+	blockCipher, blockError := aes.NewCipher(key)
+	if blockError != nil {
+		t.Fatal(blockError)
+	}
+	authenticatedCipher, gcmError := cipher.NewGCM(blockCipher)
+	if gcmError != nil {
+		t.Fatal(gcmError)
+	}
+	nonce := make([]byte, authenticatedCipher.NonceSize())
+	if _, randomError := rand.Read(nonce); randomError != nil {
+		t.Fatal(randomError)
+	}
+	return base64.StdEncoding.EncodeToString(authenticatedCipher.Seal(nonce, nonce, []byte(plainText), nil))
+}
+
+func newTestKeyring(t *testing.T, primaryKeyIdentifier string, keys map[string][]byte) *security.SecretCipher {
+	t.Helper()
+	identifiers := make([]string, 0, len(keys))
+	for identifier := range keys {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+	var keyringKeys []security.KeyringKey
+	for _, identifier := range identifiers {
+		keyringKeys = append(keyringKeys, security.KeyringKey{Identifier: identifier, Base64Key: base64.StdEncoding.EncodeToString(keys[identifier])})
+	}
+	providers, providerError := security.NewLocalKeyProviders(keyringKeys)
+	if providerError != nil {
+		t.Fatal(providerError)
+	}
+	secretCipher, cipherError := security.NewKeyringSecretCipher(providers, primaryKeyIdentifier)
+	if cipherError != nil {
+		t.Fatal(cipherError)
+	}
+	return secretCipher
+}
+
+// TestRotationMovesEveryTableAndFormatToThePrimaryKey stores values in each payload format the keyring
+// has ever written, spread over several tables, rotates to a new key and then checks that a keyring
+// holding only the new key reads everything back.
+func TestRotationMovesEveryTableAndFormatToThePrimaryKey(t *testing.T) {
+	legacyKey, secondKey, newKey := testKey(1), testKey(2), testKey(3)
+	previousKeyring := newTestKeyring(t, "2", map[string][]byte{security.LegacyKeyIdentifier: legacyKey, "2": secondKey})
+	envelopeUnderSecondKey, encryptError := previousKeyring.EncryptString(`{"bot_token":"123:abc"}`)
+	if encryptError != nil {
+		t.Fatal(encryptError)
+	}
+
+	expected := map[string][]string{
+		"binance_credentials":   {"api-key-1", "api-secret-1", "api-key-2", "api-secret-2"},
+		"notification_channels": {`{"bot_token":"123:abc"}`},
+		"webhook_endpoints":     {"whsec_1"},
+		"user_two_factor":       {"JBSWY3DPEHPK3PXP"},
+	}
+	ciphertextRepository := &memoryCiphertextRepository{
+		tables: []string{"binance_credentials", "notification_channels", "webhook_endpoints", "user_two_factor"},
+		rows: map[string][]domain.StoredCiphertext{
+			"binance_credentials": {
+				// Bare legacy payload next to an "<id>:<base64>" one in the same row.
+				{Identifier: 3, Values: []string{sealDirectly(t, legacyKey, "api-key-1"), "2:" + sealDirectly(t, secondKey, "api-secret-1")}},
+				{Identifier: 8, Values: []string{"1:" + sealDirectly(t, legacyKey, "api-key-2"), sealDirectly(t, legacyKey, "api-secret-2")}},
+			},
+			"notification_channels": {{Identifier: 5, Values: []string{envelopeUnderSecondKey}}},
+			"webhook_endpoints":     {{Identifier: 2, Values: []string{"2:" + sealDirectly(t, secondKey, "whsec_1")}}},
+			"user_two_factor":       {{Identifier: 42, Values: []string{sealDirectly(t, legacyKey, "JBSWY3DPEHPK3PXP")}}},
+		},
+	}
+
+	rotatedKeyring := newTestKeyring(t, "3", map[string][]byte{security.LegacyKeyIdentifier: legacyKey, "2": secondKey, "3": newKey})
+	reencryptionService := NewCredentialReencryptionService(ciphertextRepository, rotatedKeyring)
+	operationContext := context.Background()
+
+	progress, runError := reencryptionService.Run(operationContext, nil)
+	if runError != nil {
+		t.Fatal(runError)
+	}
+	if progress.Total != 5 || progress.Reencrypted != 5 || progress.Failed != 0 {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+
+	payloadCounts, countError := reencryptionService.CountByKeyIdentifier(operationContext)
+	if countError != nil {
+		t.Fatal(countError)
+	}
+	if len(payloadCounts) != 1 || len(payloadCounts["3"]) != 4 || payloadCounts["3"]["binance_credentials"] != 4 {
+		t.Errorf("expected every payload in every table under key 3, got %v", payloadCounts)
+	}
+
+	newKeyOnly := newTestKeyring(t, "3", map[string][]byte{"3": newKey})
+	for table, plainTexts := range expected {
+		var decrypted []string
+		for _, row := range ciphertextRepository.rows[table] {
+			for _, value := range row.Values {
+				plainText, decryptError := newKeyOnly.DecryptString(value)
+				if decryptError != nil {
+					t.Fatalf("%s row %d no longer decrypts without the old keys: %v", table, row.Identifier, decryptError)
+				}
+				decrypted = append(decrypted, plainText)
+			}
+		}
+		if len(decrypted) != len(plainTexts) {
+			t.Fatalf("%s: got %v, expected %v", table, decrypted, plainTexts)
+		}
+		for index := range plainTexts {
+			if decrypted[index] != plainTexts[index] {
+				t.Errorf("%s: got %q, expected %q", table, decrypted[index], plainTexts[index])
+			}
+		}
+	}
+
+	again, againError := reencryptionService.Run(operationContext, nil)
+	if againError != nil || again.Reencrypted != 0 || again.AlreadyCurrent != 5 {
+		t.Errorf("a second run should find everything current, got %+v, %v", again, againError)
+	}
+}
+
+// TestUnreadableRowsKeepTheirKeyInTheDryRun checks that a row under a key that is no longer in the
+// ring is reported, not silently dropped from the counts.
+func TestUnreadableRowsKeepTheirKeyInTheDryRun(t *testing.T) {
+	retiredKeyring := newTestKeyring(t, "old", map[string][]byte{"old": testKey(4)})
+	retiredPayload, encryptError := retiredKeyring.EncryptString("whsec_2")
+	if encryptError != nil {
+		t.Fatal(encryptError)
+	}
+	ciphertextRepository := &memoryCiphertextRepository{
+		tables: []string{"webhook_endpoints"},
+		rows:   map[string][]domain.StoredCiphertext{"webhook_endpoints": {{Identifier: 1, Values: []string{retiredPayload}}}},
+	}
+	reencryptionService := NewCredentialReencryptionService(ciphertextRepository, newTestKeyring(t, "new", map[string][]byte{"new": testKey(5)}))
+
+	progress, runError := reencryptionService.Run(context.Background(), nil)
+	if runError != nil || progress.Failed != 1 || progress.Reencrypted != 0 {
+		t.Fatalf("expected one failed row, got %+v, %v", progress, runError)
+	}
+	payloadCounts, _ := reencryptionService.CountByKeyIdentifier(context.Background())
+	if payloadCounts["old"]["webhook_endpoints"] != 1 {
+		t.Errorf("expected the webhook secret to stay counted under the retired key, got %v", payloadCounts)
+	}
+}
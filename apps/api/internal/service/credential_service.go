@@ -3,7 +3,6 @@ package service
 import (
         "context"
         "errors"
-        "log"
         "strings"
         "time"
 
@@ -43,7 +42,7 @@ func (service *CredentialService) InitializeCredentials(initializationContext co
 
         storedCredentials, loadCredentialsError := service.credentialRepository.LoadActiveCredentials(repositoryContext)
         if loadCredentialsError != nil {
-                log.Printf("Could not load saved credentials: %v", loadCredentialsError)
+                credentialsLogger.Error("could not load saved credentials", "error", loadCredentialsError)
         }
 
         if storedCredentials != nil {
@@ -56,7 +55,7 @@ func (service *CredentialService) InitializeCredentials(initializationContext co
                 if validationError == nil {
                         return
                 }
-                log.Printf("Saved credentials are invalid: %v", validationError)
+                credentialsLogger.Warn("saved credentials are invalid", "error", validationError)
         }
 
         if strings.TrimSpace(service.BinanceAPIKey) == "" || strings.TrimSpace(service.BinanceAPISecret) == "" {
@@ -67,7 +66,7 @@ func (service *CredentialService) InitializeCredentials(initializationContext co
         environmentName := domain.NormalizeBinanceEnvironment(service.defaultEnvironmentConfiguration.EnvironmentName)
         validationError := service.ValidateAndPersistCredentials(initializationContext, service.BinanceAPIKey, service.BinanceAPISecret, environmentName)
         if validationError != nil {
-                log.Printf("Environment credentials are invalid: %v", validationError)
+                credentialsLogger.Warn("environment credentials are invalid", "error", validationError)
         }
 }
 
@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"log"
 	"strconv"
 	"time"
 
@@ -42,7 +41,7 @@ func (service *DailyPurchaseAutomationService) startDailyPurchaseLoop(applicatio
 		select {
 		case <-applicationContext.Done():
 			timer.Stop()
-			log.Println("Daily purchase loop stopped")
+			automationLogger.Info("daily purchase loop stopped")
 			return
 		case <-timer.C:
 			service.executeDailyPurchase(applicationContext)
@@ -67,12 +66,12 @@ func (service *DailyPurchaseAutomationService) executeDailyPurchase(applicationC
 	defer settingsCancel()
 	settings, settingsError := service.DailyPurchaseSettingsService.GetActiveSettings(settingsContext)
 	if settingsError != nil {
-		log.Printf("Daily purchase settings lookup failed: %v", settingsError)
+		automationLogger.Error("daily purchase settings lookup failed", "error", settingsError)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase settings could not be loaded.")
 		return
 	}
 	if settings == nil {
-		log.Println("Daily purchase skipped because no settings are configured")
+		automationLogger.Info("daily purchase skipped because no settings are configured")
 		return
 	}
 
@@ -80,12 +79,12 @@ func (service *DailyPurchaseAutomationService) executeDailyPurchase(applicationC
 	defer priceLookupCancel()
 	currentPricePerUnit, priceLookupError := service.BinancePriceService.GetCurrentPrice(priceLookupContext, settings.TradingPairSymbol)
 	if priceLookupError != nil {
-		log.Printf("Daily purchase price lookup failed for %s: %v", settings.TradingPairSymbol, priceLookupError)
+		automationLogger.Error("daily purchase price lookup failed", "symbol", settings.TradingPairSymbol, "error", priceLookupError)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase failed: could not fetch current price.")
 		return
 	}
 	if currentPricePerUnit <= 0 {
-		log.Printf("Daily purchase price is not positive for %s: %f", settings.TradingPairSymbol, currentPricePerUnit)
+		automationLogger.Warn("daily purchase price is not positive", "symbol", settings.TradingPairSymbol, "price", currentPricePerUnit)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase failed: current price is unavailable.")
 		return
 	}
@@ -94,14 +93,14 @@ func (service *DailyPurchaseAutomationService) executeDailyPurchase(applicationC
 	defer buyExecutionCancel()
 	buyOrderResponse, buyError := service.BinanceTradingService.PlaceMarketBuyByQuote(buyExecutionContext, settings.TradingPairSymbol, settings.PurchaseAmount)
 	if buyError != nil {
-		log.Printf("Daily purchase buy failed for %s: %v", settings.TradingPairSymbol, buyError)
+		automationLogger.Error("daily purchase buy failed", "symbol", settings.TradingPairSymbol, "error", buyError)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase failed: "+buyError.Error())
 		return
 	}
 
 	executedQuantity, quantityParseError := strconv.ParseFloat(buyOrderResponse.ExecutedQty, 64)
 	if quantityParseError != nil || executedQuantity <= 0 {
-		log.Printf("Daily purchase returned invalid executed quantity for %s: %v", settings.TradingPairSymbol, quantityParseError)
+		automationLogger.Error("daily purchase returned an invalid executed quantity", "symbol", settings.TradingPairSymbol, "error", quantityParseError)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase failed: Binance returned an invalid executed quantity.")
 		return
 	}
@@ -145,13 +144,13 @@ func (service *DailyPurchaseAutomationService) executeDailyPurchase(applicationC
 	}
 	_, recordError := service.TradingOperationService.RecordPurchaseOperation(recordContext, operation)
 	if recordError != nil {
-		log.Printf("Daily purchase could not record operation for %s: %v", settings.TradingPairSymbol, recordError)
+		automationLogger.Error("daily purchase could not record the operation", "symbol", settings.TradingPairSymbol, "error", recordError)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase failed: could not record the operation.")
 		return
 	}
 
 	if sellError != nil {
-		log.Printf("Daily purchase sell order failed for %s: %v", settings.TradingPairSymbol, sellError)
+		automationLogger.Error("daily purchase sell order failed", "symbol", settings.TradingPairSymbol, "error", sellError)
 		service.logDailyPurchaseFailure(applicationContext, "Daily purchase completed but sell order failed: "+sellError.Error())
 		return
 	}
@@ -183,7 +182,7 @@ func (service *DailyPurchaseAutomationService) logDailyPurchaseExecution(applica
 	defer settingsCancel()
 	settings, settingsError := service.DailyPurchaseSettingsService.GetActiveSettings(settingsContext)
 	if settingsError != nil || settings == nil {
-		log.Printf("Daily purchase execution logging skipped because settings are unavailable: %v", settingsError)
+		automationLogger.Warn("daily purchase execution logging skipped because settings are unavailable", "error", settingsError)
 		return
 	}
 
@@ -201,6 +200,6 @@ func (service *DailyPurchaseAutomationService) logDailyPurchaseExecution(applica
 
 	_, logError := service.TradingScheduleService.LogExecution(executionContext, executionRecord)
 	if logError != nil {
-		log.Printf("Could not log daily purchase execution: %v", logError)
+		automationLogger.Error("could not log the daily purchase execution", "error", logError)
 	}
 }
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/email"
+)
+
+// digestCopy holds the localized strings of the portfolio digest email.
+type digestCopy struct {
+	dailySubject, weeklySubject string
+	heading                     string
+	openPositions               string
+	closedTrades                string
+	dailyPurchases              string
+	expiredTakeProfits          string
+	robots                      string
+	none                        string
+	unrealized, realized        string
+	priceUnavailable            string
+	enabled, paused             string
+	buttonLabel                 string
+	footer                      string
+}
+
+func portfolioDigestCopy(locale string) digestCopy {
+	switch normalizeEmailLocale(locale) {
+	case "en":
+		return digestCopy{
+			dailySubject: "Coin Hub — your daily digest", weeklySubject: "Coin Hub — your weekly digest",
+			heading:       "Your portfolio summary",
+			openPositions: "Open positions", closedTrades: "Trades closed", dailyPurchases: "Daily (DCA) purchases",
+			expiredTakeProfits: "Take-profits that expired", robots: "Robots",
+			none:       "None in this period.",
+			unrealized: "Unrealized PnL", realized: "Realized PnL",
+			priceUnavailable: "price unavailable",
+			enabled:          "running", paused: "paused",
+			buttonLabel: "Open dashboard",
+			footer:      "You receive this digest because you turned it on in Coin Hub. You can change the schedule or turn it off in your notification settings.",
+		}
+	case "es":
+		return digestCopy{
+			dailySubject: "Coin Hub — tu resumen diario", weeklySubject: "Coin Hub — tu resumen semanal",
+			heading:       "Resumen de tu portafolio",
+			openPositions: "Posiciones abiertas", closedTrades: "Operaciones cerradas", dailyPurchases: "Compras diarias (DCA)",
+			expiredTakeProfits: "Take-profits vencidos", robots: "Robots",
+			none:       "Nada en este período.",
+			unrealized: "PnL no realizado", realized: "PnL realizado",
+			priceUnavailable: "precio no disponible",
+			enabled:          "activo", paused: "pausado",
+			buttonLabel: "Abrir panel",
+			footer:      "Recibes este resumen porque lo activaste en Coin Hub. Puedes cambiar el horario o desactivarlo en tus ajustes de notificaciones.",
+		}
+	default:
+		return digestCopy{
+			dailySubject: "Coin Hub — seu resumo diário", weeklySubject: "Coin Hub — seu resumo semanal",
+			heading:       "Resumo da sua carteira",
+			openPositions: "Posições abertas", closedTrades: "Operações encerradas", dailyPurchases: "Compras diárias (DCA)",
+			expiredTakeProfits: "Take-profits expirados", robots: "Robôs",
+			none:       "Nada neste período.",
+			unrealized: "PnL não realizado", realized: "PnL realizado",
+			priceUnavailable: "preço indisponível",
+			enabled:          "ativo", paused: "pausado",
+			buttonLabel: "Abrir painel",
+			footer:      "Você recebe este resumo porque o ativou no Coin Hub. Você pode mudar o horário ou desativá-lo nas configurações de notificações.",
+		}
+	}
+}
+
+// digestSection is one titled list of lines, rendered both as plain text and HTML.
+type digestSection struct {
+	title string
+	lines []string
+}
+
+// portfolioDigestEmail renders the digest in the user's locale; times are shown in their time zone.
+func portfolioDigestEmail(locale string, report DigestReport, location *time.Location, appBaseURL string) email.Message {
+	copyText := portfolioDigestCopy(locale)
+	if location == nil {
+		location = time.UTC
+	}
+	subject := copyText.dailySubject
+	if report.Frequency == domain.DigestFrequencyWeekly {
+		subject = copyText.weeklySubject
+	}
+	if report.Environment != "" && report.Environment != domain.BinanceEnvironmentProduction {
+		subject += " (" + strings.ToLower(report.Environment) + ")"
+	}
+
+	sections := []digestSection{
+		{title: copyText.openPositions, lines: digestOpenPositionLines(report.OpenPositions, copyText)},
+		{title: copyText.closedTrades, lines: digestClosedTradeLines(report.ClosedTrades, location)},
+		{title: copyText.dailyPurchases, lines: digestExecutionLines(report.DailyPurchases, location, true)},
+		{title: copyText.expiredTakeProfits, lines: digestExecutionLines(report.ExpiredTakeProfits, location, false)},
+		{title: copyText.robots, lines: digestRobotLines(report.Robots, copyText)},
+	}
+	summary := fmt.Sprintf("%s: %+.2f · %s: %+.2f", copyText.unrealized, report.TotalUnrealized, copyText.realized, report.TotalRealized)
+	period := report.PeriodStart.In(location).Format("2006-01-02 15:04") + " → " + report.PeriodEnd.In(location).Format("2006-01-02 15:04 MST")
+
+	var textBuilder strings.Builder
+	textBuilder.WriteString(copyText.heading + "\n" + period + "\n" + summary + "\n")
+	for _, section := range sections {
+		textBuilder.WriteString("\n" + section.title + "\n")
+		if len(section.lines) == 0 {
+			textBuilder.WriteString("  " + copyText.none + "\n")
+		}
+		for _, line := range section.lines {
+			textBuilder.WriteString("  - " + line + "\n")
+		}
+	}
+	textBuilder.WriteString("\n" + appBaseURL + "\n\n" + copyText.footer)
+
+	return email.Message{
+		Subject:  subject,
+		TextBody: textBuilder.String(),
+		HTMLBody: digestEmailHTML(copyText, period, summary, sections, appBaseURL),
+	}
+}
+
+func digestOpenPositionLines(positions []DigestOpenPosition, copyText digestCopy) []string {
+	lines := make([]string, 0, len(positions))
+	for _, position := range positions {
+		if position.CurrentPrice == nil {
+			lines = append(lines, fmt.Sprintf("%s %s @ %s (%s)", position.TradingPairSymbol, formatTradeNumber(position.Quantity), formatTradeNumber(position.PurchasePrice), copyText.priceUnavailable))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s @ %s → %s (%+.2f)", position.TradingPairSymbol, formatTradeNumber(position.Quantity), formatTradeNumber(position.PurchasePrice), formatTradeNumber(*position.CurrentPrice), *position.UnrealizedProfit))
+	}
+	return lines
+}
+
+func digestClosedTradeLines(trades []DigestClosedTrade, location *time.Location) []string {
+	lines := make([]string, 0, len(trades))
+	for _, trade := range trades {
+		lines = append(lines, fmt.Sprintf("%s %s %s @ %s → %s (%+.2f)", trade.SoldAt.In(location).Format("01-02 15:04"), trade.TradingPairSymbol, formatTradeNumber(trade.Quantity), formatTradeNumber(trade.PurchasePrice), formatTradeNumber(trade.SellPrice), trade.RealizedProfit))
+	}
+	return lines
+}
+
+func digestExecutionLines(executions []domain.TradingOperationExecution, location *time.Location, withPrice bool) []string {
+	lines := make([]string, 0, len(executions))
+	for _, execution := range executions {
+		line := fmt.Sprintf("%s %s %s", execution.ExecutedAt.In(location).Format("01-02 15:04"), execution.TradingPairSymbol, formatTradeNumber(execution.Quantity))
+		if withPrice {
+			line += fmt.Sprintf(" @ %s (%.2f)", formatTradeNumber(execution.UnitPrice), execution.TotalValue)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func digestRobotLines(robots []domain.TradingRobot, copyText digestCopy) []string {
+	lines := make([]string, 0, len(robots))
+	for _, robot := range robots {
+		status := copyText.paused
+		if robot.IsEnabled {
+			status = copyText.enabled
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s): %s", robot.Name, robot.TradingPairSymbol, status))
+	}
+	return lines
+}
+
+// digestEmailHTML renders the digest with the same warm-dark + gold styling as brandedEmailHTML.
+func digestEmailHTML(copyText digestCopy, period string, summary string, sections []digestSection, appBaseURL string) string {
+	var sectionsHTML strings.Builder
+	for _, section := range sections {
+		sectionsHTML.WriteString(`<tr><td style="font-size:15px;font-weight:700;color:#ffd43b;padding:12px 0 6px;">` + html.EscapeString(section.title) + `</td></tr>`)
+		if len(section.lines) == 0 {
+			sectionsHTML.WriteString(`<tr><td style="font-size:14px;color:#a89f8c;">` + html.EscapeString(copyText.none) + `</td></tr>`)
+			continue
+		}
+		for _, line := range section.lines {
+			sectionsHTML.WriteString(`<tr><td style="font-size:14px;line-height:1.6;color:#e9e2cf;font-family:Consolas,monospace;">` + html.EscapeString(line) + `</td></tr>`)
+		}
+	}
+	safeLink := html.EscapeString(appBaseURL)
+	return fmt.Sprintf(`<!doctype html>
+<html><body style="margin:0;background:#1a1714;font-family:Segoe UI,Arial,sans-serif;color:#fff9db;">
+  <table role="presentation" width="100%%" cellpadding="0" cellspacing="0" style="background:#1a1714;padding:32px 0;">
+    <tr><td align="center">
+      <table role="presentation" width="560" cellpadding="0" cellspacing="0" style="max-width:560px;background:#231f1b;border:1px solid #3a332b;border-radius:14px;padding:32px;">
+        <tr><td style="font-size:22px;font-weight:800;color:#ffd43b;padding-bottom:16px;">Coin&nbsp;Hub</td></tr>
+        <tr><td style="font-size:18px;font-weight:700;padding-bottom:4px;">%s</td></tr>
+        <tr><td style="font-size:13px;color:#a89f8c;padding-bottom:8px;">%s</td></tr>
+        <tr><td style="font-size:15px;font-weight:700;color:#e9e2cf;padding-bottom:8px;">%s</td></tr>
+        %s
+        <tr><td style="padding:24px 0;"><a href="%s" style="display:inline-block;background:#ffd43b;color:#1a1714;font-weight:800;text-decoration:none;padding:12px 22px;border-radius:10px;">%s</a></td></tr>
+        <tr><td style="font-size:13px;line-height:1.6;color:#a89f8c;">%s</td></tr>
+      </table>
+    </td></tr>
+  </table>
+</body></html>`, html.EscapeString(copyText.heading), html.EscapeString(period), html.EscapeString(summary), sectionsHTML.String(), safeLink, html.EscapeString(copyText.buttonLabel), html.EscapeString(copyText.footer))
+}
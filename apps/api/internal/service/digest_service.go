@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/email"
+	"coin-alert/internal/repository"
+)
+
+// Digest validation errors surfaced to the API.
+var (
+	ErrDigestFrequencyInvalid = errors.New("the digest frequency must be DAILY or WEEKLY")
+	ErrDigestHourInvalid      = errors.New("the digest hour must be between 0 and 23")
+	ErrDigestWeekdayInvalid   = errors.New("the digest weekday must be between 0 (Sunday) and 6 (Saturday)")
+	ErrDigestTimeZoneInvalid  = errors.New("unknown time zone")
+)
+
+// digestCatchUpWindow is how late a digest may still go out after its slot (e.g. after a restart);
+// older slots are skipped rather than delivering a stale summary.
+const digestCatchUpWindow = 6 * time.Hour
+
+// DigestOpenPosition is an open operation valued at the current price.
+type DigestOpenPosition struct {
+	TradingPairSymbol string
+	Quantity          float64
+	PurchasePrice     float64
+	CurrentPrice      *float64 // nil when the price could not be fetched
+	UnrealizedProfit  *float64
+}
+
+// DigestClosedTrade is an operation sold during the digest period.
+type DigestClosedTrade struct {
+	TradingPairSymbol string
+	Quantity          float64
+	PurchasePrice     float64
+	SellPrice         float64
+	RealizedProfit    float64
+	SoldAt            time.Time
+}
+
+// DigestReport is everything a digest email shows. It is built from the same service calls the
+// dashboard endpoints use (operations, executions, robots, current price).
+type DigestReport struct {
+	Frequency          string
+	Environment        string
+	PeriodStart        time.Time
+	PeriodEnd          time.Time
+	OpenPositions      []DigestOpenPosition
+	ClosedTrades       []DigestClosedTrade
+	DailyPurchases     []domain.TradingOperationExecution
+	ExpiredTakeProfits []domain.TradingOperationExecution
+	Robots             []domain.TradingRobot
+	TotalUnrealized    float64
+	TotalRealized      float64
+}
+
+// DigestService manages the opt-in digest email and runs its scheduler.
+type DigestService struct {
+	subscriptionRepository repository.DigestSubscriptionRepository
+	userRepository         repository.UserRepository
+	credentialService      *UserCredentialService
+	tradingService         *UserTradingService
+	robotService           *RobotService
+	emailSender            email.Sender
+	appBaseURL             string
+}
+
+func NewDigestService(
+	subscriptionRepository repository.DigestSubscriptionRepository,
+	userRepository repository.UserRepository,
+	credentialService *UserCredentialService,
+	tradingService *UserTradingService,
+	robotService *RobotService,
+	emailSender email.Sender,
+	appBaseURL string,
+) *DigestService {
+	return &DigestService{
+		subscriptionRepository: subscriptionRepository,
+		userRepository:         userRepository,
+		credentialService:      credentialService,
+		tradingService:         tradingService,
+		robotService:           robotService,
+		emailSender:            emailSender,
+		appBaseURL:             strings.TrimRight(appBaseURL, "/"),
+	}
+}
+
+// GetSubscription returns the user's digest settings, or disabled defaults when never configured.
+func (service *DigestService) GetSubscription(operationContext context.Context, userIdentifier int64) (*domain.DigestSubscription, error) {
+	subscription, lookupError := service.subscriptionRepository.GetSubscriptionForUser(operationContext, userIdentifier)
+	if errors.Is(lookupError, repository.ErrDigestSubscriptionNotFound) {
+		return &domain.DigestSubscription{
+			UserIdentifier: userIdentifier,
+			Frequency:      domain.DigestFrequencyDaily,
+			LocalHour:      8,
+			Weekday:        int(time.Monday),
+			TimeZone:       "America/Sao_Paulo",
+			Locale:         "pt",
+			IsEnabled:      false,
+		}, nil
+	}
+	return subscription, lookupError
+}
+
+// SaveSubscription validates and stores the digest settings.
+func (service *DigestService) SaveSubscription(operationContext context.Context, subscription domain.DigestSubscription) (*domain.DigestSubscription, error) {
+	subscription.Frequency = strings.ToUpper(strings.TrimSpace(subscription.Frequency))
+	if subscription.Frequency != domain.DigestFrequencyDaily && subscription.Frequency != domain.DigestFrequencyWeekly {
+		return nil, ErrDigestFrequencyInvalid
+	}
+	if subscription.LocalHour < 0 || subscription.LocalHour > 23 {
+		return nil, ErrDigestHourInvalid
+	}
+	if subscription.Weekday < 0 || subscription.Weekday > 6 {
+		return nil, ErrDigestWeekdayInvalid
+	}
+	subscription.TimeZone = strings.TrimSpace(subscription.TimeZone)
+	if _, locationError := time.LoadLocation(subscription.TimeZone); locationError != nil || subscription.TimeZone == "" {
+		return nil, ErrDigestTimeZoneInvalid
+	}
+	subscription.Locale = normalizeEmailLocale(subscription.Locale)
+
+	if saveError := service.subscriptionRepository.SaveSubscriptionForUser(operationContext, subscription); saveError != nil {
+		return nil, saveError
+	}
+	return service.GetSubscription(operationContext, subscription.UserIdentifier)
+}
+
+// StartScheduler checks for due digests every interval until the context is cancelled.
+func (service *DigestService) StartScheduler(loopContext context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				service.sendDueDigests(loopContext, time.Now())
+			}
+		}
+	}()
+}
+
+func (service *DigestService) sendDueDigests(loopContext context.Context, now time.Time) {
+	subscriptions, listError := service.subscriptionRepository.ListEnabledSubscriptions(loopContext)
+	if listError != nil {
+		notificationLogger.ErrorContext(loopContext, "digest could not list subscriptions", "error", listError)
+		return
+	}
+	for _, subscription := range subscriptions {
+		if !isDigestDue(subscription, now) {
+			continue
+		}
+		if sendError := service.sendDigest(loopContext, subscription, now); sendError != nil {
+			notificationLogger.ErrorContext(loopContext, "could not send the digest", "user_id", subscription.UserIdentifier, "error", sendError)
+		}
+	}
+}
+
+func (service *DigestService) sendDigest(loopContext context.Context, subscription domain.DigestSubscription, now time.Time) error {
+	digestContext, cancel := context.WithTimeout(loopContext, 60*time.Second)
+	defer cancel()
+
+	user, userError := service.userRepository.FindByIdentifier(digestContext, subscription.UserIdentifier)
+	if userError != nil {
+		return userError
+	}
+	// Never mail an unconfirmed address; mark it as handled so the slot is not retried all day.
+	if !user.IsActive || !user.IsEmailVerified() {
+		return service.subscriptionRepository.MarkDigestSentForUser(digestContext, subscription.UserIdentifier, now)
+	}
+
+	report, reportError := service.BuildReport(digestContext, subscription.UserIdentifier, subscription.Frequency, now)
+	if reportError != nil {
+		return reportError
+	}
+	location, _ := time.LoadLocation(subscription.TimeZone)
+	message := portfolioDigestEmail(subscription.Locale, *report, location, service.appBaseURL)
+	message.To = user.Email
+	if sendError := service.emailSender.Send(digestContext, message); sendError != nil {
+		return sendError
+	}
+	return service.subscriptionRepository.MarkDigestSentForUser(digestContext, subscription.UserIdentifier, now)
+}
+
+// BuildReport gathers the digest data for the period ending at now, in the user's active environment.
+func (service *DigestService) BuildReport(operationContext context.Context, userIdentifier int64, frequency string, now time.Time) (*DigestReport, error) {
+	periodLength := 24 * time.Hour
+	if frequency == domain.DigestFrequencyWeekly {
+		periodLength = 7 * 24 * time.Hour
+	}
+	report := &DigestReport{
+		Frequency:   frequency,
+		Environment: service.credentialService.ActiveEnvironmentName(operationContext, userIdentifier),
+		PeriodStart: now.Add(-periodLength),
+		PeriodEnd:   now,
+	}
+
+	operations, operationsError := service.tradingService.ListOperations(operationContext, userIdentifier, 200)
+	if operationsError != nil {
+		return nil, operationsError
+	}
+	executions, executionsError := service.tradingService.ListExecutions(operationContext, userIdentifier, 200)
+	if executionsError != nil {
+		return nil, executionsError
+	}
+	robots, robotsError := service.robotService.ListRobots(operationContext, userIdentifier)
+	if robotsError != nil {
+		return nil, robotsError
+	}
+	report.Robots = robots
+
+	resolvePrice := service.priceResolver(operationContext, userIdentifier)
+	for _, operation := range operations {
+		switch {
+		case operation.Status == domain.TradingOperationStatusOpen:
+			position := DigestOpenPosition{
+				TradingPairSymbol: operation.TradingPairSymbol,
+				Quantity:          operation.QuantityPurchased,
+				PurchasePrice:     operation.PurchasePricePerUnit,
+			}
+			if currentPrice, pricePresent := resolvePrice(operation.TradingPairSymbol); pricePresent {
+				unrealizedProfit := (currentPrice - operation.PurchasePricePerUnit) * operation.QuantityPurchased
+				position.CurrentPrice = &currentPrice
+				position.UnrealizedProfit = &unrealizedProfit
+				report.TotalUnrealized += unrealizedProfit
+			}
+			report.OpenPositions = append(report.OpenPositions, position)
+		case operation.Status == domain.TradingOperationStatusSold && operation.SellTimestamp != nil && operation.SellPricePerUnit != nil &&
+			!operation.SellTimestamp.Before(report.PeriodStart):
+			realizedProfit := (*operation.SellPricePerUnit - operation.PurchasePricePerUnit) * operation.QuantityPurchased
+			report.ClosedTrades = append(report.ClosedTrades, DigestClosedTrade{
+				TradingPairSymbol: operation.TradingPairSymbol,
+				Quantity:          operation.QuantityPurchased,
+				PurchasePrice:     operation.PurchasePricePerUnit,
+				SellPrice:         *operation.SellPricePerUnit,
+				RealizedProfit:    realizedProfit,
+				SoldAt:            *operation.SellTimestamp,
+			})
+			report.TotalRealized += realizedProfit
+		}
+	}
+
+	for _, execution := range executions {
+		if execution.ExecutedAt.Before(report.PeriodStart) || !execution.Success {
+			continue
+		}
+		switch execution.OperationType {
+		case domain.TradingOperationTypeDailyBuy:
+			report.DailyPurchases = append(report.DailyPurchases, execution)
+		case domain.TradingOperationTypeSellExpire:
+			report.ExpiredTakeProfits = append(report.ExpiredTakeProfits, execution)
+		}
+	}
+	return report, nil
+}
+
+// priceResolver returns a per-report cached price lookup; without credentials it resolves nothing.
+func (service *DigestService) priceResolver(operationContext context.Context, userIdentifier int64) func(string) (float64, bool) {
+	environmentConfiguration, _ := service.credentialService.LoadActiveEnvironmentConfiguration(operationContext, userIdentifier)
+	if environmentConfiguration == nil {
+		return func(string) (float64, bool) { return 0, false }
+	}
+	priceService := NewBinancePriceService(*environmentConfiguration)
+	priceBySymbol := make(map[string]float64)
+	return func(tradingPairSymbol string) (float64, bool) {
+		if cachedPrice, present := priceBySymbol[tradingPairSymbol]; present {
+			return cachedPrice, true
+		}
+		currentPrice, priceError := priceService.GetCurrentPrice(operationContext, tradingPairSymbol)
+		if priceError != nil {
+			return 0, false
+		}
+		priceBySymbol[tradingPairSymbol] = currentPrice
+		return currentPrice, true
+	}
+}
+
+// isDigestDue reports whether the most recent scheduled slot (in the user's time zone) has passed,
+// has not been sent yet, and is still within the catch-up window.
+func isDigestDue(subscription domain.DigestSubscription, now time.Time) bool {
+	location, locationError := time.LoadLocation(subscription.TimeZone)
+	if locationError != nil {
+		return false
+	}
+	localNow := now.In(location)
+	slot := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), subscription.LocalHour, 0, 0, 0, location)
+	if slot.After(localNow) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	if subscription.Frequency == domain.DigestFrequencyWeekly {
+		for int(slot.Weekday()) != subscription.Weekday {
+			slot = slot.AddDate(0, 0, -1)
+		}
+	}
+	if now.Sub(slot) > digestCatchUpWindow {
+		return false
+	}
+	return subscription.LastSentAt == nil || subscription.LastSentAt.Before(slot)
+}
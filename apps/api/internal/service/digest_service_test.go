@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+func TestIsDigestDue(t *testing.T) {
+	instant := func(value string) time.Time {
+		parsed, parseError := time.Parse(time.RFC3339, value)
+		if parseError != nil {
+			t.Fatal(parseError)
+		}
+		return parsed
+	}
+	sentAt := func(value string) *time.Time {
+		parsed := instant(value)
+		return &parsed
+	}
+	newYorkDaily := domain.DigestSubscription{Frequency: domain.DigestFrequencyDaily, LocalHour: 8, TimeZone: "America/New_York"}
+	saoPauloMonday := domain.DigestSubscription{Frequency: domain.DigestFrequencyWeekly, LocalHour: 8, Weekday: int(time.Monday), TimeZone: "America/Sao_Paulo"}
+	withLastSent := func(subscription domain.DigestSubscription, lastSentAt *time.Time) domain.DigestSubscription {
+		subscription.LastSentAt = lastSentAt
+		return subscription
+	}
+	withHour := func(subscription domain.DigestSubscription, localHour int) domain.DigestSubscription {
+		subscription.LocalHour = localHour
+		return subscription
+	}
+	withWeekday := func(subscription domain.DigestSubscription, weekday time.Weekday) domain.DigestSubscription {
+		subscription.Weekday = int(weekday)
+		return subscription
+	}
+
+	cases := []struct {
+		name         string
+		subscription domain.DigestSubscription
+		now          string
+		expected     bool
+	}{
+		{name: "daily, after the slot in EDT", subscription: newYorkDaily, now: "2026-03-09T12:30:00Z", expected: true},
+		{name: "daily, before the slot in EST", subscription: newYorkDaily, now: "2026-03-06T12:30:00Z"},
+		{name: "daily, slot in the spring-forward gap", subscription: withHour(newYorkDaily, 2), now: "2026-03-08T07:30:00Z", expected: true},
+		{name: "daily, day after the spring-forward gap", subscription: withHour(newYorkDaily, 2), now: "2026-03-09T06:30:00Z", expected: true},
+		{name: "daily, same UTC hour before the clocks moved", subscription: newYorkDaily, now: "2026-03-07T12:30:00Z"},
+		{name: "daily, inside the catch-up window", subscription: newYorkDaily, now: "2026-03-09T17:59:00Z", expected: true},
+		{name: "daily, past the catch-up window", subscription: newYorkDaily, now: "2026-03-09T18:01:00Z"},
+		{name: "weekly, on the weekday", subscription: saoPauloMonday, now: "2026-10-19T11:30:00Z", expected: true},
+		{name: "weekly, the day after", subscription: saoPauloMonday, now: "2026-10-20T11:30:00Z"},
+		{name: "weekly, the day before", subscription: saoPauloMonday, now: "2026-10-18T11:30:00Z"},
+		{name: "weekly, Sunday slot", subscription: withWeekday(saoPauloMonday, time.Sunday), now: "2026-10-18T11:30:00Z", expected: true},
+		{name: "last sent before the slot", subscription: withLastSent(saoPauloMonday, sentAt("2026-10-19T10:59:00Z")), now: "2026-10-19T11:30:00Z", expected: true},
+		{name: "last sent at the slot", subscription: withLastSent(saoPauloMonday, sentAt("2026-10-19T11:00:00Z")), now: "2026-10-19T11:30:00Z"},
+		{name: "last sent after the slot", subscription: withLastSent(saoPauloMonday, sentAt("2026-10-19T11:10:00Z")), now: "2026-10-19T11:30:00Z"},
+		{name: "invalid time zone", subscription: domain.DigestSubscription{Frequency: domain.DigestFrequencyDaily, LocalHour: 8, TimeZone: "Mars/Olympus_Mons"}, now: "2026-03-09T12:30:00Z"},
+	}
+	for _, testCase := range cases {
+		if due := isDigestDue(testCase.subscription, instant(testCase.now)); due != testCase.expected {
+			t.Errorf("%s: isDigestDue = %t, expected %t", testCase.name, due, testCase.expected)
+		}
+	}
+}
+
+// noActiveCredentialRepository reports no connected account, so the report falls back to TESTNET
+// without prices; other methods are not used.
+type noActiveCredentialRepository struct {
+	repository.UserBinanceCredentialRepository
+}
+
+func (noActiveCredentialRepository) LoadActiveCredentialForUser(context.Context, int64) (*domain.BinanceCredentialRecord, error) {
+	return nil, nil
+}
+
+// listedOperationRepository returns fixed operations; other methods are not used.
+type listedOperationRepository struct {
+	repository.UserTradingOperationRepository
+	operations []domain.TradingOperation
+}
+
+func (operationRepository listedOperationRepository) ListRecentOperationsForUser(context.Context, int64, string, int) ([]domain.TradingOperation, error) {
+	return operationRepository.operations, nil
+}
+
+// listedExecutionRepository returns fixed executions; other methods are not used.
+type listedExecutionRepository struct {
+	repository.UserTradingOperationExecutionRepository
+	executions []domain.TradingOperationExecution
+}
+
+func (executionRepository listedExecutionRepository) ListRecentExecutionsForUser(context.Context, int64, string, int) ([]domain.TradingOperationExecution, error) {
+	return executionRepository.executions, nil
+}
+
+// TestBuildReportKeepsToThePeriod checks that closed trades and executions are taken only from the
+// digest's period, and that failed executions are left out.
+func TestBuildReportKeepsToThePeriod(t *testing.T) {
+	now := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(hours int) *time.Time {
+		moment := now.Add(-time.Duration(hours) * time.Hour)
+		return &moment
+	}
+	price := func(value float64) *float64 { return &value }
+	operations := []domain.TradingOperation{
+		{Identifier: 1, TradingPairSymbol: "BTCUSDT", QuantityPurchased: 1, PurchasePricePerUnit: 100, Status: domain.TradingOperationStatusOpen},
+		{Identifier: 2, TradingPairSymbol: "BTCUSDT", QuantityPurchased: 2, PurchasePricePerUnit: 100, Status: domain.TradingOperationStatusSold, SellPricePerUnit: price(110), SellTimestamp: hoursAgo(3)},
+		{Identifier: 3, TradingPairSymbol: "ETHUSDT", QuantityPurchased: 1, PurchasePricePerUnit: 50, Status: domain.TradingOperationStatusSold, SellPricePerUnit: price(55), SellTimestamp: hoursAgo(24)},
+		{Identifier: 4, TradingPairSymbol: "ETHUSDT", QuantityPurchased: 1, PurchasePricePerUnit: 50, Status: domain.TradingOperationStatusSold, SellPricePerUnit: price(40), SellTimestamp: hoursAgo(48)},
+		{Identifier: 5, TradingPairSymbol: "ETHUSDT", QuantityPurchased: 1, PurchasePricePerUnit: 50, Status: domain.TradingOperationStatusSold, SellPricePerUnit: price(70), SellTimestamp: hoursAgo(24 * 8)},
+		{Identifier: 6, TradingPairSymbol: "BTCUSDT", QuantityPurchased: 1, PurchasePricePerUnit: 100, Status: domain.TradingOperationStatusSold, SellTimestamp: hoursAgo(1)},
+		{Identifier: 7, TradingPairSymbol: "BTCUSDT", QuantityPurchased: 1, PurchasePricePerUnit: 100, Status: domain.TradingOperationStatusCanceled, SellPricePerUnit: price(120), SellTimestamp: hoursAgo(1)},
+	}
+	executions := []domain.TradingOperationExecution{
+		{Identifier: 11, OperationType: domain.TradingOperationTypeDailyBuy, ExecutedAt: *hoursAgo(2), Success: true},
+		{Identifier: 12, OperationType: domain.TradingOperationTypeDailyBuy, ExecutedAt: *hoursAgo(2)},
+		{Identifier: 13, OperationType: domain.TradingOperationTypeDailyBuy, ExecutedAt: *hoursAgo(30), Success: true},
+		{Identifier: 14, OperationType: domain.TradingOperationTypeSellExpire, ExecutedAt: *hoursAgo(5), Success: true},
+		{Identifier: 15, OperationType: domain.TradingOperationTypeSellExpire, ExecutedAt: *hoursAgo(24 * 8), Success: true},
+		{Identifier: 16, OperationType: domain.TradingOperationTypeSell, ExecutedAt: *hoursAgo(3), Success: true},
+	}
+	credentialService := NewUserCredentialService(noActiveCredentialRepository{}, nil, "", "", nil, nil)
+	tradingService := NewUserTradingService(credentialService, nil, listedOperationRepository{operations: operations}, listedExecutionRepository{executions: executions}, nil, nil, nil)
+	robotService := NewRobotService(&memoryRobotRepository{}, credentialService, nil, nil, nil)
+	digestService := NewDigestService(nil, nil, credentialService, tradingService, robotService, nil, "")
+
+	cases := []struct {
+		name                string
+		frequency           string
+		expectedClosed      []int64
+		expectedRealized    float64
+		expectedPurchases   []int64
+		expectedExpirations []int64
+	}{
+		{name: "daily", frequency: domain.DigestFrequencyDaily, expectedClosed: []int64{2, 3}, expectedRealized: 25, expectedPurchases: []int64{11}, expectedExpirations: []int64{14}},
+		{name: "weekly", frequency: domain.DigestFrequencyWeekly, expectedClosed: []int64{2, 3, 4}, expectedRealized: 15, expectedPurchases: []int64{11, 13}, expectedExpirations: []int64{14}},
+	}
+	closedIdentifierBySoldAt := make(map[time.Time]int64)
+	for _, operation := range operations {
+		if operation.SellTimestamp != nil {
+			closedIdentifierBySoldAt[*operation.SellTimestamp] = operation.Identifier
+		}
+	}
+	executionIdentifiers := func(listed []domain.TradingOperationExecution) []int64 {
+		identifiers := make([]int64, 0, len(listed))
+		for _, execution := range listed {
+			identifiers = append(identifiers, execution.Identifier)
+		}
+		return identifiers
+	}
+	sameIdentifiers := func(left []int64, right []int64) bool {
+		if len(left) != len(right) {
+			return false
+		}
+		for index := range left {
+			if left[index] != right[index] {
+				return false
+			}
+		}
+		return true
+	}
+	for _, testCase := range cases {
+		report, reportError := digestService.BuildReport(context.Background(), 7, testCase.frequency, now)
+		if reportError != nil {
+			t.Errorf("%s: %v", testCase.name, reportError)
+			continue
+		}
+		if report.Environment != domain.BinanceEnvironmentTestnet || !report.PeriodEnd.Equal(now) {
+			t.Errorf("%s: unexpected environment %q or period end %v", testCase.name, report.Environment, report.PeriodEnd)
+		}
+		if len(report.OpenPositions) != 1 || report.OpenPositions[0].CurrentPrice != nil {
+			t.Errorf("%s: expected one unpriced open position, got %+v", testCase.name, report.OpenPositions)
+		}
+		closed := make([]int64, 0, len(report.ClosedTrades))
+		for _, trade := range report.ClosedTrades {
+			closed = append(closed, closedIdentifierBySoldAt[trade.SoldAt])
+		}
+		if !sameIdentifiers(closed, testCase.expectedClosed) {
+			t.Errorf("%s: expected closed trades %v, got %v", testCase.name, testCase.expectedClosed, closed)
+		}
+		if report.TotalRealized != testCase.expectedRealized {
+			t.Errorf("%s: expected realized %v, got %v", testCase.name, testCase.expectedRealized, report.TotalRealized)
+		}
+		if purchases := executionIdentifiers(report.DailyPurchases); !sameIdentifiers(purchases, testCase.expectedPurchases) {
+			t.Errorf("%s: expected daily purchases %v, got %v", testCase.name, testCase.expectedPurchases, purchases)
+		}
+		if expirations := executionIdentifiers(report.ExpiredTakeProfits); !sameIdentifiers(expirations, testCase.expectedExpirations) {
+			t.Errorf("%s: expected expired take-profits %v, got %v", testCase.name, testCase.expectedExpirations, expirations)
+		}
+	}
+}
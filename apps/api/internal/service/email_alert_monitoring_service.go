@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"log"
 	"time"
 
 	"coin-alert/internal/domain"
@@ -40,7 +39,7 @@ func (service *EmailAlertMonitoringService) startMonitoringLoop(applicationConte
 	for {
 		select {
 		case <-applicationContext.Done():
-			log.Println("Email alert monitoring stopped")
+			notificationLogger.Info("email alert monitoring stopped")
 			return
 		case <-ticker.C:
 			service.evaluateActiveAlerts(applicationContext)
@@ -54,7 +53,7 @@ func (service *EmailAlertMonitoringService) evaluateActiveAlerts(applicationCont
 
 	activeAlerts, alertsError := service.EmailAlertRepository.ListActiveAlerts(alertsContext, 200)
 	if alertsError != nil {
-		log.Printf("Email alert lookup failed: %v", alertsError)
+		notificationLogger.Error("email alert lookup failed", "error", alertsError)
 		return
 	}
 	if len(activeAlerts) == 0 {
@@ -77,7 +76,7 @@ func (service *EmailAlertMonitoringService) evaluateActiveAlerts(applicationCont
 		triggerError := service.EmailAlertService.TriggerAlert(triggerContext, alert, currentPrice, triggerBoundary)
 		triggerCancel()
 		if triggerError != nil {
-			log.Printf("Email alert trigger failed for %s: %v", alert.TradingPairOrCurrency, triggerError)
+			notificationLogger.Error("email alert trigger failed", "symbol", alert.TradingPairOrCurrency, "error", triggerError)
 		}
 	}
 }
@@ -94,7 +93,7 @@ func (service *EmailAlertMonitoringService) fetchCurrentPricesForAlerts(applicat
 		currentPrice, priceError := service.BinancePriceService.GetCurrentPrice(priceContext, symbol)
 		priceCancel()
 		if priceError != nil {
-			log.Printf("Email alert price lookup failed for %s: %v", symbol, priceError)
+			notificationLogger.Error("email alert price lookup failed", "symbol", symbol, "error", priceError)
 			continue
 		}
 		currentPrices[symbol] = currentPrice
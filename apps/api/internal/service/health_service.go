@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"coin-alert/internal/logging"
+	"coin-alert/internal/security"
+)
+
+// Health statuses, of one component and of the service as a whole.
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+	HealthStatusFailing  = "failing"
+)
+
+// HealthCheck probes one component. When a Critical component fails the service is failing (not
+// ready); any other failure only degrades it. Only the database is critical: an instance that can
+// still serve sign-ins, dashboards and settings should stay in the load balancer.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Probe    func(probeContext context.Context) error
+}
+
+// ComponentHealth is the latest result of one HealthCheck. LastError survives recovery, so a flapping
+// dependency stays visible after it comes back.
+type ComponentHealth struct {
+	Name        string
+	Critical    bool
+	Status      string
+	Latency     time.Duration
+	CheckedAt   time.Time
+	LastError   string
+	LastErrorAt *time.Time
+}
+
+// HealthReport is the overall status and every component's result.
+type HealthReport struct {
+	Status     string
+	CheckedAt  time.Time
+	Components []ComponentHealth
+}
+
+// HealthService runs the readiness checks. Results are reused for resultLifetime so frequent probes
+// from a load balancer do not hammer Postgres, Binance or the scraper.
+type HealthService struct {
+	checks         []HealthCheck
+	probeTimeout   time.Duration
+	resultLifetime time.Duration
+
+	mutex      sync.Mutex
+	components []ComponentHealth
+	checkedAt  time.Time
+}
+
+func NewHealthService(checks ...HealthCheck) *HealthService {
+	components := make([]ComponentHealth, len(checks))
+	for index, check := range checks {
+		components[index] = ComponentHealth{Name: check.Name, Critical: check.Critical}
+	}
+	return &HealthService{checks: checks, probeTimeout: 5 * time.Second, resultLifetime: 10 * time.Second, components: components}
+}
+
+// Check returns the current report, running every probe again (in parallel) when the last results
+// are older than the result lifetime.
+func (service *HealthService) Check(checkContext context.Context) HealthReport {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if service.checkedAt.IsZero() || time.Since(service.checkedAt) >= service.resultLifetime {
+		service.runChecks(context.WithoutCancel(checkContext))
+	}
+	components := append([]ComponentHealth(nil), service.components...)
+	return HealthReport{Status: overallHealthStatus(components), CheckedAt: service.checkedAt, Components: components}
+}
+
+func (service *HealthService) runChecks(checkContext context.Context) {
+	var waitGroup sync.WaitGroup
+	for index := range service.checks {
+		waitGroup.Add(1)
+		go func(index int) {
+			defer waitGroup.Done()
+			probeContext, cancel := context.WithTimeout(checkContext, service.probeTimeout)
+			defer cancel()
+			startedAt := time.Now()
+			probeError := service.checks[index].Probe(probeContext)
+			component := &service.components[index]
+			component.Latency = time.Since(startedAt)
+			component.CheckedAt = startedAt
+			component.Status = HealthStatusOK
+			if probeError != nil {
+				component.Status = HealthStatusFailing
+				if !component.Critical {
+					component.Status = HealthStatusDegraded
+				}
+				component.LastError = logging.RedactString(probeError.Error())
+				component.LastErrorAt = &startedAt
+			}
+		}(index)
+	}
+	waitGroup.Wait()
+	service.checkedAt = time.Now()
+}
+
+func overallHealthStatus(components []ComponentHealth) string {
+	status := HealthStatusOK
+	for _, component := range components {
+		switch component.Status {
+		case HealthStatusFailing:
+			return HealthStatusFailing
+		case HealthStatusDegraded:
+			status = HealthStatusDegraded
+		}
+	}
+	return status
+}
+
+// DatabaseHealthCheck pings Postgres; nothing works without it.
+func DatabaseHealthCheck(database interface {
+	PingContext(pingContext context.Context) error
+}) HealthCheck {
+	return HealthCheck{Name: "database", Critical: true, Probe: database.PingContext}
+}
+
+// CipherHealthCheck verifies that credential encryption is configured and that the keyring can seal
+// and open a value (for Vault this also proves Vault is reachable). configurationError is the error
+// the cipher was built with, if any. Without it Binance keys cannot be used, which degrades trading
+// but not the rest of the app.
+func CipherHealthCheck(secretCipher *security.SecretCipher, configurationError error) HealthCheck {
+	return HealthCheck{Name: "cipher", Probe: func(context.Context) error {
+		if secretCipher == nil {
+			if configurationError != nil {
+				return fmt.Errorf("credential encryption is not configured: %w", configurationError)
+			}
+			return errors.New("credential encryption is not configured")
+		}
+		sealedValue, encryptError := secretCipher.EncryptString("health-check")
+		if encryptError != nil {
+			return encryptError
+		}
+		openedValue, decryptError := secretCipher.DecryptString(sealedValue)
+		if decryptError != nil {
+			return decryptError
+		}
+		if openedValue != "health-check" {
+			return errors.New("the keyring returned a different value than it sealed")
+		}
+		return nil
+	}}
+}
+
+// BinanceHealthCheck calls the public ping endpoint of one Binance environment. Binance being down
+// degrades trading but the rest of the app keeps working.
+func BinanceHealthCheck(name string, apiBaseURL string) HealthCheck {
+	httpClient := newBinanceHTTPClient(5 * time.Second)
+	return HealthCheck{Name: name, Probe: func(probeContext context.Context) error {
+		return expectHTTPSuccess(probeContext, httpClient, apiBaseURL+"/api/v3/ping")
+	}}
+}
+
+// HealthCheck calls the scraper's test endpoint; without it only the portfolio pages fail.
+func (client *PortfolioScraperClient) HealthCheck() HealthCheck {
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	return HealthCheck{Name: "scraper", Probe: func(probeContext context.Context) error {
+		return expectHTTPSuccess(probeContext, httpClient, client.baseURL+"/test")
+	}}
+}
+
+// HealthCheck reports a stalled automation loop: one that has not finished a pass within three of its
+// intervals. It degrades the service: taking the instance out of the load balancer would not restart
+// the loop, only cut users off from their dashboards.
+func (worker *AutomationWorker) HealthCheck() HealthCheck {
+	return HealthCheck{Name: "automation_worker", Probe: func(context.Context) error {
+		now := time.Now()
+		if heartbeatAge := now.Sub(time.Unix(0, worker.monitorHeartbeat.Load())); heartbeatAge > 3*worker.monitorInterval {
+			return fmt.Errorf("the monitor loop has not completed a pass for %s", heartbeatAge.Round(time.Second))
+		}
+		if heartbeatAge := now.Sub(time.Unix(0, worker.dailyPurchaseHeartbeat.Load())); heartbeatAge > 3*worker.dailyPurchaseInterval {
+			return fmt.Errorf("the daily purchase loop has not completed a pass for %s", heartbeatAge.Round(time.Second))
+		}
+		return nil
+	}}
+}
+
+func expectHTTPSuccess(probeContext context.Context, httpClient *http.Client, endpoint string) error {
+	probeRequest, buildError := http.NewRequestWithContext(probeContext, http.MethodGet, endpoint, nil)
+	if buildError != nil {
+		return buildError
+	}
+	probeResponse, responseError := httpClient.Do(probeRequest)
+	if responseError != nil {
+		return responseError
+	}
+	probeResponse.Body.Close()
+	if probeResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered with status %d", endpoint, probeResponse.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthServiceStatusAndLastError(t *testing.T) {
+	var scraperError error = errors.New("scraper unreachable")
+	var databaseError error
+	healthService := NewHealthService(
+		HealthCheck{Name: "database", Critical: true, Probe: func(context.Context) error { return databaseError }},
+		HealthCheck{Name: "scraper", Probe: func(context.Context) error { return scraperError }},
+	)
+
+	report := healthService.Check(context.Background())
+	if report.Status != HealthStatusDegraded || report.Components[1].Status != HealthStatusDegraded || report.Components[1].LastError != "scraper unreachable" {
+		t.Fatalf("a failing optional component should degrade the service: %+v", report)
+	}
+
+	scraperError = nil
+	databaseError = errors.New("connection refused")
+	healthService.resultLifetime = 0
+	report = healthService.Check(context.Background())
+	if report.Status != HealthStatusFailing || report.Components[0].Status != HealthStatusFailing {
+		t.Fatalf("a failing critical component should fail the service: %+v", report)
+	}
+	if report.Components[1].Status != HealthStatusOK || report.Components[1].LastError != "scraper unreachable" || report.Components[1].LastErrorAt == nil {
+		t.Fatalf("a recovered component should be ok and keep its last error: %+v", report.Components[1])
+	}
+}
+
+func TestOnlyTheDatabaseFailsReadiness(t *testing.T) {
+	worker := &AutomationWorker{monitorInterval: time.Second, dailyPurchaseInterval: time.Second}
+	checks := []HealthCheck{
+		DatabaseHealthCheck(pingFunc(func(context.Context) error { return nil })),
+		CipherHealthCheck(nil, errors.New("no key")),
+		worker.HealthCheck(),
+	}
+	for _, check := range checks {
+		if expected := check.Name == "database"; check.Critical != expected {
+			t.Errorf("%s: critical=%t, expected %t", check.Name, check.Critical, expected)
+		}
+	}
+
+	report := NewHealthService(checks...).Check(context.Background())
+	if report.Status != HealthStatusDegraded {
+		t.Errorf("a missing cipher and a stalled worker should only degrade the service, got %+v", report)
+	}
+}
+
+// pingFunc adapts a function to the database interface DatabaseHealthCheck expects.
+type pingFunc func(context.Context) error
+
+func (ping pingFunc) PingContext(pingContext context.Context) error {
+	return ping(pingContext)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// IdempotencyKeyLifetime is how long the first response to an Idempotency-Key is replayed.
+const IdempotencyKeyLifetime = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyInFlight = errors.New("a request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyReused   = errors.New("this Idempotency-Key was already used for a different request")
+)
+
+// IdempotencyService makes retried order-placing requests safe: the first request with a key runs and
+// its response is kept; retries with the same key get that response instead of placing a second order.
+type IdempotencyService struct {
+	store repository.IdempotencyRepository
+}
+
+func NewIdempotencyService(store repository.IdempotencyRepository) *IdempotencyService {
+	return &IdempotencyService{store: store}
+}
+
+// IdempotencyFingerprint identifies a request by method, path and body, so a key can only be replayed
+// for the request it was first sent with.
+func IdempotencyFingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Claim starts a request with key. It returns nil, nil when the caller should run the request and then
+// call Complete or Release; the stored record when a previous request with the key already finished;
+// ErrIdempotencyKeyInFlight while that request is still running; and ErrIdempotencyKeyReused when the
+// key was sent with a different request.
+func (service *IdempotencyService) Claim(operationContext context.Context, userIdentifier int64, key string, requestFingerprint string) (*domain.IdempotencyRecord, error) {
+	existing, claimError := service.store.ClaimIdempotencyKey(operationContext, userIdentifier, key, requestFingerprint, time.Now().Add(IdempotencyKeyLifetime))
+	if claimError != nil || existing == nil {
+		return nil, claimError
+	}
+	if existing.RequestFingerprint != requestFingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	// A claim whose request never finished (e.g. the process stopped mid-order) also stays in flight
+	// until it expires: whether the order went through is unknown, so it must not be sent again.
+	if !existing.IsCompleted() {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+	return existing, nil
+}
+
+// Complete stores the response of a claimed request for replay.
+func (service *IdempotencyService) Complete(operationContext context.Context, userIdentifier int64, key string, responseStatus int, responseLocation string, responseBody []byte) error {
+	return service.store.CompleteIdempotencyKey(operationContext, userIdentifier, key, responseStatus, responseLocation, responseBody)
+}
+
+// Release forgets a claimed request that did not run, so the client may retry it with the same key.
+func (service *IdempotencyService) Release(operationContext context.Context, userIdentifier int64, key string) error {
+	return service.store.ReleaseIdempotencyKey(operationContext, userIdentifier, key)
+}
+
+// StartCleanup periodically drops expired keys. It returns immediately; the loop stops when the
+// supplied context is cancelled.
+func (service *IdempotencyService) StartCleanup(loopContext context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				cleanupContext, cancel := context.WithTimeout(loopContext, 30*time.Second)
+				if _, deletionError := service.store.DeleteExpiredIdempotencyKeys(cleanupContext); deletionError != nil {
+					idempotencyLogger.Error("idempotency key cleanup failed", "error", deletionError)
+				}
+				cancel()
+			}
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IPLocator turns an IP address into an approximate, human-readable place ("Lisbon, Portugal").
+// An empty result means the place is unknown.
+type IPLocator interface {
+	Locate(lookupContext context.Context, ipAddress string) (string, error)
+}
+
+// HTTPIPLocator asks a JSON geolocation endpoint about an IP. urlTemplate contains "{ip}", e.g.
+// "https://ipapi.co/{ip}/json/"; the common field names of such services (city, region/regionName,
+// country_name/country) are understood.
+type HTTPIPLocator struct {
+	urlTemplate string
+	httpClient  *http.Client
+}
+
+// NewHTTPIPLocator returns nil when no endpoint is configured, which disables the lookup.
+func NewHTTPIPLocator(urlTemplate string) *HTTPIPLocator {
+	if strings.TrimSpace(urlTemplate) == "" {
+		return nil
+	}
+	return &HTTPIPLocator{urlTemplate: urlTemplate, httpClient: &http.Client{Timeout: 3 * time.Second}}
+}
+
+type ipLocationResponse struct {
+	City        string `json:"city"`
+	Region      string `json:"region"`
+	RegionName  string `json:"regionName"`
+	CountryName string `json:"country_name"`
+	Country     string `json:"country"`
+}
+
+func (locator *HTTPIPLocator) Locate(lookupContext context.Context, ipAddress string) (string, error) {
+	parsedAddress := net.ParseIP(ipAddress)
+	if parsedAddress == nil {
+		return "", nil
+	}
+	if parsedAddress.IsLoopback() || parsedAddress.IsPrivate() || parsedAddress.IsLinkLocalUnicast() {
+		return "Local network", nil
+	}
+	lookupURL := strings.ReplaceAll(locator.urlTemplate, "{ip}", url.PathEscape(parsedAddress.String()))
+	lookupRequest, requestError := http.NewRequestWithContext(lookupContext, http.MethodGet, lookupURL, nil)
+	if requestError != nil {
+		return "", requestError
+	}
+	response, responseError := locator.httpClient.Do(lookupRequest)
+	if responseError != nil {
+		return "", responseError
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("geolocation lookup returned HTTP %d", response.StatusCode)
+	}
+	var location ipLocationResponse
+	if decodeError := json.NewDecoder(io.LimitReader(response.Body, 64<<10)).Decode(&location); decodeError != nil {
+		return "", decodeError
+	}
+	region := firstNonEmpty(location.RegionName, location.Region)
+	country := firstNonEmpty(location.CountryName, location.Country)
+	parts := make([]string, 0, 3)
+	for _, part := range []string{location.City, region, country} {
+		if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
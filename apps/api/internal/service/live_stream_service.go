@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/repository"
+)
+
+// Live stream event types, sent as the SSE `event:` field.
+const (
+	LiveEventPrice     = "price"
+	LiveEventOperation = "operation"
+	LiveEventExecution = "execution"
+	LiveEventRobot     = "robot"        // a robot was created, updated or deleted
+	LiveEventRobotTick = "robot_status" // the worker's latest view of a robot
+)
+
+const (
+	// liveStreamBacklogSize is how many recent events per user are kept for Last-Event-ID resume.
+	liveStreamBacklogSize = 200
+	// liveStreamSubscriberBuffer bounds a connection's queue; a connection that falls further behind
+	// is closed and resumes from its Last-Event-ID on reconnect.
+	liveStreamSubscriberBuffer = 64
+	// liveStreamMaxConnectionsPerUser bounds one user's open streams (tabs, devices), each of which
+	// holds a goroutine and a buffer for as long as it stays open.
+	liveStreamMaxConnectionsPerUser = 5
+)
+
+// ErrTooManyLiveStreams is returned by Subscribe when the user already has the maximum open streams.
+var ErrTooManyLiveStreams = errors.New("too many live streams are open for this account")
+
+// LiveEvent is one message on a user's live stream.
+type LiveEvent struct {
+	Identifier int64
+	Type       string
+	Data       json.RawMessage
+}
+
+type livePricePayload struct {
+	Symbol   string    `json:"symbol"`
+	Price    float64   `json:"price"`
+	PricedAt time.Time `json:"priced_at"`
+}
+
+type liveRobotStatusPayload struct {
+	ID             int64     `json:"id"`
+	Symbol         string    `json:"symbol"`
+	Name           string    `json:"name"`
+	Environment    string    `json:"environment"`
+	IsEnabled      bool      `json:"is_enabled"`
+	OpenPositions  int       `json:"open_positions"`
+	StopLossActive bool      `json:"stop_loss_active"`
+	LastCheckedAt  time.Time `json:"last_checked_at"`
+}
+
+// LiveStreamService fans out per-user live updates to the dashboard's SSE connections: operation and
+// execution changes arrive from the event bus, robot status from the automation worker, and price
+// ticks from its own loop, which only runs for users with an open connection. Events are kept in a
+// short in-memory backlog per user so a reconnecting client resumes from its Last-Event-ID.
+type LiveStreamService struct {
+	credentialService   *UserCredentialService
+	robotRepository     repository.TradingRobotRepository
+	operationRepository repository.UserTradingOperationRepository
+
+	mutex          sync.Mutex
+	lastIdentifier int64
+	backlogs       map[int64][]LiveEvent
+	subscribers    map[int64]map[chan LiveEvent]struct{}
+}
+
+func NewLiveStreamService(credentialService *UserCredentialService, robotRepository repository.TradingRobotRepository, operationRepository repository.UserTradingOperationRepository) *LiveStreamService {
+	return &LiveStreamService{
+		credentialService:   credentialService,
+		robotRepository:     robotRepository,
+		operationRepository: operationRepository,
+		// Seeding from the clock keeps ids increasing across restarts, so a Last-Event-ID from before a
+		// restart simply finds no backlog instead of skipping new events.
+		lastIdentifier: time.Now().UnixMicro(),
+		backlogs:       make(map[int64][]LiveEvent),
+		subscribers:    make(map[int64]map[chan LiveEvent]struct{}),
+	}
+}
+
+// Subscribe registers a connection for the user. It returns the backlog newer than lastEventIdentifier
+// (0 = no resume), the channel of new events (closed if the connection falls behind) and the function
+// that unregisters it, or ErrTooManyLiveStreams when the user is at the connection limit.
+func (service *LiveStreamService) Subscribe(userIdentifier int64, lastEventIdentifier int64) ([]LiveEvent, <-chan LiveEvent, func(), error) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if len(service.subscribers[userIdentifier]) >= liveStreamMaxConnectionsPerUser {
+		return nil, nil, nil, ErrTooManyLiveStreams
+	}
+
+	var backlog []LiveEvent
+	if lastEventIdentifier > 0 {
+		for _, event := range service.backlogs[userIdentifier] {
+			if event.Identifier > lastEventIdentifier {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	channel := make(chan LiveEvent, liveStreamSubscriberBuffer)
+	if service.subscribers[userIdentifier] == nil {
+		service.subscribers[userIdentifier] = make(map[chan LiveEvent]struct{})
+	}
+	service.subscribers[userIdentifier][channel] = struct{}{}
+
+	unsubscribe := func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		if _, present := service.subscribers[userIdentifier][channel]; present {
+			delete(service.subscribers[userIdentifier], channel)
+			close(channel)
+		}
+		if len(service.subscribers[userIdentifier]) == 0 {
+			delete(service.subscribers, userIdentifier)
+		}
+	}
+	return backlog, channel, unsubscribe, nil
+}
+
+// IsConnected reports whether the user has at least one open stream.
+func (service *LiveStreamService) IsConnected(userIdentifier int64) bool {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	return len(service.subscribers[userIdentifier]) > 0
+}
+
+func (service *LiveStreamService) connectedUserIdentifiers() []int64 {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	userIdentifiers := make([]int64, 0, len(service.subscribers))
+	for userIdentifier := range service.subscribers {
+		userIdentifiers = append(userIdentifiers, userIdentifier)
+	}
+	return userIdentifiers
+}
+
+// Publish appends an event to the user's backlog and pushes it to every open connection.
+func (service *LiveStreamService) Publish(userIdentifier int64, eventType string, data interface{}) {
+	encodedData, encodeError := json.Marshal(data)
+	if encodeError != nil {
+		notificationLogger.Error("live stream could not encode an event", "event", eventType, "user_id", userIdentifier, "error", encodeError)
+		return
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.lastIdentifier++
+	event := LiveEvent{Identifier: service.lastIdentifier, Type: eventType, Data: encodedData}
+
+	// Price ticks and robot status are superseded by the next one, so they are not worth replaying.
+	if eventType != LiveEventPrice && eventType != LiveEventRobotTick {
+		backlog := append(service.backlogs[userIdentifier], event)
+		if len(backlog) > liveStreamBacklogSize {
+			backlog = backlog[len(backlog)-liveStreamBacklogSize:]
+		}
+		service.backlogs[userIdentifier] = backlog
+	}
+
+	for channel := range service.subscribers[userIdentifier] {
+		select {
+		case channel <- event:
+		default:
+			delete(service.subscribers[userIdentifier], channel)
+			close(channel)
+		}
+	}
+}
+
+// HandleEvent is the event bus subscriber that forwards operation, execution and robot changes.
+func (service *LiveStreamService) HandleEvent(_ context.Context, envelope events.Envelope) error {
+	switch typed := envelope.Event.(type) {
+	case events.OperationOpened:
+		service.Publish(envelope.UserIdentifier, LiveEventOperation, newWebhookOperationData(typed.Operation))
+	case events.OperationClosed:
+		service.Publish(envelope.UserIdentifier, LiveEventOperation, newWebhookOperationData(typed.Operation))
+	case events.OperationCancelled:
+		service.Publish(envelope.UserIdentifier, LiveEventOperation, newWebhookOperationData(typed.Operation))
+	case events.ExecutionLogged:
+		service.Publish(envelope.UserIdentifier, LiveEventExecution, newWebhookExecutionData(typed.Execution.Identifier, typed.Execution))
+	case events.RobotChanged:
+		service.Publish(envelope.UserIdentifier, LiveEventRobot, newWebhookRobotData(typed.Action, typed.Robot))
+	}
+	return nil
+}
+
+// PublishRobotStatus is called by the automation worker after each pass over a connected user.
+func (service *LiveStreamService) PublishRobotStatus(userIdentifier int64, robots []domain.TradingRobot, openOperations []domain.TradingOperation, checkedAt time.Time) {
+	openPositionsBySymbol := make(map[string]int)
+	for _, operation := range openOperations {
+		openPositionsBySymbol[operation.TradingPairSymbol]++
+	}
+	for _, robot := range robots {
+		service.Publish(userIdentifier, LiveEventRobotTick, liveRobotStatusPayload{
+			ID:             robot.Identifier,
+			Symbol:         robot.TradingPairSymbol,
+			Name:           robot.Name,
+			Environment:    robot.BinanceEnvironment,
+			IsEnabled:      robot.IsEnabled,
+			OpenPositions:  openPositionsBySymbol[robot.TradingPairSymbol],
+			LastCheckedAt:  checkedAt,
+			StopLossActive: robot.IsEnabled && robot.StopLossPercent != nil && *robot.StopLossPercent > 0,
+		})
+	}
+}
+
+// StartPriceTicker pushes the current price of every symbol the user trades (robots and open
+// positions in the active environment) every interval, for connected users only.
+func (service *LiveStreamService) StartPriceTicker(loopContext context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				for _, userIdentifier := range service.connectedUserIdentifiers() {
+					service.publishPrices(loopContext, userIdentifier)
+				}
+			}
+		}
+	}()
+}
+
+func (service *LiveStreamService) publishPrices(loopContext context.Context, userIdentifier int64) {
+	tickContext, cancel := context.WithTimeout(loopContext, 10*time.Second)
+	defer cancel()
+
+	environmentConfiguration, configurationError := service.credentialService.LoadActiveEnvironmentConfiguration(tickContext, userIdentifier)
+	if configurationError != nil || environmentConfiguration == nil {
+		return
+	}
+	environmentName := environmentConfiguration.EnvironmentName
+
+	var symbols []string
+	seenSymbols := make(map[string]bool)
+	addSymbol := func(symbol string) {
+		if symbol != "" && !seenSymbols[symbol] {
+			seenSymbols[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	robots, _ := service.robotRepository.ListRobotsForUser(tickContext, userIdentifier, environmentName)
+	for _, robot := range robots {
+		addSymbol(robot.TradingPairSymbol)
+	}
+	openOperations, _ := service.operationRepository.ListOpenOperationsForUser(tickContext, userIdentifier, environmentName)
+	for _, operation := range openOperations {
+		addSymbol(operation.TradingPairSymbol)
+	}
+
+	priceService := NewBinancePriceService(*environmentConfiguration)
+	for _, symbol := range symbols {
+		currentPrice, priceError := priceService.GetCurrentPrice(tickContext, symbol)
+		if priceError != nil {
+			continue
+		}
+		service.Publish(userIdentifier, LiveEventPrice, livePricePayload{Symbol: symbol, Price: currentPrice, PricedAt: time.Now()})
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLiveStreamResumesFromTheLastEventIdentifier(t *testing.T) {
+	liveStreamService := NewLiveStreamService(nil, nil, nil)
+	liveStreamService.Publish(7, LiveEventOperation, map[string]int{"id": 1})
+	liveStreamService.Publish(7, LiveEventPrice, livePricePayload{Symbol: "BTCUSDT", Price: 60000})
+	liveStreamService.Publish(7, LiveEventExecution, map[string]int{"id": 2})
+	liveStreamService.Publish(7, LiveEventRobotTick, liveRobotStatusPayload{ID: 3})
+	liveStreamService.Publish(7, LiveEventRobot, map[string]int{"id": 3})
+	liveStreamService.Publish(8, LiveEventOperation, map[string]int{"id": 4})
+
+	fullBacklog, _, unsubscribe, subscribeError := liveStreamService.Subscribe(7, 1)
+	if subscribeError != nil {
+		t.Fatal(subscribeError)
+	}
+	unsubscribe()
+	var types []string
+	for _, event := range fullBacklog {
+		types = append(types, event.Type)
+	}
+	if len(types) != 3 || types[0] != LiveEventOperation || types[1] != LiveEventExecution || types[2] != LiveEventRobot {
+		t.Fatalf("expected only the replayable events in the backlog, got %v", types)
+	}
+
+	cases := []struct {
+		name                string
+		lastEventIdentifier int64
+		expectedBacklog     int
+	}{
+		{name: "fresh connection", lastEventIdentifier: 0},
+		{name: "resume after the first event", lastEventIdentifier: fullBacklog[0].Identifier, expectedBacklog: 2},
+		{name: "resume after the last event", lastEventIdentifier: fullBacklog[2].Identifier},
+		{name: "resume across a price tick", lastEventIdentifier: fullBacklog[0].Identifier + 1, expectedBacklog: 2},
+	}
+	for _, testCase := range cases {
+		backlog, _, unsubscribe, subscribeError := liveStreamService.Subscribe(7, testCase.lastEventIdentifier)
+		if subscribeError != nil {
+			t.Errorf("%s: %v", testCase.name, subscribeError)
+			continue
+		}
+		unsubscribe()
+		if len(backlog) != testCase.expectedBacklog {
+			t.Errorf("%s: expected %d replayed events, got %d", testCase.name, testCase.expectedBacklog, len(backlog))
+		}
+		for _, event := range backlog {
+			if event.Identifier <= testCase.lastEventIdentifier {
+				t.Errorf("%s: replayed event %d the client already had", testCase.name, event.Identifier)
+			}
+		}
+	}
+}
+
+func TestLiveStreamClosesASubscriberThatFallsBehind(t *testing.T) {
+	liveStreamService := NewLiveStreamService(nil, nil, nil)
+	_, slowEvents, unsubscribeSlow, _ := liveStreamService.Subscribe(7, 0)
+	_, liveEvents, unsubscribe, _ := liveStreamService.Subscribe(7, 0)
+	defer unsubscribe()
+
+	for index := 0; index <= liveStreamSubscriberBuffer; index++ {
+		liveStreamService.Publish(7, LiveEventOperation, map[string]int{"id": index})
+		if index < liveStreamSubscriberBuffer {
+			<-liveEvents
+		}
+	}
+
+	received := 0
+	for range slowEvents {
+		received++
+	}
+	if received != liveStreamSubscriberBuffer {
+		t.Errorf("expected the %d buffered events before the close, got %d", liveStreamSubscriberBuffer, received)
+	}
+	if event, open := <-liveEvents; !open || event.Data == nil {
+		t.Error("the subscriber that kept up lost its event")
+	}
+	if !liveStreamService.IsConnected(7) {
+		t.Error("the subscriber that kept up was dropped")
+	}
+	// The handler still calls unsubscribe after seeing the close; it must not close the channel twice.
+	unsubscribeSlow()
+}
+
+func TestLiveStreamCapsConnectionsPerUser(t *testing.T) {
+	liveStreamService := NewLiveStreamService(nil, nil, nil)
+	var unsubscribes []func()
+	for index := 0; index < liveStreamMaxConnectionsPerUser; index++ {
+		_, _, unsubscribe, subscribeError := liveStreamService.Subscribe(7, 0)
+		if subscribeError != nil {
+			t.Fatalf("connection %d was refused: %v", index+1, subscribeError)
+		}
+		unsubscribes = append(unsubscribes, unsubscribe)
+	}
+
+	if _, _, _, subscribeError := liveStreamService.Subscribe(7, 0); !errors.Is(subscribeError, ErrTooManyLiveStreams) {
+		t.Errorf("expected ErrTooManyLiveStreams past the limit, got %v", subscribeError)
+	}
+	if _, _, unsubscribe, subscribeError := liveStreamService.Subscribe(8, 0); subscribeError != nil {
+		t.Errorf("another user was refused: %v", subscribeError)
+	} else {
+		unsubscribe()
+	}
+
+	unsubscribes[0]()
+	if _, _, unsubscribe, subscribeError := liveStreamService.Subscribe(7, 0); subscribeError != nil {
+		t.Errorf("a closed connection did not free its slot: %v", subscribeError)
+	} else {
+		unsubscribe()
+	}
+}
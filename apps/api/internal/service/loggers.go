@@ -0,0 +1,16 @@
+package service
+
+import "coin-alert/internal/logging"
+
+// Component loggers of the service package; each level can be set with LOG_LEVELS (e.g.
+// "automation=debug").
+var (
+	automationLogger   = logging.For("automation")
+	authLogger         = logging.For("auth")
+	credentialsLogger  = logging.For("credentials")
+	notificationLogger = logging.For("notifications")
+	auditLogger        = logging.For("audit")
+	emailLogger        = logging.For("email")
+	idempotencyLogger  = logging.For("idempotency")
+	tradingLogger      = logging.For("trading")
+)
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin-alert/internal/metrics"
+)
+
+// Trading and automation metrics exposed on /metrics.
+var (
+	ordersTotal = metrics.NewCounterVec("coinhub_orders_total",
+		"Orders sent to Binance by order type, environment and outcome (placed, rejected by Binance, or failed to reach it).",
+		"type", "environment", "outcome")
+	binanceRequestDuration = metrics.NewHistogramVec("coinhub_binance_request_duration_seconds",
+		"Latency of Binance REST requests.", metrics.DefaultDurationBuckets, "endpoint", "environment")
+	binanceRequestErrors = metrics.NewCounterVec("coinhub_binance_request_errors_total",
+		"Failed Binance REST requests by Binance error code, HTTP status (http_<status>) or network.",
+		"endpoint", "environment", "code")
+	stopLossTriggers = metrics.NewCounterVec("coinhub_stop_loss_triggers_total",
+		"Stop-loss market sells the worker placed.", "environment", "outcome")
+	takeProfitFills = metrics.NewCounterVec("coinhub_take_profit_fills_total",
+		"Take-profit limit sells found filled on Binance.", "environment")
+	workerLoopDuration = metrics.NewHistogramVec("coinhub_worker_loop_duration_seconds",
+		"Time one pass of an automation loop took.", metrics.DefaultDurationBuckets, "loop")
+	workerLoopLag = metrics.NewGaugeVec("coinhub_worker_loop_lag_seconds",
+		"How late the last pass of an automation loop started after its tick.", "loop")
+	workerLoopLastRun = metrics.NewGaugeVec("coinhub_worker_loop_last_run_timestamp_seconds",
+		"Unix time the last pass of an automation loop finished.", "loop")
+	dailyPurchasesTotal = metrics.NewCounterVec("coinhub_daily_purchases_total",
+		"Daily DCA purchases run, by outcome (succeeded, failed).", "environment", "outcome")
+	dailyPurchasesSkipped = metrics.NewCounterVec("coinhub_daily_purchases_skipped_total",
+		"Daily DCA purchases that were due but not run, by reason.", "reason")
+	scraperRequestDuration = metrics.NewHistogramVec("coinhub_scraper_request_duration_seconds",
+		"Latency of portfolio scraper requests by outcome.", []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 180}, "outcome")
+)
+
+// Automation loop names used as the loop label.
+const (
+	workerLoopMonitor       = "monitor"
+	workerLoopDailyPurchase = "daily_purchase"
+)
+
+// Order types used as the type label of coinhub_orders_total.
+const (
+	orderTypeMarketBuy  = "market_buy"
+	orderTypeLimitSell  = "limit_sell"
+	orderTypeMarketSell = "market_sell"
+)
+
+// environmentLabel turns a Binance environment name into a metric label ("testnet", "production").
+func environmentLabel(environmentName string) string {
+	return strings.ToLower(environmentName)
+}
+
+// observeWorkerLoop records one pass of an automation loop that was due at tickedAt.
+func observeWorkerLoop(loop string, tickedAt time.Time, run func()) {
+	startedAt := time.Now()
+	workerLoopLag.Set(startedAt.Sub(tickedAt).Seconds(), loop)
+	run()
+	workerLoopDuration.ObserveSince(startedAt, loop)
+	workerLoopLastRun.Set(float64(time.Now().Unix()), loop)
+}
+
+// sendOrder sends an order request and counts it as placed, rejected (Binance answered with an
+// error) or failed (Binance could not be reached or had an internal error).
+func (service *BinanceTradingService) sendOrder(orderRequest *http.Request, orderType string) (*http.Response, error) {
+	orderResponse, responseError := service.HTTPClient.Do(orderRequest)
+	outcome := "placed"
+	switch {
+	case responseError != nil || orderResponse.StatusCode >= http.StatusInternalServerError:
+		outcome = "failed"
+	case orderResponse.StatusCode != http.StatusOK:
+		outcome = "rejected"
+	}
+	ordersTotal.Inc(orderType, environmentLabel(service.EnvironmentConfiguration.EnvironmentName), outcome)
+	return orderResponse, responseError
+}
+
+// newBinanceHTTPClient returns the HTTP client the Binance services use; it times every request and
+// counts the errors.
+func newBinanceHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: binanceMetricsTransport{next: http.DefaultTransport}}
+}
+
+type binanceMetricsTransport struct {
+	next http.RoundTripper
+}
+
+func (transport binanceMetricsTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	endpoint := request.URL.Path
+	environment := hostEnvironmentLabel(request.URL.Host)
+	startedAt := time.Now()
+	response, responseError := transport.next.RoundTrip(request)
+	binanceRequestDuration.ObserveSince(startedAt, endpoint, environment)
+	if responseError != nil {
+		binanceRequestErrors.Inc(endpoint, environment, "network")
+		return response, responseError
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		binanceRequestErrors.Inc(endpoint, environment, binanceErrorCode(response))
+	}
+	return response, nil
+}
+
+// hostEnvironmentLabel tells testnet and production hosts apart by name.
+func hostEnvironmentLabel(host string) string {
+	if strings.Contains(strings.ToLower(host), "testnet") {
+		return "testnet"
+	}
+	return "production"
+}
+
+// binanceErrorCode reads the {"code": -2010, ...} error body without consuming it for the caller.
+func binanceErrorCode(response *http.Response) string {
+	fallbackCode := "http_" + strconv.Itoa(response.StatusCode)
+	responseBody, readError := io.ReadAll(io.LimitReader(response.Body, 64<<10))
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(responseBody), response.Body), response.Body}
+	if readError != nil {
+		return fallbackCode
+	}
+	var errorPayload struct {
+		Code *int `json:"code"`
+	}
+	if json.Unmarshal(responseBody, &errorPayload) != nil || errorPayload.Code == nil {
+		return fallbackCode
+	}
+	return strconv.Itoa(*errorPayload.Code)
+}
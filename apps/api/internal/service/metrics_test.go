@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coin-alert/internal/domain"
+)
+
+func TestRejectedOrderIsCountedWithItsBinanceErrorCode(t *testing.T) {
+	binance := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusBadRequest)
+		_, _ = responseWriter.Write([]byte(`{"code":-2010,"msg":"Account has insufficient balance for requested action."}`))
+	}))
+	defer binance.Close()
+
+	tradingService := NewBinanceTradingService(domain.BinanceEnvironmentConfiguration{EnvironmentName: domain.BinanceEnvironmentTestnet, RESTBaseURL: binance.URL, APIKey: "key", APISecret: "secret"})
+	rejectedBefore := ordersTotal.Value(orderTypeMarketBuy, "testnet", "rejected")
+	codeBefore := binanceRequestErrors.Value("/api/v3/order", "production", "-2010")
+
+	_, buyError := tradingService.PlaceMarketBuyByQuote(context.Background(), "BTCUSDT", 10)
+	if buyError == nil || !strings.Contains(buyError.Error(), "insufficient balance") {
+		t.Fatalf("expected the Binance rejection to reach the caller intact, got %v", buyError)
+	}
+	if rejected := ordersTotal.Value(orderTypeMarketBuy, "testnet", "rejected"); rejected != rejectedBefore+1 {
+		t.Errorf("rejected orders: expected %v, got %v", rejectedBefore+1, rejected)
+	}
+	// The test server's host is not a testnet host, so the transport labels it production.
+	if codeCount := binanceRequestErrors.Value("/api/v3/order", "production", "-2010"); codeCount != codeBefore+1 {
+		t.Errorf("error code -2010: expected %v, got %v", codeBefore+1, codeCount)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/notification"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+// ErrNotificationChannelLimitReached is returned when a user tries to add more channels than allowed.
+var ErrNotificationChannelLimitReached = errors.New("you have reached the maximum number of notification channels")
+
+// MaximumNotificationChannelsPerUser bounds the fan-out of a single notification.
+const MaximumNotificationChannelsPerUser = 10
+
+// NotificationEventTest is the event type recorded for "send test message" deliveries.
+const NotificationEventTest = "TEST"
+
+// ErrUnknownNotificationEvent is returned when a preference names an event type that does not exist.
+var ErrUnknownNotificationEvent = errors.New("unknown notification event type")
+
+// NotificationChannelView is the non-sensitive description of a channel returned to the API.
+type NotificationChannelView struct {
+	Identifier  int64
+	ChannelType string
+	Label       string
+	IsEnabled   bool
+}
+
+// NotificationService manages a user's notification channels and delivers notifications through them.
+// It implements notification.Notifier. Each attempt is written to the delivery log.
+type NotificationService struct {
+	channelRepository    repository.NotificationChannelRepository
+	deliveryRepository   repository.NotificationDeliveryRepository
+	preferenceRepository repository.NotificationPreferenceRepository
+	userRepository       repository.UserRepository
+	cipher               *security.SecretCipher
+	channelFactory       *notification.ChannelFactory
+}
+
+func NewNotificationService(
+	channelRepository repository.NotificationChannelRepository,
+	deliveryRepository repository.NotificationDeliveryRepository,
+	preferenceRepository repository.NotificationPreferenceRepository,
+	userRepository repository.UserRepository,
+	cipher *security.SecretCipher,
+	channelFactory *notification.ChannelFactory,
+) *NotificationService {
+	return &NotificationService{
+		channelRepository:    channelRepository,
+		deliveryRepository:   deliveryRepository,
+		preferenceRepository: preferenceRepository,
+		userRepository:       userRepository,
+		cipher:               cipher,
+		channelFactory:       channelFactory,
+	}
+}
+
+func (service *NotificationService) ListChannels(operationContext context.Context, userIdentifier int64) ([]NotificationChannelView, error) {
+	channels, listError := service.channelRepository.ListChannelsForUser(operationContext, userIdentifier)
+	if listError != nil {
+		return nil, listError
+	}
+	views := make([]NotificationChannelView, 0, len(channels))
+	for _, channel := range channels {
+		views = append(views, notificationChannelView(channel))
+	}
+	return views, nil
+}
+
+// AddChannel validates and stores a new channel; the configuration document is encrypted at rest.
+func (service *NotificationService) AddChannel(operationContext context.Context, userIdentifier int64, channelType string, configuration notification.Configuration) (*NotificationChannelView, error) {
+	if service.cipher == nil {
+		return nil, ErrCredentialEncryptionUnavailable
+	}
+	normalizedType := strings.ToUpper(strings.TrimSpace(channelType))
+	if validationError := configuration.Validate(normalizedType); validationError != nil {
+		return nil, validationError
+	}
+
+	channelCount, countError := service.channelRepository.CountChannelsForUser(operationContext, userIdentifier)
+	if countError != nil {
+		return nil, countError
+	}
+	if channelCount >= MaximumNotificationChannelsPerUser {
+		return nil, ErrNotificationChannelLimitReached
+	}
+
+	encodedConfiguration, encodeError := json.Marshal(configuration)
+	if encodeError != nil {
+		return nil, encodeError
+	}
+	encryptedConfiguration, encryptionError := service.cipher.EncryptString(string(encodedConfiguration))
+	if encryptionError != nil {
+		return nil, encryptionError
+	}
+
+	channel := domain.NotificationChannel{
+		UserIdentifier: userIdentifier,
+		ChannelType:    normalizedType,
+		Label:          configuration.Label(normalizedType),
+		Configuration:  encryptedConfiguration,
+		IsEnabled:      true,
+	}
+	channelIdentifier, createError := service.channelRepository.CreateChannelForUser(operationContext, userIdentifier, channel)
+	if createError != nil {
+		return nil, createError
+	}
+	channel.Identifier = channelIdentifier
+	view := notificationChannelView(channel)
+	return &view, nil
+}
+
+func (service *NotificationService) SetChannelEnabled(operationContext context.Context, userIdentifier int64, channelIdentifier int64, isEnabled bool) error {
+	return service.channelRepository.SetChannelEnabledForUser(operationContext, userIdentifier, channelIdentifier, isEnabled)
+}
+
+func (service *NotificationService) DeleteChannel(operationContext context.Context, userIdentifier int64, channelIdentifier int64) error {
+	return service.channelRepository.DeleteChannelForUser(operationContext, userIdentifier, channelIdentifier)
+}
+
+// SendTestMessage delivers a fixed message through one channel (enabled or not) so the user can
+// confirm the setup. The result is logged like any other delivery.
+func (service *NotificationService) SendTestMessage(operationContext context.Context, userIdentifier int64, channelIdentifier int64) error {
+	channel, lookupError := service.channelRepository.GetChannelForUser(operationContext, userIdentifier, channelIdentifier)
+	if lookupError != nil {
+		return lookupError
+	}
+	user, userError := service.userRepository.FindByIdentifier(operationContext, userIdentifier)
+	if userError != nil {
+		return userError
+	}
+	message := notification.Message{
+		EventType: NotificationEventTest,
+		Subject:   "Coin Hub test notification",
+		Text:      "If you can read this, notifications from Coin Hub reach this channel.",
+	}
+	return service.deliverThroughChannel(operationContext, *channel, user.Email, message)
+}
+
+func (service *NotificationService) ListDeliveries(operationContext context.Context, userIdentifier int64, limit int) ([]domain.NotificationDelivery, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return service.deliveryRepository.ListRecentDeliveriesForUser(operationContext, userIdentifier, limit)
+}
+
+// ListEventPreferences returns the opt-in state of every trade event type (false when never set).
+func (service *NotificationService) ListEventPreferences(operationContext context.Context, userIdentifier int64) (map[string]bool, error) {
+	storedPreferences, listError := service.preferenceRepository.ListPreferencesForUser(operationContext, userIdentifier)
+	if listError != nil {
+		return nil, listError
+	}
+	preferences := make(map[string]bool, len(domain.TradeEventTypes))
+	for _, eventType := range domain.TradeEventTypes {
+		preferences[eventType] = storedPreferences[eventType]
+	}
+	return preferences, nil
+}
+
+func (service *NotificationService) SetEventPreference(operationContext context.Context, userIdentifier int64, eventType string, isEnabled bool) error {
+	normalizedType := strings.ToUpper(strings.TrimSpace(eventType))
+	if !domain.IsTradeEventType(normalizedType) {
+		return ErrUnknownNotificationEvent
+	}
+	return service.preferenceRepository.SetPreferenceForUser(operationContext, userIdentifier, normalizedType, isEnabled)
+}
+
+// RememberLocale saves the language trade alerts are written in for the user.
+func (service *NotificationService) RememberLocale(operationContext context.Context, userIdentifier int64, locale string) error {
+	return service.preferenceRepository.SaveLocaleForUser(operationContext, userIdentifier, normalizeEmailLocale(locale))
+}
+
+// Notify delivers the message to every enabled channel of the user. A failing channel does not stop
+// the others; the individual failures are joined into the returned error.
+func (service *NotificationService) Notify(operationContext context.Context, userIdentifier int64, message notification.Message) error {
+	channels, listError := service.channelRepository.ListEnabledChannelsForUser(operationContext, userIdentifier)
+	if listError != nil {
+		return listError
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	user, userError := service.userRepository.FindByIdentifier(operationContext, userIdentifier)
+	if userError != nil {
+		return userError
+	}
+
+	var deliveryErrors []error
+	for _, channel := range channels {
+		if deliveryError := service.deliverThroughChannel(operationContext, channel, user.Email, message); deliveryError != nil {
+			deliveryErrors = append(deliveryErrors, fmt.Errorf("%s channel %d: %w", strings.ToLower(channel.ChannelType), channel.Identifier, deliveryError))
+		}
+	}
+	return errors.Join(deliveryErrors...)
+}
+
+func (service *NotificationService) deliverThroughChannel(operationContext context.Context, channel domain.NotificationChannel, accountEmail string, message notification.Message) error {
+	deliveryError := service.buildAndDeliver(operationContext, channel, accountEmail, message)
+
+	channelIdentifier := channel.Identifier
+	delivery := domain.NotificationDelivery{
+		UserIdentifier:    channel.UserIdentifier,
+		ChannelIdentifier: &channelIdentifier,
+		ChannelType:       channel.ChannelType,
+		EventType:         message.EventType,
+		Subject:           message.Subject,
+		Success:           deliveryError == nil,
+	}
+	if deliveryError != nil {
+		errorMessage := deliveryError.Error()
+		delivery.ErrorMessage = &errorMessage
+	}
+	if recordError := service.deliveryRepository.RecordDelivery(operationContext, delivery); recordError != nil {
+		notificationLogger.ErrorContext(operationContext, "could not record the delivery", "channel_id", channel.Identifier, "error", recordError)
+	}
+	return deliveryError
+}
+
+func (service *NotificationService) buildAndDeliver(operationContext context.Context, channel domain.NotificationChannel, accountEmail string, message notification.Message) error {
+	if service.cipher == nil {
+		return ErrCredentialEncryptionUnavailable
+	}
+	decryptedConfiguration, decryptionError := service.cipher.DecryptString(channel.Configuration)
+	if decryptionError != nil {
+		return errors.New("could not decrypt the channel configuration")
+	}
+	var configuration notification.Configuration
+	if decodeError := json.Unmarshal([]byte(decryptedConfiguration), &configuration); decodeError != nil {
+		return errors.New("the channel configuration is corrupted")
+	}
+	adapter, buildError := service.channelFactory.Build(channel.ChannelType, configuration, accountEmail)
+	if buildError != nil {
+		return buildError
+	}
+	return adapter.Deliver(operationContext, message)
+}
+
+func notificationChannelView(channel domain.NotificationChannel) NotificationChannelView {
+	return NotificationChannelView{
+		Identifier:  channel.Identifier,
+		ChannelType: channel.ChannelType,
+		Label:       channel.Label,
+		IsEnabled:   channel.IsEnabled,
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// Personal access token errors surfaced to the API.
+var (
+	ErrPersonalAccessTokenInvalid      = errors.New("invalid, expired or revoked access token")
+	ErrPersonalAccessTokenNameInvalid  = errors.New("give the token a name of at most 80 characters")
+	ErrPersonalAccessTokenScopeInvalid = errors.New("choose at least one known scope")
+	ErrPersonalAccessTokenExpiry       = errors.New("the expiry must be between 1 and 365 days, or none")
+	ErrPersonalAccessTokenLimitReached = errors.New("you have reached the maximum number of active access tokens")
+)
+
+const (
+	// MaximumActivePersonalAccessTokensPerUser bounds how many usable tokens one account may hold.
+	MaximumActivePersonalAccessTokensPerUser = 20
+	maximumPersonalAccessTokenDays           = 365
+	maximumPersonalAccessTokenNameLength     = 80
+	// personalAccessTokenPrefix marks the raw value so leaked tokens are easy to spot in logs and
+	// secret scanners.
+	personalAccessTokenPrefix = "chpat_"
+	// personalAccessTokenDisplayLength is how much of the raw token is kept to identify it in lists.
+	personalAccessTokenDisplayLength = 12
+)
+
+// PersonalAccessTokenService issues, lists, revokes and resolves the tokens users create for scripts.
+// Like sessions, only the SHA-256 hash of a token is stored; the raw value is returned once.
+type PersonalAccessTokenService struct {
+	tokenRepository repository.PersonalAccessTokenRepository
+	auditRecorder   AuditRecorder
+}
+
+func NewPersonalAccessTokenService(tokenRepository repository.PersonalAccessTokenRepository, auditRecorder AuditRecorder) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{tokenRepository: tokenRepository, auditRecorder: auditRecorder}
+}
+
+func (service *PersonalAccessTokenService) ListTokens(operationContext context.Context, userIdentifier int64) ([]domain.PersonalAccessToken, error) {
+	return service.tokenRepository.ListTokensForUser(operationContext, userIdentifier)
+}
+
+// CreateToken stores a new token and returns it with its raw value. expiresInDays 0 means the token
+// never expires.
+func (service *PersonalAccessTokenService) CreateToken(operationContext context.Context, userIdentifier int64, name string, scopes []string, expiresInDays int) (*domain.PersonalAccessToken, string, error) {
+	trimmedName := strings.TrimSpace(name)
+	if trimmedName == "" || len(trimmedName) > maximumPersonalAccessTokenNameLength {
+		return nil, "", ErrPersonalAccessTokenNameInvalid
+	}
+	normalizedScopes, scopesError := normalizePersonalAccessTokenScopes(scopes)
+	if scopesError != nil {
+		return nil, "", scopesError
+	}
+	if expiresInDays < 0 || expiresInDays > maximumPersonalAccessTokenDays {
+		return nil, "", ErrPersonalAccessTokenExpiry
+	}
+	activeCount, countError := service.tokenRepository.CountActiveTokensForUser(operationContext, userIdentifier)
+	if countError != nil {
+		return nil, "", countError
+	}
+	if activeCount >= MaximumActivePersonalAccessTokensPerUser {
+		return nil, "", ErrPersonalAccessTokenLimitReached
+	}
+
+	randomPart, randomError := generateSessionToken()
+	if randomError != nil {
+		return nil, "", randomError
+	}
+	rawToken := personalAccessTokenPrefix + randomPart
+	token := domain.PersonalAccessToken{
+		UserIdentifier: userIdentifier,
+		Name:           trimmedName,
+		TokenHash:      hashSessionToken(rawToken),
+		TokenPrefix:    rawToken[:personalAccessTokenDisplayLength],
+		Scopes:         normalizedScopes,
+		CreatedAt:      time.Now(),
+	}
+	if expiresInDays > 0 {
+		expiresAt := token.CreatedAt.Add(time.Duration(expiresInDays) * 24 * time.Hour)
+		token.ExpiresAt = &expiresAt
+	}
+	tokenIdentifier, createError := service.tokenRepository.CreateToken(operationContext, token)
+	if createError != nil {
+		return nil, "", createError
+	}
+	token.Identifier = tokenIdentifier
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           domain.AuditActionAccessTokenCreated,
+		TargetType:       "access_token",
+		TargetIdentifier: tokenIdentifier,
+		After:            map[string]interface{}{"name": token.Name, "scopes": token.Scopes, "expires_at": token.ExpiresAt},
+	})
+	return &token, rawToken, nil
+}
+
+func (service *PersonalAccessTokenService) RevokeToken(operationContext context.Context, userIdentifier int64, tokenIdentifier int64) error {
+	if revokeError := service.tokenRepository.RevokeTokenForUser(operationContext, userIdentifier, tokenIdentifier); revokeError != nil {
+		return revokeError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           domain.AuditActionAccessTokenRevoked,
+		TargetType:       "access_token",
+		TargetIdentifier: tokenIdentifier,
+	})
+	return nil
+}
+
+// Authenticate resolves a raw bearer token to its usable token row and records the use. Unknown,
+// revoked and expired tokens, and tokens of disabled accounts, all yield ErrPersonalAccessTokenInvalid.
+func (service *PersonalAccessTokenService) Authenticate(lookupContext context.Context, rawToken string, ipAddress string) (*domain.PersonalAccessToken, error) {
+	if !strings.HasPrefix(rawToken, personalAccessTokenPrefix) {
+		return nil, ErrPersonalAccessTokenInvalid
+	}
+	token, lookupError := service.tokenRepository.FindTokenByHash(lookupContext, hashSessionToken(rawToken))
+	if lookupError != nil {
+		return nil, lookupError
+	}
+	if token == nil || !token.IsUsable(time.Now()) {
+		return nil, ErrPersonalAccessTokenInvalid
+	}
+	if recordError := service.tokenRepository.RecordTokenUse(lookupContext, token.Identifier, ipAddress); recordError != nil {
+		authLogger.ErrorContext(lookupContext, "could not record the access token use", "access_token_id", token.Identifier, "error", recordError)
+	}
+	return token, nil
+}
+
+func normalizePersonalAccessTokenScopes(scopes []string) ([]string, error) {
+	normalizedScopes := make([]string, 0, len(scopes))
+	for _, knownScope := range domain.PersonalAccessTokenScopes {
+		for _, requestedScope := range scopes {
+			if strings.TrimSpace(requestedScope) == knownScope {
+				normalizedScopes = append(normalizedScopes, knownScope)
+				break
+			}
+		}
+	}
+	if len(normalizedScopes) == 0 {
+		return nil, ErrPersonalAccessTokenScopeInvalid
+	}
+	for _, requestedScope := range scopes {
+		if !(domain.PersonalAccessToken{Scopes: normalizedScopes}).HasScope(strings.TrimSpace(requestedScope)) {
+			return nil, ErrPersonalAccessTokenScopeInvalid
+		}
+	}
+	return normalizedScopes, nil
+}
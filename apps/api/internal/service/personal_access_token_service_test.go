@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+// memoryTokenRepository is an in-memory repository.PersonalAccessTokenRepository.
+type memoryTokenRepository struct {
+	tokens []domain.PersonalAccessToken
+	uses   int
+}
+
+func (repository *memoryTokenRepository) ListTokensForUser(_ context.Context, userIdentifier int64) ([]domain.PersonalAccessToken, error) {
+	var tokens []domain.PersonalAccessToken
+	for _, token := range repository.tokens {
+		if token.UserIdentifier == userIdentifier {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (repository *memoryTokenRepository) CountActiveTokensForUser(_ context.Context, userIdentifier int64) (int, error) {
+	activeCount := 0
+	for _, token := range repository.tokens {
+		if token.UserIdentifier == userIdentifier && token.IsUsable(time.Now()) {
+			activeCount++
+		}
+	}
+	return activeCount, nil
+}
+
+func (repository *memoryTokenRepository) CreateToken(_ context.Context, token domain.PersonalAccessToken) (int64, error) {
+	token.Identifier = int64(len(repository.tokens) + 1)
+	repository.tokens = append(repository.tokens, token)
+	return token.Identifier, nil
+}
+
+func (repository *memoryTokenRepository) FindTokenByHash(_ context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	for _, token := range repository.tokens {
+		if token.TokenHash == tokenHash {
+			found := token
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (repository *memoryTokenRepository) RecordTokenUse(context.Context, int64, string) error {
+	repository.uses++
+	return nil
+}
+
+func (repository *memoryTokenRepository) RevokeTokenForUser(_ context.Context, userIdentifier int64, tokenIdentifier int64) error {
+	for index := range repository.tokens {
+		if repository.tokens[index].Identifier == tokenIdentifier && repository.tokens[index].UserIdentifier == userIdentifier {
+			revokedAt := time.Now()
+			repository.tokens[index].RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return errors.New("personal access token not found")
+}
+
+func TestCreateTokenRefusesInvalidRequests(t *testing.T) {
+	cases := []struct {
+		name          string
+		tokenName     string
+		scopes        []string
+		expiresInDays int
+		activeTokens  int
+		expected      error
+	}{
+		{name: "valid", tokenName: "backup script", scopes: []string{domain.TokenScopeReadOperations}, expiresInDays: 30},
+		{name: "never expires", tokenName: "cron", scopes: []string{" trade ", domain.TokenScopeRobotsWrite}},
+		{name: "blank name", tokenName: "   ", scopes: []string{domain.TokenScopeTrade}, expected: ErrPersonalAccessTokenNameInvalid},
+		{name: "long name", tokenName: strings.Repeat("n", maximumPersonalAccessTokenNameLength+1), scopes: []string{domain.TokenScopeTrade}, expected: ErrPersonalAccessTokenNameInvalid},
+		{name: "no scope", tokenName: "cron", expected: ErrPersonalAccessTokenScopeInvalid},
+		{name: "unknown scope", tokenName: "cron", scopes: []string{"admin"}, expected: ErrPersonalAccessTokenScopeInvalid},
+		{name: "unknown scope next to a known one", tokenName: "cron", scopes: []string{domain.TokenScopeReadOperations, "write:everything"}, expected: ErrPersonalAccessTokenScopeInvalid},
+		{name: "scope with different case", tokenName: "cron", scopes: []string{"TRADE"}, expected: ErrPersonalAccessTokenScopeInvalid},
+		{name: "negative expiry", tokenName: "cron", scopes: []string{domain.TokenScopeTrade}, expiresInDays: -1, expected: ErrPersonalAccessTokenExpiry},
+		{name: "expiry over a year", tokenName: "cron", scopes: []string{domain.TokenScopeTrade}, expiresInDays: maximumPersonalAccessTokenDays + 1, expected: ErrPersonalAccessTokenExpiry},
+		{name: "too many tokens", tokenName: "cron", scopes: []string{domain.TokenScopeTrade}, activeTokens: MaximumActivePersonalAccessTokensPerUser, expected: ErrPersonalAccessTokenLimitReached},
+	}
+	for _, testCase := range cases {
+		tokenRepository := &memoryTokenRepository{}
+		for index := 0; index < testCase.activeTokens; index++ {
+			tokenRepository.tokens = append(tokenRepository.tokens, domain.PersonalAccessToken{UserIdentifier: 7})
+		}
+		auditLog := &memoryAuditLog{}
+		tokenService := NewPersonalAccessTokenService(tokenRepository, NewAuditService(auditLog))
+
+		token, rawToken, createError := tokenService.CreateToken(context.Background(), 7, testCase.tokenName, testCase.scopes, testCase.expiresInDays)
+		if !errors.Is(createError, testCase.expected) {
+			t.Errorf("%s: expected error %v, got %v", testCase.name, testCase.expected, createError)
+			continue
+		}
+		if testCase.expected != nil {
+			if len(tokenRepository.tokens) != testCase.activeTokens || len(auditLog.entries) != 0 {
+				t.Errorf("%s: a refused token was stored or audited", testCase.name)
+			}
+			continue
+		}
+		if token == nil || rawToken == "" {
+			t.Errorf("%s: expected a token", testCase.name)
+			continue
+		}
+		if actions := auditLog.actions(); len(actions) != 1 || actions[0] != domain.AuditActionAccessTokenCreated {
+			t.Errorf("%s: expected the creation to be audited, got %v", testCase.name, actions)
+		}
+	}
+}
+
+// TestTokensAreStoredOnlyAsAHash checks that the repository never sees the raw token, and that the
+// expiry and normalized scopes are what the user asked for.
+func TestTokensAreStoredOnlyAsAHash(t *testing.T) {
+	tokenRepository := &memoryTokenRepository{}
+	tokenService := NewPersonalAccessTokenService(tokenRepository, nil)
+
+	token, rawToken, createError := tokenService.CreateToken(context.Background(), 7, " deploy ", []string{domain.TokenScopeRobotsWrite, domain.TokenScopeReadOperations}, 30)
+	if createError != nil {
+		t.Fatal(createError)
+	}
+	if !strings.HasPrefix(rawToken, personalAccessTokenPrefix) || len(rawToken) < len(personalAccessTokenPrefix)+40 {
+		t.Errorf("unexpected raw token shape %q", rawToken)
+	}
+	stored := tokenRepository.tokens[0]
+	digest := sha256.Sum256([]byte(rawToken))
+	if stored.TokenHash != hex.EncodeToString(digest[:]) {
+		t.Errorf("expected the SHA-256 of the raw token to be stored, got %q", stored.TokenHash)
+	}
+	if strings.Contains(stored.TokenHash, rawToken[len(personalAccessTokenPrefix):]) || stored.TokenPrefix != rawToken[:personalAccessTokenDisplayLength] {
+		t.Errorf("the stored token leaks the raw value or has the wrong prefix: %+v", stored)
+	}
+	if stored.Name != "deploy" || strings.Join(stored.Scopes, " ") != domain.TokenScopeReadOperations+" "+domain.TokenScopeRobotsWrite {
+		t.Errorf("unexpected name %q or scopes %v", stored.Name, stored.Scopes)
+	}
+	if stored.ExpiresAt == nil || stored.ExpiresAt.Sub(stored.CreatedAt) != 30*24*time.Hour {
+		t.Errorf("expected the token to expire 30 days after creation, got %v", stored.ExpiresAt)
+	}
+	if token.Identifier != 1 || token.TokenHash != stored.TokenHash {
+		t.Errorf("the returned token does not match the stored one: %+v", token)
+	}
+
+	second, secondRaw, _ := tokenService.CreateToken(context.Background(), 7, "deploy", []string{domain.TokenScopeTrade}, 0)
+	if secondRaw == rawToken || second.TokenHash == stored.TokenHash || second.ExpiresAt != nil {
+		t.Errorf("expected a distinct token that never expires, got %+v", second)
+	}
+}
+
+func TestAuthenticateRefusesUnusableTokens(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	cases := []struct {
+		name      string
+		token     *domain.PersonalAccessToken // stored under the hash of rawToken; nil = unknown
+		rawToken  string
+		expectsOK bool
+	}{
+		{name: "usable", rawToken: "chpat_usable", token: &domain.PersonalAccessToken{ExpiresAt: &future}, expectsOK: true},
+		{name: "never expires", rawToken: "chpat_forever", token: &domain.PersonalAccessToken{}, expectsOK: true},
+		{name: "expired", rawToken: "chpat_expired", token: &domain.PersonalAccessToken{ExpiresAt: &past}},
+		{name: "revoked", rawToken: "chpat_revoked", token: &domain.PersonalAccessToken{RevokedAt: &past}},
+		{name: "unknown", rawToken: "chpat_unknown"},
+		{name: "session token", rawToken: "usable"},
+		{name: "empty", rawToken: ""},
+	}
+	for _, testCase := range cases {
+		tokenRepository := &memoryTokenRepository{}
+		if testCase.token != nil {
+			stored := *testCase.token
+			stored.UserIdentifier, stored.TokenHash, stored.Scopes = 7, hashSessionToken(testCase.rawToken), []string{domain.TokenScopeReadOperations}
+			_, _ = tokenRepository.CreateToken(context.Background(), stored)
+		}
+		tokenService := NewPersonalAccessTokenService(tokenRepository, nil)
+
+		token, authenticationError := tokenService.Authenticate(context.Background(), testCase.rawToken, "203.0.113.9")
+		if testCase.expectsOK {
+			if authenticationError != nil || token == nil || token.UserIdentifier != 7 || tokenRepository.uses != 1 {
+				t.Errorf("%s: expected the token to be accepted and its use recorded, got %+v, %v", testCase.name, token, authenticationError)
+			}
+			continue
+		}
+		if !errors.Is(authenticationError, ErrPersonalAccessTokenInvalid) || token != nil || tokenRepository.uses != 0 {
+			t.Errorf("%s: expected ErrPersonalAccessTokenInvalid without a recorded use, got %+v, %v", testCase.name, token, authenticationError)
+		}
+	}
+}
+
+func TestRevokedTokenStopsAuthenticating(t *testing.T) {
+	tokenRepository := &memoryTokenRepository{}
+	auditLog := &memoryAuditLog{}
+	tokenService := NewPersonalAccessTokenService(tokenRepository, NewAuditService(auditLog))
+	operationContext := context.Background()
+
+	token, rawToken, _ := tokenService.CreateToken(operationContext, 7, "cron", []string{domain.TokenScopeTrade}, 0)
+	if _, authenticationError := tokenService.Authenticate(operationContext, rawToken, ""); authenticationError != nil {
+		t.Fatalf("a new token was refused: %v", authenticationError)
+	}
+	if revokeError := tokenService.RevokeToken(operationContext, 8, token.Identifier); revokeError == nil {
+		t.Errorf("another user revoked the token")
+	}
+	if revokeError := tokenService.RevokeToken(operationContext, 7, token.Identifier); revokeError != nil {
+		t.Fatal(revokeError)
+	}
+	if _, authenticationError := tokenService.Authenticate(operationContext, rawToken, ""); !errors.Is(authenticationError, ErrPersonalAccessTokenInvalid) {
+		t.Errorf("expected a revoked token to be refused, got %v", authenticationError)
+	}
+	expectedActions := domain.AuditActionAccessTokenCreated + "," + domain.AuditActionAccessTokenRevoked
+	if actions := auditLog.actions(); strings.Join(actions, ",") != expectedActions {
+		t.Errorf("expected %s to be audited, got %v", expectedActions, actions)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// ErrInvalidWithdrawalKeyPolicy is returned for a withdrawal key policy other than REFUSE or FLAG.
+var ErrInvalidWithdrawalKeyPolicy = errors.New("withdrawal key policy must be REFUSE or FLAG")
+
+// PlatformPolicyUpdate lists the policy fields an admin changes; nil fields are kept.
+type PlatformPolicyUpdate struct {
+	WithdrawalKeyPolicy            *string
+	RequireTwoFactorForLiveTrading *bool
+}
+
+// PlatformPolicyService reads and updates the platform-wide security policy.
+type PlatformPolicyService struct {
+	repository    repository.PlatformPolicyRepository
+	auditRecorder AuditRecorder
+}
+
+func NewPlatformPolicyService(repositoryInstance repository.PlatformPolicyRepository, auditRecorder AuditRecorder) *PlatformPolicyService {
+	return &PlatformPolicyService{repository: repositoryInstance, auditRecorder: auditRecorder}
+}
+
+// GetPolicy returns the current policy. A nil service yields the defaults, so optional wiring stays safe.
+func (service *PlatformPolicyService) GetPolicy(loadContext context.Context) (domain.PlatformPolicy, error) {
+	if service == nil {
+		return domain.PlatformPolicy{WithdrawalKeyPolicy: domain.WithdrawalKeyPolicyRefuse}, nil
+	}
+	return service.repository.GetPolicy(loadContext)
+}
+
+// UpdatePolicy applies an admin's change and records it in the admin's audit log.
+func (service *PlatformPolicyService) UpdatePolicy(operationContext context.Context, adminUserIdentifier int64, update PlatformPolicyUpdate) (domain.PlatformPolicy, error) {
+	current, loadError := service.repository.GetPolicy(operationContext)
+	if loadError != nil {
+		return domain.PlatformPolicy{}, loadError
+	}
+	updated := current
+	if update.WithdrawalKeyPolicy != nil {
+		switch *update.WithdrawalKeyPolicy {
+		case domain.WithdrawalKeyPolicyRefuse, domain.WithdrawalKeyPolicyFlag:
+			updated.WithdrawalKeyPolicy = *update.WithdrawalKeyPolicy
+		default:
+			return domain.PlatformPolicy{}, ErrInvalidWithdrawalKeyPolicy
+		}
+	}
+	if update.RequireTwoFactorForLiveTrading != nil {
+		updated.RequireTwoFactorForLiveTrading = *update.RequireTwoFactorForLiveTrading
+	}
+	updated.UpdatedByUserIdentifier = &adminUserIdentifier
+	if updateError := service.repository.UpdatePolicy(operationContext, updated); updateError != nil {
+		return domain.PlatformPolicy{}, updateError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: adminUserIdentifier,
+		Action:         domain.AuditActionPolicyUpdated,
+		TargetType:     "platform_policy",
+		Before:         platformPolicyAuditSnapshot(current),
+		After:          platformPolicyAuditSnapshot(updated),
+	})
+	return service.repository.GetPolicy(operationContext)
+}
+
+func platformPolicyAuditSnapshot(policy domain.PlatformPolicy) map[string]interface{} {
+	return map[string]interface{}{
+		"withdrawal_key_policy":               policy.WithdrawalKeyPolicy,
+		"require_two_factor_for_live_trading": policy.RequireTwoFactorForLiveTrading,
+	}
+}
@@ -35,11 +35,14 @@ func (client *PortfolioScraperClient) FetchRaw(requestContext context.Context, p
 		return 0, nil, buildError
 	}
 
+	startedAt := time.Now()
 	scraperResponse, responseError := client.httpClient.Do(scraperRequest)
 	if responseError != nil {
+		scraperRequestDuration.ObserveSince(startedAt, "error")
 		return 0, nil, responseError
 	}
 	defer scraperResponse.Body.Close()
+	defer func() { scraperRequestDuration.ObserveSince(startedAt, scraperOutcome(scraperResponse.StatusCode)) }()
 
 	responseBody, readError := io.ReadAll(scraperResponse.Body)
 	if readError != nil {
@@ -47,3 +50,11 @@ func (client *PortfolioScraperClient) FetchRaw(requestContext context.Context, p
 	}
 	return scraperResponse.StatusCode, responseBody, nil
 }
+
+// scraperOutcome labels a scraper response for the latency histogram.
+func scraperOutcome(statusCode int) string {
+	if statusCode >= http.StatusBadRequest {
+		return "error"
+	}
+	return "ok"
+}
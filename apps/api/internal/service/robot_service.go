@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
 	"coin-alert/internal/repository"
 )
 
@@ -24,10 +25,13 @@ const StandardUserRobotLimitPerEnvironment = 1
 type RobotService struct {
 	repository        repository.TradingRobotRepository
 	credentialService *UserCredentialService
+	transactionRunner repository.TransactionRunner
+	eventPublisher    events.Publisher
+	auditRecorder     AuditRecorder
 }
 
-func NewRobotService(repositoryInstance repository.TradingRobotRepository, credentialService *UserCredentialService) *RobotService {
-	return &RobotService{repository: repositoryInstance, credentialService: credentialService}
+func NewRobotService(repositoryInstance repository.TradingRobotRepository, credentialService *UserCredentialService, transactionRunner repository.TransactionRunner, eventPublisher events.Publisher, auditRecorder AuditRecorder) *RobotService {
+	return &RobotService{repository: repositoryInstance, credentialService: credentialService, transactionRunner: transactionRunner, eventPublisher: eventPublisher, auditRecorder: auditRecorder}
 }
 
 // RobotInput carries the editable robot fields coming from the API.
@@ -56,6 +60,11 @@ func (service *RobotService) ListRobots(operationContext context.Context, userId
 	return service.repository.ListRobotsForUser(operationContext, userIdentifier, environment)
 }
 
+// GetRobot returns one of the user's robots, or repository.ErrRobotNotFound.
+func (service *RobotService) GetRobot(operationContext context.Context, userIdentifier int64, robotIdentifier int64) (*domain.TradingRobot, error) {
+	return service.repository.GetRobotForUser(operationContext, userIdentifier, robotIdentifier)
+}
+
 func (service *RobotService) CreateRobot(operationContext context.Context, userIdentifier int64, isAdmin bool, input RobotInput) (*domain.TradingRobot, error) {
 	environment := service.credentialService.ActiveEnvironmentName(operationContext, userIdentifier)
 	if !isAdmin {
@@ -69,14 +78,27 @@ func (service *RobotService) CreateRobot(operationContext context.Context, userI
 	}
 
 	robot := normalizeRobot(input, environment)
-	robotIdentifier, createError := service.repository.CreateRobotForUser(operationContext, userIdentifier, robot)
+	createError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		robotIdentifier, insertError := service.repository.CreateRobotForUser(transactionContext, userIdentifier, robot)
+		if insertError != nil {
+			return insertError
+		}
+		robot.Identifier = robotIdentifier
+		return service.eventPublisher.Publish(transactionContext, userIdentifier, events.RobotChanged{Action: events.RobotActionCreated, Robot: robot})
+	})
 	if createError != nil {
 		if errors.Is(createError, repository.ErrRobotSymbolExists) {
 			return nil, ErrRobotSymbolExists
 		}
 		return nil, createError
 	}
-	robot.Identifier = robotIdentifier
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           domain.AuditActionRobotCreated,
+		TargetType:       "robot",
+		TargetIdentifier: robot.Identifier,
+		After:            newWebhookRobotData(events.RobotActionCreated, robot),
+	})
 	return &robot, nil
 }
 
@@ -90,14 +112,104 @@ func (service *RobotService) UpdateRobot(operationContext context.Context, userI
 	robot.Identifier = robotIdentifier
 	// The coin is immutable after creation (it is part of the robot's identity within the environment).
 	robot.TradingPairSymbol = existing.TradingPairSymbol
-	if updateError := service.repository.UpdateRobotForUser(operationContext, userIdentifier, robot); updateError != nil {
+	updateError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		if updateError := service.repository.UpdateRobotForUser(transactionContext, userIdentifier, robot); updateError != nil {
+			return updateError
+		}
+		return service.eventPublisher.Publish(transactionContext, userIdentifier, events.RobotChanged{Action: events.RobotActionUpdated, Robot: robot})
+	})
+	if updateError != nil {
 		return nil, updateError
 	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           domain.AuditActionRobotUpdated,
+		TargetType:       "robot",
+		TargetIdentifier: robotIdentifier,
+		Before:           newWebhookRobotData(events.RobotActionUpdated, *existing),
+		After:            newWebhookRobotData(events.RobotActionUpdated, robot),
+	})
 	return &robot, nil
 }
 
 func (service *RobotService) DeleteRobot(operationContext context.Context, userIdentifier int64, robotIdentifier int64) error {
-	return service.repository.DeleteRobotForUser(operationContext, userIdentifier, robotIdentifier)
+	existing, lookupError := service.repository.GetRobotForUser(operationContext, userIdentifier, robotIdentifier)
+	if lookupError != nil {
+		return lookupError
+	}
+	deleteError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		if deleteError := service.repository.DeleteRobotForUser(transactionContext, userIdentifier, robotIdentifier); deleteError != nil {
+			return deleteError
+		}
+		return service.eventPublisher.Publish(transactionContext, userIdentifier, events.RobotChanged{Action: events.RobotActionDeleted, Robot: domain.TradingRobot{Identifier: robotIdentifier}})
+	})
+	if deleteError != nil {
+		return deleteError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           domain.AuditActionRobotDeleted,
+		TargetType:       "robot",
+		TargetIdentifier: robotIdentifier,
+		Before:           newWebhookRobotData(events.RobotActionDeleted, *existing),
+	})
+	return nil
+}
+
+// PauseRobotsForEnvironment disables every enabled robot the user has in the environment, e.g. when
+// Binance rejected that environment's key. It returns the robots it paused.
+func (service *RobotService) PauseRobotsForEnvironment(operationContext context.Context, userIdentifier int64, environment string) ([]domain.TradingRobot, error) {
+	var pausedRobots []domain.TradingRobot
+	pauseError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		var updateError error
+		pausedRobots, updateError = service.pauseRobotsInTransaction(transactionContext, userIdentifier, environment)
+		return updateError
+	})
+	if pauseError != nil {
+		return nil, pauseError
+	}
+	service.auditPausedRobots(operationContext, userIdentifier, pausedRobots)
+	return pausedRobots, nil
+}
+
+// pauseRobotsInTransaction disables the enabled robots and publishes their changes with the caller's
+// transaction, so a caller can commit the pause together with what caused it.
+func (service *RobotService) pauseRobotsInTransaction(transactionContext context.Context, userIdentifier int64, environment string) ([]domain.TradingRobot, error) {
+	robots, listError := service.repository.ListRobotsForUser(transactionContext, userIdentifier, environment)
+	if listError != nil {
+		return nil, listError
+	}
+	pausedRobots := make([]domain.TradingRobot, 0)
+	for _, robot := range robots {
+		if !robot.IsEnabled {
+			continue
+		}
+		robot.IsEnabled = false
+		if updateError := service.repository.UpdateRobotForUser(transactionContext, userIdentifier, robot); updateError != nil {
+			return nil, updateError
+		}
+		if publishError := service.eventPublisher.Publish(transactionContext, userIdentifier, events.RobotChanged{Action: events.RobotActionUpdated, Robot: robot}); publishError != nil {
+			return nil, publishError
+		}
+		pausedRobots = append(pausedRobots, robot)
+	}
+	return pausedRobots, nil
+}
+
+// auditPausedRobots records the pauses once they are committed.
+func (service *RobotService) auditPausedRobots(operationContext context.Context, userIdentifier int64, pausedRobots []domain.TradingRobot) {
+	for _, robot := range pausedRobots {
+		before := robot
+		before.IsEnabled = true
+		recordAudit(operationContext, service.auditRecorder, AuditRecord{
+			UserIdentifier:   userIdentifier,
+			Action:           domain.AuditActionRobotUpdated,
+			TargetType:       "robot",
+			TargetIdentifier: robot.Identifier,
+			Before:           newWebhookRobotData(events.RobotActionUpdated, before),
+			After:            newWebhookRobotData(events.RobotActionUpdated, robot),
+		})
+	}
 }
 
 func normalizeRobot(input RobotInput, environment string) domain.TradingRobot {
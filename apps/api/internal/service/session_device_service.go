@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// Session management errors surfaced to the API.
+var (
+	ErrSessionToRevokeNotFound = errors.New("session not found")
+	// ErrCannotRevokeCurrentSession points the user to sign out instead, which also clears the cookie.
+	ErrCannotRevokeCurrentSession = errors.New("this is the session you are using; sign out instead")
+)
+
+// ActiveSession is one signed-in device as shown to its user.
+type ActiveSession struct {
+	Identifier int64
+	Device     DeviceDescription
+	IPAddress  string
+	Location   string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	IsCurrent  bool
+}
+
+// SessionDeviceService lets users see and revoke their sessions, and notes the device and approximate
+// location of every sign-in, emailing the user when a device signs in for the first time.
+type SessionDeviceService struct {
+	sessionRepository   repository.UserSessionRepository
+	deviceRepository    repository.UserDeviceRepository
+	locator             IPLocator // nil = locations stay unknown
+	accountEmailService *AccountEmailService
+	auditRecorder       AuditRecorder
+}
+
+func NewSessionDeviceService(sessionRepository repository.UserSessionRepository, deviceRepository repository.UserDeviceRepository, locator IPLocator, accountEmailService *AccountEmailService, auditRecorder AuditRecorder) *SessionDeviceService {
+	return &SessionDeviceService{
+		sessionRepository:   sessionRepository,
+		deviceRepository:    deviceRepository,
+		locator:             locator,
+		accountEmailService: accountEmailService,
+		auditRecorder:       auditRecorder,
+	}
+}
+
+// ListSessions returns the user's active sessions, flagging the one behind currentRawToken.
+func (service *SessionDeviceService) ListSessions(operationContext context.Context, userIdentifier int64, currentRawToken string) ([]ActiveSession, error) {
+	sessions, listError := service.sessionRepository.ListActiveForUser(operationContext, userIdentifier)
+	if listError != nil {
+		return nil, listError
+	}
+	currentTokenHash := hashSessionToken(currentRawToken)
+	activeSessions := make([]ActiveSession, 0, len(sessions))
+	for _, session := range sessions {
+		activeSessions = append(activeSessions, ActiveSession{
+			Identifier: session.Identifier,
+			Device:     DescribeUserAgent(session.UserAgent),
+			IPAddress:  session.IPAddress,
+			Location:   session.Location,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			IsCurrent:  session.SessionTokenHash == currentTokenHash,
+		})
+	}
+	return activeSessions, nil
+}
+
+// RevokeSession signs one of the user's other sessions out.
+func (service *SessionDeviceService) RevokeSession(operationContext context.Context, userIdentifier int64, sessionIdentifier int64, currentRawToken string) error {
+	currentSession, lookupError := service.sessionRepository.FindActiveByTokenHash(operationContext, hashSessionToken(currentRawToken))
+	if lookupError != nil {
+		return lookupError
+	}
+	if currentSession != nil && currentSession.Identifier == sessionIdentifier {
+		return ErrCannotRevokeCurrentSession
+	}
+	deleted, deleteError := service.sessionRepository.DeleteForUser(operationContext, userIdentifier, sessionIdentifier)
+	if deleteError != nil {
+		return deleteError
+	}
+	if !deleted {
+		return ErrSessionToRevokeNotFound
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           domain.AuditActionSessionRevoked,
+		TargetType:       "session",
+		TargetIdentifier: sessionIdentifier,
+	})
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the user except the current one and returns how
+// many were revoked.
+func (service *SessionDeviceService) RevokeOtherSessions(operationContext context.Context, userIdentifier int64, currentRawToken string) (int64, error) {
+	revokedCount, deleteError := service.sessionRepository.DeleteAllForUserExcept(operationContext, userIdentifier, hashSessionToken(currentRawToken))
+	if deleteError != nil {
+		return 0, deleteError
+	}
+	if revokedCount > 0 {
+		recordAudit(operationContext, service.auditRecorder, AuditRecord{
+			UserIdentifier: userIdentifier,
+			Action:         domain.AuditActionOtherSessionsRevoked,
+			TargetType:     "session",
+			After:          map[string]int64{"revoked_sessions": revokedCount},
+		})
+	}
+	return revokedCount, nil
+}
+
+func (service *SessionDeviceService) NewDeviceAlertsEnabled(operationContext context.Context, userIdentifier int64) (bool, error) {
+	return service.deviceRepository.LoadNewDeviceAlertsEnabled(operationContext, userIdentifier)
+}
+
+func (service *SessionDeviceService) SetNewDeviceAlertsEnabled(operationContext context.Context, userIdentifier int64, isEnabled bool) error {
+	return service.deviceRepository.SetNewDeviceAlertsEnabled(operationContext, userIdentifier, isEnabled)
+}
+
+// RecordSignIn runs in the background after a session is issued: it stores the approximate location
+// and, when the device is new for an account that already had others, emails the user (unless they
+// opted out). The very first device of an account is not reported.
+func (service *SessionDeviceService) RecordSignIn(userIdentifier int64, rawToken string, userAgent string, ipAddress string, locale string) {
+	go func() {
+		operationContext, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		location := ""
+		if service.locator != nil {
+			resolvedLocation, locateError := service.locator.Locate(operationContext, ipAddress)
+			if locateError != nil {
+				authLogger.WarnContext(operationContext, "could not locate the sign-in IP", "user_id", userIdentifier, "error", locateError)
+			}
+			location = resolvedLocation
+			if location != "" {
+				if updateError := service.sessionRepository.UpdateLocation(operationContext, hashSessionToken(rawToken), location); updateError != nil {
+					authLogger.ErrorContext(operationContext, "could not store the session location", "user_id", userIdentifier, "error", updateError)
+				}
+			}
+		}
+
+		device := DescribeUserAgent(userAgent)
+		knownDeviceCount, countError := service.deviceRepository.CountKnownDevices(operationContext, userIdentifier)
+		if countError != nil {
+			authLogger.ErrorContext(operationContext, "could not load known devices", "user_id", userIdentifier, "error", countError)
+			return
+		}
+		isNewDevice, rememberError := service.deviceRepository.RememberDevice(operationContext, userIdentifier, device.Key())
+		if rememberError != nil {
+			authLogger.ErrorContext(operationContext, "could not remember the device", "user_id", userIdentifier, "error", rememberError)
+			return
+		}
+		if !isNewDevice || knownDeviceCount == 0 || service.accountEmailService == nil {
+			return
+		}
+		alertsEnabled, preferenceError := service.deviceRepository.LoadNewDeviceAlertsEnabled(operationContext, userIdentifier)
+		if preferenceError != nil || !alertsEnabled {
+			return
+		}
+		if noticeError := service.accountEmailService.SendNewDeviceNotice(operationContext, userIdentifier, locale, device.Label(), location, ipAddress, time.Now()); noticeError != nil {
+			authLogger.ErrorContext(operationContext, "could not send the new-device notice", "user_id", userIdentifier, "error", noticeError)
+		}
+	}()
+}
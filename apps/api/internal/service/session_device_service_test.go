@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+)
+
+// memorySessionRepository is an in-memory repository.UserSessionRepository.
+type memorySessionRepository struct {
+	mutex    sync.Mutex
+	sessions []domain.UserSession
+}
+
+func (repository *memorySessionRepository) CreateSession(_ context.Context, session domain.UserSession) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	session.Identifier = int64(len(repository.sessions) + 1)
+	session.CreatedAt, session.LastSeenAt = time.Now(), time.Now()
+	repository.sessions = append(repository.sessions, session)
+	return nil
+}
+
+func (repository *memorySessionRepository) FindActiveByTokenHash(_ context.Context, sessionTokenHash string) (*domain.UserSession, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for _, session := range repository.sessions {
+		if session.SessionTokenHash == sessionTokenHash && session.ExpiresAt.After(time.Now()) {
+			found := session
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// deleteWhere removes the sessions matching and returns how many it removed.
+func (repository *memorySessionRepository) deleteWhere(matches func(domain.UserSession) bool) int64 {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var kept []domain.UserSession
+	for _, session := range repository.sessions {
+		if !matches(session) {
+			kept = append(kept, session)
+		}
+	}
+	deletedCount := int64(len(repository.sessions) - len(kept))
+	repository.sessions = kept
+	return deletedCount
+}
+
+func (repository *memorySessionRepository) DeleteByTokenHash(_ context.Context, sessionTokenHash string) error {
+	repository.deleteWhere(func(session domain.UserSession) bool { return session.SessionTokenHash == sessionTokenHash })
+	return nil
+}
+
+func (repository *memorySessionRepository) DeleteAllForUser(_ context.Context, userIdentifier int64) error {
+	repository.deleteWhere(func(session domain.UserSession) bool { return session.UserIdentifier == userIdentifier })
+	return nil
+}
+
+func (repository *memorySessionRepository) DeleteExpiredSessions(context.Context) (int64, error) {
+	return repository.deleteWhere(func(session domain.UserSession) bool { return !session.ExpiresAt.After(time.Now()) }), nil
+}
+
+func (repository *memorySessionRepository) UpdateStepUpExpiry(_ context.Context, sessionTokenHash string, stepUpExpiresAt time.Time) error {
+	repository.update(sessionTokenHash, func(session *domain.UserSession) { session.StepUpExpiresAt = &stepUpExpiresAt })
+	return nil
+}
+
+func (repository *memorySessionRepository) ListActiveForUser(_ context.Context, userIdentifier int64) ([]domain.UserSession, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var sessions []domain.UserSession
+	for _, session := range repository.sessions {
+		if session.UserIdentifier == userIdentifier && session.ExpiresAt.After(time.Now()) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (repository *memorySessionRepository) DeleteForUser(_ context.Context, userIdentifier int64, sessionIdentifier int64) (bool, error) {
+	deletedCount := repository.deleteWhere(func(session domain.UserSession) bool {
+		return session.UserIdentifier == userIdentifier && session.Identifier == sessionIdentifier
+	})
+	return deletedCount > 0, nil
+}
+
+func (repository *memorySessionRepository) DeleteAllForUserExcept(_ context.Context, userIdentifier int64, keepTokenHash string) (int64, error) {
+	return repository.deleteWhere(func(session domain.UserSession) bool {
+		return session.UserIdentifier == userIdentifier && session.SessionTokenHash != keepTokenHash
+	}), nil
+}
+
+func (repository *memorySessionRepository) UpdateLocation(_ context.Context, sessionTokenHash string, location string) error {
+	repository.update(sessionTokenHash, func(session *domain.UserSession) { session.Location = location })
+	return nil
+}
+
+func (repository *memorySessionRepository) TouchLastSeen(_ context.Context, sessionIdentifier int64) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for index := range repository.sessions {
+		if repository.sessions[index].Identifier == sessionIdentifier {
+			repository.sessions[index].LastSeenAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (repository *memorySessionRepository) update(sessionTokenHash string, change func(*domain.UserSession)) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for index := range repository.sessions {
+		if repository.sessions[index].SessionTokenHash == sessionTokenHash {
+			change(&repository.sessions[index])
+		}
+	}
+}
+
+// memoryDeviceRepository is an in-memory repository.UserDeviceRepository. remembered receives every
+// device key RecordSignIn stores, so a test knows when the background work got that far.
+type memoryDeviceRepository struct {
+	mutex          sync.Mutex
+	devices        map[int64]map[string]bool
+	alertsDisabled map[int64]bool
+	remembered     chan string
+}
+
+func newMemoryDeviceRepository() *memoryDeviceRepository {
+	return &memoryDeviceRepository{devices: map[int64]map[string]bool{}, alertsDisabled: map[int64]bool{}, remembered: make(chan string, 10)}
+}
+
+func (repository *memoryDeviceRepository) CountKnownDevices(_ context.Context, userIdentifier int64) (int, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	return len(repository.devices[userIdentifier]), nil
+}
+
+func (repository *memoryDeviceRepository) RememberDevice(_ context.Context, userIdentifier int64, deviceKey string) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	defer func() { repository.remembered <- deviceKey }()
+	if repository.devices[userIdentifier] == nil {
+		repository.devices[userIdentifier] = map[string]bool{}
+	}
+	isNewDevice := !repository.devices[userIdentifier][deviceKey]
+	repository.devices[userIdentifier][deviceKey] = true
+	return isNewDevice, nil
+}
+
+func (repository *memoryDeviceRepository) LoadNewDeviceAlertsEnabled(_ context.Context, userIdentifier int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	return !repository.alertsDisabled[userIdentifier], nil
+}
+
+func (repository *memoryDeviceRepository) SetNewDeviceAlertsEnabled(_ context.Context, userIdentifier int64, isEnabled bool) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.alertsDisabled[userIdentifier] = !isEnabled
+	return nil
+}
+
+type fixedLocator string
+
+func (locator fixedLocator) Locate(context.Context, string) (string, error) {
+	return string(locator), nil
+}
+
+const (
+	desktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+	phoneUserAgent   = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+)
+
+// issueTestSessions signs user 7 in on a desktop and a phone and user 8 on a desktop, returning the
+// raw tokens in that order.
+func issueTestSessions(t *testing.T, sessionService *SessionService) []string {
+	t.Helper()
+	var rawTokens []string
+	for _, signIn := range []struct {
+		userIdentifier int64
+		userAgent      string
+	}{{7, desktopUserAgent}, {7, phoneUserAgent}, {8, desktopUserAgent}} {
+		rawToken, _, issueError := sessionService.IssueSession(context.Background(), signIn.userIdentifier, signIn.userAgent, "203.0.113.9")
+		if issueError != nil {
+			t.Fatal(issueError)
+		}
+		rawTokens = append(rawTokens, rawToken)
+	}
+	return rawTokens
+}
+
+// TestRevokedSessionCannotBeReused revokes a session each way a user can and then presents its cookie
+// again: it must no longer resolve nor pass a step-up check, while the sessions left alone still work.
+func TestRevokedSessionCannotBeReused(t *testing.T) {
+	const desktop, phone, otherUser = 0, 1, 2
+	cases := []struct {
+		name          string
+		revoke        func(*SessionService, *SessionDeviceService, []string) error
+		revoked       []int
+		expectedAudit string
+	}{
+		{
+			name: "revoke one session from another device",
+			revoke: func(_ *SessionService, deviceService *SessionDeviceService, rawTokens []string) error {
+				return deviceService.RevokeSession(context.Background(), 7, 2, rawTokens[desktop])
+			},
+			revoked:       []int{phone},
+			expectedAudit: domain.AuditActionSessionRevoked,
+		},
+		{
+			name: "revoke every other session",
+			revoke: func(_ *SessionService, deviceService *SessionDeviceService, rawTokens []string) error {
+				revokedCount, revokeError := deviceService.RevokeOtherSessions(context.Background(), 7, rawTokens[desktop])
+				if revokeError == nil && revokedCount != 1 {
+					return errors.New("expected one session to be revoked")
+				}
+				return revokeError
+			},
+			revoked:       []int{phone},
+			expectedAudit: domain.AuditActionOtherSessionsRevoked,
+		},
+		{
+			name: "sign out",
+			revoke: func(sessionService *SessionService, _ *SessionDeviceService, rawTokens []string) error {
+				return sessionService.RevokeSession(context.Background(), rawTokens[desktop])
+			},
+			revoked: []int{desktop},
+		},
+	}
+	for _, testCase := range cases {
+		sessionRepository := &memorySessionRepository{}
+		auditLog := &memoryAuditLog{}
+		sessionService := NewSessionService(sessionRepository, time.Hour)
+		deviceService := NewSessionDeviceService(sessionRepository, newMemoryDeviceRepository(), nil, nil, NewAuditService(auditLog))
+		rawTokens := issueTestSessions(t, sessionService)
+
+		if revokeError := testCase.revoke(sessionService, deviceService, rawTokens); revokeError != nil {
+			t.Errorf("%s: %v", testCase.name, revokeError)
+			continue
+		}
+		for index, rawToken := range rawTokens {
+			isRevoked := false
+			for _, revokedIndex := range testCase.revoked {
+				isRevoked = isRevoked || revokedIndex == index
+			}
+			userIdentifier, resolveError := sessionService.ResolveUserIdentifier(context.Background(), rawToken)
+			stepUpError := sessionService.RequireRecentAuthentication(context.Background(), rawToken)
+			switch {
+			case isRevoked && (!errors.Is(resolveError, ErrSessionNotFound) || !errors.Is(stepUpError, ErrSessionNotFound)):
+				t.Errorf("%s: revoked session %d was reused: user %d, %v, %v", testCase.name, index, userIdentifier, resolveError, stepUpError)
+			case !isRevoked && (resolveError != nil || stepUpError != nil):
+				t.Errorf("%s: session %d should still work: %v, %v", testCase.name, index, resolveError, stepUpError)
+			}
+		}
+		if _, resolveError := sessionService.ResolveUserIdentifier(context.Background(), rawTokens[otherUser]); resolveError != nil {
+			t.Errorf("%s: another user's session was signed out", testCase.name)
+		}
+		actions := auditLog.actions()
+		if (testCase.expectedAudit == "" && len(actions) != 0) || (testCase.expectedAudit != "" && (len(actions) != 1 || actions[0] != testCase.expectedAudit)) {
+			t.Errorf("%s: expected audit %q, got %v", testCase.name, testCase.expectedAudit, actions)
+		}
+	}
+}
+
+func TestRevokeSessionRefusals(t *testing.T) {
+	sessionRepository := &memorySessionRepository{}
+	sessionService := NewSessionService(sessionRepository, time.Hour)
+	deviceService := NewSessionDeviceService(sessionRepository, newMemoryDeviceRepository(), nil, nil, nil)
+	rawTokens := issueTestSessions(t, sessionService)
+	if revokeError := deviceService.RevokeSession(context.Background(), 7, 2, rawTokens[0]); revokeError != nil {
+		t.Fatal(revokeError)
+	}
+
+	cases := []struct {
+		name              string
+		sessionIdentifier int64
+		expected          error
+	}{
+		{name: "current session", sessionIdentifier: 1, expected: ErrCannotRevokeCurrentSession},
+		{name: "another user's session", sessionIdentifier: 3, expected: ErrSessionToRevokeNotFound},
+		{name: "unknown session", sessionIdentifier: 99, expected: ErrSessionToRevokeNotFound},
+		{name: "already revoked", sessionIdentifier: 2, expected: ErrSessionToRevokeNotFound},
+	}
+	for _, testCase := range cases {
+		if revokeError := deviceService.RevokeSession(context.Background(), 7, testCase.sessionIdentifier, rawTokens[0]); !errors.Is(revokeError, testCase.expected) {
+			t.Errorf("%s: expected %v, got %v", testCase.name, testCase.expected, revokeError)
+		}
+	}
+	if _, resolveError := sessionService.ResolveUserIdentifier(context.Background(), rawTokens[2]); resolveError != nil {
+		t.Errorf("user 8's session was revoked by user 7: %v", resolveError)
+	}
+}
+
+func TestNewDeviceAlerts(t *testing.T) {
+	cases := []struct {
+		name           string
+		knownAgents    []string
+		alertsDisabled bool
+		signInAgent    string
+		expectsAlert   bool
+	}{
+		{name: "first device of the account", signInAgent: desktopUserAgent},
+		{name: "known device", knownAgents: []string{desktopUserAgent}, signInAgent: desktopUserAgent},
+		{name: "new device", knownAgents: []string{desktopUserAgent}, signInAgent: phoneUserAgent, expectsAlert: true},
+		{name: "new device with alerts off", knownAgents: []string{desktopUserAgent}, alertsDisabled: true, signInAgent: phoneUserAgent},
+	}
+	for _, testCase := range cases {
+		sessionRepository := &memorySessionRepository{}
+		deviceRepository := newMemoryDeviceRepository()
+		for _, knownAgent := range testCase.knownAgents {
+			_, _ = deviceRepository.RememberDevice(context.Background(), 7, DescribeUserAgent(knownAgent).Key())
+			<-deviceRepository.remembered
+		}
+		deviceRepository.alertsDisabled[7] = testCase.alertsDisabled
+		sender := newRecordingSender()
+		accountEmailService := newTestAccountEmailService(&domain.User{Identifier: 7, Email: "ana@example.com"}, sender)
+		deviceService := NewSessionDeviceService(sessionRepository, deviceRepository, fixedLocator("Lisbon, Portugal"), accountEmailService, nil)
+		rawToken, _, _ := NewSessionService(sessionRepository, time.Hour).IssueSession(context.Background(), 7, testCase.signInAgent, "203.0.113.9")
+
+		deviceService.RecordSignIn(7, rawToken, testCase.signInAgent, "203.0.113.9", "en")
+		select {
+		case <-deviceRepository.remembered:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: the sign-in was not recorded", testCase.name)
+		}
+		message, sent := sender.nextMessage(200 * time.Millisecond)
+		if sent != testCase.expectsAlert {
+			t.Errorf("%s: alert sent=%t, expected %t", testCase.name, sent, testCase.expectsAlert)
+			continue
+		}
+		if sessions, _ := sessionRepository.ListActiveForUser(context.Background(), 7); len(sessions) != 1 || sessions[0].Location != "Lisbon, Portugal" {
+			t.Errorf("%s: expected the session location to be stored, got %+v", testCase.name, sessions)
+		}
+		if !sent {
+			continue
+		}
+		deviceLabel := DescribeUserAgent(testCase.signInAgent).Label()
+		if message.To != "ana@example.com" || !strings.Contains(message.Subject, deviceLabel) || !strings.Contains(message.TextBody, "Lisbon, Portugal") || !strings.Contains(message.TextBody, "203.0.113.9") {
+			t.Errorf("%s: unexpected alert %+v", testCase.name, message)
+		}
+	}
+}
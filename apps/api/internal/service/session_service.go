@@ -7,7 +7,6 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
-	"log"
 	"time"
 
 	"coin-alert/internal/domain"
@@ -17,6 +16,15 @@ import (
 // ErrSessionNotFound is returned when a session token does not map to an active session.
 var ErrSessionNotFound = errors.New("session not found or expired")
 
+// ErrStepUpRequired is returned when a sensitive action needs a recent password or 2FA confirmation.
+var ErrStepUpRequired = errors.New("confirm your password or authentication code to continue")
+
+// StepUpLifetime is how long a password/2FA confirmation (or a fresh sign-in) covers sensitive actions.
+const StepUpLifetime = 10 * time.Minute
+
+// sessionLastSeenResolution is how stale a session's last_seen_at may get before a request refreshes it.
+const sessionLastSeenResolution = 5 * time.Minute
+
 // SessionService issues and resolves opaque, server-side sessions. The raw token is returned
 // only once (to be placed in a secure cookie); the database stores only its SHA-256 hash.
 type SessionService struct {
@@ -38,13 +46,17 @@ func (service *SessionService) IssueSession(issueContext context.Context, userId
 		return "", time.Time{}, tokenError
 	}
 
-	expiresAt := time.Now().Add(service.sessionLifetime)
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(service.sessionLifetime)
+	// The user just proved who they are, so the new session starts stepped up.
+	stepUpExpiresAt := issuedAt.Add(StepUpLifetime)
 	session := domain.UserSession{
 		UserIdentifier:   userIdentifier,
 		SessionTokenHash: hashSessionToken(rawToken),
 		ExpiresAt:        expiresAt,
 		UserAgent:        userAgent,
 		IPAddress:        ipAddress,
+		StepUpExpiresAt:  &stepUpExpiresAt,
 	}
 
 	creationError := service.sessionRepository.CreateSession(issueContext, session)
@@ -66,9 +78,43 @@ func (service *SessionService) ResolveUserIdentifier(resolveContext context.Cont
 	if session == nil {
 		return 0, ErrSessionNotFound
 	}
+	// last_seen_at feeds the session list; refreshing it every few minutes is precise enough.
+	if time.Since(session.LastSeenAt) > sessionLastSeenResolution {
+		if touchError := service.sessionRepository.TouchLastSeen(resolveContext, session.Identifier); touchError != nil {
+			authLogger.ErrorContext(resolveContext, "could not update the session last seen time", "error", touchError)
+		}
+	}
 	return session.UserIdentifier, nil
 }
 
+// RequireRecentAuthentication returns ErrStepUpRequired unless the session confirmed the user's
+// password or 2FA code within StepUpLifetime.
+func (service *SessionService) RequireRecentAuthentication(lookupContext context.Context, rawToken string) error {
+	if rawToken == "" {
+		return ErrSessionNotFound
+	}
+	session, lookupError := service.sessionRepository.FindActiveByTokenHash(lookupContext, hashSessionToken(rawToken))
+	if lookupError != nil {
+		return lookupError
+	}
+	if session == nil {
+		return ErrSessionNotFound
+	}
+	if !session.HasRecentAuthentication(time.Now()) {
+		return ErrStepUpRequired
+	}
+	return nil
+}
+
+// MarkRecentlyAuthenticated extends the session's step-up window after a successful confirmation.
+func (service *SessionService) MarkRecentlyAuthenticated(operationContext context.Context, rawToken string) (time.Time, error) {
+	stepUpExpiresAt := time.Now().Add(StepUpLifetime)
+	if updateError := service.sessionRepository.UpdateStepUpExpiry(operationContext, hashSessionToken(rawToken), stepUpExpiresAt); updateError != nil {
+		return time.Time{}, updateError
+	}
+	return stepUpExpiresAt, nil
+}
+
 // RevokeSession deletes the session backing a raw token, if any.
 func (service *SessionService) RevokeSession(revokeContext context.Context, rawToken string) error {
 	if rawToken == "" {
@@ -104,11 +150,11 @@ func (service *SessionService) purgeExpiredSessionsOnce(parentContext context.Co
 	defer cancel()
 	deletedCount, deletionError := service.sessionRepository.DeleteExpiredSessions(purgeContext)
 	if deletionError != nil {
-		log.Printf("session cleanup: could not delete expired sessions: %v", deletionError)
+		authLogger.Error("session cleanup could not delete expired sessions", "error", deletionError)
 		return
 	}
 	if deletedCount > 0 {
-		log.Printf("session cleanup: removed %d expired session(s)", deletedCount)
+		authLogger.Info("session cleanup removed expired sessions", "count", deletedCount)
 	}
 }
 
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"coin-alert/internal/repository"
+)
+
+// ErrStepUpUnavailable is returned when the account has neither a password nor 2FA to confirm with
+// (a Google-only account); signing in again with Google starts a stepped-up session instead.
+var ErrStepUpUnavailable = errors.New("sign in again to confirm it is you")
+
+// StepUpService re-confirms the user's identity on an existing session before sensitive actions. Users
+// with 2FA confirm with an authentication (or recovery) code, everyone else with their password.
+// Wrong passwords and codes count towards the account's identity check lockout, so a stolen session
+// cookie cannot be used to guess its way to a step-up.
+type StepUpService struct {
+	sessionService   *SessionService
+	userRepository   repository.UserRepository
+	passwordService  *PasswordService
+	twoFactorService *TwoFactorService
+	rateLimitService *AuthRateLimitService
+}
+
+func NewStepUpService(sessionService *SessionService, userRepository repository.UserRepository, passwordService *PasswordService, twoFactorService *TwoFactorService, rateLimitService *AuthRateLimitService) *StepUpService {
+	return &StepUpService{
+		sessionService:   sessionService,
+		userRepository:   userRepository,
+		passwordService:  passwordService,
+		twoFactorService: twoFactorService,
+		rateLimitService: rateLimitService,
+	}
+}
+
+// Confirm checks the password or code and, when it matches, steps up the session behind rawToken.
+// It returns when the step-up expires, or a *RateLimitedError while the account has to wait after
+// failed attempts.
+func (service *StepUpService) Confirm(operationContext context.Context, rawToken string, userIdentifier int64, password string, code string) (time.Time, error) {
+	twoFactorEnabled, lookupError := service.twoFactorService.IsEnabled(operationContext, userIdentifier)
+	if lookupError != nil {
+		return time.Time{}, lookupError
+	}
+	if twoFactorEnabled {
+		// VerifyCode applies the identity check lockout itself.
+		if _, verifyError := service.twoFactorService.VerifyCode(operationContext, userIdentifier, code); verifyError != nil {
+			return time.Time{}, verifyError
+		}
+		return service.sessionService.MarkRecentlyAuthenticated(operationContext, rawToken)
+	}
+
+	user, userError := service.userRepository.FindByIdentifier(operationContext, userIdentifier)
+	if userError != nil {
+		return time.Time{}, userError
+	}
+	if !user.HasPassword() {
+		return time.Time{}, ErrStepUpUnavailable
+	}
+	if blockError := service.rateLimitService.CheckIdentityCheckBlock(operationContext, userIdentifier); blockError != nil {
+		return time.Time{}, blockError
+	}
+	if !service.passwordService.VerifyPassword(user.PasswordHash, password) {
+		service.rateLimitService.RecordIdentityCheckFailure(operationContext, userIdentifier)
+		return time.Time{}, ErrIncorrectPassword
+	}
+	service.rateLimitService.RecordIdentityCheckSuccess(operationContext, userIdentifier)
+	return service.sessionService.MarkRecentlyAuthenticated(operationContext, rawToken)
+}
+
+// RequiredMethod tells the SPA what to ask for: "totp", "password" or "sign_in".
+func (service *StepUpService) RequiredMethod(lookupContext context.Context, userIdentifier int64) (string, error) {
+	twoFactorEnabled, lookupError := service.twoFactorService.IsEnabled(lookupContext, userIdentifier)
+	if lookupError != nil {
+		return "", lookupError
+	}
+	if twoFactorEnabled {
+		return TwoFactorMethodTOTP, nil
+	}
+	user, userError := service.userRepository.FindByIdentifier(lookupContext, userIdentifier)
+	if userError != nil {
+		return "", userError
+	}
+	if user.HasPassword() {
+		return "password", nil
+	}
+	return "sign_in", nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// singleUserRepository serves one user; other UserRepository methods are not used by step-up.
+type singleUserRepository struct {
+	repository.UserRepository
+	user *domain.User
+}
+
+func (userRepository singleUserRepository) FindByIdentifier(context.Context, int64) (*domain.User, error) {
+	return userRepository.user, nil
+}
+
+// stepUpSessionRepository records step-ups; other UserSessionRepository methods are not used.
+type stepUpSessionRepository struct {
+	repository.UserSessionRepository
+	stepUps int
+}
+
+func (sessionRepository *stepUpSessionRepository) UpdateStepUpExpiry(context.Context, string, time.Time) error {
+	sessionRepository.stepUps++
+	return nil
+}
+
+func TestStepUpPasswordIsRefusedAfterRepeatedFailures(t *testing.T) {
+	passwordService := &PasswordService{hashingCost: bcrypt.MinCost}
+	passwordHash, hashError := passwordService.HashPassword("correct horse battery staple")
+	if hashError != nil {
+		t.Fatal(hashError)
+	}
+	userRepository := singleUserRepository{user: &domain.User{Identifier: 7, Email: "ana@example.com", PasswordHash: passwordHash}}
+	sessionRepository := &stepUpSessionRepository{}
+	twoFactorService := NewTwoFactorService(newMemoryTwoFactorRepository(), nil, userRepository, nil, nil, nil, nil, nil, nil, "Coin Hub")
+	rateLimitService := NewAuthRateLimitService(lockoutOnlyRateLimitStore{repository.NewMemoryRateLimitRepository()}, nil)
+	stepUpService := NewStepUpService(NewSessionService(sessionRepository, time.Hour), userRepository, passwordService, twoFactorService, rateLimitService)
+	operationContext := context.Background()
+
+	if _, confirmError := stepUpService.Confirm(operationContext, "session-token", 7, "correct horse battery staple", ""); confirmError != nil {
+		t.Fatalf("the right password was refused: %v", confirmError)
+	}
+
+	cases := []struct {
+		attempt  int
+		password string
+		check    func(confirmError error) bool
+	}{
+		{attempt: 1, password: "guess", check: func(confirmError error) bool { return errors.Is(confirmError, ErrIncorrectPassword) }},
+		{attempt: loginLockoutThreshold, password: "guess", check: func(confirmError error) bool { return errors.Is(confirmError, ErrIncorrectPassword) }},
+		{attempt: loginLockoutThreshold + 1, password: "correct horse battery staple", check: func(confirmError error) bool {
+			var rateLimitedError *RateLimitedError
+			return errors.As(confirmError, &rateLimitedError) && rateLimitedError.AccountLocked && rateLimitedError.IdentityCheck
+		}},
+	}
+	attempt := 0
+	for _, testCase := range cases {
+		var confirmError error
+		for attempt < testCase.attempt {
+			attempt++
+			_, confirmError = stepUpService.Confirm(operationContext, "session-token", 7, testCase.password, "")
+		}
+		if !testCase.check(confirmError) {
+			t.Errorf("attempt %d: unexpected result %v", testCase.attempt, confirmError)
+		}
+	}
+	if sessionRepository.stepUps != 1 {
+		t.Errorf("expected only the first confirmation to step up the session, got %d step-ups", sessionRepository.stepUps)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/notification"
+	"coin-alert/internal/repository"
+)
+
+// TradeEventNotifier turns trade events into notifications for users who opted in to the event type.
+// Security alerts (an invalid Binance key) are always sent.
+type TradeEventNotifier struct {
+	preferenceRepository repository.NotificationPreferenceRepository
+	notifier             notification.Notifier
+}
+
+func NewTradeEventNotifier(preferenceRepository repository.NotificationPreferenceRepository, notifier notification.Notifier) *TradeEventNotifier {
+	return &TradeEventNotifier{preferenceRepository: preferenceRepository, notifier: notifier}
+}
+
+// HandleEvent is the event bus subscriber for TradeEventRaised. Only a failed preference lookup is
+// retried: channel failures are already recorded in the delivery log, and retrying would resend the
+// message on the channels that did succeed. Messages are written in the user's saved alert language.
+func (eventNotifier *TradeEventNotifier) HandleEvent(eventContext context.Context, envelope events.Envelope) error {
+	raised, isTradeEvent := envelope.Event.(events.TradeEventRaised)
+	if !isTradeEvent {
+		return nil
+	}
+	event := raised.TradeEvent
+	if event.EventType == domain.TradeEventCredentialInvalid {
+		message := credentialInvalidMessage(eventNotifier.userLocale(eventContext, event.UserIdentifier), event)
+		if notifyError := eventNotifier.notifier.Notify(eventContext, event.UserIdentifier, message); notifyError != nil {
+			notificationLogger.WarnContext(eventContext, "notification not fully delivered", "event", event.EventType, "user_id", event.UserIdentifier, "error", notifyError)
+		}
+		return nil
+	}
+	isEnabled, preferenceError := eventNotifier.preferenceRepository.IsEventEnabledForUser(eventContext, event.UserIdentifier, event.EventType)
+	if preferenceError != nil {
+		return preferenceError
+	}
+	if !isEnabled {
+		return nil
+	}
+	message := tradeEventMessage(eventNotifier.userLocale(eventContext, event.UserIdentifier), event)
+	if notifyError := eventNotifier.notifier.Notify(eventContext, event.UserIdentifier, message); notifyError != nil {
+		notificationLogger.WarnContext(eventContext, "notification not fully delivered", "event", event.EventType, "user_id", event.UserIdentifier, "error", notifyError)
+	}
+	return nil
+}
+
+// userLocale is the user's alert language; a failed lookup falls back to the default rather than
+// holding the alert back.
+func (eventNotifier *TradeEventNotifier) userLocale(eventContext context.Context, userIdentifier int64) string {
+	locale, localeError := eventNotifier.preferenceRepository.LoadLocaleForUser(eventContext, userIdentifier)
+	if localeError != nil {
+		notificationLogger.WarnContext(eventContext, "could not load the alert language", "user_id", userIdentifier, "error", localeError)
+	}
+	return normalizeEmailLocale(locale)
+}
+
+// tradeAlertCopy holds the localized strings of trade alerts.
+type tradeAlertCopy struct {
+	robot, symbol, quantity, purchasePrice, amount, reason, operation string
+	fillPrice                                                         string // "%s (bought at %s)"
+	realizedProfit                                                    string // "%+.2f"
+	takeProfitFilled, stopLossTriggered, takeProfitExpired            string
+	takeProfitExpiredNote                                             string
+	dailyPurchaseFailed                                               string
+	keyRejectedSubject                                                string
+	keyRejected                                                       string // "%s" is the environment
+	pausedRobots                                                      string
+	keyRejectedAdvice                                                 string
+}
+
+func tradeAlertCopyFor(locale string) tradeAlertCopy {
+	switch normalizeEmailLocale(locale) {
+	case "en":
+		return tradeAlertCopy{
+			robot: "Robot", symbol: "Symbol", quantity: "Quantity", purchasePrice: "Purchase price", amount: "Amount", reason: "Reason", operation: "Operation",
+			fillPrice:        "Fill price: %s (bought at %s)",
+			realizedProfit:   "Realized PnL: %+.2f (before fees)",
+			takeProfitFilled: "Take-profit filled", stopLossTriggered: "Stop-loss triggered", takeProfitExpired: "Take-profit expired",
+			takeProfitExpiredNote: "The take-profit order was cancelled; the position is still open and unprotected.",
+			dailyPurchaseFailed:   "Daily purchase failed",
+			keyRejectedSubject:    "Binance API key rejected",
+			keyRejected:           "Binance rejected the API key stored for your %s environment.",
+			pausedRobots:          "Paused robots",
+			keyRejectedAdvice:     "Open positions are no longer monitored. Save a new API key, then re-enable your robots to resume.",
+		}
+	case "es":
+		return tradeAlertCopy{
+			robot: "Robot", symbol: "Par", quantity: "Cantidad", purchasePrice: "Precio de compra", amount: "Monto", reason: "Motivo", operation: "Operación",
+			fillPrice:        "Precio de venta: %s (comprado a %s)",
+			realizedProfit:   "PnL realizado: %+.2f (antes de comisiones)",
+			takeProfitFilled: "Take-profit ejecutado", stopLossTriggered: "Stop-loss activado", takeProfitExpired: "Take-profit vencido",
+			takeProfitExpiredNote: "La orden de take-profit fue cancelada; la posición sigue abierta y sin protección.",
+			dailyPurchaseFailed:   "La compra diaria falló",
+			keyRejectedSubject:    "Clave de API de Binance rechazada",
+			keyRejected:           "Binance rechazó la clave de API guardada para tu entorno %s.",
+			pausedRobots:          "Robots pausados",
+			keyRejectedAdvice:     "Las posiciones abiertas ya no se monitorean. Guarda una nueva clave de API y vuelve a activar tus robots para continuar.",
+		}
+	default:
+		return tradeAlertCopy{
+			robot: "Robô", symbol: "Par", quantity: "Quantidade", purchasePrice: "Preço de compra", amount: "Valor", reason: "Motivo", operation: "Operação",
+			fillPrice:        "Preço de venda: %s (comprado a %s)",
+			realizedProfit:   "Lucro realizado: %+.2f (antes das taxas)",
+			takeProfitFilled: "Take-profit executado", stopLossTriggered: "Stop-loss acionado", takeProfitExpired: "Take-profit expirado",
+			takeProfitExpiredNote: "A ordem de take-profit foi cancelada; a posição continua aberta e sem proteção.",
+			dailyPurchaseFailed:   "Falha na compra diária",
+			keyRejectedSubject:    "Chave de API da Binance recusada",
+			keyRejected:           "A Binance recusou a chave de API salva para o seu ambiente %s.",
+			pausedRobots:          "Robôs pausados",
+			keyRejectedAdvice:     "As posições abertas não são mais monitoradas. Salve uma nova chave de API e reative seus robôs para retomar.",
+		}
+	}
+}
+
+// tradeEventMessage renders the channel-neutral message for a trade event in the given locale.
+func tradeEventMessage(locale string, event domain.TradeEvent) notification.Message {
+	alertCopy := tradeAlertCopyFor(locale)
+	robotLabel := event.RobotName
+	if robotLabel == "" {
+		robotLabel = event.TradingPairSymbol
+	}
+	environmentSuffix := ""
+	if event.BinanceEnvironment != "" && event.BinanceEnvironment != domain.BinanceEnvironmentProduction {
+		environmentSuffix = " [" + strings.ToLower(event.BinanceEnvironment) + "]"
+	}
+
+	var subject string
+	lines := []string{alertCopy.robot + ": " + robotLabel, alertCopy.symbol + ": " + event.TradingPairSymbol}
+	switch event.EventType {
+	case domain.TradeEventTakeProfitFilled:
+		subject = fmt.Sprintf("%s: %s%s", alertCopy.takeProfitFilled, event.TradingPairSymbol, environmentSuffix)
+		lines = append(lines, tradeFillLines(alertCopy, event)...)
+	case domain.TradeEventStopLossTriggered:
+		subject = fmt.Sprintf("%s: %s%s", alertCopy.stopLossTriggered, event.TradingPairSymbol, environmentSuffix)
+		lines = append(lines, tradeFillLines(alertCopy, event)...)
+	case domain.TradeEventTakeProfitExpired:
+		subject = fmt.Sprintf("%s: %s%s", alertCopy.takeProfitExpired, event.TradingPairSymbol, environmentSuffix)
+		lines = append(lines,
+			fmt.Sprintf("%s: %s", alertCopy.quantity, formatTradeNumber(event.Quantity)),
+			fmt.Sprintf("%s: %s", alertCopy.purchasePrice, formatTradeNumber(event.PurchasePrice)),
+			alertCopy.takeProfitExpiredNote,
+		)
+	case domain.TradeEventDailyPurchaseFailed:
+		subject = fmt.Sprintf("%s: %s%s", alertCopy.dailyPurchaseFailed, event.TradingPairSymbol, environmentSuffix)
+		lines = append(lines, fmt.Sprintf("%s: %s", alertCopy.amount, formatTradeNumber(event.QuoteAmount)))
+		if event.ErrorMessage != "" {
+			lines = append(lines, alertCopy.reason+": "+event.ErrorMessage)
+		}
+	default:
+		subject = fmt.Sprintf("%s: %s%s", event.EventType, event.TradingPairSymbol, environmentSuffix)
+	}
+	if event.OperationIdentifier > 0 {
+		lines = append(lines, fmt.Sprintf("%s: #%d", alertCopy.operation, event.OperationIdentifier))
+	}
+	return notification.Message{
+		EventType: event.EventType,
+		Subject:   "Coin Hub — " + subject,
+		Text:      strings.Join(lines, "\n"),
+	}
+}
+
+// credentialInvalidMessage tells the user Binance rejected their key and which robots were paused.
+func credentialInvalidMessage(locale string, event domain.TradeEvent) notification.Message {
+	alertCopy := tradeAlertCopyFor(locale)
+	environmentLabel := strings.ToLower(event.BinanceEnvironment)
+	lines := []string{fmt.Sprintf(alertCopy.keyRejected, environmentLabel)}
+	if event.ErrorMessage != "" {
+		lines = append(lines, alertCopy.reason+": "+event.ErrorMessage)
+	}
+	if len(event.PausedRobotNames) > 0 {
+		lines = append(lines, alertCopy.pausedRobots+": "+strings.Join(event.PausedRobotNames, ", "))
+	}
+	lines = append(lines, alertCopy.keyRejectedAdvice)
+	return notification.Message{
+		EventType: event.EventType,
+		Subject:   fmt.Sprintf("Coin Hub — %s [%s]", alertCopy.keyRejectedSubject, environmentLabel),
+		Text:      strings.Join(lines, "\n"),
+	}
+}
+
+func tradeFillLines(alertCopy tradeAlertCopy, event domain.TradeEvent) []string {
+	lines := []string{
+		fmt.Sprintf("%s: %s", alertCopy.quantity, formatTradeNumber(event.Quantity)),
+		fmt.Sprintf(alertCopy.fillPrice, formatTradeNumber(event.FillPrice), formatTradeNumber(event.PurchasePrice)),
+	}
+	if event.RealizedProfit != nil {
+		lines = append(lines, fmt.Sprintf(alertCopy.realizedProfit, *event.RealizedProfit))
+	}
+	return lines
+}
+
+// formatTradeNumber prints up to 8 decimals without trailing zeros.
+func formatTradeNumber(value float64) string {
+	formatted := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.8f", value), "0"), ".")
+	if formatted == "" || formatted == "-" {
+		return "0"
+	}
+	return formatted
+}
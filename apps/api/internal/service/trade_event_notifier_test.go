@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+)
+
+// immediateTransactionRunner runs the work directly, without a database.
+type immediateTransactionRunner struct{}
+
+func (immediateTransactionRunner) RunInTransaction(operationContext context.Context, work func(transactionContext context.Context) error) error {
+	return work(operationContext)
+}
+
+// memoryAlertClaims is an in-memory dailyAlertClaimer keyed like trade_alert_claims.
+type memoryAlertClaims map[string]bool
+
+func (claims memoryAlertClaims) ClaimDailyAlert(_ context.Context, _ int64, robotIdentifier int64, eventType string, day time.Time) (bool, error) {
+	key := fmt.Sprintf("%d|%s|%s", robotIdentifier, eventType, day.UTC().Format("2006-01-02"))
+	if claims[key] {
+		return false, nil
+	}
+	claims[key] = true
+	return true, nil
+}
+
+type recordingPublisher struct {
+	published []events.Event
+}
+
+func (publisher *recordingPublisher) Publish(_ context.Context, _ int64, event events.Event) error {
+	publisher.published = append(publisher.published, event)
+	return nil
+}
+
+func TestFailedDailyPurchaseIsAlertedOncePerRobotAndDay(t *testing.T) {
+	publisher := &recordingPublisher{}
+	worker := &AutomationWorker{alertClaimer: memoryAlertClaims{}, transactionRunner: immediateTransactionRunner{}, eventPublisher: publisher}
+	firstDay := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	failure := domain.TradeEvent{EventType: domain.TradeEventDailyPurchaseFailed, UserIdentifier: 7, TradingPairSymbol: "BTCUSDT", ErrorMessage: "insufficient balance"}
+
+	cases := []struct {
+		name              string
+		robot             domain.TradingRobot
+		day               time.Time
+		retries           int
+		expectedPublished int
+	}{
+		{name: "every retry during the purchase hour", robot: domain.TradingRobot{Identifier: 1}, day: firstDay, retries: 12, expectedPublished: 1},
+		{name: "another robot the same day", robot: domain.TradingRobot{Identifier: 2}, day: firstDay, retries: 3, expectedPublished: 2},
+		{name: "the first robot the next day", robot: domain.TradingRobot{Identifier: 1}, day: firstDay.AddDate(0, 0, 1), retries: 12, expectedPublished: 3},
+	}
+	for _, testCase := range cases {
+		for retry := 0; retry < testCase.retries; retry++ {
+			robot := testCase.robot
+			if publishError := worker.publishDailyPurchaseFailure(context.Background(), &robot, testCase.day, failure); publishError != nil {
+				t.Fatalf("%s: %v", testCase.name, publishError)
+			}
+		}
+		if len(publisher.published) != testCase.expectedPublished {
+			t.Errorf("%s: %d alerts published in total, expected %d", testCase.name, len(publisher.published), testCase.expectedPublished)
+		}
+	}
+}
+
+func TestTradeEventMessagesFollowTheUserLocale(t *testing.T) {
+	profit := 1.5
+	event := domain.TradeEvent{EventType: domain.TradeEventTakeProfitFilled, RobotName: "BTC bot", TradingPairSymbol: "BTCUSDT", Quantity: 0.001, FillPrice: 61000, PurchasePrice: 60000, RealizedProfit: &profit}
+	cases := []struct {
+		locale           string
+		expectedSubject  string
+		expectedFragment string
+	}{
+		{locale: "en", expectedSubject: "Coin Hub — Take-profit filled: BTCUSDT", expectedFragment: "Fill price: 61000 (bought at 60000)"},
+		{locale: "es", expectedSubject: "Coin Hub — Take-profit ejecutado: BTCUSDT", expectedFragment: "Precio de venta: 61000 (comprado a 60000)"},
+		{locale: "pt", expectedSubject: "Coin Hub — Take-profit executado: BTCUSDT", expectedFragment: "Lucro realizado: +1.50 (antes das taxas)"},
+		{locale: "", expectedSubject: "Coin Hub — Take-profit executado: BTCUSDT", expectedFragment: "Robô: BTC bot"},
+		{locale: "de", expectedSubject: "Coin Hub — Take-profit executado: BTCUSDT", expectedFragment: "Quantidade: 0.001"},
+	}
+	for _, testCase := range cases {
+		message := tradeEventMessage(testCase.locale, event)
+		if message.Subject != testCase.expectedSubject {
+			t.Errorf("%q: subject %q, expected %q", testCase.locale, message.Subject, testCase.expectedSubject)
+		}
+		if !strings.Contains(message.Text, testCase.expectedFragment) {
+			t.Errorf("%q: text %q does not contain %q", testCase.locale, message.Text, testCase.expectedFragment)
+		}
+	}
+
+	rejected := credentialInvalidMessage("es", domain.TradeEvent{EventType: domain.TradeEventCredentialInvalid, BinanceEnvironment: domain.BinanceEnvironmentProduction, PausedRobotNames: []string{"BTC bot"}})
+	if !strings.Contains(rejected.Subject, "Clave de API de Binance rechazada") || !strings.Contains(rejected.Text, "Robots pausados: BTC bot") {
+		t.Errorf("unexpected Spanish key rejection message: %+v", rejected)
+	}
+}
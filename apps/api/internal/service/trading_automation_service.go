@@ -2,7 +2,6 @@ package service
 
 import (
         "context"
-        "log"
         "time"
 
         "coin-alert/internal/domain"
@@ -37,7 +36,7 @@ func (service *TradingAutomationService) startAutomaticSellLoop(applicationConte
         for {
                 select {
                 case <-applicationContext.Done():
-                        log.Println("Automatic sell loop stopped")
+                        automationLogger.Info("automatic sell loop stopped")
                         return
                 case <-ticker.C:
                         service.EvaluateAndSellProfitableOperations(applicationContext)
@@ -51,14 +50,14 @@ func (service *TradingAutomationService) EvaluateAndSellProfitableOperations(app
 
         currentPrice, priceError := service.BinancePriceService.GetCurrentPrice(priceLookupContext, service.TradingPairSymbol)
         if priceError != nil {
-                log.Printf("Could not fetch current price for %s: %v", service.TradingPairSymbol, priceError)
+                automationLogger.Error("could not fetch the current price", "symbol", service.TradingPairSymbol, "error", priceError)
                 service.recordExecutionFailure(applicationContext, priceError)
                 return
         }
 
         openOperations, openFetchError := service.TradingOperationService.ListOpenOperations(priceLookupContext)
         if openFetchError != nil {
-                log.Printf("Could not list open operations: %v", openFetchError)
+                automationLogger.Error("could not list open operations", "error", openFetchError)
         }
 
         totalQuantitySold := 0.0
@@ -75,7 +74,7 @@ func (service *TradingAutomationService) EvaluateAndSellProfitableOperations(app
 
         closeError := service.TradingOperationService.CloseOperationsThatReachedTargetPrice(closeContext, currentPrice)
         if closeError != nil {
-                log.Printf("Could not close profitable operations: %v", closeError)
+                automationLogger.Error("could not close profitable operations", "error", closeError)
                 service.recordExecutionFailure(applicationContext, closeError)
                 return
         }
@@ -101,7 +100,7 @@ func (service *TradingAutomationService) recordExecutionFailure(applicationConte
         }
         _, logError := service.TradingScheduleService.LogExecution(executionContext, executionRecord)
         if logError != nil {
-                log.Printf("Could not log failed execution: %v", logError)
+                automationLogger.Error("could not log the failed execution", "error", logError)
         }
 }
 
@@ -120,6 +119,6 @@ func (service *TradingAutomationService) recordExecutionSuccess(applicationConte
 
         _, logError := service.TradingScheduleService.LogExecution(executionContext, executionRecord)
         if logError != nil {
-                log.Printf("Could not log execution: %v", logError)
+                automationLogger.Error("could not log the execution", "error", logError)
         }
 }
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/repository"
+)
+
+// newExecution builds an execution history row stamped now.
+func newExecution(environment string, initiatedBy string, tradingPairSymbol string, operationType string, unitPrice float64, quantity float64, success bool, cause error, orderIdentifier *string) domain.TradingOperationExecution {
+	var errorMessage *string
+	if cause != nil {
+		message := cause.Error()
+		errorMessage = &message
+	}
+	return domain.TradingOperationExecution{
+		TradingPairSymbol:  tradingPairSymbol,
+		OperationType:      operationType,
+		BinanceEnvironment: environment,
+		InitiatedBy:        initiatedBy,
+		UnitPrice:          unitPrice,
+		Quantity:           quantity,
+		TotalValue:         unitPrice * quantity,
+		ExecutedAt:         time.Now(),
+		Success:            success,
+		ErrorMessage:       errorMessage,
+		OrderIdentifier:    orderIdentifier,
+	}
+}
+
+// recordExecution writes the execution row and publishes ExecutionLogged. Executions stay part of the
+// trading transaction (not a subscriber projection) because the daily-buy guard reads them to keep
+// the DCA purchase idempotent.
+func recordExecution(transactionContext context.Context, executionRepository repository.UserTradingOperationExecutionRepository, eventPublisher events.Publisher, userIdentifier int64, execution domain.TradingOperationExecution) error {
+	executionIdentifier, logError := executionRepository.LogExecutionForUser(transactionContext, userIdentifier, execution)
+	if logError != nil {
+		return logError
+	}
+	execution.Identifier = executionIdentifier
+	return eventPublisher.Publish(transactionContext, userIdentifier, events.ExecutionLogged{Execution: execution})
+}
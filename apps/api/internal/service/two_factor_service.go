@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+var (
+	ErrTwoFactorUnavailable     = errors.New("two-factor authentication is unavailable: encryption is not configured")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolling    = errors.New("start the two-factor setup first")
+	ErrInvalidTwoFactorCode     = errors.New("the authentication code is incorrect")
+	ErrTwoFactorChallengeFailed = errors.New("the sign-in attempt expired; sign in again")
+	// ErrTwoFactorRequired is returned when the platform policy requires 2FA for what the user tried.
+	ErrTwoFactorRequired = errors.New("enable two-factor authentication before turning on live trading")
+	// ErrLiveTradingNeedsTwoFactor is returned when disabling 2FA would leave live trading on although
+	// the platform policy requires 2FA for it.
+	ErrLiveTradingNeedsTwoFactor = errors.New("turn off live trading before disabling two-factor authentication")
+)
+
+const (
+	twoFactorLoginChallengeTTL         = 5 * time.Minute
+	twoFactorLoginChallengeMaxAttempts = 5
+	recoveryCodeCount                  = 10
+	// recoveryCodeLength is in base32 characters (5 bits each), so a code carries 80 random bits.
+	recoveryCodeLength = 16
+)
+
+// TwoFactor verification methods, as reported by VerifyCode.
+const (
+	TwoFactorMethodTOTP         = "totp"
+	TwoFactorMethodRecoveryCode = "recovery_code"
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorStatus is what the account page shows about the user's 2FA.
+type TwoFactorStatus struct {
+	Enabled                bool
+	EnabledAt              *time.Time
+	RemainingRecoveryCodes int
+}
+
+// TwoFactorEnrollment is a pending TOTP secret for the user to add to their authenticator app.
+type TwoFactorEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// TwoFactorService manages TOTP enrollment, recovery codes and the login challenge between the
+// password (or Google) step and the second factor.
+type TwoFactorService struct {
+	repository      repository.TwoFactorRepository
+	tokenRepository repository.AuthTokenRepository
+	userRepository  repository.UserRepository
+	// settingsRepository is checked when 2FA goes away while the policy requires it for live trading.
+	settingsRepository repository.UserTradingSettingsRepository
+	cipher             *security.SecretCipher
+	transactionRunner  repository.TransactionRunner
+	policyService      *PlatformPolicyService
+	auditRecorder      AuditRecorder
+	// rateLimitService delays and then locks code checks after repeated wrong codes; nil disables it.
+	rateLimitService *AuthRateLimitService
+	issuer           string
+}
+
+func NewTwoFactorService(repositoryInstance repository.TwoFactorRepository, tokenRepository repository.AuthTokenRepository, userRepository repository.UserRepository, settingsRepository repository.UserTradingSettingsRepository, cipher *security.SecretCipher, transactionRunner repository.TransactionRunner, policyService *PlatformPolicyService, auditRecorder AuditRecorder, rateLimitService *AuthRateLimitService, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		repository:         repositoryInstance,
+		tokenRepository:    tokenRepository,
+		userRepository:     userRepository,
+		settingsRepository: settingsRepository,
+		cipher:             cipher,
+		transactionRunner:  transactionRunner,
+		policyService:      policyService,
+		auditRecorder:      auditRecorder,
+		rateLimitService:   rateLimitService,
+		issuer:             issuer,
+	}
+}
+
+// IsEnabled reports whether the user has confirmed a TOTP enrollment.
+func (service *TwoFactorService) IsEnabled(lookupContext context.Context, userIdentifier int64) (bool, error) {
+	twoFactor, loadError := service.repository.LoadTwoFactor(lookupContext, userIdentifier)
+	if loadError != nil {
+		return false, loadError
+	}
+	return twoFactor.IsEnabled(), nil
+}
+
+func (service *TwoFactorService) GetStatus(lookupContext context.Context, userIdentifier int64) (TwoFactorStatus, error) {
+	twoFactor, loadError := service.repository.LoadTwoFactor(lookupContext, userIdentifier)
+	if loadError != nil {
+		return TwoFactorStatus{}, loadError
+	}
+	if !twoFactor.IsEnabled() {
+		return TwoFactorStatus{}, nil
+	}
+	remaining, countError := service.repository.CountUnusedRecoveryCodes(lookupContext, userIdentifier)
+	if countError != nil {
+		return TwoFactorStatus{}, countError
+	}
+	return TwoFactorStatus{Enabled: true, EnabledAt: twoFactor.EnabledAt, RemainingRecoveryCodes: remaining}, nil
+}
+
+// BeginEnrollment creates a fresh secret for the user to scan. Calling it again before confirming
+// replaces the pending secret.
+func (service *TwoFactorService) BeginEnrollment(operationContext context.Context, userIdentifier int64) (TwoFactorEnrollment, error) {
+	if service.cipher == nil {
+		return TwoFactorEnrollment{}, ErrTwoFactorUnavailable
+	}
+	existing, loadError := service.repository.LoadTwoFactor(operationContext, userIdentifier)
+	if loadError != nil {
+		return TwoFactorEnrollment{}, loadError
+	}
+	if existing.IsEnabled() {
+		return TwoFactorEnrollment{}, ErrTwoFactorAlreadyEnabled
+	}
+	user, userError := service.userRepository.FindByIdentifier(operationContext, userIdentifier)
+	if userError != nil {
+		return TwoFactorEnrollment{}, userError
+	}
+
+	secret, secretError := security.GenerateTOTPSecret()
+	if secretError != nil {
+		return TwoFactorEnrollment{}, secretError
+	}
+	secretCiphertext, encryptError := service.cipher.EncryptString(secret)
+	if encryptError != nil {
+		return TwoFactorEnrollment{}, encryptError
+	}
+	if saveError := service.repository.SavePendingTwoFactor(operationContext, userIdentifier, secretCiphertext); saveError != nil {
+		return TwoFactorEnrollment{}, saveError
+	}
+	return TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(service.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves their app produces valid codes, and returns the
+// recovery codes. They are shown only this once.
+func (service *TwoFactorService) ConfirmEnrollment(operationContext context.Context, userIdentifier int64, code string) ([]string, error) {
+	pending, loadError := service.repository.LoadTwoFactor(operationContext, userIdentifier)
+	if loadError != nil {
+		return nil, loadError
+	}
+	if pending == nil {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+	if pending.IsEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	secret, decryptError := service.decryptSecret(pending)
+	if decryptError != nil {
+		return nil, decryptError
+	}
+	matchedStep, verified := security.VerifyTOTPCode(secret, code, time.Now())
+	if !verified {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	recoveryCodes, codeHashes, generationError := generateRecoveryCodes()
+	if generationError != nil {
+		return nil, generationError
+	}
+	enableError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		if enableError := service.repository.EnableTwoFactor(transactionContext, userIdentifier, matchedStep); enableError != nil {
+			return enableError
+		}
+		return service.repository.ReplaceRecoveryCodes(transactionContext, userIdentifier, codeHashes)
+	})
+	if enableError != nil {
+		return nil, enableError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionTwoFactorEnabled,
+		TargetType:     "two_factor",
+	})
+	return recoveryCodes, nil
+}
+
+// VerifyCode accepts a current TOTP code or an unused recovery code (which is then spent) and reports
+// which one it was. A TOTP code is accepted once only. Every caller (sign-in, step-up, disabling 2FA,
+// new recovery codes) shares one failure count per account: after a few wrong codes each attempt has
+// to wait, and after ten it returns a *RateLimitedError until the lockout ends.
+func (service *TwoFactorService) VerifyCode(operationContext context.Context, userIdentifier int64, code string) (string, error) {
+	if blockError := service.rateLimitService.CheckIdentityCheckBlock(operationContext, userIdentifier); blockError != nil {
+		return "", blockError
+	}
+	method, verifyError := service.verifyCode(operationContext, userIdentifier, code)
+	switch {
+	case errors.Is(verifyError, ErrInvalidTwoFactorCode):
+		service.rateLimitService.RecordIdentityCheckFailure(operationContext, userIdentifier)
+	case verifyError == nil:
+		service.rateLimitService.RecordIdentityCheckSuccess(operationContext, userIdentifier)
+	}
+	return method, verifyError
+}
+
+func (service *TwoFactorService) verifyCode(operationContext context.Context, userIdentifier int64, code string) (string, error) {
+	twoFactor, loadError := service.repository.LoadTwoFactor(operationContext, userIdentifier)
+	if loadError != nil {
+		return "", loadError
+	}
+	if !twoFactor.IsEnabled() {
+		return "", ErrTwoFactorNotEnabled
+	}
+
+	normalizedCode := strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(normalizedCode) == security.TOTPDigits {
+		secret, decryptError := service.decryptSecret(twoFactor)
+		if decryptError != nil {
+			return "", decryptError
+		}
+		matchedStep, verified := security.VerifyTOTPCode(secret, normalizedCode, time.Now())
+		if !verified {
+			return "", ErrInvalidTwoFactorCode
+		}
+		consumed, consumeError := service.repository.ConsumeTimeStep(operationContext, userIdentifier, matchedStep)
+		if consumeError != nil {
+			return "", consumeError
+		}
+		if !consumed {
+			return "", ErrInvalidTwoFactorCode
+		}
+		return TwoFactorMethodTOTP, nil
+	}
+
+	used, useError := service.repository.UseRecoveryCode(operationContext, userIdentifier, hashRecoveryCode(normalizedCode))
+	if useError != nil {
+		return "", useError
+	}
+	if !used {
+		return "", ErrInvalidTwoFactorCode
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionRecoveryCodeUsed,
+		TargetType:     "two_factor",
+	})
+	return TwoFactorMethodRecoveryCode, nil
+}
+
+// Disable turns 2FA off after checking a code, so a hijacked session alone cannot remove it. While
+// the policy requires 2FA for live trading, it is refused until live trading is off everywhere.
+func (service *TwoFactorService) Disable(operationContext context.Context, userIdentifier int64, code string) error {
+	if _, verifyError := service.VerifyCode(operationContext, userIdentifier, code); verifyError != nil {
+		return verifyError
+	}
+	policy, policyError := service.policyService.GetPolicy(operationContext)
+	if policyError != nil {
+		return policyError
+	}
+	disableError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		if policy.RequireTwoFactorForLiveTrading {
+			liveTradingEnabled, lookupError := service.settingsRepository.HasLiveTradingEnabled(transactionContext, userIdentifier)
+			if lookupError != nil {
+				return lookupError
+			}
+			if liveTradingEnabled {
+				return ErrLiveTradingNeedsTwoFactor
+			}
+		}
+		return service.repository.DeleteTwoFactor(transactionContext, userIdentifier)
+	})
+	if disableError != nil {
+		return disableError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionTwoFactorDisabled,
+		TargetType:     "two_factor",
+	})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a code.
+func (service *TwoFactorService) RegenerateRecoveryCodes(operationContext context.Context, userIdentifier int64, code string) ([]string, error) {
+	if _, verifyError := service.VerifyCode(operationContext, userIdentifier, code); verifyError != nil {
+		return nil, verifyError
+	}
+	recoveryCodes, codeHashes, generationError := generateRecoveryCodes()
+	if generationError != nil {
+		return nil, generationError
+	}
+	if replaceError := service.repository.ReplaceRecoveryCodes(operationContext, userIdentifier, codeHashes); replaceError != nil {
+		return nil, replaceError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionRecoveryCodesRenewed,
+		TargetType:     "two_factor",
+	})
+	return recoveryCodes, nil
+}
+
+// ResetForUser removes a user's 2FA on an admin's behalf, for users who lost both their device and
+// their recovery codes. The user cannot turn live trading off first, so while the policy requires 2FA
+// for it, live trading is switched off in the same transaction. The entries land in the user's audit
+// log with the admin as actor.
+func (service *TwoFactorService) ResetForUser(operationContext context.Context, targetUserIdentifier int64) error {
+	twoFactor, loadError := service.repository.LoadTwoFactor(operationContext, targetUserIdentifier)
+	if loadError != nil {
+		return loadError
+	}
+	if twoFactor == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	policy, policyError := service.policyService.GetPolicy(operationContext)
+	if policyError != nil {
+		return policyError
+	}
+	var liveTradingEnvironments []string
+	resetError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		if deleteError := service.repository.DeleteTwoFactor(transactionContext, targetUserIdentifier); deleteError != nil {
+			return deleteError
+		}
+		if !policy.RequireTwoFactorForLiveTrading {
+			return nil
+		}
+		var disableError error
+		liveTradingEnvironments, disableError = service.settingsRepository.DisableLiveTradingForUser(transactionContext, targetUserIdentifier)
+		return disableError
+	})
+	if resetError != nil {
+		return resetError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: targetUserIdentifier,
+		Action:         domain.AuditActionTwoFactorReset,
+		TargetType:     "two_factor",
+	})
+	for _, environment := range liveTradingEnvironments {
+		recordAudit(operationContext, service.auditRecorder, AuditRecord{
+			UserIdentifier: targetUserIdentifier,
+			Action:         domain.AuditActionLiveTradingToggled,
+			TargetType:     "trading_settings",
+			Before:         map[string]interface{}{"environment": environment, "live_trading_enabled": true},
+			After:          map[string]interface{}{"environment": environment, "live_trading_enabled": false},
+		})
+	}
+	return nil
+}
+
+// BeginLoginChallenge is called after the first factor succeeded for a user with 2FA. The returned
+// token stands in for the session until the second factor is verified.
+func (service *TwoFactorService) BeginLoginChallenge(operationContext context.Context, userIdentifier int64) (string, error) {
+	_ = service.tokenRepository.InvalidateUserTokens(operationContext, userIdentifier, repository.AuthTokenPurposeTwoFactorLogin)
+	rawToken, tokenError := generateSessionToken()
+	if tokenError != nil {
+		return "", tokenError
+	}
+	if createError := service.tokenRepository.CreateToken(operationContext, userIdentifier, repository.AuthTokenPurposeTwoFactorLogin, hashSessionToken(rawToken), time.Now().Add(twoFactorLoginChallengeTTL)); createError != nil {
+		return "", createError
+	}
+	return rawToken, nil
+}
+
+// CompleteLoginChallenge checks the second factor for a login challenge and returns the user to sign
+// in. Too many wrong codes retire the challenge.
+func (service *TwoFactorService) CompleteLoginChallenge(operationContext context.Context, rawToken string, code string) (int64, error) {
+	token, lookupError := service.tokenRepository.FindValidByHash(operationContext, hashSessionToken(rawToken), repository.AuthTokenPurposeTwoFactorLogin)
+	if errors.Is(lookupError, repository.ErrAuthTokenInvalid) {
+		return 0, ErrTwoFactorChallengeFailed
+	}
+	if lookupError != nil {
+		return 0, lookupError
+	}
+	if _, verifyError := service.VerifyCode(operationContext, token.UserIdentifier, code); verifyError != nil {
+		if errors.Is(verifyError, ErrInvalidTwoFactorCode) {
+			_ = service.tokenRepository.RecordFailedAttempt(operationContext, token.Identifier, twoFactorLoginChallengeMaxAttempts)
+		}
+		return 0, verifyError
+	}
+	_ = service.tokenRepository.MarkUsed(operationContext, token.Identifier)
+	return token.UserIdentifier, nil
+}
+
+// EnsureLiveTradingAllowed returns ErrTwoFactorRequired when the platform policy requires 2FA for
+// live trading and the user has not enabled it.
+func (service *TwoFactorService) EnsureLiveTradingAllowed(operationContext context.Context, userIdentifier int64) error {
+	if service == nil {
+		return nil
+	}
+	policy, policyError := service.policyService.GetPolicy(operationContext)
+	if policyError != nil {
+		return policyError
+	}
+	if !policy.RequireTwoFactorForLiveTrading {
+		return nil
+	}
+	enabled, lookupError := service.IsEnabled(operationContext, userIdentifier)
+	if lookupError != nil {
+		return lookupError
+	}
+	if !enabled {
+		return ErrTwoFactorRequired
+	}
+	return nil
+}
+
+func (service *TwoFactorService) decryptSecret(twoFactor *domain.UserTwoFactor) (string, error) {
+	if service.cipher == nil {
+		return "", ErrTwoFactorUnavailable
+	}
+	return service.cipher.DecryptString(twoFactor.SecretCiphertext)
+}
+
+// generateRecoveryCodes returns display codes ("abcd-efgh-ijkl-mnop") and the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for len(recoveryCodes) < recoveryCodeCount {
+		randomBytes := make([]byte, recoveryCodeLength*5/8)
+		if _, randomError := rand.Read(randomBytes); randomError != nil {
+			return nil, nil, randomError
+		}
+		rawCode := strings.ToLower(recoveryCodeEncoding.EncodeToString(randomBytes))
+		recoveryCodes = append(recoveryCodes, rawCode[0:4]+"-"+rawCode[4:8]+"-"+rawCode[8:12]+"-"+rawCode[12:16])
+		codeHashes = append(codeHashes, hashRecoveryCode(rawCode))
+	}
+	return recoveryCodes, codeHashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code as typed, ignoring case and separators. The codes carry 80
+// random bits, so a fast hash is enough (as for session tokens).
+func hashRecoveryCode(code string) string {
+	normalizedCode := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return hashSessionToken(normalizedCode)
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+// memoryTwoFactorRepository is an in-memory repository.TwoFactorRepository.
+type memoryTwoFactorRepository struct {
+	mutex         sync.Mutex
+	enrollments   map[int64]*domain.UserTwoFactor
+	recoveryCodes map[int64]map[string]bool
+}
+
+func newMemoryTwoFactorRepository() *memoryTwoFactorRepository {
+	return &memoryTwoFactorRepository{enrollments: map[int64]*domain.UserTwoFactor{}, recoveryCodes: map[int64]map[string]bool{}}
+}
+
+func (repository *memoryTwoFactorRepository) LoadTwoFactor(_ context.Context, userIdentifier int64) (*domain.UserTwoFactor, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if enrollment := repository.enrollments[userIdentifier]; enrollment != nil {
+		copied := *enrollment
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (repository *memoryTwoFactorRepository) SavePendingTwoFactor(_ context.Context, userIdentifier int64, secretCiphertext string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.enrollments[userIdentifier] = &domain.UserTwoFactor{UserIdentifier: userIdentifier, SecretCiphertext: secretCiphertext}
+	return nil
+}
+
+func (repository *memoryTwoFactorRepository) EnableTwoFactor(_ context.Context, userIdentifier int64, timeStep int64) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	enabledAt := time.Now()
+	repository.enrollments[userIdentifier].EnabledAt = &enabledAt
+	repository.enrollments[userIdentifier].LastUsedTimeStep = timeStep
+	return nil
+}
+
+func (repository *memoryTwoFactorRepository) ConsumeTimeStep(_ context.Context, userIdentifier int64, timeStep int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	enrollment := repository.enrollments[userIdentifier]
+	if timeStep <= enrollment.LastUsedTimeStep {
+		return false, nil
+	}
+	enrollment.LastUsedTimeStep = timeStep
+	return true, nil
+}
+
+func (repository *memoryTwoFactorRepository) DeleteTwoFactor(_ context.Context, userIdentifier int64) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	delete(repository.enrollments, userIdentifier)
+	delete(repository.recoveryCodes, userIdentifier)
+	return nil
+}
+
+func (repository *memoryTwoFactorRepository) ReplaceRecoveryCodes(_ context.Context, userIdentifier int64, codeHashes []string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.recoveryCodes[userIdentifier] = map[string]bool{}
+	for _, codeHash := range codeHashes {
+		repository.recoveryCodes[userIdentifier][codeHash] = false
+	}
+	return nil
+}
+
+func (repository *memoryTwoFactorRepository) UseRecoveryCode(_ context.Context, userIdentifier int64, codeHash string) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	used, exists := repository.recoveryCodes[userIdentifier][codeHash]
+	if !exists || used {
+		return false, nil
+	}
+	repository.recoveryCodes[userIdentifier][codeHash] = true
+	return true, nil
+}
+
+func (repository *memoryTwoFactorRepository) CountUnusedRecoveryCodes(_ context.Context, userIdentifier int64) (int, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	unused := 0
+	for _, used := range repository.recoveryCodes[userIdentifier] {
+		if !used {
+			unused++
+		}
+	}
+	return unused, nil
+}
+
+// lockoutOnlyRateLimitStore drops the short progressive delays so a test can reach the lockout
+// without sleeping; blocks longer than the maximum delay (the lockout) are kept.
+type lockoutOnlyRateLimitStore struct {
+	*repository.MemoryRateLimitRepository
+}
+
+func (store lockoutOnlyRateLimitStore) BlockRateLimitKey(operationContext context.Context, key string, blockedUntil time.Time) error {
+	if time.Until(blockedUntil) <= loginMaximumDelay {
+		return nil
+	}
+	return store.MemoryRateLimitRepository.BlockRateLimitKey(operationContext, key, blockedUntil)
+}
+
+const testTwoFactorUser int64 = 42
+
+// newEnabledTwoFactorService returns a service whose test user has 2FA enabled, and the user's secret.
+func newEnabledTwoFactorService(t *testing.T, rateLimitStore repository.RateLimitRepository) (*TwoFactorService, string) {
+	t.Helper()
+	secretCipher, cipherError := security.NewSecretCipher(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	if cipherError != nil {
+		t.Fatal(cipherError)
+	}
+	secret, secretError := security.GenerateTOTPSecret()
+	if secretError != nil {
+		t.Fatal(secretError)
+	}
+	secretCiphertext, encryptError := secretCipher.EncryptString(secret)
+	if encryptError != nil {
+		t.Fatal(encryptError)
+	}
+	twoFactorRepository := newMemoryTwoFactorRepository()
+	_ = twoFactorRepository.SavePendingTwoFactor(context.Background(), testTwoFactorUser, secretCiphertext)
+	_ = twoFactorRepository.EnableTwoFactor(context.Background(), testTwoFactorUser, 0)
+	rateLimitService := NewAuthRateLimitService(rateLimitStore, nil)
+	return NewTwoFactorService(twoFactorRepository, nil, nil, nil, secretCipher, immediateTransactionRunner{}, nil, nil, rateLimitService, "Coin Hub"), secret
+}
+
+func currentTOTPCode(t *testing.T, secret string, stepOffset int64) string {
+	t.Helper()
+	code, codeError := security.TOTPCode(secret, security.TOTPTimeStep(time.Now())+stepOffset)
+	if codeError != nil {
+		t.Fatal(codeError)
+	}
+	return code
+}
+
+// wrongCode is a six-digit code that differs from every code accepted right now.
+func wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+	accepted := map[string]bool{}
+	for stepOffset := int64(-1); stepOffset <= 1; stepOffset++ {
+		accepted[currentTOTPCode(t, secret, stepOffset)] = true
+	}
+	for _, candidate := range []string{"000000", "111111", "222222", "333333"} {
+		if !accepted[candidate] {
+			return candidate
+		}
+	}
+	t.Fatal("no wrong code available")
+	return ""
+}
+
+func TestTwoFactorCodeChecksLockAfterRepeatedFailures(t *testing.T) {
+	twoFactorService, secret := newEnabledTwoFactorService(t, lockoutOnlyRateLimitStore{repository.NewMemoryRateLimitRepository()})
+	operationContext := context.Background()
+	badCode := wrongCode(t, secret)
+
+	for attempt := 1; attempt <= loginLockoutThreshold; attempt++ {
+		if _, verifyError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, badCode); !errors.Is(verifyError, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: expected an invalid code error, got %v", attempt, verifyError)
+		}
+	}
+
+	// Every path that checks a code is refused now, even with the right code.
+	checks := map[string]func() error{
+		"verify": func() error {
+			_, verifyError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, currentTOTPCode(t, secret, 0))
+			return verifyError
+		},
+		"disable": func() error {
+			return twoFactorService.Disable(operationContext, testTwoFactorUser, currentTOTPCode(t, secret, 0))
+		},
+		"regenerate recovery codes": func() error {
+			_, regenerateError := twoFactorService.RegenerateRecoveryCodes(operationContext, testTwoFactorUser, currentTOTPCode(t, secret, 0))
+			return regenerateError
+		},
+	}
+	for name, check := range checks {
+		var rateLimitedError *RateLimitedError
+		if checkError := check(); !errors.As(checkError, &rateLimitedError) || !rateLimitedError.AccountLocked || !rateLimitedError.IdentityCheck {
+			t.Errorf("%s: expected the identity check lockout, got %v", name, checkError)
+		}
+	}
+	if enabled, _ := twoFactorService.IsEnabled(operationContext, testTwoFactorUser); !enabled {
+		t.Error("2FA was disabled during the lockout")
+	}
+}
+
+func TestTwoFactorCodeChecksAreDelayedAfterThreeFailures(t *testing.T) {
+	twoFactorService, secret := newEnabledTwoFactorService(t, repository.NewMemoryRateLimitRepository())
+	operationContext := context.Background()
+	badCode := wrongCode(t, secret)
+
+	for attempt := 1; attempt <= loginDelayThreshold; attempt++ {
+		if _, verifyError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, badCode); !errors.Is(verifyError, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: expected an invalid code error, got %v", attempt, verifyError)
+		}
+	}
+	var rateLimitedError *RateLimitedError
+	_, verifyError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, currentTOTPCode(t, secret, 0))
+	if !errors.As(verifyError, &rateLimitedError) || rateLimitedError.AccountLocked || rateLimitedError.RetryAfter <= 0 {
+		t.Fatalf("expected a short delay after %d failures, got %v", loginDelayThreshold, verifyError)
+	}
+}
+
+func TestTwoFactorTimeStepIsAcceptedOnce(t *testing.T) {
+	twoFactorService, secret := newEnabledTwoFactorService(t, repository.NewMemoryRateLimitRepository())
+	operationContext := context.Background()
+	code := currentTOTPCode(t, secret, 0)
+
+	method, verifyError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, code)
+	if verifyError != nil || method != TwoFactorMethodTOTP {
+		t.Fatalf("first use: got %q, %v", method, verifyError)
+	}
+	if _, replayError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, code); !errors.Is(replayError, ErrInvalidTwoFactorCode) {
+		t.Errorf("replaying the same code: expected an invalid code error, got %v", replayError)
+	}
+	if _, olderError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, currentTOTPCode(t, secret, -1)); !errors.Is(olderError, ErrInvalidTwoFactorCode) {
+		t.Errorf("a code from the previous step after a newer one was used: expected an invalid code error, got %v", olderError)
+	}
+}
+
+func TestRecoveryCodeIsSpentOnce(t *testing.T) {
+	twoFactorService, secret := newEnabledTwoFactorService(t, repository.NewMemoryRateLimitRepository())
+	operationContext := context.Background()
+
+	recoveryCodes, regenerateError := twoFactorService.RegenerateRecoveryCodes(operationContext, testTwoFactorUser, currentTOTPCode(t, secret, 0))
+	if regenerateError != nil {
+		t.Fatal(regenerateError)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(recoveryCodes))
+	}
+
+	// Typed in upper case and without dashes, as users often do.
+	typedCode := strings.ToUpper(strings.ReplaceAll(recoveryCodes[3], "-", ""))
+	method, verifyError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, typedCode)
+	if verifyError != nil || method != TwoFactorMethodRecoveryCode {
+		t.Fatalf("first use: got %q, %v", method, verifyError)
+	}
+	if _, reuseError := twoFactorService.VerifyCode(operationContext, testTwoFactorUser, recoveryCodes[3]); !errors.Is(reuseError, ErrInvalidTwoFactorCode) {
+		t.Errorf("reusing a recovery code: expected an invalid code error, got %v", reuseError)
+	}
+	status, statusError := twoFactorService.GetStatus(operationContext, testTwoFactorUser)
+	if statusError != nil || status.RemainingRecoveryCodes != recoveryCodeCount-1 {
+		t.Errorf("remaining recovery codes: got %d, %v", status.RemainingRecoveryCodes, statusError)
+	}
+}
+
+// fixedPolicyRepository serves one platform policy.
+type fixedPolicyRepository struct {
+	repository.PlatformPolicyRepository
+	policy domain.PlatformPolicy
+}
+
+func (policyRepository fixedPolicyRepository) GetPolicy(context.Context) (domain.PlatformPolicy, error) {
+	return policyRepository.policy, nil
+}
+
+// liveTradingSettingsRepository tracks which environments have live trading on; other methods are not used.
+type liveTradingSettingsRepository struct {
+	repository.UserTradingSettingsRepository
+	liveEnvironments []string
+}
+
+func (settingsRepository *liveTradingSettingsRepository) HasLiveTradingEnabled(context.Context, int64) (bool, error) {
+	return len(settingsRepository.liveEnvironments) > 0, nil
+}
+
+func (settingsRepository *liveTradingSettingsRepository) DisableLiveTradingForUser(context.Context, int64) ([]string, error) {
+	disabled := settingsRepository.liveEnvironments
+	settingsRepository.liveEnvironments = nil
+	return disabled, nil
+}
+
+// TestRemovingTwoFactorKeepsTheLiveTradingPolicy checks that 2FA never goes away while live trading is
+// on and the policy requires it: the user is asked to turn live trading off first, and an admin reset
+// turns it off with the 2FA.
+func TestRemovingTwoFactorKeepsTheLiveTradingPolicy(t *testing.T) {
+	cases := []struct {
+		name                string
+		requireTwoFactor    bool
+		liveTrading         bool
+		adminReset          bool
+		expected            error
+		expectedLiveTrading bool
+		expectedActions     []string
+	}{
+		{name: "disable without the policy", liveTrading: true, expectedLiveTrading: true, expectedActions: []string{domain.AuditActionTwoFactorDisabled}},
+		{name: "disable with live trading off", requireTwoFactor: true, expectedActions: []string{domain.AuditActionTwoFactorDisabled}},
+		{name: "disable with live trading on", requireTwoFactor: true, liveTrading: true, expected: ErrLiveTradingNeedsTwoFactor, expectedLiveTrading: true},
+		{name: "reset without the policy", liveTrading: true, adminReset: true, expectedLiveTrading: true, expectedActions: []string{domain.AuditActionTwoFactorReset}},
+		{name: "reset with live trading on", requireTwoFactor: true, liveTrading: true, adminReset: true, expectedActions: []string{domain.AuditActionTwoFactorReset, domain.AuditActionLiveTradingToggled}},
+	}
+	for _, testCase := range cases {
+		twoFactorService, secret := newEnabledTwoFactorService(t, repository.NewMemoryRateLimitRepository())
+		settingsRepository := &liveTradingSettingsRepository{}
+		if testCase.liveTrading {
+			settingsRepository.liveEnvironments = []string{domain.BinanceEnvironmentProduction}
+		}
+		auditLog := &memoryAuditLog{}
+		twoFactorService.settingsRepository = settingsRepository
+		twoFactorService.policyService = NewPlatformPolicyService(fixedPolicyRepository{policy: domain.PlatformPolicy{RequireTwoFactorForLiveTrading: testCase.requireTwoFactor}}, nil)
+		twoFactorService.auditRecorder = NewAuditService(auditLog)
+
+		var removeError error
+		if testCase.adminReset {
+			removeError = twoFactorService.ResetForUser(context.Background(), testTwoFactorUser)
+		} else {
+			removeError = twoFactorService.Disable(context.Background(), testTwoFactorUser, currentTOTPCode(t, secret, 0))
+		}
+		if !errors.Is(removeError, testCase.expected) {
+			t.Errorf("%s: expected error %v, got %v", testCase.name, testCase.expected, removeError)
+		}
+		if enabled, _ := twoFactorService.IsEnabled(context.Background(), testTwoFactorUser); enabled != (testCase.expected != nil) {
+			t.Errorf("%s: 2FA enabled=%t after the attempt", testCase.name, enabled)
+		}
+		if liveTrading := len(settingsRepository.liveEnvironments) > 0; liveTrading != testCase.expectedLiveTrading {
+			t.Errorf("%s: live trading on=%t, expected %t", testCase.name, liveTrading, testCase.expectedLiveTrading)
+		}
+		if actions := auditLog.actions(); strings.Join(actions, ",") != strings.Join(testCase.expectedActions, ",") {
+			t.Errorf("%s: expected audit actions %v, got %v", testCase.name, testCase.expectedActions, actions)
+		}
+	}
+}
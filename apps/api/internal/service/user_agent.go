@@ -0,0 +1,94 @@
+package service
+
+import "strings"
+
+// Device types reported for a session.
+const (
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypeOther   = "other"
+)
+
+// DeviceDescription is the readable summary of a User-Agent header.
+type DeviceDescription struct {
+	Browser         string
+	OperatingSystem string
+	DeviceType      string
+}
+
+// Label is the short form shown to users, e.g. "Firefox on Windows".
+func (description DeviceDescription) Label() string {
+	return description.Browser + " on " + description.OperatingSystem
+}
+
+// Key identifies the device for new-device detection. It ignores versions so a browser update does
+// not look like a new device.
+func (description DeviceDescription) Key() string {
+	return strings.ToLower(description.Browser + "|" + description.OperatingSystem + "|" + description.DeviceType)
+}
+
+// userAgentBrowsers is checked in order: most browsers also claim to be the ones they derive from
+// (Edge says Chrome and Safari, Chrome says Safari), so the specific tokens come first.
+var userAgentBrowsers = []struct {
+	token string
+	name  string
+}{
+	{"edg/", "Edge"},
+	{"edga/", "Edge"},
+	{"edgios/", "Edge"},
+	{"opr/", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"safari/", "Safari"},
+	{"curl/", "curl"},
+	{"python-requests/", "Python requests"},
+	{"go-http-client/", "Go HTTP client"},
+	{"postmanruntime/", "Postman"},
+}
+
+var userAgentOperatingSystems = []struct {
+	token string
+	name  string
+}{
+	{"ipad", "iPadOS"},
+	{"iphone", "iOS"},
+	{"android", "Android"},
+	{"windows", "Windows"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// DescribeUserAgent extracts browser, operating system and device type from a User-Agent header. It
+// only knows the common families; anything else is reported as unknown rather than guessed.
+func DescribeUserAgent(userAgent string) DeviceDescription {
+	normalizedAgent := strings.ToLower(userAgent)
+	description := DeviceDescription{Browser: "Unknown browser", OperatingSystem: "unknown system", DeviceType: DeviceTypeOther}
+	for _, browser := range userAgentBrowsers {
+		if strings.Contains(normalizedAgent, browser.token) {
+			description.Browser = browser.name
+			break
+		}
+	}
+	for _, operatingSystem := range userAgentOperatingSystems {
+		if strings.Contains(normalizedAgent, operatingSystem.token) {
+			description.OperatingSystem = operatingSystem.name
+			break
+		}
+	}
+	switch {
+	case description.OperatingSystem == "iPadOS",
+		description.OperatingSystem == "Android" && !strings.Contains(normalizedAgent, "mobile"):
+		description.DeviceType = DeviceTypeTablet
+	case strings.Contains(normalizedAgent, "mobi"), description.OperatingSystem == "iOS":
+		description.DeviceType = DeviceTypeMobile
+	case description.OperatingSystem != "unknown system":
+		description.DeviceType = DeviceTypeDesktop
+	}
+	return description
+}
@@ -0,0 +1,31 @@
+package service
+
+import "testing"
+
+func TestDescribeUserAgent(t *testing.T) {
+	cases := map[string]DeviceDescription{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0": {
+			Browser: "Edge", OperatingSystem: "Windows", DeviceType: DeviceTypeDesktop,
+		},
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15": {
+			Browser: "Safari", OperatingSystem: "macOS", DeviceType: DeviceTypeDesktop,
+		},
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/126.0 Mobile/15E148 Safari/604.1": {
+			Browser: "Chrome", OperatingSystem: "iOS", DeviceType: DeviceTypeMobile,
+		},
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Mobile Safari/537.36": {
+			Browser: "Chrome", OperatingSystem: "Android", DeviceType: DeviceTypeMobile,
+		},
+		"Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0": {
+			Browser: "Firefox", OperatingSystem: "Linux", DeviceType: DeviceTypeDesktop,
+		},
+		"curl/8.5.0": {
+			Browser: "curl", OperatingSystem: "unknown system", DeviceType: DeviceTypeOther,
+		},
+	}
+	for userAgent, expected := range cases {
+		if described := DescribeUserAgent(userAgent); described != expected {
+			t.Fatalf("%q: expected %+v, got %+v", userAgent, expected, described)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"coin-alert/internal/domain"
 	"coin-alert/internal/repository"
@@ -12,12 +13,25 @@ import (
 // ErrCredentialEncryptionUnavailable is returned when the server has no encryption key configured.
 var ErrCredentialEncryptionUnavailable = errors.New("credential encryption is not configured on the server")
 
+// ErrWithdrawalKeyRefused is returned when a key can withdraw funds and the platform policy refuses those.
+var ErrWithdrawalKeyRefused = errors.New("this API key has withdrawals enabled; create a trade-only key with withdrawals disabled")
+
+// ErrKeyPermissionsUnverified is returned when the key's permissions could not be read while the
+// platform policy refuses withdrawal-enabled keys.
+var ErrKeyPermissionsUnverified = errors.New("could not verify the API key's permissions with Binance")
+
 // CredentialStatus is a non-sensitive summary of a user's stored Binance credentials.
 type CredentialStatus struct {
 	HasActiveCredential    bool
 	ActiveEnvironment      string
 	MaskedAPIKey           string
 	ConfiguredEnvironments []string
+	// Permissions is what Binance reported for the active key when it was saved; nil when not checked
+	// (the Spot testnet has no permission endpoint).
+	Permissions        *domain.BinanceKeyPermissions
+	PermissionWarnings []string
+	// Health is the active key's last periodic revalidation result.
+	Health domain.CredentialHealth
 }
 
 // UserCredentialService validates, encrypts, stores, and retrieves per-user Binance credentials.
@@ -26,15 +40,38 @@ type UserCredentialService struct {
 	cipher            *security.SecretCipher
 	testnetBaseURL    string
 	productionBaseURL string
+	auditRecorder     AuditRecorder
+	policyService     *PlatformPolicyService
 }
 
-func NewUserCredentialService(repositoryInstance repository.UserBinanceCredentialRepository, cipher *security.SecretCipher, testnetBaseURL string, productionBaseURL string) *UserCredentialService {
+func NewUserCredentialService(repositoryInstance repository.UserBinanceCredentialRepository, cipher *security.SecretCipher, testnetBaseURL string, productionBaseURL string, auditRecorder AuditRecorder, policyService *PlatformPolicyService) *UserCredentialService {
 	return &UserCredentialService{
 		repository:        repositoryInstance,
 		cipher:            cipher,
 		testnetBaseURL:    testnetBaseURL,
 		productionBaseURL: productionBaseURL,
+		auditRecorder:     auditRecorder,
+		policyService:     policyService,
+	}
+}
+
+// credentialAuditSnapshot is what the audit log keeps of a credential change: never the keys.
+type credentialAuditSnapshot struct {
+	ActiveEnvironment  string `json:"active_environment"`
+	MaskedAPIKey       string `json:"masked_api_key"`
+	WithdrawalsEnabled *bool  `json:"withdrawals_enabled,omitempty"`
+}
+
+func (service *UserCredentialService) auditSnapshot(operationContext context.Context, userIdentifier int64) *credentialAuditSnapshot {
+	status, statusError := service.GetStatus(operationContext, userIdentifier)
+	if statusError != nil || !status.HasActiveCredential {
+		return nil
 	}
+	snapshot := &credentialAuditSnapshot{ActiveEnvironment: status.ActiveEnvironment, MaskedAPIKey: status.MaskedAPIKey}
+	if status.Permissions != nil {
+		snapshot.WithdrawalsEnabled = &status.Permissions.WithdrawalsEnabled
+	}
+	return snapshot
 }
 
 func (service *UserCredentialService) baseURLForEnvironment(environmentName string) string {
@@ -58,6 +95,10 @@ func (service *UserCredentialService) SaveAndValidate(operationContext context.C
 	if validationError := validator.ValidateCredentials(operationContext, apiKey, apiSecret); validationError != nil {
 		return validationError
 	}
+	permissions, permissionError := service.checkKeyPermissions(operationContext, validator, normalizedEnvironment, apiKey, apiSecret)
+	if permissionError != nil {
+		return permissionError
+	}
 
 	encryptedAPIKey, keyEncryptionError := service.cipher.EncryptString(apiKey)
 	if keyEncryptionError != nil {
@@ -68,13 +109,52 @@ func (service *UserCredentialService) SaveAndValidate(operationContext context.C
 		return secretEncryptionError
 	}
 
-	return service.repository.SaveCredentialForUser(operationContext, userIdentifier, domain.BinanceCredentialRecord{
+	before := service.auditSnapshot(operationContext, userIdentifier)
+	if saveError := service.repository.SaveCredentialForUser(operationContext, userIdentifier, domain.BinanceCredentialRecord{
 		APIKey:          encryptedAPIKey,
 		APISecret:       encryptedAPISecret,
 		EnvironmentName: normalizedEnvironment,
 		APIBaseURL:      baseURL,
 		IsActive:        true,
+		Permissions:     permissions,
+	}); saveError != nil {
+		return saveError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionCredentialsSaved,
+		TargetType:     "binance_environment",
+		Before:         before,
+		After:          service.auditSnapshot(operationContext, userIdentifier),
 	})
+	return nil
+}
+
+// checkKeyPermissions reads a production key's restrictions and applies the withdrawal key policy:
+// REFUSE rejects keys that can withdraw (and keys whose permissions cannot be read), FLAG stores them
+// and surfaces a warning in the status. Testnet keys are not checked.
+func (service *UserCredentialService) checkKeyPermissions(operationContext context.Context, validator *BinanceCredentialValidator, environmentName string, apiKey string, apiSecret string) (*domain.BinanceKeyPermissions, error) {
+	if environmentName != domain.BinanceEnvironmentProduction {
+		return nil, nil
+	}
+	policy, policyError := service.policyService.GetPolicy(operationContext)
+	if policyError != nil {
+		return nil, policyError
+	}
+	refuseWithdrawalKeys := policy.WithdrawalKeyPolicy != domain.WithdrawalKeyPolicyFlag
+
+	permissions, fetchError := validator.FetchKeyPermissions(operationContext, apiKey, apiSecret)
+	if fetchError != nil {
+		if refuseWithdrawalKeys {
+			return nil, fmt.Errorf("%w: %v", ErrKeyPermissionsUnverified, fetchError)
+		}
+		credentialsLogger.WarnContext(operationContext, "could not read key permissions; storing unchecked under the FLAG policy", "error", fetchError)
+		return nil, nil
+	}
+	if permissions.WithdrawalsEnabled && refuseWithdrawalKeys {
+		return nil, ErrWithdrawalKeyRefused
+	}
+	return permissions, nil
 }
 
 // LoadActiveEnvironmentConfiguration returns the decrypted active credential ready for the Binance
@@ -101,10 +181,11 @@ func (service *UserCredentialService) LoadActiveEnvironmentConfiguration(operati
 	}
 
 	return &domain.BinanceEnvironmentConfiguration{
-		EnvironmentName: record.EnvironmentName,
-		RESTBaseURL:     record.APIBaseURL,
-		APIKey:          apiKey,
-		APISecret:       apiSecret,
+		EnvironmentName:  record.EnvironmentName,
+		RESTBaseURL:      record.APIBaseURL,
+		APIKey:           apiKey,
+		APISecret:        apiSecret,
+		CredentialHealth: record.Health.Status,
 	}, nil
 }
 
@@ -118,7 +199,18 @@ func (service *UserCredentialService) ActivateEnvironment(operationContext conte
 	if existing == nil {
 		return errors.New("no Binance credentials are stored for the selected environment")
 	}
-	return service.repository.ActivateEnvironmentForUser(operationContext, userIdentifier, normalizedEnvironment)
+	previousEnvironment := service.ActiveEnvironmentName(operationContext, userIdentifier)
+	if activationError := service.repository.ActivateEnvironmentForUser(operationContext, userIdentifier, normalizedEnvironment); activationError != nil {
+		return activationError
+	}
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier: userIdentifier,
+		Action:         domain.AuditActionEnvironmentActivated,
+		TargetType:     "binance_environment",
+		Before:         map[string]string{"active_environment": previousEnvironment},
+		After:          map[string]string{"active_environment": normalizedEnvironment},
+	})
+	return nil
 }
 
 // ActiveEnvironmentName returns the user's active Binance environment, defaulting to TESTNET when the
@@ -151,9 +243,35 @@ func (service *UserCredentialService) GetStatus(operationContext context.Context
 	status.HasActiveCredential = true
 	status.ActiveEnvironment = record.EnvironmentName
 	status.MaskedAPIKey = service.maskAPIKey(record.APIKey)
+	status.Permissions = record.Permissions
+	status.PermissionWarnings = permissionWarnings(record.EnvironmentName, record.Permissions)
+	status.Health = record.Health
 	return status, nil
 }
 
+// permissionWarnings lists what the dashboard should point out about a key's permissions. A
+// production key without permissions was stored unchecked under the FLAG policy; testnet keys are
+// never checked.
+func permissionWarnings(environmentName string, permissions *domain.BinanceKeyPermissions) []string {
+	warnings := make([]string, 0)
+	if permissions == nil {
+		if environmentName == domain.BinanceEnvironmentProduction {
+			warnings = append(warnings, "permissions_unverified")
+		}
+		return warnings
+	}
+	if permissions.WithdrawalsEnabled {
+		warnings = append(warnings, "withdrawals_enabled")
+	}
+	if !permissions.SpotTradingEnabled {
+		warnings = append(warnings, "spot_trading_disabled")
+	}
+	if !permissions.IPRestricted {
+		warnings = append(warnings, "not_ip_restricted")
+	}
+	return warnings
+}
+
 func (service *UserCredentialService) maskAPIKey(encryptedAPIKey string) string {
 	if service.cipher == nil {
 		return "****"
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coin-alert/internal/domain"
+)
+
+// fakeBinanceRestrictions serves the time endpoint and answers apiRestrictions with the given status
+// and body, remembering the API key header and signature of the last restrictions request.
+type fakeBinanceRestrictions struct {
+	statusCode   int
+	body         string
+	lastAPIKey   string
+	lastSigned   bool
+	restrictions int
+}
+
+func (binance *fakeBinanceRestrictions) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case binanceTimeEndpointPath:
+			_, _ = responseWriter.Write([]byte(`{"serverTime":1700000000000}`))
+		case binanceAPIRestrictionsEndpointPath:
+			binance.restrictions++
+			binance.lastAPIKey = request.Header.Get("X-MBX-APIKEY")
+			binance.lastSigned = request.URL.Query().Get("timestamp") == "1700000000000" && request.URL.Query().Get("signature") != ""
+			responseWriter.WriteHeader(binance.statusCode)
+			_, _ = responseWriter.Write([]byte(binance.body))
+		default:
+			http.NotFound(responseWriter, request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchKeyPermissionsDecodesTheRestrictions(t *testing.T) {
+	binance := &fakeBinanceRestrictions{statusCode: http.StatusOK, body: `{"ipRestrict":true,"createTime":1698645219000,"enableReading":true,"enableWithdrawals":false,"enableInternalTransfer":false,"enableMargin":false,"enableFutures":false,"permitsUniversalTransfer":false,"enableVanillaOptions":false,"enableFixApiTrade":false,"enableFixReadOnly":false,"enableSpotAndMarginTrading":true,"enablePortfolioMarginTrading":false}`}
+	server := binance.start(t)
+
+	permissions, fetchError := NewBinanceCredentialValidator(server.URL).FetchKeyPermissions(context.Background(), "api-key", "api-secret")
+	if fetchError != nil {
+		t.Fatal(fetchError)
+	}
+	if !permissions.ReadingEnabled || !permissions.SpotTradingEnabled || permissions.WithdrawalsEnabled || !permissions.IPRestricted || permissions.CheckedAt.IsZero() {
+		t.Errorf("unexpected permissions %+v", permissions)
+	}
+	if binance.lastAPIKey != "api-key" || !binance.lastSigned {
+		t.Errorf("expected a signed request with the API key header, got key %q signed=%t", binance.lastAPIKey, binance.lastSigned)
+	}
+
+	binance.statusCode, binance.body = http.StatusUnauthorized, `{"code":-2015}`
+	if _, rejectedError := NewBinanceCredentialValidator(server.URL).FetchKeyPermissions(context.Background(), "api-key", "api-secret"); rejectedError == nil || !strings.Contains(rejectedError.Error(), "status 401") {
+		t.Errorf("expected the refusal to be reported, got %v", rejectedError)
+	}
+}
+
+// TestCheckKeyPermissionsAppliesThePolicy covers the REFUSE/FLAG matrix, and the warnings the
+// dashboard then shows for what was stored.
+func TestCheckKeyPermissionsAppliesThePolicy(t *testing.T) {
+	const withdrawalsOn = `{"ipRestrict":true,"enableReading":true,"enableSpotAndMarginTrading":true,"enableWithdrawals":true}`
+	const withdrawalsOff = `{"ipRestrict":true,"enableReading":true,"enableSpotAndMarginTrading":true,"enableWithdrawals":false}`
+	cases := []struct {
+		name             string
+		policy           string
+		environment      string
+		statusCode       int
+		body             string
+		expected         error
+		expectedWarnings []string
+	}{
+		{name: "refuse, withdrawals off", policy: domain.WithdrawalKeyPolicyRefuse, statusCode: http.StatusOK, body: withdrawalsOff, expectedWarnings: []string{}},
+		{name: "refuse, withdrawals on", policy: domain.WithdrawalKeyPolicyRefuse, statusCode: http.StatusOK, body: withdrawalsOn, expected: ErrWithdrawalKeyRefused},
+		{name: "refuse, fetch fails", policy: domain.WithdrawalKeyPolicyRefuse, statusCode: http.StatusInternalServerError, expected: ErrKeyPermissionsUnverified},
+		{name: "flag, withdrawals off", policy: domain.WithdrawalKeyPolicyFlag, statusCode: http.StatusOK, body: withdrawalsOff, expectedWarnings: []string{}},
+		{name: "flag, withdrawals on", policy: domain.WithdrawalKeyPolicyFlag, statusCode: http.StatusOK, body: withdrawalsOn, expectedWarnings: []string{"withdrawals_enabled"}},
+		{name: "flag, fetch fails", policy: domain.WithdrawalKeyPolicyFlag, statusCode: http.StatusInternalServerError, expectedWarnings: []string{"permissions_unverified"}},
+		{name: "testnet is not checked", policy: domain.WithdrawalKeyPolicyRefuse, environment: domain.BinanceEnvironmentTestnet, statusCode: http.StatusOK, body: withdrawalsOn, expectedWarnings: []string{}},
+	}
+	for _, testCase := range cases {
+		binance := &fakeBinanceRestrictions{statusCode: testCase.statusCode, body: testCase.body}
+		server := binance.start(t)
+		environment := testCase.environment
+		if environment == "" {
+			environment = domain.BinanceEnvironmentProduction
+		}
+		credentialService := NewUserCredentialService(nil, nil, server.URL, server.URL, nil, NewPlatformPolicyService(fixedPolicyRepository{policy: domain.PlatformPolicy{WithdrawalKeyPolicy: testCase.policy}}, nil))
+
+		permissions, checkError := credentialService.checkKeyPermissions(context.Background(), NewBinanceCredentialValidator(server.URL), environment, "api-key", "api-secret")
+		if !errors.Is(checkError, testCase.expected) {
+			t.Errorf("%s: expected error %v, got %v", testCase.name, testCase.expected, checkError)
+			continue
+		}
+		if environment == domain.BinanceEnvironmentTestnet && binance.restrictions != 0 {
+			t.Errorf("%s: the testnet key's permissions were fetched", testCase.name)
+		}
+		if testCase.expected != nil {
+			continue
+		}
+		if warnings := permissionWarnings(environment, permissions); strings.Join(warnings, ",") != strings.Join(testCase.expectedWarnings, ",") {
+			t.Errorf("%s: expected warnings %v, got %v", testCase.name, testCase.expectedWarnings, warnings)
+		}
+	}
+}
@@ -9,25 +9,34 @@ import (
 	"time"
 
 	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
 	"coin-alert/internal/repository"
 )
 
 // UserTradingService orchestrates per-user trades: it loads the user's decrypted credentials,
 // places a market buy plus a take-profit limit sell, and records the operation and executions.
 // Operations, executions and settings are scoped to the user's ACTIVE Binance environment.
+// Each state change and its domain events are written in one database transaction; executions of an
+// order that already reached Binance get their own, so they survive a later failure.
 type UserTradingService struct {
 	credentialService   *UserCredentialService
 	settingsRepository  repository.UserTradingSettingsRepository
 	operationRepository repository.UserTradingOperationRepository
 	executionRepository repository.UserTradingOperationExecutionRepository
+	transactionRunner   repository.TransactionRunner
+	eventPublisher      events.Publisher
+	auditRecorder       AuditRecorder
 }
 
-func NewUserTradingService(credentialService *UserCredentialService, settingsRepository repository.UserTradingSettingsRepository, operationRepository repository.UserTradingOperationRepository, executionRepository repository.UserTradingOperationExecutionRepository) *UserTradingService {
+func NewUserTradingService(credentialService *UserCredentialService, settingsRepository repository.UserTradingSettingsRepository, operationRepository repository.UserTradingOperationRepository, executionRepository repository.UserTradingOperationExecutionRepository, transactionRunner repository.TransactionRunner, eventPublisher events.Publisher, auditRecorder AuditRecorder) *UserTradingService {
 	return &UserTradingService{
 		credentialService:   credentialService,
 		settingsRepository:  settingsRepository,
 		operationRepository: operationRepository,
 		executionRepository: executionRepository,
+		transactionRunner:   transactionRunner,
+		eventPublisher:      eventPublisher,
+		auditRecorder:       auditRecorder,
 	}
 }
 
@@ -35,6 +44,11 @@ func NewUserTradingService(credentialService *UserCredentialService, settingsRep
 // initiatedBy records whether a user or the bot triggered it. Real-money (PRODUCTION) orders are
 // refused unless the user explicitly enabled live trading.
 func (service *UserTradingService) ExecuteBuy(operationContext context.Context, userIdentifier int64, initiatedBy string, tradingPairSymbol string, quoteAmount float64, targetProfitPercent float64, sellOrderValidityDaysOverride *int) (*domain.TradingOperation, error) {
+	return service.executeBuy(operationContext, userIdentifier, initiatedBy, tradingPairSymbol, quoteAmount, targetProfitPercent, sellOrderValidityDaysOverride, false)
+}
+
+// executeBuy is ExecuteBuy; dailyPurchase also records the DAILY_BUY marker together with the buy.
+func (service *UserTradingService) executeBuy(operationContext context.Context, userIdentifier int64, initiatedBy string, tradingPairSymbol string, quoteAmount float64, targetProfitPercent float64, sellOrderValidityDaysOverride *int, dailyPurchase bool) (*domain.TradingOperation, error) {
 	tradingPairSymbol = strings.ToUpper(strings.TrimSpace(tradingPairSymbol))
 	if tradingPairSymbol == "" {
 		return nil, errors.New("a trading pair is required")
@@ -98,8 +112,16 @@ func (service *UserTradingService) ExecuteBuy(operationContext context.Context,
 		purchasePricePerUnit = cumulativeQuote / executedQuantity
 	}
 
+	// The buy has filled: record it (and the DAILY_BUY marker that keeps the daily purchase from
+	// buying again) before anything else can fail.
 	buyOrderIdentifier := strconv.FormatInt(buyOrderResponse.OrderID, 10)
-	service.logExecution(operationContext, userIdentifier, environmentName, initiatedBy, tradingPairSymbol, domain.TradingOperationTypeBuy, purchasePricePerUnit, executedQuantity, purchasePricePerUnit*executedQuantity, true, nil, &buyOrderIdentifier)
+	buyExecutions := []domain.TradingOperationExecution{
+		newExecution(environmentName, initiatedBy, tradingPairSymbol, domain.TradingOperationTypeBuy, purchasePricePerUnit, executedQuantity, true, nil, &buyOrderIdentifier),
+	}
+	if dailyPurchase {
+		buyExecutions = append(buyExecutions, newExecution(environmentName, domain.ExecutionInitiatorBot, tradingPairSymbol, domain.TradingOperationTypeDailyBuy, purchasePricePerUnit, executedQuantity, true, nil, &buyOrderIdentifier))
+	}
+	service.recordPlacedOrder(operationContext, userIdentifier, buyExecutions...)
 
 	targetSellPricePerUnit := purchasePricePerUnit * (1 + (targetProfitPercent / 100))
 	if symbolFilters.TickSize > 0 {
@@ -114,7 +136,7 @@ func (service *UserTradingService) ExecuteBuy(operationContext context.Context,
 		sellOrderIdentifier = &identifier
 		sellOrderExpiresAt = resolveSellOrderExpiry(settings, sellOrderValidityDaysOverride)
 		// Records that the take-profit ORDER was created — not that a sale happened.
-		service.logExecution(operationContext, userIdentifier, environmentName, initiatedBy, tradingPairSymbol, domain.TradingOperationTypeSellOrderPlaced, targetSellPricePerUnit, executedQuantity, targetSellPricePerUnit*executedQuantity, true, nil, sellOrderIdentifier)
+		service.recordPlacedOrder(operationContext, userIdentifier, newExecution(environmentName, initiatedBy, tradingPairSymbol, domain.TradingOperationTypeSellOrderPlaced, targetSellPricePerUnit, executedQuantity, true, nil, sellOrderIdentifier))
 	}
 
 	operation := domain.TradingOperation{
@@ -129,23 +151,59 @@ func (service *UserTradingService) ExecuteBuy(operationContext context.Context,
 		SellOrderExpiresAt:     sellOrderExpiresAt,
 		SellTargetPricePerUnit: &targetSellPricePerUnit,
 	}
-	operationIdentifier, recordError := service.operationRepository.CreatePurchaseOperationForUser(operationContext, userIdentifier, operation)
+	recordError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		operationIdentifier, createError := service.operationRepository.CreatePurchaseOperationForUser(transactionContext, userIdentifier, operation)
+		if createError != nil {
+			return createError
+		}
+		operation.Identifier = operationIdentifier
+		operation.PurchaseTimestamp = time.Now()
+		return service.eventPublisher.Publish(transactionContext, userIdentifier, events.OperationOpened{Operation: operation})
+	})
 	if recordError != nil {
 		return nil, recordError
 	}
-	operation.Identifier = operationIdentifier
+	if initiatedBy == domain.ExecutionInitiatorUser {
+		recordAudit(operationContext, service.auditRecorder, AuditRecord{
+			UserIdentifier:   userIdentifier,
+			Action:           domain.AuditActionManualBuy,
+			TargetType:       "operation",
+			TargetIdentifier: operation.Identifier,
+			After:            newWebhookOperationData(operation),
+		})
+	}
 	return &operation, nil
 }
 
+// recordPlacedOrder writes the executions of an order Binance already accepted or filled, with their
+// ExecutionLogged events, in a transaction of their own. A failure is logged rather than returned: the
+// order exists either way, and the caller still has to record (or has recorded) the operation.
+func (service *UserTradingService) recordPlacedOrder(operationContext context.Context, userIdentifier int64, executions ...domain.TradingOperationExecution) {
+	recordPlacedOrder(operationContext, service.transactionRunner, service.executionRepository, service.eventPublisher, userIdentifier, executions...)
+}
+
+func recordPlacedOrder(operationContext context.Context, transactionRunner repository.TransactionRunner, executionRepository repository.UserTradingOperationExecutionRepository, eventPublisher events.Publisher, userIdentifier int64, executions ...domain.TradingOperationExecution) {
+	recordError := transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		for _, execution := range executions {
+			if executionError := recordExecution(transactionContext, executionRepository, eventPublisher, userIdentifier, execution); executionError != nil {
+				return executionError
+			}
+		}
+		return nil
+	})
+	if recordError != nil {
+		orderIdentifier := ""
+		if executions[0].OrderIdentifier != nil {
+			orderIdentifier = *executions[0].OrderIdentifier
+		}
+		tradingLogger.ErrorContext(operationContext, "could not record an order that reached Binance", "user_id", userIdentifier, "order_id", orderIdentifier, "type", executions[0].OperationType, "error", recordError)
+	}
+}
+
 // ExecuteDailyPurchase performs the daily DCA buy (always bot-initiated) and records a DAILY_BUY
 // marker execution (used for the daily-buy history and to keep the daily purchase idempotent).
 func (service *UserTradingService) ExecuteDailyPurchase(operationContext context.Context, userIdentifier int64, environment string, tradingPairSymbol string, quoteAmount float64, targetProfitPercent float64, sellOrderValidityDays int) (*domain.TradingOperation, error) {
-	operation, buyError := service.ExecuteBuy(operationContext, userIdentifier, domain.ExecutionInitiatorBot, tradingPairSymbol, quoteAmount, targetProfitPercent, &sellOrderValidityDays)
-	if buyError != nil {
-		return nil, buyError
-	}
-	service.logExecution(operationContext, userIdentifier, operation.BinanceEnvironment, domain.ExecutionInitiatorBot, operation.TradingPairSymbol, domain.TradingOperationTypeDailyBuy, operation.PurchasePricePerUnit, operation.QuantityPurchased, operation.PurchasePricePerUnit*operation.QuantityPurchased, true, nil, operation.BuyOrderIdentifier)
-	return operation, nil
+	return service.executeBuy(operationContext, userIdentifier, domain.ExecutionInitiatorBot, tradingPairSymbol, quoteAmount, targetProfitPercent, &sellOrderValidityDays, true)
 }
 
 // CloseOperationNow immediately closes an OPEN position at market on the user's request (user-initiated):
@@ -196,16 +254,40 @@ func (service *UserTradingService) CloseOperationNow(operationContext context.Co
 	return service.finalizeManualSell(operationContext, userIdentifier, environmentName, domain.ExecutionInitiatorUser, *operation, fillPriceFromOrder(*sellResponse, fallbackPrice), &sellOrderIdentifier)
 }
 
-func (service *UserTradingService) finalizeManualSell(operationContext context.Context, userIdentifier int64, environment string, initiatedBy string, operation domain.TradingOperation, fillPrice float64, sellOrderIdentifier *string) (*domain.TradingOperation, error) {
-	if updateError := service.operationRepository.UpdateOperationAsSoldForUser(operationContext, userIdentifier, operation.Identifier, fillPrice); updateError != nil {
-		return nil, updateError
-	}
-	service.logExecution(operationContext, userIdentifier, environment, initiatedBy, operation.TradingPairSymbol, domain.TradingOperationTypeSell, fillPrice, operation.QuantityPurchased, fillPrice*operation.QuantityPurchased, true, nil, sellOrderIdentifier)
+// auditOperationChange records a user-initiated change of an operation.
+func (service *UserTradingService) auditOperationChange(operationContext context.Context, userIdentifier int64, action string, before domain.TradingOperation, after domain.TradingOperation) {
+	recordAudit(operationContext, service.auditRecorder, AuditRecord{
+		UserIdentifier:   userIdentifier,
+		Action:           action,
+		TargetType:       "operation",
+		TargetIdentifier: after.Identifier,
+		Before:           newWebhookOperationData(before),
+		After:            newWebhookOperationData(after),
+	})
+}
 
+func (service *UserTradingService) finalizeManualSell(operationContext context.Context, userIdentifier int64, environment string, initiatedBy string, operation domain.TradingOperation, fillPrice float64, sellOrderIdentifier *string) (*domain.TradingOperation, error) {
+	openOperation := operation
 	soldAt := time.Now()
 	operation.Status = domain.TradingOperationStatusSold
 	operation.SellPricePerUnit = &fillPrice
 	operation.SellTimestamp = &soldAt
+
+	// The sale already happened on Binance: the SOLD status commits first so that nothing sells the
+	// position again, and the fill is then recorded on its own.
+	finalizeError := service.transactionRunner.RunInTransaction(operationContext, func(transactionContext context.Context) error {
+		if updateError := service.operationRepository.UpdateOperationAsSoldForUser(transactionContext, userIdentifier, operation.Identifier, fillPrice); updateError != nil {
+			return updateError
+		}
+		return service.eventPublisher.Publish(transactionContext, userIdentifier, events.OperationClosed{Operation: operation})
+	})
+	if finalizeError != nil {
+		return nil, finalizeError
+	}
+	service.recordPlacedOrder(operationContext, userIdentifier, newExecution(environment, initiatedBy, operation.TradingPairSymbol, domain.TradingOperationTypeSell, fillPrice, operation.QuantityPurchased, true, nil, sellOrderIdentifier))
+	if initiatedBy == domain.ExecutionInitiatorUser {
+		service.auditOperationChange(operationContext, userIdentifier, domain.AuditActionManualSell, openOperation, operation)
+	}
 	return &operation, nil
 }
 
@@ -265,14 +347,17 @@ func (service *UserTradingService) PlaceTakeProfitForOperation(operationContext
 
 	sellOrderIdentifier := strconv.FormatInt(sellOrderResponse.OrderID, 10)
 	sellOrderExpiresAt := sellOrderExpiry(settings)
-	// Records that the take-profit ORDER was (re)placed — not that a sale happened.
-	service.logExecution(operationContext, userIdentifier, environmentName, domain.ExecutionInitiatorUser, operation.TradingPairSymbol, domain.TradingOperationTypeSellOrderPlaced, targetSellPricePerUnit, operation.QuantityPurchased, targetSellPricePerUnit*operation.QuantityPurchased, true, nil, &sellOrderIdentifier)
+	// The order id is stored first, so a retry finds the live order instead of placing another.
 	if updateError := service.operationRepository.UpdateOperationSellOrderForUser(operationContext, userIdentifier, operation.Identifier, sellOrderIdentifier, targetSellPricePerUnit, sellOrderExpiresAt); updateError != nil {
 		return nil, updateError
 	}
+	// Records that the take-profit ORDER was (re)placed — not that a sale happened.
+	service.recordPlacedOrder(operationContext, userIdentifier, newExecution(environmentName, domain.ExecutionInitiatorUser, operation.TradingPairSymbol, domain.TradingOperationTypeSellOrderPlaced, targetSellPricePerUnit, operation.QuantityPurchased, true, nil, &sellOrderIdentifier))
+	previousOperation := *operation
 	operation.SellOrderIdentifier = &sellOrderIdentifier
 	operation.SellTargetPricePerUnit = &targetSellPricePerUnit
 	operation.SellOrderExpiresAt = sellOrderExpiresAt
+	service.auditOperationChange(operationContext, userIdentifier, domain.AuditActionTakeProfitPlaced, previousOperation, *operation)
 	return operation, nil
 }
 
@@ -299,6 +384,11 @@ func resolveSellOrderExpiry(settings *domain.UserTradingSettings, overrideDays *
 	return &expiry
 }
 
+// GetOperation returns one of the user's operations, or repository.ErrOperationNotFound.
+func (service *UserTradingService) GetOperation(loadContext context.Context, userIdentifier int64, operationIdentifier int64) (*domain.TradingOperation, error) {
+	return service.operationRepository.FindOperationByIdForUser(loadContext, userIdentifier, operationIdentifier)
+}
+
 func (service *UserTradingService) ListOperations(loadContext context.Context, userIdentifier int64, limit int) ([]domain.TradingOperation, error) {
 	environment := service.credentialService.ActiveEnvironmentName(loadContext, userIdentifier)
 	return service.operationRepository.ListRecentOperationsForUser(loadContext, userIdentifier, environment, limit)
@@ -331,24 +421,3 @@ func (service *UserTradingService) ListOpenOrders(loadContext context.Context, u
 	tradingService := NewBinanceTradingService(*environmentConfiguration)
 	return tradingService.ListOpenOrders(loadContext, tradingPairSymbol)
 }
-
-func (service *UserTradingService) logExecution(operationContext context.Context, userIdentifier int64, environment string, initiatedBy string, tradingPairSymbol string, operationType string, unitPrice float64, quantity float64, totalValue float64, success bool, cause error, orderIdentifier *string) {
-	var errorMessage *string
-	if cause != nil {
-		message := cause.Error()
-		errorMessage = &message
-	}
-	_, _ = service.executionRepository.LogExecutionForUser(operationContext, userIdentifier, domain.TradingOperationExecution{
-		TradingPairSymbol:  tradingPairSymbol,
-		OperationType:      operationType,
-		BinanceEnvironment: environment,
-		InitiatedBy:        initiatedBy,
-		UnitPrice:          unitPrice,
-		Quantity:           quantity,
-		TotalValue:         totalValue,
-		ExecutedAt:         time.Now(),
-		Success:            success,
-		ErrorMessage:       errorMessage,
-		OrderIdentifier:    orderIdentifier,
-	})
-}
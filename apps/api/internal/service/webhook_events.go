@@ -0,0 +1,134 @@
+package service
+
+import (
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+)
+
+// The data documents of outgoing webhook events. Field names follow the public API payloads.
+
+type webhookOperationData struct {
+	ID                     int64      `json:"id"`
+	Symbol                 string     `json:"symbol"`
+	Environment            string     `json:"environment"`
+	Status                 string     `json:"status"`
+	Quantity               float64    `json:"quantity"`
+	PurchasePricePerUnit   float64    `json:"purchase_price_per_unit"`
+	TargetProfitPercent    float64    `json:"target_profit_percent"`
+	SellPricePerUnit       *float64   `json:"sell_price_per_unit"`
+	SellTargetPricePerUnit *float64   `json:"sell_target_price_per_unit"`
+	RealizedProfit         *float64   `json:"realized_profit"`
+	PurchasedAt            *time.Time `json:"purchased_at,omitempty"`
+	SoldAt                 *time.Time `json:"sold_at,omitempty"`
+}
+
+type webhookExecutionData struct {
+	ID            int64     `json:"id"`
+	Symbol        string    `json:"symbol"`
+	Environment   string    `json:"environment"`
+	OperationType string    `json:"operation_type"`
+	InitiatedBy   string    `json:"initiated_by"`
+	UnitPrice     float64   `json:"unit_price"`
+	Quantity      float64   `json:"quantity"`
+	TotalValue    float64   `json:"total_value"`
+	Success       bool      `json:"success"`
+	ErrorMessage  *string   `json:"error_message"`
+	OrderID       *string   `json:"order_id"`
+	ExecutedAt    time.Time `json:"executed_at"`
+}
+
+type webhookRobotData struct {
+	Action                string   `json:"action"` // created | updated | deleted
+	ID                    int64    `json:"id"`
+	Symbol                string   `json:"symbol,omitempty"`
+	Name                  string   `json:"name,omitempty"`
+	Environment           string   `json:"environment,omitempty"`
+	CapitalThreshold      float64  `json:"capital_threshold,omitempty"`
+	TargetProfitPercent   float64  `json:"target_profit_percent,omitempty"`
+	StopLossPercent       *float64 `json:"stop_loss_percent,omitempty"`
+	DailyPurchaseEnabled  bool     `json:"daily_purchase_enabled"`
+	DailyPurchaseHourUTC  int      `json:"daily_purchase_hour_utc"`
+	SellOrderValidityDays int      `json:"sell_order_validity_days"`
+	IsEnabled             bool     `json:"is_enabled"`
+}
+
+func newWebhookOperationData(operation domain.TradingOperation) webhookOperationData {
+	data := webhookOperationData{
+		ID:                     operation.Identifier,
+		Symbol:                 operation.TradingPairSymbol,
+		Environment:            operation.BinanceEnvironment,
+		Status:                 operation.Status,
+		Quantity:               operation.QuantityPurchased,
+		PurchasePricePerUnit:   operation.PurchasePricePerUnit,
+		TargetProfitPercent:    operation.TargetProfitPercent,
+		SellPricePerUnit:       operation.SellPricePerUnit,
+		SellTargetPricePerUnit: operation.SellTargetPricePerUnit,
+		SoldAt:                 operation.SellTimestamp,
+	}
+	if !operation.PurchaseTimestamp.IsZero() {
+		purchasedAt := operation.PurchaseTimestamp
+		data.PurchasedAt = &purchasedAt
+	}
+	if operation.SellPricePerUnit != nil {
+		realizedProfit := (*operation.SellPricePerUnit - operation.PurchasePricePerUnit) * operation.QuantityPurchased
+		data.RealizedProfit = &realizedProfit
+	}
+	return data
+}
+
+func newWebhookExecutionData(executionIdentifier int64, execution domain.TradingOperationExecution) webhookExecutionData {
+	return webhookExecutionData{
+		ID:            executionIdentifier,
+		Symbol:        execution.TradingPairSymbol,
+		Environment:   execution.BinanceEnvironment,
+		OperationType: execution.OperationType,
+		InitiatedBy:   execution.InitiatedBy,
+		UnitPrice:     execution.UnitPrice,
+		Quantity:      execution.Quantity,
+		TotalValue:    execution.TotalValue,
+		Success:       execution.Success,
+		ErrorMessage:  execution.ErrorMessage,
+		OrderID:       execution.OrderIdentifier,
+		ExecutedAt:    execution.ExecutedAt,
+	}
+}
+
+func newWebhookRobotData(action string, robot domain.TradingRobot) webhookRobotData {
+	return webhookRobotData{
+		Action:                action,
+		ID:                    robot.Identifier,
+		Symbol:                robot.TradingPairSymbol,
+		Name:                  robot.Name,
+		Environment:           robot.BinanceEnvironment,
+		CapitalThreshold:      robot.CapitalThreshold,
+		TargetProfitPercent:   robot.TargetProfitPercent,
+		StopLossPercent:       robot.StopLossPercent,
+		DailyPurchaseEnabled:  robot.DailyPurchaseEnabled,
+		DailyPurchaseHourUTC:  robot.DailyPurchaseHourUTC,
+		SellOrderValidityDays: robot.SellOrderValidityDays,
+		IsEnabled:             robot.IsEnabled,
+	}
+}
+
+// webhookEventFor maps a domain event to the public webhook event type and data document; ok is
+// false for events that are not exposed as webhooks.
+func webhookEventFor(event events.Event) (string, interface{}, bool) {
+	switch typed := event.(type) {
+	case events.OperationOpened:
+		return domain.WebhookEventOperationOpened, newWebhookOperationData(typed.Operation), true
+	case events.OperationClosed:
+		return domain.WebhookEventOperationClosed, newWebhookOperationData(typed.Operation), true
+	case events.OperationCancelled:
+		return domain.WebhookEventOperationCancelled, newWebhookOperationData(typed.Operation), true
+	case events.ExecutionLogged:
+		return domain.WebhookEventExecutionLogged, newWebhookExecutionData(typed.Execution.Identifier, typed.Execution), true
+	case events.RobotChanged:
+		if typed.Action == events.RobotActionDeleted {
+			return domain.WebhookEventRobotChanged, webhookRobotData{Action: typed.Action, ID: typed.Robot.Identifier}, true
+		}
+		return domain.WebhookEventRobotChanged, newWebhookRobotData(typed.Action, typed.Robot), true
+	}
+	return "", nil, false
+}
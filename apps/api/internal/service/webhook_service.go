@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/events"
+	"coin-alert/internal/notification"
+	"coin-alert/internal/repository"
+	"coin-alert/internal/security"
+)
+
+// Webhook validation errors surfaced to the API.
+var (
+	ErrWebhookEndpointLimitReached = errors.New("you have reached the maximum number of webhook endpoints")
+	ErrWebhookURLInvalid           = errors.New("the webhook URL must be a public https:// address")
+	ErrWebhookEventTypeInvalid     = errors.New("unknown webhook event type")
+)
+
+const (
+	// MaximumWebhookEndpointsPerUser bounds the fan-out of every trading event.
+	MaximumWebhookEndpointsPerUser = 5
+	// maximumWebhookAttempts is how many times a delivery is tried before it is marked FAILED
+	// (about a day and a half with the backoff below).
+	maximumWebhookAttempts = 10
+	webhookInitialBackoff  = 30 * time.Second
+	webhookMaximumBackoff  = 6 * time.Hour
+	webhookClaimLease      = 2 * time.Minute
+	webhookClaimBatchSize  = 25
+)
+
+// WebhookService manages user webhook endpoints, queues events for them and runs the dispatcher
+// that delivers the queue with exponential backoff.
+type WebhookService struct {
+	endpointRepository repository.WebhookEndpointRepository
+	deliveryRepository repository.WebhookDeliveryRepository
+	cipher             *security.SecretCipher
+	sender             *notification.SignedWebhookSender
+}
+
+func NewWebhookService(endpointRepository repository.WebhookEndpointRepository, deliveryRepository repository.WebhookDeliveryRepository, cipher *security.SecretCipher, sender *notification.SignedWebhookSender) *WebhookService {
+	return &WebhookService{
+		endpointRepository: endpointRepository,
+		deliveryRepository: deliveryRepository,
+		cipher:             cipher,
+		sender:             sender,
+	}
+}
+
+func (service *WebhookService) ListEndpoints(operationContext context.Context, userIdentifier int64) ([]domain.WebhookEndpoint, error) {
+	return service.endpointRepository.ListEndpointsForUser(operationContext, userIdentifier)
+}
+
+// CreateEndpoint registers an endpoint and returns it with its signing secret. The plain secret is
+// only ever returned here (and by RotateSecret); afterwards it is stored encrypted.
+func (service *WebhookService) CreateEndpoint(operationContext context.Context, userIdentifier int64, endpointURL string, description string, eventTypes []string) (*domain.WebhookEndpoint, string, error) {
+	if service.cipher == nil {
+		return nil, "", ErrCredentialEncryptionUnavailable
+	}
+	endpointURL = strings.TrimSpace(endpointURL)
+	if !notification.IsAllowedWebhookURL(endpointURL) {
+		return nil, "", ErrWebhookURLInvalid
+	}
+	normalizedEventTypes, eventTypesError := normalizeWebhookEventTypes(eventTypes)
+	if eventTypesError != nil {
+		return nil, "", eventTypesError
+	}
+	endpointCount, countError := service.endpointRepository.CountEndpointsForUser(operationContext, userIdentifier)
+	if countError != nil {
+		return nil, "", countError
+	}
+	if endpointCount >= MaximumWebhookEndpointsPerUser {
+		return nil, "", ErrWebhookEndpointLimitReached
+	}
+
+	signingSecret, encryptedSecret, secretError := service.newSigningSecret()
+	if secretError != nil {
+		return nil, "", secretError
+	}
+	endpoint := domain.WebhookEndpoint{
+		UserIdentifier: userIdentifier,
+		URL:            endpointURL,
+		Description:    truncateRunes(strings.TrimSpace(description), 160),
+		SigningSecret:  encryptedSecret,
+		EventTypes:     normalizedEventTypes,
+		IsEnabled:      true,
+		CreatedAt:      time.Now(),
+	}
+	endpointIdentifier, createError := service.endpointRepository.CreateEndpointForUser(operationContext, userIdentifier, endpoint)
+	if createError != nil {
+		return nil, "", createError
+	}
+	endpoint.Identifier = endpointIdentifier
+	return &endpoint, signingSecret, nil
+}
+
+// RotateSecret replaces the endpoint's signing secret and returns the new one. Deliveries still in
+// the queue are signed with the new secret.
+func (service *WebhookService) RotateSecret(operationContext context.Context, userIdentifier int64, endpointIdentifier int64) (string, error) {
+	if service.cipher == nil {
+		return "", ErrCredentialEncryptionUnavailable
+	}
+	signingSecret, encryptedSecret, secretError := service.newSigningSecret()
+	if secretError != nil {
+		return "", secretError
+	}
+	if updateError := service.endpointRepository.UpdateSigningSecretForUser(operationContext, userIdentifier, endpointIdentifier, encryptedSecret); updateError != nil {
+		return "", updateError
+	}
+	return signingSecret, nil
+}
+
+func (service *WebhookService) DeleteEndpoint(operationContext context.Context, userIdentifier int64, endpointIdentifier int64) error {
+	return service.endpointRepository.DeleteEndpointForUser(operationContext, userIdentifier, endpointIdentifier)
+}
+
+// ListDeliveries returns the delivery history; endpointIdentifier 0 means every endpoint of the user.
+func (service *WebhookService) ListDeliveries(operationContext context.Context, userIdentifier int64, endpointIdentifier int64, limit int) ([]domain.WebhookDelivery, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return service.deliveryRepository.ListDeliveriesForUser(operationContext, userIdentifier, endpointIdentifier, limit)
+}
+
+// webhookEnvelope is the JSON document every endpoint receives.
+type webhookEnvelope struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// HandleEvent is the event bus subscriber: it writes one queued delivery per enabled endpoint
+// subscribed to the event. The webhook event id is derived from the outbox id, so a redelivered
+// event does not queue a second delivery.
+func (service *WebhookService) HandleEvent(eventContext context.Context, envelope events.Envelope) error {
+	eventType, data, exposed := webhookEventFor(envelope.Event)
+	if !exposed {
+		return nil
+	}
+	endpoints, listError := service.endpointRepository.ListEnabledEndpointsForUser(eventContext, envelope.UserIdentifier)
+	if listError != nil {
+		return listError
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+	eventIdentifier := "evt_" + strconv.FormatInt(envelope.Identifier, 10)
+	payload, encodeError := json.Marshal(webhookEnvelope{ID: eventIdentifier, Type: eventType, CreatedAt: envelope.OccurredAt.UTC(), Data: data})
+	if encodeError != nil {
+		return encodeError
+	}
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribes(eventType) {
+			continue
+		}
+		if enqueueError := service.deliveryRepository.EnqueueDelivery(eventContext, domain.WebhookDelivery{
+			EndpointIdentifier: endpoint.Identifier,
+			UserIdentifier:     envelope.UserIdentifier,
+			EventIdentifier:    eventIdentifier,
+			EventType:          eventType,
+			Payload:            string(payload),
+		}); enqueueError != nil {
+			return enqueueError
+		}
+	}
+	return nil
+}
+
+// StartDispatcher delivers due queued deliveries every interval until the context is cancelled.
+func (service *WebhookService) StartDispatcher(loopContext context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopContext.Done():
+				return
+			case <-ticker.C:
+				service.dispatchDueDeliveries(loopContext)
+			}
+		}
+	}()
+}
+
+func (service *WebhookService) dispatchDueDeliveries(loopContext context.Context) {
+	deliveries, claimError := service.deliveryRepository.ClaimDueDeliveries(loopContext, webhookClaimBatchSize, webhookClaimLease)
+	if claimError != nil {
+		notificationLogger.ErrorContext(loopContext, "webhooks could not claim deliveries", "error", claimError)
+		return
+	}
+	endpointByIdentifier := make(map[int64]*domain.WebhookEndpoint)
+	for _, delivery := range deliveries {
+		if loopContext.Err() != nil {
+			return
+		}
+		endpoint, present := endpointByIdentifier[delivery.EndpointIdentifier]
+		if !present {
+			var endpointError error
+			endpoint, endpointError = service.endpointRepository.GetEndpoint(loopContext, delivery.EndpointIdentifier)
+			if endpointError != nil && !errors.Is(endpointError, repository.ErrWebhookEndpointNotFound) {
+				// Only a deleted endpoint ends its deliveries. Otherwise the claim's lease brings the
+				// delivery back later without using up an attempt.
+				notificationLogger.WarnContext(loopContext, "webhooks could not load the endpoint", "endpoint_id", delivery.EndpointIdentifier, "error", endpointError)
+				continue
+			}
+			endpointByIdentifier[delivery.EndpointIdentifier] = endpoint
+		}
+		service.attemptDelivery(loopContext, delivery, endpoint)
+	}
+}
+
+func (service *WebhookService) attemptDelivery(loopContext context.Context, delivery domain.WebhookDelivery, endpoint *domain.WebhookEndpoint) {
+	attemptContext, cancel := context.WithTimeout(loopContext, 15*time.Second)
+	defer cancel()
+
+	var statusCode int
+	var deliveryError error
+	switch {
+	case endpoint == nil:
+		deliveryError = errors.New("endpoint no longer exists")
+	case !endpoint.IsEnabled:
+		deliveryError = errors.New("endpoint is disabled")
+	case service.cipher == nil:
+		deliveryError = ErrCredentialEncryptionUnavailable
+	default:
+		signingSecret, decryptionError := service.cipher.DecryptString(endpoint.SigningSecret)
+		if decryptionError != nil {
+			deliveryError = errors.New("could not decrypt the signing secret")
+			break
+		}
+		statusCode, deliveryError = service.sender.Send(attemptContext, endpoint.URL, signingSecret, delivery.EventType, strconv.FormatInt(delivery.Identifier, 10), []byte(delivery.Payload))
+	}
+
+	if deliveryError != nil && loopContext.Err() != nil {
+		// Shutting down: the send was cut short, so the attempt is not the endpoint's fault.
+		return
+	}
+	if deliveryError == nil {
+		if markError := service.deliveryRepository.MarkDeliverySucceeded(loopContext, delivery.Identifier, statusCode); markError != nil {
+			notificationLogger.ErrorContext(loopContext, "could not mark the webhook delivery delivered", "delivery_id", delivery.Identifier, "error", markError)
+		}
+		return
+	}
+
+	attemptNumber := delivery.AttemptCount + 1
+	giveUp := attemptNumber >= maximumWebhookAttempts || endpoint == nil
+	var recordedStatusCode *int
+	if statusCode > 0 {
+		recordedStatusCode = &statusCode
+	}
+	nextAttemptAt := time.Now().Add(webhookBackoff(attemptNumber))
+	if markError := service.deliveryRepository.MarkDeliveryAttemptFailed(loopContext, delivery.Identifier, recordedStatusCode, deliveryError.Error(), nextAttemptAt, giveUp); markError != nil {
+		notificationLogger.ErrorContext(loopContext, "could not record the failed webhook attempt", "delivery_id", delivery.Identifier, "error", markError)
+	}
+}
+
+// webhookBackoff is 30s, 1m, 2m, 4m, ... capped at 6h, for the given (1-based) failed attempt.
+func webhookBackoff(attemptNumber int) time.Duration {
+	backoff := float64(webhookInitialBackoff) * math.Pow(2, float64(attemptNumber-1))
+	if backoff > float64(webhookMaximumBackoff) {
+		return webhookMaximumBackoff
+	}
+	return time.Duration(backoff)
+}
+
+func (service *WebhookService) newSigningSecret() (string, string, error) {
+	signingSecret, tokenError := randomWebhookToken("whsec_", 32)
+	if tokenError != nil {
+		return "", "", tokenError
+	}
+	encryptedSecret, encryptionError := service.cipher.EncryptString(signingSecret)
+	if encryptionError != nil {
+		return "", "", encryptionError
+	}
+	return signingSecret, encryptedSecret, nil
+}
+
+func normalizeWebhookEventTypes(eventTypes []string) ([]string, error) {
+	normalized := make([]string, 0, len(eventTypes))
+	seen := make(map[string]bool)
+	for _, eventType := range eventTypes {
+		eventType = strings.ToLower(strings.TrimSpace(eventType))
+		if eventType == "" || seen[eventType] {
+			continue
+		}
+		known := false
+		for _, knownType := range domain.WebhookEventTypes {
+			known = known || knownType == eventType
+		}
+		if !known {
+			return nil, ErrWebhookEventTypeInvalid
+		}
+		seen[eventType] = true
+		normalized = append(normalized, eventType)
+	}
+	return normalized, nil
+}
+
+func randomWebhookToken(prefix string, byteCount int) (string, error) {
+	randomBytes := make([]byte, byteCount)
+	if _, readError := rand.Read(randomBytes); readError != nil {
+		return "", readError
+	}
+	return prefix + hex.EncodeToString(randomBytes), nil
+}
+
+func truncateRunes(value string, maximumRunes int) string {
+	runes := []rune(value)
+	if len(runes) <= maximumRunes {
+		return value
+	}
+	return string(runes[:maximumRunes])
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"coin-alert/internal/domain"
+	"coin-alert/internal/repository"
+)
+
+// lookupEndpointRepository answers GetEndpoint with a fixed result; other methods are not used.
+type lookupEndpointRepository struct {
+	repository.WebhookEndpointRepository
+	endpoint    *domain.WebhookEndpoint
+	lookupError error
+}
+
+func (endpointRepository lookupEndpointRepository) GetEndpoint(context.Context, int64) (*domain.WebhookEndpoint, error) {
+	return endpointRepository.endpoint, endpointRepository.lookupError
+}
+
+// failedAttempt is one MarkDeliveryAttemptFailed call.
+type failedAttempt struct {
+	deliveryIdentifier int64
+	giveUp             bool
+}
+
+// claimedDeliveryRepository hands out its deliveries once and records the failed attempts.
+type claimedDeliveryRepository struct {
+	repository.WebhookDeliveryRepository
+	deliveries []domain.WebhookDelivery
+	failed     []failedAttempt
+}
+
+func (deliveryRepository *claimedDeliveryRepository) ClaimDueDeliveries(context.Context, int, time.Duration) ([]domain.WebhookDelivery, error) {
+	return deliveryRepository.deliveries, nil
+}
+
+func (deliveryRepository *claimedDeliveryRepository) MarkDeliveryAttemptFailed(_ context.Context, deliveryIdentifier int64, _ *int, _ string, _ time.Time, giveUp bool) error {
+	deliveryRepository.failed = append(deliveryRepository.failed, failedAttempt{deliveryIdentifier: deliveryIdentifier, giveUp: giveUp})
+	return nil
+}
+
+// TestDispatcherGivesUpOnlyOnDeletedEndpoints checks that a delivery is failed for good only when its
+// endpoint is gone, and that a database error or a shutdown leaves it queued with its attempts intact.
+func TestDispatcherGivesUpOnlyOnDeletedEndpoints(t *testing.T) {
+	cases := []struct {
+		name          string
+		endpoints     lookupEndpointRepository
+		shuttingDown  bool
+		expectedCalls []failedAttempt
+	}{
+		{name: "deleted endpoint", endpoints: lookupEndpointRepository{lookupError: repository.ErrWebhookEndpointNotFound}, expectedCalls: []failedAttempt{{deliveryIdentifier: 3, giveUp: true}}},
+		{name: "database error", endpoints: lookupEndpointRepository{lookupError: errors.New("connection reset by peer")}},
+		{name: "shutting down", endpoints: lookupEndpointRepository{lookupError: context.Canceled}, shuttingDown: true},
+		{name: "disabled endpoint", endpoints: lookupEndpointRepository{endpoint: &domain.WebhookEndpoint{Identifier: 1}}, expectedCalls: []failedAttempt{{deliveryIdentifier: 3, giveUp: false}}},
+	}
+	for _, testCase := range cases {
+		deliveries := &claimedDeliveryRepository{deliveries: []domain.WebhookDelivery{{Identifier: 3, EndpointIdentifier: 1, AttemptCount: 2}}}
+		webhookService := NewWebhookService(testCase.endpoints, deliveries, nil, nil)
+		loopContext, cancel := context.WithCancel(context.Background())
+		if testCase.shuttingDown {
+			cancel()
+		}
+
+		webhookService.dispatchDueDeliveries(loopContext)
+		cancel()
+
+		if len(deliveries.failed) != len(testCase.expectedCalls) {
+			t.Errorf("%s: expected failed attempts %v, got %v", testCase.name, testCase.expectedCalls, deliveries.failed)
+			continue
+		}
+		for index, expected := range testCase.expectedCalls {
+			if deliveries.failed[index] != expected {
+				t.Errorf("%s: expected failed attempt %+v, got %+v", testCase.name, expected, deliveries.failed[index])
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Code generated from the API's payload structs; DO NOT EDIT.
+// Regenerate with `go test ./internal/httpserver -run TestOpenAPIDocument -update` in apps/api.
+
+export interface BinanceOpenOrder {
+  orderId: number
+  symbol: string
+  price: string
+  side: string
+  status: string
+}
+
+export interface BuyRequest {
+  symbol: string
+  quote_amount: number
+  target_profit_percent: number
+}
+
+export interface Execution {
+  id: number
+  symbol: string
+  operation_type: string
+  unit_price: number
+  quantity: number
+  total_value: number
+  executed_at: string
+  success: boolean
+  error_message: string | null
+  order_id: string | null
+  initiated_by: string
+}
+
+export interface Operation {
+  id: number
+  symbol: string
+  quantity: number
+  purchase_price_per_unit: number
+  target_profit_percent: number
+  status: string
+  sell_price_per_unit: number | null
+  sell_target_price_per_unit: number | null
+  buy_order_id: string | null
+  sell_order_id: string | null
+  sell_order_expires_at: string | null
+  purchased_at: string
+  sold_at: string | null
+}
+
+export interface Robot {
+  id: number
+  symbol: string
+  name: string
+  capital_threshold: number
+  target_profit_percent: number
+  stop_loss_percent: number | null
+  daily_purchase_hour_utc: number
+  daily_purchase_enabled: boolean
+  sell_order_validity_days: number
+  is_enabled: boolean
+}
+
+export interface RobotInput {
+  id?: number
+  symbol: string
+  name: string
+  capital_threshold: number
+  target_profit_percent: number
+  stop_loss_percent: number | null
+  daily_purchase_hour_utc: number
+  daily_purchase_enabled: boolean
+  sell_order_validity_days: number
+  is_enabled: boolean
+}
+
+export interface RobotPatch {
+  name?: string | null
+  capital_threshold?: number | null
+  target_profit_percent?: number | null
+  stop_loss_percent?: number | null
+  daily_purchase_hour_utc?: number | null
+  daily_purchase_enabled?: boolean | null
+  sell_order_validity_days?: number | null
+  is_enabled?: boolean | null
+}
+
+export interface RobotsResponse {
+  robots: Robot[]
+  limit: number
+  is_admin: boolean
+}
+
+export interface TradingSettings {
+  trading_pair_symbol: string
+  capital_threshold: number
+  target_profit_percent: number
+  stop_loss_percent: number | null
+  auto_sell_interval_minutes: number
+  daily_purchase_hour_utc: number
+  daily_purchase_enabled: boolean
+  sell_order_validity_days: number
+  live_trading_enabled: boolean
+  active_binance_environment: string
+}
+
+export interface User {
+  id: number
+  email: string
+  display_name: string
+  has_password: boolean
+  google_connected: boolean
+  is_admin: boolean
+  email_verified: boolean
+  created_at: string
+}
+
+export type Requester = <T>(method: string, path: string, body?: unknown, headers?: Record<string, string>) => Promise<T>
+
+export function createApiClient(request: Requester) {
+  return {
+    getCurrentUser: () => request<User>('GET', '/auth/me'),
+    getTradingSettings: () => request<TradingSettings>('GET', '/api/v1/settings'),
+    saveTradingSettings: (body: TradingSettings) => request<TradingSettings>('PUT', '/api/v1/settings', body),
+    listRobots: () => request<RobotsResponse>('GET', '/api/v2/robots'),
+    createRobot: (body: RobotInput, idempotencyKey: string = crypto.randomUUID()) => request<Robot>('POST', '/api/v2/robots', body, { 'Idempotency-Key': idempotencyKey }),
+    getRobot: (id: number) => request<Robot>('GET', `/api/v2/robots/${id}`),
+    updateRobot: (id: number, body: RobotPatch) => request<Robot>('PATCH', `/api/v2/robots/${id}`, body),
+    deleteRobot: (id: number) => request<null>('DELETE', `/api/v2/robots/${id}`),
+    listOperations: () => request<Operation[]>('GET', '/api/v2/operations'),
+    buy: (body: BuyRequest, idempotencyKey: string = crypto.randomUUID()) => request<Operation>('POST', '/api/v2/operations', body, { 'Idempotency-Key': idempotencyKey }),
+    getOperation: (id: number) => request<Operation>('GET', `/api/v2/operations/${id}`),
+    closeOperation: (id: number, idempotencyKey: string = crypto.randomUUID()) => request<Operation>('POST', `/api/v2/operations/${id}/close`, undefined, { 'Idempotency-Key': idempotencyKey }),
+    placeTakeProfit: (id: number, idempotencyKey: string = crypto.randomUUID()) => request<Operation>('POST', `/api/v2/operations/${id}/take-profit`, undefined, { 'Idempotency-Key': idempotencyKey }),
+    listExecutions: () => request<Execution[]>('GET', '/api/v2/executions'),
+    listOpenOrders: (query: { symbol?: string } = {}) => request<BinanceOpenOrder[]>('GET', withQuery('/api/v2/binance/open-orders', query)),
+  }
+}
+
+function withQuery(path: string, query: Record<string, string | undefined>): string {
+  const search = new URLSearchParams()
+  for (const [key, value] of Object.entries(query)) {
+    if (value !== undefined && value !== '') search.set(key, value)
+  }
+  const encoded = search.toString()
+  return encoded ? `${path}?${encoded}` : path
+}
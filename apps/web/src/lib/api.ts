@@ -1,99 +1,266 @@
 // Typed client for the Coin Hub JSON API. Cookies carry the session, so every call uses
 // credentials: 'include'. Paths are relative (same-origin: dev proxy or nginx in production).
 import { showVerifyModal } from './stores'
+import { createApiClient } from './api.gen'
+import type { Robot, User } from './api.gen'
 
-export interface User {
-  id: number
-  email: string
-  display_name: string
-  has_password: boolean
-  google_connected: boolean
-  is_admin: boolean
-  email_verified: boolean
-  created_at: string
-}
+// Types for the documented routes are generated from the API's payload structs (see api.gen.ts).
+export type { BinanceOpenOrder, Execution, Operation, Robot, RobotsResponse, TradingSettings, User } from './api.gen'
 
 export interface AuthProviders {
   google: boolean
   email: boolean
 }
 
-export interface TradingSettings {
-  trading_pair_symbol: string
-  capital_threshold: number
-  target_profit_percent: number
-  stop_loss_percent: number | null
-  auto_sell_interval_minutes: number
-  daily_purchase_hour_utc: number
-  daily_purchase_enabled: boolean
-  sell_order_validity_days: number
-  live_trading_enabled: boolean
-  active_binance_environment: string
-}
-
 export interface CredentialStatus {
   has_active_credential: boolean
   active_environment: string
   masked_api_key: string
   configured_environments: string[]
+  permissions: CredentialPermissions | null // null when not checked (testnet)
+  permission_warnings: CredentialPermissionWarning[]
+  health: CredentialHealth | null
+}
+
+export type CredentialHealthStatus = 'UNKNOWN' | 'HEALTHY' | 'INVALID'
+
+export interface CredentialHealth {
+  status: CredentialHealthStatus
+  checked_at: string | null
+  last_error?: string
+}
+
+export interface CredentialPermissions {
+  reading_enabled: boolean
+  spot_trading_enabled: boolean
+  withdrawals_enabled: boolean
+  ip_restricted: boolean
+  checked_at: string
+}
+
+export type CredentialPermissionWarning =
+  | 'withdrawals_enabled'
+  | 'spot_trading_disabled'
+  | 'not_ip_restricted'
+  | 'permissions_unverified'
+
+export type WithdrawalKeyPolicy = 'REFUSE' | 'FLAG'
+
+export interface PlatformPolicy {
+  withdrawal_key_policy: WithdrawalKeyPolicy
+  require_two_factor_for_live_trading: boolean
+  updated_by_user_id?: number
+  updated_at?: string
+}
+
+// EffectiveConfiguration is the server's configuration as it runs, with secret values redacted.
+export interface EffectiveConfiguration {
+  file?: string
+  settings: {
+    key: string
+    environment_variable: string
+    value: string
+    source: 'default' | 'file' | 'environment'
+    secret: boolean
+  }[]
+}
+
+// ApiError carries the HTTP status and the machine-readable code (e.g. 'step_up_required') so callers
+// can branch without parsing the message.
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    readonly status: number,
+    readonly code?: string,
+  ) {
+    super(message)
+  }
+}
+
+export type StepUpMethod = 'totp' | 'password' | 'sign_in'
+
+export interface StepUpStatus {
+  active: boolean
+  method: StepUpMethod
 }
 
-export interface Robot {
+export type AccessTokenScope = 'read:operations' | 'trade' | 'robots:write' | 'metrics:read'
+
+// Personal access token for scripts (`Authorization: Bearer <token>`). `token` is only present in the
+// create response; it cannot be shown again.
+export interface AccessToken {
   id: number
-  symbol: string
   name: string
-  capital_threshold: number
-  target_profit_percent: number
-  stop_loss_percent: number | null
-  daily_purchase_hour_utc: number
-  daily_purchase_enabled: boolean
-  sell_order_validity_days: number
+  token_prefix: string
+  scopes: AccessTokenScope[]
+  expires_at: string | null
+  last_used_at: string | null
+  last_used_ip: string
+  revoked_at: string | null
+  created_at: string
+  token?: string
+}
+
+// One signed-in device in the account's session list.
+export interface ActiveSession {
+  id: number
+  browser: string
+  operating_system: string
+  device_type: 'desktop' | 'mobile' | 'tablet' | 'other'
+  ip_address: string
+  location: string
+  created_at: string
+  last_seen_at: string
+  expires_at: string
+  current: boolean
+}
+
+// Returned by login instead of the user when the account has two-factor authentication enabled.
+export interface TwoFactorChallenge {
+  two_factor_required: true
+  challenge_token: string
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean
+  enabled_at: string | null
+  remaining_recovery_codes: number
+}
+
+export interface TwoFactorEnrollment {
+  secret: string
+  provisioning_uri: string
+}
+
+export interface NotificationChannel {
+  id: number
+  channel_type: 'EMAIL' | 'TELEGRAM' | 'DISCORD' | 'WEBHOOK'
+  label: string
   is_enabled: boolean
 }
 
-export interface RobotsResponse {
-  robots: Robot[]
-  limit: number // 0 = unlimited (admins)
-  is_admin: boolean
+export interface NotificationChannelInput {
+  channel_type: NotificationChannel['channel_type']
+  email_address?: string
+  telegram_bot_token?: string
+  telegram_chat_id?: string
+  discord_webhook_url?: string
+  webhook_url?: string
+  locale?: string
 }
 
-export interface Operation {
+export interface NotificationDelivery {
   id: number
+  channel_id: number | null
+  channel_type: string
+  event_type: string
+  subject: string
+  success: boolean
+  error_message: string | null
+  created_at: string
+}
+
+export type TradeEventType = 'TAKE_PROFIT_FILLED' | 'STOP_LOSS_TRIGGERED' | 'TAKE_PROFIT_EXPIRED' | 'DAILY_PURCHASE_FAILED'
+
+export interface NotificationPreference {
+  event_type: TradeEventType
+  is_enabled: boolean
+}
+
+export interface DigestSubscription {
+  is_enabled: boolean
+  frequency: 'DAILY' | 'WEEKLY'
+  local_hour: number
+  weekday: number // 0 = Sunday, used by weekly digests
+  time_zone: string
+  locale?: string
+  last_sent_at?: string | null
+}
+
+export interface WebhookEndpoint {
+  id: number
+  url: string
+  description: string
+  event_types: string[] // empty = all events
+  is_enabled: boolean
+  created_at: string
+  signing_secret?: string // only returned on creation
+}
+
+export interface WebhookDelivery {
+  id: number
+  endpoint_id: number
+  event_id: string
+  event_type: string
+  status: 'PENDING' | 'SUCCEEDED' | 'FAILED'
+  attempt_count: number
+  next_attempt_at: string | null
+  last_status_code: number | null
+  last_error: string | null
+  created_at: string
+  delivered_at: string | null
+}
+
+export interface AuditEntry {
+  id: number
+  user_id: number
+  actor_user_id: number | null
+  action: string
+  target_type: string
+  target_id: string
+  ip_address: string
+  user_agent: string
+  before: unknown
+  after: unknown
+  created_at: string
+}
+
+export type LiveEventType = 'price' | 'operation' | 'execution' | 'robot' | 'robot_status'
+
+export interface LivePrice {
   symbol: string
-  quantity: number
-  purchase_price_per_unit: number
-  target_profit_percent: number
-  status: string
-  sell_price_per_unit: number | null
-  sell_target_price_per_unit: number | null
-  buy_order_id: string | null
-  sell_order_id: string | null
-  sell_order_expires_at: string | null
-  purchased_at: string
-  sold_at: string | null
-}
-
-export interface Execution {
+  price: number
+  priced_at: string
+}
+
+export interface LiveRobotStatus {
   id: number
   symbol: string
-  operation_type: string
-  unit_price: number
-  quantity: number
-  total_value: number
-  executed_at: string
-  success: boolean
-  error_message: string | null
-  order_id: string | null
-  initiated_by: string
+  name: string
+  environment: string
+  is_enabled: boolean
+  open_positions: number
+  stop_loss_active: boolean
+  last_checked_at: string
 }
 
-async function request<T>(method: string, path: string, body?: unknown): Promise<T> {
-  const response = await fetch(path, {
+// Live dashboard updates over Server-Sent Events. EventSource reconnects by itself and resumes with
+// Last-Event-ID; call close() on the returned source when the view is destroyed.
+export function openLiveStream(handlers: Partial<Record<LiveEventType, (data: any) => void>>): EventSource {
+  const source = new EventSource('/api/v1/stream', { withCredentials: true })
+  for (const [eventType, handler] of Object.entries(handlers)) {
+    if (!handler) continue
+    source.addEventListener(eventType, (event) => handler(JSON.parse((event as MessageEvent).data)))
+  }
+  return source
+}
+
+async function request<T>(method: string, path: string, body?: unknown, headers?: Record<string, string>): Promise<T> {
+  const init: RequestInit = {
     method,
     credentials: 'include',
-    headers: body ? { 'Content-Type': 'application/json' } : undefined,
+    headers: { ...(body ? { 'Content-Type': 'application/json' } : {}), ...headers },
     body: body ? JSON.stringify(body) : undefined
-  })
+  }
+  let response: Response
+  try {
+    response = await fetch(path, init)
+  } catch (networkError) {
+    // Order-placing calls carry an Idempotency-Key, so resending after a dropped connection replays
+    // the first outcome instead of placing a second order.
+    if (!headers?.['Idempotency-Key']) throw networkError
+    response = await fetch(path, init)
+  }
   const rawText = await response.text()
   const data = rawText ? JSON.parse(rawText) : null
   if (!response.ok) {
@@ -102,18 +269,23 @@ async function request<T>(method: string, path: string, body?: unknown): Promise
       showVerifyModal()
     }
     const message = data && typeof data.error === 'string' ? data.error : `Request failed (${response.status})`
-    throw new Error(message)
+    throw new ApiError(message, response.status, data && typeof data.code === 'string' ? data.code : undefined)
   }
   return data as T
 }
 
+const generated = createApiClient(request)
+
 export const api = {
   signup: (email: string, password: string, displayName: string, locale?: string) =>
     request<User>('POST', '/auth/signup', { email, password, display_name: displayName, locale }),
   login: (email: string, password: string) =>
-    request<User>('POST', '/auth/login', { email, password }),
+    request<User | TwoFactorChallenge>('POST', '/auth/login', { email, password }),
+  // challengeToken is omitted after a Google sign-in; the server then reads it from a cookie.
+  loginTwoFactor: (code: string, challengeToken?: string) =>
+    request<User>('POST', '/auth/login/two-factor', { code, challenge_token: challengeToken }),
   logout: () => request<{ message: string }>('POST', '/auth/logout'),
-  me: () => request<User>('GET', '/auth/me'),
+  me: generated.getCurrentUser,
   getAuthProviders: () => request<AuthProviders>('GET', '/auth/providers'),
 
   forgotPassword: (email: string, locale?: string) =>
@@ -133,8 +305,8 @@ export const api = {
   deleteAccount: (password: string) =>
     request<{ message: string }>('DELETE', '/api/v1/account', { password, confirm: true }),
 
-  getSettings: () => request<TradingSettings>('GET', '/api/v1/settings'),
-  saveSettings: (settings: TradingSettings) => request<TradingSettings>('PUT', '/api/v1/settings', settings),
+  getSettings: generated.getTradingSettings,
+  saveSettings: generated.saveTradingSettings,
 
   getCredentials: () => request<CredentialStatus>('GET', '/api/v1/binance/credentials'),
   saveCredentials: (environment: string, apiKey: string, apiSecret: string) =>
@@ -160,23 +332,87 @@ export const api = {
       `/api/v1/binance/klines?symbol=${encodeURIComponent(symbol)}&period=${encodeURIComponent(period)}`
     ),
 
-  getRobots: () => request<RobotsResponse>('GET', '/api/v1/robots'),
-  createRobot: (robot: Partial<Robot>) => request<Robot>('POST', '/api/v1/robots', robot),
-  updateRobot: (robot: Robot) => request<Robot>('POST', '/api/v1/robots/update', robot),
-  deleteRobot: (robotId: number) => request<{ message: string }>('POST', '/api/v1/robots/delete', { id: robotId }),
-
-  getOperations: () => request<Operation[]>('GET', '/api/v1/operations'),
-  getExecutions: () => request<Execution[]>('GET', '/api/v1/operations/executions'),
-  sellOperation: (operationId: number) =>
-    request<Operation>('POST', '/api/v1/operations/sell', { operation_id: operationId }),
-  placeSellOrder: (operationId: number) =>
-    request<Operation>('POST', '/api/v1/operations/place-sell', { operation_id: operationId }),
+  // Robots and operations use the resource-style v2 routes; /api/v1 stays for older clients.
+  getRobots: generated.listRobots,
+  createRobot: generated.createRobot,
+  updateRobot: ({ id, symbol: _symbol, ...changes }: Robot) => generated.updateRobot(id, changes),
+  deleteRobot: generated.deleteRobot,
+
+  getOperations: generated.listOperations,
+  getExecutions: generated.listExecutions,
+  sellOperation: generated.closeOperation,
+  placeSellOrder: generated.placeTakeProfit,
   buy: (symbol: string, quoteAmount: number, targetProfitPercent: number) =>
-    request<Operation>('POST', '/api/v1/operations', {
-      symbol,
-      quote_amount: quoteAmount,
-      target_profit_percent: targetProfitPercent
-    }),
+    generated.buy({ symbol, quote_amount: quoteAmount, target_profit_percent: targetProfitPercent }),
+
+  getNotificationChannels: () => request<NotificationChannel[]>('GET', '/api/v1/notifications/channels'),
+  addNotificationChannel: (channel: NotificationChannelInput) =>
+    request<NotificationChannel>('POST', '/api/v1/notifications/channels', channel),
+  setNotificationChannelEnabled: (channelId: number, isEnabled: boolean) =>
+    request<{ message: string }>('POST', '/api/v1/notifications/channels/update', { id: channelId, is_enabled: isEnabled }),
+  deleteNotificationChannel: (channelId: number) =>
+    request<{ message: string }>('POST', '/api/v1/notifications/channels/delete', { id: channelId }),
+  testNotificationChannel: (channelId: number) =>
+    request<{ message: string }>('POST', '/api/v1/notifications/channels/test', { id: channelId }),
+  getNotificationPreferences: () => request<NotificationPreference[]>('GET', '/api/v1/notifications/preferences'),
+  setNotificationPreference: (eventType: TradeEventType, isEnabled: boolean, locale?: string) =>
+    request<NotificationPreference[]>('PUT', '/api/v1/notifications/preferences', { event_type: eventType, is_enabled: isEnabled, locale }),
+  getDigestSubscription: () => request<DigestSubscription>('GET', '/api/v1/notifications/digest'),
+  saveDigestSubscription: (subscription: DigestSubscription) =>
+    request<DigestSubscription>('PUT', '/api/v1/notifications/digest', subscription),
+  getNotificationDeliveries: () => request<NotificationDelivery[]>('GET', '/api/v1/notifications/deliveries'),
+
+  getWebhooks: () => request<{ endpoints: WebhookEndpoint[]; event_types: string[] }>('GET', '/api/v1/webhooks'),
+  createWebhook: (url: string, description: string, eventTypes: string[]) =>
+    request<WebhookEndpoint>('POST', '/api/v1/webhooks', { url, description, event_types: eventTypes }),
+  deleteWebhook: (webhookId: number) => request<{ message: string }>('POST', '/api/v1/webhooks/delete', { id: webhookId }),
+  rotateWebhookSecret: (webhookId: number) =>
+    request<{ signing_secret: string }>('POST', '/api/v1/webhooks/rotate-secret', { id: webhookId }),
+  getWebhookDeliveries: (webhookId?: number) =>
+    request<WebhookDelivery[]>('GET', `/api/v1/webhooks/deliveries${webhookId ? `?endpoint_id=${webhookId}` : ''}`),
+
+  getAuditLog: (before?: number) =>
+    request<AuditEntry[]>('GET', `/api/v1/account/audit${before ? `?before=${before}` : ''}`),
+  getAdminAuditLog: (userId?: number, before?: number) => {
+    const query = new URLSearchParams()
+    if (userId) query.set('user_id', String(userId))
+    if (before) query.set('before', String(before))
+    const suffix = query.toString()
+    return request<AuditEntry[]>('GET', `/api/v1/admin/audit${suffix ? `?${suffix}` : ''}`)
+  },
+  getEffectiveConfiguration: () => request<EffectiveConfiguration>('GET', '/api/v1/admin/config'),
+  getPlatformPolicy: () => request<PlatformPolicy>('GET', '/api/v1/admin/policy'),
+  updatePlatformPolicy: (policy: Partial<Pick<PlatformPolicy, 'withdrawal_key_policy' | 'require_two_factor_for_live_trading'>>) =>
+    request<PlatformPolicy>('PUT', '/api/v1/admin/policy', policy),
+  resetUserTwoFactor: (userId: number) =>
+    request<{ message: string }>('POST', '/api/v1/admin/two-factor/reset', { user_id: userId }),
+
+  // Sensitive actions answer 403 'step_up_required' until the session re-confirms the password or code.
+  getStepUpStatus: () => request<StepUpStatus>('GET', '/api/v1/account/step-up'),
+  confirmStepUp: (confirmation: { password?: string; code?: string }) =>
+    request<{ active: true; expires_at: string }>('POST', '/api/v1/account/step-up', confirmation),
+
+  getSessions: () => request<{ sessions: ActiveSession[]; new_device_alerts: boolean }>('GET', '/api/v1/account/sessions'),
+  revokeSession: (sessionId: number) =>
+    request<{ message: string }>('POST', '/api/v1/account/sessions/revoke', { id: sessionId }),
+  revokeOtherSessions: () => request<{ revoked: number }>('POST', '/api/v1/account/sessions/revoke-others'),
+  setNewDeviceAlerts: (enabled: boolean) =>
+    request<{ new_device_alerts: boolean }>('PUT', '/api/v1/account/sessions/alerts', { enabled }),
+
+  getAccessTokens: () => request<{ tokens: AccessToken[]; scopes: AccessTokenScope[] }>('GET', '/api/v1/account/access-tokens'),
+  createAccessToken: (name: string, scopes: AccessTokenScope[], expiresInDays: number) =>
+    request<AccessToken>('POST', '/api/v1/account/access-tokens', { name, scopes, expires_in_days: expiresInDays }),
+  revokeAccessToken: (tokenId: number) =>
+    request<{ message: string }>('POST', '/api/v1/account/access-tokens/revoke', { id: tokenId }),
+
+  getTwoFactorStatus: () => request<TwoFactorStatus>('GET', '/api/v1/account/two-factor'),
+  beginTwoFactorEnrollment: () => request<TwoFactorEnrollment>('POST', '/api/v1/account/two-factor/enroll'),
+  confirmTwoFactorEnrollment: (code: string) =>
+    request<{ recovery_codes: string[] }>('POST', '/api/v1/account/two-factor/confirm', { code }),
+  disableTwoFactor: (code: string) =>
+    request<{ message: string }>('POST', '/api/v1/account/two-factor/disable', { code }),
+  regenerateRecoveryCodes: (code: string) =>
+    request<{ recovery_codes: string[] }>('POST', '/api/v1/account/two-factor/recovery-codes', { code }),
 
   getPortfolioSource: () => request<{ wallet_url: string }>('GET', '/api/v1/portfolio/source'),
   savePortfolioSource: (walletUrl: string) =>
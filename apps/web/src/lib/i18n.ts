@@ -161,6 +161,10 @@ const en: Dictionary = {
   'login.forgotSubmit': 'Send reset link',
   'login.forgotSent': 'If that email has an account, a reset link is on its way. Check your inbox (and spam).',
   'login.forgotBack': 'Back to sign in',
+  'login.twoFactorTitle': 'Two-step verification',
+  'login.twoFactorSubtitle': 'Enter the 6-digit code from your authenticator app, or one of your recovery codes.',
+  'login.twoFactorCode': 'Authentication code',
+  'login.twoFactorSubmit': 'Verify',
   'reset.title': 'Choose a new password',
   'reset.submit': 'Update password',
   'reset.invalid': 'This reset link is missing or invalid. Request a new one from the sign-in page.',
@@ -429,6 +433,10 @@ const pt: Dictionary = {
   'login.forgotSubmit': 'Enviar link',
   'login.forgotSent': 'Se esse e-mail tiver uma conta, o link de redefinição está a caminho. Verifique sua caixa de entrada (e o spam).',
   'login.forgotBack': 'Voltar ao login',
+  'login.twoFactorTitle': 'Verificação em duas etapas',
+  'login.twoFactorSubtitle': 'Digite o código de 6 dígitos do seu app autenticador ou um dos seus códigos de recuperação.',
+  'login.twoFactorCode': 'Código de autenticação',
+  'login.twoFactorSubmit': 'Verificar',
   'reset.title': 'Escolha uma nova senha',
   'reset.submit': 'Atualizar senha',
   'reset.invalid': 'Este link de redefinição está ausente ou inválido. Solicite um novo na tela de login.',
@@ -697,6 +705,10 @@ const es: Dictionary = {
   'login.forgotSubmit': 'Enviar enlace',
   'login.forgotSent': 'Si ese correo tiene una cuenta, el enlace de restablecimiento va en camino. Revisa tu bandeja (y el spam).',
   'login.forgotBack': 'Volver al inicio de sesión',
+  'login.twoFactorTitle': 'Verificación en dos pasos',
+  'login.twoFactorSubtitle': 'Introduce el código de 6 dígitos de tu app de autenticación o uno de tus códigos de recuperación.',
+  'login.twoFactorCode': 'Código de autenticación',
+  'login.twoFactorSubmit': 'Verificar',
   'reset.title': 'Elige una nueva contraseña',
   'reset.submit': 'Actualizar contraseña',
   'reset.invalid': 'Este enlace de restablecimiento falta o no es válido. Solicita uno nuevo en la pantalla de inicio de sesión.',
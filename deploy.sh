@@ -45,8 +45,9 @@ deploy_scraper() {
 health() {
   step "Health check"
   local code
-  code=$(curl -s -o /dev/null -w '%{http_code}' http://127.0.0.1:5020/health || echo 000)
-  [ "$code" = "200" ] && ok "API /health → 200" || printf '\033[1;31m✗ API /health → %s\033[0m\n' "$code"
+  # /health/ready answers 200 when ok or degraded (Binance/scraper unreachable), 503 when failing.
+  code=$(curl -s -o /dev/null -w '%{http_code}' http://127.0.0.1:5020/health/ready || echo 000)
+  [ "$code" = "200" ] && ok "API /health/ready → 200" || printf '\033[1;31m✗ API /health/ready → %s\033[0m\n' "$code"
 }
 
 targets=("${@:-web}")
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS notification_deliveries;
+DROP TABLE IF EXISTS notification_channels;
+
+COMMIT;
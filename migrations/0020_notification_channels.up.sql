@@ -0,0 +1,34 @@
+BEGIN;
+
+-- Per-user outbound notification channels (email, Telegram bot, Discord webhook, generic HTTPS
+-- webhook). The channel settings (bot token, chat id, webhook URLs) are secrets: they are stored as
+-- one AES-256-GCM encrypted JSON document, exactly like Binance keys. `label` is a masked,
+-- non-sensitive hint for the UI (e.g. "chat ****1234").
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_type VARCHAR(20) NOT NULL,      -- EMAIL | TELEGRAM | DISCORD | WEBHOOK
+    label VARCHAR(160) NOT NULL DEFAULT '',
+    configuration TEXT NOT NULL,            -- encrypted JSON
+    is_enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS notification_channels_user_idx ON notification_channels (user_id);
+
+-- Delivery log: one row per attempt to push a message through a channel. The channel reference is
+-- kept nullable so the history survives removing a channel.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id BIGINT REFERENCES notification_channels(id) ON DELETE SET NULL,
+    channel_type VARCHAR(20) NOT NULL,
+    event_type VARCHAR(60) NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS notification_deliveries_user_created_idx ON notification_deliveries (user_id, created_at DESC);
+
+COMMIT;
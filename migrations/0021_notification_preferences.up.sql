@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Per-user opt-in for each trade event type (take-profit filled, stop-loss triggered, take-profit
+-- expired, daily purchase failed). A missing row means the user has not opted in to that event.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(60) NOT NULL,
+    is_enabled BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, event_type)
+);
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Opt-in portfolio digest email. One row per user: DAILY or WEEKLY, delivered at local_hour in the
+-- user's IANA time zone (weekly digests on `weekday`, 0 = Sunday). `locale` picks the email copy
+-- (pt | en | es) and `last_sent_at` keeps the scheduler idempotent across restarts.
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'DAILY',
+    local_hour SMALLINT NOT NULL DEFAULT 8 CHECK (local_hour BETWEEN 0 AND 23),
+    weekday SMALLINT NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 0 AND 6),
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'America/Sao_Paulo',
+    locale VARCHAR(5) NOT NULL DEFAULT 'pt',
+    is_enabled BOOLEAN NOT NULL DEFAULT true,
+    last_sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS digest_subscriptions_enabled_idx ON digest_subscriptions (is_enabled) WHERE is_enabled;
+
+COMMIT;
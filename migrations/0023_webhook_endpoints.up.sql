@@ -0,0 +1,40 @@
+BEGIN;
+
+-- User-registered outgoing webhooks. Each endpoint has its own HMAC signing secret, stored encrypted
+-- (it must be recoverable to sign payloads, so it cannot be hashed). `event_types` is the list of
+-- subscribed event names; an empty list means every event.
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    description VARCHAR(160) NOT NULL DEFAULT '',
+    signing_secret TEXT NOT NULL,           -- encrypted
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    is_enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS webhook_endpoints_user_idx ON webhook_endpoints (user_id);
+
+-- Persistent delivery queue and history. A row is PENDING until delivered (SUCCEEDED) or until it
+-- runs out of attempts (FAILED). The dispatcher picks due rows by next_attempt_at and backs off
+-- exponentially between attempts, so a restart never loses or duplicates the schedule.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_id VARCHAR(40) NOT NULL,
+    event_type VARCHAR(60) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(12) NOT NULL DEFAULT 'PENDING', -- PENDING | SUCCEEDED | FAILED
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_created_idx ON webhook_deliveries (endpoint_id, created_at DESC);
+
+COMMIT;
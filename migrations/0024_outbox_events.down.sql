@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS webhook_deliveries_endpoint_event_idx;
+DROP TABLE IF EXISTS outbox_event_receipts;
+DROP TABLE IF EXISTS outbox_events;
+
+COMMIT;
@@ -0,0 +1,34 @@
+BEGIN;
+
+-- Transactional outbox. Trading state changes append their domain events here in the SAME transaction
+-- as the rows they describe, so an event exists if and only if the change was committed. The event
+-- bus reads the table and hands each event to every in-process subscriber (webhooks, notifications).
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(60) NOT NULL,
+    payload TEXT NOT NULL,                  -- JSON document of the typed event
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS outbox_events_created_idx ON outbox_events (created_at);
+
+-- Per-subscriber receipt of an outbox event. No row means "not handled yet"; DONE rows are never
+-- handed out again; PENDING rows are retried from next_attempt_at; DEAD rows ran out of attempts.
+-- Receipts (rather than a single id cursor) keep delivery correct when transactions commit out of
+-- id order.
+CREATE TABLE IF NOT EXISTS outbox_event_receipts (
+    event_id BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    subscriber VARCHAR(60) NOT NULL,
+    status VARCHAR(12) NOT NULL,            -- PENDING | DONE | DEAD
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, subscriber)
+);
+
+-- Subscribers are at-least-once, so the webhook subscriber may see the same outbox event twice; the
+-- event id is derived from the outbox id and one delivery per endpoint and event is enough.
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_endpoint_event_idx ON webhook_deliveries (endpoint_id, event_id);
+
+COMMIT;
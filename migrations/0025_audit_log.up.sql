@@ -0,0 +1,32 @@
+BEGIN;
+
+-- Append-only log of security- and money-relevant user actions (logins, credential and settings
+-- changes, robot changes, manual trades). before_value/after_value hold JSON snapshots of what
+-- changed; secrets are never recorded, only masked values. Rows cannot be updated; they only go away
+-- with the account itself (ON DELETE CASCADE), so a deleted account leaves no IP/user-agent history.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_user_id BIGINT,                   -- who performed it; NULL for the system (automation)
+    action VARCHAR(60) NOT NULL,
+    target_type VARCHAR(40) NOT NULL DEFAULT '',
+    target_id VARCHAR(60) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    before_value JSONB,
+    after_value JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id, id DESC);
+
+CREATE OR REPLACE FUNCTION audit_log_reject_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_reject_update();
+
+COMMIT;
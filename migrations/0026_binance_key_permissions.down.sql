@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TABLE IF EXISTS platform_policy;
+
+ALTER TABLE binance_credentials
+    DROP COLUMN IF EXISTS permissions_checked_at,
+    DROP COLUMN IF EXISTS reading_enabled,
+    DROP COLUMN IF EXISTS spot_trading_enabled,
+    DROP COLUMN IF EXISTS withdrawals_enabled,
+    DROP COLUMN IF EXISTS ip_restricted;
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- What Binance reports for each stored key (GET /sapi/v1/account/apiRestrictions), recorded when the
+-- key is saved. NULLs mean "not checked" (the Spot testnet has no /sapi endpoints).
+ALTER TABLE binance_credentials
+    ADD COLUMN IF NOT EXISTS permissions_checked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS reading_enabled BOOLEAN,
+    ADD COLUMN IF NOT EXISTS spot_trading_enabled BOOLEAN,
+    ADD COLUMN IF NOT EXISTS withdrawals_enabled BOOLEAN,
+    ADD COLUMN IF NOT EXISTS ip_restricted BOOLEAN;
+
+-- Platform-wide security policy, edited by admins. A single row.
+-- withdrawal_key_policy: REFUSE rejects keys that can withdraw; FLAG stores them with a warning.
+CREATE TABLE IF NOT EXISTS platform_policy (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    withdrawal_key_policy VARCHAR(10) NOT NULL DEFAULT 'REFUSE' CHECK (withdrawal_key_policy IN ('REFUSE', 'FLAG')),
+    updated_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+INSERT INTO platform_policy (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+COMMIT;
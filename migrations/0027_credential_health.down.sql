@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE binance_credentials
+    DROP COLUMN IF EXISTS health_status,
+    DROP COLUMN IF EXISTS health_checked_at,
+    DROP COLUMN IF EXISTS health_error;
+
+COMMIT;
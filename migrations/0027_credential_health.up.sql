@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Result of the periodic revalidation of each stored Binance key. INVALID means Binance rejected the
+-- key itself (revoked, expired, IP or permission change); that environment's robots are paused then.
+-- health_error keeps the last failure, including transient ones that did not change the status.
+ALTER TABLE binance_credentials
+    ADD COLUMN IF NOT EXISTS health_status VARCHAR(10) NOT NULL DEFAULT 'UNKNOWN'
+        CHECK (health_status IN ('UNKNOWN', 'HEALTHY', 'INVALID')),
+    ADD COLUMN IF NOT EXISTS health_checked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS health_error TEXT;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE platform_policy DROP COLUMN IF EXISTS require_two_factor_for_live_trading;
+ALTER TABLE auth_tokens DROP COLUMN IF EXISTS failed_attempts;
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+-- TOTP two-factor authentication. The secret is encrypted with the credentials keyring; enabled_at
+-- stays NULL until the user confirms enrollment with a first code. last_used_step is the RFC 6238
+-- time step of the last accepted code, so a code cannot be replayed within its validity window.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_ciphertext TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use recovery codes; only the SHA-256 hash of each code is stored.
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS user_recovery_codes_user_hash_unique ON user_recovery_codes (user_id, code_hash);
+
+-- The login challenge between the password and the second factor is an auth_tokens row
+-- ('two_factor_login'); failed_attempts retires it after too many wrong codes.
+ALTER TABLE auth_tokens ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
+
+-- Admins can require 2FA before a user may enable live (real-money) trading.
+ALTER TABLE platform_policy
+    ADD COLUMN IF NOT EXISTS require_two_factor_for_live_trading BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS step_up_expires_at;
+
+COMMIT;
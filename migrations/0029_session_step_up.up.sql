@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Step-up re-authentication: until step_up_expires_at the session may perform sensitive actions
+-- (saving Binance keys, activating PRODUCTION, enabling live trading, changing the password, deleting
+-- the account). Set at sign-in and whenever the user re-confirms their password or 2FA code.
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS step_up_expires_at TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Personal access tokens for scripts and integrations. Like sessions, only a SHA-256 hash of the token
+-- is stored; token_prefix keeps the first characters so the user can tell tokens apart. `scopes` lists
+-- what the token may do (read:operations, trade, robots:write). A token stops working once it is past
+-- expires_at (NULL = never) or revoked_at is set.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(80) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip VARCHAR(64),
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_idx ON personal_access_tokens (user_id);
+
+COMMIT;
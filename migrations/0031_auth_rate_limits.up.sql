@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Throttling state for the public auth endpoints (login, signup, password reset, verification
+-- resend). Each bucket_key is one counter, e.g. "login:ip:203.0.113.7" or "login_failure:ana@x.com":
+-- hit_count hits in the fixed window ending at window_ends_at. blocked_until holds progressive delays
+-- and temporary lockouts after repeated failed sign-ins. Expired rows are purged in the background.
+CREATE TABLE IF NOT EXISTS auth_rate_limits (
+    bucket_key TEXT PRIMARY KEY,
+    hit_count INTEGER NOT NULL DEFAULT 0,
+    window_ends_at TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS auth_rate_limits_window_idx ON auth_rate_limits (window_ends_at);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE users DROP COLUMN IF EXISTS new_device_alerts;
+DROP TABLE IF EXISTS user_known_devices;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS location;
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Active session management. `location` is the approximate place of the sign-in IP, resolved once
+-- when the session is created (empty when no geolocation lookup is configured).
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS location VARCHAR(160);
+
+-- Devices (browser + operating system) a user has signed in from, so a sign-in from a new one can be
+-- reported by email. Kept after the sessions themselves expire.
+CREATE TABLE IF NOT EXISTS user_known_devices (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_key VARCHAR(120) NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, device_key)
+);
+
+-- Opt-out for the new-device sign-in email.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS new_device_alerts BOOLEAN NOT NULL DEFAULT true;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Idempotency-Key support for the order-placing endpoints (buy, close, take-profit, robot creation).
+-- The first request with a key claims the row (response_status NULL while it runs); its response is
+-- then stored and replayed to retries until expires_at. request_fingerprint is a SHA-256 of the
+-- method, path and body, so a key reused for a different request is refused. Expired rows are purged
+-- in the background.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_fingerprint CHAR(64) NOT NULL,
+    response_status INTEGER,
+    response_location TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+
+COMMIT;
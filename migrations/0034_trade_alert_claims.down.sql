@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS notification_locales;
+DROP TABLE IF EXISTS trade_alert_claims;
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- One row per alert that may only go out once per robot and UTC day (a failed daily purchase is
+-- retried every few minutes during its hour). The automation claims the row in the same transaction
+-- that publishes the alert; a second claim for the same day finds the row and publishes nothing.
+CREATE TABLE IF NOT EXISTS trade_alert_claims (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    robot_id BIGINT NOT NULL REFERENCES trading_robots(id) ON DELETE CASCADE,
+    event_type VARCHAR(60) NOT NULL,
+    alert_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (robot_id, event_type, alert_date)
+);
+
+-- The language of trade alerts (pt, en or es), taken from the user's browser when they change their
+-- notification settings. Without a row alerts are sent in Portuguese, like the account emails.
+CREATE TABLE IF NOT EXISTS notification_locales (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    locale VARCHAR(8) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;